@@ -0,0 +1,235 @@
+// Package main 程序入口
+// cli.go - 运维命令行子命令
+//
+// 子命令复用服务器的配置数据库和业务服务，便于在管理后台无法访问时通过 SSH 处理问题：
+//
+//	UserFrontend [serve]                      启动服务器（默认）
+//	UserFrontend admin reset-password <用户名>  重置管理员密码
+//	UserFrontend admin disable-2fa <用户名>     关闭管理员两步验证
+//	UserFrontend backup create|list|restore   备份与恢复
+//	UserFrontend migrate up|status|down       数据库迁移
+//	UserFrontend config show|set              查看与修改配置
+//	UserFrontend key reset                    重置加密密钥
+//	UserFrontend kami import <商品ID> <文件>    从文件导入卡密
+//	UserFrontend user disable|enable <用户>    禁用或启用用户
+//	UserFrontend cache flush                  清空缓存
+//
+// 修改配置和恢复数据库等操作在服务重启后完全生效；使用本地内存缓存时，运行中服务的缓存不受命令行影响。
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+	"user-frontend/internal/service"
+)
+
+// progName 帮助信息中的程序名
+var progName = filepath.Base(os.Args[0])
+
+// cliCommand 子命令
+type cliCommand struct {
+	usage string
+	desc  string
+	run   func(args []string) int
+}
+
+// cliCommands 子命令列表（按名称分组，组内第二个参数为动作）
+var cliCommands map[string]map[string]cliCommand
+
+func init() {
+	cliCommands = map[string]map[string]cliCommand{
+		"admin": {
+			"reset-password": {"[-password 新密码] <用户名>", "重置管理员密码并启用账号（未指定密码时随机生成）", cmdAdminResetPassword},
+			"disable-2fa":    {"<用户名>", "关闭管理员两步验证", cmdAdminDisable2FA},
+		},
+		"backup": {
+			"create":  {"[-remark 备注] [-objects]", "创建数据库备份", cmdBackupCreate},
+			"list":    {"", "列出备份", cmdBackupList},
+			"restore": {"[-force] [-objects] [-yes] <备份ID>", "从备份恢复数据库", cmdBackupRestore},
+		},
+		"migrate": {
+			"up":     {"", "执行未完成的迁移", cmdMigrate("up")},
+			"status": {"", "查看迁移状态", cmdMigrate("status")},
+			"down":   {"[-steps 1]", "回滚最近的迁移", cmdMigrate("down")},
+		},
+		"config": {
+			"show": {"", "查看当前配置（密码已隐藏）", cmdConfigShow},
+			"set":  {"<配置项> <值>", "修改配置，可用配置项见 config set -h", cmdConfigSet},
+		},
+		"key": {
+			"reset": {"[-length 256] [-yes]", "重置加密密钥并改写全部加密存储的值", cmdKeyReset},
+		},
+		"kami": {
+			"import": {"<商品ID> <文件>", "从文件导入手动卡密（- 表示标准输入）", cmdKamiImport},
+		},
+		"user": {
+			"disable": {"<用户名|邮箱|ID>", "禁用用户", cmdUserStatus(0)},
+			"enable":  {"<用户名|邮箱|ID>", "启用用户", cmdUserStatus(1)},
+		},
+		"cache": {
+			"flush": {"", "清空缓存", cmdCacheFlush},
+		},
+	}
+}
+
+// cliGroupOrder 帮助信息中的分组顺序
+var cliGroupOrder = []string{"admin", "backup", "migrate", "config", "key", "kami", "user", "cache"}
+
+// runCommand 执行子命令，返回进程退出码
+func runCommand(name string, args []string) int {
+	switch name {
+	case "serve":
+		return runServe(args)
+	case "help":
+		printUsage()
+		return 0
+	}
+
+	group, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的命令: %s\n\n", name)
+		printUsage()
+		return 2
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printGroupUsage(name)
+		return 2
+	}
+	cmd, ok := group[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的命令: %s %s\n\n", name, args[0])
+		printGroupUsage(name)
+		return 2
+	}
+	return cmd.run(args[1:])
+}
+
+// printUsage 打印全部命令的帮助信息
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "用法: %s [命令] [参数]\n", progName)
+	fmt.Fprintln(os.Stderr, "\n未指定命令时启动服务器。命令:")
+	fmt.Fprintln(os.Stderr, "  serve [-migrate up|status|down]  启动服务器")
+	for _, name := range cliGroupOrder {
+		printGroupCommands(name)
+	}
+}
+
+// printGroupUsage 打印一组命令的帮助信息
+func printGroupUsage(name string) {
+	fmt.Fprintf(os.Stderr, "用法: %s %s <动作> [参数]\n", progName, name)
+	printGroupCommands(name)
+}
+
+// printGroupCommands 按动作名称顺序打印一组命令
+func printGroupCommands(name string) {
+	group := cliCommands[name]
+	actions := make([]string, 0, len(group))
+	for action := range group {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		cmd := group[action]
+		fmt.Fprintf(os.Stderr, "  %s\n      %s\n", strings.TrimSpace(name+" "+action+" "+cmd.usage), cmd.desc)
+	}
+}
+
+// newFlagSet 创建子命令参数解析器
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "用法: %s %s %s\n", progName, name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs 解析选项并返回 nargs 个位置参数，选项可以出现在位置参数之后
+// 解析失败或参数数量不符时 ok 为 false，code 为应返回的退出码（-h 为 0）
+func parseArgs(fs *flag.FlagSet, args []string, nargs int) (positional []string, code int, ok bool) {
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, 0, false
+			}
+			return nil, 2, false
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) != nargs {
+		fs.Usage()
+		return nil, 2, false
+	}
+	return positional, 0, true
+}
+
+// openMainDB 加载配置并连接主数据库
+func openMainDB() (*appContext, *repository.Repository, error) {
+	app := bootstrap()
+	repo, err := connectMainDB(app)
+	if err != nil {
+		return nil, nil, err
+	}
+	return app, repo, nil
+}
+
+// connectMainDB 连接主数据库（连接时执行未完成的迁移），并初始化配置服务与缓存
+func connectMainDB(app *appContext) (*repository.Repository, error) {
+	if err := model.InitDB(&config.GlobalConfig.DBConfig); err != nil {
+		return nil, fmt.Errorf("连接主数据库失败: %v", err)
+	}
+	repo := repository.NewRepository(model.DB)
+	app.configSvc.SetMainDB(model.DB)
+	app.configSvc.SetRepo(repo)
+	initCacheSystem(app.configSvc)
+	return repo, nil
+}
+
+// cliOperator 操作日志中的操作人
+func cliOperator() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if name == "" {
+		return "cli"
+	}
+	return "cli:" + name
+}
+
+// logCLIAction 将命令行操作记录到管理员操作日志（未连接主数据库时写入CSV文件）
+func logCLIAction(action, target, targetID string, detail interface{}) {
+	service.NewLogService(repository.NewRepository(model.DB)).LogAdminActionSimple(cliOperator(), action, target, targetID, detail, "localhost", "cli")
+}
+
+// confirm 要求在终端输入指定文本确认危险操作
+func confirm(prompt, expected string) bool {
+	fmt.Fprintf(os.Stderr, "%s\n请输入 %s 确认: ", prompt, expected)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	return strings.TrimSpace(line) == expected
+}
+
+// fail 打印错误并返回退出码 1
+func fail(format string, a ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "错误: "+format+"\n", a...)
+	return 1
+}
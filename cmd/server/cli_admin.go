@@ -0,0 +1,186 @@
+// Package main 程序入口
+// cli_admin.go - 管理员与用户账号命令
+//
+// 管理员账号可能存在于三处：admins 表（角色权限系统）、admin_users 表（旧版管理员）和
+// 系统配置中的管理员账号（数据库无管理员记录时使用），命令会同时处理所有同名账号。
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/service"
+	"user-frontend/internal/utils"
+)
+
+// cmdAdminResetPassword admin reset-password 子命令
+func cmdAdminResetPassword(args []string) int {
+	fs := newFlagSet("admin reset-password", "[-password 新密码] <用户名>")
+	password := fs.String("password", "", "新密码，至少6位（未指定时随机生成16位密码）")
+	pos, code, ok := parseArgs(fs, args, 1)
+	if !ok {
+		return code
+	}
+	username := pos[0]
+
+	generated := *password == ""
+	if generated {
+		*password = utils.GenerateRandomString(16)
+	} else if len(*password) < 6 {
+		return fail("密码长度至少6位")
+	}
+
+	app, repo, err := openMainDB()
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	var updated []string
+	roleSvc := service.NewRoleService(repo)
+	if admin, err := roleSvc.GetAdminByUsername(username); err == nil {
+		if err := roleSvc.UpdateAdminPassword(admin.ID, *password); err != nil {
+			return fail("更新管理员密码失败: %v", err)
+		}
+		// 被禁用的账号无法登录，重置密码时一并启用
+		if admin.Status != 1 {
+			if err := roleSvc.SetAdminStatus(admin.ID, 1); err != nil {
+				return fail("启用管理员失败: %v", err)
+			}
+			fmt.Printf("管理员 %s 已被禁用，现已重新启用\n", username)
+		}
+		updated = append(updated, "admins")
+	}
+
+	adminSvc := service.NewAdminService(repo)
+	if _, err := adminSvc.GetAdminByUsername(username); err == nil {
+		if err := adminSvc.ResetPassword(username, *password); err != nil {
+			return fail("更新管理员密码失败: %v", err)
+		}
+		updated = append(updated, "admin_users")
+	}
+
+	if sysCfg, err := app.configSvc.GetSystemConfig(); err == nil && sysCfg.AdminUsername == username {
+		sysCfg.AdminPassword = *password
+		if err := app.configSvc.SaveSystemConfig(sysCfg); err != nil {
+			return fail("更新系统配置失败: %v", err)
+		}
+		updated = append(updated, "system_config")
+	}
+
+	if len(updated) == 0 {
+		return fail("管理员 %s 不存在", username)
+	}
+	logCLIAction("reset_password", "admin", username, "命令行重置密码: "+strings.Join(updated, ", "))
+
+	fmt.Printf("已重置管理员 %s 的密码（%s）\n", username, strings.Join(updated, ", "))
+	if generated {
+		fmt.Printf("新密码: %s\n请登录后立即修改\n", *password)
+	}
+	return 0
+}
+
+// cmdAdminDisable2FA admin disable-2fa 子命令
+func cmdAdminDisable2FA(args []string) int {
+	fs := newFlagSet("admin disable-2fa", "<用户名>")
+	pos, code, ok := parseArgs(fs, args, 1)
+	if !ok {
+		return code
+	}
+	username := pos[0]
+
+	app, repo, err := openMainDB()
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	var updated []string
+	roleSvc := service.NewRoleService(repo)
+	if admin, err := roleSvc.GetAdminByUsername(username); err == nil {
+		if err := roleSvc.DisableAdmin2FA(admin.ID); err != nil {
+			return fail("关闭两步验证失败: %v", err)
+		}
+		updated = append(updated, "admins")
+	}
+
+	adminSvc := service.NewAdminService(repo)
+	if _, err := adminSvc.GetAdminByUsername(username); err == nil {
+		if err := adminSvc.Disable2FA(username); err != nil {
+			return fail("关闭两步验证失败: %v", err)
+		}
+		updated = append(updated, "admin_users")
+	}
+
+	if sysCfg, err := app.configSvc.GetSystemConfig(); err == nil && sysCfg.AdminUsername == username {
+		sysCfg.Enable2FA = false
+		sysCfg.TOTPSecret = ""
+		if err := app.configSvc.SaveSystemConfig(sysCfg); err != nil {
+			return fail("更新系统配置失败: %v", err)
+		}
+		updated = append(updated, "system_config")
+	}
+
+	if len(updated) == 0 {
+		return fail("管理员 %s 不存在", username)
+	}
+	logCLIAction("disable_2fa", "admin", username, "命令行关闭两步验证: "+strings.Join(updated, ", "))
+
+	fmt.Printf("已关闭管理员 %s 的两步验证（%s），登录后可重新绑定\n", username, strings.Join(updated, ", "))
+	return 0
+}
+
+// cmdUserStatus user disable/enable 子命令
+func cmdUserStatus(status int) func(args []string) int {
+	action, label := "enable", "启用"
+	if status == 0 {
+		action, label = "disable", "禁用"
+	}
+	return func(args []string) int {
+		fs := newFlagSet("user "+action, "<用户名|邮箱|ID>")
+		pos, code, ok := parseArgs(fs, args, 1)
+		if !ok {
+			return code
+		}
+
+		_, repo, err := openMainDB()
+		if err != nil {
+			return fail("%v", err)
+		}
+
+		userSvc := service.NewUserService(repo)
+		user, err := findUser(userSvc, pos[0])
+		if err != nil {
+			return fail("用户 %s 不存在", pos[0])
+		}
+		if err := userSvc.UpdateUserStatus(user.ID, status); err != nil {
+			return fail("%s用户失败: %v", label, err)
+		}
+		// 禁用后立即注销已登录的会话
+		if status == 0 {
+			if err := service.NewSessionService(repo).DeleteUserSessionsByUserID(user.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "警告: 注销用户会话失败: %v\n", err)
+			}
+		}
+		logCLIAction(action, "user", strconv.Itoa(int(user.ID)), user.Username)
+
+		fmt.Printf("已%s用户 %s（ID %d）\n", label, user.Username, user.ID)
+		return 0
+	}
+}
+
+// findUser 按用户名、邮箱或ID查找用户
+func findUser(userSvc *service.UserService, key string) (*model.User, error) {
+	if user, err := userSvc.GetUserByUsername(key); err == nil {
+		return user, nil
+	}
+	if strings.Contains(key, "@") {
+		return userSvc.GetUserByEmail(key)
+	}
+	id, err := strconv.ParseUint(key, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return userSvc.GetUserByID(uint(id))
+}
@@ -0,0 +1,315 @@
+// Package main 程序入口
+// cli_config.go - 配置查看、修改与加密密钥命令
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+)
+
+// configItem 可通过 config set 修改的配置项
+type configItem struct {
+	name   string
+	desc   string
+	mainDB bool // 存储在主数据库中（系统配置），其余存储在配置数据库中
+	set    func(app *appContext, value string) error
+}
+
+// configItems 可修改的配置项
+var configItems = []configItem{
+	{"port", "服务器端口", false, func(app *appContext, value string) error {
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("无效的端口: %s", value)
+		}
+		return app.configSvc.SaveServerPort(port)
+	}},
+	{"db.type", "数据库类型: sqlite、mysql、postgres", false, setDBConfig(func(cfg *config.DBConfig, value string) error {
+		if value != "sqlite" && value != "mysql" && value != "postgres" {
+			return fmt.Errorf("无效的数据库类型: %s", value)
+		}
+		cfg.Type = value
+		return nil
+	})},
+	{"db.host", "数据库主机", false, setDBConfig(func(cfg *config.DBConfig, value string) error {
+		cfg.Host = value
+		return nil
+	})},
+	{"db.port", "数据库端口", false, setDBConfig(func(cfg *config.DBConfig, value string) error {
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("无效的端口: %s", value)
+		}
+		cfg.Port = port
+		return nil
+	})},
+	{"db.user", "数据库用户名", false, setDBConfig(func(cfg *config.DBConfig, value string) error {
+		cfg.User = value
+		return nil
+	})},
+	{"db.password", "数据库密码", false, setDBConfig(func(cfg *config.DBConfig, value string) error {
+		cfg.Password = value
+		return nil
+	})},
+	{"db.database", "数据库名（SQLite 为文件路径）", false, setDBConfig(func(cfg *config.DBConfig, value string) error {
+		cfg.Database = value
+		return nil
+	})},
+	{"system_title", "系统标题", true, func(app *appContext, value string) error {
+		return app.configSvc.UpdateSystemTitle(value)
+	}},
+	{"admin_suffix", "管理后台路径", true, func(app *appContext, value string) error {
+		value = strings.Trim(value, "/")
+		if value == "" || strings.ContainsAny(value, "/ ") {
+			return fmt.Errorf("无效的管理后台路径: %s", value)
+		}
+		return app.configSvc.UpdateAdminSuffix(value)
+	}},
+	{"enable_login", "启用管理后台登录验证: true、false", true, func(app *appContext, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("无效的开关值: %s", value)
+		}
+		cfg, err := app.configSvc.GetSystemConfig()
+		if err != nil {
+			return err
+		}
+		cfg.EnableLogin = enabled
+		return app.configSvc.SaveSystemConfig(cfg)
+	}},
+	{"enable_whitelist", "启用管理后台IP白名单: true、false", true, func(app *appContext, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("无效的开关值: %s", value)
+		}
+		_, whitelist, err := app.configSvc.GetWhitelistConfig()
+		if err != nil {
+			return err
+		}
+		return app.configSvc.UpdateWhitelistConfig(enabled, whitelist)
+	}},
+	{"ip_whitelist", "管理后台IP白名单，多个IP以逗号分隔", true, func(app *appContext, value string) error {
+		enabled, _, err := app.configSvc.GetWhitelistConfig()
+		if err != nil {
+			return err
+		}
+		var whitelist []string
+		for _, ip := range strings.Split(value, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				whitelist = append(whitelist, ip)
+			}
+		}
+		return app.configSvc.UpdateWhitelistConfig(enabled, whitelist)
+	}},
+}
+
+// setDBConfig 修改数据库连接配置的一个字段
+func setDBConfig(fn func(cfg *config.DBConfig, value string) error) func(app *appContext, value string) error {
+	return func(app *appContext, value string) error {
+		cfg, err := app.configSvc.GetDBConfig()
+		if err != nil {
+			return err
+		}
+		if err := fn(cfg, value); err != nil {
+			return err
+		}
+		if cfg.Type == "sqlite" && cfg.Database != "" && !filepath.IsAbs(cfg.Database) {
+			if abs, err := filepath.Abs(cfg.Database); err == nil {
+				cfg.Database = abs
+			}
+		}
+		if err := app.configSvc.SaveDBConfig(cfg); err != nil {
+			return err
+		}
+		if err := model.TestConnection(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "警告: 使用新配置连接数据库失败: %v\n", err)
+		}
+		return nil
+	}
+}
+
+// cmdConfigShow config show 子命令
+func cmdConfigShow(args []string) int {
+	fs := newFlagSet("config show", "")
+	if _, code, ok := parseArgs(fs, args, 0); !ok {
+		return code
+	}
+
+	app := bootstrap()
+	svc := app.configSvc
+
+	port, _ := svc.GetServerPort()
+	fmt.Println("[服务器]")
+	fmt.Printf("  配置目录: %s\n", app.configDir)
+	fmt.Printf("  端口: %d\n", port)
+
+	dbCfg := config.GlobalConfig.DBConfig
+	fmt.Println("[数据库]")
+	fmt.Printf("  类型: %s\n", dbCfg.Type)
+	if dbCfg.Type != "sqlite" {
+		fmt.Printf("  地址: %s:%d\n", dbCfg.Host, dbCfg.Port)
+		fmt.Printf("  用户: %s\n", dbCfg.User)
+		fmt.Printf("  密码: %s\n", maskSecret(dbCfg.Password))
+	}
+	fmt.Printf("  数据库: %s\n", dbCfg.Database)
+
+	if _, keyLength, err := svc.GetEncryptionKeyInfo(); err == nil {
+		fmt.Println("[加密密钥]")
+		fmt.Printf("  长度: %d 位\n", keyLength)
+	}
+
+	if redisCfg, err := svc.GetRedisConfig(); err == nil {
+		fmt.Println("[Redis]")
+		if redisCfg.Enabled {
+			fmt.Printf("  已启用: %s 模式，%s:%d，密码 %s\n", redisCfg.Mode, redisCfg.Host, redisCfg.Port, maskSecret(redisCfg.Password))
+		} else {
+			fmt.Println("  未启用（使用本地内存缓存）")
+		}
+	}
+
+	if storageCfg, err := svc.GetStorageConfig(); err == nil {
+		fmt.Println("[对象存储]")
+		if storageCfg.Driver == "s3" {
+			fmt.Printf("  S3: %s，存储桶 %s，密钥 %s\n", storageCfg.Endpoint, storageCfg.Bucket, maskSecret(storageCfg.SecretKey))
+		} else {
+			fmt.Printf("  本地: %s\n", storageCfg.LocalRoot)
+		}
+	}
+
+	if backupCfg, err := svc.GetBackupConfig(); err == nil {
+		fmt.Println("[备份]")
+		fmt.Printf("  压缩: %s，加密: %s（%s）\n", onOff(backupCfg.Compress), onOff(backupCfg.Encrypt), backupCfg.EncryptionMode)
+		if backupCfg.ScheduleEnabled {
+			fmt.Printf("  定时备份: 每 %d 小时\n", backupCfg.IntervalHours)
+		} else {
+			fmt.Println("  定时备份: 未启用")
+		}
+		for _, t := range backupCfg.Targets {
+			fmt.Printf("  异地目标: %s（%s）\n", t.Name, t.Type)
+		}
+	}
+
+	// 系统配置存储在主数据库中，主数据库不可用时只显示上面的配置
+	if _, err := connectMainDB(app); err != nil {
+		fmt.Fprintf(os.Stderr, "警告: %v，无法读取系统配置\n", err)
+		return 0
+	}
+	sysCfg, err := svc.GetSystemConfig()
+	if err != nil {
+		return fail("读取系统配置失败: %v", err)
+	}
+	fmt.Println("[系统]")
+	fmt.Printf("  系统标题: %s\n", sysCfg.SystemTitle)
+	fmt.Printf("  管理后台路径: /%s\n", sysCfg.AdminSuffix)
+	fmt.Printf("  登录验证: %s\n", onOff(sysCfg.EnableLogin))
+	fmt.Printf("  配置管理员: %s，两步验证: %s\n", sysCfg.AdminUsername, onOff(sysCfg.Enable2FA))
+	fmt.Printf("  IP白名单: %s %s\n", onOff(sysCfg.EnableWhitelist), strings.Join(sysCfg.IPWhitelist, ", "))
+	if list, err := model.GetMigrationStatus(model.DB); err == nil {
+		applied := 0
+		for _, m := range list {
+			if m.Applied && m.Version > applied {
+				applied = m.Version
+			}
+		}
+		fmt.Printf("  数据库结构版本: %d（程序 %d）\n", applied, model.SchemaVersion)
+	}
+	return 0
+}
+
+// cmdConfigSet config set 子命令
+func cmdConfigSet(args []string) int {
+	usage := "<配置项> <值>\n\n配置项:"
+	for _, item := range configItems {
+		usage += fmt.Sprintf("\n  %-16s %s", item.name, item.desc)
+	}
+	fs := newFlagSet("config set", usage)
+	pos, code, ok := parseArgs(fs, args, 2)
+	if !ok {
+		return code
+	}
+
+	var item *configItem
+	for i := range configItems {
+		if configItems[i].name == pos[0] {
+			item = &configItems[i]
+		}
+	}
+	if item == nil {
+		fs.Usage()
+		return 2
+	}
+
+	app := bootstrap()
+	if item.mainDB {
+		if _, err := connectMainDB(app); err != nil {
+			return fail("%v", err)
+		}
+	}
+	if err := item.set(app, pos[1]); err != nil {
+		return fail("修改配置失败: %v", err)
+	}
+
+	detail := pos[0] + " = " + pos[1]
+	if strings.Contains(pos[0], "password") {
+		detail = pos[0] + " = " + maskSecret(pos[1])
+	}
+	logCLIAction("update", "config", pos[0], detail)
+
+	fmt.Printf("已修改 %s，重启服务后生效\n", pos[0])
+	return 0
+}
+
+// cmdKeyReset key reset 子命令
+func cmdKeyReset(args []string) int {
+	fs := newFlagSet("key reset", "[-length 256] [-yes]")
+	length := fs.Int("length", 256, "密钥长度: 128、192、256")
+	yes := fs.Bool("yes", false, "跳过确认")
+	if _, code, ok := parseArgs(fs, args, 0); !ok {
+		return code
+	}
+	if *length != 128 && *length != 192 && *length != 256 {
+		return fail("无效的密钥长度: %d", *length)
+	}
+
+	app, _, err := openMainDB()
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	prompt := "将生成新的加密密钥，并用新密钥改写配置数据库和主数据库中全部加密存储的值。\n" +
+		"使用旧密钥加密的备份和操作日志仍需旧密钥解密，请先保存旧密钥；运行中的服务仍持有旧密钥，必须先停止服务。"
+	if !*yes && !confirm(prompt, "RESET_KEY") {
+		return fail("确认信息不正确，已取消重置")
+	}
+
+	newKey, err := app.configSvc.ResetEncryptionKey(*length)
+	if err != nil {
+		return fail("重置密钥失败: %v", err)
+	}
+	logCLIAction("reset_encryption_key", "security", "", fmt.Sprintf("重置了%d位AES加密密钥", *length))
+
+	fmt.Printf("加密密钥已重置（%d 位）\n新密钥: %s\n请重启所有服务实例以加载新密钥\n", *length, newKey)
+	return 0
+}
+
+// maskSecret 隐藏敏感值
+func maskSecret(value string) string {
+	if value == "" {
+		return "（未设置）"
+	}
+	return "******"
+}
+
+// onOff 开关状态文本
+func onOff(enabled bool) string {
+	if enabled {
+		return "启用"
+	}
+	return "禁用"
+}
@@ -0,0 +1,203 @@
+// Package main 程序入口
+// cli_data.go - 备份、卡密与缓存命令
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"user-frontend/internal/cache"
+	"user-frontend/internal/config"
+	"user-frontend/internal/repository"
+	"user-frontend/internal/service"
+)
+
+// newCLIBackupService 创建备份服务并加载备份配置（压缩、加密与异地目标）
+func newCLIBackupService(app *appContext, repo *repository.Repository) *service.BackupService {
+	backupSvc := service.NewBackupService(repo, app.configDir)
+	if backupCfg, err := app.configSvc.GetBackupConfig(); err == nil {
+		backupSvc.SetBackupConfig(backupCfg)
+	}
+	return backupSvc
+}
+
+// cmdBackupCreate backup create 子命令
+func cmdBackupCreate(args []string) int {
+	fs := newFlagSet("backup create", "[-remark 备注] [-objects]")
+	remark := fs.String("remark", "命令行备份", "备注")
+	objects := fs.Bool("objects", false, "同时备份对象存储中的文件（商品图片、工单附件等）")
+	if _, code, ok := parseArgs(fs, args, 0); !ok {
+		return code
+	}
+
+	app, repo, err := openMainDB()
+	if err != nil {
+		return fail("%v", err)
+	}
+	if *objects {
+		initObjectStorage(app.configSvc)
+	}
+
+	backup, err := newCLIBackupService(app, repo).CreateBackup(&config.GlobalConfig.DBConfig, cliOperator(), *remark, *objects)
+	if err != nil {
+		return fail("创建备份失败: %v", err)
+	}
+	logCLIAction("create", "backup", strconv.Itoa(int(backup.ID)), backup.Filename)
+
+	fmt.Printf("备份已创建: ID %d，%s（%s）\n", backup.ID, backup.Filename, service.FormatFileSize(backup.FileSize))
+	return 0
+}
+
+// cmdBackupList backup list 子命令
+func cmdBackupList(args []string) int {
+	fs := newFlagSet("backup list", "")
+	if _, code, ok := parseArgs(fs, args, 0); !ok {
+		return code
+	}
+
+	app, repo, err := openMainDB()
+	if err != nil {
+		return fail("%v", err)
+	}
+	backups, err := newCLIBackupService(app, repo).GetAllBackups()
+	if err != nil {
+		return fail("获取备份列表失败: %v", err)
+	}
+	if len(backups) == 0 {
+		fmt.Println("暂无备份")
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t文件名\t类型\t数据库\t大小\t结构版本\t创建时间\t创建人\t备注")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", b.ID, b.Filename, b.Kind, b.DBType, service.FormatFileSize(b.FileSize),
+			b.SchemaVersion, b.CreatedAt.Local().Format("2006-01-02 15:04:05"), b.CreatedBy, b.Remark)
+	}
+	w.Flush()
+	return 0
+}
+
+// cmdBackupRestore backup restore 子命令
+func cmdBackupRestore(args []string) int {
+	fs := newFlagSet("backup restore", "[-force] [-objects] [-yes] <备份ID>")
+	force := fs.Bool("force", false, "强制恢复无法完整校验的旧备份或结构版本较旧的备份")
+	objects := fs.Bool("objects", false, "同时恢复备份中的对象存储文件")
+	yes := fs.Bool("yes", false, "跳过确认")
+	pos, code, ok := parseArgs(fs, args, 1)
+	if !ok {
+		return code
+	}
+	id, err := strconv.ParseUint(pos[0], 10, 32)
+	if err != nil {
+		return fail("无效的备份ID: %s", pos[0])
+	}
+
+	app, repo, err := openMainDB()
+	if err != nil {
+		return fail("%v", err)
+	}
+	backupSvc := newCLIBackupService(app, repo)
+	backup, err := backupSvc.GetBackupByID(uint(id))
+	if err != nil {
+		return fail("备份不存在")
+	}
+
+	prompt := fmt.Sprintf("将使用备份 %s 覆盖当前数据库（恢复前会自动创建快照）。\n"+
+		"命令行恢复不会让运行中的服务进入维护模式，建议先停止服务，恢复后再启动。", backup.Filename)
+	if !*yes && !confirm(prompt, backup.Filename) {
+		return fail("确认信息不正确，已取消恢复")
+	}
+	if *objects {
+		initObjectStorage(app.configSvc)
+	}
+
+	result, err := backupSvc.RestoreBackup(&config.GlobalConfig.DBConfig, uint(id), cliOperator(), service.RestoreOptions{
+		Force:          *force,
+		RestoreObjects: *objects,
+	})
+	if err != nil {
+		return fail("%v", err)
+	}
+	logCLIAction("restore", "backup", pos[0], fmt.Sprintf("%s（恢复前快照: %s）", backup.Filename, result.SnapshotFile))
+
+	fmt.Printf("数据库已恢复: %d 个表，%d 条记录，耗时 %s\n", result.Tables, result.Rows, result.Duration)
+	if result.Objects > 0 {
+		fmt.Printf("已恢复 %d 个存储文件\n", result.Objects)
+	}
+	fmt.Printf("恢复前快照: ID %d，%s\n", result.SnapshotID, result.SnapshotFile)
+	for _, w := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", w)
+	}
+	return 0
+}
+
+// cmdKamiImport kami import 子命令
+func cmdKamiImport(args []string) int {
+	fs := newFlagSet("kami import", "<商品ID> <文件>")
+	pos, code, ok := parseArgs(fs, args, 2)
+	if !ok {
+		return code
+	}
+	productID, err := strconv.ParseUint(pos[0], 10, 32)
+	if err != nil {
+		return fail("无效的商品ID: %s", pos[0])
+	}
+
+	var data []byte
+	if pos[1] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(pos[1])
+	}
+	if err != nil {
+		return fail("读取卡密文件失败: %v", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return fail("卡密文件为空")
+	}
+
+	_, repo, err := openMainDB()
+	if err != nil {
+		return fail("%v", err)
+	}
+	imported, duplicates, err := service.NewManualKamiService(repo).ImportKamiCodes(uint(productID), string(data))
+	if err != nil {
+		return fail("导入卡密失败: %v", err)
+	}
+	logCLIAction("import", "kami", pos[0], fmt.Sprintf("导入 %d 个，跳过重复 %d 个", imported, duplicates))
+
+	fmt.Printf("已导入 %d 个卡密，跳过重复 %d 个\n", imported, duplicates)
+	return 0
+}
+
+// cmdCacheFlush cache flush 子命令
+func cmdCacheFlush(args []string) int {
+	fs := newFlagSet("cache flush", "")
+	if _, code, ok := parseArgs(fs, args, 0); !ok {
+		return code
+	}
+
+	app := bootstrap()
+	redisCfg, err := app.configSvc.GetRedisConfig()
+	if err != nil || !redisCfg.Enabled {
+		// 本地内存缓存位于服务进程内，只能通过重启服务清空
+		return fail("未启用 Redis，缓存位于服务进程内存中，请重启服务以清空缓存")
+	}
+	initCacheSystem(app.configSvc)
+	cm := cache.GetCacheManager()
+	if cm == nil || !cm.IsRedisHealthy() {
+		return fail("无法连接 Redis")
+	}
+	if err := cm.FlushAll(); err != nil {
+		return fail("清空缓存失败: %v", err)
+	}
+	logCLIAction("flush", "cache", "", "命令行清空缓存")
+
+	fmt.Println("缓存已清空")
+	return 0
+}
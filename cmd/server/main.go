@@ -1,21 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"user-frontend/internal/api"
 	"user-frontend/internal/cache"
 	"user-frontend/internal/config"
+	"user-frontend/internal/lifecycle"
 	"user-frontend/internal/model"
 	"user-frontend/internal/service"
+	"user-frontend/internal/storage"
+	"user-frontend/internal/trace"
+	"user-frontend/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// appContext 服务器与命令行共用的启动环境
+type appContext struct {
+	execDir   string
+	configDir string
+	cfg       *config.Config
+	configSvc *service.ConfigService
+}
+
 func main() {
+	// 第一个参数不是选项时作为子命令执行，未指定子命令时启动服务器（兼容旧的启动方式）
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+	os.Exit(runServe(os.Args[1:]))
+}
+
+// bootstrap 加载全局配置、配置数据库和加密密钥，并将数据库连接配置加载到全局配置
+func bootstrap() *appContext {
 	// 获取可执行文件所在目录
 	execPath, err := os.Executable()
 	if err != nil {
@@ -32,6 +61,9 @@ func main() {
 		log.Printf("警告: 加载配置失败: %v，使用默认配置", err)
 	}
 
+	// 初始化运行环境配置（APP_ENV 及相关环境变量）
+	config.InitEnvironmentConfig()
+
 	// 初始化配置数据库（SQLite，存储数据库连接配置）
 	if err := model.InitConfigDB(configDir); err != nil {
 		log.Fatalf("初始化配置数据库失败: %v", err)
@@ -74,12 +106,38 @@ func main() {
 		log.Printf("已从配置数据库加载服务器端口: %d", serverPort)
 	}
 
+	return &appContext{execDir: execDir, configDir: configDir, cfg: cfg, configSvc: configSvc}
+}
+
+// runServe 启动 HTTP 服务器
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	migrateCmd := fs.String("migrate", "", "执行数据库迁移命令后退出（同 migrate 子命令）: up、status、down")
+	migrateSteps := fs.Int("steps", 1, "配合 -migrate down 使用，回滚的迁移数量")
+	fs.Parse(args)
+
+	app := bootstrap()
+	cfg, configDir, configSvc := app.cfg, app.configDir, app.configSvc
+
+	// 数据库迁移命令行
+	dbCfg := &config.GlobalConfig.DBConfig
+	if *migrateCmd != "" {
+		return runMigrateCommand(dbCfg, *migrateCmd, *migrateSteps)
+	}
+
+	// 初始化结构化日志和链路追踪（导出器最先注册，在其他后台任务之后关闭以导出剩余 Span）
+	initLogging()
+	initTracing()
+
 	// 设置数据库配置服务到API层
 	api.InitDBConfigService(configSvc)
 
 	// 尝试连接主数据库
-	dbCfg := &config.GlobalConfig.DBConfig
 	if err := model.InitDB(dbCfg); err != nil {
+		if errors.Is(err, model.ErrMigrationFailed) {
+			// 已连接但迁移失败时不能切换到默认数据库，否则会覆盖已保存的数据库配置
+			log.Fatalf("错误: %v（可使用 migrate status 子命令查看迁移状态）", err)
+		}
 		log.Printf("警告: 主数据库连接失败: %v", err)
 		
 		// 如果是首次启动或配置的数据库无法连接，使用本地SQLite作为默认主数据库
@@ -116,6 +174,9 @@ func main() {
 	// 初始化缓存系统
 	initCacheSystem(configSvc)
 
+	// 初始化对象存储
+	initObjectStorage(configSvc)
+
 	// 初始化服务
 	api.InitServices(cfg)
 
@@ -131,21 +192,77 @@ func main() {
 	// 注册路由
 	api.RegisterRoutes(r, cfg)
 
+	// 启动后台任务
+	lifecycle.Start()
+
 	// 启动服务器
 	addr := fmt.Sprintf(":%d", cfg.ServerConfig.Port)
 	log.Printf("服务器启动在 http://localhost%s", addr)
 	log.Printf("管理后台: http://localhost%s/%s", addr, cfg.ServerConfig.AdminSuffix)
 
-	if cfg.ServerConfig.UseHTTPS && cfg.ServerConfig.CertFile != "" && cfg.ServerConfig.KeyFile != "" {
-		log.Println("使用HTTPS模式")
-		if err := r.RunTLS(addr, cfg.ServerConfig.CertFile, cfg.ServerConfig.KeyFile); err != nil {
-			log.Fatal("启动服务器失败:", err)
+	srv := &http.Server{Addr: addr, Handler: r}
+	if err := serveUntilSignal(srv, cfg); err != nil {
+		log.Printf("服务器停止时出错: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// serveUntilSignal 运行 HTTP 服务器直到收到 SIGINT/SIGTERM，然后依次停止接收新请求、
+// 等待进行中的请求（如支付回调发货）完成、停止后台任务并关闭数据库连接
+func serveUntilSignal(srv *http.Server, cfg *config.Config) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if cfg.ServerConfig.UseHTTPS && cfg.ServerConfig.CertFile != "" && cfg.ServerConfig.KeyFile != "" {
+			log.Println("使用HTTPS模式")
+			err = srv.ListenAndServeTLS(cfg.ServerConfig.CertFile, cfg.ServerConfig.KeyFile)
+		} else {
+			err = srv.ListenAndServe()
 		}
-	} else {
-		if err := r.Run(addr); err != nil {
-			log.Fatal("启动服务器失败:", err)
+		serverErr <- err
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("启动服务器失败: %v", err)
+			lifecycle.Shutdown(context.Background())
+			model.CloseDB()
+			return err
 		}
+	case sig := <-quit:
+		log.Printf("收到信号 %s，开始停机...", sig)
+	}
+
+	timeout := 30 * time.Second
+	if config.GlobalEnvConfig != nil && config.GlobalEnvConfig.ShutdownTimeout > 0 {
+		timeout = time.Duration(config.GlobalEnvConfig.ShutdownTimeout) * time.Second
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	// 停止接收新连接，等待进行中的请求完成
+	if err := srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("HTTP 服务器停止失败: %w", err))
+	}
+	// 按注册的相反顺序停止后台任务（WebSocket 客户端收到重连通知）
+	if err := lifecycle.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	// 数据库最后关闭
+	if err := model.CloseDB(); err != nil {
+		errs = append(errs, fmt.Errorf("关闭数据库失败: %w", err))
+	}
+	if len(errs) == 0 {
+		log.Println("服务器已停止")
+	}
+	return errors.Join(errs...)
 }
 
 // initCacheSystem 初始化缓存系统
@@ -155,6 +272,7 @@ func initCacheSystem(configSvc *service.ConfigService) {
 	if err != nil {
 		log.Printf("警告: 加载Redis配置失败: %v, 将使用本地缓存", err)
 		cache.InitCacheManager(nil)
+		registerCacheWorker()
 		return
 	}
 
@@ -162,6 +280,7 @@ func initCacheSystem(configSvc *service.ConfigService) {
 	if !redisConfig.Enabled {
 		log.Println("Redis未启用，使用本地内存缓存")
 		cache.InitCacheManager(nil)
+		registerCacheWorker()
 		return
 	}
 
@@ -192,6 +311,7 @@ func initCacheSystem(configSvc *service.ConfigService) {
 		TLSCACert:        redisConfig.TLSCACert,
 	}
 	cache.InitCacheManager(cacheConfig)
+	registerCacheWorker()
 
 	// 验证连接状态
 	manager := cache.GetCacheManager()
@@ -203,3 +323,63 @@ func initCacheSystem(configSvc *service.ConfigService) {
 		}
 	}
 }
+
+// initLogging 按运行环境配置结构化日志（LOG_LEVEL、LOG_FORMAT、LOG_OUTPUT、LOG_FILE）
+func initLogging() {
+	env := config.GlobalEnvConfig
+	if env == nil {
+		return
+	}
+	logCfg := utils.DefaultLoggerConfig
+	logCfg.Level = utils.ParseLogLevel(env.LogLevel)
+	logCfg.Format = env.LogFormat
+	logCfg.Output = env.LogOutput
+	logCfg.FilePath = env.LogFile
+	logCfg.EnableColor = env.LogFormat != "json"
+	utils.SetLogger(utils.NewLogger(logCfg))
+}
+
+// initTracing 配置了 OTLP 采集器地址时启用 Span 导出
+func initTracing() {
+	env := config.GlobalEnvConfig
+	if env == nil || env.TracingEndpoint == "" {
+		return
+	}
+	exp := trace.NewExporter(env.TracingEndpoint, env.TracingServiceName)
+	trace.SetExporter(exp)
+	lifecycle.Register(lifecycle.Worker{Name: "otlp-exporter", Run: exp.Run})
+	log.Printf("链路追踪已启用，导出到: %s", env.TracingEndpoint)
+}
+
+// registerCacheWorker 注册缓存管理器的停止任务，最先注册因此在其他后台任务之后关闭
+func registerCacheWorker() {
+	lifecycle.Register(lifecycle.Worker{
+		Name: "cache",
+		Stop: func(ctx context.Context) error {
+			if manager := cache.GetCacheManager(); manager != nil {
+				return manager.Close()
+			}
+			return nil
+		},
+	})
+}
+
+// initObjectStorage 初始化对象存储（商品图片、工单附件等文件）
+func initObjectStorage(configSvc *service.ConfigService) {
+	storageConfig, err := configSvc.GetStorageConfig()
+	if err != nil {
+		log.Printf("警告: 加载存储配置失败: %v, 将使用本地存储", err)
+		storage.Init(nil)
+		return
+	}
+
+	if err := storage.Init(service.ToStorageConfig(storageConfig)); err != nil {
+		log.Printf("警告: 对象存储初始化失败: %v, 已降级到本地存储", err)
+		return
+	}
+	if err := storage.Default().Ping(); err != nil {
+		log.Printf("警告: 对象存储不可用: %v", err)
+		return
+	}
+	log.Printf("对象存储已就绪 (%s)", storage.Default().Driver())
+}
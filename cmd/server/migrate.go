@@ -0,0 +1,88 @@
+// Package main 程序入口
+// migrate.go - 数据库迁移命令行
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+)
+
+// cmdMigrate migrate 子命令
+func cmdMigrate(action string) func(args []string) int {
+	return func(args []string) int {
+		fs := newFlagSet("migrate "+action, "")
+		steps := fs.Int("steps", 1, "回滚的迁移数量")
+		if _, code, ok := parseArgs(fs, args, 0); !ok {
+			return code
+		}
+		bootstrap()
+		return runMigrateCommand(&config.GlobalConfig.DBConfig, action, *steps)
+	}
+}
+
+// runMigrateCommand 执行迁移命令（migrate 子命令或 serve -migrate），返回进程退出码
+func runMigrateCommand(dbCfg *config.DBConfig, cmd string, steps int) int {
+	db, err := model.OpenDB(dbCfg)
+	if err != nil {
+		log.Printf("连接数据库失败: %v", err)
+		return 1
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	switch cmd {
+	case "up":
+		count, err := model.MigrateUp(db)
+		if err != nil {
+			log.Printf("迁移失败: %v", err)
+			return 1
+		}
+		if count == 0 {
+			fmt.Printf("数据库结构已是最新版本（%d）\n", model.SchemaVersion)
+		} else {
+			fmt.Printf("已执行 %d 个迁移，当前版本 %d\n", count, model.SchemaVersion)
+		}
+	case "status":
+		list, err := model.GetMigrationStatus(db)
+		if err != nil {
+			log.Printf("获取迁移状态失败: %v", err)
+			return 1
+		}
+		fmt.Printf("数据库: %s %s\n", db.Dialector.Name(), dbCfg.Database)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "版本\t名称\t状态\t执行时间\t可回滚")
+		for _, m := range list {
+			status, appliedAt := "未执行", "-"
+			if m.Applied {
+				status = "已执行"
+				appliedAt = m.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			if m.Unknown {
+				status = "未知（程序中未定义）"
+			}
+			reversible := "否"
+			if m.Reversible {
+				reversible = "是"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", m.Version, m.Name, status, appliedAt, reversible)
+		}
+		w.Flush()
+	case "down":
+		count, err := model.MigrateDown(db, steps)
+		if err != nil {
+			log.Printf("回滚失败（已回滚 %d 个）: %v", count, err)
+			return 1
+		}
+		fmt.Printf("已回滚 %d 个迁移\n", count)
+	default:
+		log.Printf("未知的迁移命令: %s（可选 up、status、down）", cmd)
+		return 2
+	}
+	return 0
+}
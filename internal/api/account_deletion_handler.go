@@ -42,7 +42,7 @@ func RequestAccountDeletion(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "request_deletion", "account", strconv.Itoa(int(userID)), "申请账户注销", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "request_deletion", "account", strconv.Itoa(int(userID)), "申请账户注销", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -70,7 +70,7 @@ func CancelAccountDeletion(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "cancel_deletion", "account", strconv.Itoa(int(userID)), "取消账户注销申请", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "cancel_deletion", "account", strconv.Itoa(int(userID)), "取消账户注销申请", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "注销申请已取消"})
@@ -159,7 +159,7 @@ func AdminApproveDeletion(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "approve_deletion", "account_deletion", strconv.Itoa(int(requestID)), "批准账户注销申请", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "approve_deletion", "account_deletion", strconv.Itoa(int(requestID)), "批准账户注销申请", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "已批准注销申请，账户将在7天后删除"})
@@ -197,7 +197,7 @@ func AdminRejectDeletion(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "reject_deletion", "account_deletion", strconv.Itoa(int(requestID)), "拒绝账户注销申请: "+req.Reason, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "reject_deletion", "account_deletion", strconv.Itoa(int(requestID)), "拒绝账户注销申请: "+req.Reason, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "已拒绝注销申请"})
@@ -137,7 +137,7 @@ func AdminTestDBConnection(c *gin.Context) {
 	c.JSON(200, gin.H{"success": true, "message": "连接成功"})
 }
 
-// AdminResetEncryptionKey 重置加密密钥（危险操作）
+// AdminResetEncryptionKey 重置加密密钥并改写全部加密存储的值（危险操作）
 func AdminResetEncryptionKey(c *gin.Context) {
 	var req struct {
 		KeyLength   int    `json:"key_length"`
@@ -151,7 +151,7 @@ func AdminResetEncryptionKey(c *gin.Context) {
 	}
 
 	// 二级确认验证
-	if req.Confirm != "RESET_KEY" || req.ConfirmText != "我确认重置密钥并了解数据将永久丢失" {
+	if req.Confirm != "RESET_KEY" || req.ConfirmText != "我已保存旧密钥并确认重置" {
 		c.JSON(400, gin.H{"success": false, "error": "确认信息不正确，请仔细阅读警告信息"})
 		return
 	}
@@ -176,13 +176,13 @@ func AdminResetEncryptionKey(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple("system", "reset_encryption_key", "security", "",
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple("system", "reset_encryption_key", "security", "",
 			fmt.Sprintf("重置了%d位AES加密密钥", keyLength), c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
 		"success":        true,
-		"message":        "加密密钥已重置，之前使用旧密钥加密的数据将无法解密",
+		"message":        "加密密钥已重置，已加密的配置已改用新密钥；请立即重启所有服务实例以加载新密钥，旧备份和操作日志仍需旧密钥解密",
 		"encryption_key": newKey,
 		"key_length":     keyLength,
 	})
@@ -68,7 +68,7 @@ func AdminCreateAnnouncement(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "create", "announcement", strconv.Itoa(int(announcement.ID)), req.Title, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "create", "announcement", strconv.Itoa(int(announcement.ID)), req.Title, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "announcement": announcement})
@@ -117,7 +117,7 @@ func AdminUpdateAnnouncement(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "update", "announcement", idStr, req.Title, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update", "announcement", idStr, req.Title, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "announcement": announcement})
@@ -140,7 +140,7 @@ func AdminDeleteAnnouncement(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "delete", "announcement", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "announcement", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "公告已删除"})
@@ -190,7 +190,7 @@ func AdminCreateCategory(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "create", "category", strconv.Itoa(int(category.ID)), req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "create", "category", strconv.Itoa(int(category.ID)), req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "category": category})
@@ -226,7 +226,7 @@ func AdminUpdateCategory(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "update", "category", idStr, req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update", "category", idStr, req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "category": category})
@@ -249,7 +249,7 @@ func AdminDeleteCategory(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "delete", "category", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "category", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "分类已删除"})
@@ -258,7 +258,8 @@ func AdminDeleteCategory(c *gin.Context) {
 // ==================== 操作日志 ====================
 
 // AdminGetOperationLogs 获取操作日志
-// 支持按日期查询，日志从加密的CSV文件中读取
+// 默认查询数据库审计日志，支持按操作者、操作、分类、对象、请求ID和日期范围筛选；
+// source=file 时按日期读取加密的CSV日志文件（升级前的日志或启用了文件日志）
 func AdminGetOperationLogs(c *gin.Context) {
 	if LogSvc == nil {
 		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
@@ -267,12 +268,62 @@ func AdminGetOperationLogs(c *gin.Context) {
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
 	userType := c.Query("user_type")
 	action := c.Query("action")
 	category := c.Query("category")
 	date := c.Query("date") // 日期参数，格式: YYYY-MM-DD
 
-	logs, total, err := LogSvc.GetOperationLogs(date, page, pageSize, userType, action, category)
+	var logs []service.LogEntry
+	var total int64
+	var err error
+	if c.Query("source") == "file" {
+		logs, total, err = LogSvc.GetOperationLogs(date, page, pageSize, userType, action, category)
+	} else {
+		query := service.LogQuery{
+			UserType:  userType,
+			Username:  c.Query("username"),
+			Action:    action,
+			Category:  category,
+			Target:    c.Query("target"),
+			TargetID:  c.Query("target_id"),
+			RequestID: c.Query("request_id"),
+			Page:      page,
+			PageSize:  pageSize,
+		}
+		if userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32); userID > 0 {
+			query.UserID = uint(userID)
+		}
+
+		// date 查询单日，start_date/end_date 查询日期范围（均含当天）
+		startDate, endDate := c.Query("start_date"), c.Query("end_date")
+		if date != "" {
+			startDate, endDate = date, date
+		}
+		if startDate != "" {
+			t, perr := time.ParseInLocation("2006-01-02", startDate, time.Local)
+			if perr != nil {
+				c.JSON(400, gin.H{"success": false, "error": "开始日期格式错误"})
+				return
+			}
+			query.StartTime = t
+		}
+		if endDate != "" {
+			t, perr := time.ParseInLocation("2006-01-02", endDate, time.Local)
+			if perr != nil {
+				c.JSON(400, gin.H{"success": false, "error": "结束日期格式错误"})
+				return
+			}
+			query.EndTime = t.AddDate(0, 0, 1)
+		}
+
+		logs, total, err = LogSvc.SearchOperationLogs(query)
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": err.Error()})
 		return
@@ -290,7 +341,33 @@ func AdminGetOperationLogs(c *gin.Context) {
 	})
 }
 
-// AdminGetLogDates 获取可用的日志日期列表
+// AdminVerifyOperationLogs 校验操作日志哈希链，检查记录是否被修改或删除
+func AdminVerifyOperationLogs(c *gin.Context) {
+	if LogSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	result, err := LogSvc.VerifyAuditChain()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if !result.Valid {
+		adminUsername, _ := c.Get("admin_username")
+		username, _ := adminUsername.(string)
+		LogSvc.WithContext(c.Request.Context()).LogSecurityEvent("audit_chain_broken", c.ClientIP(), c.GetHeader("User-Agent"),
+			map[string]interface{}{"broken_id": result.BrokenID, "reason": result.Reason, "admin": username})
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"result":  result,
+	})
+}
+
+// AdminGetLogDates 获取CSV日志文件的日期列表（配合 source=file 查询）
 func AdminGetLogDates(c *gin.Context) {
 	if LogSvc == nil {
 		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
@@ -333,6 +410,7 @@ func AdminUpdateLogConfig(c *gin.Context) {
 	var req struct {
 		EnableUserLog  bool `json:"enable_user_log"`
 		EnableAdminLog bool `json:"enable_admin_log"`
+		EnableFileLog  bool `json:"enable_file_log"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
@@ -342,6 +420,7 @@ func AdminUpdateLogConfig(c *gin.Context) {
 	config := service.LogConfig{
 		EnableUserLog:  req.EnableUserLog,
 		EnableAdminLog: req.EnableAdminLog,
+		EnableFileLog:  req.EnableFileLog,
 	}
 
 	if err := LogSvc.UpdateLogConfig(config); err != nil {
@@ -465,7 +544,7 @@ func AdminRemoveFromBlacklist(c *gin.Context) {
 	if RemoveFromBlacklist(ip) {
 		// 记录操作日志
 		if LogSvc != nil {
-			LogSvc.LogAdminActionSimple("admin", "remove_blacklist", "blacklist", ip, "移除IP黑名单: "+ip, c.ClientIP(), c.GetHeader("User-Agent"))
+			LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple("admin", "remove_blacklist", "blacklist", ip, "移除IP黑名单: "+ip, c.ClientIP(), c.GetHeader("User-Agent"))
 		}
 		c.JSON(200, gin.H{"success": true, "message": "已从黑名单中移除"})
 	} else {
@@ -479,7 +558,7 @@ func AdminClearBlacklist(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple("admin", "clear_blacklist", "blacklist", "", "清空IP黑名单", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple("admin", "clear_blacklist", "blacklist", "", "清空IP黑名单", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -543,7 +622,7 @@ func AdminSaveWhitelist(c *gin.Context) {
 		} else {
 			detail += "（已禁用）"
 		}
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), action, "whitelist", "", detail, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), action, "whitelist", "", detail, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
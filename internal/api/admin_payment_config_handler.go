@@ -29,13 +29,15 @@ func AdminGetPaymentConfig(c *gin.Context) {
 				"has_private_key": paymentCfg.AlipayF2F.PrivateKey != "",
 				"has_public_key":  paymentCfg.AlipayF2F.PublicKey != "",
 				"notify_url":      paymentCfg.AlipayF2F.NotifyURL,
+				"gateway_url":     paymentCfg.AlipayF2F.GatewayURL,
 			},
 			"wechat_pay": gin.H{
-				"enabled":     paymentCfg.WechatPay.Enabled,
-				"app_id":      paymentCfg.WechatPay.AppID,
-				"mch_id":      paymentCfg.WechatPay.MchID,
-				"has_api_key": paymentCfg.WechatPay.APIKey != "",
-				"notify_url":  paymentCfg.WechatPay.NotifyURL,
+				"enabled":      paymentCfg.WechatPay.Enabled,
+				"app_id":       paymentCfg.WechatPay.AppID,
+				"mch_id":       paymentCfg.WechatPay.MchID,
+				"has_api_key":  paymentCfg.WechatPay.APIKey != "",
+				"notify_url":   paymentCfg.WechatPay.NotifyURL,
+				"api_base_url": paymentCfg.WechatPay.APIBaseURL,
 			},
 			"yi_pay": gin.H{
 				"enabled":    paymentCfg.YiPay.Enabled,
@@ -53,6 +55,7 @@ func AdminGetPaymentConfig(c *gin.Context) {
 				"currency":          paymentCfg.PayPal.Currency,
 				"return_url":        paymentCfg.PayPal.ReturnURL,
 				"cancel_url":        paymentCfg.PayPal.CancelURL,
+				"api_base_url":      paymentCfg.PayPal.APIBaseURL,
 			},
 			"stripe": gin.H{
 				"enabled":            paymentCfg.StripeEnabled,
@@ -60,6 +63,7 @@ func AdminGetPaymentConfig(c *gin.Context) {
 				"has_secret_key":     paymentCfg.StripeSecretKey != "",
 				"has_webhook_secret": paymentCfg.StripeWebhookSecret != "",
 				"currency":           paymentCfg.StripeCurrency,
+				"api_base_url":       paymentCfg.StripeAPIBaseURL,
 			},
 			"usdt": gin.H{
 				"enabled":            paymentCfg.USDTEnabled,
@@ -72,6 +76,11 @@ func AdminGetPaymentConfig(c *gin.Context) {
 				"exchange_rate":      paymentCfg.USDTExchangeRate,
 				"min_amount":         paymentCfg.USDTMinAmount,
 				"confirmations":      paymentCfg.USDTConfirmations,
+				"chain_api_type":     paymentCfg.USDTChainAPIType,
+				"chain_api_url":      paymentCfg.USDTChainAPIURL,
+				"has_chain_api_key":  paymentCfg.USDTChainAPIKey != "",
+				"contract_address":   paymentCfg.USDTContractAddress,
+				"api_base_url":       paymentCfg.USDTAPIBaseURL,
 			},
 		},
 	})
@@ -87,12 +96,14 @@ func AdminSavePaymentConfig(c *gin.Context) {
 		AlipayPrivateKey string `json:"alipay_private_key"`
 		AlipayPublicKey  string `json:"alipay_public_key"`
 		AlipayNotifyURL  string `json:"alipay_notify_url"`
+		AlipayGatewayURL string `json:"alipay_gateway_url"`
 		// 微信支付
-		WechatEnabled   bool   `json:"wechat_enabled"`
-		WechatAppID     string `json:"wechat_app_id"`
-		WechatMchID     string `json:"wechat_mch_id"`
-		WechatAPIKey    string `json:"wechat_api_key"`
-		WechatNotifyURL string `json:"wechat_notify_url"`
+		WechatEnabled    bool   `json:"wechat_enabled"`
+		WechatAppID      string `json:"wechat_app_id"`
+		WechatMchID      string `json:"wechat_mch_id"`
+		WechatAPIKey     string `json:"wechat_api_key"`
+		WechatNotifyURL  string `json:"wechat_notify_url"`
+		WechatAPIBaseURL string `json:"wechat_api_base_url"`
 		// 易支付
 		YiPayEnabled   bool   `json:"yipay_enabled"`
 		YiPayAPIURL    string `json:"yipay_api_url"`
@@ -108,12 +119,14 @@ func AdminSavePaymentConfig(c *gin.Context) {
 		PayPalCurrency     string `json:"paypal_currency"`
 		PayPalReturnURL    string `json:"paypal_return_url"`
 		PayPalCancelURL    string `json:"paypal_cancel_url"`
+		PayPalAPIBaseURL   string `json:"paypal_api_base_url"`
 		// Stripe 配置
 		StripeEnabled        bool   `json:"stripe_enabled"`
 		StripePublishableKey string `json:"stripe_publishable_key"`
 		StripeSecretKey      string `json:"stripe_secret_key"`
 		StripeWebhookSecret  string `json:"stripe_webhook_secret"`
 		StripeCurrency       string `json:"stripe_currency"`
+		StripeAPIBaseURL     string `json:"stripe_api_base_url"`
 		// USDT 配置
 		USDTEnabled       bool    `json:"usdt_enabled"`
 		USDTNetwork       string  `json:"usdt_network"`
@@ -125,6 +138,13 @@ func AdminSavePaymentConfig(c *gin.Context) {
 		USDTExchangeRate  float64 `json:"usdt_exchange_rate"`
 		USDTMinAmount     float64 `json:"usdt_min_amount"`
 		USDTConfirmations int     `json:"usdt_confirmations"`
+		// USDT 自托管链上收款
+		USDTChainAPIType    string `json:"usdt_chain_api_type"`
+		USDTChainAPIURL     string `json:"usdt_chain_api_url"`
+		USDTChainAPIKey     string `json:"usdt_chain_api_key"`
+		USDTContractAddress string `json:"usdt_contract_address"`
+		// 第三方网关API地址（为空使用提供商正式地址）
+		USDTAPIBaseURL string `json:"usdt_api_base_url"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -140,9 +160,10 @@ func AdminSavePaymentConfig(c *gin.Context) {
 			// 获取现有配置以保留密钥
 			existingCfg, _ := ConfigSvc.GetPaymentConfig()
 			alipayCfg := &config.AlipayF2FConfig{
-				Enabled:   req.AlipayEnabled,
-				AppID:     req.AlipayAppID,
-				NotifyURL: req.AlipayNotifyURL,
+				Enabled:    req.AlipayEnabled,
+				AppID:      req.AlipayAppID,
+				NotifyURL:  req.AlipayNotifyURL,
+				GatewayURL: req.AlipayGatewayURL,
 			}
 			if req.AlipayPrivateKey != "" {
 				alipayCfg.PrivateKey = req.AlipayPrivateKey
@@ -162,10 +183,11 @@ func AdminSavePaymentConfig(c *gin.Context) {
 		case "wechat_pay":
 			existingCfg, _ := ConfigSvc.GetPaymentConfig()
 			wechatCfg := &config.WechatPayConfig{
-				Enabled:   req.WechatEnabled,
-				AppID:     req.WechatAppID,
-				MchID:     req.WechatMchID,
-				NotifyURL: req.WechatNotifyURL,
+				Enabled:    req.WechatEnabled,
+				AppID:      req.WechatAppID,
+				MchID:      req.WechatMchID,
+				NotifyURL:  req.WechatNotifyURL,
+				APIBaseURL: req.WechatAPIBaseURL,
 			}
 			if req.WechatAPIKey != "" {
 				wechatCfg.APIKey = req.WechatAPIKey
@@ -199,12 +221,13 @@ func AdminSavePaymentConfig(c *gin.Context) {
 		case "paypal":
 			existingCfg, _ := ConfigSvc.GetPaymentConfig()
 			paypalCfg := &config.PayPalConfig{
-				Enabled:   req.PayPalEnabled,
-				ClientID:  req.PayPalClientID,
-				Sandbox:   req.PayPalSandbox,
-				Currency:  req.PayPalCurrency,
-				ReturnURL: req.PayPalReturnURL,
-				CancelURL: req.PayPalCancelURL,
+				Enabled:    req.PayPalEnabled,
+				ClientID:   req.PayPalClientID,
+				Sandbox:    req.PayPalSandbox,
+				Currency:   req.PayPalCurrency,
+				ReturnURL:  req.PayPalReturnURL,
+				CancelURL:  req.PayPalCancelURL,
+				APIBaseURL: req.PayPalAPIBaseURL,
 			}
 			if paypalCfg.Currency == "" {
 				paypalCfg.Currency = "USD"
@@ -243,6 +266,10 @@ func AdminSavePaymentConfig(c *gin.Context) {
 				config.GlobalConfig.PaymentConfig.StripeSecretKey,
 				config.GlobalConfig.PaymentConfig.StripeWebhookSecret,
 				config.GlobalConfig.PaymentConfig.StripeCurrency)
+			if saveErr == nil {
+				config.GlobalConfig.PaymentConfig.StripeAPIBaseURL = req.StripeAPIBaseURL
+				saveErr = ConfigSvc.SavePaymentAPIBaseURL("stripe", req.StripeAPIBaseURL)
+			}
 			// 重新初始化Stripe服务
 			if saveErr == nil {
 				InitStripeService(config.GlobalConfig)
@@ -273,6 +300,14 @@ func AdminSavePaymentConfig(c *gin.Context) {
 			config.GlobalConfig.PaymentConfig.USDTExchangeRate = req.USDTExchangeRate
 			config.GlobalConfig.PaymentConfig.USDTMinAmount = req.USDTMinAmount
 			config.GlobalConfig.PaymentConfig.USDTConfirmations = req.USDTConfirmations
+			config.GlobalConfig.PaymentConfig.USDTChainAPIType = req.USDTChainAPIType
+			config.GlobalConfig.PaymentConfig.USDTChainAPIURL = req.USDTChainAPIURL
+			config.GlobalConfig.PaymentConfig.USDTContractAddress = req.USDTContractAddress
+			if req.USDTChainAPIKey != "" {
+				config.GlobalConfig.PaymentConfig.USDTChainAPIKey = req.USDTChainAPIKey
+			} else if existingCfg != nil {
+				config.GlobalConfig.PaymentConfig.USDTChainAPIKey = existingCfg.USDTChainAPIKey
+			}
 			// 保存到数据库
 			saveErr = ConfigSvc.SaveUSDTConfig(
 				req.USDTEnabled, req.USDTNetwork, req.USDTWalletAddress, req.USDTAPIProvider,
@@ -280,6 +315,14 @@ func AdminSavePaymentConfig(c *gin.Context) {
 				config.GlobalConfig.PaymentConfig.USDTAPISecret,
 				config.GlobalConfig.PaymentConfig.USDTWebhookSecret,
 				req.USDTExchangeRate, req.USDTMinAmount, req.USDTConfirmations)
+			if saveErr == nil {
+				saveErr = ConfigSvc.SaveUSDTChainConfig(req.USDTChainAPIType, req.USDTChainAPIURL,
+					config.GlobalConfig.PaymentConfig.USDTChainAPIKey, req.USDTContractAddress)
+			}
+			if saveErr == nil {
+				config.GlobalConfig.PaymentConfig.USDTAPIBaseURL = req.USDTAPIBaseURL
+				saveErr = ConfigSvc.SavePaymentAPIBaseURL("usdt", req.USDTAPIBaseURL)
+			}
 			// 重新初始化USDT服务
 			if saveErr == nil {
 				InitUSDTService(config.GlobalConfig)
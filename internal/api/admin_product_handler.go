@@ -2,11 +2,12 @@ package api
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"strconv"
 	"time"
 
 	"user-frontend/internal/model"
+	"user-frontend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -58,6 +59,9 @@ func AdminCreateProduct(c *gin.Context) {
 		DurationUnit string  `json:"duration_unit"`
 		Stock        int     `json:"stock"`
 		ImageURL     string  `json:"image_url"`
+		// 续费定价
+		RenewalPrice    float64 `json:"renewal_price"`    // 续费单价（0表示按售价）
+		RenewalDiscount float64 `json:"renewal_discount"` // 续费折扣百分比
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -69,6 +73,11 @@ func AdminCreateProduct(c *gin.Context) {
 		req.DurationUnit = "天"
 	}
 
+	if req.RenewalPrice < 0 || req.RenewalDiscount < 0 || req.RenewalDiscount >= 100 {
+		c.JSON(400, gin.H{"success": false, "error": "续费价格或折扣设置无效"})
+		return
+	}
+
 	// 手动卡密类型初始库存为0，由导入的卡密数量决定
 	stock := 0
 
@@ -86,6 +95,9 @@ func AdminCreateProduct(c *gin.Context) {
 		Stock:        stock,
 		ImageURL:     req.ImageURL,
 		ProductType:  model.ProductTypeManual,
+
+		RenewalPrice:    req.RenewalPrice,
+		RenewalDiscount: req.RenewalDiscount,
 	}
 
 	if err := ProductSvc.CreateProductFull(product); err != nil {
@@ -124,6 +136,9 @@ func AdminUpdateProduct(c *gin.Context) {
 		Stock        int     `json:"stock"`
 		Status       int     `json:"status"`
 		ImageURL     string  `json:"image_url"`
+		// 续费定价
+		RenewalPrice    float64 `json:"renewal_price"`    // 续费单价（0表示按售价）
+		RenewalDiscount float64 `json:"renewal_discount"` // 续费折扣百分比
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -131,6 +146,11 @@ func AdminUpdateProduct(c *gin.Context) {
 		return
 	}
 
+	if req.RenewalPrice < 0 || req.RenewalDiscount < 0 || req.RenewalDiscount >= 100 {
+		c.JSON(400, gin.H{"success": false, "error": "续费价格或折扣设置无效"})
+		return
+	}
+
 	// 获取现有商品
 	existing, err := ProductSvc.GetProductByID(uint(id))
 	if err != nil {
@@ -159,6 +179,8 @@ func AdminUpdateProduct(c *gin.Context) {
 	existing.Stock = req.Stock
 	existing.Status = req.Status
 	existing.ImageURL = req.ImageURL
+	existing.RenewalPrice = req.RenewalPrice
+	existing.RenewalDiscount = req.RenewalDiscount
 
 	if err := ProductSvc.UpdateProductFull(existing); err != nil {
 		c.JSON(400, gin.H{"success": false, "error": err.Error()})
@@ -243,48 +265,40 @@ func AdminUploadProductImage(c *gin.Context) {
 		return
 	}
 
-	// 创建商品专属目录: Product/{product_id}/
-	productDir := fmt.Sprintf("Product/%d", product.ID)
-	if err := os.MkdirAll(productDir, 0755); err != nil {
-		c.JSON(500, gin.H{"success": false, "error": "创建目录失败"})
+	// 保存文件到对象存储，地址为 /product-files/{id}/image_xxx.jpg
+	// 图片会按真实类型校验、去除元数据并限制尺寸，文件名使用时间戳避免缓存问题
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "保存图片失败"})
 		return
 	}
-
-	// 生成文件名（使用时间戳避免缓存问题）
-	ext := ".jpg"
-	switch contentType {
-	case "image/png":
-		ext = ".png"
-	case "image/gif":
-		ext = ".gif"
-	case "image/webp":
-		ext = ".webp"
-	}
-	filename := fmt.Sprintf("image_%d%s", time.Now().Unix(), ext)
-	filepath := fmt.Sprintf("%s/%s", productDir, filename)
-
-	// 删除旧图片（如果存在）
-	if product.ImageURL != "" {
-		oldPath := "." + product.ImageURL // 转换为相对路径
-		os.Remove(oldPath)
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, 5*1024*1024+1))
+	if err != nil || len(data) > 5*1024*1024 {
+		c.JSON(400, gin.H{"success": false, "error": "图片大小不能超过5MB"})
+		return
 	}
-
-	// 保存文件
-	if err := c.SaveUploadedFile(file, filepath); err != nil {
-		c.JSON(500, gin.H{"success": false, "error": "保存图片失败"})
+	imageURL, err := service.SaveProductMainImage(product.ID, fmt.Sprintf("image_%d", time.Now().Unix()), contentType, data)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
 	// 更新数据库中的图片路径
-	imageURL := "/product-files/" + filepath[8:] // 存储为 /product-files/{id}/image_xxx.jpg，去掉 Product/ 前缀
+	oldImageURL := product.ImageURL
 	product.ImageURL = imageURL
 	if err := ProductSvc.UpdateProductImageURL(uint(id), imageURL); err != nil {
 		// 如果数据库更新失败，删除已上传的文件
-		os.Remove(filepath)
+		service.DeleteProductFile(imageURL)
 		c.JSON(500, gin.H{"success": false, "error": "更新商品信息失败"})
 		return
 	}
 
+	// 删除旧图片（如果存在）
+	if oldImageURL != "" && oldImageURL != imageURL {
+		service.DeleteProductFile(oldImageURL)
+	}
+
 	c.JSON(200, gin.H{
 		"success":   true,
 		"message":   "图片上传成功",
@@ -320,8 +334,7 @@ func AdminDeleteProductImage(c *gin.Context) {
 
 	// 删除图片文件
 	if product.ImageURL != "" {
-		oldPath := "." + product.ImageURL
-		os.Remove(oldPath)
+		service.DeleteProductFile(product.ImageURL)
 	}
 
 	// 清空数据库中的图片路径
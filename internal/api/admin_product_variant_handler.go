@@ -0,0 +1,160 @@
+package api
+
+import (
+	"strconv"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ==================== 商品多规格管理 API ====================
+
+// AdminGetProductVariants 获取商品的规格维度与全部规格
+func AdminGetProductVariants(c *gin.Context) {
+	if !model.DBConnected {
+		c.JSON(500, gin.H{"success": false, "error": "数据库未连接"})
+		return
+	}
+
+	if ProductSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	product, err := ProductSvc.GetProductByID(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{"success": false, "error": "商品不存在"})
+		return
+	}
+
+	variants, err := ProductSvc.GetVariants(product.ID, false)
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success":  true,
+		"options":  product.GetVariantOptions(),
+		"variants": variants,
+	})
+}
+
+// AdminSetVariantOptions 设置商品的规格维度（如 时长、版本、地区）
+func AdminSetVariantOptions(c *gin.Context) {
+	if !model.DBConnected {
+		c.JSON(500, gin.H{"success": false, "error": "数据库未连接"})
+		return
+	}
+
+	if ProductSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	var req struct {
+		Options []model.VariantOption `json:"options"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	product, err := ProductSvc.SetVariantOptions(uint(id), req.Options)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "options": product.GetVariantOptions()})
+}
+
+// AdminCreateProductVariant 创建规格（规格的卡密通过 /product/{规格ID}/kami/import 导入）
+func AdminCreateProductVariant(c *gin.Context) {
+	if !model.DBConnected {
+		c.JSON(500, gin.H{"success": false, "error": "数据库未连接"})
+		return
+	}
+
+	if ProductSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	var req service.VariantInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	variant, err := ProductSvc.CreateVariant(uint(id), req)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "variant": variant})
+}
+
+// AdminUpdateProductVariant 更新规格
+func AdminUpdateProductVariant(c *gin.Context) {
+	if !model.DBConnected {
+		c.JSON(500, gin.H{"success": false, "error": "数据库未连接"})
+		return
+	}
+
+	if ProductSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	var req service.VariantInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	variant, err := ProductSvc.UpdateVariant(uint(id), req)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "variant": variant})
+}
+
+// AdminDeleteProductVariant 删除规格
+func AdminDeleteProductVariant(c *gin.Context) {
+	if !model.DBConnected {
+		c.JSON(500, gin.H{"success": false, "error": "数据库未连接"})
+		return
+	}
+
+	if ProductSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	variant, err := ProductSvc.GetProductByID(uint(id))
+	if err != nil || !variant.IsVariant() {
+		c.JSON(404, gin.H{"success": false, "error": "规格不存在"})
+		return
+	}
+
+	if err := ProductSvc.DeleteProduct(variant.ID); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "规格已删除"})
+}
@@ -70,3 +70,40 @@ func AdminUpdateUserStatus(c *gin.Context) {
 
 	c.JSON(200, gin.H{"success": true, "message": "状态已更新"})
 }
+
+// AdminUpdateUserLevel 更新用户等级
+// PUT /api/admin/user/:id/level
+func AdminUpdateUserLevel(c *gin.Context) {
+	if !model.DBConnected {
+		c.JSON(500, gin.H{"success": false, "error": "数据库未连接"})
+		return
+	}
+
+	if UserSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 32)
+
+	var req struct {
+		Level string `json:"level" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	if err := UserSvc.UpdateUserLevel(uint(id), req.Level); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update_level", "user", idStr, req.Level, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "用户等级已更新"})
+}
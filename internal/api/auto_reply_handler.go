@@ -110,7 +110,7 @@ func AdminSaveAutoReplyConfig(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "更新智能客服配置", "auto_reply_config", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "更新智能客服配置", "auto_reply_config", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -191,7 +191,7 @@ func AdminCreateAutoReplyRule(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "创建自动回复规则", "auto_reply_rule", "", req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "创建自动回复规则", "auto_reply_rule", "", req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -254,7 +254,7 @@ func AdminUpdateAutoReplyRule(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "更新自动回复规则", "auto_reply_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "更新自动回复规则", "auto_reply_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -282,7 +282,7 @@ func AdminDeleteAutoReplyRule(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "删除自动回复规则", "auto_reply_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "删除自动回复规则", "auto_reply_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -0,0 +1,168 @@
+// Package api 提供 HTTP API 处理器
+// backup_config_handler.go - 备份加密、定时备份、保留策略与异地目标配置
+package api
+
+import (
+	"strconv"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminGetBackupConfig 获取备份配置（口令和目标密钥不返回明文）
+// GET /api/admin/backup/config
+func AdminGetBackupConfig(c *gin.Context) {
+	if DBConfigSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "配置服务未初始化"})
+		return
+	}
+
+	cfg, err := DBConfigSvc.GetBackupConfig()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取备份配置失败: " + err.Error()})
+		return
+	}
+
+	targets := make([]gin.H, 0, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		targets = append(targets, gin.H{
+			"name":                 t.Name,
+			"type":                 t.Type,
+			"enabled":              t.Enabled,
+			"path":                 t.Path,
+			"endpoint":             t.Endpoint,
+			"region":               t.Region,
+			"bucket":               t.Bucket,
+			"access_key":           t.AccessKey,
+			"has_secret_key":       t.SecretKey != "",
+			"path_style":           t.PathStyle,
+			"prefix":               t.Prefix,
+			"host":                 t.Host,
+			"port":                 t.Port,
+			"user":                 t.User,
+			"has_password":         t.Password != "",
+			"has_private_key":      t.PrivateKey != "",
+			"host_key_fingerprint": t.HostKeyFingerprint,
+			"timeout":              t.Timeout,
+		})
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"config": gin.H{
+			"compress":         cfg.Compress,
+			"encrypt":          cfg.Encrypt,
+			"encryption_mode":  cfg.EncryptionMode,
+			"has_passphrase":   cfg.Passphrase != "",
+			"schedule_enabled": cfg.ScheduleEnabled,
+			"interval_hours":   cfg.IntervalHours,
+			"include_objects":  cfg.IncludeObjects,
+			"keep_last":        cfg.KeepLast,
+			"keep_daily":       cfg.KeepDaily,
+			"keep_weekly":      cfg.KeepWeekly,
+			"keep_monthly":     cfg.KeepMonthly,
+			"targets":          targets,
+		},
+	})
+}
+
+// AdminSaveBackupConfig 保存备份配置
+// PUT /api/admin/backup/config
+// 口令、目标密钥留空时沿用已保存的值；保存后立即生效
+func AdminSaveBackupConfig(c *gin.Context) {
+	if DBConfigSvc == nil || BackupSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req config.BackupConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误: " + err.Error()})
+		return
+	}
+
+	if err := DBConfigSvc.SaveBackupConfig(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	cfg, err := DBConfigSvc.GetBackupConfig()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "备份配置已保存，但重新加载失败: " + err.Error()})
+		return
+	}
+	BackupSvc.SetBackupConfig(cfg)
+
+	if LogSvc != nil {
+		detail := "encrypt=" + strconv.FormatBool(cfg.Encrypt) + " mode=" + cfg.EncryptionMode + " targets=" + strconv.Itoa(len(cfg.Targets))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update", "backup_config", "", detail, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "备份配置保存成功"})
+}
+
+// AdminTestBackupTarget 测试异地备份目标连通性
+// POST /api/admin/backup/target/test
+// 密钥留空时使用已保存的同名目标的密钥；SFTP 目标返回服务器主机公钥指纹
+func AdminTestBackupTarget(c *gin.Context) {
+	if DBConfigSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "配置服务未初始化"})
+		return
+	}
+
+	var target config.BackupTarget
+	if err := c.ShouldBindJSON(&target); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误: " + err.Error()})
+		return
+	}
+
+	if saved, err := DBConfigSvc.GetBackupConfig(); err == nil {
+		for _, t := range saved.Targets {
+			if t.Name != target.Name {
+				continue
+			}
+			if target.SecretKey == "" {
+				target.SecretKey = t.SecretKey
+			}
+			if target.Password == "" {
+				target.Password = t.Password
+			}
+			if target.PrivateKey == "" {
+				target.PrivateKey = t.PrivateKey
+			}
+		}
+	}
+
+	fingerprint, err := service.TestBackupTarget(&target)
+	if err != nil {
+		c.JSON(200, gin.H{"success": false, "error": "连接失败: " + err.Error(), "fingerprint": fingerprint})
+		return
+	}
+	c.JSON(200, gin.H{"success": true, "message": "连接成功", "fingerprint": fingerprint})
+}
+
+// AdminUploadBackup 将备份上传到所有启用的异地目标
+// POST /api/admin/backup/:id/upload
+func AdminUploadBackup(c *gin.Context) {
+	if BackupSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 32)
+
+	uploads, err := BackupSvc.UploadBackupByID(uint(id))
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "upload", "backup", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "uploads": uploads})
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/model"
 	"user-frontend/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -27,27 +29,48 @@ func AdminGetBackups(c *gin.Context) {
 
 	// 格式化文件大小
 	type BackupInfo struct {
-		ID           uint   `json:"id"`
-		Filename     string `json:"filename"`
-		FileSizeText string `json:"file_size_text"`
-		FileSize     int64  `json:"file_size"`
-		DBType       string `json:"db_type"`
-		Remark       string `json:"remark"`
-		CreatedBy    string `json:"created_by"`
-		CreatedAt    string `json:"created_at"`
+		ID             uint   `json:"id"`
+		Filename       string `json:"filename"`
+		FileSizeText   string `json:"file_size_text"`
+		FileSize       int64  `json:"file_size"`
+		DBType         string `json:"db_type"`
+		IncludeObjects bool   `json:"include_objects"`
+		Kind           string `json:"kind"`
+		Compressed     bool   `json:"compressed"`
+		Encrypted      bool   `json:"encrypted"`
+		Checksum       string `json:"checksum"`
+		SchemaVersion  int    `json:"schema_version"`
+		Remark         string `json:"remark"`
+		CreatedBy      string `json:"created_by"`
+		CreatedAt      string `json:"created_at"`
+
+		Uploads []model.BackupUpload `json:"uploads"`
 	}
 
+	ids := make([]uint, 0, len(backups))
+	for _, b := range backups {
+		ids = append(ids, b.ID)
+	}
+	uploads := BackupSvc.GetBackupUploads(ids)
+
 	var backupInfos []BackupInfo
 	for _, b := range backups {
 		backupInfos = append(backupInfos, BackupInfo{
-			ID:           b.ID,
-			Filename:     b.Filename,
-			FileSizeText: service.FormatFileSize(b.FileSize),
-			FileSize:     b.FileSize,
-			DBType:       b.DBType,
-			Remark:       b.Remark,
-			CreatedBy:    b.CreatedBy,
-			CreatedAt:    b.CreatedAt.Format("2006-01-02 15:04:05"),
+			ID:             b.ID,
+			Filename:       b.Filename,
+			FileSizeText:   service.FormatFileSize(b.FileSize),
+			FileSize:       b.FileSize,
+			DBType:         b.DBType,
+			IncludeObjects: b.IncludeObjects,
+			Kind:           b.Kind,
+			Compressed:     b.Compressed,
+			Encrypted:      b.Encrypted,
+			Uploads:        uploads[b.ID],
+			Checksum:       b.Checksum,
+			SchemaVersion:  b.SchemaVersion,
+			Remark:         b.Remark,
+			CreatedBy:      b.CreatedBy,
+			CreatedAt:      b.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 
@@ -62,7 +85,8 @@ func AdminCreateBackup(c *gin.Context) {
 	}
 
 	var req struct {
-		Remark string `json:"remark"`
+		Remark         string `json:"remark"`
+		IncludeObjects bool   `json:"include_objects"` // 是否包含对象存储文件
 	}
 	c.ShouldBindJSON(&req)
 
@@ -78,7 +102,7 @@ func AdminCreateBackup(c *gin.Context) {
 		adminUsername = "admin"
 	}
 
-	backup, err := BackupSvc.CreateBackup(dbConfig, adminUsername, req.Remark)
+	backup, err := BackupSvc.CreateBackup(dbConfig, adminUsername, req.Remark, req.IncludeObjects)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": err.Error()})
 		return
@@ -86,20 +110,26 @@ func AdminCreateBackup(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "create", "backup", strconv.Itoa(int(backup.ID)), backup.Filename, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "create", "backup", strconv.Itoa(int(backup.ID)), backup.Filename, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
 		"success": true,
 		"backup": gin.H{
-			"id":             backup.ID,
-			"filename":       backup.Filename,
-			"file_size_text": service.FormatFileSize(backup.FileSize),
-			"file_size":      backup.FileSize,
-			"db_type":        backup.DBType,
-			"remark":         backup.Remark,
-			"created_by":     backup.CreatedBy,
-			"created_at":     backup.CreatedAt.Format("2006-01-02 15:04:05"),
+			"id":              backup.ID,
+			"filename":        backup.Filename,
+			"file_size_text":  service.FormatFileSize(backup.FileSize),
+			"file_size":       backup.FileSize,
+			"db_type":         backup.DBType,
+			"include_objects": backup.IncludeObjects,
+			"kind":            backup.Kind,
+			"compressed":      backup.Compressed,
+			"encrypted":       backup.Encrypted,
+			"checksum":        backup.Checksum,
+			"schema_version":  backup.SchemaVersion,
+			"remark":          backup.Remark,
+			"created_by":      backup.CreatedBy,
+			"created_at":      backup.CreatedAt.Format("2006-01-02 15:04:05"),
 		},
 		"message": "备份创建成功",
 	})
@@ -123,7 +153,7 @@ func AdminDownloadBackup(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "download", "backup", idStr, filename, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "download", "backup", idStr, filename, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.Header("Content-Description", "File Transfer")
@@ -149,7 +179,7 @@ func AdminDeleteBackup(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "delete", "backup", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "backup", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "备份已删除"})
@@ -173,3 +203,203 @@ func AdminGetBackupInfo(c *gin.Context) {
 		"db_connected": true,
 	})
 }
+
+// AdminVerifyBackup 校验备份能否恢复（校验和、数据库类型、结构版本）
+func AdminVerifyBackup(c *gin.Context) {
+	if BackupSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	result, err := BackupSvc.VerifyBackup(uint(id), config.GlobalConfig.DBConfig.Type)
+	if err != nil {
+		c.JSON(404, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "verification": result})
+}
+
+// AdminRestoreBackup 从备份恢复数据库（危险操作）
+// 恢复前自动创建当前数据库快照，恢复期间系统进入维护模式
+func AdminRestoreBackup(c *gin.Context) {
+	if BackupSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 32)
+
+	var req struct {
+		Confirm        string `json:"confirm" binding:"required"` // 需填写备份文件名
+		Force          bool   `json:"force"`                      // 强制恢复无法完整校验的备份
+		RestoreObjects bool   `json:"restore_objects"`            // 同时恢复存储文件
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	backup, err := BackupSvc.GetBackupByID(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{"success": false, "error": "备份不存在"})
+		return
+	}
+	if req.Confirm != backup.Filename {
+		c.JSON(400, gin.H{"success": false, "error": "确认信息不正确，请输入要恢复的备份文件名"})
+		return
+	}
+
+	dbConfig := &config.GlobalConfig.DBConfig
+	if dbConfig.Type == "" {
+		c.JSON(500, gin.H{"success": false, "error": "数据库配置不存在"})
+		return
+	}
+
+	adminUsername := c.GetString("admin_username")
+	if adminUsername == "" {
+		adminUsername = "admin"
+	}
+
+	result, err := BackupSvc.RestoreBackup(dbConfig, uint(id), adminUsername, service.RestoreOptions{
+		Force:          req.Force,
+		RestoreObjects: req.RestoreObjects,
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	// 记录操作日志
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "restore", "backup", idStr,
+			fmt.Sprintf("%s（恢复前快照: %s）", backup.Filename, result.SnapshotFile), c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "result": result, "message": "数据库已恢复"})
+}
+
+// AdminExportData 导出全部业务数据（可移植的 JSON Lines 文件，可导入任意类型数据库）
+// POST /api/admin/backup/export
+func AdminExportData(c *gin.Context) {
+	if BackupSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	adminUsername := c.GetString("admin_username")
+	if adminUsername == "" {
+		adminUsername = "admin"
+	}
+
+	backup, err := BackupSvc.ExportDataAsJSON(adminUsername)
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "export", "backup", strconv.Itoa(int(backup.ID)), backup.Filename, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"backup": gin.H{
+			"id":             backup.ID,
+			"filename":       backup.Filename,
+			"file_size_text": service.FormatFileSize(backup.FileSize),
+			"file_size":      backup.FileSize,
+			"db_type":        backup.DBType,
+			"checksum":       backup.Checksum,
+		},
+		"message": "数据导出成功",
+	})
+}
+
+// AdminUploadExport 上传数据导出文件（用于将其他服务器导出的数据导入本机）
+// POST /api/admin/backup/upload
+func AdminUploadExport(c *gin.Context) {
+	if BackupSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "请选择要上传的文件"})
+		return
+	}
+	defer file.Close()
+
+	adminUsername := c.GetString("admin_username")
+	backup, err := BackupSvc.SaveUploadedExport(header.Filename, file, adminUsername)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "upload", "backup", strconv.Itoa(int(backup.ID)), backup.Filename, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "backup": backup, "message": "上传成功"})
+}
+
+// AdminImportData 将数据导出文件导入当前数据库（危险操作）
+// POST /api/admin/backup/:id/import
+// 导出文件中包含的表将被整体替换，导入前自动创建当前数据库快照
+func AdminImportData(c *gin.Context) {
+	if BackupSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, _ := strconv.ParseUint(idStr, 10, 32)
+
+	var req struct {
+		Confirm string `json:"confirm" binding:"required"` // 需填写导出文件名
+		Force   bool   `json:"force"`                      // 结构版本较旧时仍然导入
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	backup, err := BackupSvc.GetBackupByID(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{"success": false, "error": "备份不存在"})
+		return
+	}
+	if req.Confirm != backup.Filename {
+		c.JSON(400, gin.H{"success": false, "error": "确认信息不正确，请输入要导入的文件名"})
+		return
+	}
+
+	dbConfig := &config.GlobalConfig.DBConfig
+	if dbConfig.Type == "" {
+		c.JSON(500, gin.H{"success": false, "error": "数据库配置不存在"})
+		return
+	}
+
+	adminUsername := c.GetString("admin_username")
+	if adminUsername == "" {
+		adminUsername = "admin"
+	}
+
+	result, err := BackupSvc.ImportData(dbConfig, uint(id), adminUsername, service.ImportOptions{Force: req.Force})
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "import", "backup", idStr,
+			fmt.Sprintf("%s（导入前快照: %s）", backup.Filename, result.SnapshotFile), c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "result": result, "message": "数据已导入"})
+}
@@ -103,7 +103,7 @@ func AdminHandleBalanceAlert(c *gin.Context) {
 		if req.Status == model.AlertStatusIgnored {
 			action = "忽略余额告警"
 		}
-		LogSvc.LogAdminActionSimple(adminUsername.(string), action, "balance_alert", strconv.FormatUint(id, 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), action, "balance_alert", strconv.FormatUint(id, 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "处理成功"})
@@ -141,7 +141,7 @@ func AdminBatchCheckBalanceMismatch(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "批量检查余额一致性", "balance_alert", "", map[string]int{"alert_count": count}, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "批量检查余额一致性", "balance_alert", "", map[string]int{"alert_count": count}, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -178,7 +178,7 @@ func AdminCleanOldAlerts(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "清理旧余额告警", "balance_alert", "", map[string]interface{}{"days": req.Days, "deleted_count": count}, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "清理旧余额告警", "balance_alert", "", map[string]interface{}{"days": req.Days, "deleted_count": count}, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
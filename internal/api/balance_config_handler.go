@@ -45,7 +45,7 @@ func AdminSaveBalanceConfig(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "修改余额配置", "balance_config", "", req, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "修改余额配置", "balance_config", "", req, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "保存成功"})
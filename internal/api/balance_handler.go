@@ -348,7 +348,7 @@ func AdminAdjustBalance(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil && adminUsername != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "调整余额", "balance", strconv.FormatUint(uint64(req.UserID), 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "调整余额", "balance", strconv.FormatUint(uint64(req.UserID), 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "调整成功"})
@@ -391,7 +391,7 @@ func AdminGiftBalance(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil && adminUsername != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "赠送余额", "balance", strconv.FormatUint(uint64(req.UserID), 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "赠送余额", "balance", strconv.FormatUint(uint64(req.UserID), 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "赠送成功"})
@@ -473,6 +473,12 @@ func PayOrderWithBalance(c *gin.Context) {
 		return
 	}
 
+	// 校验支付方式并锁定手续费，余额以基础货币计价
+	order, ok := lockOrderPaymentMethod(c, order, "balance")
+	if !ok {
+		return
+	}
+
 	// 获取用户余额
 	balance, err := BalanceSvc.GetUserBalance(userID.(uint))
 	if err != nil {
@@ -501,7 +507,7 @@ func PayOrderWithBalance(c *gin.Context) {
 	}
 
 	// 步骤2：处理订单支付
-	_, err = OrderSvc.ProcessPayment(order.OrderNo, "balance", "BAL"+order.OrderNo)
+	_, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(order.OrderNo, "balance", "BAL"+order.OrderNo)
 	if err != nil {
 		// 订单处理失败，解冻余额
 		unfreezeErr := BalanceSvc.Unfreeze(userID.(uint), order.Price, order.OrderNo, "支付失败解冻", operator)
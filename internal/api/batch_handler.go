@@ -30,17 +30,18 @@ func AdminBatchDeleteProducts(c *gin.Context) {
 		return
 	}
 
-	// 批量删除
-	result := model.DB.Where("id IN ?", req.IDs).Delete(&model.Product{})
+	// 批量删除（多规格父商品的规格一并删除）
+	result := model.DB.Where("id IN ? OR parent_id IN ?", req.IDs, req.IDs).Delete(&model.Product{})
 	if result.Error != nil {
 		c.JSON(500, gin.H{"success": false, "error": "删除失败"})
 		return
 	}
+	ProductSvc.InvalidateProducts(req.IDs)
 
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "batch_delete_products", "product", "", "批量删除商品: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "batch_delete_products", "product", "", "批量删除商品: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -73,6 +74,9 @@ func AdminBatchUpdateProductStatus(c *gin.Context) {
 		c.JSON(500, gin.H{"success": false, "error": "更新失败"})
 		return
 	}
+	if ProductSvc != nil {
+		ProductSvc.InvalidateProducts(req.IDs)
+	}
 
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
@@ -81,7 +85,7 @@ func AdminBatchUpdateProductStatus(c *gin.Context) {
 		action = "batch_enable_products"
 	}
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, action, "product", "", "批量更新商品状态: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, action, "product", "", "批量更新商品状态: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -118,7 +122,7 @@ func AdminBatchDeleteUsers(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "batch_delete_users", "user", "", "批量删除用户: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "batch_delete_users", "user", "", "批量删除用户: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -159,7 +163,7 @@ func AdminBatchUpdateUserStatus(c *gin.Context) {
 		action = "batch_enable_users"
 	}
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, action, "user", "", "批量更新用户状态: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, action, "user", "", "批量更新用户状态: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -196,7 +200,7 @@ func AdminBatchDeleteOrders(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "batch_delete_orders", "order", "", "批量删除订单: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "batch_delete_orders", "order", "", "批量删除订单: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -233,7 +237,7 @@ func AdminBatchDeleteCoupons(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "batch_delete_coupons", "coupon", "", "批量删除优惠券: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "batch_delete_coupons", "coupon", "", "批量删除优惠券: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -274,7 +278,7 @@ func AdminBatchUpdateCouponStatus(c *gin.Context) {
 		action = "batch_enable_coupons"
 	}
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, action, "coupon", "", "批量更新优惠券状态: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, action, "coupon", "", "批量更新优惠券状态: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -311,7 +315,7 @@ func AdminBatchDeleteAnnouncements(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "batch_delete_announcements", "announcement", "", "批量删除公告: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "batch_delete_announcements", "announcement", "", "批量删除公告: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
@@ -356,7 +360,7 @@ func AdminBatchDeleteCategories(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "batch_delete_categories", "category", "", "批量删除分类: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "batch_delete_categories", "category", "", "批量删除分类: "+strconv.Itoa(len(req.IDs))+"个", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{
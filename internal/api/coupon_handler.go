@@ -83,7 +83,7 @@ func AdminCreateCoupon(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "create", "coupon", strconv.Itoa(int(coupon.ID)), req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "create", "coupon", strconv.Itoa(int(coupon.ID)), req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "coupon": coupon})
@@ -143,7 +143,7 @@ func AdminUpdateCoupon(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "update", "coupon", idStr, req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update", "coupon", idStr, req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "coupon": coupon})
@@ -166,7 +166,7 @@ func AdminDeleteCoupon(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(c.GetString("admin_username"), "delete", "coupon", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "coupon", idStr, "", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "优惠券已删除"})
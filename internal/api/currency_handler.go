@@ -0,0 +1,300 @@
+// Package api 提供 HTTP API 处理器
+// currency_handler.go - 多币种定价与汇率 API
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ==================== 公开 API ====================
+
+// GetCurrencies 获取可用币种及汇率
+// GET /api/currencies
+func GetCurrencies(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	rates, err := CurrencySvc.GetRates()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取汇率失败"})
+		return
+	}
+
+	rateMap := make(map[string]float64, len(rates))
+	for _, r := range rates {
+		rateMap[r.Currency] = r.Rate
+	}
+
+	c.JSON(200, gin.H{
+		"success":    true,
+		"base":       CurrencySvc.GetBaseCurrency(),
+		"currencies": CurrencySvc.GetSupportedCurrencies(),
+		"rates":      rateMap,
+	})
+}
+
+// GetProductPriceInCurrency 获取商品在指定币种下的价格
+// GET /api/product/:id/price?currency=USD
+func GetProductPriceInCurrency(c *gin.Context) {
+	if CurrencySvc == nil || ProductSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
+	product, err := ProductSvc.GetProductByID(uint(id))
+	if err != nil {
+		c.JSON(404, gin.H{"success": false, "error": "商品不存在"})
+		return
+	}
+
+	currency := c.DefaultQuery("currency", CurrencySvc.GetBaseCurrency())
+	price, err := CurrencySvc.GetProductPriceIn(product, currency)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success":    true,
+		"product_id": product.ID,
+		"currency":   currency,
+		"price":      price,
+		"base_price": product.Price,
+		"base":       CurrencySvc.GetBaseCurrency(),
+	})
+}
+
+// ==================== 管理员 API ====================
+
+// AdminGetCurrencyConfig 获取基础货币与汇率列表
+// GET /api/admin/currency
+func AdminGetCurrencyConfig(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	rates, err := CurrencySvc.GetRates()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取汇率失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success":   true,
+		"base":      CurrencySvc.GetBaseCurrency(),
+		"rates":     rates,
+		"rate_file": CurrencySvc.GetRateFilePath(),
+	})
+}
+
+// AdminSetBaseCurrency 设置店铺基础货币
+// POST /api/admin/currency/base
+func AdminSetBaseCurrency(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req struct {
+		Currency string `json:"currency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	if err := CurrencySvc.SetBaseCurrency(req.Currency); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update", "currency", "base", req.Currency, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "基础货币已更新，请同步检查汇率表"})
+}
+
+// AdminSaveExchangeRate 新增或更新汇率
+// POST /api/admin/currency/rate
+func AdminSaveExchangeRate(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req struct {
+		Currency string  `json:"currency" binding:"required"`
+		Rate     float64 `json:"rate" binding:"required"`
+		Remark   string  `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	adminUsername := c.GetString("admin_username")
+	rate, err := CurrencySvc.SaveRate(req.Currency, req.Rate, "", req.Remark, adminUsername)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "update", "exchange_rate", rate.Currency, strconv.FormatFloat(rate.Rate, 'f', -1, 64), c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": rate})
+}
+
+// AdminDeleteExchangeRate 删除汇率
+// DELETE /api/admin/currency/rate/:currency
+func AdminDeleteExchangeRate(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	currency := c.Param("currency")
+	if err := CurrencySvc.DeleteRate(currency); err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "删除失败"})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "exchange_rate", currency, "", c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
+}
+
+// AdminImportExchangeRates 从本地汇率文件导入汇率
+// POST /api/admin/currency/import
+func AdminImportExchangeRates(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	count, err := CurrencySvc.LoadRatesFromFile("", c.GetString("admin_username"))
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "导入成功", "count": count})
+}
+
+// AdminGetProductPrices 获取商品的多币种价格
+// GET /api/admin/product/:id/prices
+func AdminGetProductPrices(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
+	prices, err := CurrencySvc.GetProductPrices(uint(id))
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取价格失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": prices, "base": CurrencySvc.GetBaseCurrency()})
+}
+
+// AdminSaveProductPrice 设置商品在某币种下的固定价格
+// POST /api/admin/product/:id/price
+func AdminSaveProductPrice(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
+	var req struct {
+		Currency string  `json:"currency" binding:"required"`
+		Price    float64 `json:"price" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	price, err := CurrencySvc.SaveProductPrice(uint(id), req.Currency, req.Price)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": price})
+}
+
+// AdminDeleteProductPrice 删除商品在某币种下的固定价格（恢复按汇率换算）
+// DELETE /api/admin/product/:id/price/:currency
+func AdminDeleteProductPrice(c *gin.Context) {
+	if CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
+	if err := CurrencySvc.DeleteProductPrice(uint(id), c.Param("currency")); err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "删除失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
+}
+
+// AdminGetCurrencyStats 获取按支付币种分组的收入统计
+// GET /api/admin/stats/currency
+func AdminGetCurrencyStats(c *gin.Context) {
+	if StatsSvc == nil || CurrencySvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	startDateStr := c.DefaultQuery("start_date", time.Now().AddDate(0, -1, 0).Format("2006-01-02"))
+	endDateStr := c.DefaultQuery("end_date", time.Now().Format("2006-01-02"))
+
+	startDate, _ := time.Parse("2006-01-02", startDateStr)
+	endDate, _ := time.Parse("2006-01-02", endDateStr)
+	endDate = endDate.Add(24*time.Hour - time.Second)
+
+	base := CurrencySvc.GetBaseCurrency()
+	data, err := StatsSvc.GetCurrencyStats(startDate, endDate, base)
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取数据失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": data, "base": base})
+}
@@ -0,0 +1,1024 @@
+// Package api 提供 HTTP API 处理器
+// dev_gateway_handler.go - 支付网关沙箱模拟器接口（仅开发/测试环境启用）
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GatewaySim 支付网关模拟器（未启用时为nil）
+var GatewaySim *service.GatewaySimulator
+
+// devGatewayPrefix 模拟网关路由前缀
+const devGatewayPrefix = "/dev/gateway"
+
+// devPayPalAccessToken 模拟PayPal访问令牌
+const devPayPalAccessToken = "SIM-PAYPAL-ACCESS-TOKEN"
+
+// registerDevGatewayRoutes 注册支付网关模拟器路由
+// 仅在 ENABLE_GATEWAY_SIMULATOR=true 且 APP_ENV 显式设置为 development 或 testing 时挂载
+func registerDevGatewayRoutes(r *gin.Engine, cfg *config.Config) {
+	envCfg := config.GlobalEnvConfig
+	if envCfg == nil || !envCfg.EnableGatewaySimulator {
+		return
+	}
+	if !envCfg.GatewaySimulatorEnabled() {
+		log.Printf("警告: 已设置 ENABLE_GATEWAY_SIMULATOR，但 APP_ENV 未显式设置为 development 或 testing（当前 %q），支付网关模拟器未启用", os.Getenv("APP_ENV"))
+		return
+	}
+
+	sim, err := service.NewGatewaySimulator(cfg)
+	if err != nil {
+		log.Printf("[GatewaySim] 初始化失败: %v", err)
+		return
+	}
+	GatewaySim = sim
+	banner := strings.Repeat("!", 72)
+	log.Print(banner)
+	log.Printf("[GatewaySim] 警告: 支付网关模拟器已启用（APP_ENV=%s），挂载于 %s", envCfg.Env, devGatewayPrefix)
+	log.Print("[GatewaySim] 任何人都可以通过模拟器将订单标记为已支付，切勿在生产环境或对外开放的实例上启用")
+	log.Print("[GatewaySim] 关闭方法: 移除 ENABLE_GATEWAY_SIMULATOR 环境变量后重启")
+	log.Print(banner)
+
+	r.GET(devGatewayPrefix, DevGatewayIndex)
+	r.Any(devGatewayPrefix+"/:provider/*path", DevGatewayHandler)
+}
+
+// DevGatewayIndex 模拟网关说明（各支付方式需配置的API地址）
+// GET /dev/gateway
+func DevGatewayIndex(c *gin.Context) {
+	base := devSiteURL(c) + devGatewayPrefix
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"base_urls": gin.H{
+				"stripe_api_base_url":   base + "/stripe",
+				"paypal_api_base_url":   base + "/paypal",
+				"alipay_gateway_url":    base + "/alipay/gateway.do",
+				"wechat_api_base_url":   base + "/wechat",
+				"yipay_api_url":         base + "/yipay/",
+				"usdt_api_base_url":     base + "/nowpayments 或 " + base + "/coingate",
+				"alipay_public_key_url": base + "/alipay/_sim/public-key",
+			},
+			"control": gin.H{
+				"list":     "GET " + base + "/:provider/_sim/payments",
+				"detail":   "GET " + base + "/:provider/_sim/payments/:ref",
+				"pay":      "POST " + base + "/:provider/_sim/pay/:ref",
+				"cancel":   "POST " + base + "/:provider/_sim/cancel/:ref",
+				"checkout": "GET " + base + "/:provider/_sim/checkout/:ref",
+			},
+			"note": "模拟网关使用商户配置的密钥校验请求并签名通知；支付宝需将“支付宝公钥”配置为模拟器公钥（每次启动重新生成）",
+		},
+	})
+}
+
+// DevGatewayHandler 模拟网关请求分发
+// ANY /dev/gateway/:provider/*path
+func DevGatewayHandler(c *gin.Context) {
+	if GatewaySim == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	provider := c.Param("provider")
+	path := c.Param("path")
+
+	if strings.HasPrefix(path, "/_sim/") {
+		devGatewayControl(c, provider, strings.TrimPrefix(path, "/_sim/"))
+		return
+	}
+
+	switch provider {
+	case service.SimProviderStripe:
+		devStripe(c, path)
+	case service.SimProviderPayPal:
+		devPayPal(c, path)
+	case service.SimProviderAlipay:
+		devAlipay(c, path)
+	case service.SimProviderWechat:
+		devWechat(c, path)
+	case service.SimProviderYiPay:
+		devYiPay(c, path)
+	case service.SimProviderNowPayments:
+		devNowPayments(c, path)
+	case service.SimProviderCoinGate:
+		devCoinGate(c, path)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "不支持的提供商"})
+	}
+}
+
+// ==================== 控制接口 ====================
+
+// devGatewayControl 处理模拟器控制接口（查询交易、模拟支付/取消、收银台页面）
+func devGatewayControl(c *gin.Context, provider, path string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	action, ref := parts[0], ""
+	if len(parts) > 1 {
+		ref = parts[1]
+	}
+
+	switch {
+	case action == "public-key" && provider == service.SimProviderAlipay:
+		c.String(http.StatusOK, GatewaySim.AlipayPublicKeyPEM())
+
+	case action == "payments" && ref == "":
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": GatewaySim.List(provider)})
+
+	case action == "payments":
+		p, ok := GatewaySim.Find(provider, ref)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "交易不存在"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": p})
+
+	case action == "pay" && c.Request.Method == http.MethodPost:
+		p, notify, err := GatewaySim.Complete(provider, ref, devSiteURL(c))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":      true,
+			"data":         p,
+			"notify":       notify,
+			"redirect_url": devReturnURL(&p),
+		})
+
+	case action == "cancel" && c.Request.Method == http.MethodPost:
+		p, err := GatewaySim.Cancel(provider, ref)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": p, "redirect_url": devCancelURL(&p)})
+
+	case action == "checkout":
+		devCheckout(c, provider, ref, parts)
+
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "未知的模拟器接口"})
+	}
+}
+
+// devCheckout 模拟收银台页面（买家确认支付或取消后跳转回商户）
+func devCheckout(c *gin.Context, provider, ref string, parts []string) {
+	if len(parts) > 2 && c.Request.Method == http.MethodPost {
+		var p service.SimPayment
+		var err error
+		var target string
+		if parts[2] == "pay" {
+			p, _, err = GatewaySim.Complete(provider, ref, devSiteURL(c))
+			target = devReturnURL(&p)
+		} else {
+			p, err = GatewaySim.Cancel(provider, ref)
+			target = devCancelURL(&p)
+		}
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if target == "" {
+			c.String(http.StatusOK, "模拟支付已处理，交易状态: "+p.Status)
+			return
+		}
+		c.Redirect(http.StatusFound, target)
+		return
+	}
+
+	p, ok := GatewaySim.Find(provider, ref)
+	if !ok {
+		c.String(http.StatusNotFound, "交易不存在")
+		return
+	}
+
+	action := devGatewayPrefix + "/" + provider + "/_sim/checkout/" + url.PathEscape(p.ID)
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>模拟收银台 - %s</title></head>
+<body style="font-family:sans-serif;max-width:480px;margin:40px auto">
+<h2>模拟收银台（%s）</h2>
+<p>交易号：%s</p><p>商户订单号：%s</p><p>商品：%s</p>
+<p>金额：<strong>%.2f %s</strong></p><p>状态：%s</p>
+<form method="post" action="%s/pay" style="display:inline"><button type="submit">确认支付</button></form>
+<form method="post" action="%s/cancel" style="display:inline"><button type="submit">取消支付</button></form>
+</body></html>`,
+		html.EscapeString(provider), html.EscapeString(provider),
+		html.EscapeString(p.ID), html.EscapeString(p.OutTradeNo), html.EscapeString(p.Subject),
+		p.Amount, html.EscapeString(strings.ToUpper(p.Currency)), html.EscapeString(p.Status),
+		action, action)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// devReturnURL 构建支付完成后的跳转地址（按提供商协议附加参数）
+func devReturnURL(p *service.SimPayment) string {
+	if p.ReturnURL == "" {
+		return ""
+	}
+
+	switch p.Provider {
+	case service.SimProviderStripe:
+		return strings.ReplaceAll(p.ReturnURL, "{CHECKOUT_SESSION_ID}", p.ID)
+	case service.SimProviderPayPal:
+		return devAppendQuery(p.ReturnURL, url.Values{"token": {p.ID}, "PayerID": {"SIMPAYER"}})
+	case service.SimProviderYiPay:
+		return devAppendQuery(p.ReturnURL, GatewaySim.YiPayReturnParams(p))
+	}
+	return p.ReturnURL
+}
+
+// devCancelURL 构建取消支付后的跳转地址
+func devCancelURL(p *service.SimPayment) string {
+	if p.CancelURL == "" {
+		return ""
+	}
+	if p.Provider == service.SimProviderPayPal {
+		return devAppendQuery(p.CancelURL, url.Values{"token": {p.ID}})
+	}
+	return p.CancelURL
+}
+
+// devAppendQuery 向URL追加查询参数
+func devAppendQuery(rawURL string, values url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + values.Encode()
+}
+
+// devSiteURL 获取本站地址（用于拼接回调和收银台地址）
+func devSiteURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// devCheckoutURL 获取模拟收银台地址
+func devCheckoutURL(c *gin.Context, provider, id string) string {
+	return devSiteURL(c) + devGatewayPrefix + "/" + provider + "/_sim/checkout/" + url.PathEscape(id)
+}
+
+// devParseAmount 解析金额字符串
+func devParseAmount(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v
+}
+
+// ==================== Stripe ====================
+
+// devStripeError 返回Stripe格式的错误
+func devStripeError(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{"error": gin.H{"type": "invalid_request_error", "message": message}})
+}
+
+// devStripe 模拟Stripe API（Checkout Session、PaymentIntent、退款、余额）
+func devStripe(c *gin.Context, path string) {
+	cfg := GatewaySim.Config()
+	if cfg.StripeSecretKey == "" || c.GetHeader("Authorization") != "Bearer "+cfg.StripeSecretKey {
+		devStripeError(c, http.StatusUnauthorized, "Invalid API Key provided")
+		return
+	}
+
+	c.Request.ParseForm()
+	form := c.Request.PostForm
+	method := c.Request.Method
+
+	switch {
+	case method == http.MethodPost && path == "/v1/checkout/sessions":
+		unitAmount, _ := strconv.ParseInt(form.Get("line_items[0][price_data][unit_amount]"), 10, 64)
+		quantity, _ := strconv.ParseInt(form.Get("line_items[0][quantity]"), 10, 64)
+		if quantity <= 0 {
+			quantity = 1
+		}
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderStripe,
+			OutTradeNo: form.Get("client_reference_id"),
+			Amount:     float64(unitAmount*quantity) / 100,
+			Currency:   form.Get("line_items[0][price_data][currency]"),
+			Subject:    form.Get("line_items[0][price_data][product_data][name]"),
+			ReturnURL:  form.Get("success_url"),
+			CancelURL:  form.Get("cancel_url"),
+			Metadata:   devStripeMetadata(form),
+			Extra:      map[string]string{"object": "checkout.session"},
+		}, "cs_sim_")
+		c.JSON(http.StatusOK, service.StripeSimSessionObject(&p, devCheckoutURL(c, service.SimProviderStripe, p.ID)))
+
+	case method == http.MethodGet && strings.HasPrefix(path, "/v1/checkout/sessions/"):
+		p, ok := GatewaySim.Find(service.SimProviderStripe, strings.TrimPrefix(path, "/v1/checkout/sessions/"))
+		if !ok || p.Extra["object"] != "checkout.session" {
+			devStripeError(c, http.StatusNotFound, "No such checkout.session")
+			return
+		}
+		c.JSON(http.StatusOK, service.StripeSimSessionObject(&p, devCheckoutURL(c, service.SimProviderStripe, p.ID)))
+
+	case method == http.MethodPost && path == "/v1/payment_intents":
+		amount, _ := strconv.ParseInt(form.Get("amount"), 10, 64)
+		metadata := devStripeMetadata(form)
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderStripe,
+			OutTradeNo: metadata["order_no"],
+			Amount:     float64(amount) / 100,
+			Currency:   form.Get("currency"),
+			Subject:    form.Get("description"),
+			Metadata:   metadata,
+			Extra:      map[string]string{"object": "payment_intent"},
+		}, "pi_sim_")
+		c.JSON(http.StatusOK, service.StripeSimIntentObject(&p))
+
+	case method == http.MethodGet && strings.HasPrefix(path, "/v1/payment_intents/"):
+		p, ok := devStripeFindIntent(strings.TrimPrefix(path, "/v1/payment_intents/"))
+		if !ok {
+			devStripeError(c, http.StatusNotFound, "No such payment_intent")
+			return
+		}
+		c.JSON(http.StatusOK, service.StripeSimIntentObject(&p))
+
+	case method == http.MethodPost && path == "/v1/refunds":
+		intent, ok := devStripeFindIntent(form.Get("payment_intent"))
+		if !ok {
+			devStripeError(c, http.StatusNotFound, "No such payment_intent")
+			return
+		}
+		cents, _ := strconv.ParseInt(form.Get("amount"), 10, 64)
+		_, refunded, err := GatewaySim.Refund(service.SimProviderStripe, intent.Extra["ref"], float64(cents)/100)
+		if err != nil {
+			devStripeError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"id":             GatewaySim.NextID("re_sim_"),
+			"object":         "refund",
+			"amount":         int64(refunded*100 + 0.5),
+			"currency":       intent.Currency,
+			"payment_intent": intent.ID,
+			"status":         "succeeded",
+		})
+
+	case method == http.MethodGet && path == "/v1/balance":
+		currency := cfg.StripeCurrency
+		if currency == "" {
+			currency = "usd"
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"object":    "balance",
+			"livemode":  false,
+			"available": []gin.H{{"amount": 0, "currency": currency}},
+			"pending":   []gin.H{{"amount": 0, "currency": currency}},
+		})
+
+	default:
+		devStripeError(c, http.StatusNotFound, "Unrecognized request URL")
+	}
+}
+
+// devStripeFindIntent 查找PaymentIntent（兼容Checkout Session关联的PaymentIntent）
+// 返回的交易 Extra["ref"] 为实际存储的交易号，ID 为PaymentIntent ID
+func devStripeFindIntent(intentID string) (service.SimPayment, bool) {
+	if p, ok := GatewaySim.Find(service.SimProviderStripe, intentID); ok && p.Extra["object"] == "payment_intent" {
+		p.Extra = map[string]string{"object": "payment_intent", "ref": p.ID}
+		return p, true
+	}
+
+	sessionID := "cs_" + strings.TrimPrefix(intentID, "pi_")
+	p, ok := GatewaySim.Find(service.SimProviderStripe, sessionID)
+	if !ok || service.StripeSimSessionIntentID(p.ID) != intentID {
+		return service.SimPayment{}, false
+	}
+	p.Extra = map[string]string{"object": "payment_intent", "ref": p.ID}
+	p.ID = intentID
+	return p, true
+}
+
+// devStripeMetadata 解析表单中的 metadata[key] 参数
+func devStripeMetadata(form url.Values) map[string]string {
+	metadata := make(map[string]string)
+	for k := range form {
+		if strings.HasPrefix(k, "metadata[") && strings.HasSuffix(k, "]") {
+			metadata[strings.TrimSuffix(strings.TrimPrefix(k, "metadata["), "]")] = form.Get(k)
+		}
+	}
+	return metadata
+}
+
+// ==================== PayPal ====================
+
+// devPayPalError 返回PayPal格式的错误
+func devPayPalError(c *gin.Context, status int, name, issue string) {
+	c.JSON(status, gin.H{
+		"name":    name,
+		"message": issue,
+		"details": []gin.H{{"issue": issue}},
+	})
+}
+
+// devPayPal 模拟PayPal REST API（OAuth令牌、订单创建/查询/捕获）
+func devPayPal(c *gin.Context, path string) {
+	cfg := GatewaySim.Config().PayPal
+	method := c.Request.Method
+
+	if method == http.MethodPost && path == "/v1/oauth2/token" {
+		id, secret, ok := c.Request.BasicAuth()
+		if !ok || cfg.ClientID == "" || id != cfg.ClientID || secret != cfg.ClientSecret {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client", "error_description": "Client Authentication failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": devPayPalAccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   32400,
+		})
+		return
+	}
+
+	if c.GetHeader("Authorization") != "Bearer "+devPayPalAccessToken {
+		devPayPalError(c, http.StatusUnauthorized, "AUTHENTICATION_FAILURE", "INVALID_TOKEN")
+		return
+	}
+
+	switch {
+	case method == http.MethodPost && path == "/v2/checkout/orders":
+		var req struct {
+			PurchaseUnits []struct {
+				ReferenceID string `json:"reference_id"`
+				Description string `json:"description"`
+				Amount      struct {
+					CurrencyCode string `json:"currency_code"`
+					Value        string `json:"value"`
+				} `json:"amount"`
+			} `json:"purchase_units"`
+			ApplicationContext struct {
+				ReturnURL string `json:"return_url"`
+				CancelURL string `json:"cancel_url"`
+			} `json:"application_context"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.PurchaseUnits) == 0 {
+			devPayPalError(c, http.StatusBadRequest, "INVALID_REQUEST", "MISSING_REQUIRED_PARAMETER")
+			return
+		}
+		unit := req.PurchaseUnits[0]
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderPayPal,
+			OutTradeNo: unit.ReferenceID,
+			Amount:     devParseAmount(unit.Amount.Value),
+			Currency:   unit.Amount.CurrencyCode,
+			Subject:    unit.Description,
+			ReturnURL:  req.ApplicationContext.ReturnURL,
+			CancelURL:  req.ApplicationContext.CancelURL,
+		}, "SIMPP")
+		c.JSON(http.StatusCreated, devPayPalOrder(c, &p))
+
+	case method == http.MethodGet && strings.HasPrefix(path, "/v2/checkout/orders/"):
+		p, ok := GatewaySim.Find(service.SimProviderPayPal, strings.TrimPrefix(path, "/v2/checkout/orders/"))
+		if !ok {
+			devPayPalError(c, http.StatusNotFound, "RESOURCE_NOT_FOUND", "INVALID_RESOURCE_ID")
+			return
+		}
+		c.JSON(http.StatusOK, devPayPalOrder(c, &p))
+
+	case method == http.MethodPost && strings.HasSuffix(path, "/capture"):
+		ref := strings.TrimSuffix(strings.TrimPrefix(path, "/v2/checkout/orders/"), "/capture")
+		p, err := GatewaySim.Capture(service.SimProviderPayPal, ref)
+		if err != nil {
+			switch p.Status {
+			case "":
+				devPayPalError(c, http.StatusNotFound, "RESOURCE_NOT_FOUND", "INVALID_RESOURCE_ID")
+			case service.SimStatusPaid, service.SimStatusRefunded:
+				devPayPalError(c, http.StatusUnprocessableEntity, "UNPROCESSABLE_ENTITY", "ORDER_ALREADY_CAPTURED")
+			default:
+				devPayPalError(c, http.StatusUnprocessableEntity, "UNPROCESSABLE_ENTITY", "ORDER_NOT_APPROVED")
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, devPayPalOrder(c, &p))
+
+	default:
+		devPayPalError(c, http.StatusNotFound, "RESOURCE_NOT_FOUND", "INVALID_RESOURCE_ID")
+	}
+}
+
+// devPayPalOrder 构建PayPal订单对象
+func devPayPalOrder(c *gin.Context, p *service.SimPayment) gin.H {
+	status := "CREATED"
+	switch p.Status {
+	case service.SimStatusApproved:
+		status = "APPROVED"
+	case service.SimStatusPaid, service.SimStatusRefunded:
+		status = "COMPLETED"
+	case service.SimStatusCanceled:
+		status = "VOIDED"
+	}
+
+	amount := gin.H{"currency_code": p.Currency, "value": fmt.Sprintf("%.2f", p.Amount)}
+	unit := gin.H{"reference_id": p.OutTradeNo, "description": p.Subject, "amount": amount}
+	if status == "COMPLETED" {
+		unit["payments"] = gin.H{"captures": []gin.H{{
+			"id":     "CAP" + p.ID,
+			"status": "COMPLETED",
+			"amount": amount,
+		}}}
+	}
+
+	self := devSiteURL(c) + devGatewayPrefix + "/paypal/v2/checkout/orders/" + p.ID
+	return gin.H{
+		"id":             p.ID,
+		"status":         status,
+		"intent":         "CAPTURE",
+		"purchase_units": []gin.H{unit},
+		"payment_source": gin.H{"paypal": gin.H{"email_address": "sim-buyer@example.com", "account_id": "SIMPAYER"}},
+		"links": []gin.H{
+			{"href": self, "rel": "self", "method": "GET"},
+			{"href": devCheckoutURL(c, service.SimProviderPayPal, p.ID), "rel": "approve", "method": "GET"},
+			{"href": self + "/capture", "rel": "capture", "method": "POST"},
+		},
+	}
+}
+
+// ==================== 支付宝 ====================
+
+// devAlipay 模拟支付宝开放平台网关（预下单、查询、退款、关闭）
+func devAlipay(c *gin.Context, path string) {
+	if path != "/gateway.do" {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "未知的支付宝接口"})
+		return
+	}
+
+	c.Request.ParseForm()
+	params := c.Request.Form
+	method := params.Get("method")
+	cfg := GatewaySim.Config().AlipayF2F
+
+	if params.Get("app_id") != cfg.AppID {
+		devAlipayResponse(c, method, gin.H{"code": "40002", "msg": "Invalid Arguments", "sub_code": "isv.invalid-app-id", "sub_msg": "无效的AppID参数"})
+		return
+	}
+	if err := GatewaySim.AlipayVerifyRequest(params); err != nil {
+		devAlipayResponse(c, method, gin.H{"code": "40002", "msg": "Invalid Arguments", "sub_code": "isv.invalid-signature", "sub_msg": "验签出错"})
+		return
+	}
+
+	var biz map[string]string
+	json.Unmarshal([]byte(params.Get("biz_content")), &biz)
+	ref := biz["out_trade_no"]
+	if ref == "" {
+		ref = biz["trade_no"]
+	}
+
+	notExist := gin.H{"code": "40004", "msg": "Business Failed", "sub_code": "ACQ.TRADE_NOT_EXIST", "sub_msg": "交易不存在"}
+
+	switch method {
+	case "alipay.trade.precreate":
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderAlipay,
+			OutTradeNo: biz["out_trade_no"],
+			Amount:     devParseAmount(biz["total_amount"]),
+			Currency:   "CNY",
+			Subject:    biz["subject"],
+			NotifyURL:  params.Get("notify_url"),
+		}, time.Now().Format("20060102")+"SIM")
+		devAlipayResponse(c, method, gin.H{
+			"code":         "10000",
+			"msg":          "Success",
+			"out_trade_no": p.OutTradeNo,
+			"qr_code":      devCheckoutURL(c, service.SimProviderAlipay, p.ID),
+		})
+
+	case "alipay.trade.query":
+		p, ok := GatewaySim.Find(service.SimProviderAlipay, ref)
+		if !ok {
+			devAlipayResponse(c, method, notExist)
+			return
+		}
+		status := "WAIT_BUYER_PAY"
+		switch p.Status {
+		case service.SimStatusPaid:
+			status = "TRADE_SUCCESS"
+		case service.SimStatusRefunded, service.SimStatusCanceled:
+			status = "TRADE_CLOSED"
+		}
+		devAlipayResponse(c, method, gin.H{
+			"code":         "10000",
+			"msg":          "Success",
+			"trade_no":     p.ID,
+			"out_trade_no": p.OutTradeNo,
+			"trade_status": status,
+			"total_amount": fmt.Sprintf("%.2f", p.Amount),
+		})
+
+	case "alipay.trade.refund":
+		p, refunded, err := GatewaySim.Refund(service.SimProviderAlipay, ref, devParseAmount(biz["refund_amount"]))
+		if err != nil {
+			if p.ID == "" {
+				devAlipayResponse(c, method, notExist)
+			} else {
+				devAlipayResponse(c, method, gin.H{"code": "40004", "msg": "Business Failed", "sub_code": "ACQ.TRADE_STATUS_ERROR", "sub_msg": err.Error()})
+			}
+			return
+		}
+		devAlipayResponse(c, method, gin.H{
+			"code":         "10000",
+			"msg":          "Success",
+			"trade_no":     p.ID,
+			"out_trade_no": p.OutTradeNo,
+			"fund_change":  "Y",
+			"refund_fee":   fmt.Sprintf("%.2f", refunded),
+		})
+
+	case "alipay.trade.close", "alipay.trade.cancel":
+		p, err := GatewaySim.Cancel(service.SimProviderAlipay, ref)
+		if err != nil && p.ID == "" {
+			devAlipayResponse(c, method, notExist)
+			return
+		}
+		devAlipayResponse(c, method, gin.H{"code": "10000", "msg": "Success", "trade_no": p.ID, "out_trade_no": p.OutTradeNo})
+
+	default:
+		devAlipayResponse(c, method, gin.H{"code": "40004", "msg": "Business Failed", "sub_code": "isv.invalid-method", "sub_msg": "不存在的方法名"})
+	}
+}
+
+// devAlipayResponse 输出支付宝格式的响应（响应节点使用模拟平台私钥签名）
+func devAlipayResponse(c *gin.Context, method string, content gin.H) {
+	if method == "" {
+		method = "error"
+	}
+	contentJSON, _ := json.Marshal(content)
+	signJSON, _ := json.Marshal(GatewaySim.AlipaySign(string(contentJSON)))
+
+	body := `{"` + strings.ReplaceAll(method, ".", "_") + `_response":` + string(contentJSON) + `,"sign":` + string(signJSON) + `}`
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(body))
+}
+
+// ==================== 微信支付 ====================
+
+// devWechat 模拟微信支付V2接口（统一下单、查询、退款、关单）
+func devWechat(c *gin.Context, path string) {
+	body, _ := io.ReadAll(c.Request.Body)
+	params, err := service.DecodeWechatXML(body)
+	if err != nil {
+		devWechatResponse(c, map[string]string{"return_code": "FAIL", "return_msg": "XML格式错误"}, false)
+		return
+	}
+
+	cfg := GatewaySim.Config().WechatPay
+	signParams := make(map[string]string, len(params))
+	for k, v := range params {
+		if k != "sign" {
+			signParams[k] = v
+		}
+	}
+	if params["mch_id"] != cfg.MchID || params["sign"] != GatewaySim.WechatSign(signParams) {
+		devWechatResponse(c, map[string]string{"return_code": "FAIL", "return_msg": "签名错误"}, false)
+		return
+	}
+
+	result := map[string]string{
+		"return_code": "SUCCESS",
+		"return_msg":  "OK",
+		"appid":       cfg.AppID,
+		"mch_id":      cfg.MchID,
+		"nonce_str":   strconv.FormatInt(time.Now().UnixNano(), 36),
+		"result_code": "SUCCESS",
+	}
+	fail := func(code, des string) {
+		result["result_code"] = "FAIL"
+		result["err_code"] = code
+		result["err_code_des"] = des
+		devWechatResponse(c, result, true)
+	}
+
+	ref := params["out_trade_no"]
+	if ref == "" {
+		ref = params["transaction_id"]
+	}
+
+	switch path {
+	case "/pay/unifiedorder":
+		totalFee, _ := strconv.ParseInt(params["total_fee"], 10, 64)
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderWechat,
+			OutTradeNo: params["out_trade_no"],
+			Amount:     float64(totalFee) / 100,
+			Currency:   "CNY",
+			Subject:    params["body"],
+			NotifyURL:  params["notify_url"],
+		}, "4200SIM")
+		result["trade_type"] = "NATIVE"
+		result["prepay_id"] = "wx" + p.ID
+		result["code_url"] = devCheckoutURL(c, service.SimProviderWechat, p.ID)
+		devWechatResponse(c, result, true)
+
+	case "/pay/orderquery":
+		p, ok := GatewaySim.Find(service.SimProviderWechat, ref)
+		if !ok {
+			fail("ORDERNOTEXIST", "订单不存在")
+			return
+		}
+		state := "NOTPAY"
+		switch p.Status {
+		case service.SimStatusPaid:
+			state = "SUCCESS"
+		case service.SimStatusRefunded:
+			state = "REFUND"
+		case service.SimStatusCanceled:
+			state = "CLOSED"
+		}
+		result["trade_state"] = state
+		result["out_trade_no"] = p.OutTradeNo
+		result["total_fee"] = strconv.FormatInt(int64(p.Amount*100+0.5), 10)
+		if state != "NOTPAY" {
+			result["transaction_id"] = p.ID
+		}
+		devWechatResponse(c, result, true)
+
+	case "/secapi/pay/refund":
+		refundFee, _ := strconv.ParseInt(params["refund_fee"], 10, 64)
+		p, refunded, err := GatewaySim.Refund(service.SimProviderWechat, ref, float64(refundFee)/100)
+		if err != nil {
+			if p.ID == "" {
+				fail("ORDERNOTEXIST", "订单不存在")
+			} else {
+				fail("TRADE_STATE_ERROR", err.Error())
+			}
+			return
+		}
+		result["transaction_id"] = p.ID
+		result["out_trade_no"] = p.OutTradeNo
+		result["out_refund_no"] = params["out_refund_no"]
+		result["refund_id"] = GatewaySim.NextID("5030SIM")
+		result["refund_fee"] = strconv.FormatInt(int64(refunded*100+0.5), 10)
+		devWechatResponse(c, result, true)
+
+	case "/pay/closeorder":
+		p, err := GatewaySim.Cancel(service.SimProviderWechat, ref)
+		if err != nil {
+			if p.ID == "" {
+				fail("ORDERNOTEXIST", "订单不存在")
+			} else {
+				fail("ORDERPAID", err.Error())
+			}
+			return
+		}
+		devWechatResponse(c, result, true)
+
+	default:
+		devWechatResponse(c, map[string]string{"return_code": "FAIL", "return_msg": "未知的接口"}, false)
+	}
+}
+
+// devWechatResponse 输出微信支付XML响应（通信成功时附加签名）
+func devWechatResponse(c *gin.Context, result map[string]string, signed bool) {
+	if signed {
+		result["sign"] = GatewaySim.WechatSign(result)
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", service.EncodeWechatXML(result))
+}
+
+// ==================== 易支付 ====================
+
+// devYiPay 模拟易支付（页面跳转支付、订单查询）
+func devYiPay(c *gin.Context, path string) {
+	cfg := GatewaySim.Config().YiPay
+	c.Request.ParseForm()
+	form := c.Request.Form
+
+	switch path {
+	case "/", "":
+		c.String(http.StatusOK, "YiPay simulator")
+
+	case "/submit.php":
+		params := make(map[string]string)
+		for k := range form {
+			params[k] = form.Get(k)
+		}
+		if params["pid"] != cfg.PID || params["sign"] != GatewaySim.YiPaySign(params) {
+			c.String(http.StatusBadRequest, "签名校验失败")
+			return
+		}
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderYiPay,
+			OutTradeNo: params["out_trade_no"],
+			Amount:     devParseAmount(params["money"]),
+			Currency:   "CNY",
+			Subject:    params["name"],
+			NotifyURL:  params["notify_url"],
+			ReturnURL:  params["return_url"],
+			Extra:      map[string]string{"type": params["type"]},
+		}, time.Now().Format("20060102")+"YP")
+		c.Redirect(http.StatusFound, devCheckoutURL(c, service.SimProviderYiPay, p.ID))
+
+	case "/api.php":
+		if form.Get("pid") != cfg.PID || form.Get("key") != cfg.Key {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "商户ID或密钥错误"})
+			return
+		}
+		ref := form.Get("out_trade_no")
+		if ref == "" {
+			ref = form.Get("trade_no")
+		}
+		p, ok := GatewaySim.Find(service.SimProviderYiPay, ref)
+		if form.Get("act") != "order" || !ok {
+			c.JSON(http.StatusOK, gin.H{"code": -1, "msg": "订单不存在"})
+			return
+		}
+		status := 0
+		if p.Status == service.SimStatusPaid {
+			status = 1
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":         1,
+			"msg":          "查询订单号成功！",
+			"trade_no":     p.ID,
+			"out_trade_no": p.OutTradeNo,
+			"type":         p.Extra["type"],
+			"pid":          cfg.PID,
+			"name":         p.Subject,
+			"money":        fmt.Sprintf("%.2f", p.Amount),
+			"status":       status,
+		})
+
+	default:
+		c.String(http.StatusNotFound, "未知的易支付接口")
+	}
+}
+
+// ==================== USDT 网关 ====================
+
+// devNowPayments 模拟NOWPayments API（状态、创建支付、查询支付）
+func devNowPayments(c *gin.Context, path string) {
+	cfg := GatewaySim.Config()
+	if cfg.USDTAPIKey == "" || c.GetHeader("x-api-key") != cfg.USDTAPIKey {
+		c.JSON(http.StatusForbidden, gin.H{"statusCode": 403, "code": "INVALID_API_KEY", "message": "Invalid api key"})
+		return
+	}
+
+	switch {
+	case path == "/v1/status":
+		c.JSON(http.StatusOK, gin.H{"message": "OK"})
+
+	case c.Request.Method == http.MethodPost && path == "/v1/payment":
+		var req struct {
+			PriceAmount      float64 `json:"price_amount"`
+			PriceCurrency    string  `json:"price_currency"`
+			PayCurrency      string  `json:"pay_currency"`
+			OrderID          string  `json:"order_id"`
+			OrderDescription string  `json:"order_description"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"statusCode": 400, "code": "INVALID_REQUEST_PARAMS", "message": err.Error()})
+			return
+		}
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderNowPayments,
+			OutTradeNo: req.OrderID,
+			Amount:     req.PriceAmount,
+			Currency:   req.PriceCurrency,
+			Subject:    req.OrderDescription,
+			Extra:      map[string]string{"pay_currency": req.PayCurrency},
+		}, "5")
+		c.JSON(http.StatusCreated, devNowPaymentsObject(&p))
+
+	case c.Request.Method == http.MethodGet && strings.HasPrefix(path, "/v1/payment/"):
+		p, ok := GatewaySim.Find(service.SimProviderNowPayments, strings.TrimPrefix(path, "/v1/payment/"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"statusCode": 404, "code": "NOT_FOUND", "message": "Payment not found"})
+			return
+		}
+		c.JSON(http.StatusOK, devNowPaymentsObject(&p))
+
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"statusCode": 404, "code": "NOT_FOUND", "message": "Not found"})
+	}
+}
+
+// devNowPaymentsObject 构建NOWPayments支付对象
+func devNowPaymentsObject(p *service.SimPayment) gin.H {
+	status, paid := "waiting", 0.0
+	switch p.Status {
+	case service.SimStatusPaid:
+		status, paid = "finished", p.Amount
+	case service.SimStatusRefunded:
+		status, paid = "refunded", p.Amount
+	case service.SimStatusCanceled:
+		status = "expired"
+	}
+
+	obj := gin.H{
+		"payment_id":               p.ID,
+		"payment_status":           status,
+		"pay_address":              devUSDTAddress(),
+		"pay_amount":               p.Amount,
+		"pay_currency":             p.Extra["pay_currency"],
+		"price_amount":             p.Amount,
+		"price_currency":           p.Currency,
+		"order_id":                 p.OutTradeNo,
+		"actually_paid":            paid,
+		"expiration_estimate_date": p.CreatedAt.Add(time.Hour).UTC().Format(time.RFC3339),
+	}
+	if paid > 0 {
+		obj["payin_hash"] = "0xsim" + p.ID
+	}
+	return obj
+}
+
+// devCoinGate 模拟CoinGate API（连通性、创建订单、查询订单）
+func devCoinGate(c *gin.Context, path string) {
+	cfg := GatewaySim.Config()
+	if cfg.USDTAPIKey == "" || c.GetHeader("Authorization") != "Token "+cfg.USDTAPIKey {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized", "reason": "BadAuthToken"})
+		return
+	}
+
+	switch {
+	case path == "/v2/ping":
+		c.JSON(http.StatusOK, gin.H{"ping": "pong"})
+
+	case c.Request.Method == http.MethodPost && path == "/v2/orders":
+		var req struct {
+			OrderID         string  `json:"order_id"`
+			PriceAmount     float64 `json:"price_amount"`
+			PriceCurrency   string  `json:"price_currency"`
+			ReceiveCurrency string  `json:"receive_currency"`
+			Title           string  `json:"title"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error(), "reason": "OrderIsNotValid"})
+			return
+		}
+		p := GatewaySim.CreatePayment(&service.SimPayment{
+			Provider:   service.SimProviderCoinGate,
+			OutTradeNo: req.OrderID,
+			Amount:     req.PriceAmount,
+			Currency:   req.PriceCurrency,
+			Subject:    req.Title,
+		}, "7")
+		c.JSON(http.StatusOK, devCoinGateObject(c, &p))
+
+	case c.Request.Method == http.MethodGet && strings.HasPrefix(path, "/v2/orders/"):
+		p, ok := GatewaySim.Find(service.SimProviderCoinGate, strings.TrimPrefix(path, "/v2/orders/"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Order not found", "reason": "OrderNotFound"})
+			return
+		}
+		c.JSON(http.StatusOK, devCoinGateObject(c, &p))
+
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found", "reason": "NotFound"})
+	}
+}
+
+// devCoinGateObject 构建CoinGate订单对象
+func devCoinGateObject(c *gin.Context, p *service.SimPayment) gin.H {
+	status, received := "new", 0.0
+	switch p.Status {
+	case service.SimStatusPaid:
+		status, received = "paid", p.Amount
+	case service.SimStatusRefunded:
+		status, received = "refunded", p.Amount
+	case service.SimStatusCanceled:
+		status = "canceled"
+	}
+
+	id, _ := strconv.Atoi(p.ID)
+	return gin.H{
+		"id":               id,
+		"status":           status,
+		"order_id":         p.OutTradeNo,
+		"price_amount":     p.Amount,
+		"price_currency":   p.Currency,
+		"receive_currency": "USDT",
+		"receive_amount":   received,
+		"pay_amount":       p.Amount,
+		"pay_address":      devUSDTAddress(),
+		"payment_url":      devCheckoutURL(c, service.SimProviderCoinGate, p.ID),
+		"expire_at":        p.CreatedAt.Add(time.Hour).UTC().Format(time.RFC3339),
+	}
+}
+
+// devUSDTAddress 获取模拟收款地址（优先使用配置的钱包地址）
+func devUSDTAddress() string {
+	if addr := GatewaySim.Config().USDTWalletAddress; addr != "" {
+		return addr
+	}
+	return "TSimulatorWalletAddress000000000000"
+}
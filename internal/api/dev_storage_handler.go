@@ -0,0 +1,41 @@
+// Package api 提供 HTTP API 处理器
+// dev_storage_handler.go - S3 兼容存储模拟服务（仅开发/测试环境启用）
+package api
+
+import (
+	"log"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 模拟存储服务设置
+const (
+	// devStoragePrefix 模拟存储路由前缀（作为 S3 服务地址填写，并开启路径风格）
+	devStoragePrefix = "/dev/s3"
+	// devStorageRoot 模拟存储数据目录
+	devStorageRoot = "./storage-simulator"
+	// devStorageAccessKey 模拟存储访问密钥ID（与 MinIO 默认凭证一致）
+	devStorageAccessKey = "minioadmin"
+	// devStorageSecretKey 模拟存储访问密钥
+	devStorageSecretKey = "minioadmin"
+)
+
+// registerDevStorageRoutes 注册 S3 兼容存储模拟服务路由
+// 仅在 ENABLE_STORAGE_SIMULATOR=true 且非生产环境时挂载
+func registerDevStorageRoutes(r *gin.Engine) {
+	if config.GlobalEnvConfig == nil || !config.GlobalEnvConfig.StorageSimulatorEnabled() {
+		return
+	}
+
+	standIn, err := storage.NewS3StandIn(devStorageRoot, devStoragePrefix, devStorageAccessKey, devStorageSecretKey)
+	if err != nil {
+		log.Printf("[StorageSim] 初始化失败: %v", err)
+		return
+	}
+	log.Printf("[StorageSim] S3 兼容存储模拟服务已启用: %s（访问密钥 %s，请勿在生产环境使用）", devStoragePrefix, devStorageAccessKey)
+
+	r.Any(devStoragePrefix+"/*path", gin.WrapH(standIn))
+}
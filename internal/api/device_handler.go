@@ -65,7 +65,7 @@ func RemoveLoginDevice(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("username")
-		LogSvc.LogUserActionSimple(userID.(uint), username.(string), "remove_device", "login_device",
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID.(uint), username.(string), "remove_device", "login_device",
 			strconv.FormatUint(deviceID, 10), "移除登录设备", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
@@ -98,7 +98,7 @@ func RemoveAllOtherDevices(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("username")
-		LogSvc.LogUserActionSimple(userID.(uint), username.(string), "remove_all_devices", "login_device",
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID.(uint), username.(string), "remove_all_devices", "login_device",
 			"", "移除所有其他设备", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
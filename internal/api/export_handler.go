@@ -46,7 +46,7 @@ func AdminExportOrders(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		adminUsername, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "export_orders", "order", "",
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "export_orders", "order", "",
 			fmt.Sprintf("导出订单数据 %s 至 %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02")),
 			c.ClientIP(), c.GetHeader("User-Agent"))
 	}
@@ -95,7 +95,7 @@ func AdminExportUsers(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		adminUsername, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "export_users", "user", "",
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "export_users", "user", "",
 			fmt.Sprintf("导出用户数据 %s 至 %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02")),
 			c.ClientIP(), c.GetHeader("User-Agent"))
 	}
@@ -144,7 +144,7 @@ func AdminExportLogs(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		adminUsername, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "export_logs", "log", "",
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "export_logs", "log", "",
 			fmt.Sprintf("导出操作日志 %s 至 %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02")),
 			c.ClientIP(), c.GetHeader("User-Agent"))
 	}
@@ -192,7 +192,7 @@ func AdminExportLoginHistory(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		adminUsername, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "export_login_history", "login_history", "",
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "export_login_history", "login_history", "",
 			fmt.Sprintf("导出登录历史 %s 至 %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02")),
 			c.ClientIP(), c.GetHeader("User-Agent"))
 	}
@@ -227,7 +227,7 @@ func UserExportOrders(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("username")
-		LogSvc.LogUserActionSimple(userID.(uint), username.(string), "export_orders", "order", "",
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID.(uint), username.(string), "export_orders", "order", "",
 			"导出个人订单数据", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
@@ -204,7 +204,7 @@ func AdminCreateFAQCategory(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(username.(string), "create", "faq_category", strconv.Itoa(int(category.ID)),
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(username.(string), "create", "faq_category", strconv.Itoa(int(category.ID)),
 			"创建FAQ分类: "+category.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
@@ -256,7 +256,7 @@ func AdminUpdateFAQCategory(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(username.(string), "update", "faq_category", strconv.Itoa(int(id)),
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(username.(string), "update", "faq_category", strconv.Itoa(int(id)),
 			"更新FAQ分类: "+category.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
@@ -284,7 +284,7 @@ func AdminDeleteFAQCategory(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(username.(string), "delete", "faq_category", strconv.Itoa(int(id)),
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(username.(string), "delete", "faq_category", strconv.Itoa(int(id)),
 			"删除FAQ分类", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
@@ -352,7 +352,7 @@ func AdminCreateFAQ(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(username.(string), "create", "faq", strconv.Itoa(int(faq.ID)),
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(username.(string), "create", "faq", strconv.Itoa(int(faq.ID)),
 			"创建FAQ: "+faq.Question, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
@@ -408,7 +408,7 @@ func AdminUpdateFAQ(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(username.(string), "update", "faq", strconv.Itoa(int(id)),
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(username.(string), "update", "faq", strconv.Itoa(int(id)),
 			"更新FAQ: "+faq.Question, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
@@ -436,7 +436,7 @@ func AdminDeleteFAQ(c *gin.Context) {
 	// 记录操作日志
 	if LogSvc != nil {
 		username, _ := c.Get("admin_username")
-		LogSvc.LogAdminActionSimple(username.(string), "delete", "faq", strconv.Itoa(int(id)),
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(username.(string), "delete", "faq", strconv.Itoa(int(id)),
 			"删除FAQ", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
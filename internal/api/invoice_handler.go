@@ -346,7 +346,7 @@ func AdminIssueInvoice(c *gin.Context) {
 	}
 
 	// 记录操作日志
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "开具发票", "invoice", invoiceNo, nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "开具发票", "invoice", invoiceNo, nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -377,7 +377,7 @@ func AdminRejectInvoice(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "拒绝发票", "invoice", invoiceNo, req.Reason, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "拒绝发票", "invoice", invoiceNo, req.Reason, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -424,7 +424,7 @@ func AdminSaveInvoiceConfig(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "更新发票配置", "invoice_config", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "更新发票配置", "invoice_config", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
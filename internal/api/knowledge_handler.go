@@ -208,7 +208,7 @@ func AdminCreateKnowledgeCategory(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "create_knowledge_category", "knowledge_category", strconv.Itoa(int(category.ID)), "创建知识库分类: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "create_knowledge_category", "knowledge_category", strconv.Itoa(int(category.ID)), "创建知识库分类: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "data": category})
@@ -259,7 +259,7 @@ func AdminUpdateKnowledgeCategory(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "update_knowledge_category", "knowledge_category", strconv.Itoa(int(categoryID)), "更新知识库分类", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "update_knowledge_category", "knowledge_category", strconv.Itoa(int(categoryID)), "更新知识库分类", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "更新成功"})
@@ -287,7 +287,7 @@ func AdminDeleteKnowledgeCategory(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "delete_knowledge_category", "knowledge_category", strconv.Itoa(int(categoryID)), "删除知识库分类", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "delete_knowledge_category", "knowledge_category", strconv.Itoa(int(categoryID)), "删除知识库分类", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
@@ -363,7 +363,7 @@ func AdminCreateKnowledgeArticle(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "create_knowledge_article", "knowledge_article", strconv.Itoa(int(article.ID)), "创建知识库文章: "+req.Title, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "create_knowledge_article", "knowledge_article", strconv.Itoa(int(article.ID)), "创建知识库文章: "+req.Title, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "data": article})
@@ -417,7 +417,7 @@ func AdminUpdateKnowledgeArticle(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "update_knowledge_article", "knowledge_article", strconv.Itoa(int(articleID)), "更新知识库文章", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "update_knowledge_article", "knowledge_article", strconv.Itoa(int(articleID)), "更新知识库文章", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "更新成功"})
@@ -445,7 +445,7 @@ func AdminDeleteKnowledgeArticle(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "delete_knowledge_article", "knowledge_article", strconv.Itoa(int(articleID)), "删除知识库文章", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "delete_knowledge_article", "knowledge_article", strconv.Itoa(int(articleID)), "删除知识库文章", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
@@ -133,7 +133,7 @@ func TrustLoginLocation(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "trust_location", "login_location", strconv.Itoa(int(locationID)), "标记登录地点为可信", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "trust_location", "login_location", strconv.Itoa(int(locationID)), "标记登录地点为可信", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "已标记为可信地点"})
@@ -163,7 +163,7 @@ func RemoveLoginLocation(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "remove_location", "login_location", strconv.Itoa(int(locationID)), "移除登录地点", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "remove_location", "login_location", strconv.Itoa(int(locationID)), "移除登录地点", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "已移除"})
@@ -0,0 +1,224 @@
+// Package api 提供 HTTP API 处理器
+// metrics_handler.go - Prometheus 指标接口与请求耗时统计
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-frontend/internal/cache"
+	"user-frontend/internal/config"
+	"user-frontend/internal/metrics"
+	"user-frontend/internal/model"
+	"user-frontend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsPath 指标接口路径
+const metricsPath = "/metrics"
+
+// MetricsMiddleware 按路由模板记录请求耗时和状态码（不含 /metrics 自身）
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.URL.Path == metricsPath {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTPRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
+
+// registerMetricsRoutes 注册指标接口，METRICS_ENABLED=false 时不挂载
+func registerMetricsRoutes(r *gin.Engine) {
+	if config.GlobalEnvConfig != nil && !config.GlobalEnvConfig.MetricsEnabled {
+		return
+	}
+	r.GET(metricsPath, MetricsHandler)
+}
+
+// MetricsHandler 输出 Prometheus 文本格式的指标
+// GET /metrics
+func MetricsHandler(c *gin.Context) {
+	if !metricsAccessAllowed(c) {
+		c.String(403, "forbidden")
+		return
+	}
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(200)
+	metrics.Default.WriteText(c.Writer)
+}
+
+// metricsAccessAllowed 校验抓取令牌和来源 IP
+// 既未配置令牌也未配置 IP 白名单时仅允许本机抓取（与 APP_ENV 无关，APP_ENV 默认为 development）
+func metricsAccessAllowed(c *gin.Context) bool {
+	envCfg := config.GlobalEnvConfig
+	if envCfg == nil {
+		return metricsLocalRequest(c)
+	}
+
+	if envCfg.MetricsToken != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(envCfg.MetricsToken)) != 1 {
+			return false
+		}
+	}
+
+	if len(envCfg.MetricsAllowIPs) > 0 {
+		clientIP := net.ParseIP(c.ClientIP())
+		return clientIP != nil && ipAllowed(clientIP, envCfg.MetricsAllowIPs)
+	}
+	if envCfg.MetricsToken == "" {
+		return metricsLocalRequest(c)
+	}
+	return true
+}
+
+// metricsLocalRequest 判断是否为本机直接发起的请求
+// 经本机反向代理转发的外部请求来源同样是回环地址，带转发头时不视为本机请求
+func metricsLocalRequest(c *gin.Context) bool {
+	if c.GetHeader("X-Forwarded-For") != "" || c.GetHeader("X-Real-IP") != "" || c.GetHeader("Forwarded") != "" {
+		return false
+	}
+	clientIP := net.ParseIP(c.ClientIP())
+	return clientIP != nil && clientIP.IsLoopback()
+}
+
+// ipAllowed 判断 IP 是否匹配列表中的地址或 CIDR
+func ipAllowed(ip net.IP, allowed []string) bool {
+	for _, entry := range allowed {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ==================== 抓取时计算的指标 ====================
+
+func init() {
+	metrics.NewGaugeFunc("shop_websocket_connections", "当前 WebSocket 连接数（按用户类型）", func() []metrics.Sample {
+		var samples []metrics.Sample
+		for userType, count := range service.GetWSHub().GetConnectionCounts() {
+			samples = append(samples, metrics.Sample{LabelValues: []string{userType}, Value: float64(count)})
+		}
+		return samples
+	}, "type")
+
+	metrics.NewGaugeFunc("shop_kami_available", "可用卡密数量（按商品）", collectKamiStock, "product_id", "product")
+
+	registerCacheMetrics()
+	registerDBPoolMetrics()
+}
+
+// collectKamiStock 统计各商品的可用卡密数量
+func collectKamiStock() []metrics.Sample {
+	if !model.DBConnected || model.DB == nil {
+		return nil
+	}
+
+	var rows []struct {
+		ProductID uint
+		Count     int64
+	}
+	if err := model.DB.Model(&model.ManualKami{}).
+		Select("product_id, COUNT(*) AS count").
+		Where("status = ?", model.ManualKamiStatusAvailable).
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return nil
+	}
+
+	// 卡密为 0 的商品也输出，方便对售罄告警
+	var products []model.Product
+	model.DB.Select("id, name").Find(&products)
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ProductID] = row.Count
+	}
+
+	samples := make([]metrics.Sample, 0, len(products))
+	for _, p := range products {
+		samples = append(samples, metrics.Sample{
+			LabelValues: []string{strconv.FormatUint(uint64(p.ID), 10), p.Name},
+			Value:       float64(counts[p.ID]),
+		})
+	}
+	return samples
+}
+
+// registerCacheMetrics 注册缓存命中、未命中和故障转移指标
+func registerCacheMetrics() {
+	cacheMetric := func(get func(m *cache.CacheMetrics) int64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			manager := cache.GetCacheManager()
+			if manager == nil {
+				return nil
+			}
+			return metrics.Value(float64(get(manager.GetMetrics())))
+		}
+	}
+	metrics.NewCounterFunc("shop_cache_hits_total", "缓存命中次数", cacheMetric((*cache.CacheMetrics).GetHits))
+	metrics.NewCounterFunc("shop_cache_misses_total", "缓存未命中次数", cacheMetric((*cache.CacheMetrics).GetMisses))
+	metrics.NewCounterFunc("shop_cache_failovers_total", "Redis 故障转移到本地缓存的次数", cacheMetric((*cache.CacheMetrics).GetFailovers))
+	metrics.NewGaugeFunc("shop_cache_redis_healthy", "Redis 是否可用（未启用 Redis 时为 0）", func() []metrics.Sample {
+		manager := cache.GetCacheManager()
+		if manager == nil {
+			return nil
+		}
+		healthy := 0.0
+		if manager.IsRedisHealthy() {
+			healthy = 1
+		}
+		return metrics.Value(healthy)
+	})
+}
+
+// registerDBPoolMetrics 注册主数据库连接池指标
+func registerDBPoolMetrics() {
+	poolStats := func(fn func(s sql.DBStats) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			if !model.DBConnected || model.DB == nil {
+				return nil
+			}
+			sqlDB, err := model.DB.DB()
+			if err != nil {
+				return nil
+			}
+			return metrics.Value(fn(sqlDB.Stats()))
+		}
+	}
+	metrics.NewGaugeFunc("shop_db_connections", "数据库连接数（按状态）", func() []metrics.Sample {
+		if !model.DBConnected || model.DB == nil {
+			return nil
+		}
+		sqlDB, err := model.DB.DB()
+		if err != nil {
+			return nil
+		}
+		stats := sqlDB.Stats()
+		return []metrics.Sample{
+			{LabelValues: []string{"open"}, Value: float64(stats.OpenConnections)},
+			{LabelValues: []string{"in_use"}, Value: float64(stats.InUse)},
+			{LabelValues: []string{"idle"}, Value: float64(stats.Idle)},
+		}
+	}, "state")
+	metrics.NewGaugeFunc("shop_db_max_open_connections", "数据库最大连接数（0 表示不限制）",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	metrics.NewCounterFunc("shop_db_wait_total", "等待空闲连接的累计次数",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	metrics.NewCounterFunc("shop_db_wait_seconds_total", "等待空闲连接的累计耗时（秒）",
+		poolStats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -12,6 +13,9 @@ import (
 	"sync"
 	"time"
 
+	"user-frontend/internal/lifecycle"
+	"user-frontend/internal/service"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -453,6 +457,20 @@ func IPBlacklistMiddleware() gin.HandlerFunc {
 	}
 }
 
+// MaintenanceMiddleware 维护模式中间件
+// 数据库恢复等操作期间拒绝所有请求，返回 503 并提示稍后重试（维护状态经配置数据库在所有实例间共享）
+func MaintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status := service.GetMaintenanceStatus(); status.Active {
+			c.Header("Retry-After", "30")
+			RenderErrorPage(c, 503, "系统维护中（"+status.Reason+"），请稍后再试", 30)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // CleanupExpiredBlacklist 清理过期的黑名单
 func CleanupExpiredBlacklist() {
 	ipBlacklistMu.Lock()
@@ -550,16 +568,33 @@ func IPWhitelistMiddleware() gin.HandlerFunc {
 
 // ==================== 安全清理任务 ====================
 
-// StartSecurityCleanupTask 启动安全清理定时任务
+// StartSecurityCleanupTask 注册安全清理定时任务（随应用生命周期启动和停止）
 func StartSecurityCleanupTask() {
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		for range ticker.C {
-			CleanupExpiredCSRFTokens()
-			CleanupExpiredRateLimits()
-			CleanupExpiredBlacklist()
+	lifecycle.Register(lifecycle.Worker{
+		Name: "security-cleanup",
+		Run:  runSecurityCleanup,
+	})
+}
+
+// runSecurityCleanup 每5分钟清理过期的 CSRF 令牌、限流记录和黑名单，直到 ctx 取消，维护模式中跳过
+func runSecurityCleanup(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		done, ok := service.BeginBackgroundWork()
+		if !ok {
+			continue
 		}
-	}()
+		CleanupExpiredCSRFTokens()
+		CleanupExpiredRateLimits()
+		CleanupExpiredBlacklist()
+		done()
+	}
 }
 
 
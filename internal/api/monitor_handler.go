@@ -82,6 +82,7 @@ func AdminGetMonitorOverview(c *gin.Context) {
 		"memory":   MonitorSvc.GetMemoryStats(),
 		"health":   MonitorSvc.GetHealthStatus(),
 		"realtime": MonitorSvc.GetRealtimeStats(),
+		"api":      MonitorSvc.GetAPIStats(),
 	}
 
 	// 获取数据库统计
@@ -10,7 +10,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetProducts 获取商品列表
+// GetProducts 获取商品列表（支持关键词搜索、筛选、排序、分页与分面统计）
+// 查询参数：q、category_id、min_price、max_price、in_stock、sort、page、page_size、cursor
 func GetProducts(c *gin.Context) {
 	if !model.DBConnected {
 		c.JSON(500, gin.H{"success": false, "error": "数据库未连接"})
@@ -22,15 +23,54 @@ func GetProducts(c *gin.Context) {
 		return
 	}
 
-	products, err := ProductSvc.GetAllProducts(true)
+	categoryID, _ := strconv.ParseUint(c.Query("category_id"), 10, 32)
+	minPrice, _ := strconv.ParseFloat(c.Query("min_price"), 64)
+	maxPrice, _ := strconv.ParseFloat(c.Query("max_price"), 64)
+	inStock, _ := strconv.ParseBool(c.Query("in_stock"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := ProductSvc.SearchCatalog(service.CatalogQuery{
+		Keyword:    c.Query("q"),
+		CategoryID: uint(categoryID),
+		MinPrice:   minPrice,
+		MaxPrice:   maxPrice,
+		InStock:    inStock,
+		Sort:       c.Query("sort"),
+		Page:       page,
+		PageSize:   pageSize,
+		Cursor:     c.Query("cursor"),
+	})
+	if err == service.ErrInvalidCatalogCursor {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
+	// 登录用户附加按其价格等级计算的价格
+	if userID := c.GetUint("user_id"); userID > 0 && PricingSvc != nil && len(result.Products) > 0 {
+		products := make([]model.Product, len(result.Products))
+		for i := range result.Products {
+			products[i] = result.Products[i].Product
+		}
+		prices := PricingSvc.UnitPrices(products, PricingSvc.UserLevel(userID))
+		for i := range result.Products {
+			result.Products[i].UserPrice = prices[result.Products[i].ID]
+		}
+	}
+
 	c.JSON(200, gin.H{
-		"success":  true,
-		"products": products,
+		"success":     true,
+		"products":    result.Products,
+		"total":       result.Total,
+		"page":        result.Page,
+		"page_size":   result.PageSize,
+		"has_more":    result.HasMore,
+		"next_cursor": result.NextCursor,
+		"facets":      result.Facets,
 	})
 }
 
@@ -58,11 +98,33 @@ func GetProduct(c *gin.Context) {
 		c.JSON(404, gin.H{"success": false, "error": "商品不存在"})
 		return
 	}
+	ProductSvc.AttachVariants(product)
 
-	c.JSON(200, gin.H{
+	resp := gin.H{
 		"success": true,
 		"product": product,
-	})
+	}
+
+	// 当前用户（游客按零售）的成交单价与批发阶梯；多规格商品返回各规格的单件价格
+	if PricingSvc != nil {
+		level := PricingSvc.UserLevel(c.GetUint("user_id"))
+		if product.IsVariantParent() {
+			resp["pricing"] = gin.H{
+				"level":          level,
+				"variant_prices": PricingSvc.UnitPrices(product.Variants, level),
+			}
+		} else {
+			quote := PricingSvc.Quote(product, level, 1)
+			resp["pricing"] = gin.H{
+				"level":      quote.Level,
+				"list_price": quote.ListPrice,
+				"unit_price": quote.UnitPrice,
+				"tiers":      PricingSvc.Ladder(product, level),
+			}
+		}
+	}
+
+	c.JSON(200, resp)
 }
 
 // CreateOrder 创建订单
@@ -81,8 +143,9 @@ func CreateOrder(c *gin.Context) {
 	username := c.GetString("username")
 
 	var req struct {
-		ProductID uint `json:"product_id" binding:"required"`
-		Quantity  int  `json:"quantity"` // 购买数量，默认为1
+		ProductID uint   `json:"product_id" binding:"required"`
+		Quantity  int    `json:"quantity"` // 购买数量，默认为1
+		Currency  string `json:"currency"` // 期望支付币种，默认基础货币
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -96,7 +159,14 @@ func CreateOrder(c *gin.Context) {
 		quantity = 1
 	}
 
-	order, err := OrderSvc.CreateOrderWithQuantity(userID, username, req.ProductID, quantity, c.ClientIP())
+	order, err := OrderSvc.CreateOrderWithParams(&service.CreateOrderParams{
+		UserID:    userID,
+		Username:  username,
+		ProductID: req.ProductID,
+		Quantity:  quantity,
+		ClientIP:  c.ClientIP(),
+		Currency:  req.Currency,
+	})
 	if err != nil {
 		c.JSON(400, gin.H{"success": false, "error": err.Error()})
 		return
@@ -221,12 +291,18 @@ func PayPalCreatePayment(c *gin.Context) {
 		return
 	}
 
+	// 校验支付方式，锁定手续费与支付币种（PayPal配置的币种）
+	order, ok := lockOrderPaymentMethod(c, order, "paypal")
+	if !ok {
+		return
+	}
+
 	// 创建PayPal服务
-	paypalSvc := service.NewPayPalService(paypalConfig)
+	paypalSvc := service.NewPayPalService(paypalConfig).WithContext(c.Request.Context())
 
 	// 创建PayPal订单
 	description := order.ProductName + " - " + order.OrderNo
-	paypalOrder, err := paypalSvc.CreateOrder(order.OrderNo, order.Price, description)
+	paypalOrder, err := paypalSvc.CreateOrder(order.OrderNo, order.GetPayAmount(), description)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "创建PayPal订单失败: " + err.Error()})
 		return
@@ -302,7 +378,7 @@ func PayPalCapturePayment(c *gin.Context) {
 	}
 
 	// 创建PayPal服务
-	paypalSvc := service.NewPayPalService(paypalConfig)
+	paypalSvc := service.NewPayPalService(paypalConfig).WithContext(c.Request.Context())
 
 	// 捕获支付
 	captureResp, err := paypalSvc.CaptureOrder(req.PayPalOrderID)
@@ -324,7 +400,7 @@ func PayPalCapturePayment(c *gin.Context) {
 	}
 
 	// 处理订单支付（传递支付订单号）
-	order, err = OrderSvc.ProcessPayment(req.OrderNo, "PayPal", paymentNo)
+	order, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(req.OrderNo, "PayPal", paymentNo)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "处理订单失败: " + err.Error()})
 		return
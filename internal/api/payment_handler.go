@@ -1,6 +1,9 @@
 package api
 
 import (
+	"net"
+	"strings"
+
 	"user-frontend/internal/config"
 	"user-frontend/internal/model"
 	"user-frontend/internal/service"
@@ -8,6 +11,58 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// lockOrderPaymentMethod 按支付方式校验路由规则，锁定手续费、支付币种和汇率
+// 失败时直接写入错误响应并返回 false
+func lockOrderPaymentMethod(c *gin.Context, order *model.Order, paymentMethod string) (*model.Order, bool) {
+	locked, err := OrderSvc.ApplyPaymentMethod(order, paymentMethod, clientCountry(c))
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return nil, false
+	}
+	return locked, true
+}
+
+// clientCountry 获取客户端国家代码，未知时返回空
+// 只读取配置的请求头（COUNTRY_HEADER，由前置 CDN/反向代理注入），配置了代理地址
+// （COUNTRY_HEADER_PROXIES）时还要求请求直接来自这些代理，避免客户端伪造国家绕过路由和手续费规则
+func clientCountry(c *gin.Context) string {
+	envCfg := config.GlobalEnvConfig
+	if envCfg == nil || envCfg.CountryHeader == "" {
+		return ""
+	}
+	if len(envCfg.CountryHeaderProxies) > 0 {
+		host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if err != nil {
+			host = c.Request.RemoteAddr
+		}
+		peer := net.ParseIP(host)
+		if peer == nil || !ipAllowed(peer, envCfg.CountryHeaderProxies) {
+			return ""
+		}
+	}
+
+	country := strings.ToUpper(strings.TrimSpace(c.GetHeader(envCfg.CountryHeader)))
+	// XX/T1 为 Cloudflare 的未知地区/Tor 标记
+	if len(country) == 2 && country != "XX" && country != "T1" {
+		return country
+	}
+	return ""
+}
+
+// convertRechargePayAmount 将充值金额（基础货币）换算为支付方式的扣款币种
+// 失败时直接写入错误响应并返回 false
+func convertRechargePayAmount(c *gin.Context, amount float64, paymentMethod string) (float64, bool) {
+	if CurrencySvc == nil {
+		return amount, true
+	}
+	converted, err := CurrencySvc.Convert(amount, CurrencySvc.GetBaseCurrency(), CurrencySvc.PaymentMethodCurrency(paymentMethod))
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "计算支付金额失败: " + err.Error()})
+		return 0, false
+	}
+	return converted, true
+}
+
 // ==========================================
 //         支付宝当面付 API
 // ==========================================
@@ -60,12 +115,18 @@ func AlipayCreatePayment(c *gin.Context) {
 		return
 	}
 
+	// 校验支付方式，锁定手续费与支付币种（人民币）
+	order, ok := lockOrderPaymentMethod(c, order, "alipay")
+	if !ok {
+		return
+	}
+
 	// 创建支付宝服务
-	alipaySvc := service.NewAlipayService(alipayCfg)
+	alipaySvc := service.NewAlipayService(alipayCfg).WithContext(c.Request.Context())
 
 	// 创建支付宝订单，获取二维码
 	description := order.ProductName + " - " + order.OrderNo
-	qrCode, err := alipaySvc.CreatePreOrder(order.OrderNo, order.Price, description)
+	qrCode, err := alipaySvc.CreatePreOrder(order.OrderNo, order.GetPayAmount(), description)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "创建支付宝订单失败: " + err.Error()})
 		return
@@ -91,7 +152,7 @@ func AlipayNotify(c *gin.Context) {
 		return
 	}
 
-	alipaySvc := service.NewAlipayService(alipayCfg)
+	alipaySvc := service.NewAlipayService(alipayCfg).WithContext(c.Request.Context())
 
 	// 解析通知参数
 	if err := c.Request.ParseForm(); err != nil {
@@ -107,7 +168,7 @@ func AlipayNotify(c *gin.Context) {
 	}
 
 	// 处理订单支付
-	_, err = OrderSvc.ProcessPayment(orderNo, "支付宝", tradeNo)
+	_, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(orderNo, "支付宝", tradeNo)
 	if err != nil {
 		// 记录错误但仍返回成功，避免重复通知
 		c.String(200, "success")
@@ -157,7 +218,7 @@ func AlipayQueryStatus(c *gin.Context) {
 	}
 
 	// 查询支付宝订单状态
-	alipaySvc := service.NewAlipayService(alipayCfg)
+	alipaySvc := service.NewAlipayService(alipayCfg).WithContext(c.Request.Context())
 	paid, tradeNo, err := alipaySvc.QueryOrder(orderNo)
 	if err != nil {
 		c.JSON(200, gin.H{"success": true, "paid": false})
@@ -166,7 +227,7 @@ func AlipayQueryStatus(c *gin.Context) {
 
 	// 如果已支付，处理订单
 	if paid {
-		order, err = OrderSvc.ProcessPayment(orderNo, "支付宝", tradeNo)
+		order, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(orderNo, "支付宝", tradeNo)
 		if err != nil {
 			c.JSON(500, gin.H{"success": false, "error": "处理订单失败"})
 			return
@@ -231,12 +292,18 @@ func WechatCreatePayment(c *gin.Context) {
 		return
 	}
 
+	// 校验支付方式，锁定手续费与支付币种（人民币）
+	order, ok := lockOrderPaymentMethod(c, order, "wechat")
+	if !ok {
+		return
+	}
+
 	// 创建微信支付服务
-	wechatSvc := service.NewWechatPayService(wechatCfg)
+	wechatSvc := service.NewWechatPayService(wechatCfg).WithContext(c.Request.Context())
 
 	// 创建微信支付订单，获取二维码
 	description := order.ProductName
-	qrCode, err := wechatSvc.CreateNativeOrder(order.OrderNo, order.Price, description)
+	qrCode, err := wechatSvc.CreateNativeOrder(order.OrderNo, order.GetPayAmount(), description)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "创建微信支付订单失败: " + err.Error()})
 		return
@@ -262,7 +329,7 @@ func WechatNotify(c *gin.Context) {
 		return
 	}
 
-	wechatSvc := service.NewWechatPayService(wechatCfg)
+	wechatSvc := service.NewWechatPayService(wechatCfg).WithContext(c.Request.Context())
 
 	// 验证签名并获取订单信息
 	orderNo, tradeNo, err := wechatSvc.VerifyNotify(c.Request)
@@ -272,7 +339,7 @@ func WechatNotify(c *gin.Context) {
 	}
 
 	// 处理订单支付
-	_, err = OrderSvc.ProcessPayment(orderNo, "微信支付", tradeNo)
+	_, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(orderNo, "微信支付", tradeNo)
 	if err != nil {
 		// 记录错误但仍返回成功
 	}
@@ -320,7 +387,7 @@ func WechatQueryStatus(c *gin.Context) {
 	}
 
 	// 查询微信支付订单状态
-	wechatSvc := service.NewWechatPayService(wechatCfg)
+	wechatSvc := service.NewWechatPayService(wechatCfg).WithContext(c.Request.Context())
 	paid, tradeNo, err := wechatSvc.QueryOrder(orderNo)
 	if err != nil {
 		c.JSON(200, gin.H{"success": true, "paid": false})
@@ -329,7 +396,7 @@ func WechatQueryStatus(c *gin.Context) {
 
 	// 如果已支付，处理订单
 	if paid {
-		order, err = OrderSvc.ProcessPayment(orderNo, "微信支付", tradeNo)
+		order, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(orderNo, "微信支付", tradeNo)
 		if err != nil {
 			c.JSON(500, gin.H{"success": false, "error": "处理订单失败"})
 			return
@@ -394,12 +461,18 @@ func YiPayCreatePayment(c *gin.Context) {
 		return
 	}
 
+	// 校验支付方式，锁定手续费与支付币种（人民币）
+	order, ok := lockOrderPaymentMethod(c, order, "yipay")
+	if !ok {
+		return
+	}
+
 	// 创建易支付服务
 	yipaySvc := service.NewYiPayService(yipayCfg)
 
 	// 创建易支付订单，获取支付URL
 	productName := order.ProductName
-	payURL, err := yipaySvc.CreateOrder(order.OrderNo, order.Price, productName)
+	payURL, err := yipaySvc.CreateOrder(order.OrderNo, order.GetPayAmount(), productName)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "创建易支付订单失败: " + err.Error()})
 		return
@@ -435,7 +508,7 @@ func YiPayNotify(c *gin.Context) {
 	}
 
 	// 处理订单支付
-	_, err = OrderSvc.ProcessPayment(orderNo, "易支付", tradeNo)
+	_, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(orderNo, "易支付", tradeNo)
 	if err != nil {
 		// 记录错误但仍返回成功
 	}
@@ -494,7 +567,7 @@ func YiPayCallback(c *gin.Context) {
 	}
 
 	// 处理订单支付
-	order, err = OrderSvc.ProcessPayment(req.OutTradeNo, "易支付", req.TradeNo)
+	order, err = OrderSvc.WithContext(c.Request.Context()).ProcessPayment(req.OutTradeNo, "易支付", req.TradeNo)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "处理订单失败: " + err.Error()})
 		return
@@ -569,6 +642,12 @@ func YiPayCreateRechargePayment(c *gin.Context) {
 	if payAmount <= 0 {
 		payAmount = order.Amount
 	}
+	// 换算为易支付扣款币种（人民币）
+	payAmount, ok := convertRechargePayAmount(c, payAmount, "yipay")
+	if !ok {
+		return
+	}
+
 	productName := "余额充值"
 	payURL, err := yipaySvc.CreateOrder(order.RechargeNo, payAmount, productName)
 	if err != nil {
@@ -728,7 +807,7 @@ func AlipayCreateRechargePayment(c *gin.Context) {
 	}
 
 	// 创建支付宝服务
-	alipaySvc := service.NewAlipayService(alipayCfg)
+	alipaySvc := service.NewAlipayService(alipayCfg).WithContext(c.Request.Context())
 
 	// 使用实际支付金额
 	payAmount := order.PayAmount
@@ -736,6 +815,12 @@ func AlipayCreateRechargePayment(c *gin.Context) {
 		payAmount = order.Amount
 	}
 
+	// 换算为支付宝扣款币种（人民币）
+	payAmount, ok := convertRechargePayAmount(c, payAmount, "alipay")
+	if !ok {
+		return
+	}
+
 	// 创建支付宝订单，获取二维码
 	description := "余额充值 - " + order.RechargeNo
 	qrCode, err := alipaySvc.CreatePreOrder(order.RechargeNo, payAmount, description)
@@ -793,7 +878,7 @@ func AlipayRechargeQueryStatus(c *gin.Context) {
 	}
 
 	// 查询支付宝订单状态
-	alipaySvc := service.NewAlipayService(alipayCfg)
+	alipaySvc := service.NewAlipayService(alipayCfg).WithContext(c.Request.Context())
 	paid, tradeNo, err := alipaySvc.QueryOrder(rechargeNo)
 	if err != nil {
 		c.JSON(200, gin.H{"success": true, "paid": false})
@@ -872,7 +957,7 @@ func WechatCreateRechargePayment(c *gin.Context) {
 	}
 
 	// 创建微信支付服务
-	wechatSvc := service.NewWechatPayService(wechatCfg)
+	wechatSvc := service.NewWechatPayService(wechatCfg).WithContext(c.Request.Context())
 
 	// 使用实际支付金额
 	payAmount := order.PayAmount
@@ -880,6 +965,12 @@ func WechatCreateRechargePayment(c *gin.Context) {
 		payAmount = order.Amount
 	}
 
+	// 换算为微信支付扣款币种（人民币）
+	payAmount, ok := convertRechargePayAmount(c, payAmount, "wechat")
+	if !ok {
+		return
+	}
+
 	// 创建微信支付订单，获取二维码
 	description := "余额充值"
 	qrCode, err := wechatSvc.CreateNativeOrder(order.RechargeNo, payAmount, description)
@@ -937,7 +1028,7 @@ func WechatRechargeQueryStatus(c *gin.Context) {
 	}
 
 	// 查询微信支付订单状态
-	wechatSvc := service.NewWechatPayService(wechatCfg)
+	wechatSvc := service.NewWechatPayService(wechatCfg).WithContext(c.Request.Context())
 	paid, tradeNo, err := wechatSvc.QueryOrder(rechargeNo)
 	if err != nil {
 		c.JSON(200, gin.H{"success": true, "paid": false})
@@ -1016,7 +1107,7 @@ func PayPalCreateRechargePayment(c *gin.Context) {
 	}
 
 	// 创建 PayPal 服务
-	paypalSvc := service.NewPayPalService(paypalConfig)
+	paypalSvc := service.NewPayPalService(paypalConfig).WithContext(c.Request.Context())
 
 	// 使用实际支付金额
 	payAmount := order.PayAmount
@@ -1024,6 +1115,12 @@ func PayPalCreateRechargePayment(c *gin.Context) {
 		payAmount = order.Amount
 	}
 
+	// 换算为 PayPal 扣款币种
+	payAmount, ok := convertRechargePayAmount(c, payAmount, "paypal")
+	if !ok {
+		return
+	}
+
 	// 创建 PayPal 订单
 	description := "余额充值 - " + order.RechargeNo
 	paypalOrder, err := paypalSvc.CreateOrder(order.RechargeNo, payAmount, description)
@@ -1102,7 +1199,7 @@ func PayPalCaptureRechargePayment(c *gin.Context) {
 	}
 
 	// 创建 PayPal 服务
-	paypalSvc := service.NewPayPalService(paypalConfig)
+	paypalSvc := service.NewPayPalService(paypalConfig).WithContext(c.Request.Context())
 
 	// 捕获支付
 	captureResult, err := paypalSvc.CaptureOrder(req.PayPalOrderID)
@@ -1191,6 +1288,12 @@ func StripeCreateRechargeCheckoutSession(c *gin.Context) {
 		payAmount = order.Amount
 	}
 
+	// 换算为 Stripe 扣款币种
+	payAmount, ok := convertRechargePayAmount(c, payAmount, "stripe")
+	if !ok {
+		return
+	}
+
 	// 获取基础URL
 	baseURL := c.Request.Header.Get("Origin")
 	if baseURL == "" {
@@ -1206,7 +1309,7 @@ func StripeCreateRechargeCheckoutSession(c *gin.Context) {
 	successURL := baseURL + "/payment/result?type=recharge&recharge_no=" + order.RechargeNo + "&status=success"
 	cancelURL := baseURL + "/payment?type=recharge&recharge_no=" + order.RechargeNo
 
-	session, err := StripeSvc.CreateCheckoutSession(
+	session, err := StripeSvc.WithContext(c.Request.Context()).CreateCheckoutSession(
 		order.RechargeNo,
 		int64(payAmount*100), // 转换为分
 		productName,
@@ -1280,15 +1383,26 @@ func USDTCreateRechargePayment(c *gin.Context) {
 		payAmount = order.Amount
 	}
 
-	// 创建 USDT 支付
+	// 创建 USDT 支付（手动/链上模式直接以 USDT 计价，需先按汇率换算）
+	payCurrency := "CNY"
+	if CurrencySvc != nil {
+		payCurrency = CurrencySvc.GetBaseCurrency()
+		if CurrencySvc.PaymentMethodCurrency("usdt") == model.CurrencyUSDT {
+			if usdtAmount, err := CurrencySvc.Convert(payAmount, payCurrency, model.CurrencyUSDT); err == nil {
+				payAmount = usdtAmount
+				payCurrency = model.CurrencyUSDT
+			}
+		}
+	}
 	paymentReq := &service.USDTPaymentRequest{
 		OrderNo:     order.RechargeNo,
 		Amount:      payAmount,
-		Currency:    "CNY",
+		Currency:    payCurrency,
 		Description: "余额充值",
+		Kind:        model.USDTPaymentKindRecharge,
 	}
 
-	payment, err := USDTSvc.CreatePayment(paymentReq)
+	payment, err := USDTSvc.WithContext(c.Request.Context()).CreatePayment(paymentReq)
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "创建 USDT 订单失败: " + err.Error()})
 		return
@@ -1344,7 +1458,7 @@ func USDTGetRechargePaymentStatus(c *gin.Context) {
 	}
 
 	// 查询 USDT 支付状态
-	status, err := USDTSvc.GetPaymentStatus(paymentID)
+	status, err := USDTSvc.WithContext(c.Request.Context()).GetPaymentStatus(paymentID)
 	if err != nil {
 		c.JSON(200, gin.H{
 			"success":     true,
@@ -0,0 +1,200 @@
+// Package api 提供 HTTP API 处理器
+// payment_route_handler.go - 支付方式路由规则与手续费 API
+package api
+
+import (
+	"strconv"
+
+	"user-frontend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paymentRuleRequest 路由规则请求参数
+type paymentRuleRequest struct {
+	Name        string  `json:"name"`
+	Method      string  `json:"method" binding:"required"`
+	Action      string  `json:"action" binding:"required"`
+	MinAmount   float64 `json:"min_amount"`
+	MaxAmount   float64 `json:"max_amount"`
+	CategoryIDs string  `json:"category_ids"`
+	UserLevels  string  `json:"user_levels"`
+	Countries   string  `json:"countries"`
+	Priority    int     `json:"priority"`
+	Enabled     *bool   `json:"enabled"`
+	Remark      string  `json:"remark"`
+}
+
+// AdminGetPaymentRules 获取支付路由规则列表
+// GET /api/admin/payment/rules
+func AdminGetPaymentRules(c *gin.Context) {
+	if PaymentRouteSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	rules, err := PaymentRouteSvc.GetRules()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取规则失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": rules})
+}
+
+// AdminSavePaymentRule 创建或更新支付路由规则
+// POST /api/admin/payment/rule
+// PUT  /api/admin/payment/rule/:id
+func AdminSavePaymentRule(c *gin.Context) {
+	if PaymentRouteSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req paymentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误: " + err.Error()})
+		return
+	}
+
+	rule := &model.PaymentRule{
+		Name:        req.Name,
+		Method:      req.Method,
+		Action:      req.Action,
+		MinAmount:   req.MinAmount,
+		MaxAmount:   req.MaxAmount,
+		CategoryIDs: req.CategoryIDs,
+		UserLevels:  req.UserLevels,
+		Countries:   req.Countries,
+		Priority:    req.Priority,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		Remark:      req.Remark,
+	}
+
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			c.JSON(400, gin.H{"success": false, "error": "无效的规则ID"})
+			return
+		}
+		rule.ID = uint(id)
+	}
+
+	if err := PaymentRouteSvc.SaveRule(rule); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "save", "payment_rule", strconv.FormatUint(uint64(rule.ID), 10), rule, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": rule})
+}
+
+// AdminDeletePaymentRule 删除支付路由规则
+// DELETE /api/admin/payment/rule/:id
+func AdminDeletePaymentRule(c *gin.Context) {
+	if PaymentRouteSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的规则ID"})
+		return
+	}
+
+	if err := PaymentRouteSvc.DeleteRule(uint(id)); err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "删除失败"})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "payment_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
+}
+
+// AdminGetPaymentFees 获取支付方式手续费配置
+// GET /api/admin/payment/fees
+func AdminGetPaymentFees(c *gin.Context) {
+	if PaymentRouteSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	fees, err := PaymentRouteSvc.GetFees()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取手续费配置失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": fees})
+}
+
+// AdminSavePaymentFee 保存支付方式手续费（正数为附加费，负数为优惠）
+// POST /api/admin/payment/fee
+func AdminSavePaymentFee(c *gin.Context) {
+	if PaymentRouteSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req struct {
+		Method  string  `json:"method" binding:"required"`
+		FeeType string  `json:"fee_type" binding:"required"`
+		Value   float64 `json:"value"`
+		MinFee  float64 `json:"min_fee"`
+		MaxFee  float64 `json:"max_fee"`
+		Enabled *bool   `json:"enabled"`
+		Remark  string  `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误: " + err.Error()})
+		return
+	}
+
+	fee := &model.PaymentFee{
+		Method:  req.Method,
+		FeeType: req.FeeType,
+		Value:   req.Value,
+		MinFee:  req.MinFee,
+		MaxFee:  req.MaxFee,
+		Enabled: req.Enabled == nil || *req.Enabled,
+		Remark:  req.Remark,
+	}
+	if err := PaymentRouteSvc.SaveFee(fee); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "save", "payment_fee", fee.Method, fee, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": fee})
+}
+
+// AdminDeletePaymentFee 删除支付方式手续费
+// DELETE /api/admin/payment/fee/:method
+func AdminDeletePaymentFee(c *gin.Context) {
+	if PaymentRouteSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	method := c.Param("method")
+	if err := PaymentRouteSvc.DeleteFee(method); err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "删除失败"})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "payment_fee", method, nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,10 +34,7 @@ func PayPalTestConnection(c *gin.Context) {
 	}
 
 	// 测试获取访问令牌
-	baseURL := "https://api-m.paypal.com"
-	if paypalCfg.Sandbox {
-		baseURL = "https://api-m.sandbox.paypal.com"
-	}
+	baseURL := service.PayPalAPIBaseURL(&paypalCfg)
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	req, err := http.NewRequest("POST", baseURL+"/v1/oauth2/token", bytes.NewBufferString("grant_type=client_credentials"))
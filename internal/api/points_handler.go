@@ -214,7 +214,7 @@ func AdminCreatePointsRule(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "创建积分规则", "points_rule", "", "创建积分规则: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "创建积分规则", "points_rule", "", "创建积分规则: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "创建成功", "rule": rule})
 }
@@ -264,7 +264,7 @@ func AdminUpdatePointsRule(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "更新积分规则", "points_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "更新积分规则", "points_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "更新成功"})
 }
@@ -289,7 +289,7 @@ func AdminDeletePointsRule(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "删除积分规则", "points_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "删除积分规则", "points_rule", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
 }
@@ -322,7 +322,7 @@ func AdminAdjustPoints(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "调整用户积分", "user_points", "", req.Remark, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "调整用户积分", "user_points", "", req.Remark, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "调整成功"})
 }
@@ -0,0 +1,117 @@
+// Package api 提供 HTTP API 处理器
+// pricing_handler.go - 批发阶梯价与用户价格等级 API
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"user-frontend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminGetPriceLevels 获取各用户等级的折扣设置
+// GET /api/admin/price-levels
+func AdminGetPriceLevels(c *gin.Context) {
+	if PricingSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	levels, err := PricingSvc.GetPriceLevels()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取价格等级失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": levels})
+}
+
+// AdminSetPriceLevel 设置用户等级折扣
+// PUT /api/admin/price-level/:level
+func AdminSetPriceLevel(c *gin.Context) {
+	if PricingSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req struct {
+		Discount float64 `json:"discount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	level := c.Param("level")
+	if err := PricingSvc.SetLevelDiscount(level, req.Discount); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update_price_level", "price_level", level,
+			fmt.Sprintf("折扣 %.2f%%", req.Discount), c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "价格等级已更新"})
+}
+
+// AdminGetPriceTiers 获取商品阶梯价
+// GET /api/admin/product/:id/price-tiers
+func AdminGetPriceTiers(c *gin.Context) {
+	if PricingSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
+	tiers, err := PricingSvc.GetTiers(uint(id))
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取阶梯价失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": tiers})
+}
+
+// AdminSavePriceTiers 保存商品阶梯价（整体替换，传空列表表示清除）
+// PUT /api/admin/product/:id/price-tiers
+func AdminSavePriceTiers(c *gin.Context) {
+	if PricingSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
+	var req struct {
+		Tiers []model.ProductPriceTier `json:"tiers"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	if err := PricingSvc.SaveTiers(uint(id), req.Tiers); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update_price_tiers", "product", c.Param("id"),
+			fmt.Sprintf("阶梯价 %d 条", len(req.Tiers)), c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "data": req.Tiers})
+}
@@ -2,12 +2,14 @@ package api
 
 import (
 	"fmt"
-	"io"
-	"os"
+	"mime"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"user-frontend/internal/model"
+	"user-frontend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,25 +22,20 @@ func GetProductDetailFile(c *gin.Context) {
 		return
 	}
 
-	productID := c.Param("id")
-	
-	// 构建文件路径
-	filePath := fmt.Sprintf("./Product/%s/detail.md", productID)
-	
-	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		c.JSON(200, gin.H{"success": true, "content": "", "exists": false})
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
 		return
 	}
-	
-	// 读取文件内容
-	content, err := os.ReadFile(filePath)
+
+	// 读取详情文件内容
+	content, exists, err := service.ReadProductDetail(uint(productID))
 	if err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "读取文件失败"})
 		return
 	}
-	
-	c.JSON(200, gin.H{"success": true, "content": string(content), "exists": true})
+
+	c.JSON(200, gin.H{"success": true, "content": content, "exists": exists})
 }
 
 // SaveProductDetailFile 保存商品详情文件
@@ -49,31 +46,27 @@ func SaveProductDetailFile(c *gin.Context) {
 		return
 	}
 
-	productID := c.Param("id")
-	
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
 	var req struct {
 		Content string `json:"content"`
 	}
-	
+
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
 		return
 	}
-	
-	// 创建目录
-	dirPath := fmt.Sprintf("./Product/%s", productID)
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		c.JSON(500, gin.H{"success": false, "error": "创建目录失败"})
-		return
-	}
-	
+
 	// 保存文件
-	filePath := filepath.Join(dirPath, "detail.md")
-	if err := os.WriteFile(filePath, []byte(req.Content), 0644); err != nil {
+	if err := service.SaveProductDetail(uint(productID), req.Content); err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "保存文件失败"})
 		return
 	}
-	
+
 	c.JSON(200, gin.H{"success": true, "message": "保存成功"})
 }
 
@@ -85,8 +78,12 @@ func UploadProductDetailImage(c *gin.Context) {
 		return
 	}
 
-	productID := c.Param("id")
-	
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "无效的商品ID"})
+		return
+	}
+
 	// 获取上传的文件
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
@@ -94,41 +91,23 @@ func UploadProductDetailImage(c *gin.Context) {
 		return
 	}
 	defer file.Close()
-	
+
 	// 验证文件扩展名
-	ext := filepath.Ext(header.Filename)
+	ext := strings.ToLower(filepath.Ext(header.Filename))
 	allowedExts := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
 	if !allowedExts[ext] {
 		c.JSON(400, gin.H{"success": false, "error": "不支持的图片格式，仅支持 jpg/jpeg/png/gif/webp"})
 		return
 	}
-	
-	// 创建目录
-	dirPath := fmt.Sprintf("./Product/%s/detail_images", productID)
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		c.JSON(500, gin.H{"success": false, "error": "创建目录失败"})
-		return
-	}
-	
-	// 生成文件名
-	filename := fmt.Sprintf("%d%s", generateTimestamp(), ext)
-	filePath := filepath.Join(dirPath, filename)
-	
-	// 保存文件
-	dst, err := os.Create(filePath)
+
+	// 保存到对象存储
+	filename := fmt.Sprintf("detail_images/%d%s", generateTimestamp(), ext)
+	imageURL, err := service.SaveProductFile(uint(productID), filename, file, header.Size, mime.TypeByExtension(ext))
 	if err != nil {
-		c.JSON(500, gin.H{"success": false, "error": "创建文件失败"})
-		return
-	}
-	defer dst.Close()
-	
-	if _, err := io.Copy(dst, file); err != nil {
 		c.JSON(500, gin.H{"success": false, "error": "保存文件失败"})
 		return
 	}
-	
-	// 返回图片URL
-	imageURL := fmt.Sprintf("/product-files/%s/detail_images/%s", productID, filename)
+
 	c.JSON(200, gin.H{"success": true, "url": imageURL})
 }
 
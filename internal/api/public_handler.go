@@ -1,8 +1,11 @@
 package api
 
 import (
+	"strconv"
+
 	"user-frontend/internal/config"
 	"user-frontend/internal/model"
+	"user-frontend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -99,35 +102,88 @@ func QueryOrderPublic(c *gin.Context) {
 
 // GetPaymentMethods 获取可用的支付方式（公开）
 // 返回当前启用的支付方式列表，不包含敏感配置信息
+// 可选参数 order_no（需登录且为本人订单）或 amount/product_id，用于按路由规则过滤并计算手续费
 func GetPaymentMethods(c *gin.Context) {
 	paymentCfg := config.GlobalConfig.PaymentConfig
 
+	methods := gin.H{
+		model.PayMethodPayPal: gin.H{
+			"enabled": paymentCfg.PayPal.Enabled,
+			"sandbox": paymentCfg.PayPal.Sandbox,
+		},
+		model.PayMethodStripe: gin.H{
+			"enabled": paymentCfg.StripeEnabled,
+		},
+		model.PayMethodAlipayF2F: gin.H{
+			"enabled": paymentCfg.AlipayF2F.Enabled,
+		},
+		model.PayMethodWechatPay: gin.H{
+			"enabled": paymentCfg.WechatPay.Enabled,
+		},
+		model.PayMethodYiPay: gin.H{
+			"enabled": paymentCfg.YiPay.Enabled,
+		},
+		model.PayMethodUSDT: gin.H{
+			"enabled": paymentCfg.USDTEnabled,
+			"network": paymentCfg.USDTNetwork,
+		},
+		model.PayMethodBalance: gin.H{
+			"enabled": true, // 余额支付始终可用
+		},
+	}
+
+	if PaymentRouteSvc != nil && model.DBConnected {
+		applyPaymentRoutes(c, methods)
+	}
+
 	c.JSON(200, gin.H{
 		"success": true,
-		"methods": gin.H{
-			"paypal": gin.H{
-				"enabled": paymentCfg.PayPal.Enabled,
-				"sandbox": paymentCfg.PayPal.Sandbox,
-			},
-			"stripe": gin.H{
-				"enabled": paymentCfg.StripeEnabled,
-			},
-			"alipay_f2f": gin.H{
-				"enabled": paymentCfg.AlipayF2F.Enabled,
-			},
-			"wechat_pay": gin.H{
-				"enabled": paymentCfg.WechatPay.Enabled,
-			},
-			"yi_pay": gin.H{
-				"enabled": paymentCfg.YiPay.Enabled,
-			},
-			"usdt": gin.H{
-				"enabled": paymentCfg.USDTEnabled,
-				"network": paymentCfg.USDTNetwork,
-			},
-			"balance": gin.H{
-				"enabled": true, // 余额支付始终可用
-			},
-		},
+		"methods": methods,
 	})
 }
+
+// applyPaymentRoutes 按路由规则过滤支付方式并附加手续费信息
+func applyPaymentRoutes(c *gin.Context, methods gin.H) {
+	ctx := &service.PaymentRouteContext{Country: clientCountry(c)}
+
+	if orderNo := c.Query("order_no"); orderNo != "" && OrderSvc != nil {
+		// 仅本人订单可按订单信息路由
+		if order, err := OrderSvc.ValidateOrderOwnership(orderNo, c.GetUint("user_id")); err == nil {
+			ctx = OrderSvc.BuildPaymentRouteContext(order, ctx.Country)
+		}
+	} else {
+		ctx.Amount, _ = strconv.ParseFloat(c.Query("amount"), 64)
+		if productID, err := strconv.ParseUint(c.Query("product_id"), 10, 32); err == nil && ProductSvc != nil {
+			if product, err := ProductSvc.GetProductByID(uint(productID)); err == nil {
+				ctx.CategoryID = product.CategoryID
+			}
+		}
+		if userID := c.GetUint("user_id"); userID > 0 && UserSvc != nil {
+			if user, err := UserSvc.GetUserByID(userID); err == nil {
+				ctx.UserLevel = user.GetLevel()
+			}
+		}
+	}
+
+	codes := make([]string, 0, len(methods))
+	for code := range methods {
+		codes = append(codes, code)
+	}
+	allowed := PaymentRouteSvc.FilterMethods(ctx, codes)
+
+	for code, info := range methods {
+		entry := info.(gin.H)
+		entry["enabled"] = entry["enabled"].(bool) && allowed[code]
+		if fee := PaymentRouteSvc.GetFee(code); fee != nil {
+			feeInfo := gin.H{
+				"type":   fee.FeeType,
+				"value":  fee.Value,
+				"remark": fee.Remark,
+			}
+			if ctx.Amount > 0 {
+				feeInfo["amount"] = PaymentRouteSvc.CalculateFee(code, ctx.Amount)
+			}
+			entry["fee"] = feeInfo
+		}
+	}
+}
@@ -121,7 +121,7 @@ func AdminCreateRechargePromo(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "创建充值优惠活动", "recharge_promo", strconv.FormatUint(uint64(promo.ID), 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "创建充值优惠活动", "recharge_promo", strconv.FormatUint(uint64(promo.ID), 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "data": promo})
@@ -216,7 +216,7 @@ func AdminUpdateRechargePromo(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "更新充值优惠活动", "recharge_promo", strconv.FormatUint(id, 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "更新充值优惠活动", "recharge_promo", strconv.FormatUint(id, 10), req, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "data": promo})
@@ -243,7 +243,7 @@ func AdminDeleteRechargePromo(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername.(string), "删除充值优惠活动", "recharge_promo", strconv.FormatUint(id, 10), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "删除充值优惠活动", "recharge_promo", strconv.FormatUint(id, 10), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
@@ -1,6 +1,10 @@
 package api
 
 import (
+	"net/url"
+
+	"user-frontend/internal/service"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -183,3 +187,81 @@ func GetRenewalStats(c *gin.Context) {
 		},
 	})
 }
+
+// CreateRenewalOrder 创建续费订单
+// POST /api/user/renewal/create
+// 续费订单购买原订单的同款商品，支付完成后在当前到期时间基础上顺延，卡密不变
+func CreateRenewalOrder(c *gin.Context) {
+	if OrderSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req struct {
+		OrderNo  string `json:"order_no" binding:"required"` // 要续费的订单号
+		Periods  int    `json:"periods"`                     // 续费周期数，默认1
+		Currency string `json:"currency"`                    // 期望支付币种
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	order, err := OrderSvc.CreateRenewalOrder(&service.CreateRenewalParams{
+		UserID:   c.GetUint("user_id"),
+		Username: c.GetString("username"),
+		OrderNo:  req.OrderNo,
+		Periods:  req.Periods,
+		ClientIP: c.ClientIP(),
+		Currency: req.Currency,
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success":  true,
+		"message":  "续费订单创建成功",
+		"order_no": order.OrderNo,
+		"order":    order,
+	})
+}
+
+// RenewalOneClick 一键续费（续费提醒邮件中的链接）
+// GET /renew/:order_no?token=xxx
+// 校验签名令牌后创建（或复用）续费订单并跳转到支付页面
+func RenewalOneClick(c *gin.Context) {
+	if OrderSvc == nil || UserSvc == nil {
+		RenderErrorPage(c, 503, "服务未初始化", 0)
+		return
+	}
+
+	orderNo := c.Param("order_no")
+	userID, err := service.VerifyRenewalToken(orderNo, c.Query("token"))
+	if err != nil {
+		RenderErrorPage(c, 400, err.Error(), 0)
+		return
+	}
+
+	user, err := UserSvc.GetUserByID(userID)
+	if err != nil {
+		RenderErrorPage(c, 404, "用户不存在", 0)
+		return
+	}
+
+	order, err := OrderSvc.CreateRenewalOrder(&service.CreateRenewalParams{
+		UserID:   user.ID,
+		Username: user.Username,
+		OrderNo:  orderNo,
+		Periods:  1,
+		ClientIP: c.ClientIP(),
+	})
+	if err != nil {
+		RenderErrorPage(c, 400, err.Error(), 0)
+		return
+	}
+
+	c.Redirect(302, "/payment?order_no="+url.QueryEscape(order.OrderNo))
+}
@@ -39,7 +39,7 @@ func CreateProductReview(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "create_review", "product_review", strconv.Itoa(int(review.ID)), "创建商品评价", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "create_review", "product_review", strconv.Itoa(int(review.ID)), "创建商品评价", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "data": review})
@@ -237,7 +237,7 @@ func AdminReplyReview(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "reply_review", "product_review", strconv.Itoa(int(reviewID)), "回复商品评价", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "reply_review", "product_review", strconv.Itoa(int(reviewID)), "回复商品评价", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "回复成功"})
@@ -278,7 +278,7 @@ func AdminUpdateReviewStatus(c *gin.Context) {
 		action = "show_review"
 	}
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, action, "product_review", strconv.Itoa(int(reviewID)), "更新评价状态", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, action, "product_review", strconv.Itoa(int(reviewID)), "更新评价状态", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "更新成功"})
@@ -306,7 +306,7 @@ func AdminDeleteReview(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "delete_review", "product_review", strconv.Itoa(int(reviewID)), "删除商品评价", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "delete_review", "product_review", strconv.Itoa(int(reviewID)), "删除商品评价", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
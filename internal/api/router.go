@@ -11,18 +11,30 @@ import (
 
 // RegisterRoutes 注册所有路由
 func RegisterRoutes(r *gin.Engine, cfg *config.Config) {
+	// 请求ID与链路追踪、请求耗时统计（最先执行，覆盖被限流和拦截的请求）
+	r.Use(RequestIDMiddleware())
+	r.Use(MetricsMiddleware())
+
 	// 全局安全中间件
 	r.Use(SecurityHeadersMiddleware())
 	r.Use(IPBlacklistMiddleware())
 	r.Use(RateLimitMiddleware())
+	r.Use(MaintenanceMiddleware())
 
 	// 启动安全清理任务
 	StartSecurityCleanupTask()
 
 	// 静态文件服务（自动选择嵌入式或外部模式）
-	// 注意：SetupStaticRoutes 内部已处理 /product-files 和 /uploads 路由
+	// 注意：SetupStaticRoutes 内部已处理 /uploads 路由
 	static.SetupStaticRoutes(r)
 
+	// 商品文件（从对象存储读取，兼容旧的 ./Product 目录）
+	r.GET("/product-files/*filepath", ServeProductFile)
+	r.HEAD("/product-files/*filepath", ServeProductFile)
+
+	// Prometheus 指标
+	registerMetricsRoutes(r)
+
 	// CSRF令牌API
 	r.GET("/api/csrf-token", GetCSRFToken)
 
@@ -36,6 +48,8 @@ func RegisterRoutes(r *gin.Engine, cfg *config.Config) {
 	registerProductRoutes(r)
 	registerSupportRoutes(r)
 	registerAdminRoutes(r, cfg)
+	registerDevGatewayRoutes(r, cfg)
+	registerDevStorageRoutes(r)
 	registerSPARoutes(r, cfg)
 }
 
@@ -153,6 +167,7 @@ func registerUserRoutes(r *gin.Engine) {
 		userAPI.GET("/kamis/expiring", AuthRequired(), GetExpiringKamis)
 		userAPI.GET("/kamis/expired", AuthRequired(), GetExpiredKamis)
 		userAPI.POST("/renewal/remind", AuthRequired(), RequestRenewalReminder)
+		userAPI.POST("/renewal/create", AuthRequired(), CreateRenewalOrder)
 		userAPI.GET("/renewal/history", AuthRequired(), GetRenewalHistory)
 		userAPI.GET("/renewal/stats", AuthRequired(), GetRenewalStats)
 
@@ -274,14 +289,14 @@ func registerPaymentRoutes(r *gin.Engine) {
 	r.POST("/usdt/webhook", USDTWebhook)
 
 	// 支付方式查询
-	r.GET("/api/payment/methods", GetPaymentMethods)
+	r.GET("/api/payment/methods", OptionalAuth(), GetPaymentMethods)
 }
 
 // registerProductRoutes 注册商品相关路由
 func registerProductRoutes(r *gin.Engine) {
 	// 商品API（公开）
-	r.GET("/api/products", GetProducts)
-	r.GET("/api/product/:id", GetProduct)
+	r.GET("/api/products", OptionalAuth(), GetProducts)
+	r.GET("/api/product/:id", OptionalAuth(), GetProduct)
 	r.GET("/api/product/:id/images", GetProductImages)
 	r.GET("/api/product/:id/detail-file", GetProductDetailFile)
 	r.GET("/api/categories", GetCategories)
@@ -306,6 +321,10 @@ func registerProductRoutes(r *gin.Engine) {
 	// 发票配置（公开）
 	r.GET("/api/invoice/config", GetInvoiceConfig)
 
+	// 多币种（公开）
+	r.GET("/api/currencies", GetCurrencies)
+	r.GET("/api/product/:id/price", GetProductPriceInCurrency)
+
 	// 健康检查
 	r.GET("/health", HealthCheck)
 	r.GET("/api/health", HealthCheck)
@@ -330,6 +349,7 @@ func registerSupportRoutes(r *gin.Engine) {
 		supportAPI.POST("/ticket/:ticket_no/reply", OptionalAuth(), ReplyTicket)
 		supportAPI.POST("/ticket/:ticket_no/close", OptionalAuth(), CloseTicket)
 		supportAPI.POST("/ticket/:ticket_no/upload", OptionalAuth(), UploadTicketAttachment)
+		supportAPI.GET("/attachment/:id", OptionalAuth(), GetTicketAttachmentFile)
 		supportAPI.GET("/tickets", AuthRequired(), GetUserTickets)
 		supportAPI.POST("/ticket/:ticket_no/rate", OptionalAuth(), RateTicket)
 		supportAPI.GET("/ticket/:ticket_no/rating", OptionalAuth(), GetTicketRating)
@@ -371,6 +391,8 @@ func registerSupportRoutes(r *gin.Engine) {
 			staffAPI.PUT("/ticket/:ticket_no/status", StaffUpdateTicketStatus)
 			staffAPI.POST("/ticket/:ticket_no/assign", StaffAssignTicket)
 			staffAPI.GET("/tickets/stats", StaffGetTicketStats)
+			staffAPI.GET("/tickets/sla-stats", StaffGetSLAStats)
+			staffAPI.GET("/tickets/overdue", StaffGetOverdueTickets)
 			// 工单转接与合并
 			staffAPI.POST("/ticket/:ticket_no/transfer", StaffTransferTicket)
 			staffAPI.POST("/tickets/merge", StaffMergeTickets)
@@ -390,6 +412,19 @@ func registerSupportRoutes(r *gin.Engine) {
 			staffAPI.GET("/knowledge/search", StaffSearchKnowledge)
 			staffAPI.GET("/knowledge/hot", StaffGetHotKnowledge)
 			staffAPI.POST("/knowledge/:id/use", StaffUseKnowledge)
+			// 快捷回复
+			staffAPI.GET("/macros", StaffGetMacros)
+			staffAPI.POST("/macro", StaffSaveMacro)
+			staffAPI.PUT("/macro/:id", StaffSaveMacro)
+			staffAPI.DELETE("/macro/:id", StaffDeleteMacro)
+			staffAPI.GET("/macro/:id/preview", StaffPreviewMacro)
+			staffAPI.POST("/ticket/:ticket_no/macro/:id", StaffApplyMacroToTicket)
+			staffAPI.POST("/chat/:chat_id/macro/:id", StaffApplyMacroToChat)
+			// 订单工作台
+			staffAPI.GET("/ticket/:ticket_no/context", StaffGetTicketContext)
+			staffAPI.POST("/ticket/:ticket_no/order/resend-delivery", StaffResendDelivery)
+			staffAPI.POST("/ticket/:ticket_no/order/replace-kami", StaffReplaceKami)
+			staffAPI.POST("/ticket/:ticket_no/order/refund-request", StaffRequestRefund)
 		}
 	}
 }
@@ -461,6 +496,13 @@ func registerAdminProductRoutes(adminAPI *gin.RouterGroup) {
 	adminAPI.POST("/product/:id/detail-file", SaveProductDetailFile)
 	adminAPI.POST("/product/:id/detail-image", UploadProductDetailImage)
 
+	// 商品多规格
+	adminAPI.GET("/product/:id/variants", AdminGetProductVariants)
+	adminAPI.PUT("/product/:id/variant-options", AdminSetVariantOptions)
+	adminAPI.POST("/product/:id/variants", AdminCreateProductVariant)
+	adminAPI.PUT("/product-variant/:id", AdminUpdateProductVariant)
+	adminAPI.DELETE("/product-variant/:id", AdminDeleteProductVariant)
+
 	// 手动卡密管理
 	adminAPI.POST("/product/:id/kami/import", AdminImportKami)
 	adminAPI.GET("/product/:id/kami", AdminGetProductKamis)
@@ -482,6 +524,7 @@ func registerAdminOrderRoutes(adminAPI *gin.RouterGroup) {
 func registerAdminUserRoutes(adminAPI *gin.RouterGroup) {
 	adminAPI.GET("/users", AdminGetUsers)
 	adminAPI.PUT("/user/:id/status", AdminUpdateUserStatus)
+	adminAPI.PUT("/user/:id/level", AdminUpdateUserLevel)
 }
 
 // registerAdminSettingsRoutes 注册管理后台设置相关路由
@@ -508,6 +551,32 @@ func registerAdminSettingsRoutes(adminAPI *gin.RouterGroup) {
 	adminAPI.GET("/payment/config", AdminGetPaymentConfig)
 	adminAPI.POST("/payment/config", AdminSavePaymentConfig)
 
+	// 支付路由规则与手续费
+	adminAPI.GET("/payment/rules", AdminGetPaymentRules)
+	adminAPI.POST("/payment/rule", AdminSavePaymentRule)
+	adminAPI.PUT("/payment/rule/:id", AdminSavePaymentRule)
+	adminAPI.DELETE("/payment/rule/:id", AdminDeletePaymentRule)
+	adminAPI.GET("/payment/fees", AdminGetPaymentFees)
+	adminAPI.POST("/payment/fee", AdminSavePaymentFee)
+	adminAPI.DELETE("/payment/fee/:method", AdminDeletePaymentFee)
+
+	// 阶梯价与用户价格等级
+	adminAPI.GET("/price-levels", AdminGetPriceLevels)
+	adminAPI.PUT("/price-level/:level", AdminSetPriceLevel)
+	adminAPI.GET("/product/:id/price-tiers", AdminGetPriceTiers)
+	adminAPI.PUT("/product/:id/price-tiers", AdminSavePriceTiers)
+
+	// 多币种与汇率
+	adminAPI.GET("/currency", AdminGetCurrencyConfig)
+	adminAPI.POST("/currency/base", AdminSetBaseCurrency)
+	adminAPI.POST("/currency/rate", AdminSaveExchangeRate)
+	adminAPI.DELETE("/currency/rate/:currency", AdminDeleteExchangeRate)
+	adminAPI.POST("/currency/import", AdminImportExchangeRates)
+	adminAPI.GET("/product/:id/prices", AdminGetProductPrices)
+	adminAPI.POST("/product/:id/price", AdminSaveProductPrice)
+	adminAPI.DELETE("/product/:id/price/:currency", AdminDeleteProductPrice)
+	adminAPI.GET("/stats/currency", AdminGetCurrencyStats)
+
 	// 邮箱配置
 	adminAPI.GET("/email/config", AdminGetEmailConfig)
 	adminAPI.POST("/email/config", AdminSaveEmailConfig)
@@ -517,6 +586,8 @@ func registerAdminSettingsRoutes(adminAPI *gin.RouterGroup) {
 	adminAPI.POST("/stripe/test", StripeTestConnection)
 	adminAPI.POST("/usdt/test", USDTTestConnection)
 	adminAPI.POST("/usdt/confirm", AdminConfirmUSDTPayment)
+	adminAPI.GET("/usdt/payments", AdminGetUSDTChainPayments)
+	adminAPI.POST("/usdt/scan", AdminScanUSDTChain)
 
 	// Redis配置
 	adminAPI.GET("/redis/config", AdminGetRedisConfig)
@@ -529,6 +600,12 @@ func registerAdminSettingsRoutes(adminAPI *gin.RouterGroup) {
 	adminAPI.GET("/redis/key/info", AdminGetCacheKeyInfo)
 	adminAPI.POST("/redis/flush", AdminFlushCache)
 	adminAPI.POST("/redis/refresh", AdminRefreshCache)
+
+	// 对象存储管理
+	adminAPI.GET("/storage/config", AdminGetStorageConfig)
+	adminAPI.POST("/storage/config", AdminSaveStorageConfig)
+	adminAPI.POST("/storage/test", AdminTestStorageConnection)
+	adminAPI.POST("/storage/migrate", AdminMigrateStorage)
 }
 
 
@@ -637,6 +714,26 @@ func registerAdminSupportRoutes(adminAPI *gin.RouterGroup) {
 	adminAPI.DELETE("/support/staff/:id", AdminDeleteStaff)
 	adminAPI.GET("/support/stats", AdminGetSupportStats)
 
+	// 工单 SLA 策略
+	adminAPI.GET("/support/sla/policies", AdminGetSLAPolicies)
+	adminAPI.POST("/support/sla/policy", AdminSaveSLAPolicy)
+	adminAPI.PUT("/support/sla/policy/:id", AdminSaveSLAPolicy)
+	adminAPI.DELETE("/support/sla/policy/:id", AdminDeleteSLAPolicy)
+	adminAPI.GET("/support/sla/stats", AdminGetSLAStats)
+	adminAPI.GET("/support/sla/overdue", AdminGetOverdueTickets)
+
+	// 客服快捷回复
+	adminAPI.GET("/support/macros", AdminGetMacros)
+	adminAPI.GET("/support/macros/stats", AdminGetMacroStats)
+	adminAPI.POST("/support/macro", AdminSaveMacro)
+	adminAPI.PUT("/support/macro/:id", AdminSaveMacro)
+	adminAPI.DELETE("/support/macro/:id", AdminDeleteMacro)
+
+	// 客服退款申请审批
+	adminAPI.GET("/support/refund-requests", AdminGetRefundRequests)
+	adminAPI.POST("/support/refund-request/:id/approve", AdminApproveRefundRequest)
+	adminAPI.POST("/support/refund-request/:id/reject", AdminRejectRefundRequest)
+
 	// 知识库管理
 	adminAPI.GET("/knowledge/categories", AdminGetKnowledgeCategories)
 	adminAPI.POST("/knowledge/category", AdminCreateKnowledgeCategory)
@@ -670,6 +767,7 @@ func registerAdminSystemRoutes(adminAPI *gin.RouterGroup) {
 	// 操作日志
 	adminAPI.GET("/logs", AdminGetOperationLogs)
 	adminAPI.GET("/logs/dates", AdminGetLogDates)
+	adminAPI.GET("/logs/verify", AdminVerifyOperationLogs)
 	adminAPI.GET("/logs/config", AdminGetLogConfig)
 	adminAPI.POST("/logs/config", AdminUpdateLogConfig)
 
@@ -682,6 +780,15 @@ func registerAdminSystemRoutes(adminAPI *gin.RouterGroup) {
 	adminAPI.POST("/backup", AdminCreateBackup)
 	adminAPI.GET("/backup/:id/download", AdminDownloadBackup)
 	adminAPI.DELETE("/backup/:id", AdminDeleteBackup)
+	adminAPI.POST("/backup/:id/verify", AdminVerifyBackup)
+	adminAPI.POST("/backup/:id/restore", AdminRestoreBackup)
+	adminAPI.POST("/backup/:id/upload", AdminUploadBackup)
+	adminAPI.POST("/backup/export", AdminExportData)
+	adminAPI.POST("/backup/upload", AdminUploadExport)
+	adminAPI.POST("/backup/:id/import", AdminImportData)
+	adminAPI.GET("/backup/config", AdminGetBackupConfig)
+	adminAPI.PUT("/backup/config", AdminSaveBackupConfig)
+	adminAPI.POST("/backup/target/test", AdminTestBackupTarget)
 
 	// IP黑名单管理
 	adminAPI.GET("/blacklist", AdminGetBlacklist)
@@ -789,6 +896,9 @@ func registerSPARoutes(r *gin.Engine, cfg *config.Config) {
 	r.GET("/payment/qrcode", ServeReactPage("payment/qrcode/index.html"))
 	r.GET("/payment/qrcode/", ServeReactPage("payment/qrcode/index.html"))
 
+	// 一键续费（续费提醒邮件链接）
+	r.GET("/renew/:order_no", RenewalOneClick)
+
 	// 客服支持页面
 	r.GET("/message", ServeReactPage("message/index.html"))
 	r.GET("/message/", ServeReactPage("message/index.html"))
@@ -193,7 +193,7 @@ func UpdatePasswordWithVerification(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "change_password", "user", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "change_password", "user", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "密码修改成功"})
@@ -248,7 +248,7 @@ func BindEmailWithVerification(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "bind_email", "user", "", map[string]interface{}{"email": req.Email}, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "bind_email", "user", "", map[string]interface{}{"email": req.Email}, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "邮箱绑定成功"})
@@ -295,7 +295,7 @@ func Disable2FAWithVerification(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "disable_2fa", "user", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "disable_2fa", "user", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "两步验证已禁用"})
@@ -3,9 +3,14 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"log"
+	"os"
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/lifecycle"
 	"user-frontend/internal/model"
 	"user-frontend/internal/repository"
 	"user-frontend/internal/service"
@@ -29,6 +34,9 @@ var (
 	SessionSvc      *service.SessionService      // 会话服务（数据库持久化）
 	SupportSvc      *service.SupportService      // 客服支持服务
 	ManualKamiSvc   *service.ManualKamiService   // 手动卡密服务
+	CurrencySvc     *service.CurrencyService     // 多币种服务
+	PaymentRouteSvc *service.PaymentRouteService // 支付路由服务
+	PricingSvc      *service.PricingService      // 阶梯价与价格等级服务
 )
 
 // ==================== 扩展服务 ====================
@@ -73,6 +81,12 @@ func InitServices(cfg *config.Config) {
 		AddToBlacklist(key, duration)
 	})
 
+	// WebSocket Hub 不依赖数据库，停机时通知客户端重连
+	lifecycle.Register(lifecycle.Worker{
+		Name: "websocket",
+		Run:  service.GetWSHub().Run,
+	})
+
 	if model.DBConnected {
 		repo := repository.NewRepository(model.DB)
 		
@@ -81,6 +95,11 @@ func InitServices(cfg *config.Config) {
 		
 		// 初始化扩展服务
 		initExtendedServices(repo)
+
+		// 初始化Stripe/USDT支付服务（此前仅在后台保存支付配置后才初始化）
+		initUSDTChainService(service.NewUSDTChainService(repo, cfg))
+		InitStripeService(cfg)
+		InitUSDTService(cfg)
 		
 		// 只有在初始化设置完成后（密码不是默认值）才创建管理员
 		// 避免用默认密码 admin123 创建管理员
@@ -88,8 +107,8 @@ func InitServices(cfg *config.Config) {
 			AdminSvc.InitDefaultAdmin(cfg.ServerConfig.AdminUsername, cfg.ServerConfig.AdminPassword)
 		}
 
-		// 启动定时任务
-		go startScheduledTasks()
+		// 注册后台任务（随应用生命周期启动和停止）
+		registerBackgroundWorkers()
 	}
 }
 
@@ -119,15 +138,34 @@ func initCoreServices(repo *repository.Repository, cfg *config.Config) {
 		cfg.PaymentConfig = *paymentCfg
 	}
 
+	// 多币种服务（存在本地汇率文件时启动即导入）
+	CurrencySvc = service.NewCurrencyService(repo, cfg.ConfigDir)
+	if _, err := os.Stat(CurrencySvc.GetRateFilePath()); err == nil {
+		if count, err := CurrencySvc.LoadRatesFromFile("", "system"); err != nil {
+			log.Printf("警告: 导入本地汇率文件失败: %v", err)
+		} else {
+			log.Printf("已从本地汇率文件导入 %d 条汇率", count)
+		}
+	}
+
 	// 订单服务
 	OrderSvc = service.NewOrderService(repo, cfg)
 	OrderSvc.SetConfigService(ConfigSvc)
+	OrderSvc.SetCurrencyService(CurrencySvc)
+
+	// 支付方式路由与手续费
+	PaymentRouteSvc = service.NewPaymentRouteService(repo)
+	OrderSvc.SetPaymentRouteService(PaymentRouteSvc)
+
+	// 阶梯价与用户价格等级
+	PricingSvc = service.NewPricingService(repo)
+	OrderSvc.SetPricingService(PricingSvc)
 
 	// 初始化安全服务
 	SecuritySvc = service.NewSecurityService(repo)
 
-	// 初始化日志服务（文件存储版本，不再使用数据库）
-	LogSvc = service.NewLogService()
+	// 初始化日志服务（操作日志写入数据库审计表，可选同时写入CSV文件）
+	LogSvc = service.NewLogService(repo)
 
 	// 初始化公告服务
 	AnnouncementSvc = service.NewAnnouncementService(repo)
@@ -140,6 +178,9 @@ func initCoreServices(repo *repository.Repository, cfg *config.Config) {
 
 	// 初始化备份服务
 	BackupSvc = service.NewBackupService(repo, cfg.ConfigDir)
+	if backupCfg, err := ConfigSvc.GetBackupConfig(); err == nil {
+		BackupSvc.SetBackupConfig(backupCfg)
+	}
 
 	// 初始化会话服务
 	SessionSvc = service.NewSessionService(repo)
@@ -151,6 +192,7 @@ func initCoreServices(repo *repository.Repository, cfg *config.Config) {
 	// 初始化手动卡密服务
 	ManualKamiSvc = service.NewManualKamiService(repo)
 	OrderSvc.SetManualKamiService(ManualKamiSvc)
+	SupportSvc.SetManualKamiService(ManualKamiSvc)
 }
 
 // loadSystemConfig 从数据库加载系统配置
@@ -181,6 +223,7 @@ func initExtendedServices(repo *repository.Repository) {
 	// 余额服务
 	BalanceSvc = service.NewBalanceService(repo)
 	BalanceSvc.SetConfigService(ConfigSvc) // 设置配置服务引用
+	SupportSvc.SetBalanceService(BalanceSvc)
 
 	// 余额告警服务
 	BalanceAlertSvc = service.NewBalanceAlertService(repo)
@@ -200,6 +243,7 @@ func initExtendedServices(repo *repository.Repository) {
 
 	// 发票服务
 	InvoiceSvc = service.NewInvoiceService(repo, EmailSvc)
+	InvoiceSvc.SetCurrencyService(CurrencySvc)
 
 	// 设备管理服务
 	DeviceSvc = service.NewDeviceService(repo)
@@ -227,6 +271,14 @@ func initExtendedServices(repo *repository.Repository) {
 
 	// 定时任务服务
 	TaskSvc = service.NewTaskService(repo)
+	TaskSvc.SetLogService(LogSvc)
+	TaskSvc.RegisterTask(model.TaskTypeBackupDatabase, func(string) error {
+		if BackupSvc == nil {
+			return errors.New("备份服务未初始化")
+		}
+		_, err := BackupSvc.BackupAndUpload(&config.GlobalConfig.DBConfig)
+		return err
+	})
 
 	// 知识库服务
 	KnowledgeSvc = service.NewKnowledgeService(repo)
@@ -262,11 +314,45 @@ func initExtendedServices(repo *repository.Repository) {
 	HomepageSvc = service.NewHomepageService(model.DB)
 }
 
-// startScheduledTasks 启动定时任务
-func startScheduledTasks() {
+// registerBackgroundWorkers 注册依赖数据库的后台任务
+// 停止顺序与注册顺序相反：先停止链上轮询，再停止定时任务和任务调度器，停止时等待执行中的备份和任务完成
+func registerBackgroundWorkers() {
+	lifecycle.Register(lifecycle.Worker{
+		Name: "task-scheduler",
+		Run:  TaskSvc.Run,
+		Stop: TaskSvc.Wait,
+	})
+	lifecycle.Register(lifecycle.Worker{
+		Name: "scheduled-tasks",
+		Run:  runScheduledTasks,
+		Stop: func(ctx context.Context) error {
+			if BackupSvc == nil {
+				return nil
+			}
+			return BackupSvc.WaitScheduled(ctx)
+		},
+	})
+	lifecycle.Register(lifecycle.Worker{
+		Name: "usdt-watcher",
+		Run:  runUSDTChainWatcher,
+	})
+}
+
+// runScheduledTasks 运行定时任务直到 ctx 取消，维护模式中跳过
+func runScheduledTasks(ctx context.Context) {
 	// 每分钟执行一次
 	ticker := time.NewTicker(time.Minute)
-	for range ticker.C {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		done, ok := service.BeginBackgroundWork()
+		if !ok {
+			continue
+		}
 		// 取消过期订单（30分钟未支付）
 		if OrderSvc != nil {
 			OrderSvc.CancelExpiredOrders(30)
@@ -284,6 +370,13 @@ func startScheduledTasks() {
 		// 清理过期客服会话
 		if SupportSvc != nil {
 			SupportSvc.CleanupExpiredStaffSessions()
+			// 检查工单 SLA 超时并升级
+			SupportSvc.CheckSLABreaches()
+		}
+		// 定时数据库备份（到达间隔时在后台执行）
+		if BackupSvc != nil {
+			BackupSvc.RunScheduledBackup(&config.GlobalConfig.DBConfig)
 		}
+		done()
 	}
 }
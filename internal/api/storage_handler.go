@@ -0,0 +1,256 @@
+// Package api 提供 HTTP API 处理器
+// storage_handler.go - 对象存储配置管理与商品文件访问
+package api
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/service"
+	"user-frontend/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveStoredObject 输出存储对象内容（调用方需先设置 Content-Type）
+// 本地文件支持 Range 请求，远程对象以流式输出并支持 If-Modified-Since
+func serveStoredObject(c *gin.Context, rc io.ReadCloser, info *storage.ObjectInfo) {
+	defer rc.Close()
+
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", info.ModTime, rs)
+		return
+	}
+
+	if !info.ModTime.IsZero() {
+		c.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+		if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !info.ModTime.Truncate(time.Second).After(since) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	if info.Size > 0 {
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	if c.Request.Method != http.MethodHead {
+		io.Copy(c.Writer, rc)
+	}
+}
+
+// ServeProductFile 获取商品文件（图片、详情图片）
+// GET /product-files/*filepath
+func ServeProductFile(c *gin.Context) {
+	relPath := strings.TrimPrefix(c.Param("filepath"), "/")
+	rc, info, err := service.OpenProductFile(relPath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			c.Status(http.StatusNotFound)
+		} else {
+			c.Status(http.StatusBadGateway)
+		}
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(relPath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "public, max-age=3600")
+	serveStoredObject(c, rc, info)
+}
+
+// ==================== 对象存储配置管理 ====================
+
+// SaveStorageConfigRequest 保存对象存储配置请求
+type SaveStorageConfigRequest struct {
+	Driver    string `json:"driver"`
+	LocalRoot string `json:"local_root"`
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"` // 留空表示不修改
+	PathStyle bool   `json:"path_style"`
+	Prefix    string `json:"prefix"`
+	Timeout   int    `json:"timeout"`
+}
+
+// AdminGetStorageConfig 获取对象存储配置
+// GET /api/admin/storage/config
+func AdminGetStorageConfig(c *gin.Context) {
+	if DBConfigSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "配置服务未初始化"})
+		return
+	}
+
+	cfg, err := DBConfigSvc.GetStorageConfig()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取存储配置失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"config": gin.H{
+			"driver":         cfg.Driver,
+			"local_root":     cfg.LocalRoot,
+			"endpoint":       cfg.Endpoint,
+			"region":         cfg.Region,
+			"bucket":         cfg.Bucket,
+			"access_key":     cfg.AccessKey,
+			"has_secret_key": cfg.SecretKey != "",
+			"path_style":     cfg.PathStyle,
+			"prefix":         cfg.Prefix,
+			"timeout":        cfg.Timeout,
+		},
+		"active_driver": storage.Default().Driver(),
+	})
+}
+
+// AdminSaveStorageConfig 保存对象存储配置
+// POST /api/admin/storage/config
+// 保存前先测试连通性，成功后立即在当前节点生效；其他节点需重启后生效
+func AdminSaveStorageConfig(c *gin.Context) {
+	if DBConfigSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "配置服务未初始化"})
+		return
+	}
+
+	var req SaveStorageConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "参数错误: " + err.Error()})
+		return
+	}
+
+	switch req.Driver {
+	case storage.DriverLocal, "":
+		req.Driver = storage.DriverLocal
+		if req.LocalRoot == "" {
+			req.LocalRoot = storage.DefaultLocalRoot
+		}
+	case storage.DriverS3:
+		if req.Endpoint == "" || req.Bucket == "" || req.AccessKey == "" {
+			c.JSON(400, gin.H{"success": false, "error": "S3存储需要配置endpoint、bucket和access_key"})
+			return
+		}
+	default:
+		c.JSON(400, gin.H{"success": false, "error": "不支持的存储驱动"})
+		return
+	}
+	if req.Region == "" {
+		req.Region = "us-east-1"
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = 30
+	}
+
+	// 未填写密钥时沿用已保存的密钥
+	current, _ := DBConfigSvc.GetStorageConfig()
+	secretKey := req.SecretKey
+	if secretKey == "" && current != nil {
+		secretKey = current.SecretKey
+	}
+
+	storageConfig := &config.StorageConfig{
+		Driver:    req.Driver,
+		LocalRoot: req.LocalRoot,
+		Endpoint:  strings.TrimRight(req.Endpoint, "/"),
+		Region:    req.Region,
+		Bucket:    req.Bucket,
+		AccessKey: req.AccessKey,
+		SecretKey: secretKey,
+		PathStyle: req.PathStyle,
+		Prefix:    req.Prefix,
+		Timeout:   req.Timeout,
+	}
+
+	if ok, message, _ := DBConfigSvc.TestStorageConnection(storageConfig); !ok {
+		c.JSON(400, gin.H{"success": false, "error": "存储连接测试失败: " + message})
+		return
+	}
+
+	storageConfig.SecretKey = req.SecretKey
+	if err := DBConfigSvc.SaveStorageConfig(storageConfig); err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "保存存储配置失败: " + err.Error()})
+		return
+	}
+
+	storageConfig.SecretKey = secretKey
+	if err := storage.Init(service.ToStorageConfig(storageConfig)); err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "存储配置已保存，但切换失败: " + err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "update", "storage_config", "", storageConfig.Driver, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{"success": true, "message": "存储配置保存成功，当前节点已生效，其他节点需重启后生效；如有旧文件请执行迁移"})
+}
+
+// AdminTestStorageConnection 测试对象存储连通性（使用已保存的配置）
+// POST /api/admin/storage/test
+func AdminTestStorageConnection(c *gin.Context) {
+	if DBConfigSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "配置服务未初始化"})
+		return
+	}
+
+	cfg, err := DBConfigSvc.GetStorageConfig()
+	if err != nil {
+		c.JSON(500, gin.H{"success": false, "error": "获取存储配置失败: " + err.Error()})
+		return
+	}
+
+	success, message, latency := DBConfigSvc.TestStorageConnection(cfg)
+	c.JSON(200, gin.H{
+		"success": success,
+		"result": gin.H{
+			"connected": success,
+			"message":   message,
+			"latency":   latency,
+		},
+	})
+}
+
+// AdminMigrateStorage 将本地旧文件迁移到当前对象存储
+// POST /api/admin/storage/migrate
+// 包括 ./Product 下的商品文件、本地路径保存的工单附件，以及切换前本地存储中的对象
+func AdminMigrateStorage(c *gin.Context) {
+	if ProductImageSvc == nil || SupportSvc == nil || DBConfigSvc == nil {
+		c.JSON(500, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	cfg, _ := DBConfigSvc.GetStorageConfig()
+	localRoot := ""
+	if cfg != nil {
+		localRoot = cfg.LocalRoot
+	}
+
+	products := ProductImageSvc.MigrateFilesToStorage(localRoot)
+	attachments := SupportSvc.MigrateAttachmentsToStorage(localRoot)
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "migrate", "storage", "", storage.Default().Driver(), c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(200, gin.H{
+		"success": products.Failed == 0 && attachments.Failed == 0,
+		"result": gin.H{
+			"driver":      storage.Default().Driver(),
+			"products":    products,
+			"attachments": attachments,
+		},
+	})
+}
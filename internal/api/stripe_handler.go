@@ -111,10 +111,16 @@ func StripeCreateCheckoutSession(c *gin.Context) {
 		baseURL = scheme + "://" + c.Request.Host
 	}
 
+	// 校验支付方式，锁定手续费与支付币种（Stripe配置的币种）
+	order, ok := lockOrderPaymentMethod(c, order, "stripe")
+	if !ok {
+		return
+	}
+
 	// 创建Checkout会话
-	session, err := StripeSvc.CreateCheckoutSessionForOrder(
+	session, err := StripeSvc.WithContext(c.Request.Context()).CreateCheckoutSessionForOrder(
 		order.OrderNo,
-		order.Price,
+		order.GetPayAmount(),
 		productName,
 		baseURL,
 	)
@@ -155,7 +161,7 @@ func StripeVerifyPayment(c *gin.Context) {
 		return
 	}
 
-	result, err := StripeSvc.VerifyPayment(sessionID)
+	result, err := StripeSvc.WithContext(c.Request.Context()).VerifyPayment(sessionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -193,7 +199,7 @@ func StripeWebhook(c *gin.Context) {
 	if signature == "" {
 		// 记录安全日志
 		if LogSvc != nil {
-			LogSvc.LogSecurityEvent(
+			LogSvc.WithContext(c.Request.Context()).LogSecurityEvent(
 				"stripe_webhook_missing_signature",
 				c.ClientIP(),
 				c.GetHeader("User-Agent"),
@@ -209,7 +215,7 @@ func StripeWebhook(c *gin.Context) {
 	if err != nil {
 		// 记录安全日志
 		if LogSvc != nil {
-			LogSvc.LogSecurityEvent(
+			LogSvc.WithContext(c.Request.Context()).LogSecurityEvent(
 				"stripe_webhook_signature_failed",
 				c.ClientIP(),
 				c.GetHeader("User-Agent"),
@@ -231,11 +237,11 @@ func StripeWebhook(c *gin.Context) {
 		}
 		// 完成订单（带金额验证）
 		if OrderSvc != nil && orderNo != "" {
-			_, err := OrderSvc.ProcessPaymentWithAmount(orderNo, "Stripe", event.ID, paidAmount)
+			_, err := OrderSvc.WithContext(c.Request.Context()).ProcessPaymentWithAmount(orderNo, "Stripe", event.ID, paidAmount)
 			if err != nil {
 				// 记录支付处理失败
 				if LogSvc != nil {
-					LogSvc.LogSecurityEvent(
+					LogSvc.WithContext(c.Request.Context()).LogSecurityEvent(
 						"stripe_payment_process_failed",
 						c.ClientIP(),
 						c.GetHeader("User-Agent"),
@@ -260,11 +266,11 @@ func StripeWebhook(c *gin.Context) {
 		}
 		// 完成订单（带金额验证）
 		if OrderSvc != nil && orderNo != "" {
-			_, err := OrderSvc.ProcessPaymentWithAmount(orderNo, "Stripe", event.ID, paidAmount)
+			_, err := OrderSvc.WithContext(c.Request.Context()).ProcessPaymentWithAmount(orderNo, "Stripe", event.ID, paidAmount)
 			if err != nil {
 				// 记录安全日志
 				if LogSvc != nil {
-					LogSvc.LogSecurityEvent(
+					LogSvc.WithContext(c.Request.Context()).LogSecurityEvent(
 						"stripe_payment_process_failed",
 						c.ClientIP(),
 						c.GetHeader("User-Agent"),
@@ -281,7 +287,7 @@ func StripeWebhook(c *gin.Context) {
 	case "payment_intent.payment_failed":
 		// 支付失败 - 记录日志
 		if LogSvc != nil {
-			LogSvc.LogAdminActionSimple("system", "stripe_payment_failed", "webhook", event.ID, string(event.Data), "", "")
+			LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple("system", "stripe_payment_failed", "webhook", event.ID, string(event.Data), "", "")
 		}
 	}
 
@@ -300,7 +306,7 @@ func StripeTestConnection(c *gin.Context) {
 		return
 	}
 
-	err := StripeSvc.TestConnection()
+	err := StripeSvc.WithContext(c.Request.Context()).TestConnection()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
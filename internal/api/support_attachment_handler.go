@@ -0,0 +1,102 @@
+// Package api 提供 HTTP API 处理器
+// support_attachment_handler.go - 工单附件受控访问
+package api
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staffSessionFromCookie 从 Cookie 中读取已通过二步验证的客服会话
+func staffSessionFromCookie(c *gin.Context) *model.SupportStaffSession {
+	sessionID, err := c.Cookie("staff_session")
+	if err != nil || sessionID == "" {
+		return nil
+	}
+	session, err := SupportSvc.GetStaffSession(sessionID)
+	if err != nil || !session.Verified {
+		return nil
+	}
+	return session
+}
+
+// canAccessAttachment 检查当前请求是否有权访问附件
+// 依次接受：有效签名地址、客服会话、工单所属用户会话、游客令牌；内部备注附件仅客服可访问
+func canAccessAttachment(c *gin.Context, attachment *model.SupportAttachment, ticket *model.SupportTicket) bool {
+	if service.VerifyAttachmentSignature(attachment.ID, c.Query("expires"), c.Query("sig")) {
+		return true
+	}
+	if staffSessionFromCookie(c) != nil {
+		return true
+	}
+	if SupportSvc.IsAttachmentInternal(attachment) {
+		return false
+	}
+	if uid, exists := c.Get("user_id"); exists && ticket.UserID > 0 && uid.(uint) == ticket.UserID {
+		return true
+	}
+	if token := c.Query("guest_token"); token != "" && ticket.GuestToken != "" && token == ticket.GuestToken {
+		return true
+	}
+	return false
+}
+
+// GetTicketAttachmentFile 获取工单附件文件
+// GET /api/support/attachment/:id
+func GetTicketAttachmentFile(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "无效的附件ID"})
+		return
+	}
+
+	attachment, err := SupportSvc.GetAttachmentByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "附件不存在"})
+		return
+	}
+	ticket, err := SupportSvc.GetTicketByID(attachment.TicketID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "附件不存在"})
+		return
+	}
+
+	if !canAccessAttachment(c, attachment, ticket) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "无权访问此附件"})
+		return
+	}
+
+	rc, info, err := service.OpenAttachmentFile(attachment)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "附件文件不存在"})
+		return
+	}
+
+	// 仅图片和 PDF 内联显示，其余一律下载；禁止浏览器猜测类型
+	contentType := attachment.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") || contentType == "application/pdf" {
+		disposition = "inline"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=300")
+
+	serveStoredObject(c, rc, info)
+}
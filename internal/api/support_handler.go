@@ -1,10 +1,8 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"user-frontend/internal/model"
 
@@ -65,7 +63,9 @@ func CreateTicket(c *gin.Context) {
 	}
 	
 	// 自动分配工单
-	SupportSvc.AutoAssignTicket(ticket.ID)
+	if staff, _ := SupportSvc.AutoAssignTicket(ticket.ID); staff != nil {
+		NotifyTicketAssigned(ticket.ID, staff.ID, staff.Nickname)
+	}
 	
 	// 通知在线客服（邮件）
 	SupportSvc.NotifyStaffOnNewTicket(ticket)
@@ -334,6 +334,9 @@ func StartLiveChat(c *gin.Context) {
 	// WebSocket 通知所有客服有新聊天
 	NotifyNewChat(chat)
 	
+	// 按分配策略自动接入（未启用或无可用客服时保持等待）
+	SupportSvc.AutoAssignChat(chat.ID)
+	
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
 		"session_id":  chat.SessionID,
@@ -541,52 +544,30 @@ func UploadTicketAttachment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "请选择要上传的文件"})
 		return
 	}
-	
-	// 获取配置检查文件大小限制
-	config, _ := SupportSvc.GetSupportConfig()
-	maxSize := int64(config.MaxAttachmentSize) * 1024 * 1024 // MB转字节
-	if maxSize == 0 {
-		maxSize = 5 * 1024 * 1024 // 默认5MB
-	}
-	
-	if file.Size > maxSize {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "文件大小超过限制"})
-		return
-	}
-	
-	// 生成存储路径
-	uploadDir := "./uploads/tickets/" + ticketNo
-	fileName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), file.Filename)
-	filePath := uploadDir + "/" + fileName
-	
-	// 保存文件
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "文件保存失败"})
+
+	// 校验大小和类型（按文件内容识别）并保存到私有目录
+	stored, err := SupportSvc.StoreTicketAttachment(ticket.TicketNo, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
-	
-	// 生成访问URL
-	fileURL := "/uploads/tickets/" + ticketNo + "/" + fileName
-	
+
 	// 创建带附件的消息
 	content := c.PostForm("content")
 	if content == "" {
 		content = "[附件]"
 	}
-	
-	msg, err := SupportSvc.ReplyTicketWithAttachment(ticket.ID, senderType, senderID, senderName, content, false, fileURL, file.Filename, file.Size)
+
+	msg, err := SupportSvc.CreateAttachmentReply(ticket.ID, senderType, senderID, senderName, content, false, stored)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "保存消息失败"})
 		return
 	}
-	
-	// 保存附件记录
-	SupportSvc.SaveAttachment(ticket.ID, msg.ID, file.Filename, filePath, file.Header.Get("Content-Type"), file.Size)
-	
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"message":  msg,
-		"file_url": fileURL,
+		"file_url": msg.FileURL,
 	})
 }
 
@@ -618,55 +599,34 @@ func StaffUploadTicketAttachment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "请选择要上传的文件"})
 		return
 	}
-	
-	// 获取配置检查文件大小限制
-	config, _ := SupportSvc.GetSupportConfig()
-	maxSize := int64(config.MaxAttachmentSize) * 1024 * 1024
-	if maxSize == 0 {
-		maxSize = 5 * 1024 * 1024
-	}
-	
-	if file.Size > maxSize {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "文件大小超过限制"})
-		return
-	}
-	
-	// 生成存储路径
-	uploadDir := "./uploads/tickets/" + ticketNo
-	fileName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), file.Filename)
-	filePath := uploadDir + "/" + fileName
-	
-	// 保存文件
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "文件保存失败"})
+
+	stored, err := SupportSvc.StoreTicketAttachment(ticket.TicketNo, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
-	
-	fileURL := "/uploads/tickets/" + ticketNo + "/" + fileName
-	
+
 	content := c.PostForm("content")
 	if content == "" {
 		content = "[附件]"
 	}
 	isInternal := c.PostForm("is_internal") == "true"
-	
-	msg, err := SupportSvc.ReplyTicketWithAttachment(ticket.ID, "staff", staffID, staffName, content, isInternal, fileURL, file.Filename, file.Size)
+
+	msg, err := SupportSvc.CreateAttachmentReply(ticket.ID, "staff", staffID, staffName, content, isInternal, stored)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "保存消息失败"})
 		return
 	}
-	
-	SupportSvc.SaveAttachment(ticket.ID, msg.ID, file.Filename, filePath, file.Header.Get("Content-Type"), file.Size)
-	
+
 	// 非内部备注时通知用户
 	if !isInternal {
 		SupportSvc.NotifyUserOnReply(ticket.ID, content)
 	}
-	
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"message":  msg,
-		"file_url": fileURL,
+		"file_url": msg.FileURL,
 	})
 }
 
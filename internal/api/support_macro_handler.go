@@ -0,0 +1,384 @@
+// Package api 提供 HTTP API 处理器
+// support_macro_handler.go - 客服快捷回复（宏）API
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// macroRequest 快捷回复请求参数
+type macroRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Shortcut      string `json:"shortcut"`
+	Category      string `json:"category"`
+	Content       string `json:"content"`
+	IsInternal    bool   `json:"is_internal"`
+	Shared        bool   `json:"shared"` // 是否为共享宏（客服后台仅主管可设置）
+	SetStatus     *int   `json:"set_status"`
+	AddTags       string `json:"add_tags"`
+	AssignStaffID uint   `json:"assign_staff_id"`
+	AssignToSelf  bool   `json:"assign_to_self"`
+	SortOrder     int    `json:"sort_order"`
+	Enabled       *bool  `json:"enabled"`
+}
+
+// toModel 转换为快捷回复模型，ownerID 为个人宏的所属客服
+func (req *macroRequest) toModel(ownerID uint) *model.SupportMacro {
+	macro := &model.SupportMacro{
+		Name:          req.Name,
+		Shortcut:      req.Shortcut,
+		Category:      req.Category,
+		Content:       req.Content,
+		IsInternal:    req.IsInternal,
+		SetStatus:     req.SetStatus,
+		AddTags:       req.AddTags,
+		AssignStaffID: req.AssignStaffID,
+		AssignToSelf:  req.AssignToSelf,
+		SortOrder:     req.SortOrder,
+		Status:        1,
+	}
+	if req.Enabled != nil && !*req.Enabled {
+		macro.Status = 0
+	}
+	if !req.Shared {
+		macro.OwnerID = ownerID
+	}
+	return macro
+}
+
+// parseMacroID 解析路径中的快捷回复ID
+func parseMacroID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "无效的快捷回复ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currentStaffName 获取当前客服显示名称
+func currentStaffName(c *gin.Context) string {
+	staff, err := SupportSvc.GetStaffByID(c.GetUint("staff_id"))
+	if err != nil {
+		return c.GetString("staff_username")
+	}
+	if staff.Nickname != "" {
+		return staff.Nickname
+	}
+	return staff.Username
+}
+
+// ==========================================
+//         客服后台 - 快捷回复
+// ==========================================
+
+// StaffGetMacros 获取可用的快捷回复（共享 + 个人）
+// GET /api/staff/macros?scope=shared|personal
+func StaffGetMacros(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	macros, err := SupportSvc.GetMacros(c.GetUint("staff_id"), c.Query("scope"), c.Query("all") != "1")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"macros":       macros,
+		"placeholders": service.MacroPlaceholders,
+	})
+}
+
+// StaffSaveMacro 创建或更新快捷回复
+// POST /api/staff/macro
+// PUT  /api/staff/macro/:id
+func StaffSaveMacro(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req macroRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	macro := req.toModel(c.GetUint("staff_id"))
+	if c.Param("id") != "" {
+		id, ok := parseMacroID(c)
+		if !ok {
+			return
+		}
+		macro.ID = id
+	}
+
+	isSupervisor := c.GetString("staff_role") == model.StaffRoleSupervisor
+	if err := SupportSvc.SaveMacro(macro, isSupervisor); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "macro": macro})
+}
+
+// StaffDeleteMacro 删除快捷回复（主管可删除共享宏）
+// DELETE /api/staff/macro/:id
+func StaffDeleteMacro(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, ok := parseMacroID(c)
+	if !ok {
+		return
+	}
+
+	staffID := c.GetUint("staff_id")
+	macro, err := SupportSvc.GetMacro(id, staffID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	ownerID := staffID
+	if macro.IsShared() {
+		if c.GetString("staff_role") != model.StaffRoleSupervisor {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "无权删除共享快捷回复"})
+			return
+		}
+		ownerID = 0
+	}
+
+	if err := SupportSvc.DeleteMacro(id, ownerID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// StaffPreviewMacro 预览快捷回复在工单上的渲染结果
+// GET /api/staff/macro/:id/preview?ticket_no=
+func StaffPreviewMacro(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, ok := parseMacroID(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := SupportSvc.GetTicketByNo(c.Query("ticket_no"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "工单不存在"})
+		return
+	}
+
+	content, missing, err := SupportSvc.PreviewMacro(id, ticket.ID, c.GetUint("staff_id"), currentStaffName(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "content": content, "missing": missing})
+}
+
+// StaffApplyMacroToTicket 在工单上执行快捷回复（发送回复并执行附带操作）
+// POST /api/staff/ticket/:ticket_no/macro/:id
+func StaffApplyMacroToTicket(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, ok := parseMacroID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"` // 可选，客服编辑后的回复内容
+	}
+	c.ShouldBindJSON(&req)
+
+	ticket, err := SupportSvc.GetTicketByNo(c.Param("ticket_no"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "工单不存在"})
+		return
+	}
+
+	staffName := currentStaffName(c)
+	result, err := SupportSvc.ApplyMacroToTicket(id, ticket.ID, c.GetUint("staff_id"), staffName, req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	// 与手动回复一致的通知
+	if result.Message != nil && !result.Message.IsInternal {
+		SupportSvc.NotifyUserOnReply(ticket.ID, result.Message.Content)
+		NotifyTicketMessage(ticket.ID, result.Message)
+	}
+	if result.AssignedStaff != nil {
+		NotifyTicketAssigned(ticket.ID, result.AssignedStaff.ID, result.AssignedStaff.Nickname)
+	}
+	if result.StatusChanged && result.Ticket != nil {
+		NotifyTicketStatusChange(ticket.ID, result.Ticket.Status, staffName)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "result": result})
+}
+
+// StaffApplyMacroToChat 在实时聊天中发送快捷回复
+// POST /api/staff/chat/:chat_id/macro/:id
+func StaffApplyMacroToChat(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, ok := parseMacroID(c)
+	if !ok {
+		return
+	}
+	chatID, err := strconv.ParseUint(c.Param("chat_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "无效的聊天ID"})
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	c.ShouldBindJSON(&req)
+
+	msg, err := SupportSvc.ApplyMacroToChat(id, uint(chatID), c.GetUint("staff_id"), currentStaffName(c), req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	NotifyChatMessage(uint(chatID), msg)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": msg})
+}
+
+// ==========================================
+//         管理后台 - 快捷回复
+// ==========================================
+
+// AdminGetMacros 获取快捷回复列表
+// GET /api/admin/support/macros?scope=shared|personal
+func AdminGetMacros(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	macros, err := SupportSvc.GetMacros(0, c.Query("scope"), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"macros":       macros,
+		"placeholders": service.MacroPlaceholders,
+	})
+}
+
+// AdminSaveMacro 创建或更新共享快捷回复
+// POST /api/admin/support/macro
+// PUT  /api/admin/support/macro/:id
+func AdminSaveMacro(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req macroRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	// 管理后台维护的均为共享宏
+	req.Shared = true
+	macro := req.toModel(0)
+	if c.Param("id") != "" {
+		id, ok := parseMacroID(c)
+		if !ok {
+			return
+		}
+		macro.ID = id
+	}
+
+	if err := SupportSvc.SaveMacro(macro, true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "save", "support_macro", strconv.FormatUint(uint64(macro.ID), 10), macro, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "macro": macro})
+}
+
+// AdminDeleteMacro 删除快捷回复
+// DELETE /api/admin/support/macro/:id
+func AdminDeleteMacro(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, ok := parseMacroID(c)
+	if !ok {
+		return
+	}
+
+	if err := SupportSvc.DeleteMacro(id, 0); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "support_macro", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// AdminGetMacroStats 获取快捷回复使用统计
+// GET /api/admin/support/macros/stats?days=30
+func AdminGetMacroStats(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	stats, err := SupportSvc.GetMacroStats(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取统计失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "stats": stats})
+}
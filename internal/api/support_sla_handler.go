@@ -0,0 +1,194 @@
+// Package api 提供 HTTP API 处理器
+// support_sla_handler.go - 工单 SLA 策略与达标统计 API
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"user-frontend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slaPolicyRequest SLA 策略请求参数
+type slaPolicyRequest struct {
+	Name                 string `json:"name" binding:"required"`
+	Category             string `json:"category"`
+	Priority             int    `json:"priority"`
+	FirstResponseMinutes int    `json:"first_response_minutes"`
+	ResolutionMinutes    int    `json:"resolution_minutes"`
+	UseWorkingHours      bool   `json:"use_working_hours"`
+	EscalateOnBreach     bool   `json:"escalate_on_breach"`
+	Enabled              *bool  `json:"enabled"`
+}
+
+// ==========================================
+//         管理后台 - SLA 策略
+// ==========================================
+
+// AdminGetSLAPolicies 获取 SLA 策略列表
+// GET /api/admin/support/sla/policies
+func AdminGetSLAPolicies(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	policies, err := SupportSvc.GetSLAPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取策略失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "policies": policies})
+}
+
+// AdminSaveSLAPolicy 创建或更新 SLA 策略
+// POST /api/admin/support/sla/policy
+// PUT  /api/admin/support/sla/policy/:id
+func AdminSaveSLAPolicy(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req slaPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	policy := &model.SupportSLAPolicy{
+		Name:                 req.Name,
+		Category:             req.Category,
+		Priority:             req.Priority,
+		FirstResponseMinutes: req.FirstResponseMinutes,
+		ResolutionMinutes:    req.ResolutionMinutes,
+		UseWorkingHours:      req.UseWorkingHours,
+		EscalateOnBreach:     req.EscalateOnBreach,
+		Enabled:              req.Enabled == nil || *req.Enabled,
+	}
+
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "无效的策略ID"})
+			return
+		}
+		policy.ID = uint(id)
+	}
+
+	if err := SupportSvc.SaveSLAPolicy(policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "save", "sla_policy", strconv.FormatUint(uint64(policy.ID), 10), policy, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "policy": policy})
+}
+
+// AdminDeleteSLAPolicy 删除 SLA 策略
+// DELETE /api/admin/support/sla/policy/:id
+func AdminDeleteSLAPolicy(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "无效的策略ID"})
+		return
+	}
+
+	if err := SupportSvc.DeleteSLAPolicy(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "删除失败"})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "delete", "sla_policy", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// AdminGetSLAStats 获取 SLA 达标统计（可按 staff_id 查看单个客服）
+// GET /api/admin/support/sla/stats
+func AdminGetSLAStats(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	staffID, _ := strconv.ParseUint(c.DefaultQuery("staff_id", "0"), 10, 32)
+	stats, err := SupportSvc.GetStaffSLAStats(uint(staffID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取统计失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "stats": stats})
+}
+
+// AdminGetOverdueTickets 获取当前超时工单列表
+// GET /api/admin/support/sla/overdue
+func AdminGetOverdueTickets(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	staffID, _ := strconv.ParseUint(c.DefaultQuery("staff_id", "0"), 10, 32)
+	tickets, err := SupportSvc.GetOverdueTickets(uint(staffID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取工单失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "tickets": tickets, "total": len(tickets)})
+}
+
+// ==========================================
+//         客服后台 - SLA
+// ==========================================
+
+// StaffGetSLAStats 客服获取 SLA 达标统计（全部及个人）
+// GET /api/staff/tickets/sla-stats
+func StaffGetSLAStats(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	overall, _ := SupportSvc.GetSLAStats()
+	mine, _ := SupportSvc.GetStaffSLAStats(c.GetUint("staff_id"))
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "stats": overall, "mine": mine})
+}
+
+// StaffGetOverdueTickets 客服获取超时工单（mine=1 时仅返回分配给自己的）
+// GET /api/staff/tickets/overdue
+func StaffGetOverdueTickets(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var staffID uint
+	if c.Query("mine") == "1" {
+		staffID = c.GetUint("staff_id")
+	}
+
+	tickets, err := SupportSvc.GetOverdueTickets(staffID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取工单失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "tickets": tickets, "total": len(tickets)})
+}
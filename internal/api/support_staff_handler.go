@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"user-frontend/internal/model"
+	"user-frontend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -205,6 +206,7 @@ func StaffInfo(c *gin.Context) {
 			"status":        staff.Status,
 			"max_tickets":   staff.MaxTickets,
 			"current_load":  staff.CurrentLoad,
+			"skills":        staff.Skills,
 			"last_active":   staff.LastActiveAt,
 		},
 	})
@@ -490,7 +492,7 @@ func StaffAcceptChat(c *gin.Context) {
 	staff, _ := SupportSvc.GetStaffByID(staffID)
 	
 	if err := SupportSvc.AcceptChat(uint(chatID), staffID, staff.Nickname); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "接入失败"})
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 	
@@ -658,6 +660,7 @@ func AdminCreateStaff(c *gin.Context) {
 		Nickname string `json:"nickname"`
 		Email    string `json:"email"`
 		Role     string `json:"role"`
+		Skills   string `json:"skills"` // 技能标签（工单分类，逗号分隔）
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -678,6 +681,11 @@ func AdminCreateStaff(c *gin.Context) {
 		return
 	}
 	
+	if req.Skills != "" {
+		SupportSvc.UpdateStaffSkills(staff.ID, req.Skills)
+		staff.Skills = service.NormalizeTagList(req.Skills)
+	}
+	
 	c.JSON(http.StatusOK, gin.H{"success": true, "staff": staff})
 }
 
@@ -691,11 +699,13 @@ func AdminUpdateStaff(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
 	
 	var req struct {
-		Nickname   string `json:"nickname"`
-		Email      string `json:"email"`
-		MaxTickets int    `json:"max_tickets"`
-		Status     int    `json:"status"`
-		Password   string `json:"password"` // 可选，不为空则更新密码
+		Nickname   string  `json:"nickname"`
+		Email      string  `json:"email"`
+		MaxTickets int     `json:"max_tickets"`
+		Status     int     `json:"status"`
+		Password   string  `json:"password"` // 可选，不为空则更新密码
+		Role       string  `json:"role"`     // 可选，staff/supervisor
+		Skills     *string `json:"skills"`   // 可选，技能标签（逗号分隔，传空字符串清空）
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -708,6 +718,22 @@ func AdminUpdateStaff(c *gin.Context) {
 		return
 	}
 	
+	// 更新角色
+	if req.Role != "" {
+		if err := SupportSvc.UpdateStaffRole(uint(id), req.Role); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+	
+	// 更新技能标签
+	if req.Skills != nil {
+		if err := SupportSvc.UpdateStaffSkills(uint(id), *req.Skills); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "更新技能失败"})
+			return
+		}
+	}
+	
 	// 更新密码
 	if req.Password != "" {
 		if err := SupportSvc.UpdateStaffPassword(uint(id), req.Password); err != nil {
@@ -744,6 +770,7 @@ func AdminGetSupportStats(c *gin.Context) {
 	}
 
 	ticketStats, _ := SupportSvc.GetTicketStats()
+	slaStats, _ := SupportSvc.GetSLAStats()
 	onlineStaff, _ := SupportSvc.GetOnlineStaff()
 	allStaff, _ := SupportSvc.GetAllStaff()
 	
@@ -751,6 +778,7 @@ func AdminGetSupportStats(c *gin.Context) {
 		"success": true,
 		"stats": gin.H{
 			"tickets":      ticketStats,
+			"sla":          slaStats,
 			"online_staff": len(onlineStaff),
 			"total_staff":  len(allStaff),
 		},
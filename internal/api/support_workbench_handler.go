@@ -0,0 +1,220 @@
+// Package api 提供 HTTP API 处理器
+// support_workbench_handler.go - 客服工作台订单操作 API
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ==========================================
+//         客服后台 - 订单工作台
+// ==========================================
+
+// StaffGetTicketContext 获取工单关联的订单与用户上下文
+// GET /api/staff/ticket/:ticket_no/context
+func StaffGetTicketContext(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	ticket, err := SupportSvc.GetTicketByNo(c.Param("ticket_no"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "工单不存在"})
+		return
+	}
+
+	ctx, err := SupportSvc.GetTicketOrderContext(ticket.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "context": ctx})
+}
+
+// StaffResendDelivery 重发工单关联订单的发货邮件
+// POST /api/staff/ticket/:ticket_no/order/resend-delivery
+func StaffResendDelivery(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	ticket, err := SupportSvc.GetTicketByNo(c.Param("ticket_no"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "工单不存在"})
+		return
+	}
+
+	email, err := SupportSvc.ResendDelivery(ticket.ID, c.GetUint("staff_id"), currentStaffName(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "email": email})
+}
+
+// StaffReplaceKami 为工单关联订单更换失效卡密
+// POST /api/staff/ticket/:ticket_no/order/replace-kami
+func StaffReplaceKami(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req struct {
+		KamiID    uint   `json:"kami_id" binding:"required"`
+		Reason    string `json:"reason" binding:"required"`
+		SendEmail bool   `json:"send_email"` // 更换后是否向用户重发发货邮件
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	ticket, err := SupportSvc.GetTicketByNo(c.Param("ticket_no"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "工单不存在"})
+		return
+	}
+
+	kami, err := SupportSvc.ReplaceTicketKami(ticket.ID, req.KamiID, c.GetUint("staff_id"), currentStaffName(c), c.GetString("staff_role"), req.Reason, req.SendEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "kami": kami})
+}
+
+// StaffRequestRefund 为工单关联订单提交退款申请（需管理员审批）
+// POST /api/staff/ticket/:ticket_no/order/refund-request
+func StaffRequestRefund(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount" binding:"required"`
+		Reason string  `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "参数错误"})
+		return
+	}
+
+	ticket, err := SupportSvc.GetTicketByNo(c.Param("ticket_no"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "工单不存在"})
+		return
+	}
+
+	refund, err := SupportSvc.RequestRefund(ticket.ID, c.GetUint("staff_id"), currentStaffName(c), req.Amount, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "refund_request": refund})
+}
+
+// ==========================================
+//         管理后台 - 退款申请审批
+// ==========================================
+
+// AdminGetRefundRequests 获取客服退款申请列表
+// GET /api/admin/support/refund-requests?status=0
+func AdminGetRefundRequests(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	var status *int
+	if s := c.Query("status"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			status = &v
+		}
+	}
+
+	requests, total, err := SupportSvc.GetRefundRequests(status, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "获取失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "requests": requests, "total": total, "page": page})
+}
+
+// AdminApproveRefundRequest 通过客服退款申请
+// POST /api/admin/support/refund-request/:id/approve
+func AdminApproveRefundRequest(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "无效的申请ID"})
+		return
+	}
+
+	var req struct {
+		Remark string `json:"remark"`
+	}
+	c.ShouldBindJSON(&req)
+
+	refund, err := SupportSvc.ApproveRefundRequest(uint(id), c.GetString("admin_username"), req.Remark, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "approve", "support_refund_request", c.Param("id"), refund, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "refund_request": refund})
+}
+
+// AdminRejectRefundRequest 驳回客服退款申请
+// POST /api/admin/support/refund-request/:id/reject
+func AdminRejectRefundRequest(c *gin.Context) {
+	if SupportSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "服务未初始化"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "无效的申请ID"})
+		return
+	}
+
+	var req struct {
+		Remark string `json:"remark"`
+	}
+	c.ShouldBindJSON(&req)
+
+	refund, err := SupportSvc.RejectRefundRequest(uint(id), c.GetString("admin_username"), req.Remark)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if LogSvc != nil {
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(c.GetString("admin_username"), "reject", "support_refund_request", c.Param("id"), refund, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "refund_request": refund})
+}
@@ -116,7 +116,7 @@ func AdminCreateTask(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "创建定时任务", "scheduled_task", "", "创建任务: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "创建定时任务", "scheduled_task", "", "创建任务: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "创建成功", "task": task})
 }
@@ -175,7 +175,7 @@ func AdminUpdateTask(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "更新定时任务", "scheduled_task", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "更新定时任务", "scheduled_task", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "更新成功"})
 }
@@ -200,7 +200,7 @@ func AdminDeleteTask(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "删除定时任务", "scheduled_task", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "删除定时任务", "scheduled_task", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
 }
@@ -225,7 +225,7 @@ func AdminRunTaskNow(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "手动执行任务", "scheduled_task", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "手动执行任务", "scheduled_task", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": "任务已开始执行"})
 }
@@ -268,7 +268,7 @@ func AdminToggleTaskStatus(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "切换任务状态", "scheduled_task", c.Param("id"), statusText, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "切换任务状态", "scheduled_task", c.Param("id"), statusText, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{"success": true, "message": statusText, "status": task.Status})
 }
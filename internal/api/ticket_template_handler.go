@@ -175,7 +175,7 @@ func AdminCreateTicketTemplate(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "create_template", "ticket_template", strconv.Itoa(int(template.ID)), "创建工单模板: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "create_template", "ticket_template", strconv.Itoa(int(template.ID)), "创建工单模板: "+req.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "data": template})
@@ -235,7 +235,7 @@ func AdminUpdateTicketTemplate(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "update_template", "ticket_template", strconv.Itoa(int(templateID)), "更新工单模板", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "update_template", "ticket_template", strconv.Itoa(int(templateID)), "更新工单模板", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "更新成功"})
@@ -263,7 +263,7 @@ func AdminDeleteTicketTemplate(c *gin.Context) {
 	// 记录操作日志
 	adminUsername := c.GetString("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(adminUsername, "delete_template", "ticket_template", strconv.Itoa(int(templateID)), "删除工单模板", c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername, "delete_template", "ticket_template", strconv.Itoa(int(templateID)), "删除工单模板", c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "删除成功"})
@@ -0,0 +1,71 @@
+// Package api 提供 HTTP API 处理器
+// trace_middleware.go - 请求ID、链路追踪与请求日志中间件
+package api
+
+import (
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/trace"
+	"user-frontend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDMiddleware 为每个请求分配请求ID并开始入站 Span
+//
+// 优先使用入站的 X-Request-ID（格式合法时），否则生成新ID；请求ID写入响应头和
+// c.Request 的 context，服务层、数据库查询、支付网关调用和操作日志通过该 context 关联到本次请求。
+// 入站 traceparent 作为父 Span，使上游的链路可以延续。
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(trace.HeaderRequestID)
+		if !trace.ValidRequestID(requestID) {
+			requestID = trace.NewRequestID()
+		}
+		c.Header(trace.HeaderRequestID, requestID)
+		c.Set("request_id", requestID)
+
+		ctx := trace.WithRequestID(c.Request.Context(), requestID)
+		ctx = trace.WithRemoteParent(ctx, c.GetHeader(trace.HeaderTraceparent))
+		ctx, span := trace.Start(ctx, c.Request.Method+" "+c.Request.URL.Path, trace.KindServer)
+		c.Request = c.Request.WithContext(ctx)
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route != "" {
+			span.Name = c.Request.Method + " " + route
+		}
+		status := c.Writer.Status()
+		span.SetAttr("http.method", c.Request.Method)
+		span.SetAttr("http.route", route)
+		span.SetAttr("http.status_code", status)
+		span.SetAttr("client.address", c.ClientIP())
+		if len(c.Errors) > 0 {
+			span.End(c.Errors.Last())
+		} else {
+			span.End(nil)
+		}
+
+		if config.GlobalEnvConfig != nil && config.GlobalEnvConfig.EnableRequestLog {
+			fields := &utils.RequestLogFields{
+				Method:     c.Request.Method,
+				Path:       c.Request.URL.Path,
+				IP:         c.ClientIP(),
+				UserAgent:  c.Request.UserAgent(),
+				StatusCode: status,
+				Latency:    time.Since(start),
+				RequestID:  requestID,
+				TraceID:    span.TraceID,
+			}
+			if userID, ok := c.Get("user_id"); ok {
+				if id, ok := userID.(uint); ok {
+					fields.UserID = id
+				}
+			}
+			utils.LogRequest(fields)
+		}
+	}
+}
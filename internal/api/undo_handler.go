@@ -94,7 +94,7 @@ func AdminUndoOperation(c *gin.Context) {
 	}
 
 	// 记录操作日志
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "撤销操作", "undo_operation", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "撤销操作", "undo_operation", c.Param("id"), nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -141,7 +141,7 @@ func AdminSaveUndoConfig(c *gin.Context) {
 
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
-	LogSvc.LogAdminActionSimple(adminUsername.(string), "更新撤销配置", "undo_config", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
+	LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(adminUsername.(string), "更新撤销配置", "undo_config", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -1,20 +1,82 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/model"
 	"user-frontend/internal/service"
+	"user-frontend/internal/trace"
 
 	"github.com/gin-gonic/gin"
 )
 
 var USDTSvc *service.USDTService
 
+// USDTChainSvc 自托管链上收款服务（onchain 模式）
+var USDTChainSvc *service.USDTChainService
+
 // InitUSDTService 初始化USDT服务
 func InitUSDTService(cfg *config.Config) {
 	USDTSvc = service.NewUSDTService(cfg)
+	if USDTChainSvc != nil {
+		USDTSvc.SetChainService(USDTChainSvc)
+	}
+}
+
+// initUSDTChainService 初始化链上收款服务并设置入账回调
+func initUSDTChainService(chainSvc *service.USDTChainService) {
+	USDTChainSvc = chainSvc
+	USDTChainSvc.SetConfirmHandler(completeUSDTChainPayment)
+}
+
+// completeUSDTChainPayment 链上收款确认后完成订单或充值
+func completeUSDTChainPayment(payment *model.USDTPayment) error {
+	switch payment.Kind {
+	case model.USDTPaymentKindRecharge:
+		if BalanceSvc == nil {
+			return errors.New("余额服务未初始化")
+		}
+		return BalanceSvc.CompleteRechargeOrder(payment.OrderNo, payment.TxHash)
+	default:
+		if OrderSvc == nil {
+			return errors.New("订单服务未初始化")
+		}
+		// 链上确认不经过 HTTP 请求，单独分配请求ID以便追踪
+		ctx := trace.WithRequestID(context.Background(), trace.NewRequestID())
+		_, err := OrderSvc.WithContext(ctx).ProcessPaymentWithAmount(payment.OrderNo, "usdt", payment.TxHash, payment.PaidAmount)
+		return err
+	}
+}
+
+// runUSDTChainWatcher 定时轮询链上转账直到 ctx 取消（仅 onchain 模式下实际扫描）
+func runUSDTChainWatcher(ctx context.Context) {
+	ticker := time.NewTicker(service.USDTChainPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if USDTChainSvc == nil {
+			continue
+		}
+		done, ok := service.BeginBackgroundWork()
+		if !ok {
+			continue
+		}
+		if err := USDTChainSvc.Poll(); err != nil {
+			log.Printf("[USDT] 链上轮询失败: %v", err)
+		}
+		done()
+	}
 }
 
 // USDTGetConfig 获取USDT配置（前端使用）
@@ -97,15 +159,22 @@ func USDTCreatePayment(c *gin.Context) {
 		}
 	}
 
+	// 校验支付方式，锁定手续费与支付币种（第三方网关按基础货币报价，手动模式直接以USDT计价）
+	order, ok := lockOrderPaymentMethod(c, order, "usdt")
+	if !ok {
+		return
+	}
+
 	// 创建USDT支付
 	paymentReq := &service.USDTPaymentRequest{
 		OrderNo:     order.OrderNo,
-		Amount:      order.Price,
-		Currency:    "CNY", // 默认人民币
+		Amount:      order.GetPayAmount(),
+		Currency:    order.PayCurrency,
 		Description: productName,
+		Kind:        model.USDTPaymentKindOrder,
 	}
 
-	payment, err := USDTSvc.CreatePayment(paymentReq)
+	payment, err := USDTSvc.WithContext(c.Request.Context()).CreatePayment(paymentReq)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -140,7 +209,7 @@ func USDTGetPaymentStatus(c *gin.Context) {
 		return
 	}
 
-	status, err := USDTSvc.GetPaymentStatus(paymentID)
+	status, err := USDTSvc.WithContext(c.Request.Context()).GetPaymentStatus(paymentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -184,7 +253,7 @@ func USDTWebhook(c *gin.Context) {
 	if err := USDTSvc.VerifyWebhook(payload, signature); err != nil {
 		// 记录安全日志
 		if LogSvc != nil {
-			LogSvc.LogSecurityEvent(
+			LogSvc.WithContext(c.Request.Context()).LogSecurityEvent(
 				"usdt_webhook_signature_failed",
 				c.ClientIP(),
 				c.GetHeader("User-Agent"),
@@ -209,11 +278,11 @@ func USDTWebhook(c *gin.Context) {
 	if status == "confirmed" || status == "finished" || status == "paid" {
 		if OrderSvc != nil && orderNo != "" {
 			// 使用带金额验证的支付处理方法
-			_, err := OrderSvc.ProcessPaymentWithAmount(orderNo, "USDT", "", paidAmount)
+			_, err := OrderSvc.WithContext(c.Request.Context()).ProcessPaymentWithAmount(orderNo, "USDT", "", paidAmount)
 			if err != nil {
 				// 记录支付处理失败
 				if LogSvc != nil {
-					LogSvc.LogSecurityEvent(
+					LogSvc.WithContext(c.Request.Context()).LogSecurityEvent(
 						"usdt_payment_process_failed",
 						c.ClientIP(),
 						c.GetHeader("User-Agent"),
@@ -244,7 +313,7 @@ func USDTTestConnection(c *gin.Context) {
 		return
 	}
 
-	err := USDTSvc.TestConnection()
+	err := USDTSvc.WithContext(c.Request.Context()).TestConnection()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -283,7 +352,7 @@ func AdminConfirmUSDTPayment(c *gin.Context) {
 	}
 
 	// 完成订单
-	_, err := OrderSvc.ProcessPayment(req.OrderNo, "usdt", req.TxHash)
+	_, err := OrderSvc.WithContext(c.Request.Context()).ProcessPayment(req.OrderNo, "usdt", req.TxHash)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -295,7 +364,7 @@ func AdminConfirmUSDTPayment(c *gin.Context) {
 	// 记录操作日志
 	adminUsername, _ := c.Get("admin_username")
 	if LogSvc != nil {
-		LogSvc.LogAdminActionSimple(
+		LogSvc.WithContext(c.Request.Context()).LogAdminActionSimple(
 			adminUsername.(string),
 			"confirm_usdt_payment",
 			"order",
@@ -311,3 +380,65 @@ func AdminConfirmUSDTPayment(c *gin.Context) {
 		"message": "支付确认成功",
 	})
 }
+
+// AdminGetUSDTChainPayments 获取链上收款记录（管理员）
+// GET /api/admin/usdt/payments
+func AdminGetUSDTChainPayments(c *gin.Context) {
+	if USDTChainSvc == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "服务未初始化",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	payments, total, err := USDTChainSvc.ListPayments(page, pageSize, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "获取记录失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    payments,
+		"total":   total,
+		"page":    page,
+	})
+}
+
+// AdminScanUSDTChain 立即执行一次链上扫描（管理员）
+// POST /api/admin/usdt/scan
+func AdminScanUSDTChain(c *gin.Context) {
+	if USDTChainSvc == nil || !USDTChainSvc.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "未启用链上收款模式",
+		})
+		return
+	}
+
+	if err := USDTChainSvc.Poll(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "扫描完成",
+	})
+}
@@ -60,7 +60,7 @@ func Enable2FA(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(userID, username, "enable_2fa", "user", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(userID, username, "enable_2fa", "user", "", nil, c.ClientIP(), c.GetHeader("User-Agent"))
 	}
 
 	c.JSON(200, gin.H{"success": true, "message": "两步验证已启用"})
@@ -198,7 +198,7 @@ func UserLogin(c *gin.Context) {
 
 	// 记录操作日志
 	if LogSvc != nil {
-		LogSvc.LogUserActionSimple(user.ID, user.Username, "login", "user", "", nil, clientIP, c.GetHeader("User-Agent"))
+		LogSvc.WithContext(c.Request.Context()).LogUserActionSimple(user.ID, user.Username, "login", "user", "", nil, clientIP, c.GetHeader("User-Agent"))
 	}
 
 	// 检查是否启用了两步验证
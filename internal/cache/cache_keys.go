@@ -62,6 +62,7 @@ const (
 	ProductTTL     = 5 * time.Minute  // 商品缓存
 	CategoryTTL    = 10 * time.Minute // 分类缓存
 	ReviewStatsTTL = 5 * time.Minute  // 评价统计
+	CatalogTTL     = 2 * time.Minute  // 商品目录查询结果（库存、销量变化依赖过期刷新）
 
 	// 优惠相关
 	CouponTTL   = 5 * time.Minute  // 优惠券列表
@@ -127,6 +128,18 @@ func ProductListKey(page, size int, onlyActive bool, categoryID uint) string {
 	return fmt.Sprintf("%s%slist:%d:%d:%s:%d", keyPrefix, PrefixProduct, page, size, status, categoryID)
 }
 
+// ProductCatalogVersionKey 生成商品目录版本号缓存键（商品变更时自增，使目录查询缓存整体失效）
+// 格式：{prefix}product:catalog:version
+func ProductCatalogVersionKey() string {
+	return fmt.Sprintf("%s%scatalog:version", keyPrefix, PrefixProduct)
+}
+
+// ProductCatalogKey 生成商品目录查询结果缓存键
+// 格式：{prefix}product:catalog:{version}:{query_hash}
+func ProductCatalogKey(version int64, queryHash string) string {
+	return fmt.Sprintf("%s%scatalog:%d:%s", keyPrefix, PrefixProduct, version, queryHash)
+}
+
 // ProductStockKey 生成商品库存缓存键（短 TTL）
 // 格式：{prefix}product:stock:{product_id}
 func ProductStockKey(productID uint) string {
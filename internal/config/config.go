@@ -41,17 +41,24 @@ type PaymentConfig struct {
 	StripeSecretKey      string `json:"stripe_secret_key"`      // Stripe私钥（后端使用）
 	StripeWebhookSecret  string `json:"stripe_webhook_secret"`  // Webhook签名密钥
 	StripeCurrency       string `json:"stripe_currency"`        // 货币代码，默认usd
+	StripeAPIBaseURL     string `json:"stripe_api_base_url"`    // API地址（为空使用 https://api.stripe.com，可指向模拟网关）
 	// USDT支付配置
 	USDTEnabled       bool    `json:"usdt_enabled"`        // 是否启用USDT
 	USDTNetwork       string  `json:"usdt_network"`        // 网络类型：TRC20, ERC20, BEP20
 	USDTWalletAddress string  `json:"usdt_wallet_address"` // 收款钱包地址
-	USDTAPIProvider   string  `json:"usdt_api_provider"`   // API提供商：nowpayments, coingate, manual
+	USDTAPIProvider   string  `json:"usdt_api_provider"`   // API提供商：nowpayments, coingate, manual, onchain
 	USDTAPIKey        string  `json:"usdt_api_key"`        // API密钥
 	USDTAPISecret     string  `json:"usdt_api_secret"`     // API密钥（部分提供商需要）
 	USDTWebhookSecret string  `json:"usdt_webhook_secret"` // Webhook签名密钥
 	USDTExchangeRate  float64 `json:"usdt_exchange_rate"`  // 汇率（手动模式使用）
 	USDTMinAmount     float64 `json:"usdt_min_amount"`     // 最小支付金额（USDT）
 	USDTConfirmations int     `json:"usdt_confirmations"`  // 需要的确认数
+	USDTAPIBaseURL    string  `json:"usdt_api_base_url"`   // 第三方网关API地址（为空使用提供商正式地址，可指向模拟网关）
+	// 自托管链上收款（onchain 模式）
+	USDTChainAPIType    string `json:"usdt_chain_api_type"`    // 链上接口类型：trongrid, jsonrpc
+	USDTChainAPIURL     string `json:"usdt_chain_api_url"`     // 链上接口地址（可指向本地模拟链）
+	USDTChainAPIKey     string `json:"usdt_chain_api_key"`     // 链上接口密钥（TronGrid API Key等，可选）
+	USDTContractAddress string `json:"usdt_contract_address"`  // USDT合约地址（为空使用主网默认合约）
 }
 
 // PayPalConfig PayPal支付配置
@@ -63,6 +70,7 @@ type PayPalConfig struct {
 	Currency     string `json:"currency"`
 	ReturnURL    string `json:"return_url"`
 	CancelURL    string `json:"cancel_url"`
+	APIBaseURL   string `json:"api_base_url"` // API地址（为空按 Sandbox 选择官方地址，可指向模拟网关）
 }
 
 // DBConfig 数据库配置（从SQLite配置数据库加载）
@@ -97,15 +105,17 @@ type AlipayF2FConfig struct {
 	PrivateKey string `json:"private_key"`
 	PublicKey  string `json:"public_key"`
 	NotifyURL  string `json:"notify_url"`
+	GatewayURL string `json:"gateway_url"` // 网关地址（为空使用 https://openapi.alipay.com/gateway.do，可指向沙箱或模拟网关）
 }
 
 // WechatPayConfig 微信支付配置
 type WechatPayConfig struct {
-	Enabled   bool   `json:"enabled"`
-	AppID     string `json:"app_id"`
-	MchID     string `json:"mch_id"`
-	APIKey    string `json:"api_key"`
-	NotifyURL string `json:"notify_url"`
+	Enabled    bool   `json:"enabled"`
+	AppID      string `json:"app_id"`
+	MchID      string `json:"mch_id"`
+	APIKey     string `json:"api_key"`
+	NotifyURL  string `json:"notify_url"`
+	APIBaseURL string `json:"api_base_url"` // API地址（为空使用 https://api.mch.weixin.qq.com，可指向模拟网关）
 }
 
 // YiPayConfig 易支付配置
@@ -162,6 +172,93 @@ type RedisConfig struct {
 	TLSCACert  string `json:"tls_ca_cert"`
 }
 
+// StorageConfig 对象存储配置结构
+//
+// 运行时使用的对象存储配置，从 SQLite 配置数据库加载。
+type StorageConfig struct {
+	// Driver 存储驱动 (local/s3)
+	Driver string `json:"driver"`
+
+	// LocalRoot 本地存储根目录
+	LocalRoot string `json:"local_root"`
+
+	// S3 兼容存储配置
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	PathStyle bool   `json:"path_style"`
+	Prefix    string `json:"prefix"`
+	Timeout   int    `json:"timeout"`
+}
+
+// BackupConfig 备份配置结构
+//
+// 控制备份文件的压缩、加密、定时备份、保留策略和异地存放，从 SQLite 配置数据库加载。
+type BackupConfig struct {
+	// Compress 使用 gzip 压缩 SQL 导出文件（SQLite 备份本身为 zip 压缩）
+	Compress bool `json:"compress"`
+
+	// Encrypt 使用 AES-256-GCM 加密备份文件
+	Encrypt bool `json:"encrypt"`
+
+	// EncryptionMode 加密密钥来源: config_key（配置加密密钥）/passphrase（独立备份口令）
+	EncryptionMode string `json:"encryption_mode"`
+
+	// Passphrase 备份口令
+	Passphrase string `json:"passphrase"`
+
+	// 定时备份
+	ScheduleEnabled bool `json:"schedule_enabled"`
+	IntervalHours   int  `json:"interval_hours"`
+	IncludeObjects  bool `json:"include_objects"`
+
+	// 保留策略（仅作用于定时备份，均为 0 时不自动清理）
+	KeepLast    int `json:"keep_last"`
+	KeepDaily   int `json:"keep_daily"`
+	KeepWeekly  int `json:"keep_weekly"`
+	KeepMonthly int `json:"keep_monthly"`
+
+	// Targets 异地存放目标，定时备份完成后依次上传
+	Targets []BackupTarget `json:"targets"`
+}
+
+// BackupTarget 备份异地存放目标
+type BackupTarget struct {
+	// Name 目标名称（唯一）
+	Name string `json:"name"`
+
+	// Type 目标类型: s3/sftp/local
+	Type string `json:"type"`
+
+	// Enabled 是否启用
+	Enabled bool `json:"enabled"`
+
+	// Path 存放目录（local 为挂载目录，sftp 为远程目录）
+	Path string `json:"path"`
+
+	// S3 兼容存储配置
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	PathStyle bool   `json:"path_style"`
+	Prefix    string `json:"prefix"`
+
+	// SFTP 配置
+	Host               string `json:"host"`
+	Port               int    `json:"port"`
+	User               string `json:"user"`
+	Password           string `json:"password"`
+	PrivateKey         string `json:"private_key"`
+	HostKeyFingerprint string `json:"host_key_fingerprint"`
+
+	// Timeout 超时（秒）
+	Timeout int `json:"timeout"`
+}
+
 var (
 	GlobalConfig *Config
 	once         sync.Once
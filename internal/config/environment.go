@@ -22,6 +22,8 @@ const (
 type EnvironmentConfig struct {
 	// 当前环境
 	Env Environment
+	// EnvExplicit APP_ENV 是否显式设置为已知环境（未设置或无法识别时按开发环境处理）
+	EnvExplicit bool
 
 	// 安全设置
 	SecureCookie bool   // Cookie是否启用Secure标志
@@ -39,6 +41,15 @@ type EnvironmentConfig struct {
 	EnableSQLLog      bool // 是否启用SQL日志
 	EnableRequestLog  bool // 是否启用请求日志
 
+	// 站点对外访问地址（如 https://shop.example.com，用于邮件中的链接）
+	SiteURL string
+
+	// 支付网关模拟器（挂载于 /dev/gateway/:provider，仅用于本地/CI 测试，需显式设置非生产环境的 APP_ENV）
+	EnableGatewaySimulator bool
+
+	// S3 兼容存储模拟服务（挂载于 /dev/s3，仅用于本地/CI 测试，生产环境强制关闭）
+	EnableStorageSimulator bool
+
 	// CORS设置
 	AllowOrigins     []string // 允许的跨域来源
 	AllowCredentials bool     // 是否允许携带凭证
@@ -46,6 +57,22 @@ type EnvironmentConfig struct {
 	// 性能设置
 	RateLimitEnabled bool // 是否启用限流
 	MaxRequestBody   int  // 最大请求体大小（字节）
+
+	// 停机设置
+	ShutdownTimeout int // 收到停止信号后等待请求和后台任务结束的最长时间（秒）
+
+	// 监控指标（Prometheus 格式，挂载于 /metrics）
+	MetricsEnabled  bool     // 是否启用 /metrics
+	MetricsToken    string   // 抓取令牌（Authorization: Bearer <token>），为空表示不校验
+	MetricsAllowIPs []string // 允许抓取的 IP 或 CIDR，为空表示不限制（未设置令牌时仅允许本机）
+
+	// 链路追踪（OTLP/HTTP 导出，地址为空时不导出）
+	TracingEndpoint    string // 采集器地址，如 http://localhost:4318
+	TracingServiceName string // 上报的服务名
+
+	// 客户端国家（用于支付方式路由和手续费规则）
+	CountryHeader        string   // 前置 CDN/反向代理注入国家代码的请求头（如 CF-IPCountry），为空表示不识别国家
+	CountryHeaderProxies []string // 允许设置该请求头的代理 IP 或 CIDR，为空表示不限制来源（须确保代理会覆盖客户端传入的同名头）
 }
 
 // 默认环境配置
@@ -66,6 +93,8 @@ var defaultEnvConfigs = map[Environment]*EnvironmentConfig{
 		AllowCredentials:  true,
 		RateLimitEnabled:  false,
 		MaxRequestBody:    10 * 1024 * 1024, // 10MB
+		ShutdownTimeout:   30,
+		MetricsEnabled:    true,
 	},
 	EnvProduction: {
 		Env:               EnvProduction,
@@ -83,6 +112,8 @@ var defaultEnvConfigs = map[Environment]*EnvironmentConfig{
 		AllowCredentials:  true,
 		RateLimitEnabled:  true,
 		MaxRequestBody:    5 * 1024 * 1024, // 5MB
+		ShutdownTimeout:   30,
+		MetricsEnabled:    true,
 	},
 	EnvTesting: {
 		Env:               EnvTesting,
@@ -100,6 +131,8 @@ var defaultEnvConfigs = map[Environment]*EnvironmentConfig{
 		AllowCredentials:  true,
 		RateLimitEnabled:  false,
 		MaxRequestBody:    10 * 1024 * 1024, // 10MB
+		ShutdownTimeout:   30,
+		MetricsEnabled:    true,
 	},
 }
 
@@ -120,6 +153,8 @@ func InitEnvironmentConfig() *EnvironmentConfig {
 
 	// 复制一份以避免修改默认配置
 	envConfig := *config
+	_, known := defaultEnvConfigs[env]
+	envConfig.EnvExplicit = known && os.Getenv("APP_ENV") != ""
 
 	// 从环境变量覆盖配置
 	envConfig.overrideFromEnv()
@@ -165,6 +200,15 @@ func (c *EnvironmentConfig) overrideFromEnv() {
 	if v := os.Getenv("ENABLE_REQUEST_LOG"); v != "" {
 		c.EnableRequestLog = v == "true" || v == "1"
 	}
+	if v := os.Getenv("SITE_URL"); v != "" {
+		c.SiteURL = strings.TrimRight(v, "/")
+	}
+	if v := os.Getenv("ENABLE_GATEWAY_SIMULATOR"); v != "" {
+		c.EnableGatewaySimulator = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ENABLE_STORAGE_SIMULATOR"); v != "" {
+		c.EnableStorageSimulator = v == "true" || v == "1"
+	}
 
 	// CORS设置
 	if v := os.Getenv("ALLOW_ORIGINS"); v != "" {
@@ -183,6 +227,49 @@ func (c *EnvironmentConfig) overrideFromEnv() {
 			c.MaxRequestBody = size
 		}
 	}
+
+	// 停机设置
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+			c.ShutdownTimeout = seconds
+		}
+	}
+
+	// 链路追踪（沿用 OpenTelemetry 标准环境变量名）
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		c.TracingEndpoint = v
+	}
+	c.TracingServiceName = getEnvOrDefault("OTEL_SERVICE_NAME", "buyserver")
+
+	// 监控指标
+	if v := os.Getenv("METRICS_ENABLED"); v != "" {
+		c.MetricsEnabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("METRICS_TOKEN"); v != "" {
+		c.MetricsToken = v
+	}
+	if v := os.Getenv("METRICS_ALLOW_IPS"); v != "" {
+		c.MetricsAllowIPs = splitList(v)
+	}
+
+	// 客户端国家请求头
+	if v := os.Getenv("COUNTRY_HEADER"); v != "" {
+		c.CountryHeader = strings.TrimSpace(v)
+	}
+	if v := os.Getenv("COUNTRY_HEADER_PROXIES"); v != "" {
+		c.CountryHeaderProxies = splitList(v)
+	}
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // getEnvOrDefault 获取环境变量，如果不存在则返回默认值
@@ -208,6 +295,18 @@ func (c *EnvironmentConfig) IsTesting() bool {
 	return c.Env == EnvTesting
 }
 
+// GatewaySimulatorEnabled 是否启用支付网关模拟器
+// 需设置 ENABLE_GATEWAY_SIMULATOR=true 并将 APP_ENV 显式设置为 development 或 testing；
+// APP_ENV 未设置时默认按开发环境处理，为避免生产部署遗漏 APP_ENV 时误开模拟器，此时同样返回 false
+func (c *EnvironmentConfig) GatewaySimulatorEnabled() bool {
+	return c.EnableGatewaySimulator && c.EnvExplicit && !c.IsProduction()
+}
+
+// StorageSimulatorEnabled 是否启用 S3 兼容存储模拟服务（生产环境始终返回 false）
+func (c *EnvironmentConfig) StorageSimulatorEnabled() bool {
+	return c.EnableStorageSimulator && !c.IsProduction()
+}
+
 // GetLogLevel 获取日志级别
 func (c *EnvironmentConfig) GetLogLevel() string {
 	return c.LogLevel
@@ -0,0 +1,165 @@
+// Package lifecycle 提供应用生命周期管理
+// lifecycle.go - 后台任务注册、启动与有序停止
+//
+// 后台任务按注册顺序启动、按相反顺序停止：先注册的基础设施（数据库、缓存）最后停止，
+// 依赖它们的任务（定时任务、链上轮询）先停止。每个任务在独立的 context 中运行，
+// 停止时取消该 context 并等待任务返回，再调用 Stop 等待进行中的工作完成。
+// 超过停机时限时不再等待剩余任务，直接返回错误。
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Worker 后台任务
+type Worker struct {
+	// Name 任务名称（用于日志）
+	Name string
+	// Run 在独立协程中运行，ctx 取消后应尽快返回；为空表示没有常驻协程
+	Run func(ctx context.Context)
+	// Stop 在 Run 返回后调用，用于等待进行中的工作并释放资源；为空表示无需清理
+	Stop func(ctx context.Context) error
+}
+
+// worker 已注册的任务
+type worker struct {
+	Worker
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager 后台任务管理器
+type Manager struct {
+	mu      sync.Mutex
+	workers []*worker
+	started bool
+	stopped bool
+}
+
+// NewManager 创建后台任务管理器
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Register 注册后台任务；管理器已启动时立即启动，已停止时忽略
+func (m *Manager) Register(w Worker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		log.Printf("[Lifecycle] 应用正在停止，忽略后台任务 %s", w.Name)
+		return
+	}
+	wk := &worker{Worker: w}
+	m.workers = append(m.workers, wk)
+	if m.started {
+		wk.start()
+	}
+}
+
+// Start 按注册顺序启动全部后台任务
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started || m.stopped {
+		return
+	}
+	m.started = true
+	for _, wk := range m.workers {
+		wk.start()
+	}
+}
+
+// Shutdown 按注册的相反顺序停止全部后台任务
+// ctx 到期后不再等待剩余任务，返回的错误包含超时和各任务 Stop 返回的错误
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopped = true
+	workers := m.workers
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(workers) - 1; i >= 0; i-- {
+		if err := workers[i].stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// start 启动任务
+func (wk *worker) start() {
+	if wk.Run == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	wk.cancel = cancel
+	wk.done = make(chan struct{})
+	go func() {
+		defer close(wk.done)
+		wk.Run(ctx)
+	}()
+}
+
+// stop 取消任务并等待其返回，随后调用 Stop
+func (wk *worker) stop(ctx context.Context) error {
+	start := time.Now()
+	if wk.cancel != nil {
+		wk.cancel()
+		select {
+		case <-wk.done:
+		case <-ctx.Done():
+			return fmt.Errorf("后台任务 %s 未能在停机时限内结束", wk.Name)
+		}
+	}
+	if wk.Stop != nil {
+		if err := wk.Stop(ctx); err != nil {
+			return fmt.Errorf("后台任务 %s 停止失败: %v", wk.Name, err)
+		}
+	}
+	log.Printf("[Lifecycle] 后台任务 %s 已停止（%s）", wk.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// defaultManager 全局后台任务管理器
+var defaultManager = NewManager()
+
+// Register 向全局管理器注册后台任务
+func Register(w Worker) {
+	defaultManager.Register(w)
+}
+
+// Start 启动全局管理器中的后台任务
+func Start() {
+	defaultManager.Start()
+}
+
+// Shutdown 停止全局管理器中的后台任务
+func Shutdown(ctx context.Context) error {
+	return defaultManager.Shutdown(ctx)
+}
+
+// WaitGroup 等待 wg 完成，ctx 到期时返回错误，用于 Worker.Stop 中等待进行中的工作
+func WaitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
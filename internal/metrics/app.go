@@ -0,0 +1,146 @@
+// Package metrics 提供 Prometheus 兼容的监控指标
+// app.go - 业务指标定义与记录函数
+package metrics
+
+import (
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var startTime = time.Now()
+
+// ==================== HTTP 请求 ====================
+
+var (
+	httpRequestDuration = NewHistogramVec(
+		"shop_http_request_duration_seconds",
+		"HTTP 请求耗时（按路由模板、方法和状态码）",
+		nil, "method", "route", "status",
+	)
+
+	// 累计值，供管理后台的 API 统计使用
+	apiRequests    atomic.Int64
+	apiErrors      atomic.Int64
+	apiDurationSum atomic.Int64 // 微秒
+)
+
+// ObserveHTTPRequest 记录一次 HTTP 请求，route 为路由模板（未匹配路由传空字符串）
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequestDuration.Observe(duration.Seconds(), method, route, strconv.Itoa(status))
+
+	apiRequests.Add(1)
+	apiDurationSum.Add(duration.Microseconds())
+	if status >= 500 {
+		apiErrors.Add(1)
+	}
+}
+
+// APISnapshot 自启动以来的请求总数、平均耗时（毫秒）和 5xx 错误率（百分比）
+func APISnapshot() (total int64, avgMs float64, errorRate float64) {
+	total = apiRequests.Load()
+	if total == 0 {
+		return 0, 0, 0
+	}
+	avgMs = float64(apiDurationSum.Load()) / float64(total) / 1000
+	errorRate = float64(apiErrors.Load()) / float64(total) * 100
+	return total, avgMs, errorRate
+}
+
+// ==================== 订单 ====================
+
+var (
+	ordersCreated = NewCounterVec(
+		"shop_orders_created_total",
+		"创建的订单数（按订单类型）",
+		"type",
+	)
+	orderPayments = NewCounterVec(
+		"shop_order_payments_total",
+		"支付回调处理结果（按支付方式，result: paid/rejected）",
+		"provider", "result",
+	)
+	orderFulfilments = NewCounterVec(
+		"shop_order_fulfilments_total",
+		"订单发货结果（按支付方式，result: success/failed）",
+		"provider", "result",
+	)
+)
+
+// RecordOrderCreated 记录订单创建
+func RecordOrderCreated(orderType string) {
+	ordersCreated.Inc(orderType)
+}
+
+// RecordOrderPayment 记录支付处理结果，paid 为 false 表示支付被拒绝（金额不符、订单状态异常等）
+// 支付成功在发货完成后记录；provider 应为统一后的支付方式代码（见 model.NormalizePaymentMethod）
+func RecordOrderPayment(provider string, paid bool) {
+	result := "paid"
+	if !paid {
+		result = "rejected"
+	}
+	orderPayments.Inc(providerLabel(provider), result)
+}
+
+// RecordOrderFulfilment 记录发货结果
+func RecordOrderFulfilment(provider string, ok bool) {
+	result := "success"
+	if !ok {
+		result = "failed"
+	}
+	orderFulfilments.Inc(providerLabel(provider), result)
+}
+
+// providerLabel 支付方式为空时使用 unknown
+func providerLabel(provider string) string {
+	if provider == "" {
+		return "unknown"
+	}
+	return provider
+}
+
+// ==================== 定时任务 ====================
+
+var (
+	taskRunDuration = NewHistogramVec(
+		"shop_task_run_duration_seconds",
+		"定时任务执行耗时（status: success/failed）",
+		[]float64{.01, .1, .5, 1, 5, 15, 60, 300},
+		"task", "status",
+	)
+	taskRunFailures = NewCounterVec(
+		"shop_task_run_failures_total",
+		"定时任务执行失败次数",
+		"task",
+	)
+)
+
+// ObserveTaskRun 记录一次定时任务执行
+func ObserveTaskRun(task string, duration time.Duration, failed bool) {
+	status := "success"
+	if failed {
+		status = "failed"
+		taskRunFailures.Inc(task)
+	}
+	taskRunDuration.Observe(duration.Seconds(), task, status)
+}
+
+// ==================== 运行时 ====================
+
+func init() {
+	NewGaugeFunc("go_goroutines", "当前 Goroutine 数量", func() []Sample {
+		return Value(float64(runtime.NumGoroutine()))
+	})
+	NewGaugeFunc("go_memstats_heap_alloc_bytes", "已分配的堆内存（字节）", func() []Sample {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return Value(float64(m.HeapAlloc))
+	})
+	NewGaugeFunc("process_start_time_seconds", "进程启动时间（Unix 时间戳）", func() []Sample {
+		return Value(float64(startTime.Unix()))
+	})
+}
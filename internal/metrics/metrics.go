@@ -0,0 +1,386 @@
+// Package metrics 提供 Prometheus 兼容的监控指标
+// metrics.go - 指标类型与文本格式输出
+//
+// 实现 Prometheus 文本格式（0.0.4）所需的最小子集：计数器、仪表盘、直方图，
+// 以及在抓取时才计算取值的 Func 指标（用于数据库连接池、卡密库存等按需查询的数据）。
+// 指标名称在注册时检查唯一性，重复注册属于编程错误，直接 panic。
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType 抓取响应的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Sample Func 指标返回的单个样本
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// family 一组同名指标
+type family interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu       sync.RWMutex
+	families []family
+	names    map[string]bool
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Default 全局指标注册表
+var Default = NewRegistry()
+
+// register 注册指标，名称重复时 panic
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[f.name()] {
+		panic("metrics: 重复注册指标 " + f.name())
+	}
+	r.names[f.name()] = true
+	r.families = append(r.families, f)
+}
+
+// WriteText 以 Prometheus 文本格式输出全部指标
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	families := make([]family, len(r.families))
+	copy(families, r.families)
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// desc 指标描述
+type desc struct {
+	fqName     string
+	help       string
+	typ        string
+	labelNames []string
+}
+
+func (d *desc) name() string { return d.fqName }
+
+// writeHeader 输出 HELP 和 TYPE 行
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.fqName, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.fqName, d.typ)
+}
+
+// writeSample 输出一行样本，extra 为追加的标签（如直方图的 le）
+func (d *desc) writeSample(w *bufio.Writer, suffix string, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(d.fqName)
+	w.WriteString(suffix)
+	if len(d.labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, name := range d.labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(name)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabel(labelValues[i]))
+			w.WriteByte('"')
+		}
+		if extraName != "" {
+			if len(d.labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName)
+			w.WriteString(`="`)
+			w.WriteString(extraValue)
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// checkLabels 检查标签值数量，不一致属于编程错误
+func (d *desc) checkLabels(labelValues []string) {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metrics: 指标 %s 需要 %d 个标签值，实际 %d 个", d.fqName, len(d.labelNames), len(labelValues)))
+	}
+}
+
+// seriesKey 标签值组合的键
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// sortedKeys 返回排序后的键，保证输出顺序稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ==================== 计数器与仪表盘 ====================
+
+// valueSeries 单个标签组合的取值
+type valueSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// valueVec 计数器和仪表盘的公共实现
+type valueVec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*valueSeries
+}
+
+func newValueVec(typ, name, help string, labelNames []string) *valueVec {
+	return &valueVec{
+		desc:   desc{fqName: name, help: help, typ: typ, labelNames: labelNames},
+		series: make(map[string]*valueSeries),
+	}
+}
+
+// get 获取标签组合对应的序列，调用方需持有锁
+func (v *valueVec) get(labelValues []string) *valueSeries {
+	v.checkLabels(labelValues)
+	key := seriesKey(labelValues)
+	s, ok := v.series[key]
+	if !ok {
+		s = &valueSeries{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *valueVec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.series) == 0 && len(v.labelNames) > 0 {
+		return
+	}
+	v.writeHeader(w)
+	if len(v.labelNames) == 0 && len(v.series) == 0 {
+		v.writeSample(w, "", nil, "", "", 0)
+		return
+	}
+	for _, key := range sortedKeys(v.series) {
+		s := v.series[key]
+		v.writeSample(w, "", s.labelValues, "", "", s.value)
+	}
+}
+
+// CounterVec 只增不减的计数器
+type CounterVec struct {
+	*valueVec
+}
+
+// NewCounterVec 创建计数器并注册到全局注册表
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{newValueVec("counter", name, help, labelNames)}
+	Default.register(c)
+	return c
+}
+
+// Inc 计数加一
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加 delta（负数忽略）
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.get(labelValues).value += delta
+	c.mu.Unlock()
+}
+
+// GaugeVec 可增可减的仪表盘
+type GaugeVec struct {
+	*valueVec
+}
+
+// NewGaugeVec 创建仪表盘并注册到全局注册表
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{newValueVec("gauge", name, help, labelNames)}
+	Default.register(g)
+	return g
+}
+
+// Set 设置取值
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).value = value
+	g.mu.Unlock()
+}
+
+// Add 取值增加 delta
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).value += delta
+	g.mu.Unlock()
+}
+
+// ==================== 直方图 ====================
+
+// DefBuckets 默认的耗时分桶（秒）
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogramSeries 单个标签组合的直方图
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 // 各分桶的计数（非累计）
+	count       uint64
+	sum         float64
+}
+
+// HistogramVec 直方图
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// NewHistogramVec 创建直方图并注册到全局注册表，buckets 为空时使用 DefBuckets
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{
+		desc:    desc{fqName: name, help: help, typ: "histogram", labelNames: labelNames},
+		buckets: sorted,
+		series:  make(map[string]*histogramSeries),
+	}
+	Default.register(h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.checkLabels(labelValues)
+	key := seriesKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.series) == 0 {
+		return
+	}
+	h.writeHeader(w)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			h.writeSample(w, "_bucket", s.labelValues, "le", formatFloat(upper), float64(cumulative))
+		}
+		h.writeSample(w, "_bucket", s.labelValues, "le", "+Inf", float64(s.count))
+		h.writeSample(w, "_sum", s.labelValues, "", "", s.sum)
+		h.writeSample(w, "_count", s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// ==================== Func 指标 ====================
+
+// funcFamily 抓取时调用函数取值的指标
+type funcFamily struct {
+	desc
+	fn func() []Sample
+}
+
+func (f *funcFamily) write(w *bufio.Writer) {
+	samples := f.fn()
+	if len(samples) == 0 {
+		return
+	}
+	f.writeHeader(w)
+	for _, s := range samples {
+		if len(s.LabelValues) != len(f.labelNames) {
+			continue
+		}
+		f.writeSample(w, "", s.LabelValues, "", "", s.Value)
+	}
+}
+
+// NewGaugeFunc 注册抓取时取值的仪表盘，fn 返回空切片时不输出该指标
+func NewGaugeFunc(name, help string, fn func() []Sample, labelNames ...string) {
+	Default.register(&funcFamily{desc: desc{fqName: name, help: help, typ: "gauge", labelNames: labelNames}, fn: fn})
+}
+
+// NewCounterFunc 注册抓取时取值的计数器（取值需单调递增，如数据库连接池的累计等待次数）
+func NewCounterFunc(name, help string, fn func() []Sample, labelNames ...string) {
+	Default.register(&funcFamily{desc: desc{fqName: name, help: help, typ: "counter", labelNames: labelNames}, fn: fn})
+}
+
+// Value 无标签指标的单个样本
+func Value(v float64) []Sample {
+	return []Sample{{Value: v}}
+}
+
+// ==================== 格式化 ====================
+
+// formatFloat 按 Prometheus 文本格式输出浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
@@ -27,7 +27,7 @@ type Admin struct {
 	Nickname     string     `gorm:"size:100" json:"nickname"`                     // 昵称
 	Avatar       string     `gorm:"size:500" json:"avatar"`                       // 头像
 	Enable2FA    bool       `gorm:"default:false" json:"enable_2fa"`              // 是否启用两步验证
-	TOTPSecret   string     `gorm:"size:64" json:"-"`                             // TOTP密钥
+	TOTPSecret   string     `gorm:"size:255;serializer:encrypted" json:"-"`        // TOTP密钥（加密存储）
 	Status       int        `gorm:"default:1" json:"status"`                      // 状态：1启用 0禁用
 	LastLoginAt  *time.Time `json:"last_login_at"`                                // 最后登录时间
 	LastLoginIP  string     `gorm:"size:50" json:"last_login_ip"`                 // 最后登录IP
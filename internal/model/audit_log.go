@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// 审计日志字符串列的长度（字符数），须与 AuditLog 的 size 标签一致；写入前按此截断，
+// 保证数据库中保存的内容与计算哈希的内容相同
+const (
+	AuditUserTypeSize  = 20
+	AuditUsernameSize  = 100
+	AuditActionSize    = 100
+	AuditCategorySize  = 50
+	AuditTargetSize    = 50
+	AuditTargetIDSize  = 100
+	AuditIPSize        = 64
+	AuditRequestIDSize = 128
+)
+
+// AuditLog 操作审计日志
+//
+// 每条记录保存前一条记录的哈希（PrevHash）和自身内容的 HMAC（Hash），形成哈希链：
+// 修改任一记录会使其哈希校验失败，删除中间记录会使下一条的 PrevHash 对不上，
+// 删除最新的记录会与配置数据库中的锚点（AuditAnchorDB）对不上。
+// 详情和 User-Agent 加密存储，哈希按明文计算，因此密钥轮换不影响校验。
+// 记录只追加、不更新；过期记录由 clean_old_logs 任务归档到文件后删除（见 AuditLogArchive）。
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserType  string    `gorm:"size:20;index:idx_audit_actor,priority:1" json:"user_type"`   // user, admin, security
+	UserID    uint      `gorm:"index:idx_audit_actor,priority:2" json:"user_id"`             // 用户ID（管理员为0）
+	Username  string    `gorm:"size:100;index" json:"username"`                              // 操作者
+	Action    string    `gorm:"size:100;index" json:"action"`                                // 操作类型
+	Category  string    `gorm:"size:50;index" json:"category"`                               // 操作分类
+	Target    string    `gorm:"size:50;index:idx_audit_target,priority:1" json:"target"`     // 操作对象类型
+	TargetID  string    `gorm:"size:100;index:idx_audit_target,priority:2" json:"target_id"` // 操作对象ID
+	Detail    string    `gorm:"type:text;serializer:encrypted" json:"detail"`                // 详情
+	IP        string    `gorm:"size:64" json:"ip"`
+	UserAgent string    `gorm:"type:text;serializer:encrypted" json:"user_agent"`
+	RequestID string    `gorm:"size:128;index" json:"request_id"`     // 产生该记录的请求ID
+	PrevHash  string    `gorm:"size:64;uniqueIndex" json:"prev_hash"` // 前一条记录的哈希，第一条为空（唯一，防止并发写入产生分叉）
+	Hash      string    `gorm:"size:64;index" json:"hash"`            // 本条记录的哈希
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditLogArchive 审计日志归档记录
+//
+// 归档时从表头按 ID 顺序移出一段连续的记录写入归档文件，LastHash 为这段记录最后一条的哈希，
+// 表中剩余的第一条记录的 PrevHash 应与最近一次归档的 LastHash 一致，从而可以发现绕过归档的删除。
+type AuditLogArchive struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	FromID        uint      `json:"from_id"`                        // 归档的第一条记录ID
+	ToID          uint      `gorm:"index" json:"to_id"`             // 归档的最后一条记录ID
+	Count         int64     `json:"count"`                          // 归档记录数
+	StartTime     time.Time `json:"start_time"`                     // 第一条记录时间
+	EndTime       time.Time `json:"end_time"`                       // 最后一条记录时间
+	FirstPrevHash string    `gorm:"size:64" json:"first_prev_hash"` // 第一条记录的 PrevHash
+	LastHash      string    `gorm:"size:64" json:"last_hash"`       // 最后一条记录的哈希
+	FileName      string    `gorm:"size:255" json:"file_name"`      // 归档文件名（位于 server_log/archive）
+	FileHash      string    `gorm:"size:64" json:"file_hash"`       // 归档文件的 SHA-256
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 指定表名
+func (AuditLogArchive) TableName() string {
+	return "audit_log_archives"
+}
@@ -0,0 +1,38 @@
+// Package model 数据模型
+// backup.go - 备份类型与异地上传记录
+package model
+
+import (
+	"time"
+)
+
+// 备份类型
+const (
+	BackupKindManual    = "manual"    // 手动备份
+	BackupKindScheduled = "scheduled" // 定时备份（受保留策略管理）
+	BackupKindSnapshot  = "snapshot"  // 恢复前自动快照
+	BackupKindUploaded  = "uploaded"  // 上传的数据导出文件
+)
+
+// 上传状态
+const (
+	BackupUploadSuccess = "success"
+	BackupUploadFailed  = "failed"
+)
+
+// BackupUpload 备份异地上传记录
+type BackupUpload struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BackupID  uint      `gorm:"index" json:"backup_id"`
+	Target    string    `gorm:"type:varchar(100)" json:"target"`     // 目标名称
+	RemoteKey string    `gorm:"type:varchar(500)" json:"remote_key"` // 远程对象键
+	Status    string    `gorm:"type:varchar(20)" json:"status"`      // success/failed
+	Error     string    `gorm:"type:varchar(500)" json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (BackupUpload) TableName() string {
+	return "backup_uploads"
+}
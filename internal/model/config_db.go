@@ -189,6 +189,175 @@ func (r *RedisConfigDB) ToRedisConfig() *config.RedisConfig {
 	return cfg
 }
 
+// StorageConfigDB 对象存储配置模型
+//
+// 存储商品图片、工单附件等文件所用的对象存储设置，保存在 SQLite 配置数据库中。
+// 多台应用服务器部署时需使用 S3 兼容存储。
+type StorageConfigDB struct {
+	// ID 主键，自增
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Driver 存储驱动: local/s3
+	Driver string `gorm:"type:varchar(20);default:local" json:"driver"`
+
+	// LocalRoot 本地存储根目录
+	LocalRoot string `gorm:"type:varchar(500)" json:"local_root"`
+
+	// ==================== S3 兼容存储配置 ====================
+
+	// Endpoint 服务地址
+	Endpoint string `gorm:"type:varchar(500)" json:"endpoint"`
+
+	// Region 区域
+	Region string `gorm:"type:varchar(50)" json:"region"`
+
+	// Bucket 存储桶
+	Bucket string `gorm:"type:varchar(200)" json:"bucket"`
+
+	// AccessKey 访问密钥ID
+	AccessKey string `gorm:"type:varchar(200)" json:"access_key"`
+
+	// SecretKey 访问密钥（加密存储）
+	SecretKey string `gorm:"type:varchar(500)" json:"secret_key"`
+
+	// PathStyle 是否使用路径风格地址
+	PathStyle bool `gorm:"default:false" json:"path_style"`
+
+	// Prefix 对象键前缀
+	Prefix string `gorm:"type:varchar(200)" json:"prefix"`
+
+	// Timeout 请求超时（秒）
+	Timeout int `gorm:"default:30" json:"timeout"`
+
+	// CreatedAt 创建时间
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt 更新时间
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定数据库表名
+func (StorageConfigDB) TableName() string {
+	return "storage_configs"
+}
+
+// BackupConfigDB 备份配置模型
+//
+// 备份压缩、加密、定时备份、保留策略与异地存放设置，保存在 SQLite 配置数据库中，
+// 不随主数据库的恢复而改变。口令与各目标的密钥均加密存储。
+type BackupConfigDB struct {
+	// ID 主键，自增
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Compress 是否压缩 SQL 导出文件
+	Compress bool `gorm:"default:true" json:"compress"`
+
+	// Encrypt 是否加密备份文件
+	Encrypt bool `gorm:"default:true" json:"encrypt"`
+
+	// EncryptionMode 加密密钥来源: config_key/passphrase
+	EncryptionMode string `gorm:"type:varchar(20);default:config_key" json:"encryption_mode"`
+
+	// Passphrase 备份口令（加密存储）
+	Passphrase string `gorm:"type:varchar(500)" json:"passphrase"`
+
+	// ScheduleEnabled 是否启用定时备份
+	ScheduleEnabled bool `gorm:"default:false" json:"schedule_enabled"`
+
+	// IntervalHours 定时备份间隔（小时）
+	IntervalHours int `gorm:"default:24" json:"interval_hours"`
+
+	// IncludeObjects 定时备份是否包含对象存储文件
+	IncludeObjects bool `gorm:"default:false" json:"include_objects"`
+
+	// ==================== 保留策略 ====================
+
+	// KeepLast 保留最近 N 份
+	KeepLast int `gorm:"default:0" json:"keep_last"`
+
+	// KeepDaily 保留最近 N 天每天最新的一份
+	KeepDaily int `gorm:"default:0" json:"keep_daily"`
+
+	// KeepWeekly 保留最近 N 周每周最新的一份
+	KeepWeekly int `gorm:"default:0" json:"keep_weekly"`
+
+	// KeepMonthly 保留最近 N 月每月最新的一份
+	KeepMonthly int `gorm:"default:0" json:"keep_monthly"`
+
+	// Targets 异地存放目标（JSON 数组，密钥字段加密存储）
+	Targets string `gorm:"type:text" json:"targets"`
+
+	// CreatedAt 创建时间
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt 更新时间
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定数据库表名
+func (BackupConfigDB) TableName() string {
+	return "backup_configs"
+}
+
+// AuditAnchorDB 审计日志锚点
+//
+// 保存在 SQLite 配置数据库中，与主数据库的审计日志分开存放：HMACKey 为计算审计日志哈希的密钥，
+// HeadID/HeadHash 为最近写入的记录。只能访问主数据库时既无法重新计算哈希，
+// 也无法在删除最新的记录后不被发现。每个主数据库（按类型、地址和库名区分）一条记录。
+type AuditAnchorDB struct {
+	// ID 主键，自增
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// DatabaseName 主数据库标识
+	DatabaseName string `gorm:"type:varchar(500);uniqueIndex" json:"database_name"`
+
+	// HMACKey 哈希密钥（Base64）
+	HMACKey string `gorm:"type:varchar(100)" json:"-"`
+
+	// HeadID 最近写入的审计日志ID
+	HeadID uint `json:"head_id"`
+
+	// HeadHash 最近写入的审计日志哈希
+	HeadHash string `gorm:"type:varchar(64)" json:"head_hash"`
+
+	// CreatedAt 创建时间
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt 更新时间
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定数据库表名
+func (AuditAnchorDB) TableName() string {
+	return "audit_anchors"
+}
+
+// MaintenanceLockDB 维护模式锁
+//
+// 保存在 SQLite 配置数据库中，共用配置目录的所有实例据此进入维护模式（返回 503 并暂停后台任务）。
+// 只有一条记录（ID 为 1），持有者定期刷新 HeartbeatAt，持有者异常退出后心跳超时的锁视为失效。
+type MaintenanceLockDB struct {
+	// ID 主键，固定为 1
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Owner 持有者（主机名:进程号）
+	Owner string `gorm:"type:varchar(200)" json:"owner"`
+
+	// Reason 维护原因
+	Reason string `gorm:"type:varchar(200)" json:"reason"`
+
+	// Since 进入维护模式的时间
+	Since time.Time `json:"since"`
+
+	// HeartbeatAt 持有者最近一次刷新的时间
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+}
+
+// TableName 指定数据库表名
+func (MaintenanceLockDB) TableName() string {
+	return "maintenance_locks"
+}
+
 // parseCommaSeparated 解析逗号分隔的字符串
 func parseCommaSeparated(s string) []string {
 	if s == "" {
@@ -224,7 +393,7 @@ func InitConfigDB(configDir string) error {
 	}
 
 	// 自动迁移配置表
-	if err := ConfigDB.AutoMigrate(&DBConfigDB{}, &RedisConfigDB{}); err != nil {
+	if err := ConfigDB.AutoMigrate(&DBConfigDB{}, &RedisConfigDB{}, &StorageConfigDB{}, &BackupConfigDB{}, &AuditAnchorDB{}, &MaintenanceLockDB{}); err != nil {
 		return err
 	}
 
@@ -0,0 +1,57 @@
+// Package model 数据模型
+// currency.go - 多币种定价与汇率模型
+package model
+
+import (
+	"time"
+)
+
+// 常用币种代码
+const (
+	CurrencyCNY  = "CNY"
+	CurrencyUSD  = "USD"
+	CurrencyEUR  = "EUR"
+	CurrencyUSDT = "USDT"
+)
+
+// DefaultBaseCurrency 默认店铺基础货币
+const DefaultBaseCurrency = CurrencyCNY
+
+// 汇率来源
+const (
+	RateSourceManual = "manual" // 管理员手动维护
+	RateSourceFile   = "file"   // 本地汇率文件导入
+)
+
+// ExchangeRate 汇率表
+// Rate 表示 1 单位基础货币可兑换多少目标货币，如基础货币CNY、目标USD时 Rate≈0.14
+type ExchangeRate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Currency  string    `gorm:"size:10;uniqueIndex" json:"currency"`    // 目标币种代码
+	Rate      float64   `gorm:"type:decimal(18,8)" json:"rate"`         // 汇率（1基础货币=Rate目标货币）
+	Source    string    `gorm:"size:20;default:'manual'" json:"source"` // 来源：manual/file
+	Remark    string    `gorm:"size:255" json:"remark"`                 // 备注
+	UpdatedBy string    `gorm:"size:100" json:"updated_by"`             // 最后修改人
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProductPrice 商品在其他币种下的固定售价（未设置则按汇率换算）
+type ProductPrice struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProductID uint      `gorm:"uniqueIndex:idx_product_currency" json:"product_id"`       // 商品ID
+	Currency  string    `gorm:"size:10;uniqueIndex:idx_product_currency" json:"currency"` // 币种代码
+	Price     float64   `gorm:"type:decimal(12,2)" json:"price"`                          // 该币种下的单价
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+// TableName 设置表名
+func (ProductPrice) TableName() string {
+	return "product_prices"
+}
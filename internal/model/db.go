@@ -19,7 +19,53 @@ import (
 var DB *gorm.DB
 var DBConnected bool
 
+// SchemaVersion 数据库结构版本，即当前程序的最新迁移版本号
+// 用于校验备份和导出文件能否恢复到当前版本
+var SchemaVersion = LatestMigrationVersion()
+
+// InitDB 连接主数据库并执行未完成的迁移
 func InitDB(cfg *config.DBConfig) error {
+	var err error
+	DB, err = OpenDB(cfg)
+	if err != nil {
+		DBConnected = false
+		return err
+	}
+
+	if _, err = MigrateUp(DB); err != nil {
+		DBConnected = false
+		return fmt.Errorf("%w: %v", ErrMigrationFailed, err)
+	}
+
+	if err := registerTracing(DB); err != nil {
+		log.Printf("警告: 注册数据库查询追踪失败: %v", err)
+	}
+
+	DBConnected = true
+	return nil
+}
+
+// CloseDB 关闭主数据库和配置数据库连接，在停机时最后调用
+func CloseDB() error {
+	var firstErr error
+	for _, db := range []*gorm.DB{DB, ConfigDB} {
+		if db == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	DBConnected = false
+	return firstErr
+}
+
+// OpenDB 连接数据库但不执行迁移，供迁移命令行等场景使用
+func OpenDB(cfg *config.DBConfig) (*gorm.DB, error) {
 	var dialector gorm.Dialector
 
 	switch cfg.Type {
@@ -51,19 +97,32 @@ func InitDB(cfg *config.DBConfig) error {
 		},
 	)
 
-	var err error
-	DB, err = gorm.Open(dialector, &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: newLogger,
 	})
 	if err != nil {
-		DBConnected = false
-		return err
+		return nil, err
 	}
 
-	// 自动迁移（注意：OperationLog 已改为文件存储，不再使用数据库）
-	err = DB.AutoMigrate(&User{}, &Order{}, &Product{}, &AdminUser{}, &SystemSetting{}, &EmailVerifyCode{}, &EmailConfigDB{}, &PaymentConfigDB{}, &SystemConfigDB{}, &LoginAttempt{}, &Announcement{}, &ProductCategory{}, &Coupon{}, &CouponUsage{}, &DatabaseBackup{}, &UserSession{}, &AdminSession{}, &LoginFailureRecord{},
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	return db, nil
+}
+
+// AllModels 返回主数据库的全部模型（按迁移顺序）
+// 基线迁移与数据导出/导入共用此列表；新增模型时在此登记，并在 migrations.go 追加创建该表的迁移
+func AllModels() []interface{} {
+	return []interface{}{
+		&User{}, &Order{}, &Product{}, &AdminUser{}, &SystemSetting{}, &EmailVerifyCode{}, &EmailConfigDB{}, &PaymentConfigDB{}, &SystemConfigDB{}, &LoginAttempt{}, &Announcement{}, &ProductCategory{}, &Coupon{}, &CouponUsage{}, &DatabaseBackup{}, &BackupUpload{}, &UserSession{}, &AdminSession{}, &LoginFailureRecord{},
 		// 客服支持系统
-		&SupportTicket{}, &SupportMessage{}, &SupportStaff{}, &SupportStaffSession{}, &SupportConfigDB{}, &LiveChat{}, &LiveChatMessage{},
+		&SupportTicket{}, &SupportMessage{}, &SupportStaff{}, &SupportStaffSession{}, &SupportConfigDB{}, &LiveChat{}, &LiveChatMessage{}, &SupportSLAPolicy{}, &SupportMacro{}, &SupportMacroUsage{}, &SupportOrderAction{}, &SupportRefundRequest{},
 		// 手动卡密
 		&ManualKami{},
 		// FAQ系统
@@ -111,24 +170,18 @@ func InitDB(cfg *config.DBConfig) error {
 		// 充值优惠活动
 		&RechargePromo{}, &RechargePromoUsage{},
 		// 首页配置
-		&HomepageConfig{})
-	if err != nil {
-		DBConnected = false
-		return err
+		&HomepageConfig{},
+		// 多币种定价
+		&ExchangeRate{}, &ProductPrice{},
+		// 链上USDT收款
+		&USDTPayment{},
+		// 支付路由与手续费
+		&PaymentRule{}, &PaymentFee{},
+		// 阶梯价与价格等级
+		&ProductPriceTier{}, &PriceLevel{},
+		// 操作审计日志
+		&AuditLog{}, &AuditLogArchive{},
 	}
-
-	sqlDB, err := DB.DB()
-	if err != nil {
-		DBConnected = false
-		return err
-	}
-
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-
-	DBConnected = true
-	return nil
 }
 
 // TestConnection 测试数据库连接
@@ -23,8 +23,11 @@
 //   - AdminSession: 管理员会话模型
 //   - LoginDevice: 登录设备模型
 //
-// 注意：OperationLog 已移除，操作日志现在使用加密的CSV文件存储
-// 参见 service/log_service.go 中的 LogEntry 结构
+//   - AuditLog: 操作审计日志模型（哈希链防篡改）
+//   - AuditLogArchive: 审计日志归档记录
+//
+// 注意：旧的 OperationLog 已移除，操作日志写入 AuditLog，可选同时写入加密的CSV文件
+// 参见 service/log_service.go
 //
 // 数据库支持：
 //   - MySQL 5.7+
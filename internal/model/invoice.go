@@ -31,6 +31,9 @@ type Invoice struct {
 	Title         string         `gorm:"size:200" json:"title"`                      // 发票抬头
 	TaxNo         string         `gorm:"size:50" json:"tax_no"`                      // 税号（企业发票）
 	Amount        float64        `json:"amount"`                                     // 发票金额
+	Currency      string         `gorm:"size:10" json:"currency"`                    // 发票金额币种
+	PayCurrency   string         `gorm:"size:10" json:"pay_currency"`                // 订单实际支付币种
+	PayAmount     float64        `gorm:"default:0" json:"pay_amount"`                // 订单实际支付币种金额
 	Email         string         `gorm:"size:255" json:"email"`                      // 接收邮箱
 	Phone         string         `gorm:"size:20" json:"phone"`                       // 联系电话
 	Address       string         `gorm:"size:500" json:"address"`                    // 企业地址（企业发票）
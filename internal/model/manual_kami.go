@@ -12,7 +12,7 @@ type ManualKami struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	ProductID uint           `gorm:"index" json:"product_id"`       // 关联商品ID
 	KamiCode  string         `gorm:"type:varchar(255)" json:"kami_code"` // 卡密内容
-	Status    int            `gorm:"default:0" json:"status"`       // 状态：0可用 1已售出 2已禁用
+	Status    int            `gorm:"default:0" json:"status"`       // 状态：0可用 1已售出 2已禁用 3已作废
 	OrderID   uint           `gorm:"default:0" json:"order_id"`     // 关联订单ID（售出后填充）
 	OrderNo   string         `gorm:"type:varchar(64)" json:"order_no"` // 关联订单号
 	SoldAt    *time.Time     `json:"sold_at"`                       // 售出时间
@@ -26,6 +26,7 @@ const (
 	ManualKamiStatusAvailable = 0 // 可用
 	ManualKamiStatusSold      = 1 // 已售出
 	ManualKamiStatusDisabled  = 2 // 已禁用
+	ManualKamiStatusInvalid   = 3 // 已作废（售后更换下来的失效卡密，不再回到卡密池）
 )
 
 // ProductType 商品类型常量
@@ -0,0 +1,293 @@
+// Package model 数据模型
+// migration.go - 版本化数据库迁移
+//
+// 迁移按版本号顺序执行，已执行的版本记录在 schema_migrations 表中。每个迁移在独立事务内执行
+// （MySQL 的 DDL 会隐式提交，无法整体回滚）；可提供 Down 用于回滚。版本 1 为基线迁移，
+// 创建全部模型对应的表，已有数据库执行基线迁移时只会补齐缺失的表和字段。
+//
+// 新增或修改表结构、回填数据时，在 migrations.go 末尾追加新版本，不要修改已发布的迁移。
+package model
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MigrationFunc 迁移步骤，dialect 为 sqlite/mysql/postgres
+type MigrationFunc func(tx *gorm.DB, dialect string) error
+
+// Migration 数据库迁移
+type Migration struct {
+	Version int           // 版本号（从 1 开始连续递增）
+	Name    string        // 迁移说明
+	Up      MigrationFunc // 升级
+	Down    MigrationFunc // 回滚（为空表示不可回滚）
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"type:varchar(200)" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+	Duration  int64     `json:"duration"` // 执行耗时（毫秒）
+}
+
+// TableName 设置表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	Applied    bool       `json:"applied"`
+	AppliedAt  *time.Time `json:"applied_at,omitempty"`
+	Reversible bool       `json:"reversible"`
+	Unknown    bool       `json:"unknown"` // 数据库中存在、当前程序中未定义（数据库由更新版本的程序迁移过）
+}
+
+// DialectSQL 按数据库类型执行的 SQL 语句，未单独列出的类型使用 Default
+type DialectSQL struct {
+	Default  []string
+	SQLite   []string
+	MySQL    []string
+	Postgres []string
+}
+
+// Func 转换为迁移步骤
+func (d DialectSQL) Func() MigrationFunc {
+	return func(tx *gorm.DB, dialect string) error {
+		statements := d.Default
+		switch {
+		case dialect == "sqlite" && d.SQLite != nil:
+			statements = d.SQLite
+		case dialect == "mysql" && d.MySQL != nil:
+			statements = d.MySQL
+		case dialect == "postgres" && d.Postgres != nil:
+			statements = d.Postgres
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// ErrMigrationFailed 数据库已连接但迁移失败（区别于连接失败，调用方不应切换到其他数据库）
+var ErrMigrationFailed = errors.New("数据库迁移失败")
+
+// migrationLockKey 迁移锁标识（MySQL GET_LOCK 名称 / PostgreSQL 咨询锁键值）
+const (
+	migrationLockName = "user_frontend_schema_migrations"
+	migrationLockKey  = 720193
+)
+
+// LatestMigrationVersion 当前程序定义的最新迁移版本
+func LatestMigrationVersion() int {
+	latest := 0
+	for _, m := range migrations {
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+// validateMigrations 检查迁移定义：版本号从 1 开始连续且不重复
+func validateMigrations() error {
+	sorted := sortedMigrations()
+	for i, m := range sorted {
+		if m.Version != i+1 {
+			return fmt.Errorf("迁移版本号必须从 1 开始连续递增，版本 %d 位置错误", m.Version)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("迁移 %d 缺少 Up", m.Version)
+		}
+	}
+	return nil
+}
+
+// sortedMigrations 按版本号排序的迁移列表
+func sortedMigrations() []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// appliedMigrations 读取已执行的迁移
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	var records []SchemaMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// GetMigrationStatus 获取全部迁移的执行状态
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []MigrationStatus
+	known := make(map[int]bool)
+	for _, m := range sortedMigrations() {
+		known[m.Version] = true
+		s := MigrationStatus{Version: m.Version, Name: m.Name, Reversible: m.Down != nil}
+		if r, ok := applied[m.Version]; ok {
+			at := r.AppliedAt
+			s.Applied, s.AppliedAt = true, &at
+		}
+		list = append(list, s)
+	}
+	for v, r := range applied {
+		if !known[v] {
+			at := r.AppliedAt
+			list = append(list, MigrationStatus{Version: v, Name: r.Name, Applied: true, AppliedAt: &at, Unknown: true})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// MigrateUp 执行全部未执行的迁移，返回执行的数量
+func MigrateUp(db *gorm.DB) (int, error) {
+	if err := validateMigrations(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := withMigrationLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for v := range applied {
+			if v > LatestMigrationVersion() {
+				log.Printf("警告: 数据库结构版本(%d)高于当前程序(%d)，请确认程序版本", v, LatestMigrationVersion())
+				break
+			}
+		}
+
+		dialect := conn.Dialector.Name()
+		for _, m := range sortedMigrations() {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			start := time.Now()
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx, dialect); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:   m.Version,
+					Name:      m.Name,
+					AppliedAt: time.Now(),
+					Duration:  time.Since(start).Milliseconds(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("迁移 %d（%s）失败: %v", m.Version, m.Name, err)
+			}
+			log.Printf("数据库迁移 %d（%s）已完成，耗时 %s", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrateDown 按版本倒序回滚最近执行的 steps 个迁移，返回回滚的数量
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, nil
+	}
+
+	byVersion := make(map[int]Migration)
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	count := 0
+	err := withMigrationLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		dialect := conn.Dialector.Name()
+		for _, v := range versions {
+			if count >= steps {
+				break
+			}
+			m, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("迁移 %d 未在当前程序中定义，无法回滚", v)
+			}
+			if m.Down == nil {
+				return fmt.Errorf("迁移 %d（%s）不可回滚", m.Version, m.Name)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx, dialect); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("回滚迁移 %d（%s）失败: %v", m.Version, m.Name, err)
+			}
+			log.Printf("数据库迁移 %d（%s）已回滚", m.Version, m.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// withMigrationLock 在持有迁移锁的单个连接上执行，避免多个实例同时迁移
+// SQLite 为单文件数据库，依靠事务串行化，不额外加锁
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(tx *gorm.DB) error {
+		// Connection 返回的实例会累积链式条件，使用新会话后每次调用互不影响
+		conn := tx.Session(&gorm.Session{NewDB: true})
+		switch conn.Dialector.Name() {
+		case "mysql":
+			var got int
+			if err := conn.Raw("SELECT GET_LOCK(?, 300)", migrationLockName).Scan(&got).Error; err != nil {
+				return err
+			}
+			if got != 1 {
+				return errors.New("等待迁移锁超时，可能有其他实例正在迁移")
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+		case "postgres":
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		}
+		return fn(conn)
+	})
+}
@@ -0,0 +1,79 @@
+// Package model 数据模型
+// migrations.go - 数据库迁移列表
+package model
+
+import "gorm.io/gorm"
+
+// migrations 按版本号登记的全部迁移，只允许在末尾追加
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		// 基线：创建全部模型对应的表（已有数据库只补齐缺失的表和字段），不可回滚
+		Up: func(tx *gorm.DB, dialect string) error {
+			return tx.AutoMigrate(AllModels()...)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "backfill_backup_snapshot_kind",
+		// 备份类型字段上线前创建的恢复前快照被默认标记为手动备份，按备注回填为快照
+		Up: DialectSQL{
+			Default: []string{
+				"UPDATE database_backups SET kind = 'snapshot' WHERE kind = 'manual' AND remark LIKE '恢复前自动快照%'",
+			},
+		}.Func(),
+		Down: DialectSQL{
+			Default: []string{
+				"UPDATE database_backups SET kind = 'manual' WHERE kind = 'snapshot' AND remark LIKE '恢复前自动快照%'",
+			},
+		}.Func(),
+	},
+	{
+		Version: 3,
+		Name:    "encrypt_secrets",
+		// 加密存储 TOTP 密钥、SMTP 密码和支付密钥：加密后长度增加，MySQL/PostgreSQL 需放宽 TOTP 密钥列
+		// （SQLite 不限制 varchar 长度）；随后加密升级前以明文存储的值
+		Up: func(tx *gorm.DB, dialect string) error {
+			if dialect != "sqlite" {
+				for _, m := range []interface{}{&User{}, &AdminUser{}, &SystemConfigDB{}, &Admin{}, &SupportStaff{}} {
+					if err := tx.Migrator().AlterColumn(m, "TOTPSecret"); err != nil {
+						return err
+					}
+				}
+			}
+			return RewriteSecrets(tx, EncryptSecret)
+		},
+		Down: func(tx *gorm.DB, dialect string) error {
+			return RewriteSecrets(tx, func(v string) (string, error) {
+				return DecryptSecret(v), nil
+			})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create_audit_logs",
+		// 操作日志从加密 CSV 文件改为数据库存储（带哈希链），升级前的文件日志保留在 server_log 目录
+		Up: func(tx *gorm.DB, dialect string) error {
+			return tx.AutoMigrate(&AuditLog{}, &AuditLogArchive{})
+		},
+		Down: func(tx *gorm.DB, dialect string) error {
+			return tx.Migrator().DropTable(&AuditLogArchive{}, &AuditLog{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_usdt_payment_start_block",
+		// 链上收款记录开始扫描的区块，JSON-RPC 模式重启后从最早的待支付收款处继续扫描
+		// （新建的数据库已由基线迁移创建该字段）
+		Up: func(tx *gorm.DB, dialect string) error {
+			if tx.Migrator().HasColumn(&USDTPayment{}, "StartBlock") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&USDTPayment{}, "StartBlock")
+		},
+		Down: func(tx *gorm.DB, dialect string) error {
+			return tx.Migrator().DropColumn(&USDTPayment{}, "StartBlock")
+		},
+	},
+}
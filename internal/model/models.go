@@ -15,13 +15,14 @@ type User struct {
 	Phone             string         `gorm:"type:varchar(20)" json:"phone"`
 	EmailVerified     bool           `gorm:"default:false" json:"email_verified"`    // 邮箱是否已验证
 	Enable2FA         bool           `gorm:"default:false" json:"enable_2fa"`        // 是否启用两步验证
-	TOTPSecret        string         `gorm:"type:varchar(100)" json:"-"`
+	TOTPSecret        string         `gorm:"type:varchar(255);serializer:encrypted" json:"-"`
 	PreferEmailAuth   bool           `gorm:"default:true" json:"prefer_email_auth"`  // 登录时优先使用邮箱验证（否则使用TOTP）
 	PayPassword       string         `gorm:"type:varchar(255)" json:"-"`             // 支付密码（bcrypt加密）
 	PayPasswordSet    bool           `gorm:"default:false" json:"pay_password_set"`  // 是否已设置支付密码
 	PayPasswordErrors int            `gorm:"default:0" json:"-"`                     // 支付密码连续错误次数
 	PayPasswordLockAt *time.Time     `json:"-"`                                      // 支付密码锁定时间
 	Status            int            `gorm:"default:1" json:"status"`                // 1:正常 0:禁用
+	Level             string         `gorm:"type:varchar(20);default:''" json:"level"` // 用户等级：retail/vip/reseller（为空视为retail）
 	LastLoginAt       *time.Time     `json:"last_login_at"`
 	LastLoginIP       string         `gorm:"type:varchar(50)" json:"last_login_ip"`
 	CreatedAt         time.Time      `json:"created_at"`
@@ -29,6 +30,21 @@ type User struct {
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// 用户等级常量
+const (
+	UserLevelRetail   = "retail"   // 普通零售用户
+	UserLevelVIP      = "vip"      // VIP用户
+	UserLevelReseller = "reseller" // 分销商
+)
+
+// GetLevel 获取用户等级（未设置时为普通零售用户）
+func (u *User) GetLevel() string {
+	if u.Level == "" {
+		return UserLevelRetail
+	}
+	return u.Level
+}
+
 // EmailVerifyCode 邮箱验证码
 type EmailVerifyCode struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
@@ -46,7 +62,7 @@ type AdminUser struct {
 	Username     string         `gorm:"type:varchar(100);uniqueIndex" json:"username"`
 	PasswordHash string         `gorm:"type:varchar(255)" json:"-"`
 	Enable2FA    bool           `gorm:"default:false" json:"enable_2fa"`
-	TOTPSecret   string         `gorm:"type:varchar(100)" json:"-"`
+	TOTPSecret   string         `gorm:"type:varchar(255);serializer:encrypted" json:"-"`
 	Role         string         `gorm:"type:varchar(50);default:'admin'" json:"role"`
 	LastLoginAt  *time.Time     `json:"last_login_at"`
 	LastLoginIP  string         `gorm:"type:varchar(50)" json:"last_login_ip"`
@@ -73,6 +89,14 @@ type Product struct {
 	ImageURL     string         `gorm:"type:varchar(500)" json:"image_url"`
 	CategoryID   uint           `gorm:"default:0" json:"category_id"`  // 分类ID
 	ProductType  int            `gorm:"default:1" json:"product_type"` // 商品类型：1手动卡密
+	// 多规格（父商品定义规格维度，每个规格是一条 ParentID 指向父商品的子商品，拥有独立价格、库存和卡密池）
+	ParentID       uint      `gorm:"default:0;index" json:"parent_id"`                   // 父商品ID（0表示独立商品或父商品）
+	VariantOptions string    `gorm:"type:text" json:"variant_options,omitempty"`         // 父商品：规格维度定义（JSON）
+	OptionValues   string    `gorm:"type:varchar(500)" json:"option_values,omitempty"`   // 规格商品：各维度取值（JSON）
+	Variants       []Product `gorm:"-" json:"variants,omitempty"`                        // 规格列表（非数据库字段）
+	// 续费定价（续费单价优先，未设置时按售价和续费折扣计算）
+	RenewalPrice    float64 `gorm:"default:0" json:"renewal_price"`    // 续费单价（0表示按售价）
+	RenewalDiscount float64 `gorm:"default:0" json:"renewal_discount"` // 续费折扣百分比（如 10 表示续费减免10%）
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
@@ -88,14 +112,26 @@ type Order struct {
 	ProductID      uint           `json:"product_id"`
 	ProductName    string         `gorm:"type:varchar(200)" json:"product_name"`
 	Quantity       int            `gorm:"default:1" json:"quantity"`              // 购买数量
-	OriginalPrice  float64        `json:"original_price"`                         // 原价（锁定商品价格，单价*数量）
+	ListPrice      float64        `gorm:"default:0" json:"list_price"`            // 下单时的商品标价（单价）
+	UnitPrice      float64        `gorm:"default:0" json:"unit_price"`            // 成交单价（按阶梯价与用户价格等级计算）
+	PriceLevel     string         `gorm:"type:varchar(20)" json:"price_level"`    // 下单时的用户价格等级
+	OriginalPrice  float64        `json:"original_price"`                         // 原价（锁定成交单价*数量）
 	DiscountAmount float64        `gorm:"default:0" json:"discount_amount"`       // 优惠金额
-	Price          float64        `json:"price"`                                  // 实际应付金额
+	PaymentFee     float64        `gorm:"default:0" json:"payment_fee"`           // 支付方式手续费（正数为附加费，负数为支付优惠）
+	FeeMethod      string         `gorm:"type:varchar(50)" json:"fee_method"`     // 手续费对应的支付方式
+	Price          float64        `json:"price"`                                  // 实际应付金额（原价-优惠+支付手续费）
 	PaidAmount     float64        `gorm:"default:0" json:"paid_amount"`           // 实际支付金额（用于验证）
+	Currency       string         `gorm:"type:varchar(10)" json:"currency"`       // 订单基础货币（Price/OriginalPrice 的币种）
+	PayCurrency    string         `gorm:"type:varchar(10)" json:"pay_currency"`   // 锁定的支付币种
+	PayAmount      float64        `gorm:"default:0" json:"pay_amount"`            // 锁定的支付币种应付金额
+	ExchangeRate   float64        `gorm:"default:0" json:"exchange_rate"`         // 锁定的汇率（1基础货币=ExchangeRate支付币种）
 	CouponID       uint           `gorm:"default:0" json:"coupon_id"`             // 使用的优惠券ID
 	CouponCode     string         `gorm:"type:varchar(50)" json:"coupon_code"`    // 使用的优惠券码
 	Duration       int            `json:"duration"`
 	DurationUnit   string         `gorm:"type:varchar(20)" json:"duration_unit"`
+	OrderType      string         `gorm:"type:varchar(20);default:'normal';index" json:"order_type"` // 订单类型：normal 购买 / renewal 续费
+	RenewOrderID   uint           `gorm:"default:0;index" json:"renew_order_id"`                    // 续费订单关联的原始购买订单ID
+	ExpireAt       *time.Time     `json:"expire_at"`                                                // 到期时间（原始订单为续费链当前到期时间，续费订单为本次续费后的到期时间）
 	Status         int            `gorm:"default:0" json:"status"` // 0:待支付 1:已支付 2:已完成 3:已取消 4:已退款
 	PaymentMethod  string         `gorm:"type:varchar(50)" json:"payment_method"`
 	PaymentTime    *time.Time     `json:"payment_time"`
@@ -116,11 +152,31 @@ const (
 	OrderStatusRefunded  = 4 // 已退款
 )
 
+// 订单类型常量
+const (
+	OrderTypeNormal  = "normal"  // 普通购买
+	OrderTypeRenewal = "renewal" // 续费（延长原始订单的有效期，不分配新卡密）
+)
+
+// IsRenewal 是否为续费订单
+func (o *Order) IsRenewal() bool {
+	return o.OrderType == OrderTypeRenewal
+}
+
+// GetPayAmount 获取支付币种下的应付金额
+// 未锁定支付币种的旧订单直接返回 Price
+func (o *Order) GetPayAmount() float64 {
+	if o.PayCurrency != "" && o.PayAmount > 0 {
+		return o.PayAmount
+	}
+	return o.Price
+}
+
 // ValidatePaymentAmount 验证支付金额
-// 参数：paidAmount 实际支付金额
+// 参数：paidAmount 实际支付金额（支付币种）
 // 返回：是否有效，误差不超过0.01
 func (o *Order) ValidatePaymentAmount(paidAmount float64) bool {
-	diff := o.Price - paidAmount
+	diff := o.GetPayAmount() - paidAmount
 	if diff < 0 {
 		diff = -diff
 	}
@@ -144,7 +200,7 @@ type EmailConfigDB struct {
 	SMTPHost     string    `gorm:"type:varchar(255)" json:"smtp_host"`
 	SMTPPort     int       `gorm:"default:465" json:"smtp_port"`
 	SMTPUser     string    `gorm:"type:varchar(255)" json:"smtp_user"`
-	SMTPPassword string    `gorm:"type:varchar(255)" json:"smtp_password"`
+	SMTPPassword string    `gorm:"type:varchar(255);serializer:encrypted" json:"smtp_password"`
 	FromName     string    `gorm:"type:varchar(100)" json:"from_name"`
 	FromEmail    string    `gorm:"type:varchar(255)" json:"from_email"`
 	Encryption   string    `gorm:"type:varchar(20);default:'ssl'" json:"encryption"` // 加密方式：none/ssl/starttls
@@ -172,7 +228,7 @@ type SystemConfigDB struct {
 	AdminUsername        string    `gorm:"type:varchar(100)" json:"admin_username"`          // 管理员用户名
 	AdminPassword        string    `gorm:"type:varchar(255)" json:"admin_password"`          // 管理员密码
 	Enable2FA            bool      `gorm:"default:false" json:"enable_2fa"`                  // 是否启用两步验证
-	TOTPSecret           string    `gorm:"type:varchar(100)" json:"totp_secret"`             // TOTP密钥
+	TOTPSecret           string    `gorm:"type:varchar(255);serializer:encrypted" json:"totp_secret"`             // TOTP密钥
 	EnableWhitelist      bool      `gorm:"default:false" json:"enable_whitelist"`            // 是否启用IP白名单
 	IPWhitelist          string    `gorm:"type:text" json:"ip_whitelist"`                    // IP白名单（JSON数组格式）
 	CreatedAt            time.Time `json:"created_at"`
@@ -254,14 +310,20 @@ type CouponUsage struct {
 
 // DatabaseBackup 数据库备份记录
 type DatabaseBackup struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Filename  string    `gorm:"type:varchar(255)" json:"filename"`
-	FilePath  string    `gorm:"type:varchar(500)" json:"file_path"`
-	FileSize  int64     `json:"file_size"`
-	DBType    string    `gorm:"type:varchar(20)" json:"db_type"` // sqlite, mysql, postgres
-	Remark    string    `gorm:"type:varchar(255)" json:"remark"`
-	CreatedBy string    `gorm:"type:varchar(100)" json:"created_by"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Filename       string    `gorm:"type:varchar(255)" json:"filename"`
+	FilePath       string    `gorm:"type:varchar(500)" json:"file_path"`
+	FileSize       int64     `json:"file_size"`
+	DBType         string    `gorm:"type:varchar(20)" json:"db_type"`      // sqlite, mysql, postgres
+	IncludeObjects bool      `gorm:"default:false" json:"include_objects"` // 是否包含对象存储文件
+	Kind           string    `gorm:"type:varchar(20);default:manual;index" json:"kind"` // manual/scheduled/snapshot
+	Compressed     bool      `gorm:"default:false" json:"compressed"`      // 是否 gzip 压缩
+	Encrypted      bool      `gorm:"default:false" json:"encrypted"`       // 是否加密
+	Checksum       string    `gorm:"type:varchar(64)" json:"checksum"`     // 备份文件 SHA-256（旧备份为空）
+	SchemaVersion  int       `gorm:"default:0" json:"schema_version"`      // 备份时的数据库结构版本（0 表示未知）
+	Remark         string    `gorm:"type:varchar(255)" json:"remark"`
+	CreatedBy      string    `gorm:"type:varchar(100)" json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 // UserSession 用户会话（数据库持久化）
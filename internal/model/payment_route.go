@@ -0,0 +1,101 @@
+// Package model 数据模型
+// payment_route.go - 支付方式路由规则与手续费模型
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 支付方式代码（与 /api/payment/methods 返回的键一致）
+const (
+	PayMethodPayPal    = "paypal"
+	PayMethodStripe    = "stripe"
+	PayMethodAlipayF2F = "alipay_f2f"
+	PayMethodWechatPay = "wechat_pay"
+	PayMethodYiPay     = "yi_pay"
+	PayMethodUSDT      = "usdt"
+	PayMethodBalance   = "balance"
+	PayMethodAll       = "*" // 规则匹配所有支付方式
+)
+
+// 路由规则动作
+const (
+	PaymentRuleShow = "show" // 显示该支付方式
+	PaymentRuleHide = "hide" // 隐藏该支付方式
+)
+
+// 手续费类型
+const (
+	PaymentFeePercent = "percent" // 按比例（Value 为百分比）
+	PaymentFeeFixed   = "fixed"   // 固定金额（基础货币）
+)
+
+// PaymentRule 支付方式路由规则
+// 按优先级从高到低匹配，每种支付方式取第一条命中的规则决定显示或隐藏；
+// 条件字段为空（或金额为0）表示不限制
+type PaymentRule struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100" json:"name"`                 // 规则名称
+	Method      string    `gorm:"size:50;index" json:"method"`          // 支付方式代码，* 表示全部
+	Action      string    `gorm:"size:10;default:'hide'" json:"action"` // 动作：show/hide
+	MinAmount   float64   `gorm:"default:0" json:"min_amount"`          // 订单金额下限（含）
+	MaxAmount   float64   `gorm:"default:0" json:"max_amount"`          // 订单金额上限（含）
+	CategoryIDs string    `gorm:"size:500" json:"category_ids"`         // 商品分类ID，逗号分隔
+	UserLevels  string    `gorm:"size:200" json:"user_levels"`          // 用户等级，逗号分隔
+	Countries   string    `gorm:"size:500" json:"countries"`            // 客户端国家代码（ISO 3166-1），逗号分隔
+	Priority    int       `gorm:"default:0;index" json:"priority"`      // 优先级（越大越先匹配）
+	Enabled     bool      `json:"enabled"`                              // 是否启用
+	Remark      string    `gorm:"size:255" json:"remark"`               // 备注
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PaymentFee 支付方式手续费/优惠
+type PaymentFee struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Method    string    `gorm:"size:50;uniqueIndex" json:"method"`         // 支付方式代码
+	FeeType   string    `gorm:"size:20;default:'percent'" json:"fee_type"` // 类型：percent/fixed
+	Value     float64   `gorm:"type:decimal(10,4)" json:"value"`           // 费率或金额（正数为附加费，负数为优惠）
+	MinFee    float64   `gorm:"default:0" json:"min_fee"`                  // 附加费下限（仅按比例时生效，0不限）
+	MaxFee    float64   `gorm:"default:0" json:"max_fee"`                  // 附加费/优惠绝对值上限（0不限）
+	Enabled   bool      `json:"enabled"`                                   // 是否启用
+	Remark    string    `gorm:"size:255" json:"remark"`                    // 说明（展示给用户）
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (PaymentRule) TableName() string {
+	return "payment_rules"
+}
+
+// TableName 设置表名
+func (PaymentFee) TableName() string {
+	return "payment_fees"
+}
+
+// NormalizePaymentMethod 将各支付接口使用的方式名称统一为支付方式代码
+func NormalizePaymentMethod(method string) string {
+	method = strings.ToLower(strings.TrimSpace(method))
+	switch method {
+	case "alipay", "支付宝":
+		return PayMethodAlipayF2F
+	case "wechat", "微信支付":
+		return PayMethodWechatPay
+	case "yipay", "易支付":
+		return PayMethodYiPay
+	}
+	return method
+}
+
+// SplitList 解析逗号分隔的条件列表（去空格，空字符串返回nil）
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,39 @@
+// Package model 数据模型
+// pricing.go - 批发阶梯价与用户价格等级模型
+package model
+
+import (
+	"time"
+)
+
+// ProductPriceTier 商品阶梯价
+// 购买数量达到 MinQuantity 时按 Price 计价（单价，基础货币）；
+// Level 为空表示适用于所有用户等级（仍叠加等级折扣），指定等级时为该等级的专属价（不再叠加等级折扣）
+type ProductPriceTier struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProductID   uint      `gorm:"index" json:"product_id"`                  // 商品ID
+	Level       string    `gorm:"type:varchar(20);default:''" json:"level"` // 适用用户等级（空表示全部）
+	MinQuantity int       `gorm:"default:1" json:"min_quantity"`            // 起购数量
+	Price       float64   `json:"price"`                                    // 单价
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (ProductPriceTier) TableName() string {
+	return "product_price_tiers"
+}
+
+// PriceLevel 用户价格等级折扣
+// 对未设置专属阶梯价的商品，按等级折扣百分比在标价（或通用阶梯价）基础上减价
+type PriceLevel struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Level     string    `gorm:"type:varchar(20);uniqueIndex" json:"level"` // 用户等级：retail/vip/reseller
+	Discount  float64   `gorm:"default:0" json:"discount"`                 // 折扣百分比（如 10 表示减价10%）
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (PriceLevel) TableName() string {
+	return "price_levels"
+}
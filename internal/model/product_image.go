@@ -11,5 +11,21 @@ type ProductImage struct {
 	URL       string    `gorm:"size:500" json:"url"`               // 图片URL
 	SortOrder int       `gorm:"default:0" json:"sort_order"`       // 排序顺序
 	IsPrimary bool      `gorm:"default:false" json:"is_primary"`   // 是否主图
+	Width     int       `gorm:"default:0" json:"width"`            // 图片宽度（处理后，外部地址为0）
+	Height    int       `gorm:"default:0" json:"height"`           // 图片高度
+	Variants  string    `gorm:"type:text" json:"-"`                // 缩略图与 WebP 变体（JSON）
 	CreatedAt time.Time `json:"created_at"`
+
+	// 以下字段不存储，读取时由变体生成
+	SrcSet     string `gorm:"-" json:"srcset,omitempty"`      // 原格式 srcset
+	WebPSrcSet string `gorm:"-" json:"webp_srcset,omitempty"` // WebP srcset
+	Thumbnail  string `gorm:"-" json:"thumbnail,omitempty"`   // 最小尺寸缩略图地址
+}
+
+// ProductImageVariant 商品图片变体（不同宽度、格式）
+type ProductImageVariant struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"` // jpeg/png/gif/webp
+	URL    string `json:"url"`
 }
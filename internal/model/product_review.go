@@ -10,6 +10,7 @@ import (
 type ProductReview struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	ProductID uint           `gorm:"index" json:"product_id"`                   // 商品ID
+	VariantID uint           `gorm:"default:0;index" json:"variant_id"`         // 规格商品ID（多规格商品评价时记录所购规格，0表示无）
 	UserID    uint           `gorm:"index" json:"user_id"`                      // 用户ID
 	Username  string         `gorm:"type:varchar(100)" json:"username"`         // 用户名
 	OrderNo   string         `gorm:"type:varchar(64);index" json:"order_no"`    // 订单号
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/json"
+)
+
+// 常用规格维度
+const (
+	VariantDimensionDuration = "duration" // 时长（如 1个月、1年）
+	VariantDimensionEdition  = "edition"  // 版本（如 标准版、专业版）
+	VariantDimensionRegion   = "region"   // 地区（如 国区、全球）
+)
+
+// VariantOption 规格维度定义
+type VariantOption struct {
+	Name   string   `json:"name"`   // 维度标识（如 duration）
+	Label  string   `json:"label"`  // 显示名称（如 时长）
+	Values []string `json:"values"` // 可选值
+}
+
+// IsVariantParent 是否为多规格父商品（父商品本身不可直接购买）
+func (p *Product) IsVariantParent() bool {
+	return p.ParentID == 0 && p.VariantOptions != ""
+}
+
+// IsVariant 是否为规格商品
+func (p *Product) IsVariant() bool {
+	return p.ParentID > 0
+}
+
+// RootID 返回商品所属的父商品ID（非规格商品返回自身ID）
+func (p *Product) RootID() uint {
+	if p.ParentID > 0 {
+		return p.ParentID
+	}
+	return p.ID
+}
+
+// GetVariantOptions 解析父商品的规格维度定义
+func (p *Product) GetVariantOptions() []VariantOption {
+	var options []VariantOption
+	if p.VariantOptions != "" {
+		json.Unmarshal([]byte(p.VariantOptions), &options)
+	}
+	return options
+}
+
+// GetOptionValues 解析规格商品的各维度取值
+func (p *Product) GetOptionValues() map[string]string {
+	values := map[string]string{}
+	if p.OptionValues != "" {
+		json.Unmarshal([]byte(p.OptionValues), &values)
+	}
+	return values
+}
@@ -0,0 +1,236 @@
+// Package model 数据模型
+// secret.go - 敏感字段加密存储
+//
+// 敏感值使用配置加密密钥（AES-GCM）加密后存储：
+//   - 结构体字段通过 `serializer:encrypted` 标签在读写时自动加解密（TOTP 密钥、SMTP 密码等）
+//   - 支付配置 ConfigJSON 中的密钥字段按 PaymentSecretKeys 加密
+//
+// 升级前以明文存储的值仍可读取，由迁移统一加密。数据导出文件中的加密字段为明文（导入时使用
+// 目标服务器的密钥重新加密），支付密钥则保持加密，迁移服务器时需同时迁移加密密钥。
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+
+	"user-frontend/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// rewriteBatchSize 改写加密字段时每批读取的行数
+const rewriteBatchSize = 500
+
+// PaymentSecretKeys 支付配置 ConfigJSON 中加密存储的字段（按支付类型）
+var PaymentSecretKeys = map[string][]string{
+	"alipay_f2f": {"private_key"},
+	"wechat_pay": {"api_key"},
+	"yi_pay":     {"key"},
+	"paypal":     {"client_secret"},
+	"stripe":     {"secret_key", "webhook_secret"},
+	"usdt":       {"api_key", "api_secret", "webhook_secret", "chain_api_key"},
+}
+
+// EncryptSecret 使用当前配置加密密钥加密敏感值，空值和已加密的值原样返回
+func EncryptSecret(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if _, err := utils.AESDecrypt(value); err == nil {
+		return value, nil
+	}
+	return utils.AESEncrypt(value)
+}
+
+// DecryptSecret 解密敏感值，无法解密时视为升级前的明文原样返回
+func DecryptSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if decrypted, err := utils.AESDecrypt(value); err == nil {
+		return decrypted
+	}
+	return value
+}
+
+// ReEncryptSecret 将旧密钥加密的值改用新密钥加密（密钥均为 Base64 编码）
+// 旧密钥无法解密的值（未加密的明文或其他密钥加密的值）保持不变并记录警告，
+// 不能当作明文再次加密，否则原值将无法恢复
+func ReEncryptSecret(value, oldKey, newKey string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	plain, err := utils.AESDecryptWithKey(value, oldKey)
+	if err != nil {
+		log.Printf("警告: 有一个加密字段无法使用旧密钥解密，已保持原值（长度 %d）", len(value))
+		return value, nil
+	}
+	return utils.AESEncryptWithKey(plain, newKey)
+}
+
+// EncryptedSerializer 加密存储字符串字段的 GORM 序列化器
+type EncryptedSerializer struct{}
+
+// Scan 读取时解密
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("加密字段 %s 的类型无效: %T", field.Name, dbValue)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(DecryptSecret(value))
+	return nil
+}
+
+// Value 写入时加密
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+	return EncryptSecret(value)
+}
+
+// EncryptPaymentSecrets 加密支付配置 JSON 中的密钥字段
+func EncryptPaymentSecrets(paymentType, configJSON string) (string, error) {
+	return RewritePaymentSecrets(paymentType, configJSON, EncryptSecret)
+}
+
+// DecryptPaymentSecrets 解密支付配置 JSON 中的密钥字段
+func DecryptPaymentSecrets(paymentType, configJSON string) string {
+	decrypted, err := RewritePaymentSecrets(paymentType, configJSON, func(v string) (string, error) {
+		return DecryptSecret(v), nil
+	})
+	if err != nil {
+		return configJSON
+	}
+	return decrypted
+}
+
+// RewritePaymentSecrets 对支付配置 JSON 中的每个密钥字段执行 fn，其余字段保持不变
+func RewritePaymentSecrets(paymentType, configJSON string, fn func(string) (string, error)) (string, error) {
+	keys := PaymentSecretKeys[paymentType]
+	if len(keys) == 0 || configJSON == "" {
+		return configJSON, nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(configJSON), &data); err != nil {
+		return "", fmt.Errorf("支付配置 %s 格式无效: %v", paymentType, err)
+	}
+	for _, key := range keys {
+		value, ok := data[key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		rewritten, err := fn(value)
+		if err != nil {
+			return "", fmt.Errorf("支付配置 %s 字段 %s: %v", paymentType, key, err)
+		}
+		data[key] = rewritten
+	}
+
+	out, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// RewriteSecrets 对主数据库中全部加密存储的值执行 fn（加密字段与支付配置密钥），用于迁移和密钥轮换
+// 直接按表和列读写原始值，不经过序列化器
+func RewriteSecrets(tx *gorm.DB, fn func(string) (string, error)) error {
+	for _, m := range AllModels() {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if pk == nil {
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.TagSettings["SERIALIZER"] != "encrypted" {
+				continue
+			}
+			if err := rewriteColumn(tx, stmt.Schema.Table, pk.DBName, field.DBName, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	var configs []PaymentConfigDB
+	if err := tx.Table("payment_configs").Unscoped().Select("id", "payment_type", "config_json").Find(&configs).Error; err != nil {
+		return err
+	}
+	for _, c := range configs {
+		rewritten, err := RewritePaymentSecrets(c.PaymentType, c.ConfigJSON, fn)
+		if err != nil {
+			return err
+		}
+		if rewritten != c.ConfigJSON {
+			if err := tx.Table("payment_configs").Where("id = ?", c.ID).UpdateColumn("config_json", rewritten).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteColumn 逐行改写一列的非空值
+// 按主键分批读取（每批 rewriteBatchSize 行），内存占用不随表的大小增长
+func rewriteColumn(tx *gorm.DB, table, pk, column string, fn func(string) (string, error)) error {
+	quotedPK := tx.Statement.Quote(pk)
+	var lastPK interface{}
+	for {
+		query := tx.Table(table).
+			Select(quotedPK, tx.Statement.Quote(column)).
+			Where(fmt.Sprintf("%s <> ''", tx.Statement.Quote(column)))
+		if lastPK != nil {
+			query = query.Where(fmt.Sprintf("%s > ?", quotedPK), lastPK)
+		}
+		var rows []map[string]interface{}
+		if err := query.Order(quotedPK).Limit(rewriteBatchSize).Find(&rows).Error; err != nil {
+			return fmt.Errorf("读取 %s.%s 失败: %v", table, column, err)
+		}
+
+		for _, row := range rows {
+			var value string
+			switch v := row[column].(type) {
+			case string:
+				value = v
+			case []byte:
+				value = string(v)
+			default:
+				continue
+			}
+			rewritten, err := fn(value)
+			if err != nil {
+				return fmt.Errorf("%s.%s（%v）: %v", table, column, row[pk], err)
+			}
+			if rewritten == value {
+				continue
+			}
+			err = tx.Table(table).Where(fmt.Sprintf("%s = ?", quotedPK), row[pk]).
+				UpdateColumn(column, rewritten).Error
+			if err != nil {
+				return fmt.Errorf("更新 %s.%s 失败: %v", table, column, err)
+			}
+		}
+
+		if len(rows) < rewriteBatchSize {
+			return nil
+		}
+		lastPK = rows[len(rows)-1][pk]
+	}
+}
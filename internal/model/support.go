@@ -39,6 +39,18 @@ type SupportTicket struct {
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// SLA 跟踪
+	SLAPolicyID        uint       `gorm:"default:0;index" json:"sla_policy_id"`     // 适用的SLA策略ID（0表示无）
+	FirstResponseAt    *time.Time `json:"first_response_at"`                        // 客服首次回复时间
+	ResponseDueAt      *time.Time `gorm:"index" json:"response_due_at"`             // 客服响应截止时间（等待客服回复时有效）
+	ResolutionDueAt    *time.Time `gorm:"index" json:"resolution_due_at"`           // 解决截止时间
+	ResponseBreached   bool       `gorm:"default:false" json:"response_breached"`   // 是否发生过响应超时
+	ResolutionBreached bool       `gorm:"default:false" json:"resolution_breached"` // 是否解决超时
+	EscalationLevel    int        `gorm:"default:0" json:"escalation_level"`        // 升级次数
+	EscalatedAt        *time.Time `json:"escalated_at"`                             // 最近升级时间
+
+	Tags string `gorm:"type:varchar(500)" json:"tags"` // 工单标签（逗号分隔）
 }
 
 // SupportMessage 工单消息/聊天消息
@@ -64,10 +76,12 @@ type SupportAttachment struct {
 	TicketID  uint      `gorm:"index" json:"ticket_id"`                // 关联工单ID
 	MessageID uint      `gorm:"index" json:"message_id"`               // 关联消息ID
 	FileName  string    `gorm:"type:varchar(255)" json:"file_name"`    // 原始文件名
-	FilePath  string    `gorm:"type:varchar(500)" json:"file_path"`    // 存储路径
+	FilePath  string    `gorm:"type:varchar(500)" json:"-"`            // 存储路径（私有目录，不对外暴露）
 	FileSize  int64     `gorm:"default:0" json:"file_size"`            // 文件大小（字节）
-	MimeType  string    `gorm:"type:varchar(100)" json:"mime_type"`    // MIME类型
+	MimeType  string    `gorm:"type:varchar(100)" json:"mime_type"`    // MIME类型（按文件内容识别）
 	CreatedAt time.Time `json:"created_at"`
+
+	URL string `gorm:"-" json:"url"` // 短期签名访问地址（不存储）
 }
 
 func (SupportAttachment) TableName() string {
@@ -87,8 +101,10 @@ type SupportStaff struct {
 	MaxTickets   int            `gorm:"default:10" json:"max_tickets"`        // 最大同时处理工单数
 	CurrentLoad  int            `gorm:"default:0" json:"current_load"`        // 当前处理工单数
 	Enable2FA    bool           `gorm:"default:false" json:"enable_2fa"`      // 是否启用二步验证
-	TOTPSecret   string         `gorm:"type:varchar(64)" json:"-"`            // TOTP密钥
+	TOTPSecret   string         `gorm:"type:varchar(255);serializer:encrypted" json:"-"` // TOTP密钥（加密存储）
+	Skills       string         `gorm:"type:varchar(500)" json:"skills"`      // 技能标签（对应工单分类，逗号分隔）
 	LastActiveAt *time.Time     `json:"last_active_at"`
+	LastAssignAt *time.Time     `json:"last_assign_at"`                       // 最近一次被自动分配的时间（轮询分配用）
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
@@ -129,6 +145,7 @@ type SupportConfigDB struct {
 	NotifyOnReply       bool      `gorm:"default:true" json:"notify_on_reply"`            // 有新回复时通知用户
 	MaxAttachmentSize   int       `gorm:"default:5" json:"max_attachment_size"`           // 最大附件大小（MB）
 	AllowedFileTypes    string    `gorm:"type:text" json:"allowed_file_types"`            // 允许的文件类型（JSON数组）
+	AssignStrategy      string    `gorm:"type:varchar(30)" json:"assign_strategy"`        // 自动分配策略：least_loaded/round_robin/skill_match/sticky
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 }
@@ -218,6 +235,14 @@ const (
 	ChatStatusEnded    = 2 // 已结束
 )
 
+// 自动分配策略
+const (
+	AssignStrategyLeastLoaded = "least_loaded" // 负载最低优先
+	AssignStrategyRoundRobin  = "round_robin"  // 轮询
+	AssignStrategySkillMatch  = "skill_match"  // 技能匹配优先，其次负载最低
+	AssignStrategySticky      = "sticky"       // 优先分配给该用户上次的客服，其次技能匹配
+)
+
 // GetTicketStatusText 获取工单状态文本
 func GetTicketStatusText(status int) string {
 	switch status {
@@ -0,0 +1,57 @@
+// Package model 定义数据模型
+// support_macro.go - 客服快捷回复（宏）模型
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SupportMacro 客服快捷回复（宏）
+// 回复内容支持占位符，可附带一键执行的操作（设置状态、添加标签、分配）
+type SupportMacro struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Name          string         `gorm:"type:varchar(100)" json:"name"`          // 名称
+	Shortcut      string         `gorm:"type:varchar(50);index" json:"shortcut"` // 快捷指令，如 /refund
+	Category      string         `gorm:"type:varchar(50)" json:"category"`       // 适用工单分类（空表示全部）
+	Content       string         `gorm:"type:text" json:"content"`               // 回复内容（支持 {{username}} 等占位符）
+	IsInternal    bool           `gorm:"default:false" json:"is_internal"`       // 是否作为内部备注发送
+	OwnerID       uint           `gorm:"default:0;index" json:"owner_id"`        // 所属客服ID（0表示共享）
+	SetStatus     *int           `json:"set_status"`                             // 操作：设置工单状态（为空不修改）
+	AddTags       string         `gorm:"type:varchar(255)" json:"add_tags"`      // 操作：添加标签（逗号分隔）
+	AssignStaffID uint           `gorm:"default:0" json:"assign_staff_id"`       // 操作：分配给指定客服（0不分配）
+	AssignToSelf  bool           `gorm:"default:false" json:"assign_to_self"`    // 操作：分配给执行人
+	SortOrder     int            `gorm:"default:0" json:"sort_order"`            // 排序
+	UseCount      int            `gorm:"default:0" json:"use_count"`             // 使用次数
+	LastUsedAt    *time.Time     `json:"last_used_at"`                           // 最近使用时间
+	Status        int            `gorm:"default:1" json:"status"`                // 状态：1启用 0禁用
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// IsShared 是否为共享宏
+func (m *SupportMacro) IsShared() bool {
+	return m.OwnerID == 0
+}
+
+// SupportMacroUsage 快捷回复使用记录
+type SupportMacroUsage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MacroID   uint      `gorm:"index" json:"macro_id"`
+	StaffID   uint      `gorm:"index" json:"staff_id"`
+	TicketID  uint      `gorm:"default:0" json:"ticket_id"`
+	ChatID    uint      `gorm:"default:0" json:"chat_id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 设置表名
+func (SupportMacro) TableName() string {
+	return "support_macros"
+}
+
+// TableName 设置表名
+func (SupportMacroUsage) TableName() string {
+	return "support_macro_usages"
+}
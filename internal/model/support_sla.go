@@ -0,0 +1,32 @@
+// Package model 定义数据模型
+// support_sla.go - 工单 SLA 策略模型
+package model
+
+import "time"
+
+// SupportSLAPolicy 工单 SLA 策略
+// 按工单分类和优先级匹配，分类为空或优先级为0表示匹配全部
+type SupportSLAPolicy struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	Name                 string    `gorm:"type:varchar(100)" json:"name"`           // 策略名称
+	Category             string    `gorm:"type:varchar(50);index" json:"category"`  // 适用分类（空表示全部）
+	Priority             int       `gorm:"default:0" json:"priority"`               // 适用优先级（0表示全部）
+	FirstResponseMinutes int       `gorm:"default:0" json:"first_response_minutes"` // 响应时限（分钟，0表示不限）
+	ResolutionMinutes    int       `gorm:"default:0" json:"resolution_minutes"`     // 解决时限（分钟，0表示不限）
+	UseWorkingHours      bool      `gorm:"default:false" json:"use_working_hours"`  // 是否仅按工作时间计时
+	EscalateOnBreach     bool      `gorm:"default:false" json:"escalate_on_breach"` // 超时后是否自动升级
+	Enabled              bool      `gorm:"default:false" json:"enabled"`            // 是否启用
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (SupportSLAPolicy) TableName() string {
+	return "support_sla_policies"
+}
+
+// 客服角色
+const (
+	StaffRoleStaff      = "staff"      // 普通客服
+	StaffRoleSupervisor = "supervisor" // 主管
+)
@@ -0,0 +1,74 @@
+// Package model 定义数据模型
+// support_workbench.go - 客服工作台订单操作模型（操作记录、退款申请）
+package model
+
+import "time"
+
+// SupportOrderAction 客服对订单执行操作的审计记录
+type SupportOrderAction struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	TicketID     uint      `gorm:"index" json:"ticket_id"`                 // 来源工单ID
+	OrderID      uint      `gorm:"index" json:"order_id"`                  // 订单ID
+	OrderNo      string    `gorm:"type:varchar(64);index" json:"order_no"` // 订单号
+	Action       string    `gorm:"type:varchar(30);index" json:"action"`   // 操作类型
+	OperatorType string    `gorm:"type:varchar(20)" json:"operator_type"`  // 操作者类型：staff/admin
+	OperatorID   uint      `gorm:"default:0" json:"operator_id"`           // 操作者ID（管理员为0）
+	OperatorName string    `gorm:"type:varchar(100)" json:"operator_name"` // 操作者名称
+	OldKamiID    uint      `gorm:"default:0" json:"old_kami_id"`           // 更换前的卡密ID
+	NewKamiID    uint      `gorm:"default:0" json:"new_kami_id"`           // 更换后的卡密ID
+	Amount       float64   `gorm:"default:0" json:"amount"`                // 涉及金额（退款）
+	Reason       string    `gorm:"type:varchar(500)" json:"reason"`        // 操作原因
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// 客服订单操作类型
+const (
+	OrderActionResendDelivery = "resend_delivery" // 重发发货邮件
+	OrderActionReplaceKami    = "replace_kami"    // 更换失效卡密
+	OrderActionRefundRequest  = "refund_request"  // 提交退款申请
+	OrderActionRefundApprove  = "refund_approve"  // 退款申请通过
+	OrderActionRefundReject   = "refund_reject"   // 退款申请驳回
+)
+
+// TableName 设置表名
+func (SupportOrderAction) TableName() string {
+	return "support_order_actions"
+}
+
+// SupportRefundRequest 客服提交、管理员审批的退款申请
+type SupportRefundRequest struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	TicketID     uint       `gorm:"index" json:"ticket_id"`                 // 来源工单ID
+	OrderID      uint       `gorm:"index" json:"order_id"`                  // 订单ID
+	OrderNo      string     `gorm:"type:varchar(64);index" json:"order_no"` // 订单号
+	UserID       uint       `gorm:"index" json:"user_id"`                   // 订单用户ID
+	Amount       float64    `json:"amount"`                                 // 申请退款金额
+	RefundMethod string     `gorm:"type:varchar(20)" json:"refund_method"`  // 退款方式：balance 退回余额 / manual 线下原路退回
+	Reason       string     `gorm:"type:varchar(500)" json:"reason"`        // 申请原因
+	StaffID      uint       `gorm:"index" json:"staff_id"`                  // 申请客服ID
+	StaffName    string     `gorm:"type:varchar(100)" json:"staff_name"`    // 申请客服名称
+	Status       int        `gorm:"default:0;index" json:"status"`          // 状态：0待审批 1已通过 2已驳回
+	ReviewedBy   string     `gorm:"type:varchar(100)" json:"reviewed_by"`   // 审批管理员
+	ReviewRemark string     `gorm:"type:varchar(500)" json:"review_remark"` // 审批备注
+	ReviewedAt   *time.Time `json:"reviewed_at"`                            // 审批时间
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// 退款申请状态
+const (
+	RefundRequestPending  = 0 // 待审批
+	RefundRequestApproved = 1 // 已通过
+	RefundRequestRejected = 2 // 已驳回
+)
+
+// 退款方式
+const (
+	RefundMethodBalance = "balance" // 退回账户余额
+	RefundMethodManual  = "manual"  // 线下原路退回
+)
+
+// TableName 设置表名
+func (SupportRefundRequest) TableName() string {
+	return "support_refund_requests"
+}
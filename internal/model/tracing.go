@@ -0,0 +1,92 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/trace"
+	"user-frontend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// 数据库查询追踪
+//
+// 通过 db.WithContext(ctx) 执行的查询（如 repository.WithContext 返回的仓库），在 ctx 带有请求ID时
+// 记录一个数据库 Span；启用 SQL 日志（ENABLE_SQL_LOG）时同时输出带 request_id 的调试日志。
+// 未携带请求ID的查询（定时任务、启动迁移等）不做任何处理。
+
+const (
+	traceSpanKey    = "trace:span"
+	maxTracedSQLLen = 1000
+)
+
+// registerTracing 为数据库连接注册查询追踪回调
+func registerTracing(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(name string, fn func(*gorm.DB)) error
+		after  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		op := h.name
+		if err := h.before("trace:before_"+op, func(tx *gorm.DB) { startQuerySpan(tx, op) }); err != nil {
+			return err
+		}
+		if err := h.after("trace:after_"+op, endQuerySpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startQuerySpan 查询开始前创建 Span
+func startQuerySpan(tx *gorm.DB, op string) {
+	ctx := tx.Statement.Context
+	if trace.RequestID(ctx) == "" {
+		return
+	}
+	_, span := trace.Start(ctx, "db."+op, trace.KindClient)
+	tx.InstanceSet(traceSpanKey, span)
+}
+
+// endQuerySpan 查询结束后记录 SQL、影响行数并结束 Span
+func endQuerySpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(traceSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(*trace.Span)
+
+	sql := tx.Statement.SQL.String()
+	if len(sql) > maxTracedSQLLen {
+		sql = sql[:maxTracedSQLLen]
+	}
+	span.SetAttr("db.system", tx.Dialector.Name())
+	span.SetAttr("db.table", tx.Statement.Table)
+	span.SetAttr("db.statement", sql)
+	span.SetAttr("db.rows_affected", tx.Statement.RowsAffected)
+
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	span.End(err)
+
+	if config.GlobalEnvConfig != nil && config.GlobalEnvConfig.EnableSQLLog {
+		utils.WithContext(tx.Statement.Context).Debug("SQL", map[string]interface{}{
+			"sql":         sql,
+			"rows":        tx.Statement.RowsAffected,
+			"duration_ms": time.Since(span.StartTime).Milliseconds(),
+		})
+	}
+}
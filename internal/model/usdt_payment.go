@@ -0,0 +1,50 @@
+// Package model 数据模型
+// usdt_payment.go - 自托管链上USDT收款记录
+package model
+
+import (
+	"time"
+)
+
+// 链上收款业务类型
+const (
+	USDTPaymentKindOrder    = "order"    // 商品订单
+	USDTPaymentKindRecharge = "recharge" // 余额充值
+)
+
+// 链上收款状态
+const (
+	USDTPaymentWaiting    = "waiting"    // 等待转账
+	USDTPaymentConfirming = "confirming" // 已检测到转账，等待确认数
+	USDTPaymentConfirmed  = "confirmed"  // 已确认并完成入账
+	USDTPaymentExpired    = "expired"    // 超时未支付
+	USDTPaymentFailed     = "failed"     // 已确认但业务入账失败
+)
+
+// USDTPayment 链上USDT收款记录
+// 每笔待支付订单分配一个唯一的USDT金额，轮询链上接口按收款地址+金额+时间窗口匹配转账
+type USDTPayment struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	OrderNo       string     `gorm:"size:64;uniqueIndex" json:"order_no"`           // 订单号/充值单号
+	Kind          string     `gorm:"size:20;index" json:"kind"`                     // 业务类型：order/recharge
+	Network       string     `gorm:"size:20" json:"network"`                        // 网络：TRC20/ERC20/BEP20
+	WalletAddress string     `gorm:"size:100" json:"wallet_address"`                // 收款地址
+	Amount        float64    `gorm:"type:decimal(18,6);index" json:"amount"`        // 应付USDT金额（含唯一尾数）
+	Status        string     `gorm:"size:20;index;default:'waiting'" json:"status"` // 状态
+	TxHash        string     `gorm:"size:100;index" json:"tx_hash"`                 // 匹配到的交易哈希
+	FromAddress   string     `gorm:"size:100" json:"from_address"`                  // 付款地址
+	PaidAmount    float64    `gorm:"type:decimal(18,6)" json:"paid_amount"`         // 链上实际到账金额
+	BlockNumber   int64      `gorm:"default:0" json:"block_number"`                 // 交易所在区块
+	Confirmations int        `gorm:"default:0" json:"confirmations"`                // 当前确认数
+	StartBlock    int64      `gorm:"default:0" json:"start_block"`                  // 开始扫描的区块（首次轮询时记录）
+	Error         string     `gorm:"size:500" json:"error"`                         // 入账失败原因
+	ExpiresAt     time.Time  `gorm:"index" json:"expires_at"`                       // 过期时间
+	ConfirmedAt   *time.Time `json:"confirmed_at"`                                  // 确认时间
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (USDTPayment) TableName() string {
+	return "usdt_payments"
+}
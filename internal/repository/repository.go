@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"user-frontend/internal/model"
@@ -16,6 +17,11 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// WithContext 返回使用 ctx 执行查询的仓库副本（ctx 中的请求ID会关联到数据库查询）
+func (r *Repository) WithContext(ctx context.Context) *Repository {
+	return &Repository{db: r.db.WithContext(ctx)}
+}
+
 // GetDB 获取数据库连接（供需要直接操作数据库的服务使用）
 func (r *Repository) GetDB() *gorm.DB {
 	return r.db
@@ -209,7 +215,8 @@ func (r *Repository) GetOrderStats() (map[string]interface{}, error) {
 // SystemSetting 相关操作
 func (r *Repository) GetSetting(key string) (string, error) {
 	var setting model.SystemSetting
-	err := r.db.Where("`key` = ?", key).First(&setting).Error
+	// 按结构体条件查询，由 gorm 按数据库类型引用列名（key 在 MySQL 中是保留字）
+	err := r.db.Where(&model.SystemSetting{Key: key}).First(&setting).Error
 	if err != nil {
 		return "", err
 	}
@@ -218,7 +225,7 @@ func (r *Repository) GetSetting(key string) (string, error) {
 
 func (r *Repository) SetSetting(key, value, remark string) error {
 	var setting model.SystemSetting
-	err := r.db.Where("`key` = ?", key).First(&setting).Error
+	err := r.db.Where(&model.SystemSetting{Key: key}).First(&setting).Error
 	if err != nil {
 		// 不存在则创建
 		setting = model.SystemSetting{
@@ -610,6 +617,41 @@ func (r *Repository) DeleteBackupRecord(id uint) error {
 	return r.db.Delete(&model.DatabaseBackup{}, id).Error
 }
 
+// GetBackupsByKind 获取指定类型的备份记录（按创建时间倒序）
+func (r *Repository) GetBackupsByKind(kind string) ([]model.DatabaseBackup, error) {
+	var backups []model.DatabaseBackup
+	err := r.db.Where("kind = ?", kind).Order("created_at DESC, id DESC").Find(&backups).Error
+	return backups, err
+}
+
+// SaveBackupUpload 保存备份上传记录（同一备份同一目标只保留最新一条）
+func (r *Repository) SaveBackupUpload(upload *model.BackupUpload) error {
+	var existing model.BackupUpload
+	err := r.db.Where("backup_id = ? AND target = ?", upload.BackupID, upload.Target).First(&existing).Error
+	if err == nil {
+		upload.ID = existing.ID
+		upload.CreatedAt = existing.CreatedAt
+		return r.db.Save(upload).Error
+	}
+	return r.db.Create(upload).Error
+}
+
+// GetBackupUploads 获取备份的上传记录，backupIDs 为空时返回全部
+func (r *Repository) GetBackupUploads(backupIDs ...uint) ([]model.BackupUpload, error) {
+	var uploads []model.BackupUpload
+	query := r.db.Order("id ASC")
+	if len(backupIDs) > 0 {
+		query = query.Where("backup_id IN ?", backupIDs)
+	}
+	err := query.Find(&uploads).Error
+	return uploads, err
+}
+
+// DeleteBackupUploads 删除备份的上传记录
+func (r *Repository) DeleteBackupUploads(backupID uint) error {
+	return r.db.Where("backup_id = ?", backupID).Delete(&model.BackupUpload{}).Error
+}
+
 // ==================== 用户会话相关操作 ====================
 
 func (r *Repository) CreateUserSession(session *model.UserSession) error {
@@ -767,9 +809,10 @@ func (r *Repository) GetManualKamiStats(productID uint) (map[string]int64, error
 		"available": 0,
 		"sold":      0,
 		"disabled":  0,
+		"invalid":   0,
 	}
 
-	var total, available, sold, disabled int64
+	var total, available, sold, disabled, invalid int64
 
 	// 总数
 	r.db.Model(&model.ManualKami{}).Where("product_id = ?", productID).Count(&total)
@@ -787,5 +830,9 @@ func (r *Repository) GetManualKamiStats(productID uint) (map[string]int64, error
 	r.db.Model(&model.ManualKami{}).Where("product_id = ? AND status = ?", productID, 2).Count(&disabled)
 	stats["disabled"] = disabled
 
+	// 已作废
+	r.db.Model(&model.ManualKami{}).Where("product_id = ? AND status = ?", productID, 3).Count(&invalid)
+	stats["invalid"] = invalid
+
 	return stats, nil
 }
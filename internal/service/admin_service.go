@@ -82,6 +82,22 @@ func (s *AdminService) UpdatePassword(username, oldPassword, newPassword string)
 	return s.repo.UpdateAdminUser(admin)
 }
 
+// ResetPassword 重置密码（不校验原密码，用于命令行恢复管理员账号）
+func (s *AdminService) ResetPassword(username, newPassword string) error {
+	admin, err := s.repo.GetAdminByUsername(username)
+	if err != nil {
+		return errors.New("管理员不存在")
+	}
+
+	passwordHash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+
+	admin.PasswordHash = passwordHash
+	return s.repo.UpdateAdminUser(admin)
+}
+
 // Enable2FA 启用两步验证
 func (s *AdminService) Enable2FA(username, secret string) error {
 	admin, err := s.repo.GetAdminByUsername(username)
@@ -1,25 +1,31 @@
 package service
 
 import (
+	"context"
 	"crypto"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"sort"
 	"strings"
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/trace"
 )
 
 // AlipayService 支付宝当面付服务
 type AlipayService struct {
 	config *config.AlipayF2FConfig
+	requestScope
 }
 
 // NewAlipayService 创建支付宝服务
@@ -27,6 +33,13 @@ func NewAlipayService(cfg *config.AlipayF2FConfig) *AlipayService {
 	return &AlipayService{config: cfg}
 }
 
+// WithContext 返回绑定 ctx 的服务副本，网关请求会携带 ctx 中的请求ID
+func (s *AlipayService) WithContext(ctx context.Context) *AlipayService {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
 // CreatePreOrder 创建支付宝预下单（当面付）
 // 返回二维码内容（用于生成二维码供用户扫描）
 // 参数：
@@ -46,36 +59,31 @@ func (s *AlipayService) CreatePreOrder(orderNo string, amount float64, subject s
 		return "", errors.New("支付宝配置不完整")
 	}
 
-	// 构建业务参数
-	bizContent := fmt.Sprintf(`{"out_trade_no":"%s","total_amount":"%.2f","subject":"%s"}`,
-		orderNo, amount, subject)
+	bizContent, _ := json.Marshal(map[string]string{
+		"out_trade_no": orderNo,
+		"total_amount": fmt.Sprintf("%.2f", amount),
+		"subject":      subject,
+	})
 
-	// 构建请求参数
-	params := map[string]string{
-		"app_id":      s.config.AppID,
-		"method":      "alipay.trade.precreate",
-		"format":      "JSON",
-		"charset":     "utf-8",
-		"sign_type":   "RSA2",
-		"timestamp":   time.Now().Format("2006-01-02 15:04:05"),
-		"version":     "1.0",
-		"notify_url":  s.config.NotifyURL,
-		"biz_content": bizContent,
+	resp, err := s.execute("alipay.trade.precreate", string(bizContent), map[string]string{
+		"notify_url": s.config.NotifyURL,
+	})
+	if err != nil {
+		return "", err
 	}
 
-	// 签名
-	sign, err := s.sign(params)
-	if err != nil {
-		return "", fmt.Errorf("签名失败: %v", err)
+	var result struct {
+		OutTradeNo string `json:"out_trade_no"`
+		QRCode     string `json:"qr_code"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if result.QRCode == "" {
+		return "", errors.New("支付宝未返回二维码")
 	}
-	params["sign"] = sign
 
-	// 注意：这里只是返回构建好的参数，实际调用需要使用HTTP客户端
-	// 由于没有实际的支付宝SDK，这里返回一个占位符
-	// 实际使用时需要：
-	// 1. 发送POST请求到 https://openapi.alipay.com/gateway.do
-	// 2. 解析响应获取 qr_code
-	return fmt.Sprintf("alipay://pay?orderNo=%s&amount=%.2f", orderNo, amount), nil
+	return result.QRCode, nil
 }
 
 // VerifyNotify 验证支付宝异步通知
@@ -133,9 +141,122 @@ func (s *AlipayService) QueryOrder(orderNo string) (bool, string, error) {
 		return false, "", errors.New("支付宝当面付未启用")
 	}
 
-	// 注意：这里需要实际调用支付宝查询接口
-	// 由于没有实际的SDK，返回false
-	return false, "", nil
+	bizContent, _ := json.Marshal(map[string]string{"out_trade_no": orderNo})
+	resp, err := s.execute("alipay.trade.query", string(bizContent), nil)
+	if err != nil {
+		// 用户尚未扫码时支付宝返回交易不存在
+		if strings.Contains(err.Error(), "ACQ.TRADE_NOT_EXIST") {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+
+	var result struct {
+		TradeNo     string `json:"trade_no"`
+		TradeStatus string `json:"trade_status"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return false, "", fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	paid := result.TradeStatus == "TRADE_SUCCESS" || result.TradeStatus == "TRADE_FINISHED"
+	return paid, result.TradeNo, nil
+}
+
+// gatewayURL 获取支付宝网关地址（支持配置沙箱或模拟网关）
+func (s *AlipayService) gatewayURL() string {
+	if s.config.GatewayURL != "" {
+		return s.config.GatewayURL
+	}
+	return "https://openapi.alipay.com/gateway.do"
+}
+
+// execute 调用支付宝开放平台接口
+// 参数：
+//   - method: 接口名称，如 alipay.trade.precreate
+//   - bizContent: 业务参数JSON
+//   - extra: 额外的公共参数（如 notify_url）
+//
+// 返回：
+//   - 响应节点（<method>_response）的原始JSON
+//   - 错误信息（业务失败时包含 sub_code）
+func (s *AlipayService) execute(method, bizContent string, extra map[string]string) (json.RawMessage, error) {
+	params := map[string]string{
+		"app_id":      s.config.AppID,
+		"method":      method,
+		"format":      "JSON",
+		"charset":     "utf-8",
+		"sign_type":   "RSA2",
+		"timestamp":   time.Now().Format("2006-01-02 15:04:05"),
+		"version":     "1.0",
+		"biz_content": bizContent,
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+
+	sign, err := s.sign(params)
+	if err != nil {
+		return nil, fmt.Errorf("签名失败: %v", err)
+	}
+	params["sign"] = sign
+
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", s.gatewayURL(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := trace.NewHTTPClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求支付宝失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	content, ok := envelope[strings.ReplaceAll(method, ".", "_")+"_response"]
+	if !ok {
+		return nil, fmt.Errorf("支付宝响应格式错误: %s", string(body))
+	}
+
+	// 验证响应签名（签名内容为响应节点的原始JSON）
+	var respSign string
+	if raw, ok := envelope["sign"]; ok {
+		json.Unmarshal(raw, &respSign)
+	}
+	if respSign != "" {
+		if err := s.verifyContent(string(content), respSign); err != nil {
+			return nil, fmt.Errorf("响应签名验证失败: %v", err)
+		}
+	}
+
+	var status struct {
+		Code    string `json:"code"`
+		Msg     string `json:"msg"`
+		SubCode string `json:"sub_code"`
+		SubMsg  string `json:"sub_msg"`
+	}
+	json.Unmarshal(content, &status)
+	if status.Code != "10000" {
+		return nil, fmt.Errorf("支付宝返回错误: %s %s (%s)", status.Msg, status.SubMsg, status.SubCode)
+	}
+
+	return content, nil
 }
 
 // sign 生成签名
@@ -143,13 +264,22 @@ func (s *AlipayService) sign(params map[string]string) (string, error) {
 	// 获取排序后的参数字符串
 	signStr := s.getSignString(params)
 
-	// 解析私钥
-	block, _ := pem.Decode([]byte(s.config.PrivateKey))
+	rsaKey, err := parseAlipayPrivateKey(s.config.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return rsa2Sign(rsaKey, signStr)
+}
+
+// parseAlipayPrivateKey 解析RSA私钥（支持PEM或纯Base64，PKCS8/PKCS1格式）
+func parseAlipayPrivateKey(key string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(key))
 	if block == nil {
 		// 尝试直接解析（不带PEM头尾）
-		keyBytes, err := base64.StdEncoding.DecodeString(s.config.PrivateKey)
+		keyBytes, err := base64.StdEncoding.DecodeString(key)
 		if err != nil {
-			return "", errors.New("私钥格式错误")
+			return nil, errors.New("私钥格式错误")
 		}
 		block = &pem.Block{Bytes: keyBytes}
 	}
@@ -159,18 +289,21 @@ func (s *AlipayService) sign(params map[string]string) (string, error) {
 		// 尝试PKCS1格式
 		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
 		if err != nil {
-			return "", fmt.Errorf("解析私钥失败: %v", err)
+			return nil, fmt.Errorf("解析私钥失败: %v", err)
 		}
 	}
 
 	rsaKey, ok := privateKey.(*rsa.PrivateKey)
 	if !ok {
-		return "", errors.New("私钥类型错误")
+		return nil, errors.New("私钥类型错误")
 	}
+	return rsaKey, nil
+}
 
-	// SHA256签名
-	hashed := sha256.Sum256([]byte(signStr))
-	signature, err := rsa.SignPKCS1v15(nil, rsaKey, crypto.SHA256, hashed[:])
+// rsa2Sign 使用SHA256WithRSA签名并Base64编码
+func rsa2Sign(key *rsa.PrivateKey, content string) (string, error) {
+	hashed := sha256.Sum256([]byte(content))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hashed[:])
 	if err != nil {
 		return "", fmt.Errorf("签名失败: %v", err)
 	}
@@ -180,10 +313,6 @@ func (s *AlipayService) sign(params map[string]string) (string, error) {
 
 // verifySign 验证签名
 func (s *AlipayService) verifySign(params url.Values, sign string) error {
-	if s.config.PublicKey == "" {
-		return errors.New("未配置支付宝公钥")
-	}
-
 	// 构建待验签字符串
 	signParams := make(map[string]string)
 	for key := range params {
@@ -191,7 +320,14 @@ func (s *AlipayService) verifySign(params url.Values, sign string) error {
 			signParams[key] = params.Get(key)
 		}
 	}
-	signStr := s.getSignString(signParams)
+	return s.verifyContent(s.getSignString(signParams), sign)
+}
+
+// verifyContent 使用支付宝公钥验证签名
+func (s *AlipayService) verifyContent(signStr, sign string) error {
+	if s.config.PublicKey == "" {
+		return errors.New("未配置支付宝公钥")
+	}
 
 	// 解析公钥
 	block, _ := pem.Decode([]byte(s.config.PublicKey))
@@ -213,6 +349,11 @@ func (s *AlipayService) verifySign(params url.Values, sign string) error {
 		return errors.New("公钥类型错误")
 	}
 
+	return rsa2Verify(rsaPubKey, signStr, sign)
+}
+
+// rsa2Verify 验证SHA256WithRSA签名（签名为Base64编码）
+func rsa2Verify(key *rsa.PublicKey, content, sign string) error {
 	// 解码签名
 	signBytes, err := base64.StdEncoding.DecodeString(sign)
 	if err != nil {
@@ -220,8 +361,8 @@ func (s *AlipayService) verifySign(params url.Values, sign string) error {
 	}
 
 	// 验证签名
-	hashed := sha256.Sum256([]byte(signStr))
-	return rsa.VerifyPKCS1v15(rsaPubKey, crypto.SHA256, hashed[:], signBytes)
+	hashed := sha256.Sum256([]byte(content))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signBytes)
 }
 
 // getSignString 获取待签名字符串
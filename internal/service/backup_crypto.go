@@ -0,0 +1,353 @@
+// Package service 提供业务逻辑服务
+// backup_crypto.go - 备份文件压缩与加密
+//
+// 加密文件格式（流式分块 AES-256-GCM，可处理任意大小的备份）：
+//
+//	头部 32 字节：魔数 "UFBKENC1"(8) | 密钥来源(1) | 盐(16) | 随机数前缀(7)
+//	数据块：密文长度 uint32 | 密文（明文最多 64KB）
+//
+// 每块的随机数为 前缀(7) | 块序号 uint32 | 结束标记(1)，头部作为附加数据参与认证，
+// 可检测块的篡改、重排以及文件被截断。
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/utils"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	backupEncMagic     = "UFBKENC1"
+	backupEncExt       = ".enc"
+	backupGzipExt      = ".gz"
+	backupEncHeaderLen = 32
+	backupEncChunkSize = 64 * 1024
+
+	backupKeyModeConfig     byte = 1
+	backupKeyModePassphrase byte = 2
+)
+
+// ErrBackupDecrypt 备份解密失败
+var ErrBackupDecrypt = errors.New("备份解密失败：密钥不正确或文件已损坏")
+
+// encodeBackupFile 按备份配置压缩、加密备份文件，返回最终文件路径
+// zip 文件本身已压缩，仅对 SQL 导出文件进行 gzip 压缩；处理完成后删除中间文件
+func encodeBackupFile(src string, cfg *config.BackupConfig) (string, bool, bool, error) {
+	current := src
+	compressed, encrypted := false, false
+
+	if cfg.Compress && !strings.HasSuffix(current, ".zip") {
+		dst := current + backupGzipExt
+		if err := gzipFile(current, dst); err != nil {
+			os.Remove(dst)
+			return "", false, false, fmt.Errorf("压缩备份失败: %v", err)
+		}
+		os.Remove(current)
+		current = dst
+		compressed = true
+	}
+
+	if cfg.Encrypt {
+		dst := current + backupEncExt
+		if err := encryptBackupFile(current, dst, cfg); err != nil {
+			os.Remove(dst)
+			return "", false, false, fmt.Errorf("加密备份失败: %v", err)
+		}
+		os.Remove(current)
+		current = dst
+		encrypted = true
+	}
+
+	return current, compressed, encrypted, nil
+}
+
+// decodeBackupFile 将加密/压缩的备份还原为明文文件（保存在 workDir 的临时文件中）
+// 备份未加密也未压缩时直接返回原路径；cleanup 用于删除生成的临时文件
+func decodeBackupFile(path, workDir string, cfg *config.BackupConfig) (string, func(), error) {
+	noop := func() {}
+	name := filepath.Base(path)
+	if !strings.HasSuffix(name, backupEncExt) && !strings.HasSuffix(name, backupGzipExt) {
+		return path, noop, nil
+	}
+
+	var temps []string
+	cleanup := func() {
+		for _, t := range temps {
+			os.Remove(t)
+		}
+	}
+	current := path
+
+	if strings.HasSuffix(name, backupEncExt) {
+		name = strings.TrimSuffix(name, backupEncExt)
+		tmp, err := os.CreateTemp(workDir, "decode_*_"+name)
+		if err != nil {
+			return "", noop, err
+		}
+		temps = append(temps, tmp.Name())
+		tmp.Close()
+		if err := decryptBackupFile(current, tmp.Name(), cfg); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+		current = tmp.Name()
+	}
+
+	if strings.HasSuffix(name, backupGzipExt) {
+		name = strings.TrimSuffix(name, backupGzipExt)
+		tmp, err := os.CreateTemp(workDir, "decode_*_"+name)
+		if err != nil {
+			cleanup()
+			return "", noop, err
+		}
+		temps = append(temps, tmp.Name())
+		tmp.Close()
+		if err := gunzipFile(current, tmp.Name()); err != nil {
+			cleanup()
+			return "", noop, fmt.Errorf("解压备份失败: %v", err)
+		}
+		current = tmp.Name()
+	}
+
+	return current, cleanup, nil
+}
+
+// gzipFile 压缩文件
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(src)
+	if _, err := io.Copy(zw, in); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// gunzipFile 解压文件
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(bufio.NewReader(in))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, zr); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// backupKey 根据密钥来源和盐派生 256 位加密密钥
+func backupKey(mode byte, salt []byte, cfg *config.BackupConfig) ([]byte, error) {
+	switch mode {
+	case backupKeyModePassphrase:
+		if cfg == nil || cfg.Passphrase == "" {
+			return nil, errors.New("备份使用口令加密，但未配置备份口令")
+		}
+		return scrypt.Key([]byte(cfg.Passphrase), salt, 1<<15, 8, 1, 32)
+	case backupKeyModeConfig:
+		key := make([]byte, 32)
+		r := hkdf.New(sha256.New, utils.GetConfigEncryptionKey(), salt, []byte("user-frontend backup"))
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("未知的备份密钥来源: %d", mode)
+}
+
+// encryptBackupFile 加密备份文件
+func encryptBackupFile(src, dst string, cfg *config.BackupConfig) error {
+	header := make([]byte, backupEncHeaderLen)
+	copy(header, backupEncMagic)
+	header[8] = backupKeyModeConfig
+	if cfg.EncryptionMode == BackupKeyPassphrase {
+		header[8] = backupKeyModePassphrase
+	}
+	if _, err := rand.Read(header[9:]); err != nil {
+		return err
+	}
+	key, err := backupKey(header[8], header[9:25], cfg)
+	if err != nil {
+		return err
+	}
+	aead, err := newBackupAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// 预读一块以判断当前块是否为最后一块
+	r := bufio.NewReaderSize(in, backupEncChunkSize)
+	buf := make([]byte, backupEncChunkSize)
+	var counter uint32
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		_, peekErr := r.Peek(1)
+		last := peekErr == io.EOF
+
+		sealed := aead.Seal(nil, backupNonce(header[25:32], counter, last), buf[:n], header)
+		if err := binary.Write(w, binary.BigEndian, uint32(len(sealed))); err != nil {
+			return err
+		}
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			break
+		}
+		counter++
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// decryptBackupFile 解密备份文件
+func decryptBackupFile(src, dst string, cfg *config.BackupConfig) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	r := bufio.NewReader(in)
+
+	header := make([]byte, backupEncHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:8]) != backupEncMagic {
+		return errors.New("不是有效的加密备份文件")
+	}
+	key, err := backupKey(header[8], header[9:25], cfg)
+	if err != nil {
+		return err
+	}
+	aead, err := newBackupAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+
+	var counter uint32
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			// 未读到结束块即到达文件末尾，说明文件被截断
+			return ErrBackupDecrypt
+		}
+		if size > backupEncChunkSize+uint32(aead.Overhead()) {
+			return ErrBackupDecrypt
+		}
+		sealed := make([]byte, size)
+		if _, err := io.ReadFull(r, sealed); err != nil {
+			return ErrBackupDecrypt
+		}
+
+		// 先按普通块解密，失败时再按结束块解密
+		last := false
+		plain, err := aead.Open(nil, backupNonce(header[25:32], counter, false), sealed, header)
+		if err != nil {
+			if plain, err = aead.Open(nil, backupNonce(header[25:32], counter, true), sealed, header); err != nil {
+				return ErrBackupDecrypt
+			}
+			last = true
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			if _, err := r.Peek(1); err != io.EOF {
+				return ErrBackupDecrypt
+			}
+			break
+		}
+		counter++
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// newBackupAEAD 创建 AES-GCM 实例
+func newBackupAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// backupNonce 生成数据块随机数：前缀(7) | 块序号(4) | 结束标记(1)
+func backupNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[7:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
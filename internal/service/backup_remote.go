@@ -0,0 +1,315 @@
+// Package service 提供业务逻辑服务
+// backup_remote.go - 定时备份、异地上传与保留策略
+//
+// 定时备份完成后上传到所有启用的异地目标（S3 兼容存储、SFTP、本地挂载目录），
+// 并同时上传 .sha256 校验文件；随后按保留策略清理过期的定时备份（含异地副本）。
+// 手动备份与恢复前快照不受保留策略影响。
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/lifecycle"
+	"user-frontend/internal/model"
+	"user-frontend/internal/storage"
+)
+
+// backupChecksumExt 异地校验文件后缀（内容格式与 sha256sum 输出一致）
+const backupChecksumExt = ".sha256"
+
+// RunScheduledBackup 检查是否到达定时备份时间，到达时在后台执行备份、上传与清理
+// 由定时任务每分钟调用；上一次定时备份尚未完成或处于维护模式时跳过
+func (s *BackupService) RunScheduledBackup(dbConfig *config.DBConfig) {
+	cfg := s.backupConfig()
+	if !cfg.ScheduleEnabled || dbConfig == nil || dbConfig.Type == "" {
+		return
+	}
+	if GetMaintenanceStatus().Active {
+		return
+	}
+
+	backups, err := s.repo.GetBackupsByKind(model.BackupKindScheduled)
+	if err != nil {
+		return
+	}
+	interval := time.Duration(cfg.IntervalHours) * time.Hour
+	if len(backups) > 0 && time.Since(backups[0].CreatedAt) < interval {
+		return
+	}
+
+	if !s.scheduled.CompareAndSwap(false, true) {
+		return
+	}
+	// 执行中的备份登记为后台任务，恢复或导入前等待其完成
+	done, ok := BeginBackgroundWork()
+	if !ok {
+		s.scheduled.Store(false)
+		return
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer done()
+		defer s.scheduled.Store(false)
+		if _, err := s.BackupAndUpload(dbConfig); err != nil {
+			log.Printf("[Backup] 定时备份失败: %v", err)
+		}
+	}()
+}
+
+// WaitScheduled 等待执行中的定时备份完成，ctx 到期时返回错误
+func (s *BackupService) WaitScheduled(ctx context.Context) error {
+	return lifecycle.WaitGroup(ctx, &s.running)
+}
+
+// BackupAndUpload 立即执行一次定时备份：创建备份、上传到异地目标并应用保留策略
+// 上传或清理失败只记录日志，不影响本地备份结果
+func (s *BackupService) BackupAndUpload(dbConfig *config.DBConfig) (*model.DatabaseBackup, error) {
+	cfg := s.backupConfig()
+	backup, err := s.createBackup(dbConfig, model.BackupKindScheduled, "system", "定时备份", cfg.IncludeObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, upload := range s.UploadBackup(backup) {
+		if upload.Status != model.BackupUploadSuccess {
+			log.Printf("[Backup] 备份 %s 上传到 %s 失败: %s", backup.Filename, upload.Target, upload.Error)
+		}
+	}
+
+	if pruned, err := s.ApplyRetention(); err != nil {
+		log.Printf("[Backup] 应用保留策略失败: %v", err)
+	} else if pruned > 0 {
+		log.Printf("[Backup] 按保留策略清理了 %d 个过期备份", pruned)
+	}
+	return backup, nil
+}
+
+// UploadBackup 将备份上传到所有启用的异地目标，返回各目标的上传记录
+func (s *BackupService) UploadBackup(backup *model.DatabaseBackup) []model.BackupUpload {
+	var uploads []model.BackupUpload
+	for _, target := range s.backupConfig().Targets {
+		if !target.Enabled {
+			continue
+		}
+		upload := model.BackupUpload{
+			BackupID:  backup.ID,
+			Target:    target.Name,
+			RemoteKey: backup.Filename,
+			Status:    model.BackupUploadSuccess,
+		}
+		if err := uploadBackupFile(backup, &target); err != nil {
+			upload.Status = model.BackupUploadFailed
+			upload.Error = truncateString(err.Error(), 500)
+		}
+		s.repo.SaveBackupUpload(&upload)
+		uploads = append(uploads, upload)
+	}
+	return uploads
+}
+
+// UploadBackupByID 手动将指定备份上传到所有启用的异地目标
+func (s *BackupService) UploadBackupByID(id uint) ([]model.BackupUpload, error) {
+	backup, err := s.repo.GetBackupByID(id)
+	if err != nil {
+		return nil, errors.New("备份不存在")
+	}
+	if _, err := os.Stat(backup.FilePath); err != nil {
+		return nil, errors.New("备份文件不存在")
+	}
+	if !hasEnabledTarget(s.backupConfig()) {
+		return nil, errors.New("未配置启用的异地备份目标")
+	}
+	return s.UploadBackup(backup), nil
+}
+
+// GetBackupUploads 获取备份的异地上传记录，按备份ID分组
+func (s *BackupService) GetBackupUploads(ids []uint) map[uint][]model.BackupUpload {
+	result := make(map[uint][]model.BackupUpload)
+	if len(ids) == 0 {
+		return result
+	}
+	uploads, err := s.repo.GetBackupUploads(ids...)
+	if err != nil {
+		return result
+	}
+	for _, u := range uploads {
+		result[u.BackupID] = append(result[u.BackupID], u)
+	}
+	return result
+}
+
+// TestBackupTarget 测试异地备份目标是否可用
+// SFTP 目标同时返回服务器主机公钥指纹，供管理员核对后填入配置
+func TestBackupTarget(target *config.BackupTarget) (string, error) {
+	st, err := NewBackupTargetStorage(target)
+	if err != nil {
+		return "", err
+	}
+	if sftp, ok := st.(*storage.SFTPStorage); ok {
+		fingerprint, err := sftp.HostKeyFingerprint()
+		if err != nil {
+			return "", err
+		}
+		return fingerprint, sftp.Ping()
+	}
+	return "", st.Ping()
+}
+
+// ApplyRetention 按保留策略清理定时备份，返回清理数量
+//
+// 保留最近 KeepLast 个备份，以及最近 KeepDaily 天、KeepWeekly 周、KeepMonthly 月中
+// 每个周期内最新的一个备份；各项均为 0 时不清理。
+func (s *BackupService) ApplyRetention() (int, error) {
+	cfg := s.backupConfig()
+	if cfg.KeepLast == 0 && cfg.KeepDaily == 0 && cfg.KeepWeekly == 0 && cfg.KeepMonthly == 0 {
+		return 0, nil
+	}
+
+	backups, err := s.repo.GetBackupsByKind(model.BackupKindScheduled)
+	if err != nil {
+		return 0, err
+	}
+
+	keep := retainedBackups(backups, cfg)
+	pruned := 0
+	var errs []string
+	for i := range backups {
+		if keep[backups[i].ID] {
+			continue
+		}
+		if err := s.pruneBackup(&backups[i]); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", backups[i].Filename, err))
+			continue
+		}
+		pruned++
+	}
+	if len(errs) > 0 {
+		return pruned, errors.New(strings.Join(errs, "；"))
+	}
+	return pruned, nil
+}
+
+// retainedBackups 计算保留策略下需保留的备份（backups 按创建时间倒序）
+func retainedBackups(backups []model.DatabaseBackup, cfg *config.BackupConfig) map[uint]bool {
+	keep := make(map[uint]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	months := make(map[string]bool)
+
+	for i, b := range backups {
+		if i < cfg.KeepLast {
+			keep[b.ID] = true
+		}
+		t := b.CreatedAt.Local()
+
+		day := t.Format("2006-01-02")
+		if !days[day] && len(days) < cfg.KeepDaily {
+			days[day] = true
+			keep[b.ID] = true
+		}
+
+		year, week := t.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < cfg.KeepWeekly {
+			weeks[weekKey] = true
+			keep[b.ID] = true
+		}
+
+		month := t.Format("2006-01")
+		if !months[month] && len(months) < cfg.KeepMonthly {
+			months[month] = true
+			keep[b.ID] = true
+		}
+	}
+	return keep
+}
+
+// pruneBackup 删除备份的异地副本、本地文件与记录
+// 异地副本删除失败时保留记录，下次清理时重试
+func (s *BackupService) pruneBackup(backup *model.DatabaseBackup) error {
+	uploads, _ := s.repo.GetBackupUploads(backup.ID)
+	targets := make(map[string]config.BackupTarget)
+	for _, t := range s.backupConfig().Targets {
+		targets[t.Name] = t
+	}
+
+	for _, u := range uploads {
+		if u.Status != model.BackupUploadSuccess {
+			continue
+		}
+		target, ok := targets[u.Target]
+		if !ok {
+			// 目标已从配置中移除，无法再访问
+			continue
+		}
+		st, err := NewBackupTargetStorage(&target)
+		if err != nil {
+			return fmt.Errorf("删除异地副本失败（%s）: %v", u.Target, err)
+		}
+		if err := st.Delete(u.RemoteKey); err != nil {
+			return fmt.Errorf("删除异地副本失败（%s）: %v", u.Target, err)
+		}
+		st.Delete(u.RemoteKey + backupChecksumExt)
+	}
+
+	if backup.FilePath != "" {
+		os.Remove(backup.FilePath)
+	}
+	s.repo.DeleteBackupUploads(backup.ID)
+	return s.repo.DeleteBackupRecord(backup.ID)
+}
+
+// uploadBackupFile 上传备份文件及其校验文件，并核对远端文件大小
+func uploadBackupFile(backup *model.DatabaseBackup, target *config.BackupTarget) error {
+	st, err := NewBackupTargetStorage(target)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(backup.FilePath)
+	if err != nil {
+		return fmt.Errorf("打开备份文件失败: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := st.Put(backup.Filename, f, info.Size(), "application/octet-stream"); err != nil {
+		return err
+	}
+	remote, err := st.Stat(backup.Filename)
+	if err != nil {
+		return fmt.Errorf("上传后检查失败: %v", err)
+	}
+	if remote.Size != info.Size() {
+		return fmt.Errorf("上传后大小不一致（本地 %d，远端 %d）", info.Size(), remote.Size)
+	}
+
+	if backup.Checksum != "" {
+		sum := fmt.Sprintf("%s  %s\n", backup.Checksum, backup.Filename)
+		if err := st.Put(backup.Filename+backupChecksumExt, strings.NewReader(sum), int64(len(sum)), "text/plain"); err != nil {
+			return fmt.Errorf("上传校验文件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// hasEnabledTarget 是否配置了启用的异地目标
+func hasEnabledTarget(cfg *config.BackupConfig) bool {
+	for _, t := range cfg.Targets {
+		if t.Enabled {
+			return true
+		}
+	}
+	return false
+}
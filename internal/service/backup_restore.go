@@ -0,0 +1,746 @@
+// Package service 提供业务逻辑服务
+// backup_restore.go - 数据库备份校验与恢复
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-frontend/internal/cache"
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// schemaVersionHeader SQL 备份头部记录结构版本的注释行前缀
+	schemaVersionHeader = "-- Schema-Version: "
+	// schemaVersionComment SQLite 备份 zip 注释中记录结构版本的前缀
+	schemaVersionComment = "schema_version="
+	// objectsPrefix 备份包中对象存储文件的目录
+	objectsPrefix = "objects/"
+)
+
+// restoreSkipTables 恢复时保留当前数据、不被备份覆盖的表
+//   - database_backups：保留最新的备份记录（含恢复前快照），避免恢复后丢失备份文件索引
+//   - admin_sessions：保留当前管理员会话，避免执行恢复的管理员被强制下线
+//   - backup_uploads：与 database_backups 对应的异地上传记录
+//   - schema_migrations：迁移记录与当前表结构一致，恢复只替换数据，不回退结构版本
+//   - audit_logs/audit_log_archives：审计日志只追加、不回退，恢复后配置数据库中的锚点仍指向当前链尾，
+//     替换为备份中较旧（或其他实例以不同密钥签名）的链会导致哈希链永久校验失败；恢复操作本身记入审计日志
+var restoreSkipTables = map[string]bool{
+	"database_backups":   true,
+	"admin_sessions":     true,
+	"backup_uploads":     true,
+	"schema_migrations":  true,
+	"audit_logs":         true,
+	"audit_log_archives": true,
+}
+
+// restoreIdentPattern 备份中允许出现的表名
+var restoreIdentPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	Force          bool // 允许恢复无法完整校验的旧备份或结构版本较旧的备份
+	RestoreObjects bool // 同时恢复备份包中的对象存储文件
+}
+
+// BackupVerification 备份校验结果
+type BackupVerification struct {
+	BackupID       uint     `json:"backup_id"`
+	Filename       string   `json:"filename"`
+	DBType         string   `json:"db_type"`
+	Checksum       string   `json:"checksum"`               // 备份文件当前的 SHA-256
+	ChecksumOK     bool     `json:"checksum_ok"`            // 与备份时记录的校验和一致
+	SchemaVersion  int      `json:"schema_version"`         // 备份内记录的结构版本（0 表示未知）
+	CurrentVersion int      `json:"current_schema_version"` // 当前程序的结构版本
+	Restorable     bool     `json:"restorable"`             // 可直接恢复
+	RequiresForce  bool     `json:"requires_force"`         // 需确认后强制恢复
+	Errors         []string `json:"errors,omitempty"`       // 阻止恢复的问题
+	Warnings       []string `json:"warnings,omitempty"`     // 需强制恢复的原因
+}
+
+// RestoreResult 恢复结果
+type RestoreResult struct {
+	BackupID     uint     `json:"backup_id"`
+	SnapshotID   uint     `json:"snapshot_id"`   // 恢复前自动快照的备份ID
+	SnapshotFile string   `json:"snapshot_file"` // 恢复前自动快照的文件名
+	Tables       int      `json:"tables"`        // 恢复的表数量
+	Rows         int64    `json:"rows"`          // 恢复的记录数
+	Objects      int      `json:"objects"`       // 恢复的对象存储文件数
+	Duration     string   `json:"duration"`
+	Warnings     []string `json:"warnings,omitempty"`
+}
+
+// VerifyBackup 校验备份能否恢复到当前数据库
+// 检查备份文件是否存在、数据库类型是否一致、校验和是否匹配以及结构版本是否兼容
+func (s *BackupService) VerifyBackup(id uint, dbType string) (*BackupVerification, error) {
+	v, _, _, cleanup, err := s.verifyBackup(id, dbType)
+	if err != nil {
+		return nil, err
+	}
+	cleanup()
+	return v, nil
+}
+
+// verifyBackup 校验备份，并返回解密、解压后的明文备份路径
+// 调用方需执行 cleanup 删除临时明文文件
+func (s *BackupService) verifyBackup(id uint, dbType string) (v *BackupVerification, backup *model.DatabaseBackup, plainPath string, cleanup func(), err error) {
+	cleanup = func() {}
+	backup, err = s.repo.GetBackupByID(id)
+	if err != nil {
+		return nil, nil, "", cleanup, errors.New("备份不存在")
+	}
+
+	v = &BackupVerification{
+		BackupID:       backup.ID,
+		Filename:       backup.Filename,
+		DBType:         backup.DBType,
+		CurrentVersion: model.SchemaVersion,
+	}
+	defer func() {
+		v.Restorable = len(v.Errors) == 0 && !v.RequiresForce
+	}()
+
+	switch backup.DBType {
+	case "sqlite", "mysql", "postgres":
+	default:
+		v.Errors = append(v.Errors, "该类型的备份不支持恢复")
+		return v, backup, "", cleanup, nil
+	}
+	if backup.DBType != dbType {
+		v.Errors = append(v.Errors, fmt.Sprintf("备份数据库类型(%s)与当前数据库类型(%s)不一致", backup.DBType, dbType))
+		return v, backup, "", cleanup, nil
+	}
+	if _, err := os.Stat(backup.FilePath); err != nil {
+		v.Errors = append(v.Errors, "备份文件不存在")
+		return v, backup, "", cleanup, nil
+	}
+
+	// 校验和
+	sum, err := fileSHA256(backup.FilePath)
+	if err != nil {
+		v.Errors = append(v.Errors, "读取备份文件失败: "+err.Error())
+		return v, backup, "", cleanup, nil
+	}
+	v.Checksum = sum
+	if backup.Checksum == "" {
+		v.RequiresForce = true
+		v.Warnings = append(v.Warnings, "备份未记录校验和，无法确认文件完整性")
+	} else if sum != backup.Checksum {
+		v.Errors = append(v.Errors, "校验和不匹配，备份文件已损坏或被修改")
+		return v, backup, "", cleanup, nil
+	} else {
+		v.ChecksumOK = true
+	}
+
+	// 解密、解压
+	plainPath, cleanup, err = decodeBackupFile(backup.FilePath, s.backupDir, s.backupConfig())
+	if err != nil {
+		v.Errors = append(v.Errors, err.Error())
+		return v, backup, "", cleanup, nil
+	}
+
+	// 结构版本
+	version, err := readBackupSchemaVersion(plainPath, backup.DBType)
+	if err != nil {
+		cleanup()
+		v.Errors = append(v.Errors, "无法读取备份内容: "+err.Error())
+		return v, backup, "", func() {}, nil
+	}
+	v.SchemaVersion = version
+	switch {
+	case version == 0:
+		v.RequiresForce = true
+		v.Warnings = append(v.Warnings, "备份未记录数据库结构版本")
+	case version > model.SchemaVersion:
+		v.Errors = append(v.Errors, fmt.Sprintf("备份的数据库结构版本(%d)高于当前版本(%d)，请先升级程序", version, model.SchemaVersion))
+	case version < model.SchemaVersion:
+		v.RequiresForce = true
+		v.Warnings = append(v.Warnings, fmt.Sprintf("备份的数据库结构版本(%d)低于当前版本(%d)，恢复后新增字段将使用默认值", version, model.SchemaVersion))
+	}
+
+	return v, backup, plainPath, cleanup, nil
+}
+
+// RestoreBackup 从备份恢复数据库
+// 恢复前先校验备份并自动创建当前数据库的快照，恢复期间进入维护模式暂停对外服务；
+// 数据在单个事务内替换，任一步骤失败时整体回滚，数据库保持恢复前状态
+func (s *BackupService) RestoreBackup(dbConfig *config.DBConfig, id uint, operator string, opts RestoreOptions) (*RestoreResult, error) {
+	v, backup, plainPath, cleanup, err := s.verifyBackup(id, dbConfig.Type)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	if len(v.Errors) > 0 {
+		return nil, errors.New(strings.Join(v.Errors, "；"))
+	}
+	if v.RequiresForce && !opts.Force {
+		return nil, fmt.Errorf("%s，确认无误后请使用强制恢复", strings.Join(v.Warnings, "；"))
+	}
+
+	if err := EnterMaintenance("正在恢复数据库"); err != nil {
+		return nil, err
+	}
+	defer LeaveMaintenance()
+
+	start := time.Now()
+
+	// 恢复前快照
+	snapshot, err := s.createBackup(dbConfig, model.BackupKindSnapshot, operator, fmt.Sprintf("恢复前自动快照（恢复 %s）", backup.Filename), false)
+	if err != nil {
+		return nil, fmt.Errorf("创建恢复前快照失败，已取消恢复: %v", err)
+	}
+
+	payload, err := openBackupPayload(plainPath)
+	if err != nil {
+		return nil, err
+	}
+	defer payload.Close()
+
+	result := &RestoreResult{
+		BackupID:     backup.ID,
+		SnapshotID:   snapshot.ID,
+		SnapshotFile: snapshot.Filename,
+	}
+	if dbConfig.Type == "sqlite" {
+		result.Tables, result.Rows, err = s.restoreSQLite(payload.reader)
+	} else {
+		result.Tables, result.Rows, result.Warnings, err = s.restoreSQLDump(payload.reader, dbConfig.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("恢复失败，数据库未变更（恢复前快照: %s）: %v", snapshot.Filename, err)
+	}
+
+	if opts.RestoreObjects && backup.IncludeObjects {
+		n, err := restoreObjects(plainPath)
+		result.Objects = n
+		if err != nil {
+			result.Warnings = append(result.Warnings, "部分存储文件恢复失败: "+err.Error())
+		}
+	}
+
+	// 数据已整体替换，清空缓存避免读到恢复前的数据
+	if cm := cache.GetManager(); cm != nil {
+		cm.FlushAll()
+	}
+
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	return result, nil
+}
+
+// restoreSQLite 恢复 SQLite 备份
+// 将备份数据库附加到当前连接，在事务内逐表清空并按两边共有的字段复制数据
+func (s *BackupService) restoreSQLite(r io.Reader) (int, int64, error) {
+	tmp, err := os.CreateTemp(s.backupDir, "restore_*.db")
+	if err != nil {
+		return 0, 0, err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	_, err = io.Copy(tmp, r)
+	tmp.Close()
+	if err != nil {
+		return 0, 0, fmt.Errorf("解压备份失败: %v", err)
+	}
+
+	sqlDB, err := s.repo.GetDB().DB()
+	if err != nil {
+		return 0, 0, err
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS restore_src", tmpPath); err != nil {
+		return 0, 0, fmt.Errorf("无法打开备份数据库: %v", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE restore_src")
+
+	var check string
+	if err := conn.QueryRowContext(ctx, "PRAGMA restore_src.quick_check").Scan(&check); err != nil || check != "ok" {
+		return 0, 0, errors.New("备份数据库文件已损坏")
+	}
+
+	// 外键检查需在事务外关闭
+	var foreignKeys int
+	conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys)
+	if foreignKeys == 1 {
+		conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	mainTables, err := sqliteTables(ctx, conn, "main")
+	if err != nil {
+		return 0, 0, err
+	}
+	srcTables, err := sqliteTables(ctx, conn, "restore_src")
+	if err != nil {
+		return 0, 0, err
+	}
+	srcSet := make(map[string]bool, len(srcTables))
+	for _, t := range srcTables {
+		srcSet[t] = true
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	tables, rows := 0, int64(0)
+	for _, table := range mainTables {
+		if restoreSkipTables[table] {
+			continue
+		}
+		quoted := quoteIdent("sqlite", table)
+		if _, err := tx.ExecContext(ctx, "DELETE FROM main."+quoted); err != nil {
+			return 0, 0, fmt.Errorf("清空表 %s 失败: %v", table, err)
+		}
+		if !srcSet[table] {
+			continue
+		}
+
+		columns, err := sqliteCommonColumns(ctx, tx, table)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		res, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM restore_src.%s",
+			quoted, columns, columns, quoted))
+		if err != nil {
+			return 0, 0, fmt.Errorf("恢复表 %s 失败: %v", table, err)
+		}
+		n, _ := res.RowsAffected()
+		tables++
+		rows += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return tables, rows, nil
+}
+
+// sqliteTables 获取 SQLite 指定库中的用户表
+func sqliteTables(ctx context.Context, conn *sql.Conn, schema string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT name FROM %s.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%%' ORDER BY name", schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// sqliteCommonColumns 获取当前库与备份库中同名表共有的字段（已加引号，逗号分隔）
+func sqliteCommonColumns(ctx context.Context, tx *sql.Tx, table string) (string, error) {
+	columnsOf := func(schema string) ([]string, error) {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA %s.table_info(%s)", schema, quoteIdent("sqlite", table)))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var columns []string
+		for rows.Next() {
+			var (
+				cid, notNull, pk int
+				name, colType    string
+				defaultValue     sql.NullString
+			)
+			if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+				return nil, err
+			}
+			columns = append(columns, name)
+		}
+		return columns, rows.Err()
+	}
+
+	mainColumns, err := columnsOf("main")
+	if err != nil {
+		return "", err
+	}
+	srcColumns, err := columnsOf("restore_src")
+	if err != nil {
+		return "", err
+	}
+	srcSet := make(map[string]bool, len(srcColumns))
+	for _, c := range srcColumns {
+		srcSet[c] = true
+	}
+
+	var common []string
+	for _, c := range mainColumns {
+		if srcSet[c] {
+			common = append(common, quoteIdent("sqlite", c))
+		}
+	}
+	return strings.Join(common, ", "), nil
+}
+
+// restoreSQLDump 恢复 MySQL/PostgreSQL 的 SQL 备份
+// 流式读取备份语句，在事务内按 "-- Table:" 标记逐表清空后执行该表的 INSERT 语句
+func (s *BackupService) restoreSQLDump(r io.Reader, dbType string) (int, int64, []string, error) {
+	currentTables, err := s.repo.GetDB().Migrator().GetTables()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	tableSet := make(map[string]bool, len(currentTables))
+	for _, t := range currentTables {
+		tableSet[t] = true
+	}
+
+	sqlDB, err := s.repo.GetDB().DB()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer conn.Close()
+
+	if dbType == "mysql" {
+		// 备份按标准 SQL 转义字符串（仅将 ' 写作 ''），恢复时需关闭反斜杠转义；外键检查在恢复期间关闭
+		var sqlMode string
+		if err := conn.QueryRowContext(ctx, "SELECT @@SESSION.sql_mode").Scan(&sqlMode); err != nil {
+			return 0, 0, nil, err
+		}
+		if _, err := conn.ExecContext(ctx, "SET SESSION sql_mode = CONCAT_WS(',', NULLIF(@@SESSION.sql_mode, ''), 'NO_BACKSLASH_ESCAPES'), FOREIGN_KEY_CHECKS = 0"); err != nil {
+			return 0, 0, nil, err
+		}
+		defer conn.ExecContext(ctx, "SET SESSION sql_mode = ?, FOREIGN_KEY_CHECKS = 1", sqlMode)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer tx.Rollback()
+
+	var warnings []string
+	if dbType == "postgres" {
+		// 外键约束无法延迟时尝试在事务内停用触发器（需要相应权限，失败时按备份中的表顺序恢复）
+		tx.ExecContext(ctx, "SAVEPOINT restore_fk")
+		if _, err := tx.ExecContext(ctx, "SET LOCAL session_replication_role = replica"); err != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT restore_fk")
+			warnings = append(warnings, "当前数据库账号无法停用外键检查，按备份中的表顺序恢复")
+		}
+	}
+
+	scanner := newSQLStatementScanner(r)
+	var (
+		current  string
+		skip     bool
+		restored []string
+		rows     int64
+	)
+	for {
+		stmt, table, err := scanner.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		if table != "" {
+			if !restoreIdentPattern.MatchString(table) {
+				return 0, 0, nil, fmt.Errorf("备份中包含无效的表名: %s", table)
+			}
+			current = table
+			skip = restoreSkipTables[table]
+			if skip {
+				continue
+			}
+			if !tableSet[table] {
+				skip = true
+				warnings = append(warnings, fmt.Sprintf("表 %s 在当前版本中不存在，已跳过", table))
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+quoteIdent(dbType, table)); err != nil {
+				return 0, 0, nil, fmt.Errorf("清空表 %s 失败: %v", table, err)
+			}
+			restored = append(restored, table)
+			continue
+		}
+
+		if skip {
+			continue
+		}
+		// 仅允许向当前表插入数据，拒绝备份中的其他语句
+		if current == "" || !(strings.HasPrefix(stmt, "INSERT INTO "+quoteIdent(dbType, current)+" ") ||
+			strings.HasPrefix(stmt, "INSERT INTO "+current+" ")) {
+			return 0, 0, nil, errors.New("备份中包含不允许执行的语句")
+		}
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return 0, 0, nil, fmt.Errorf("恢复表 %s 失败: %v", current, err)
+		}
+		rows++
+	}
+
+	if dbType == "postgres" {
+		for _, table := range restored {
+			if err := resetPostgresSequence(ctx, tx, table); err != nil {
+				return 0, 0, nil, fmt.Errorf("重置表 %s 的自增序列失败: %v", table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, nil, err
+	}
+	return len(restored), rows, warnings, nil
+}
+
+// resetPostgresSequence 将表 id 字段的自增序列重置为当前最大值之后
+func resetPostgresSequence(ctx context.Context, tx gorm.ConnPool, table string) error {
+	var hasID int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'id'", table).Scan(&hasID); err != nil {
+		return err
+	}
+	if hasID == 0 {
+		return nil
+	}
+
+	var seq sql.NullString
+	if err := tx.QueryRowContext(ctx, "SELECT pg_get_serial_sequence($1, 'id')", table).Scan(&seq); err != nil {
+		return err
+	}
+	if !seq.Valid {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("SELECT setval($1, COALESCE((SELECT MAX(id) FROM %s), 0) + 1, false)", quoteIdent("postgres", table)), seq.String)
+	return err
+}
+
+// restoreObjects 将备份包 objects/ 目录下的文件写回对象存储
+func restoreObjects(path string) (int, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	st := storage.Default()
+	count := 0
+	var failed []string
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, objectsPrefix) || f.FileInfo().IsDir() {
+			continue
+		}
+		key, err := storage.CleanKey(strings.TrimPrefix(f.Name, objectsPrefix))
+		if err != nil {
+			failed = append(failed, f.Name)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			failed = append(failed, key)
+			continue
+		}
+		err = st.Put(key, rc, int64(f.UncompressedSize64), mime.TypeByExtension(filepath.Ext(key)))
+		rc.Close()
+		if err != nil {
+			failed = append(failed, key)
+			continue
+		}
+		count++
+	}
+
+	if len(failed) > 0 {
+		return count, fmt.Errorf("%d 个文件: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return count, nil
+}
+
+// ==================== 备份文件读取 ====================
+
+// backupPayload 备份文件中的数据库部分（SQLite 数据库文件或 SQL 文件）
+type backupPayload struct {
+	reader  io.ReadCloser
+	comment string // zip 注释
+	zr      *zip.ReadCloser
+}
+
+// Close 关闭备份文件
+func (p *backupPayload) Close() {
+	p.reader.Close()
+	if p.zr != nil {
+		p.zr.Close()
+	}
+}
+
+// openBackupPayload 打开备份文件中的数据库部分
+// zip 备份取 objects/ 目录外的第一个文件，SQL 备份直接读取
+func openBackupPayload(path string) (*backupPayload, error) {
+	if !strings.HasSuffix(path, ".zip") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return &backupPayload{reader: f}, nil
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("备份文件格式无效: %v", err)
+	}
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, objectsPrefix) || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			zr.Close()
+			return nil, err
+		}
+		return &backupPayload{reader: rc, comment: zr.Comment, zr: zr}, nil
+	}
+	zr.Close()
+	return nil, errors.New("备份文件中没有数据库数据")
+}
+
+// readBackupSchemaVersion 读取备份中记录的结构版本（未记录时返回 0）
+func readBackupSchemaVersion(path, dbType string) (int, error) {
+	payload, err := openBackupPayload(path)
+	if err != nil {
+		return 0, err
+	}
+	defer payload.Close()
+
+	if dbType == "sqlite" {
+		if v, ok := strings.CutPrefix(payload.comment, schemaVersionComment); ok {
+			return strconv.Atoi(strings.TrimSpace(v))
+		}
+		return 0, nil
+	}
+
+	// SQL 备份的版本记录在文件头部的注释中
+	br := bufio.NewReader(payload.reader)
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, schemaVersionHeader); ok {
+			return strconv.Atoi(strings.TrimSpace(v))
+		}
+		if err != nil || strings.HasPrefix(line, "-- Table:") || (line != "" && !strings.HasPrefix(line, "--")) {
+			return 0, nil
+		}
+	}
+}
+
+// sqlStatementScanner 流式读取 SQL 备份中的语句
+// 单引号外的分号为语句结束符；语句之外以 "--" 开头的行为注释，其中 "-- Table: xxx" 标记后续语句所属的表
+type sqlStatementScanner struct {
+	r       *bufio.Reader
+	buf     strings.Builder
+	inQuote bool
+}
+
+func newSQLStatementScanner(r io.Reader) *sqlStatementScanner {
+	return &sqlStatementScanner{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next 返回下一条语句，遇到表标记时 table 非空；读取完毕返回 io.EOF
+func (sc *sqlStatementScanner) Next() (stmt string, table string, err error) {
+	for {
+		line, readErr := sc.r.ReadString('\n')
+		if line == "" && readErr != nil {
+			if readErr == io.EOF && strings.TrimSpace(sc.buf.String()) != "" {
+				return "", "", errors.New("备份文件不完整：最后一条语句未结束")
+			}
+			return "", "", readErr
+		}
+
+		if sc.buf.Len() == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, "--") {
+				if name, ok := strings.CutPrefix(trimmed, "-- Table:"); ok {
+					return "", strings.TrimSpace(name), nil
+				}
+				continue
+			}
+		}
+
+		for i := 0; i < len(line); i++ {
+			switch line[i] {
+			case '\'':
+				sc.inQuote = !sc.inQuote
+			case ';':
+				if sc.inQuote {
+					continue
+				}
+				if strings.TrimSpace(line[i+1:]) != "" {
+					return "", "", errors.New("备份格式无效：同一行包含多条语句")
+				}
+				sc.buf.WriteString(line[:i])
+				stmt = strings.TrimSpace(sc.buf.String())
+				sc.buf.Reset()
+				return stmt, "", nil
+			}
+		}
+		sc.buf.WriteString(line)
+	}
+}
+
+// ==================== 工具函数 ====================
+
+// fileSHA256 计算文件的 SHA-256
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// quoteIdent 按数据库类型为表名/字段名加引号
+func quoteIdent(dbType, name string) string {
+	if dbType == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
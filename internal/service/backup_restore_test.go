@@ -0,0 +1,173 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestSQLite 打开并迁移全部表的 SQLite 文件数据库
+func openTestSQLite(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("无法创建测试数据库: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(model.AllModels()...); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	return db
+}
+
+// newTestAuditServices 创建共用同一主数据库的备份服务和日志服务，并写入 n 条审计日志
+func newTestAuditServices(t *testing.T, n int) (*BackupService, *LogService, *gorm.DB) {
+	t.Helper()
+	dir := t.TempDir()
+
+	useTestConfigDB(t, &model.AuditAnchorDB{})
+	oldConnected := model.DBConnected
+	model.DBConnected = true
+	t.Cleanup(func() { model.DBConnected = oldConnected })
+
+	db := openTestSQLite(t, filepath.Join(dir, "main.db"))
+	repo := repository.NewRepository(db)
+	logSvc := &LogService{repo: repo, logDir: dir}
+	for i := 0; i < n; i++ {
+		appendTestAuditLog(t, logSvc, "action")
+	}
+	return &BackupService{repo: repo, backupDir: dir}, logSvc, db
+}
+
+// appendTestAuditLog 写入一条审计日志
+func appendTestAuditLog(t *testing.T, s *LogService, action string) {
+	t.Helper()
+	entry := &LogEntry{UserType: "admin", Username: "admin", Action: action, Category: "system", CreatedAt: time.Now()}
+	if err := s.appendAuditLog(entry); err != nil {
+		t.Fatalf("写入审计日志失败: %v", err)
+	}
+}
+
+// assertAuditChainValid 校验哈希链完整且链尾为 headID
+func assertAuditChainValid(t *testing.T, s *LogService, headID uint) {
+	t.Helper()
+	result, err := s.VerifyAuditChain()
+	if err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if !result.Valid {
+		t.Fatalf("哈希链应完整，实际失败于 %d: %s", result.BrokenID, result.Reason)
+	}
+	if result.HeadID != headID {
+		t.Errorf("链尾应为 %d，实际 %d", headID, result.HeadID)
+	}
+}
+
+// TestRestoreSQLite_KeepsAuditChain 恢复较旧的备份后审计日志保留当前数据，哈希链仍可校验并继续追加
+func TestRestoreSQLite_KeepsAuditChain(t *testing.T) {
+	backupSvc, logSvc, db := newTestAuditServices(t, 3)
+	if err := db.Create(&model.USDTPayment{OrderNo: "CURRENT", ExpiresAt: time.Now()}).Error; err != nil {
+		t.Fatalf("写入数据失败: %v", err)
+	}
+
+	// 备份中的审计日志只有一条且与当前链不同（备份之后又写入了新记录）
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	backupDB := openTestSQLite(t, backupPath)
+	if err := backupDB.Create(&model.USDTPayment{OrderNo: "RESTORED", ExpiresAt: time.Now()}).Error; err != nil {
+		t.Fatalf("写入备份数据失败: %v", err)
+	}
+	if err := backupDB.Create(&model.AuditLog{Action: "old", Hash: strings.Repeat("0", 64), CreatedAt: time.Now()}).Error; err != nil {
+		t.Fatalf("写入备份审计日志失败: %v", err)
+	}
+	sqlDB, _ := backupDB.DB()
+	sqlDB.Close()
+
+	f, err := os.Open(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, _, err := backupSvc.restoreSQLite(f); err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+
+	var orders []string
+	db.Model(&model.USDTPayment{}).Pluck("order_no", &orders)
+	if len(orders) != 1 || orders[0] != "RESTORED" {
+		t.Errorf("业务数据应替换为备份中的数据，实际 %v", orders)
+	}
+	var count int64
+	db.Model(&model.AuditLog{}).Count(&count)
+	if count != 3 {
+		t.Errorf("审计日志应保留当前的 3 条，实际 %d", count)
+	}
+	assertAuditChainValid(t, logSvc, 3)
+
+	appendTestAuditLog(t, logSvc, "restore")
+	assertAuditChainValid(t, logSvc, 4)
+}
+
+// TestImportExport_KeepsAuditChain 导入包含审计日志的旧版本导出文件时忽略审计日志
+func TestImportExport_KeepsAuditChain(t *testing.T) {
+	backupSvc, logSvc, db := newTestAuditServices(t, 2)
+
+	export := strings.Join([]string{
+		`{"type":"header","format":"user-frontend-export","version":1}`,
+		`{"type":"table","table":"audit_logs","columns":["id","action","hash"]}`,
+		`{"type":"row","data":{"id":1,"action":"old","hash":"` + strings.Repeat("0", 64) + `"}}`,
+		`{"type":"end","table":"audit_logs","rows":1}`,
+		`{"type":"table","table":"usdt_payments","columns":["id","order_no"]}`,
+		`{"type":"row","data":{"id":7,"order_no":"IMPORTED"}}`,
+		`{"type":"end","table":"usdt_payments","rows":1}`,
+		`{"type":"footer","tables":2,"rows":2}`,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	if err := os.WriteFile(path, []byte(export), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result := &ImportResult{}
+	if err := backupSvc.importExport(path, "sqlite", result); err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.Tables != 1 {
+		t.Errorf("应只导入 1 张表，实际 %d", result.Tables)
+	}
+
+	var payment model.USDTPayment
+	if err := db.First(&payment, 7).Error; err != nil || payment.OrderNo != "IMPORTED" {
+		t.Errorf("业务数据未导入: %v", err)
+	}
+	var count int64
+	db.Model(&model.AuditLog{}).Count(&count)
+	if count != 2 {
+		t.Errorf("审计日志应保留当前的 2 条，实际 %d", count)
+	}
+	assertAuditChainValid(t, logSvc, 2)
+}
+
+// TestExportTables_SkipsAuditLogs 导出文件不包含审计日志
+func TestExportTables_SkipsAuditLogs(t *testing.T) {
+	db := openTestSQLite(t, filepath.Join(t.TempDir(), "main.db"))
+	tables, err := exportTables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if restoreSkipTables[table.schema.Table] {
+			t.Errorf("导出不应包含表 %s", table.schema.Table)
+		}
+	}
+}
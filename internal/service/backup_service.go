@@ -3,22 +3,29 @@ package service
 import (
 	"archive/zip"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"user-frontend/internal/config"
 	"user-frontend/internal/model"
 	"user-frontend/internal/repository"
+	"user-frontend/internal/storage"
 )
 
 type BackupService struct {
 	repo      *repository.Repository
 	backupDir string
+
+	cfgMu     sync.RWMutex
+	cfg       *config.BackupConfig
+	scheduled atomic.Bool    // 定时备份是否正在执行
+	running   sync.WaitGroup // 执行中的定时备份，停机时等待其完成
 }
 
 func NewBackupService(repo *repository.Repository, baseDir string) *BackupService {
@@ -27,12 +34,36 @@ func NewBackupService(repo *repository.Repository, baseDir string) *BackupServic
 	return &BackupService{
 		repo:      repo,
 		backupDir: backupDir,
+		cfg:       DefaultBackupConfig(),
+	}
+}
+
+// SetBackupConfig 更新备份配置（压缩、加密、定时与保留策略、异地目标）
+func (s *BackupService) SetBackupConfig(cfg *config.BackupConfig) {
+	if cfg == nil {
+		cfg = DefaultBackupConfig()
 	}
+	s.cfgMu.Lock()
+	s.cfg = cfg
+	s.cfgMu.Unlock()
+}
+
+// backupConfig 获取当前备份配置
+func (s *BackupService) backupConfig() *config.BackupConfig {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
 }
 
 // CreateBackup 创建数据库备份
-func (s *BackupService) CreateBackup(dbConfig *config.DBConfig, createdBy, remark string) (*model.DatabaseBackup, error) {
-	timestamp := time.Now().Format("20060102_150405")
+// includeObjects 为 true 时将对象存储中的文件（商品图片、工单附件等）一并打包到 objects/ 目录下
+func (s *BackupService) CreateBackup(dbConfig *config.DBConfig, createdBy, remark string, includeObjects bool) (*model.DatabaseBackup, error) {
+	return s.createBackup(dbConfig, model.BackupKindManual, createdBy, remark, includeObjects)
+}
+
+// createBackup 创建指定类型的备份，按备份配置压缩、加密后计算校验和
+func (s *BackupService) createBackup(dbConfig *config.DBConfig, kind, createdBy, remark string, includeObjects bool) (*model.DatabaseBackup, error) {
+	timestamp := s.uniqueTimestamp()
 	var filename string
 	var filePath string
 	var fileSize int64
@@ -69,14 +100,54 @@ func (s *BackupService) CreateBackup(dbConfig *config.DBConfig, createdBy, remar
 		return nil, fmt.Errorf("不支持的数据库类型: %s", dbConfig.Type)
 	}
 
+	// 打包对象存储文件
+	if includeObjects {
+		bundleName := strings.TrimSuffix(filename, filepath.Ext(filename)) + "_with_files.zip"
+		bundlePath := filepath.Join(s.backupDir, bundleName)
+		size, err := s.bundleWithObjects(filePath, bundlePath)
+		os.Remove(filePath)
+		if err != nil {
+			os.Remove(bundlePath)
+			return nil, fmt.Errorf("打包存储文件失败: %v", err)
+		}
+		filename, filePath, fileSize = bundleName, bundlePath, size
+	}
+
+	// 压缩、加密（备份中包含全部客户邮箱与卡密，默认加密存放）
+	encodedPath, compressed, encrypted, err := encodeBackupFile(filePath, s.backupConfig())
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+	if encodedPath != filePath {
+		filePath = encodedPath
+		filename = filepath.Base(encodedPath)
+		if info, err := os.Stat(filePath); err == nil {
+			fileSize = info.Size()
+		}
+	}
+
+	// 计算校验和（针对最终存放的文件，恢复和异地上传前用于校验文件是否完整、未被篡改）
+	checksum, err := fileSHA256(filePath)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("计算备份校验和失败: %v", err)
+	}
+
 	// 保存备份记录
 	backup := &model.DatabaseBackup{
-		Filename:  filename,
-		FilePath:  filePath,
-		FileSize:  fileSize,
-		DBType:    dbConfig.Type,
-		Remark:    remark,
-		CreatedBy: createdBy,
+		Filename:       filename,
+		FilePath:       filePath,
+		FileSize:       fileSize,
+		DBType:         dbConfig.Type,
+		IncludeObjects: includeObjects,
+		Kind:           kind,
+		Compressed:     compressed,
+		Encrypted:      encrypted,
+		Checksum:       checksum,
+		SchemaVersion:  model.SchemaVersion,
+		Remark:         remark,
+		CreatedBy:      createdBy,
 	}
 
 	if err := s.repo.CreateBackupRecord(backup); err != nil {
@@ -88,6 +159,21 @@ func (s *BackupService) CreateBackup(dbConfig *config.DBConfig, createdBy, remar
 	return backup, nil
 }
 
+// uniqueTimestamp 生成备份文件名使用的时间戳
+// 同一秒内已有备份（如恢复前自动快照）时追加序号，避免覆盖已有备份文件
+func (s *BackupService) uniqueTimestamp() string {
+	base := time.Now().Format("20060102_150405")
+	timestamp := base
+	for i := 2; ; i++ {
+		matches, _ := filepath.Glob(filepath.Join(s.backupDir, "backup_*_"+timestamp+".*"))
+		bundles, _ := filepath.Glob(filepath.Join(s.backupDir, "backup_*_"+timestamp+"_with_files.zip*"))
+		if len(matches) == 0 && len(bundles) == 0 {
+			return timestamp
+		}
+		timestamp = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
 // backupSQLite 备份SQLite数据库
 func (s *BackupService) backupSQLite(dbPath, backupPath string) (int64, error) {
 	// 创建zip文件
@@ -99,6 +185,7 @@ func (s *BackupService) backupSQLite(dbPath, backupPath string) (int64, error) {
 
 	zipWriter := zip.NewWriter(zipFile)
 	defer zipWriter.Close()
+	zipWriter.SetComment(fmt.Sprintf("%s%d", schemaVersionComment, model.SchemaVersion))
 
 	// 打开源数据库文件
 	srcFile, err := os.Open(dbPath)
@@ -145,6 +232,90 @@ func (s *BackupService) backupSQLite(dbPath, backupPath string) (int64, error) {
 	return backupInfo.Size(), nil
 }
 
+// bundleWithObjects 将数据库备份与对象存储中的所有文件打包为一个 zip
+// 数据库备份为 zip 时复制其中的条目，否则以原文件名作为条目；对象文件位于 objects/ 下
+func (s *BackupService) bundleWithObjects(dbBackupPath, bundlePath string) (int64, error) {
+	out, err := os.Create(bundlePath)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+
+	if strings.HasSuffix(dbBackupPath, ".zip") {
+		zr, err := zip.OpenReader(dbBackupPath)
+		if err != nil {
+			return 0, err
+		}
+		zw.SetComment(zr.Comment)
+		for _, f := range zr.File {
+			if err := zw.Copy(f); err != nil {
+				zr.Close()
+				return 0, err
+			}
+		}
+		zr.Close()
+	} else {
+		if err := addFileToZip(zw, dbBackupPath, filepath.Base(dbBackupPath)); err != nil {
+			return 0, err
+		}
+	}
+
+	st := storage.Default()
+	objects, err := st.List("")
+	if err != nil {
+		return 0, err
+	}
+	for _, obj := range objects {
+		rc, _, err := st.Get(obj.Key)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", obj.Key, err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: "objects/" + obj.Key, Method: zip.Deflate, Modified: obj.ModTime})
+		if err == nil {
+			_, err = io.Copy(w, rc)
+		}
+		rc.Close()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", obj.Key, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// addFileToZip 将本地文件写入 zip 条目
+func addFileToZip(zw *zip.Writer, filePath, name string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
 // backupMySQL 备份MySQL数据库（使用SQL导出）
 func (s *BackupService) backupMySQL(cfg *config.DBConfig, backupPath string) (int64, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
@@ -182,8 +353,8 @@ func (s *BackupService) exportToSQL(db *sql.DB, dbName, backupPath, dbType strin
 	defer file.Close()
 
 	// 写入头部信息
-	header := fmt.Sprintf("-- Database Backup\n-- Database: %s\n-- Type: %s\n-- Created: %s\n\n",
-		dbName, dbType, time.Now().Format("2006-01-02 15:04:05"))
+	header := fmt.Sprintf("-- Database Backup\n-- Database: %s\n-- Type: %s\n%s%d\n-- Created: %s\n\n",
+		dbName, dbType, schemaVersionHeader, model.SchemaVersion, time.Now().Format("2006-01-02 15:04:05"))
 	file.WriteString(header)
 
 	// 获取所有表
@@ -212,12 +383,16 @@ func (s *BackupService) exportToSQL(db *sql.DB, dbName, backupPath, dbType strin
 		file.WriteString(fmt.Sprintf("\n-- Table: %s\n", table))
 
 		// 获取表数据
-		dataRows, err := db.Query(fmt.Sprintf("SELECT * FROM %s", table))
+		dataRows, err := db.Query(fmt.Sprintf("SELECT * FROM %s", quoteIdent(dbType, table)))
 		if err != nil {
 			continue
 		}
 
 		columns, _ := dataRows.Columns()
+		quotedColumns := make([]string, len(columns))
+		for i, col := range columns {
+			quotedColumns[i] = quoteIdent(dbType, col)
+		}
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
 		for i := range values {
@@ -246,7 +421,7 @@ func (s *BackupService) exportToSQL(db *sql.DB, dbName, backupPath, dbType strin
 			}
 
 			insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);\n",
-				table, strings.Join(columns, ", "), strings.Join(valueStrings, ", "))
+				quoteIdent(dbType, table), strings.Join(quotedColumns, ", "), strings.Join(valueStrings, ", "))
 			file.WriteString(insertSQL)
 		}
 		dataRows.Close()
@@ -283,7 +458,8 @@ func (s *BackupService) DeleteBackup(id uint) error {
 		os.Remove(backup.FilePath)
 	}
 
-	// 删除记录
+	// 删除记录（异地副本保留，需在目标存储中自行清理）
+	s.repo.DeleteBackupUploads(id)
 	return s.repo.DeleteBackupRecord(id)
 }
 
@@ -321,46 +497,3 @@ func FormatFileSize(size int64) string {
 		return fmt.Sprintf("%d B", size)
 	}
 }
-
-// ExportDataAsJSON 导出数据为JSON格式（用于简单备份）
-func (s *BackupService) ExportDataAsJSON(createdBy string) (*model.DatabaseBackup, error) {
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("backup_json_%s.json", timestamp)
-	filePath := filepath.Join(s.backupDir, filename)
-
-	// 收集所有数据
-	data := make(map[string]interface{})
-
-	// 这里可以根据需要导出各种数据
-	// 示例：导出基本统计信息
-	data["export_time"] = time.Now().Format("2006-01-02 15:04:05")
-	data["export_by"] = createdBy
-
-	// 写入JSON文件
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return nil, err
-	}
-
-	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
-		return nil, err
-	}
-
-	fileInfo, _ := os.Stat(filePath)
-
-	backup := &model.DatabaseBackup{
-		Filename:  filename,
-		FilePath:  filePath,
-		FileSize:  fileInfo.Size(),
-		DBType:    "json",
-		Remark:    "JSON数据导出",
-		CreatedBy: createdBy,
-	}
-
-	if err := s.repo.CreateBackupRecord(backup); err != nil {
-		os.Remove(filePath)
-		return nil, err
-	}
-
-	return backup, nil
-}
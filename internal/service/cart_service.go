@@ -135,6 +135,9 @@ func (s *CartService) AddToCart(userID, productID uint, quantity int) (*model.Ca
 	if product.Status != 1 {
 		return nil, errors.New("商品已下架")
 	}
+	if err := checkPurchasable(s.repo, &product); err != nil {
+		return nil, err
+	}
 
 	// 检查库存
 	if product.Stock != -1 && product.Stock < quantity {
@@ -256,8 +259,8 @@ func (s *CartService) ValidateCart(userID uint) ([]model.CartItem, []string, err
 			cacheInvalidated = true
 			continue
 		}
-		if item.Product.Status != 1 {
-			// 商品已下架
+		if item.Product.Status != 1 || checkPurchasable(s.repo, item.Product) != nil {
+			// 商品已下架（或已改为多规格商品）
 			db.Delete(&item)
 			warnings = append(warnings, item.Product.Name+" 已下架，已自动移除")
 			cacheInvalidated = true
@@ -147,6 +147,9 @@ func (s *CategoryService) invalidateCategoryListCache() {
 	if err := cm.Delete(treeKey); err != nil {
 		log.Printf("[CategoryService] 删除分类树缓存失败: %v", err)
 	}
+
+	// 分类名称出现在商品目录分面中
+	invalidateCatalogCache()
 }
 
 // CreateCategory 创建分类
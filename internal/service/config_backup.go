@@ -0,0 +1,273 @@
+// Package service 提供业务逻辑服务
+// config_backup.go - 备份配置管理
+//
+// 本模块负责备份压缩、加密、定时备份、保留策略与异地存放目标的读取和保存。
+// 配置存储在 SQLite 配置数据库中，恢复主数据库不会影响备份配置。
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/storage"
+)
+
+// 备份加密密钥来源
+const (
+	BackupKeyConfig     = "config_key" // 使用配置加密密钥
+	BackupKeyPassphrase = "passphrase" // 使用独立备份口令
+)
+
+// 备份目标类型
+const (
+	BackupTargetS3    = "s3"
+	BackupTargetSFTP  = "sftp"
+	BackupTargetLocal = "local"
+)
+
+// ==================== 备份配置管理 ====================
+
+// GetBackupConfig 获取备份配置
+//
+// 从 SQLite 配置数据库读取并解密口令与目标密钥，配置不存在时返回默认配置。
+func (s *ConfigService) GetBackupConfig() (*config.BackupConfig, error) {
+	if s.configDB == nil {
+		return DefaultBackupConfig(), nil
+	}
+
+	var dbConfig model.BackupConfigDB
+	if err := s.configDB.First(&dbConfig).Error; err != nil {
+		return DefaultBackupConfig(), nil
+	}
+
+	cfg := &config.BackupConfig{
+		Compress:        dbConfig.Compress,
+		Encrypt:         dbConfig.Encrypt,
+		EncryptionMode:  dbConfig.EncryptionMode,
+		ScheduleEnabled: dbConfig.ScheduleEnabled,
+		IntervalHours:   dbConfig.IntervalHours,
+		IncludeObjects:  dbConfig.IncludeObjects,
+		KeepLast:        dbConfig.KeepLast,
+		KeepDaily:       dbConfig.KeepDaily,
+		KeepWeekly:      dbConfig.KeepWeekly,
+		KeepMonthly:     dbConfig.KeepMonthly,
+	}
+	if dbConfig.Passphrase != "" {
+		if decrypted, err := decryptPassword(dbConfig.Passphrase); err == nil {
+			cfg.Passphrase = decrypted
+		}
+	}
+	if dbConfig.Targets != "" {
+		if err := json.Unmarshal([]byte(dbConfig.Targets), &cfg.Targets); err != nil {
+			return nil, fmt.Errorf("备份目标配置损坏: %v", err)
+		}
+		for i := range cfg.Targets {
+			t := &cfg.Targets[i]
+			t.SecretKey = decryptOptional(t.SecretKey)
+			t.Password = decryptOptional(t.Password)
+			t.PrivateKey = decryptOptional(t.PrivateKey)
+		}
+	}
+	return cfg, nil
+}
+
+// SaveBackupConfig 保存备份配置
+//
+// 口令与目标密钥加密存储；传入空值时保留原有值（目标按名称匹配）。
+func (s *ConfigService) SaveBackupConfig(cfg *config.BackupConfig) error {
+	if s.configDB == nil {
+		return errors.New("配置数据库未初始化")
+	}
+	if err := ValidateBackupConfig(cfg); err != nil {
+		return err
+	}
+
+	existing, _ := s.GetBackupConfig()
+	if cfg.EncryptionMode == BackupKeyPassphrase && cfg.Passphrase == "" && (existing == nil || existing.Passphrase == "") {
+		return errors.New("使用备份口令加密时口令不能为空")
+	}
+	previous := make(map[string]config.BackupTarget)
+	if existing != nil {
+		for _, t := range existing.Targets {
+			previous[t.Name] = t
+		}
+	}
+
+	// 布尔字段带有默认值，创建时零值会被默认值替换，因此先创建记录再整体保存
+	var dbConfig model.BackupConfigDB
+	if err := s.configDB.First(&dbConfig).Error; err != nil {
+		if err := s.configDB.Create(&dbConfig).Error; err != nil {
+			return err
+		}
+	}
+
+	dbConfig.Compress = cfg.Compress
+	dbConfig.Encrypt = cfg.Encrypt
+	dbConfig.EncryptionMode = cfg.EncryptionMode
+	dbConfig.ScheduleEnabled = cfg.ScheduleEnabled
+	dbConfig.IntervalHours = cfg.IntervalHours
+	dbConfig.IncludeObjects = cfg.IncludeObjects
+	dbConfig.KeepLast = cfg.KeepLast
+	dbConfig.KeepDaily = cfg.KeepDaily
+	dbConfig.KeepWeekly = cfg.KeepWeekly
+	dbConfig.KeepMonthly = cfg.KeepMonthly
+
+	if cfg.Passphrase != "" {
+		encrypted, err := encryptPassword(cfg.Passphrase)
+		if err != nil {
+			return err
+		}
+		dbConfig.Passphrase = encrypted
+	}
+
+	targets := make([]config.BackupTarget, len(cfg.Targets))
+	for i, t := range cfg.Targets {
+		old := previous[t.Name]
+		if t.SecretKey == "" {
+			t.SecretKey = old.SecretKey
+		}
+		if t.Password == "" {
+			t.Password = old.Password
+		}
+		if t.PrivateKey == "" {
+			t.PrivateKey = old.PrivateKey
+		}
+		var err error
+		if t.SecretKey, err = encryptOptional(t.SecretKey); err != nil {
+			return err
+		}
+		if t.Password, err = encryptOptional(t.Password); err != nil {
+			return err
+		}
+		if t.PrivateKey, err = encryptOptional(t.PrivateKey); err != nil {
+			return err
+		}
+		targets[i] = t
+	}
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return err
+	}
+	dbConfig.Targets = string(data)
+
+	return s.configDB.Save(&dbConfig).Error
+}
+
+// DefaultBackupConfig 默认备份配置：压缩并使用配置加密密钥加密，不启用定时备份
+func DefaultBackupConfig() *config.BackupConfig {
+	return &config.BackupConfig{
+		Compress:       true,
+		Encrypt:        true,
+		EncryptionMode: BackupKeyConfig,
+		IntervalHours:  24,
+	}
+}
+
+// ValidateBackupConfig 校验备份配置
+func ValidateBackupConfig(cfg *config.BackupConfig) error {
+	if cfg == nil {
+		return errors.New("配置为空")
+	}
+	if cfg.EncryptionMode == "" {
+		cfg.EncryptionMode = BackupKeyConfig
+	}
+	if cfg.EncryptionMode != BackupKeyConfig && cfg.EncryptionMode != BackupKeyPassphrase {
+		return errors.New("无效的加密密钥来源")
+	}
+	if cfg.Passphrase != "" && len(cfg.Passphrase) < 12 {
+		return errors.New("备份口令至少12位")
+	}
+	if cfg.IntervalHours <= 0 {
+		cfg.IntervalHours = 24
+	}
+	if cfg.KeepLast < 0 || cfg.KeepDaily < 0 || cfg.KeepWeekly < 0 || cfg.KeepMonthly < 0 {
+		return errors.New("保留数量不能为负数")
+	}
+
+	names := make(map[string]bool)
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			return errors.New("备份目标名称不能为空")
+		}
+		if names[t.Name] {
+			return fmt.Errorf("备份目标名称重复: %s", t.Name)
+		}
+		names[t.Name] = true
+
+		switch t.Type {
+		case BackupTargetS3:
+			if t.Endpoint == "" || t.Bucket == "" {
+				return fmt.Errorf("备份目标 %s 缺少服务地址或存储桶", t.Name)
+			}
+		case BackupTargetSFTP:
+			if t.Host == "" || t.User == "" {
+				return fmt.Errorf("备份目标 %s 缺少主机或用户名", t.Name)
+			}
+		case BackupTargetLocal:
+			if t.Path == "" {
+				return fmt.Errorf("备份目标 %s 缺少挂载目录", t.Name)
+			}
+		default:
+			return fmt.Errorf("备份目标 %s 类型无效", t.Name)
+		}
+	}
+	return nil
+}
+
+// NewBackupTargetStorage 根据备份目标创建存储实例
+func NewBackupTargetStorage(t *config.BackupTarget) (storage.Storage, error) {
+	switch t.Type {
+	case BackupTargetS3:
+		return storage.NewS3Storage(&storage.Config{
+			Driver:    storage.DriverS3,
+			Endpoint:  t.Endpoint,
+			Region:    t.Region,
+			Bucket:    t.Bucket,
+			AccessKey: t.AccessKey,
+			SecretKey: t.SecretKey,
+			PathStyle: t.PathStyle,
+			Prefix:    t.Prefix,
+			Timeout:   t.Timeout,
+		})
+	case BackupTargetSFTP:
+		return storage.NewSFTPStorage(&storage.SFTPConfig{
+			Host:               t.Host,
+			Port:               t.Port,
+			User:               t.User,
+			Password:           t.Password,
+			PrivateKey:         t.PrivateKey,
+			HostKeyFingerprint: t.HostKeyFingerprint,
+			Root:               t.Path,
+			Timeout:            t.Timeout,
+		})
+	case BackupTargetLocal:
+		return storage.NewLocalStorage(t.Path)
+	}
+	return nil, fmt.Errorf("不支持的备份目标类型: %s", t.Type)
+}
+
+// encryptOptional 加密非空字符串
+func encryptOptional(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return encryptPassword(value)
+}
+
+// decryptOptional 解密非空字符串，失败时返回空
+func decryptOptional(value string) string {
+	if value == "" {
+		return ""
+	}
+	decrypted, err := decryptPassword(value)
+	if err != nil {
+		return ""
+	}
+	return decrypted
+}
@@ -11,6 +11,8 @@ import (
 	"user-frontend/internal/config"
 	"user-frontend/internal/model"
 	"user-frontend/internal/utils"
+
+	"gorm.io/gorm"
 )
 
 // GetDBConfig 获取数据库配置（从SQLite配置数据库）
@@ -171,7 +173,16 @@ func (s *ConfigService) InitEncryptionKey() error {
 	return nil
 }
 
-// ResetEncryptionKey 重置加密密钥（危险操作！会导致已加密数据无法解密）
+// ResetEncryptionKey 生成新的加密密钥，并将全部加密存储的值改用新密钥加密
+//
+// 配置数据库（数据库密码、Redis、对象存储与备份密钥）和主数据库（SMTP 密码、支付密钥、TOTP 密钥、
+// 审计日志的详情和 User-Agent）各在一个事务内改写。主数据库先提交，配置数据库（含新密钥）后提交；
+// 配置数据库提交失败时将主数据库改回旧密钥。轮换期间必须持有配置数据库中的维护锁，使共用配置的
+// 所有实例暂停请求和后台任务，避免并发写入使用旧密钥；无法写入维护锁时拒绝轮换。
+// 其他实例启动时已将旧密钥加载到内存，轮换完成后所有实例都必须重启以加载新密钥，否则会继续使用
+// 旧密钥加密和解密。
+// 审计日志的哈希按明文计算，改写后仍可校验。使用配置加密密钥加密的备份文件、CSV日志文件和
+// 审计日志归档文件不会改写，仍需旧密钥解密。
 func (s *ConfigService) ResetEncryptionKey(keyLength int) (string, error) {
 	if s.configDB == nil {
 		return "", fmt.Errorf("配置数据库未初始化")
@@ -182,15 +193,33 @@ func (s *ConfigService) ResetEncryptionKey(keyLength int) (string, error) {
 		keyLength = 256
 	}
 
+	if err := EnterMaintenance("正在轮换加密密钥"); err != nil {
+		return "", err
+	}
+	defer LeaveMaintenance()
+	if !holdsSharedMaintenance() {
+		return "", fmt.Errorf("无法在配置数据库中写入维护锁，不能确认其他实例已暂停写入，已拒绝轮换密钥")
+	}
+
 	// 生成新密钥
 	keyBase64, err := utils.GenerateAESKey(keyLength)
 	if err != nil {
 		return "", fmt.Errorf("生成密钥失败: %v", err)
 	}
+	oldKeyBase64 := base64.StdEncoding.EncodeToString(utils.GetConfigEncryptionKey())
+	rotate := func(v string) (string, error) {
+		return model.ReEncryptSecret(v, oldKeyBase64, keyBase64)
+	}
+
+	configTx := s.configDB.Begin()
+	if err := rotateConfigSecrets(configTx, rotate); err != nil {
+		configTx.Rollback()
+		return "", fmt.Errorf("改写配置数据库密钥失败: %v", err)
+	}
 
-	// 更新数据库
+	// 更新密钥记录
 	var dbConfig model.DBConfigDB
-	err = s.configDB.First(&dbConfig).Error
+	err = configTx.First(&dbConfig).Error
 	if err != nil {
 		dbConfig = model.DBConfigDB{
 			Type:          "sqlite",
@@ -200,17 +229,43 @@ func (s *ConfigService) ResetEncryptionKey(keyLength int) (string, error) {
 			EncryptionKey: keyBase64,
 			KeyLength:     keyLength,
 		}
-		if err := s.configDB.Create(&dbConfig).Error; err != nil {
+		if err := configTx.Create(&dbConfig).Error; err != nil {
+			configTx.Rollback()
 			return "", fmt.Errorf("保存配置失败: %v", err)
 		}
 	} else {
 		dbConfig.EncryptionKey = keyBase64
 		dbConfig.KeyLength = keyLength
-		if err := s.configDB.Save(&dbConfig).Error; err != nil {
+		if err := configTx.Save(&dbConfig).Error; err != nil {
+			configTx.Rollback()
 			return "", fmt.Errorf("保存密钥失败: %v", err)
 		}
 	}
 
+	if s.mainDB != nil {
+		err := s.mainDB.Transaction(func(tx *gorm.DB) error {
+			return model.RewriteSecrets(tx, rotate)
+		})
+		if err != nil {
+			configTx.Rollback()
+			return "", fmt.Errorf("改写主数据库密钥失败: %v", err)
+		}
+	}
+
+	if err := configTx.Commit().Error; err != nil {
+		if s.mainDB != nil {
+			revert := func(v string) (string, error) {
+				return model.ReEncryptSecret(v, keyBase64, oldKeyBase64)
+			}
+			if rbErr := s.mainDB.Transaction(func(tx *gorm.DB) error {
+				return model.RewriteSecrets(tx, revert)
+			}); rbErr != nil {
+				return "", fmt.Errorf("保存密钥失败: %v；主数据库已使用新密钥加密且回退失败（%v），请妥善保存新密钥: %s", err, rbErr, keyBase64)
+			}
+		}
+		return "", fmt.Errorf("保存密钥失败: %v", err)
+	}
+
 	// 更新全局密钥
 	key, _ := base64.StdEncoding.DecodeString(keyBase64)
 	utils.SetConfigEncryptionKey(key)
@@ -218,6 +273,96 @@ func (s *ConfigService) ResetEncryptionKey(keyLength int) (string, error) {
 	return keyBase64, nil
 }
 
+// rotateConfigSecrets 改写配置数据库中全部加密存储的值
+func rotateConfigSecrets(tx *gorm.DB, fn func(string) (string, error)) error {
+	var dbConfigs []model.DBConfigDB
+	if err := tx.Find(&dbConfigs).Error; err != nil {
+		return err
+	}
+	for i := range dbConfigs {
+		if err := rewriteFields(fn, &dbConfigs[i].Password); err != nil {
+			return err
+		}
+		if err := tx.Save(&dbConfigs[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	var redisConfigs []model.RedisConfigDB
+	if err := tx.Find(&redisConfigs).Error; err != nil {
+		return err
+	}
+	for i := range redisConfigs {
+		r := &redisConfigs[i]
+		if err := rewriteFields(fn, &r.Password, &r.SentinelPassword, &r.TLSKey); err != nil {
+			return err
+		}
+		if err := tx.Save(r).Error; err != nil {
+			return err
+		}
+	}
+
+	var storageConfigs []model.StorageConfigDB
+	if err := tx.Find(&storageConfigs).Error; err != nil {
+		return err
+	}
+	for i := range storageConfigs {
+		if err := rewriteFields(fn, &storageConfigs[i].SecretKey); err != nil {
+			return err
+		}
+		if err := tx.Save(&storageConfigs[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	var backupConfigs []model.BackupConfigDB
+	if err := tx.Find(&backupConfigs).Error; err != nil {
+		return err
+	}
+	for i := range backupConfigs {
+		b := &backupConfigs[i]
+		if err := rewriteFields(fn, &b.Passphrase); err != nil {
+			return err
+		}
+		if b.Targets != "" {
+			var targets []config.BackupTarget
+			if err := json.Unmarshal([]byte(b.Targets), &targets); err != nil {
+				return fmt.Errorf("备份目标配置格式无效: %v", err)
+			}
+			for j := range targets {
+				t := &targets[j]
+				if err := rewriteFields(fn, &t.SecretKey, &t.Password, &t.PrivateKey); err != nil {
+					return err
+				}
+			}
+			data, err := json.Marshal(targets)
+			if err != nil {
+				return err
+			}
+			b.Targets = string(data)
+		}
+		if err := tx.Save(b).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteFields 对每个非空字段执行 fn
+func rewriteFields(fn func(string) (string, error), fields ...*string) error {
+	for _, f := range fields {
+		if *f == "" {
+			continue
+		}
+		v, err := fn(*f)
+		if err != nil {
+			return err
+		}
+		*f = v
+	}
+	return nil
+}
+
 // GetServerPort 获取服务器端口配置（从SQLite配置数据库）
 func (s *ConfigService) GetServerPort() (int, error) {
 	if s.configDB == nil {
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/utils"
+)
+
+// newTestKeyConfigService 创建使用内存配置数据库的配置服务，写入当前加密密钥
+func newTestKeyConfigService(t *testing.T) *ConfigService {
+	t.Helper()
+	db := useTestConfigDB(t, &model.DBConfigDB{}, &model.RedisConfigDB{}, &model.StorageConfigDB{},
+		&model.BackupConfigDB{}, &model.MaintenanceLockDB{})
+	withoutMaintenanceSettle(t)
+
+	oldKey := utils.GetConfigEncryptionKey()
+	t.Cleanup(func() { utils.SetConfigEncryptionKey(oldKey) })
+	keyBase64, err := utils.GenerateAESKey(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.DBConfigDB{Type: "sqlite", EncryptionKey: keyBase64, KeyLength: 256}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := InitConfigService(db)
+	if err := s.InitEncryptionKey(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// storedEncryptionKey 读取配置数据库中的加密密钥
+func storedEncryptionKey(t *testing.T, s *ConfigService) string {
+	t.Helper()
+	var dbConfig model.DBConfigDB
+	if err := s.configDB.First(&dbConfig).Error; err != nil {
+		t.Fatal(err)
+	}
+	return dbConfig.EncryptionKey
+}
+
+// TestResetEncryptionKey_RequiresSharedLock 测试轮换密钥必须持有配置数据库中的维护锁
+func TestResetEncryptionKey_RequiresSharedLock(t *testing.T) {
+	t.Run("持有维护锁", func(t *testing.T) {
+		s := newTestKeyConfigService(t)
+		oldKey := storedEncryptionKey(t, s)
+
+		newKey, err := s.ResetEncryptionKey(256)
+		if err != nil {
+			t.Fatalf("轮换失败: %v", err)
+		}
+		if newKey == oldKey || storedEncryptionKey(t, s) != newKey {
+			t.Error("密钥未更新")
+		}
+		var count int64
+		s.configDB.Model(&model.MaintenanceLockDB{}).Count(&count)
+		if count != 0 || GetMaintenanceStatus().Active {
+			t.Error("轮换完成后应退出维护模式")
+		}
+	})
+
+	t.Run("其他实例持有维护锁", func(t *testing.T) {
+		s := newTestKeyConfigService(t)
+		oldKey := storedEncryptionKey(t, s)
+		now := time.Now()
+		s.configDB.Create(&model.MaintenanceLockDB{ID: maintenanceLockID, Owner: "other:1", Reason: "正在恢复数据库", Since: now, HeartbeatAt: now})
+
+		if _, err := s.ResetEncryptionKey(256); !errors.Is(err, errMaintenanceBusy) {
+			t.Errorf("应返回 errMaintenanceBusy，实际 %v", err)
+		}
+		if storedEncryptionKey(t, s) != oldKey {
+			t.Error("不应轮换密钥")
+		}
+	})
+
+	t.Run("无法写入维护锁", func(t *testing.T) {
+		s := newTestKeyConfigService(t)
+		oldKey := storedEncryptionKey(t, s)
+		// 全局配置数据库不可用时维护模式只在本实例生效
+		model.ConfigDB = nil
+
+		if _, err := s.ResetEncryptionKey(256); err == nil {
+			t.Error("未持有维护锁时应拒绝轮换")
+		}
+		if storedEncryptionKey(t, s) != oldKey {
+			t.Error("不应轮换密钥")
+		}
+		if GetMaintenanceStatus().Active {
+			t.Error("拒绝后应退出维护模式")
+		}
+	})
+}
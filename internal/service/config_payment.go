@@ -9,14 +9,14 @@ import (
 	"user-frontend/internal/model"
 )
 
-// GetPaymentConfig 获取所有支付配置
+// GetPaymentConfig 获取所有支付配置（密钥字段已解密）
 func (s *ConfigService) GetPaymentConfig() (*config.PaymentConfig, error) {
 	result := &config.PaymentConfig{}
 
 	// 获取支付宝配置
 	if alipay, err := s.repo.GetPaymentConfig("alipay_f2f"); err == nil {
 		var cfg config.AlipayF2FConfig
-		json.Unmarshal([]byte(alipay.ConfigJSON), &cfg)
+		json.Unmarshal([]byte(model.DecryptPaymentSecrets(alipay.PaymentType, alipay.ConfigJSON)), &cfg)
 		cfg.Enabled = alipay.Enabled
 		result.AlipayF2F = cfg
 	}
@@ -24,7 +24,7 @@ func (s *ConfigService) GetPaymentConfig() (*config.PaymentConfig, error) {
 	// 获取微信支付配置
 	if wechat, err := s.repo.GetPaymentConfig("wechat_pay"); err == nil {
 		var cfg config.WechatPayConfig
-		json.Unmarshal([]byte(wechat.ConfigJSON), &cfg)
+		json.Unmarshal([]byte(model.DecryptPaymentSecrets(wechat.PaymentType, wechat.ConfigJSON)), &cfg)
 		cfg.Enabled = wechat.Enabled
 		result.WechatPay = cfg
 	}
@@ -32,7 +32,7 @@ func (s *ConfigService) GetPaymentConfig() (*config.PaymentConfig, error) {
 	// 获取易支付配置
 	if yipay, err := s.repo.GetPaymentConfig("yi_pay"); err == nil {
 		var cfg config.YiPayConfig
-		json.Unmarshal([]byte(yipay.ConfigJSON), &cfg)
+		json.Unmarshal([]byte(model.DecryptPaymentSecrets(yipay.PaymentType, yipay.ConfigJSON)), &cfg)
 		cfg.Enabled = yipay.Enabled
 		result.YiPay = cfg
 	}
@@ -40,7 +40,7 @@ func (s *ConfigService) GetPaymentConfig() (*config.PaymentConfig, error) {
 	// 获取PayPal配置
 	if paypal, err := s.repo.GetPaymentConfig("paypal"); err == nil {
 		var cfg config.PayPalConfig
-		json.Unmarshal([]byte(paypal.ConfigJSON), &cfg)
+		json.Unmarshal([]byte(model.DecryptPaymentSecrets(paypal.PaymentType, paypal.ConfigJSON)), &cfg)
 		cfg.Enabled = paypal.Enabled
 		result.PayPal = cfg
 	}
@@ -48,7 +48,7 @@ func (s *ConfigService) GetPaymentConfig() (*config.PaymentConfig, error) {
 	// 获取Stripe配置
 	if stripe, err := s.repo.GetPaymentConfig("stripe"); err == nil {
 		var cfg map[string]interface{}
-		json.Unmarshal([]byte(stripe.ConfigJSON), &cfg)
+		json.Unmarshal([]byte(model.DecryptPaymentSecrets(stripe.PaymentType, stripe.ConfigJSON)), &cfg)
 		result.StripeEnabled = stripe.Enabled
 		if v, ok := cfg["publishable_key"].(string); ok {
 			result.StripePublishableKey = v
@@ -62,12 +62,15 @@ func (s *ConfigService) GetPaymentConfig() (*config.PaymentConfig, error) {
 		if v, ok := cfg["currency"].(string); ok {
 			result.StripeCurrency = v
 		}
+		if v, ok := cfg["api_base_url"].(string); ok {
+			result.StripeAPIBaseURL = v
+		}
 	}
 
 	// 获取USDT配置
 	if usdt, err := s.repo.GetPaymentConfig("usdt"); err == nil {
 		var cfg map[string]interface{}
-		json.Unmarshal([]byte(usdt.ConfigJSON), &cfg)
+		json.Unmarshal([]byte(model.DecryptPaymentSecrets(usdt.PaymentType, usdt.ConfigJSON)), &cfg)
 		result.USDTEnabled = usdt.Enabled
 		if v, ok := cfg["network"].(string); ok {
 			result.USDTNetwork = v
@@ -96,6 +99,21 @@ func (s *ConfigService) GetPaymentConfig() (*config.PaymentConfig, error) {
 		if v, ok := cfg["confirmations"].(float64); ok {
 			result.USDTConfirmations = int(v)
 		}
+		if v, ok := cfg["chain_api_type"].(string); ok {
+			result.USDTChainAPIType = v
+		}
+		if v, ok := cfg["chain_api_url"].(string); ok {
+			result.USDTChainAPIURL = v
+		}
+		if v, ok := cfg["chain_api_key"].(string); ok {
+			result.USDTChainAPIKey = v
+		}
+		if v, ok := cfg["contract_address"].(string); ok {
+			result.USDTContractAddress = v
+		}
+		if v, ok := cfg["api_base_url"].(string); ok {
+			result.USDTAPIBaseURL = v
+		}
 	}
 
 	return result, nil
@@ -109,7 +127,7 @@ func (s *ConfigService) SaveAlipayConfig(cfg *config.AlipayF2FConfig) error {
 		Enabled:     cfg.Enabled,
 		ConfigJSON:  string(jsonData),
 	}
-	return s.repo.SavePaymentConfig(dbConfig)
+	return s.savePaymentConfig(dbConfig)
 }
 
 // SaveWechatPayConfig 保存微信支付配置
@@ -120,7 +138,7 @@ func (s *ConfigService) SaveWechatPayConfig(cfg *config.WechatPayConfig) error {
 		Enabled:     cfg.Enabled,
 		ConfigJSON:  string(jsonData),
 	}
-	return s.repo.SavePaymentConfig(dbConfig)
+	return s.savePaymentConfig(dbConfig)
 }
 
 // SaveYiPayConfig 保存易支付配置
@@ -131,7 +149,7 @@ func (s *ConfigService) SaveYiPayConfig(cfg *config.YiPayConfig) error {
 		Enabled:     cfg.Enabled,
 		ConfigJSON:  string(jsonData),
 	}
-	return s.repo.SavePaymentConfig(dbConfig)
+	return s.savePaymentConfig(dbConfig)
 }
 
 // SavePayPalConfig 保存PayPal配置
@@ -142,7 +160,7 @@ func (s *ConfigService) SavePayPalConfig(cfg *config.PayPalConfig) error {
 		Enabled:     cfg.Enabled,
 		ConfigJSON:  string(jsonData),
 	}
-	return s.repo.SavePaymentConfig(dbConfig)
+	return s.savePaymentConfig(dbConfig)
 }
 
 // SaveStripeConfig 保存Stripe配置
@@ -160,7 +178,7 @@ func (s *ConfigService) SaveStripeConfig(enabled bool, publishableKey, secretKey
 		Enabled:     enabled,
 		ConfigJSON:  string(jsonData),
 	}
-	return s.repo.SavePaymentConfig(dbConfig)
+	return s.savePaymentConfig(dbConfig)
 }
 
 // SaveUSDTConfig 保存USDT配置
@@ -183,5 +201,52 @@ func (s *ConfigService) SaveUSDTConfig(enabled bool, network, walletAddress, api
 		Enabled:     enabled,
 		ConfigJSON:  string(jsonData),
 	}
+	return s.savePaymentConfig(dbConfig)
+}
+
+// SaveUSDTChainConfig 保存USDT自托管链上收款配置
+// 与 SaveUSDTConfig 共用同一条配置记录，仅更新链上接口相关字段
+func (s *ConfigService) SaveUSDTChainConfig(apiType, apiURL, apiKey, contractAddress string) error {
+	dbConfig, err := s.repo.GetPaymentConfig("usdt")
+	if err != nil {
+		return err
+	}
+
+	cfgData := map[string]interface{}{}
+	json.Unmarshal([]byte(dbConfig.ConfigJSON), &cfgData)
+	cfgData["chain_api_type"] = apiType
+	cfgData["chain_api_url"] = apiURL
+	cfgData["chain_api_key"] = apiKey
+	cfgData["contract_address"] = contractAddress
+
+	jsonData, _ := json.Marshal(cfgData)
+	dbConfig.ConfigJSON = string(jsonData)
+	return s.savePaymentConfig(dbConfig)
+}
+
+// SavePaymentAPIBaseURL 保存Stripe/USDT的自定义API地址（合并到已有配置中，用于沙箱或模拟网关）
+func (s *ConfigService) SavePaymentAPIBaseURL(paymentType, baseURL string) error {
+	dbConfig, err := s.repo.GetPaymentConfig(paymentType)
+	if err != nil {
+		return err
+	}
+
+	cfgData := map[string]interface{}{}
+	json.Unmarshal([]byte(dbConfig.ConfigJSON), &cfgData)
+	cfgData["api_base_url"] = baseURL
+
+	jsonData, _ := json.Marshal(cfgData)
+	dbConfig.ConfigJSON = string(jsonData)
+	return s.savePaymentConfig(dbConfig)
+}
+
+// savePaymentConfig 加密密钥字段后保存支付配置
+// 合并更新时 ConfigJSON 中可能已有加密的字段，已加密的值保持不变
+func (s *ConfigService) savePaymentConfig(dbConfig *model.PaymentConfigDB) error {
+	encrypted, err := model.EncryptPaymentSecrets(dbConfig.PaymentType, dbConfig.ConfigJSON)
+	if err != nil {
+		return err
+	}
+	dbConfig.ConfigJSON = encrypted
 	return s.repo.SavePaymentConfig(dbConfig)
 }
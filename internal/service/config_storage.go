@@ -0,0 +1,151 @@
+// Package service 提供业务逻辑服务
+// config_storage.go - 对象存储配置管理
+//
+// 本模块负责对象存储配置的读取、保存和连通性测试。
+// 配置存储在 SQLite 配置数据库中。
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/storage"
+)
+
+// ==================== 对象存储配置管理 ====================
+
+// GetStorageConfig 获取对象存储配置
+//
+// 从 SQLite 配置数据库读取，配置不存在时返回本地存储默认配置。
+func (s *ConfigService) GetStorageConfig() (*config.StorageConfig, error) {
+	if s.configDB == nil {
+		return s.getDefaultStorageConfig(), nil
+	}
+
+	var dbConfig model.StorageConfigDB
+	if err := s.configDB.First(&dbConfig).Error; err != nil {
+		return s.getDefaultStorageConfig(), nil
+	}
+
+	cfg := &config.StorageConfig{
+		Driver:    dbConfig.Driver,
+		LocalRoot: dbConfig.LocalRoot,
+		Endpoint:  dbConfig.Endpoint,
+		Region:    dbConfig.Region,
+		Bucket:    dbConfig.Bucket,
+		AccessKey: dbConfig.AccessKey,
+		PathStyle: dbConfig.PathStyle,
+		Prefix:    dbConfig.Prefix,
+		Timeout:   dbConfig.Timeout,
+	}
+	if dbConfig.SecretKey != "" {
+		if decrypted, err := decryptPassword(dbConfig.SecretKey); err == nil {
+			cfg.SecretKey = decrypted
+		}
+	}
+	return cfg, nil
+}
+
+// SaveStorageConfig 保存对象存储配置
+//
+// 访问密钥加密存储；传入空密钥时保留原有密钥。
+func (s *ConfigService) SaveStorageConfig(cfg *config.StorageConfig) error {
+	if s.configDB == nil {
+		return errors.New("配置数据库未初始化")
+	}
+
+	var dbConfig model.StorageConfigDB
+	s.configDB.First(&dbConfig)
+
+	dbConfig.Driver = cfg.Driver
+	dbConfig.LocalRoot = cfg.LocalRoot
+	dbConfig.Endpoint = cfg.Endpoint
+	dbConfig.Region = cfg.Region
+	dbConfig.Bucket = cfg.Bucket
+	dbConfig.AccessKey = cfg.AccessKey
+	dbConfig.PathStyle = cfg.PathStyle
+	dbConfig.Prefix = cfg.Prefix
+	dbConfig.Timeout = cfg.Timeout
+
+	if cfg.SecretKey != "" {
+		encrypted, err := encryptPassword(cfg.SecretKey)
+		if err != nil {
+			return err
+		}
+		dbConfig.SecretKey = encrypted
+	}
+
+	if dbConfig.ID == 0 {
+		return s.configDB.Create(&dbConfig).Error
+	}
+	return s.configDB.Save(&dbConfig).Error
+}
+
+// getDefaultStorageConfig 获取默认对象存储配置
+func (s *ConfigService) getDefaultStorageConfig() *config.StorageConfig {
+	return &config.StorageConfig{
+		Driver:    storage.DriverLocal,
+		LocalRoot: storage.DefaultLocalRoot,
+		Region:    "us-east-1",
+		Timeout:   30,
+	}
+}
+
+// ToStorageConfig 转换为存储包使用的配置结构
+func ToStorageConfig(cfg *config.StorageConfig) *storage.Config {
+	return &storage.Config{
+		Driver:    cfg.Driver,
+		LocalRoot: cfg.LocalRoot,
+		Endpoint:  cfg.Endpoint,
+		Region:    cfg.Region,
+		Bucket:    cfg.Bucket,
+		AccessKey: cfg.AccessKey,
+		SecretKey: cfg.SecretKey,
+		PathStyle: cfg.PathStyle,
+		Prefix:    cfg.Prefix,
+		Timeout:   cfg.Timeout,
+	}
+}
+
+// TestStorageConnection 测试对象存储连通性
+//
+// 依次执行写入、读取校验、删除一个探测对象，返回是否成功、说明信息和耗时（毫秒）。
+func (s *ConfigService) TestStorageConnection(cfg *config.StorageConfig) (bool, string, int64) {
+	if cfg == nil {
+		return false, "配置为空", 0
+	}
+
+	startTime := time.Now()
+	st, err := storage.New(ToStorageConfig(cfg))
+	if err != nil {
+		return false, err.Error(), 0
+	}
+	if err := st.Ping(); err != nil {
+		return false, "连接失败: " + err.Error(), time.Since(startTime).Milliseconds()
+	}
+
+	key := fmt.Sprintf("healthcheck/%d.txt", time.Now().UnixNano())
+	payload := []byte("storage healthcheck")
+	if err := st.Put(key, bytes.NewReader(payload), int64(len(payload)), "text/plain"); err != nil {
+		return false, "写入失败: " + err.Error(), time.Since(startTime).Milliseconds()
+	}
+	defer st.Delete(key)
+
+	rc, _, err := st.Get(key)
+	if err != nil {
+		return false, "读取失败: " + err.Error(), time.Since(startTime).Milliseconds()
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(data, payload) {
+		return false, "读取内容与写入不一致", time.Since(startTime).Milliseconds()
+	}
+
+	return true, strings.ToUpper(st.Driver()) + " 存储读写正常", time.Since(startTime).Milliseconds()
+}
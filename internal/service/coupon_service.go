@@ -254,14 +254,20 @@ func (s *CouponService) ValidateCoupon(code string, userID uint, productID uint,
 		return nil, 0, fmt.Errorf("订单金额需满%.2f元才能使用此优惠券", coupon.MinAmount)
 	}
 
-	// 检查适用商品
+	// 检查适用商品（适用于多规格父商品的优惠券同样适用于其各规格）
 	if coupon.ProductIDs != "" {
+		matchIDs := []string{strconv.Itoa(int(productID))}
+		if product, err := s.repo.GetProductByID(productID); err == nil && product.IsVariant() {
+			matchIDs = append(matchIDs, strconv.Itoa(int(product.ParentID)))
+		}
 		productIDList := strings.Split(coupon.ProductIDs, ",")
 		found := false
 		for _, pid := range productIDList {
-			if pid == strconv.Itoa(int(productID)) {
-				found = true
-				break
+			for _, id := range matchIDs {
+				if strings.TrimSpace(pid) == id {
+					found = true
+					break
+				}
 			}
 		}
 		if !found {
@@ -0,0 +1,378 @@
+// Package service 提供业务逻辑服务
+// currency_service.go - 多币种定价与汇率服务
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+)
+
+// ExchangeRateFileName 本地汇率文件名（位于配置目录下）
+const ExchangeRateFileName = "exchange_rates.json"
+
+// CurrencyService 多币种服务
+// 负责店铺基础货币、汇率表、商品多币种定价以及订单支付币种换算
+type CurrencyService struct {
+	repo      *repository.Repository
+	configDir string
+
+	mu           sync.RWMutex
+	baseCurrency string // 基础货币缓存
+}
+
+// NewCurrencyService 创建多币种服务
+func NewCurrencyService(repo *repository.Repository, configDir string) *CurrencyService {
+	return &CurrencyService{repo: repo, configDir: configDir}
+}
+
+// ExchangeRateFile 本地汇率文件格式
+// 示例：{"base": "CNY", "rates": {"USD": 0.138, "EUR": 0.127, "USDT": 0.138}}
+type ExchangeRateFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// NormalizeCurrency 规范化币种代码（大写、去空格）
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// roundAmount 金额保留两位小数
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// ==================== 基础货币 ====================
+
+// GetBaseCurrency 获取店铺基础货币
+func (s *CurrencyService) GetBaseCurrency() string {
+	s.mu.RLock()
+	cached := s.baseCurrency
+	s.mu.RUnlock()
+	if cached != "" {
+		return cached
+	}
+
+	base := model.DefaultBaseCurrency
+	if s.repo != nil {
+		if value, err := s.repo.GetSetting("base_currency"); err == nil && value != "" {
+			base = NormalizeCurrency(value)
+		}
+	}
+
+	s.mu.Lock()
+	s.baseCurrency = base
+	s.mu.Unlock()
+	return base
+}
+
+// SetBaseCurrency 设置店铺基础货币
+// 注意：汇率表以基础货币为参照，切换基础货币后需重新维护汇率
+func (s *CurrencyService) SetBaseCurrency(code string) error {
+	code = NormalizeCurrency(code)
+	if len(code) < 3 || len(code) > 10 {
+		return errors.New("无效的币种代码")
+	}
+	if err := s.repo.SetSetting("base_currency", code, "店铺基础货币"); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.baseCurrency = code
+	s.mu.Unlock()
+	return nil
+}
+
+// ==================== 汇率管理 ====================
+
+// GetRates 获取汇率列表
+func (s *CurrencyService) GetRates() ([]model.ExchangeRate, error) {
+	var rates []model.ExchangeRate
+	err := s.repo.GetDB().Order("currency ASC").Find(&rates).Error
+	return rates, err
+}
+
+// SaveRate 新增或更新汇率
+func (s *CurrencyService) SaveRate(currency string, rate float64, source, remark, operator string) (*model.ExchangeRate, error) {
+	currency = NormalizeCurrency(currency)
+	if currency == "" {
+		return nil, errors.New("币种代码不能为空")
+	}
+	if currency == s.GetBaseCurrency() {
+		return nil, errors.New("基础货币无需设置汇率")
+	}
+	if rate <= 0 {
+		return nil, errors.New("汇率必须大于0")
+	}
+	if source == "" {
+		source = model.RateSourceManual
+	}
+
+	var record model.ExchangeRate
+	err := s.repo.GetDB().Where("currency = ?", currency).First(&record).Error
+	if err != nil {
+		record = model.ExchangeRate{Currency: currency}
+	}
+	record.Rate = rate
+	record.Source = source
+	record.Remark = remark
+	record.UpdatedBy = operator
+
+	if err := s.repo.GetDB().Save(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteRate 删除汇率
+func (s *CurrencyService) DeleteRate(currency string) error {
+	return s.repo.GetDB().Where("currency = ?", NormalizeCurrency(currency)).Delete(&model.ExchangeRate{}).Error
+}
+
+// GetRateFilePath 获取本地汇率文件路径
+func (s *CurrencyService) GetRateFilePath() string {
+	return filepath.Join(s.configDir, ExchangeRateFileName)
+}
+
+// LoadRatesFromFile 从本地汇率文件导入汇率
+// 参数：
+//   - path: 文件路径（为空则使用配置目录下的 exchange_rates.json）
+//   - operator: 操作人
+//
+// 返回：
+//   - 导入的汇率数量
+//   - 错误信息
+func (s *CurrencyService) LoadRatesFromFile(path, operator string) (int, error) {
+	if path == "" {
+		path = s.GetRateFilePath()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("读取汇率文件失败: %v", err)
+	}
+
+	var file ExchangeRateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, fmt.Errorf("解析汇率文件失败: %v", err)
+	}
+
+	base := s.GetBaseCurrency()
+	if file.Base != "" && NormalizeCurrency(file.Base) != base {
+		return 0, fmt.Errorf("汇率文件基础货币(%s)与店铺基础货币(%s)不一致", file.Base, base)
+	}
+
+	count := 0
+	for currency, rate := range file.Rates {
+		if NormalizeCurrency(currency) == base || rate <= 0 {
+			continue
+		}
+		if _, err := s.SaveRate(currency, rate, model.RateSourceFile, "从汇率文件导入", operator); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// GetRate 获取基础货币到目标币种的汇率
+// USDT 未在汇率表中配置时，兼容使用支付配置中的 USDTExchangeRate（1 USDT = N 基础货币）
+func (s *CurrencyService) GetRate(currency string) (float64, error) {
+	currency = NormalizeCurrency(currency)
+	if currency == "" || currency == s.GetBaseCurrency() {
+		return 1, nil
+	}
+
+	var record model.ExchangeRate
+	if err := s.repo.GetDB().Where("currency = ?", currency).First(&record).Error; err == nil && record.Rate > 0 {
+		return record.Rate, nil
+	}
+
+	if currency == model.CurrencyUSDT && config.GlobalConfig != nil {
+		if legacy := config.GlobalConfig.PaymentConfig.USDTExchangeRate; legacy > 0 {
+			return 1 / legacy, nil
+		}
+	}
+
+	return 0, fmt.Errorf("未配置 %s 汇率", currency)
+}
+
+// Convert 币种换算
+func (s *CurrencyService) Convert(amount float64, from, to string) (float64, error) {
+	from = NormalizeCurrency(from)
+	to = NormalizeCurrency(to)
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, err := s.GetRate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := s.GetRate(to)
+	if err != nil {
+		return 0, err
+	}
+	return roundAmount(amount / fromRate * toRate), nil
+}
+
+// GetSupportedCurrencies 获取可用币种列表（基础货币 + 已配置汇率的币种）
+func (s *CurrencyService) GetSupportedCurrencies() []string {
+	currencies := []string{s.GetBaseCurrency()}
+	rates, _ := s.GetRates()
+	for _, r := range rates {
+		currencies = append(currencies, r.Currency)
+	}
+	return currencies
+}
+
+// ==================== 商品多币种价格 ====================
+
+// GetProductPrices 获取商品的多币种固定价格
+func (s *CurrencyService) GetProductPrices(productID uint) ([]model.ProductPrice, error) {
+	var prices []model.ProductPrice
+	err := s.repo.GetDB().Where("product_id = ?", productID).Order("currency ASC").Find(&prices).Error
+	return prices, err
+}
+
+// SaveProductPrice 设置商品在某币种下的固定价格
+func (s *CurrencyService) SaveProductPrice(productID uint, currency string, price float64) (*model.ProductPrice, error) {
+	currency = NormalizeCurrency(currency)
+	if currency == "" {
+		return nil, errors.New("币种代码不能为空")
+	}
+	if currency == s.GetBaseCurrency() {
+		return nil, errors.New("基础货币价格请直接修改商品价格")
+	}
+	if price <= 0 {
+		return nil, errors.New("价格必须大于0")
+	}
+	if _, err := s.repo.GetProductByID(productID); err != nil {
+		return nil, errors.New("商品不存在")
+	}
+
+	var record model.ProductPrice
+	err := s.repo.GetDB().Where("product_id = ? AND currency = ?", productID, currency).First(&record).Error
+	if err != nil {
+		record = model.ProductPrice{ProductID: productID, Currency: currency}
+	}
+	record.Price = price
+
+	if err := s.repo.GetDB().Save(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteProductPrice 删除商品的某币种固定价格
+func (s *CurrencyService) DeleteProductPrice(productID uint, currency string) error {
+	return s.repo.GetDB().
+		Where("product_id = ? AND currency = ?", productID, NormalizeCurrency(currency)).
+		Delete(&model.ProductPrice{}).Error
+}
+
+// GetProductPriceIn 获取商品在指定币种下的单价
+// 优先使用商品设置的固定价格，否则按汇率换算
+func (s *CurrencyService) GetProductPriceIn(product *model.Product, currency string) (float64, error) {
+	currency = NormalizeCurrency(currency)
+	if currency == "" || currency == s.GetBaseCurrency() {
+		return product.Price, nil
+	}
+
+	var fixed model.ProductPrice
+	if err := s.repo.GetDB().Where("product_id = ? AND currency = ?", product.ID, currency).First(&fixed).Error; err == nil && fixed.Price > 0 {
+		return fixed.Price, nil
+	}
+
+	return s.Convert(product.Price, s.GetBaseCurrency(), currency)
+}
+
+// ==================== 订单币种锁定 ====================
+
+// PaymentMethodCurrency 获取支付方式实际扣款的币种
+// PayPal/Stripe 按各自配置的币种扣款，支付宝/微信/易支付固定人民币，
+// USDT 第三方网关自行换算（按基础货币报价），自托管/手动模式直接以 USDT 计价
+func (s *CurrencyService) PaymentMethodCurrency(method string) string {
+	base := s.GetBaseCurrency()
+	if config.GlobalConfig == nil {
+		return base
+	}
+	paymentCfg := &config.GlobalConfig.PaymentConfig
+
+	switch strings.ToLower(method) {
+	case "paypal":
+		return NormalizeCurrency(getStringOrDefault(paymentCfg.PayPal.Currency, model.CurrencyUSD))
+	case "stripe":
+		return NormalizeCurrency(getStringOrDefault(paymentCfg.StripeCurrency, model.CurrencyUSD))
+	case "alipay", "alipay_f2f", "wechat", "wechat_pay", "yipay", "yi_pay":
+		return model.CurrencyCNY
+	case "usdt":
+		switch paymentCfg.USDTAPIProvider {
+		case "nowpayments", "coingate":
+			return base
+		default:
+			return model.CurrencyUSDT
+		}
+	default:
+		return base
+	}
+}
+
+// QuoteOrder 计算订单在指定币种下的应付金额
+// 返回：
+//   - 支付币种应付金额
+//   - 实际使用的汇率（1基础货币=rate支付币种）
+//   - 错误信息
+func (s *CurrencyService) QuoteOrder(order *model.Order, currency string) (float64, float64, error) {
+	currency = NormalizeCurrency(currency)
+	orderCurrency := order.Currency
+	if orderCurrency == "" {
+		orderCurrency = s.GetBaseCurrency()
+	}
+	if currency == "" || currency == orderCurrency {
+		return order.Price, 1, nil
+	}
+
+	// 商品设置了该币种固定价格时，按固定价格计价，并按相同比例扣减优惠
+	var fixed model.ProductPrice
+	if err := s.repo.GetDB().Where("product_id = ? AND currency = ?", order.ProductID, currency).First(&fixed).Error; err == nil && fixed.Price > 0 && order.OriginalPrice > 0 {
+		quantity := order.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+		fixedUnit := fixed.Price
+		if order.ListPrice > 0 && order.UnitPrice > 0 && order.UnitPrice < order.ListPrice {
+			// 阶梯价/等级价按成交单价相对标价的比例折算固定价格
+			fixedUnit = fixed.Price * order.UnitPrice / order.ListPrice
+		}
+		payAmount := roundAmount(fixedUnit * float64(quantity) * order.Price / order.OriginalPrice)
+		rate := 0.0
+		if order.Price > 0 {
+			rate = payAmount / order.Price
+		}
+		return payAmount, rate, nil
+	}
+
+	rate, err := s.GetRate(currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	if orderCurrency != s.GetBaseCurrency() {
+		// 订单基础货币与当前店铺基础货币不一致（切换过基础货币），先换算到当前基础货币
+		orderRate, err := s.GetRate(orderCurrency)
+		if err != nil {
+			return 0, 0, err
+		}
+		rate = rate / orderRate
+	}
+	return roundAmount(order.Price * rate), rate, nil
+}
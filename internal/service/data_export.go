@@ -0,0 +1,557 @@
+// Package service 提供业务逻辑服务
+// data_export.go - 可移植的业务数据导出与导入
+//
+// 导出 model.AllModels() 中登记的全部表（备份索引、管理员会话与审计日志除外），格式为逐行 JSON（JSON Lines），
+// 字段按数据库列名记录、值按 Go 类型的 JSON 编码保存，与数据库类型无关，可将 SQLite 的数据
+// 导入到 MySQL/PostgreSQL，并保留原有 ID 与关联关系。文件按备份配置压缩、加密。
+//
+//	{"type":"header","format":"user-frontend-export","version":1,"schema_version":1,"source_db":"sqlite",...}
+//	{"type":"table","table":"users","columns":["id","email",...]}
+//	{"type":"row","data":{"id":1,"email":"a@example.com",...}}
+//	{"type":"end","table":"users","rows":1}
+//	{"type":"footer","tables":70,"rows":12345}
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"user-frontend/internal/cache"
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	exportFormat    = "user-frontend-export"
+	exportVersion   = 1
+	exportExt       = ".jsonl"
+	exportBatchSize = 500
+	importBatchSize = 200
+)
+
+// exportLine 导出文件中的一行
+type exportLine struct {
+	Type string `json:"type"` // header/table/row/end/footer
+
+	// header
+	Format        string     `json:"format,omitempty"`
+	Version       int        `json:"version,omitempty"`
+	SchemaVersion int        `json:"schema_version,omitempty"`
+	SourceDB      string     `json:"source_db,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	CreatedBy     string     `json:"created_by,omitempty"`
+
+	// table/end
+	Table   string   `json:"table,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+
+	// row
+	Data map[string]json.RawMessage `json:"data,omitempty"`
+
+	// end/footer
+	Rows   int64 `json:"rows,omitempty"`
+	Tables int   `json:"tables,omitempty"`
+}
+
+// exportTable 可导出的表
+type exportTable struct {
+	model  interface{}
+	schema *schema.Schema
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Force bool // 导出文件的结构版本低于当前版本时仍然导入
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	BackupID     uint     `json:"backup_id"`
+	SnapshotID   uint     `json:"snapshot_id"`   // 导入前自动快照的备份ID
+	SnapshotFile string   `json:"snapshot_file"` // 导入前自动快照的文件名
+	SourceDB     string   `json:"source_db"`     // 导出时的数据库类型
+	Tables       int      `json:"tables"`        // 导入的表数量
+	Rows         int64    `json:"rows"`          // 导入的记录数
+	Duration     string   `json:"duration"`
+	Warnings     []string `json:"warnings,omitempty"`
+}
+
+// exportTables 解析全部可导出的表（按迁移顺序）
+func exportTables(db *gorm.DB) ([]exportTable, error) {
+	var tables []exportTable
+	for _, m := range model.AllModels() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		if restoreSkipTables[stmt.Schema.Table] {
+			continue
+		}
+		tables = append(tables, exportTable{model: m, schema: stmt.Schema})
+	}
+	return tables, nil
+}
+
+// ExportDataAsJSON 导出全部业务数据为可移植的 JSON Lines 文件
+// 可通过 ImportData 导入到任意类型的数据库，用于更换数据库类型或迁移服务器
+func (s *BackupService) ExportDataAsJSON(createdBy string) (*model.DatabaseBackup, error) {
+	db := s.repo.GetDB()
+	tables, err := exportTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("backup_json_%s%s", s.uniqueTimestamp(), exportExt)
+	filePath := filepath.Join(s.backupDir, filename)
+	if err := writeExport(db, tables, filePath, createdBy); err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("导出数据失败: %v", err)
+	}
+
+	encodedPath, compressed, encrypted, err := encodeBackupFile(filePath, s.backupConfig())
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+	checksum, err := fileSHA256(encodedPath)
+	if err != nil {
+		os.Remove(encodedPath)
+		return nil, fmt.Errorf("计算备份校验和失败: %v", err)
+	}
+	info, err := os.Stat(encodedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &model.DatabaseBackup{
+		Filename:      filepath.Base(encodedPath),
+		FilePath:      encodedPath,
+		FileSize:      info.Size(),
+		DBType:        "json",
+		Kind:          model.BackupKindManual,
+		Compressed:    compressed,
+		Encrypted:     encrypted,
+		Checksum:      checksum,
+		SchemaVersion: model.SchemaVersion,
+		Remark:        "数据导出（可导入任意类型数据库）",
+		CreatedBy:     createdBy,
+	}
+	if err := s.repo.CreateBackupRecord(backup); err != nil {
+		os.Remove(encodedPath)
+		return nil, err
+	}
+	return backup, nil
+}
+
+// writeExport 逐表分批读取数据并写入导出文件
+func writeExport(db *gorm.DB, tables []exportTable, path, createdBy string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	now := time.Now()
+	if err := enc.Encode(exportLine{
+		Type:          "header",
+		Format:        exportFormat,
+		Version:       exportVersion,
+		SchemaVersion: model.SchemaVersion,
+		SourceDB:      db.Dialector.Name(),
+		CreatedAt:     &now,
+		CreatedBy:     createdBy,
+	}); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var total int64
+	for _, t := range tables {
+		if err := enc.Encode(exportLine{Type: "table", Table: t.schema.Table, Columns: t.schema.DBNames}); err != nil {
+			return err
+		}
+
+		var rows int64
+		batch := reflect.New(reflect.SliceOf(t.schema.ModelType))
+		query := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(t.model)
+		writeBatch := func() error {
+			slice := batch.Elem()
+			for i := 0; i < slice.Len(); i++ {
+				data, err := encodeExportRow(ctx, t.schema, slice.Index(i))
+				if err != nil {
+					return err
+				}
+				if err := enc.Encode(exportLine{Type: "row", Data: data}); err != nil {
+					return err
+				}
+				rows++
+			}
+			return nil
+		}
+		if t.schema.PrioritizedPrimaryField != nil {
+			err = query.FindInBatches(batch.Interface(), exportBatchSize, func(*gorm.DB, int) error {
+				return writeBatch()
+			}).Error
+		} else if err = query.Find(batch.Interface()).Error; err == nil {
+			err = writeBatch()
+		}
+		if err != nil {
+			return fmt.Errorf("导出表 %s 失败: %v", t.schema.Table, err)
+		}
+
+		if err := enc.Encode(exportLine{Type: "end", Table: t.schema.Table, Rows: rows}); err != nil {
+			return err
+		}
+		total += rows
+	}
+
+	if err := enc.Encode(exportLine{Type: "footer", Tables: len(tables), Rows: total}); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// encodeExportRow 按数据库列名编码一行数据
+func encodeExportRow(ctx context.Context, sch *schema.Schema, rv reflect.Value) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage, len(sch.DBNames))
+	for _, name := range sch.DBNames {
+		field := sch.FieldsByDBName[name]
+		raw, err := json.Marshal(field.ReflectValueOf(ctx, rv).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("字段 %s 编码失败: %v", name, err)
+		}
+		data[name] = raw
+	}
+	return data, nil
+}
+
+// ImportData 将导出文件导入当前数据库
+//
+// 导入前校验文件并自动创建当前数据库的快照，导入期间进入维护模式；导出文件中包含的表
+// 先清空再写入（保留原 ID），全部在一个事务内完成，失败时整体回滚。
+// 与恢复相同，restoreSkipTables 中的表（含审计日志）保留当前数据，旧版本导出文件中的审计日志会被忽略。
+func (s *BackupService) ImportData(dbConfig *config.DBConfig, id uint, operator string, opts ImportOptions) (*ImportResult, error) {
+	backup, err := s.repo.GetBackupByID(id)
+	if err != nil {
+		return nil, errors.New("备份不存在")
+	}
+	if backup.DBType != "json" {
+		return nil, errors.New("只能导入数据导出文件，数据库备份请使用恢复")
+	}
+	if backup.Checksum != "" {
+		sum, err := fileSHA256(backup.FilePath)
+		if err != nil {
+			return nil, errors.New("备份文件不存在")
+		}
+		if sum != backup.Checksum {
+			return nil, errors.New("校验和不匹配，导出文件已损坏或被修改")
+		}
+	}
+
+	plainPath, cleanup, err := decodeBackupFile(backup.FilePath, s.backupDir, s.backupConfig())
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	header, err := readExportHeader(plainPath)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case header.SchemaVersion > model.SchemaVersion:
+		return nil, fmt.Errorf("导出文件的数据库结构版本(%d)高于当前版本(%d)，请先升级程序", header.SchemaVersion, model.SchemaVersion)
+	case header.SchemaVersion < model.SchemaVersion && !opts.Force:
+		return nil, fmt.Errorf("导出文件的数据库结构版本(%d)低于当前版本(%d)，确认无误后请使用强制导入", header.SchemaVersion, model.SchemaVersion)
+	}
+
+	if err := EnterMaintenance("正在导入数据"); err != nil {
+		return nil, err
+	}
+	defer LeaveMaintenance()
+
+	start := time.Now()
+	snapshot, err := s.createBackup(dbConfig, model.BackupKindSnapshot, operator, fmt.Sprintf("导入前自动快照（导入 %s）", backup.Filename), false)
+	if err != nil {
+		return nil, fmt.Errorf("创建导入前快照失败，已取消导入: %v", err)
+	}
+
+	result := &ImportResult{
+		BackupID:     backup.ID,
+		SnapshotID:   snapshot.ID,
+		SnapshotFile: snapshot.Filename,
+		SourceDB:     header.SourceDB,
+	}
+	if err := s.importExport(plainPath, dbConfig.Type, result); err != nil {
+		return nil, fmt.Errorf("导入失败，数据库未变更（导入前快照: %s）: %v", snapshot.Filename, err)
+	}
+
+	if cm := cache.GetManager(); cm != nil {
+		cm.FlushAll()
+	}
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	return result, nil
+}
+
+// readExportHeader 读取并校验导出文件头
+func readExportHeader(path string) (*exportLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var header exportLine
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&header); err != nil || header.Type != "header" || header.Format != exportFormat {
+		return nil, errors.New("不是有效的数据导出文件")
+	}
+	if header.Version > exportVersion {
+		return nil, fmt.Errorf("导出文件格式版本(%d)高于当前支持的版本(%d)", header.Version, exportVersion)
+	}
+	return &header, nil
+}
+
+// importExport 在单个事务内导入导出文件的全部数据
+func (s *BackupService) importExport(path, dbType string, result *ImportResult) error {
+	db := s.repo.GetDB()
+	tables, err := exportTables(db)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]exportTable, len(tables))
+	for _, t := range tables {
+		byName[t.schema.Table] = t
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReaderSize(f, 1<<20))
+
+	return db.Connection(func(conn *gorm.DB) error {
+		// 数据按表顺序写入，写入期间关闭外键检查
+		switch dbType {
+		case "sqlite":
+			var foreignKeys int
+			conn.Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+			if foreignKeys == 1 {
+				conn.Exec("PRAGMA foreign_keys = OFF")
+				defer conn.Exec("PRAGMA foreign_keys = ON")
+			}
+		case "mysql":
+			if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+		}
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if dbType == "postgres" {
+				tx.Exec("SAVEPOINT import_fk")
+				if err := tx.Exec("SET LOCAL session_replication_role = replica").Error; err != nil {
+					tx.Exec("ROLLBACK TO SAVEPOINT import_fk")
+					result.Warnings = append(result.Warnings, "当前数据库账号无法停用外键检查，按导出文件中的表顺序导入")
+				}
+			}
+			return importStream(tx, dec, dbType, byName, result)
+		})
+	})
+}
+
+// importStream 逐行读取导出文件并写入数据库
+func importStream(tx *gorm.DB, dec *json.Decoder, dbType string, tables map[string]exportTable, result *ImportResult) error {
+	ctx := context.Background()
+
+	var (
+		current  *exportTable
+		batch    []map[string]interface{}
+		rows     int64
+		imported []string
+		footer   bool
+		unknown  map[string]bool
+	)
+	flush := func() error {
+		if current == nil || len(batch) == 0 {
+			return nil
+		}
+		// 按列写入：结构体写入时带默认值的字段零值会被默认值替换（如 false 变为 true），且会触发钩子
+		if err := tx.Session(&gorm.Session{SkipHooks: true, NewDB: true}).Table(current.schema.Table).Create(&batch).Error; err != nil {
+			return fmt.Errorf("写入表 %s 失败: %v", current.schema.Table, err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for !footer {
+		var line exportLine
+		if err := dec.Decode(&line); err != nil {
+			if err == io.EOF {
+				return errors.New("导出文件不完整（缺少结束标记）")
+			}
+			return fmt.Errorf("导出文件格式错误: %v", err)
+		}
+
+		switch line.Type {
+		case "header":
+		case "table":
+			t, ok := tables[line.Table]
+			if !ok {
+				current = nil
+				if !restoreSkipTables[line.Table] {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("表 %s 在当前版本中不存在，已跳过", line.Table))
+				}
+				continue
+			}
+			if err := tx.Session(&gorm.Session{NewDB: true}).Exec("DELETE FROM " + quoteIdent(dbType, t.schema.Table)).Error; err != nil {
+				return fmt.Errorf("清空表 %s 失败: %v", t.schema.Table, err)
+			}
+			current = &t
+			batch = make([]map[string]interface{}, 0, importBatchSize)
+			rows = 0
+			unknown = make(map[string]bool)
+		case "row":
+			if current == nil {
+				continue
+			}
+			rv := reflect.New(current.schema.ModelType).Elem()
+			for name, raw := range line.Data {
+				field := current.schema.LookUpField(name)
+				if field == nil || field.DBName == "" {
+					if !unknown[name] {
+						unknown[name] = true
+						result.Warnings = append(result.Warnings, fmt.Sprintf("表 %s 的字段 %s 在当前版本中不存在，已忽略", current.schema.Table, name))
+					}
+					continue
+				}
+				if err := json.Unmarshal(raw, field.ReflectValueOf(ctx, rv).Addr().Interface()); err != nil {
+					return fmt.Errorf("表 %s 字段 %s 的数据无效: %v", current.schema.Table, name, err)
+				}
+			}
+			values := make(map[string]interface{}, len(current.schema.DBNames))
+			for _, name := range current.schema.DBNames {
+				values[name], _ = current.schema.FieldsByDBName[name].ValueOf(ctx, rv)
+			}
+			batch = append(batch, values)
+			rows++
+			if len(batch) >= importBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case "end":
+			if current == nil {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			if line.Rows != rows {
+				return fmt.Errorf("表 %s 记录数不一致（导出 %d，读取 %d），导出文件可能已损坏", current.schema.Table, line.Rows, rows)
+			}
+			if dbType == "postgres" {
+				if err := resetPostgresSequence(ctx, tx.Statement.ConnPool, current.schema.Table); err != nil {
+					return fmt.Errorf("重置表 %s 的自增序列失败: %v", current.schema.Table, err)
+				}
+			}
+			imported = append(imported, current.schema.Table)
+			result.Rows += rows
+			current = nil
+		case "footer":
+			if current != nil {
+				return errors.New("导出文件不完整（表数据未结束）")
+			}
+			footer = true
+		default:
+			return fmt.Errorf("导出文件中包含未知的记录类型: %s", line.Type)
+		}
+	}
+
+	result.Tables = len(imported)
+	if missing := missingTables(tables, imported); len(missing) > 0 {
+		result.Warnings = append(result.Warnings, "以下表不在导出文件中，保留当前数据: "+strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// missingTables 返回当前版本存在但未导入的表
+func missingTables(tables map[string]exportTable, imported []string) []string {
+	done := make(map[string]bool, len(imported))
+	for _, t := range imported {
+		done[t] = true
+	}
+	var missing []string
+	for name := range tables {
+		if !done[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// SaveUploadedExport 保存上传的数据导出文件并登记为备份记录，用于在新服务器上导入
+// 文件需为本系统生成的导出文件（.jsonl，可带 .gz/.enc 后缀）
+func (s *BackupService) SaveUploadedExport(name string, r io.Reader, createdBy string) (*model.DatabaseBackup, error) {
+	base := filepath.Base(name)
+	trimmed := strings.TrimSuffix(strings.TrimSuffix(base, backupEncExt), backupGzipExt)
+	if !strings.HasSuffix(trimmed, exportExt) {
+		return nil, errors.New("仅支持上传数据导出文件（.jsonl / .jsonl.gz / .jsonl.gz.enc）")
+	}
+	if !restoreIdentPattern.MatchString(strings.NewReplacer(".", "_", "-", "_").Replace(base)) {
+		return nil, errors.New("文件名包含无效字符")
+	}
+
+	filename := fmt.Sprintf("upload_%s_%s", s.uniqueTimestamp(), base)
+	filePath := filepath.Join(s.backupDir, filename)
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(f, r)
+	f.Close()
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	checksum, err := fileSHA256(filePath)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+	backup := &model.DatabaseBackup{
+		Filename:   filename,
+		FilePath:   filePath,
+		FileSize:   size,
+		DBType:     "json",
+		Kind:       model.BackupKindUploaded,
+		Compressed: strings.Contains(base, exportExt+backupGzipExt),
+		Encrypted:  strings.HasSuffix(base, backupEncExt),
+		Checksum:   checksum,
+		Remark:     "上传的数据导出文件: " + base,
+		CreatedBy:  createdBy,
+	}
+	if err := s.repo.CreateBackupRecord(backup); err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+	return backup, nil
+}
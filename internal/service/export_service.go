@@ -170,20 +170,16 @@ func (s *ExportService) ExportUsers(startDate, endDate time.Time) ([]byte, error
 // 返回：
 //   - Excel文件字节数据
 //   - 错误信息
-// 注意：日志已改为文件存储，此函数从文件读取日志数据
+// 注意：从数据库审计日志表读取，升级前写入CSV文件的日志不包含在内
 func (s *ExportService) ExportOperationLogs(startDate, endDate time.Time, userType string) ([]byte, error) {
-	// 创建日志服务读取文件日志
-	logSvc := NewLogService()
-	
-	// 获取日期范围内的所有日志
-	var allLogs []LogEntry
-	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
-		dateStr := d.Format("2006-01-02")
-		logs, _, err := logSvc.GetOperationLogs(dateStr, 1, 10000, userType, "", "")
-		if err != nil {
-			continue
-		}
-		allLogs = append(allLogs, logs...)
+	// 从审计日志表读取日期范围内的日志
+	allLogs, _, err := NewLogService(s.repo).SearchOperationLogs(LogQuery{
+		UserType:  userType,
+		StartTime: startDate,
+		EndTime:   endDate,
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	f := excelize.NewFile()
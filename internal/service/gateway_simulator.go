@@ -0,0 +1,637 @@
+// Package service 提供业务逻辑服务
+// gateway_simulator.go - 支付网关沙箱模拟器（本地/CI 离线测试用）
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"user-frontend/internal/config"
+)
+
+// 模拟网关支持的提供商
+const (
+	SimProviderStripe      = "stripe"
+	SimProviderPayPal      = "paypal"
+	SimProviderAlipay      = "alipay"
+	SimProviderWechat      = "wechat"
+	SimProviderYiPay       = "yipay"
+	SimProviderNowPayments = "nowpayments"
+	SimProviderCoinGate    = "coingate"
+)
+
+// 模拟交易状态
+const (
+	SimStatusPending  = "pending"  // 待支付
+	SimStatusApproved = "approved" // 买家已授权，待商户捕获（PayPal）
+	SimStatusPaid     = "paid"     // 已支付
+	SimStatusRefunded = "refunded" // 已全额退款
+	SimStatusCanceled = "canceled" // 已取消
+)
+
+// SimPayment 模拟网关中的一笔交易
+type SimPayment struct {
+	ID         string            `json:"id"`                 // 网关交易号
+	Provider   string            `json:"provider"`           // 提供商
+	OutTradeNo string            `json:"out_trade_no"`       // 商户订单号
+	Amount     float64           `json:"amount"`             // 金额（主币种单位）
+	Currency   string            `json:"currency"`           // 币种
+	Subject    string            `json:"subject"`            // 商品描述
+	Status     string            `json:"status"`             // 交易状态
+	Refunded   float64           `json:"refunded"`           // 已退款金额
+	NotifyURL  string            `json:"notify_url"`         // 异步通知地址（为空时使用本站默认回调路径）
+	ReturnURL  string            `json:"return_url"`         // 支付完成跳转地址
+	CancelURL  string            `json:"cancel_url"`         // 取消支付跳转地址
+	Metadata   map[string]string `json:"metadata,omitempty"` // 商户透传数据（原样回传）
+	Extra      map[string]string `json:"extra,omitempty"`    // 提供商特有字段
+	Notifies   []SimNotifyRecord `json:"notifies,omitempty"` // 通知记录
+	CreatedAt  time.Time         `json:"created_at"`
+	PaidAt     *time.Time        `json:"paid_at,omitempty"`
+}
+
+// SimNotifyRecord 模拟网关回调通知记录
+type SimNotifyRecord struct {
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code"`
+	Response   string    `json:"response"`
+	Error      string    `json:"error,omitempty"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+// GatewaySimulator 支付网关模拟器
+// 在内存中模拟各支付网关的下单、查询、退款接口，并按真实协议签名后回调本站通知接口，
+// 用于在无外网的环境中跑通 下单 → 通知 → 发货 的完整流程
+type GatewaySimulator struct {
+	cfg       *config.Config
+	mu        sync.Mutex
+	seq       int64
+	payments  map[string]*SimPayment
+	alipayKey *rsa.PrivateKey // 模拟支付宝平台密钥（商户需将其公钥配置为支付宝公钥）
+	client    *http.Client
+}
+
+// NewGatewaySimulator 创建支付网关模拟器
+func NewGatewaySimulator(cfg *config.Config) (*GatewaySimulator, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("生成模拟支付宝密钥失败: %v", err)
+	}
+
+	return &GatewaySimulator{
+		cfg:       cfg,
+		payments:  make(map[string]*SimPayment),
+		alipayKey: key,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Config 获取当前支付配置（模拟器按商户配置的密钥校验请求并签名通知）
+func (s *GatewaySimulator) Config() *config.PaymentConfig {
+	return &s.cfg.PaymentConfig
+}
+
+// ==================== 交易存储 ====================
+
+// NextID 生成交易号（前缀 + 递增序号）
+func (s *GatewaySimulator) NextID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return fmt.Sprintf("%s%08d", prefix, s.seq)
+}
+
+// CreatePayment 创建模拟交易
+// 同一提供商下相同商户订单号的待支付交易会被复用并更新金额（与真实网关的幂等行为一致）
+func (s *GatewaySimulator) CreatePayment(p *SimPayment, idPrefix string) SimPayment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.OutTradeNo != "" {
+		if existing := s.findLocked(p.Provider, p.OutTradeNo); existing != nil && existing.Status == SimStatusPending &&
+			existing.Extra["object"] == p.Extra["object"] {
+			existing.Amount = p.Amount
+			existing.Currency = p.Currency
+			existing.Subject = p.Subject
+			existing.NotifyURL = p.NotifyURL
+			existing.ReturnURL = p.ReturnURL
+			existing.CancelURL = p.CancelURL
+			existing.Metadata = p.Metadata
+			return existing.snapshot()
+		}
+	}
+
+	s.seq++
+	p.ID = fmt.Sprintf("%s%08d", idPrefix, s.seq)
+	p.Status = SimStatusPending
+	p.CreatedAt = time.Now()
+	if p.Extra == nil {
+		p.Extra = map[string]string{}
+	}
+	s.payments[p.ID] = p
+	return p.snapshot()
+}
+
+// Find 按网关交易号或商户订单号查找交易
+func (s *GatewaySimulator) Find(provider, ref string) (SimPayment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findLocked(provider, ref)
+	if p == nil {
+		return SimPayment{}, false
+	}
+	return p.snapshot(), true
+}
+
+// List 列出提供商的全部交易（按创建时间倒序）
+func (s *GatewaySimulator) List(provider string) []SimPayment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]SimPayment, 0)
+	for _, p := range s.payments {
+		if provider == "" || p.Provider == provider {
+			list = append(list, p.snapshot())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return list
+}
+
+// findLocked 查找交易（调用方需持有锁）
+// 优先匹配网关交易号，其次匹配最近创建的同商户订单号交易
+func (s *GatewaySimulator) findLocked(provider, ref string) *SimPayment {
+	if p, ok := s.payments[ref]; ok && p.Provider == provider {
+		return p
+	}
+	var found *SimPayment
+	for _, p := range s.payments {
+		if p.Provider == provider && p.OutTradeNo == ref && (found == nil || p.ID > found.ID) {
+			found = p
+		}
+	}
+	return found
+}
+
+// snapshot 复制交易数据（避免调用方在锁外修改）
+func (p *SimPayment) snapshot() SimPayment {
+	cp := *p
+	cp.Notifies = append([]SimNotifyRecord(nil), p.Notifies...)
+	return cp
+}
+
+// ==================== 交易状态流转 ====================
+
+// Complete 模拟买家完成支付并向商户发送异步通知
+// PayPal 无异步通知，买家授权后由商户调用捕获接口完成支付
+// 参数：
+//   - provider: 提供商
+//   - ref: 网关交易号或商户订单号
+//   - siteURL: 本站地址，用于通知地址为空时拼接默认回调路径
+//
+// 返回：
+//   - 更新后的交易
+//   - 通知记录（无通知时为nil）
+//   - 错误信息
+func (s *GatewaySimulator) Complete(provider, ref, siteURL string) (SimPayment, *SimNotifyRecord, error) {
+	s.mu.Lock()
+	p := s.findLocked(provider, ref)
+	if p == nil {
+		s.mu.Unlock()
+		return SimPayment{}, nil, errors.New("交易不存在")
+	}
+	if p.Status != SimStatusPending {
+		s.mu.Unlock()
+		return SimPayment{}, nil, fmt.Errorf("交易状态为 %s，无法支付", p.Status)
+	}
+
+	if provider == SimProviderPayPal {
+		p.Status = SimStatusApproved
+		snap := p.snapshot()
+		s.mu.Unlock()
+		return snap, nil, nil
+	}
+
+	now := time.Now()
+	p.Status = SimStatusPaid
+	p.PaidAt = &now
+	snap := p.snapshot()
+	s.mu.Unlock()
+
+	record := s.sendNotify(&snap, siteURL)
+
+	s.mu.Lock()
+	if current := s.payments[snap.ID]; current != nil {
+		current.Notifies = append(current.Notifies, *record)
+		snap = current.snapshot()
+	}
+	s.mu.Unlock()
+
+	return snap, record, nil
+}
+
+// Capture 捕获已授权的交易（PayPal）
+func (s *GatewaySimulator) Capture(provider, ref string) (SimPayment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findLocked(provider, ref)
+	if p == nil {
+		return SimPayment{}, errors.New("交易不存在")
+	}
+	if p.Status != SimStatusApproved {
+		return p.snapshot(), fmt.Errorf("交易状态为 %s，无法捕获", p.Status)
+	}
+
+	now := time.Now()
+	p.Status = SimStatusPaid
+	p.PaidAt = &now
+	return p.snapshot(), nil
+}
+
+// Cancel 模拟买家取消支付
+func (s *GatewaySimulator) Cancel(provider, ref string) (SimPayment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findLocked(provider, ref)
+	if p == nil {
+		return SimPayment{}, errors.New("交易不存在")
+	}
+	if p.Status != SimStatusPending && p.Status != SimStatusApproved {
+		return p.snapshot(), fmt.Errorf("交易状态为 %s，无法取消", p.Status)
+	}
+	p.Status = SimStatusCanceled
+	return p.snapshot(), nil
+}
+
+// Refund 退款
+// 参数：
+//   - amount: 退款金额（<=0 表示退还剩余全部金额）
+func (s *GatewaySimulator) Refund(provider, ref string, amount float64) (SimPayment, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.findLocked(provider, ref)
+	if p == nil {
+		return SimPayment{}, 0, errors.New("交易不存在")
+	}
+	if p.Status != SimStatusPaid {
+		return p.snapshot(), 0, fmt.Errorf("交易状态为 %s，无法退款", p.Status)
+	}
+
+	remaining := roundAmount(p.Amount - p.Refunded)
+	if amount <= 0 {
+		amount = remaining
+	}
+	amount = roundAmount(amount)
+	if amount > remaining {
+		return p.snapshot(), 0, fmt.Errorf("退款金额超过可退金额 %.2f", remaining)
+	}
+
+	p.Refunded = roundAmount(p.Refunded + amount)
+	if p.Refunded >= p.Amount {
+		p.Status = SimStatusRefunded
+	}
+	return p.snapshot(), amount, nil
+}
+
+// ==================== 签名 ====================
+
+// AlipayPublicKeyPEM 获取模拟支付宝平台公钥（商户需配置为"支付宝公钥"）
+func (s *GatewaySimulator) AlipayPublicKeyPEM() string {
+	der, _ := x509.MarshalPKIXPublicKey(&s.alipayKey.PublicKey)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// AlipaySign 使用模拟平台私钥签名
+func (s *GatewaySimulator) AlipaySign(content string) string {
+	sign, _ := rsa2Sign(s.alipayKey, content)
+	return sign
+}
+
+// AlipayVerifyRequest 验证商户请求签名（使用商户配置的应用私钥推导公钥）
+func (s *GatewaySimulator) AlipayVerifyRequest(params url.Values) error {
+	key, err := parseAlipayPrivateKey(s.cfg.PaymentConfig.AlipayF2F.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	signParams := make(map[string]string)
+	for k := range params {
+		if k != "sign" {
+			signParams[k] = params.Get(k)
+		}
+	}
+	alipay := &AlipayService{config: &s.cfg.PaymentConfig.AlipayF2F}
+	return rsa2Verify(&key.PublicKey, alipay.getSignString(signParams), params.Get("sign"))
+}
+
+// WechatSign 按商户API密钥生成微信支付签名
+func (s *GatewaySimulator) WechatSign(params map[string]string) string {
+	return NewWechatPayService(&s.cfg.PaymentConfig.WechatPay).sign(params)
+}
+
+// YiPaySign 按商户密钥生成易支付签名
+func (s *GatewaySimulator) YiPaySign(params map[string]string) string {
+	return NewYiPayService(&s.cfg.PaymentConfig.YiPay).sign(params)
+}
+
+// YiPayReturnParams 构建易支付同步跳转参数（与异步通知参数一致）
+func (s *GatewaySimulator) YiPayReturnParams(p *SimPayment) url.Values {
+	params := map[string]string{
+		"pid":          s.cfg.PaymentConfig.YiPay.PID,
+		"trade_no":     p.ID,
+		"out_trade_no": p.OutTradeNo,
+		"type":         p.Extra["type"],
+		"name":         p.Subject,
+		"money":        fmt.Sprintf("%.2f", p.Amount),
+		"trade_status": "TRADE_SUCCESS",
+	}
+	params["sign"] = s.YiPaySign(params)
+	params["sign_type"] = "MD5"
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	return values
+}
+
+// ==================== 异步通知 ====================
+
+// sendNotify 按提供商协议构建并发送签名的异步通知
+func (s *GatewaySimulator) sendNotify(p *SimPayment, siteURL string) *SimNotifyRecord {
+	siteURL = strings.TrimRight(siteURL, "/")
+	record := &SimNotifyRecord{SentAt: time.Now()}
+
+	var req *http.Request
+	var err error
+	switch p.Provider {
+	case SimProviderAlipay:
+		req, err = s.alipayNotify(p, notifyTarget(p.NotifyURL, siteURL, "/alipay/notify"))
+	case SimProviderWechat:
+		req, err = s.wechatNotify(p, notifyTarget(p.NotifyURL, siteURL, "/wechat/notify"))
+	case SimProviderYiPay:
+		req, err = s.yipayNotify(p, notifyTarget(p.NotifyURL, siteURL, "/yipay/notify"))
+	case SimProviderStripe:
+		req, err = s.stripeNotify(p, siteURL+"/stripe/webhook")
+	case SimProviderNowPayments, SimProviderCoinGate:
+		req, err = s.usdtNotify(p, siteURL+"/usdt/webhook")
+	default:
+		err = errors.New("不支持的提供商")
+	}
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+
+	record.URL = req.URL.String()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	record.StatusCode = resp.StatusCode
+	record.Response = string(body)
+	return record
+}
+
+// notifyTarget 获取通知地址（商户未传入时使用本站默认回调路径）
+func notifyTarget(notifyURL, siteURL, defaultPath string) string {
+	if strings.HasPrefix(notifyURL, "http://") || strings.HasPrefix(notifyURL, "https://") {
+		return notifyURL
+	}
+	return siteURL + defaultPath
+}
+
+// alipayNotify 构建支付宝异步通知（RSA2签名，表单提交）
+func (s *GatewaySimulator) alipayNotify(p *SimPayment, target string) (*http.Request, error) {
+	amount := fmt.Sprintf("%.2f", p.Amount)
+	params := map[string]string{
+		"notify_time":      time.Now().Format("2006-01-02 15:04:05"),
+		"notify_type":      "trade_status_sync",
+		"notify_id":        s.NextID("simnotify"),
+		"app_id":           s.cfg.PaymentConfig.AlipayF2F.AppID,
+		"charset":          "utf-8",
+		"version":          "1.0",
+		"trade_no":         p.ID,
+		"out_trade_no":     p.OutTradeNo,
+		"total_amount":     amount,
+		"receipt_amount":   amount,
+		"buyer_pay_amount": amount,
+		"subject":          p.Subject,
+		"trade_status":     "TRADE_SUCCESS",
+		"gmt_payment":      p.PaidAt.Format("2006-01-02 15:04:05"),
+	}
+	alipay := &AlipayService{config: &s.cfg.PaymentConfig.AlipayF2F}
+	params["sign"] = s.AlipaySign(alipay.getSignString(params))
+	params["sign_type"] = "RSA2"
+
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	req, err := http.NewRequest("POST", target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	return req, nil
+}
+
+// wechatNotify 构建微信支付异步通知（MD5签名，XML提交）
+func (s *GatewaySimulator) wechatNotify(p *SimPayment, target string) (*http.Request, error) {
+	wechatCfg := s.cfg.PaymentConfig.WechatPay
+	params := map[string]string{
+		"return_code":    "SUCCESS",
+		"return_msg":     "OK",
+		"appid":          wechatCfg.AppID,
+		"mch_id":         wechatCfg.MchID,
+		"nonce_str":      generateNonceStr(),
+		"result_code":    "SUCCESS",
+		"out_trade_no":   p.OutTradeNo,
+		"transaction_id": p.ID,
+		"trade_type":     "NATIVE",
+		"total_fee":      strconv.FormatInt(amountToCents(p.Amount+0.005), 10),
+	}
+	params["sign"] = s.WechatSign(params)
+
+	req, err := http.NewRequest("POST", target, bytes.NewReader(EncodeWechatXML(params)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	return req, nil
+}
+
+// yipayNotify 构建易支付异步通知（MD5签名，表单提交）
+func (s *GatewaySimulator) yipayNotify(p *SimPayment, target string) (*http.Request, error) {
+	req, err := http.NewRequest("POST", target, strings.NewReader(s.YiPayReturnParams(p).Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// stripeNotify 构建Stripe Webhook事件（HMAC-SHA256签名）
+func (s *GatewaySimulator) stripeNotify(p *SimPayment, target string) (*http.Request, error) {
+	var eventType string
+	var object map[string]interface{}
+	if p.Extra["object"] == "payment_intent" {
+		eventType = "payment_intent.succeeded"
+		object = StripeSimIntentObject(p)
+	} else {
+		eventType = "checkout.session.completed"
+		object = StripeSimSessionObject(p, "")
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"id":       s.NextID("evt_sim_"),
+		"object":   "event",
+		"type":     eventType,
+		"created":  time.Now().Unix(),
+		"livemode": false,
+		"data":     map[string]interface{}{"object": object},
+	})
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(s.cfg.PaymentConfig.StripeWebhookSecret))
+	mac.Write([]byte(timestamp + "." + string(payload)))
+
+	req, err := http.NewRequest("POST", target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil)))
+	return req, nil
+}
+
+// usdtNotify 构建USDT网关（NOWPayments/CoinGate）Webhook（HMAC-SHA256签名）
+func (s *GatewaySimulator) usdtNotify(p *SimPayment, target string) (*http.Request, error) {
+	var payload []byte
+	header := "X-Nowpayments-Sig"
+	if p.Provider == SimProviderCoinGate {
+		header = "X-Coingate-Signature"
+		id, _ := strconv.Atoi(p.ID)
+		payload, _ = json.Marshal(map[string]interface{}{
+			"id":               id,
+			"order_id":         p.OutTradeNo,
+			"status":           "paid",
+			"price_amount":     p.Amount,
+			"price_currency":   p.Currency,
+			"receive_amount":   p.Amount,
+			"receive_currency": "USDT",
+		})
+	} else {
+		payload, _ = json.Marshal(map[string]interface{}{
+			"payment_id":     p.ID,
+			"payment_status": "finished",
+			"order_id":       p.OutTradeNo,
+			"price_amount":   p.Amount,
+			"price_currency": p.Currency,
+			"pay_amount":     p.Amount,
+			"actually_paid":  p.Amount,
+			"pay_currency":   p.Extra["pay_currency"],
+			"outcome_amount": p.Amount,
+		})
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.PaymentConfig.USDTWebhookSecret))
+	mac.Write(payload)
+
+	req, err := http.NewRequest("POST", target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(header, hex.EncodeToString(mac.Sum(nil)))
+	return req, nil
+}
+
+// ==================== Stripe 对象 ====================
+
+// StripeSimSessionObject 构建 Checkout Session 对象
+func StripeSimSessionObject(p *SimPayment, checkoutURL string) map[string]interface{} {
+	status, paymentStatus := "open", "unpaid"
+	switch p.Status {
+	case SimStatusPaid, SimStatusRefunded:
+		status, paymentStatus = "complete", "paid"
+	case SimStatusCanceled:
+		status = "expired"
+	}
+
+	object := map[string]interface{}{
+		"id":                  p.ID,
+		"object":              "checkout.session",
+		"url":                 checkoutURL,
+		"status":              status,
+		"payment_status":      paymentStatus,
+		"amount_total":        amountToCents(p.Amount + 0.005),
+		"currency":            p.Currency,
+		"client_reference_id": p.OutTradeNo,
+		"metadata":            simMetadata(p),
+		"success_url":         p.ReturnURL,
+		"cancel_url":          p.CancelURL,
+	}
+	if paymentStatus == "paid" {
+		object["payment_intent"] = StripeSimSessionIntentID(p.ID)
+	}
+	return object
+}
+
+// StripeSimIntentObject 构建 PaymentIntent 对象
+func StripeSimIntentObject(p *SimPayment) map[string]interface{} {
+	status := "requires_payment_method"
+	switch p.Status {
+	case SimStatusPaid, SimStatusRefunded:
+		status = "succeeded"
+	case SimStatusCanceled:
+		status = "canceled"
+	}
+
+	return map[string]interface{}{
+		"id":            p.ID,
+		"object":        "payment_intent",
+		"amount":        amountToCents(p.Amount + 0.005),
+		"currency":      p.Currency,
+		"status":        status,
+		"description":   p.Subject,
+		"client_secret": p.ID + "_secret_sim",
+		"metadata":      simMetadata(p),
+	}
+}
+
+// StripeSimSessionIntentID 获取 Checkout Session 关联的 PaymentIntent ID
+func StripeSimSessionIntentID(sessionID string) string {
+	return "pi_" + strings.TrimPrefix(sessionID, "cs_")
+}
+
+// simMetadata 获取透传数据（保证序列化为对象而非null）
+func simMetadata(p *SimPayment) map[string]string {
+	if p.Metadata == nil {
+		return map[string]string{}
+	}
+	return p.Metadata
+}
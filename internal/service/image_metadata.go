@@ -0,0 +1,215 @@
+// Package service 提供业务逻辑服务
+// image_metadata.go - 图片元数据清理（EXIF/XMP/IPTC/文本块）与 EXIF 方向读取
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errInvalidImage = errors.New("invalid image data")
+
+// StripImageMetadata 清理图片中的元数据（拍摄设备、GPS 位置等），不重新编码图像数据
+// 支持 JPEG、PNG、WebP，其他类型原样返回
+func StripImageMetadata(mimeType string, data []byte) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	}
+	return data, nil
+}
+
+// stripJPEGMetadata 移除 JPEG 的 APP1（EXIF/XMP）、APP13（IPTC）和注释段
+// 注意：EXIF 中的方向信息会一并移除
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF {
+			return nil, errInvalidImage
+		}
+		start := pos
+		// 跳过填充字节
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, errInvalidImage
+		}
+		marker := data[pos]
+		pos++
+
+		switch {
+		case marker == 0xDA || marker == 0xD9:
+			// 扫描数据开始（或图像结束）：之后的内容原样保留
+			return append(out, data[start:]...), nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// 无长度字段的独立标记
+			out = append(out, data[start:pos]...)
+			continue
+		}
+
+		if pos+2 > len(data) {
+			return nil, errInvalidImage
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, errInvalidImage
+		}
+		end := pos + length
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[start:end]...)
+		}
+		pos = end
+	}
+	return out, nil
+}
+
+// pngMetadataChunks PNG 中需要移除的元数据块
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNGMetadata 移除 PNG 的 EXIF 与文本块
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	if !bytes.HasPrefix(data, signature) {
+		return nil, errInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	pos := len(signature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errInvalidImage
+		}
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			return out, nil
+		}
+	}
+	return nil, errInvalidImage
+}
+
+// stripWebPMetadata 移除 WebP 的 EXIF 与 XMP 块，并同步更新 VP8X 标志和 RIFF 长度
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[0:12]...)
+	pos := 12
+	for pos+8 <= len(data) {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2 // 块数据按偶数字节对齐
+		if size < 0 || end > len(data) {
+			if pos+8+size == len(data) {
+				end = len(data) // 兼容末尾缺少对齐字节的文件
+			} else {
+				return nil, errInvalidImage
+			}
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+			// 丢弃
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if size > 0 {
+				chunk[8] &^= 0x08 | 0x04 // 清除 EXIF 与 XMP 标志位
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
+
+// jpegOrientation 读取 JPEG 的 EXIF 方向标记（1-8），不存在或无法解析时返回 1
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			pos++
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			return 1
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			pos += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation 从 TIFF 结构的第一个 IFD 中读取方向标记（0x0112）
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -12,6 +12,7 @@ import (
 type InvoiceService struct {
 	repo         *repository.Repository
 	emailService *EmailService
+	currencySvc  *CurrencyService
 }
 
 // NewInvoiceService 创建发票服务实例
@@ -22,6 +23,11 @@ func NewInvoiceService(repo *repository.Repository, emailService *EmailService)
 	}
 }
 
+// SetCurrencyService 设置多币种服务
+func (s *InvoiceService) SetCurrencyService(currencySvc *CurrencyService) {
+	s.currencySvc = currencySvc
+}
+
 // generateInvoiceNo 生成发票编号
 func (s *InvoiceService) generateInvoiceNo() string {
 	return fmt.Sprintf("INV%s%04d", time.Now().Format("20060102150405"), time.Now().Nanosecond()%10000)
@@ -128,6 +134,14 @@ func (s *InvoiceService) ApplyInvoice(userID uint, orderNo string, req *InvoiceA
 		Status:      model.InvoiceStatusPending,
 	}
 
+	// 发票按基础货币开具，同时记录订单实际支付币种
+	invoice.Currency = order.Currency
+	if invoice.Currency == "" && s.currencySvc != nil {
+		invoice.Currency = s.currencySvc.GetBaseCurrency()
+	}
+	invoice.PayCurrency = order.PayCurrency
+	invoice.PayAmount = order.PayAmount
+
 	if err := s.repo.GetDB().Create(invoice).Error; err != nil {
 		return nil, err
 	}
@@ -263,10 +277,10 @@ func (s *InvoiceService) AdminIssueInvoice(invoiceNo, invoiceURL, issuedBy strin
 			<p>尊敬的用户，您申请的电子发票已开具成功。</p>
 			<p><strong>发票编号：</strong>%s</p>
 			<p><strong>发票抬头：</strong>%s</p>
-			<p><strong>发票金额：</strong>%.2f 元</p>
+			<p><strong>发票金额：</strong>%.2f %s</p>
 			<p><strong>开具时间：</strong>%s</p>
 			<p>请登录系统下载电子发票。</p>
-		`, invoice.InvoiceNo, invoice.Title, invoice.Amount, now.Format("2006-01-02 15:04:05"))
+		`, invoice.InvoiceNo, invoice.Title, invoice.Amount, invoiceCurrencyLabel(invoice.Currency), now.Format("2006-01-02 15:04:05"))
 		s.emailService.SendEmail(invoice.Email, subject, body)
 	}
 
@@ -383,3 +397,11 @@ func (s *InvoiceService) GetInvoiceStats() map[string]interface{} {
 		"total_amount": totalAmount,
 	}
 }
+
+// invoiceCurrencyLabel 发票金额币种显示文本
+func invoiceCurrencyLabel(currency string) string {
+	if currency == "" || currency == model.CurrencyCNY {
+		return "元"
+	}
+	return currency
+}
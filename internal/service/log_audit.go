@@ -0,0 +1,523 @@
+// Package service 提供业务逻辑服务
+// log_audit.go - 操作审计日志的数据库存储、查询、哈希链校验与归档
+//
+// 操作日志按写入顺序追加到 audit_logs 表，每条记录的哈希为覆盖前一条记录的哈希和本条内容的
+// HMAC-SHA256。密钥和链尾（最近写入的记录ID与哈希）保存在配置数据库的 audit_anchors 表中，
+// 只能修改主数据库时无法重新计算哈希，删除最新的记录也会因链尾对不上而被发现。
+// VerifyAuditChain 按 ID 顺序重新计算即可发现被修改或删除的记录。
+// 过期记录由 clean_old_logs 任务调用 ArchiveAuditLogs，从表头按顺序写入 server_log/archive
+// 下的 gzip 压缩 JSON Lines 文件后删除，归档记录保存被移出部分的首尾哈希，保证链可以继续校验。
+package service
+
+import (
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// auditArchiveDir 审计日志归档目录（位于日志目录下）
+	auditArchiveDir = "archive"
+	// auditBatchSize 校验和归档时每批读取的记录数
+	auditBatchSize = 1000
+)
+
+// errAuditChainBroken 哈希链校验失败，用于提前结束分批读取
+var errAuditChainBroken = errors.New("审计日志哈希链校验失败")
+
+// LogQuery 操作日志查询条件
+type LogQuery struct {
+	UserType  string
+	UserID    uint
+	Username  string // 操作者
+	Action    string
+	Category  string
+	Target    string
+	TargetID  string
+	RequestID string
+	StartTime time.Time // 起始时间（含），为零表示不限
+	EndTime   time.Time // 结束时间（不含），为零表示不限
+	Page      int
+	PageSize  int
+}
+
+// AuditVerifyResult 哈希链校验结果
+type AuditVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`             // 校验通过的记录数
+	Archives int    `json:"archives"`            // 归档次数
+	HeadID   uint   `json:"head_id"`             // 锚点记录的链尾ID
+	BrokenID uint   `json:"broken_id,omitempty"` // 第一条校验失败的记录ID
+	Reason   string `json:"reason,omitempty"`
+}
+
+// db 获取审计日志所在的主数据库，未连接时返回 nil
+func (s *LogService) db() *gorm.DB {
+	if s.repo == nil || !model.DBConnected {
+		return nil
+	}
+	return s.repo.GetDB()
+}
+
+// appendAuditLog 将日志条目追加到审计日志表，调用方需持有写入锁
+// 其他进程（如命令行）同时写入时 prev_hash 唯一索引冲突，重新读取链尾后重试
+func (s *LogService) appendAuditLog(entry *LogEntry) error {
+	db := s.db()
+	key, err := s.auditHMACKey()
+	if err != nil {
+		return err
+	}
+
+	// 超长的字段先截断到列宽，保证存储的内容与计算哈希的内容一致
+	record := &model.AuditLog{
+		UserType:  truncateColumn(entry.UserType, model.AuditUserTypeSize),
+		UserID:    entry.UserID,
+		Username:  truncateColumn(entry.Username, model.AuditUsernameSize),
+		Action:    truncateColumn(entry.Action, model.AuditActionSize),
+		Category:  truncateColumn(entry.Category, model.AuditCategorySize),
+		Target:    truncateColumn(entry.Target, model.AuditTargetSize),
+		TargetID:  truncateColumn(entry.TargetID, model.AuditTargetIDSize),
+		Detail:    entry.Detail,
+		IP:        truncateColumn(entry.IP, model.AuditIPSize),
+		UserAgent: entry.UserAgent,
+		RequestID: truncateColumn(entry.RequestID, model.AuditRequestIDSize),
+		CreatedAt: entry.CreatedAt,
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		if record.PrevHash, err = lastAuditHash(db); err != nil {
+			return err
+		}
+		record.ID = 0
+		record.Hash = auditHash(key, record)
+		if err = db.Create(record).Error; err == nil {
+			entry.ID = record.ID
+			entry.Hash = record.Hash
+			if err := updateAuditHead(record.ID, record.Hash); err != nil {
+				fmt.Printf("更新审计日志锚点失败: %v\n", err)
+			}
+			return nil
+		}
+	}
+	return err
+}
+
+// auditDatabaseName 当前主数据库标识，区分切换数据库前后的审计日志
+func auditDatabaseName() string {
+	if config.GlobalConfig == nil {
+		return ""
+	}
+	c := config.GlobalConfig.DBConfig
+	if c.Type == "sqlite" {
+		return "sqlite:" + c.Database
+	}
+	return fmt.Sprintf("%s://%s:%d/%s", c.Type, c.Host, c.Port, c.Database)
+}
+
+// findAuditAnchor 读取当前主数据库的审计日志锚点，不存在时返回 nil
+func findAuditAnchor() (*model.AuditAnchorDB, error) {
+	if model.ConfigDB == nil {
+		return nil, errors.New("配置数据库未初始化")
+	}
+	var anchor model.AuditAnchorDB
+	if err := model.ConfigDB.Where("database_name = ?", auditDatabaseName()).Limit(1).Find(&anchor).Error; err != nil {
+		return nil, err
+	}
+	if anchor.ID == 0 {
+		return nil, nil
+	}
+	return &anchor, nil
+}
+
+// decodeAuditKey 解码锚点中的哈希密钥
+func decodeAuditKey(anchor *model.AuditAnchorDB) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(anchor.HMACKey)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("审计日志密钥无效")
+	}
+	return key, nil
+}
+
+// auditHMACKey 获取当前主数据库的审计日志哈希密钥，首次写入时生成并保存到配置数据库
+func (s *LogService) auditHMACKey() ([]byte, error) {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+
+	name := auditDatabaseName()
+	if s.auditKey != nil && s.auditKeyDB == name {
+		return s.auditKey, nil
+	}
+
+	anchor, err := findAuditAnchor()
+	if err != nil {
+		return nil, err
+	}
+	if anchor == nil {
+		keyBase64, err := utils.GenerateAESKey(256)
+		if err != nil {
+			return nil, fmt.Errorf("生成审计日志密钥失败: %v", err)
+		}
+		anchor = &model.AuditAnchorDB{DatabaseName: name, HMACKey: keyBase64}
+		if err := model.ConfigDB.Create(anchor).Error; err != nil {
+			return nil, fmt.Errorf("保存审计日志密钥失败: %v", err)
+		}
+	}
+
+	key, err := decodeAuditKey(anchor)
+	if err != nil {
+		return nil, err
+	}
+	s.auditKey, s.auditKeyDB = key, name
+	return key, nil
+}
+
+// updateAuditHead 更新锚点中的链尾，只向前推进（其他进程可能已写入更新的记录）
+func updateAuditHead(id uint, hash string) error {
+	return model.ConfigDB.Model(&model.AuditAnchorDB{}).
+		Where("database_name = ? AND head_id < ?", auditDatabaseName(), id).
+		Updates(map[string]interface{}{"head_id": id, "head_hash": hash}).Error
+}
+
+// truncateColumn 截断到列长度（按字符计），超出时以 ... 结尾
+func truncateColumn(s string, size int) string {
+	runes := []rune(s)
+	if len(runes) <= size {
+		return s
+	}
+	return string(runes[:size-3]) + "..."
+}
+
+// lastAuditHash 获取哈希链末尾的哈希：表中最后一条记录，表为空时为最近一次归档的最后一条
+func lastAuditHash(db *gorm.DB) (string, error) {
+	var last model.AuditLog
+	if err := db.Select("id", "hash").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return "", err
+	}
+	if last.ID != 0 {
+		return last.Hash, nil
+	}
+
+	var archive model.AuditLogArchive
+	if err := db.Order("to_id DESC").Limit(1).Find(&archive).Error; err != nil {
+		return "", err
+	}
+	return archive.LastHash, nil
+}
+
+// auditHash 计算记录哈希：HMAC-SHA256(密钥, 前一条哈希 + 记录内容)
+// 时间按秒计算，不受各数据库时间精度和时区的影响
+func auditHash(key []byte, l *model.AuditLog) string {
+	data, _ := json.Marshal([]interface{}{
+		l.PrevHash, l.UserType, l.UserID, l.Username, l.Action, l.Category, l.Target, l.TargetID,
+		l.Detail, l.IP, l.UserAgent, l.RequestID, l.CreatedAt.Unix(),
+	})
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// toLogEntry 转换为日志条目
+func toLogEntry(l *model.AuditLog) LogEntry {
+	return LogEntry{
+		ID:        l.ID,
+		UserType:  l.UserType,
+		UserID:    l.UserID,
+		Username:  l.Username,
+		Action:    l.Action,
+		Category:  l.Category,
+		Target:    l.Target,
+		TargetID:  l.TargetID,
+		Detail:    l.Detail,
+		IP:        l.IP,
+		UserAgent: l.UserAgent,
+		RequestID: l.RequestID,
+		Hash:      l.Hash,
+		CreatedAt: l.CreatedAt,
+	}
+}
+
+// SearchOperationLogs 查询数据库中的操作日志（按时间倒序），PageSize 为 0 时不分页
+func (s *LogService) SearchOperationLogs(q LogQuery) ([]LogEntry, int64, error) {
+	db := s.db()
+	if db == nil {
+		return nil, 0, errors.New("数据库未连接")
+	}
+
+	query := db.Model(&model.AuditLog{})
+	if q.UserType != "" {
+		query = query.Where("user_type = ?", q.UserType)
+	}
+	if q.UserID != 0 {
+		query = query.Where("user_id = ?", q.UserID)
+	}
+	if q.Username != "" {
+		query = query.Where("username = ?", q.Username)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if q.Category != "" {
+		query = query.Where("category = ?", q.Category)
+	}
+	if q.Target != "" {
+		query = query.Where("target = ?", q.Target)
+	}
+	if q.TargetID != "" {
+		query = query.Where("target_id = ?", q.TargetID)
+	}
+	if q.RequestID != "" {
+		query = query.Where("request_id = ?", q.RequestID)
+	}
+	if !q.StartTime.IsZero() {
+		query = query.Where("created_at >= ?", q.StartTime)
+	}
+	if !q.EndTime.IsZero() {
+		query = query.Where("created_at < ?", q.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.PageSize > 0 {
+		if q.Page < 1 {
+			q.Page = 1
+		}
+		query = query.Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize)
+	}
+	var logs []model.AuditLog
+	if err := query.Order("id DESC").Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]LogEntry, len(logs))
+	for i := range logs {
+		entries[i] = toLogEntry(&logs[i])
+	}
+	return entries, total, nil
+}
+
+// VerifyAuditChain 校验审计日志哈希链
+// 先检查各次归档首尾相接，再按 ID 顺序逐条重新计算哈希，遇到第一处不一致即停止；
+// 最后确认配置数据库中记录的链尾仍在链上，发现删除最新记录的情况
+func (s *LogService) VerifyAuditChain() (*AuditVerifyResult, error) {
+	db := s.db()
+	if db == nil {
+		return nil, errors.New("数据库未连接")
+	}
+
+	result := &AuditVerifyResult{Valid: true}
+	fail := func(id uint, reason string) {
+		result.Valid = false
+		result.BrokenID = id
+		result.Reason = reason
+	}
+
+	anchor, err := findAuditAnchor()
+	if err != nil {
+		return nil, err
+	}
+	if anchor == nil {
+		// 尚未写入过审计日志时没有锚点，此时表中和归档中都不应有记录
+		var count, archived int64
+		if err := db.Model(&model.AuditLog{}).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Model(&model.AuditLogArchive{}).Count(&archived).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 || archived > 0 {
+			fail(0, "配置数据库中没有审计日志密钥和锚点，无法校验")
+		}
+		return result, nil
+	}
+	key, err := decodeAuditKey(anchor)
+	if err != nil {
+		return nil, err
+	}
+	result.HeadID = anchor.HeadID
+	headFound := anchor.HeadID == 0
+
+	var archives []model.AuditLogArchive
+	if err := db.Order("to_id ASC").Find(&archives).Error; err != nil {
+		return nil, err
+	}
+	result.Archives = len(archives)
+
+	prevHash := ""
+	for _, a := range archives {
+		if a.FirstPrevHash != prevHash {
+			fail(a.FromID, fmt.Sprintf("归档 %s（记录 %d-%d）与之前的记录不连续", a.FileName, a.FromID, a.ToID))
+			return result, nil
+		}
+		prevHash = a.LastHash
+		if a.ToID == anchor.HeadID && a.LastHash == anchor.HeadHash {
+			headFound = true
+		}
+	}
+
+	var batch []model.AuditLog
+	err = db.FindInBatches(&batch, auditBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			l := &batch[i]
+			if l.PrevHash != prevHash {
+				fail(l.ID, "与前一条记录的哈希不连续，之前的记录可能被删除或修改")
+				return errAuditChainBroken
+			}
+			if auditHash(key, l) != l.Hash {
+				fail(l.ID, "记录内容与哈希不符，记录可能被修改")
+				return errAuditChainBroken
+			}
+			if l.ID == anchor.HeadID {
+				if l.Hash != anchor.HeadHash {
+					fail(l.ID, "记录哈希与锚点记录的链尾不一致")
+					return errAuditChainBroken
+				}
+				headFound = true
+			}
+			prevHash = l.Hash
+			result.Checked++
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		return nil, err
+	}
+	if result.Valid && !headFound {
+		fail(anchor.HeadID, "锚点记录的链尾不存在，最新的记录可能被删除")
+	}
+	return result, nil
+}
+
+// ArchiveAuditLogs 将 before 之前的审计日志归档到 server_log/archive 并从数据库删除，返回归档的记录数
+// 归档文件每行一条记录（与数据库一致，详情和 User-Agent 加密），归档范围为 before 之前最后一条记录及其之前的全部记录
+func (s *LogService) ArchiveAuditLogs(before time.Time) (int64, error) {
+	db := s.db()
+	if db == nil {
+		return 0, nil
+	}
+
+	var maxID int64
+	row := db.Model(&model.AuditLog{}).Where("created_at < ?", before).Select("COALESCE(MAX(id), 0)").Row()
+	if err := row.Scan(&maxID); err != nil {
+		return 0, err
+	}
+	if maxID == 0 {
+		return 0, nil
+	}
+
+	dir := filepath.Join(s.logDir, auditArchiveDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("创建归档目录失败: %v", err)
+	}
+	fileName := fmt.Sprintf("audit_%s_%d.jsonl.gz", time.Now().Format("20060102_150405"), maxID)
+	filePath := filepath.Join(dir, fileName)
+
+	archive := &model.AuditLogArchive{FileName: fileName}
+	fileHash, err := writeAuditArchive(db, filePath, uint(maxID), archive)
+	if err != nil {
+		os.Remove(filePath)
+		return 0, fmt.Errorf("写入归档文件失败: %v", err)
+	}
+	archive.FileHash = fileHash
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(archive).Error; err != nil {
+			return err
+		}
+		return tx.Where("id <= ?", archive.ToID).Delete(&model.AuditLog{}).Error
+	})
+	if err != nil {
+		os.Remove(filePath)
+		return 0, fmt.Errorf("删除已归档的日志失败: %v", err)
+	}
+	return archive.Count, nil
+}
+
+// writeAuditArchive 将 ID 不超过 maxID 的记录写入归档文件，同时填写归档记录的范围和首尾哈希，返回文件的 SHA-256
+func writeAuditArchive(db *gorm.DB, filePath string, maxID uint, archive *model.AuditLogArchive) (string, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(file, hasher))
+	enc := json.NewEncoder(gz)
+
+	var batch []model.AuditLog
+	err = db.Where("id <= ?", maxID).FindInBatches(&batch, auditBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			l := batch[i]
+			if archive.Count == 0 {
+				archive.FromID = l.ID
+				archive.StartTime = l.CreatedAt
+				archive.FirstPrevHash = l.PrevHash
+			}
+			archive.ToID = l.ID
+			archive.EndTime = l.CreatedAt
+			archive.LastHash = l.Hash
+			archive.Count++
+
+			var err error
+			if l.Detail, err = model.EncryptSecret(l.Detail); err != nil {
+				return err
+			}
+			if l.UserAgent, err = model.EncryptSecret(l.UserAgent); err != nil {
+				return err
+			}
+			if err := enc.Encode(&l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// CleanLogFiles 删除 before 之前日期的CSV日志文件，返回删除的文件数
+func (s *LogService) CleanLogFiles(before time.Time) (int, error) {
+	dates, err := s.GetAvailableLogDates()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := before.Format("2006-01-02")
+	removed := 0
+	for _, date := range dates {
+		if date >= cutoff {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.logDir, date+".csv")); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 	"sync"
 	"time"
 
+	"user-frontend/internal/repository"
+	"user-frontend/internal/trace"
 	"user-frontend/internal/utils"
 )
 
@@ -27,6 +30,8 @@ type LogEntry struct {
 	Detail    string    `json:"detail"`
 	IP        string    `json:"ip"`
 	UserAgent string    `json:"user_agent"`
+	RequestID string    `json:"request_id,omitempty"` // 产生该日志的请求ID
+	Hash      string    `json:"hash,omitempty"`       // 审计日志哈希链中的哈希
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -34,19 +39,27 @@ type LogEntry struct {
 type LogConfig struct {
 	EnableUserLog  bool `json:"enable_user_log"`  // 是否启用用户端日志
 	EnableAdminLog bool `json:"enable_admin_log"` // 是否启用管理端日志
+	EnableFileLog  bool `json:"enable_file_log"`  // 是否同时写入加密CSV文件
 }
 
-// LogService 日志服务（文件存储版本）
+// LogService 日志服务
+// 操作日志写入数据库审计表（见 log_audit.go），可选同时写入加密CSV文件；
+// 数据库不可用或写入失败时写入CSV文件，避免丢失日志
 type LogService struct {
+	repo       *repository.Repository
 	logDir     string     // 日志目录
 	mu         sync.Mutex // 写入锁
 	config     LogConfig  // 日志配置
 	configPath string     // 配置文件路径
+
+	keyMu      sync.Mutex // 审计日志密钥缓存锁
+	auditKey   []byte     // 审计日志哈希密钥
+	auditKeyDB string     // 密钥所属的主数据库标识
 }
 
 // NewLogService 创建日志服务
-// 日志文件保存在程序根目录的 server_log 文件夹下
-func NewLogService() *LogService {
+// CSV日志文件、日志配置和审计日志归档保存在程序根目录的 server_log 文件夹下
+func NewLogService(repo *repository.Repository) *LogService {
 	// 获取程序根目录
 	execPath, err := os.Executable()
 	if err != nil {
@@ -72,6 +85,7 @@ func NewLogService() *LogService {
 	}
 	
 	svc := &LogService{
+		repo:       repo,
 		logDir:     logDir,
 		configPath: configPath,
 		config: LogConfig{
@@ -143,8 +157,12 @@ func (s *LogService) getTodayLogFilePath() string {
 }
 
 // LogOperation 记录操作日志
-// 日志使用AES-256-GCM加密后保存到CSV文件
 func (s *LogService) LogOperation(userType string, userID uint, username, action, category, target, targetID string, detail interface{}, ip, userAgent string) {
+	s.logOperation("", userType, userID, username, action, category, target, targetID, detail, ip, userAgent)
+}
+
+// logOperation 记录操作日志，requestID 为空表示非请求触发（如定时任务）
+func (s *LogService) logOperation(requestID, userType string, userID uint, username, action, category, target, targetID string, detail interface{}, ip, userAgent string) {
 	// 检查日志开关
 	if userType == "user" && !s.config.EnableUserLog {
 		return
@@ -179,16 +197,33 @@ func (s *LogService) LogOperation(userType string, userID uint, username, action
 		Detail:    detailStr,
 		IP:        ip,
 		UserAgent: userAgent,
+		RequestID: requestID,
 		CreatedAt: time.Now(),
 	}
 
-	// 写入日志文件
-	if err := s.writeLogEntry(entry); err != nil {
-		fmt.Printf("写入日志失败: %v\n", err)
+	s.writeEntry(entry)
+}
+
+// writeEntry 写入审计日志表，启用文件日志或写入数据库失败时写入CSV文件
+// 调用方需持有写入锁
+func (s *LogService) writeEntry(entry *LogEntry) {
+	written := false
+	if s.db() != nil {
+		if err := s.appendAuditLog(entry); err != nil {
+			fmt.Printf("写入审计日志失败: %v\n", err)
+		} else {
+			written = true
+		}
+	}
+	if !written || s.config.EnableFileLog {
+		if err := s.writeLogEntry(entry); err != nil {
+			fmt.Printf("写入日志失败: %v\n", err)
+		}
 	}
 }
 
 // writeLogEntry 将日志条目写入CSV文件
+// 每个字段使用AES-256-GCM加密；已写入审计表的条目带有哈希，可与数据库记录对照
 func (s *LogService) writeLogEntry(entry *LogEntry) error {
 	filePath := s.getTodayLogFilePath()
 	
@@ -210,7 +245,7 @@ func (s *LogService) writeLogEntry(entry *LogEntry) error {
 
 	// 如果是新文件，写入表头（表头不加密）
 	if isNewFile {
-		header := []string{"user_type", "user_id", "username", "action", "category", "target", "target_id", "detail", "ip", "user_agent", "created_at"}
+		header := []string{"user_type", "user_id", "username", "action", "category", "target", "target_id", "detail", "ip", "user_agent", "created_at", "request_id", "hash"}
 		if err := writer.Write(header); err != nil {
 			return fmt.Errorf("写入表头失败: %v", err)
 		}
@@ -229,6 +264,8 @@ func (s *LogService) writeLogEntry(entry *LogEntry) error {
 		entry.IP,
 		entry.UserAgent,
 		entry.CreatedAt.Format("2006-01-02 15:04:05"),
+		entry.RequestID,
+		entry.Hash,
 	}
 
 	// 加密每个字段
@@ -275,6 +312,42 @@ func (s *LogService) LogAdminActionSimple(username, action, target, targetID str
 	s.LogOperation("admin", 0, username, action, category, target, targetID, detail, ip, userAgent)
 }
 
+// OperationLogger 绑定请求上下文的操作日志记录器，写入的日志带有请求ID
+type OperationLogger struct {
+	svc       *LogService
+	requestID string
+}
+
+// WithContext 返回绑定 ctx 中请求ID的操作日志记录器
+func (s *LogService) WithContext(ctx context.Context) *OperationLogger {
+	return &OperationLogger{svc: s, requestID: trace.RequestID(ctx)}
+}
+
+// LogUserAction 记录用户操作
+func (l *OperationLogger) LogUserAction(userID uint, username, action, category, target, targetID string, detail interface{}, ip, userAgent string) {
+	l.svc.logOperation(l.requestID, "user", userID, username, action, category, target, targetID, detail, ip, userAgent)
+}
+
+// LogUserActionSimple 记录用户操作（根据 target 自动推断分类）
+func (l *OperationLogger) LogUserActionSimple(userID uint, username, action, target, targetID string, detail interface{}, ip, userAgent string) {
+	l.svc.logOperation(l.requestID, "user", userID, username, action, inferCategory(target), target, targetID, detail, ip, userAgent)
+}
+
+// LogAdminAction 记录管理员操作
+func (l *OperationLogger) LogAdminAction(username, action, category, target, targetID string, detail interface{}, ip, userAgent string) {
+	l.svc.logOperation(l.requestID, "admin", 0, username, action, category, target, targetID, detail, ip, userAgent)
+}
+
+// LogAdminActionSimple 记录管理员操作（根据 target 自动推断分类）
+func (l *OperationLogger) LogAdminActionSimple(username, action, target, targetID string, detail interface{}, ip, userAgent string) {
+	l.svc.logOperation(l.requestID, "admin", 0, username, action, inferCategory(target), target, targetID, detail, ip, userAgent)
+}
+
+// LogSecurityEvent 记录安全事件
+func (l *OperationLogger) LogSecurityEvent(action, ip, userAgent string, detail interface{}) {
+	l.svc.logSecurityEvent(l.requestID, action, ip, userAgent, detail)
+}
+
 // inferCategory 根据 target 推断分类
 func inferCategory(target string) string {
 	switch target {
@@ -303,6 +376,11 @@ func inferCategory(target string) string {
 
 // LogSecurityEvent 记录安全事件
 func (s *LogService) LogSecurityEvent(action, ip, userAgent string, detail interface{}) {
+	s.logSecurityEvent("", action, ip, userAgent, detail)
+}
+
+// logSecurityEvent 记录安全事件，requestID 为空表示非请求触发
+func (s *LogService) logSecurityEvent(requestID, action, ip, userAgent string, detail interface{}) {
 	// 安全事件始终记录，不受开关控制
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -328,15 +406,15 @@ func (s *LogService) LogSecurityEvent(action, ip, userAgent string, detail inter
 		Detail:    detailStr,
 		IP:        ip,
 		UserAgent: userAgent,
+		RequestID: requestID,
 		CreatedAt: time.Now(),
 	}
 
-	if err := s.writeLogEntry(entry); err != nil {
-		fmt.Printf("写入日志失败: %v\n", err)
-	}
+	s.writeEntry(entry)
 }
 
-// GetOperationLogs 获取操作日志（从文件读取并解密）
+// GetOperationLogs 获取CSV文件中的操作日志（从文件读取并解密）
+// 用于查看升级到数据库存储前的日志和启用文件日志后写入的日志，数据库中的日志使用 SearchOperationLogs 查询
 // date: 日期字符串，格式为 YYYY-MM-DD，为空则使用今天
 // page, pageSize: 分页参数
 // userType, action, category: 过滤条件
@@ -416,6 +494,8 @@ func (s *LogService) readLogFile(filePath string) ([]LogEntry, error) {
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	// 旧日志文件没有 request_id、hash 列，同一文件中可能混有新旧两种记录
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		return nil, fmt.Errorf("读取CSV失败: %v", err)
@@ -464,13 +544,19 @@ func (s *LogService) readLogFile(filePath string) ([]LogEntry, error) {
 			UserAgent: decrypted[9],
 			CreatedAt: createdAt,
 		}
+		if len(decrypted) > 11 {
+			entry.RequestID = decrypted[11]
+		}
+		if len(decrypted) > 12 {
+			entry.Hash = decrypted[12]
+		}
 		entries = append(entries, entry)
 	}
 
 	return entries, nil
 }
 
-// GetAvailableLogDates 获取可用的日志日期列表
+// GetAvailableLogDates 获取CSV日志文件的日期列表
 func (s *LogService) GetAvailableLogDates() ([]string, error) {
 	files, err := os.ReadDir(s.logDir)
 	if err != nil {
@@ -499,8 +585,12 @@ func (s *LogService) GetAvailableLogDates() ([]string, error) {
 }
 
 // GetUserOperationLogs 获取用户操作日志（兼容旧接口）
-// 注意：此方法需要遍历所有日志文件，性能较低
+// 数据库未连接时遍历所有日志文件，性能较低
 func (s *LogService) GetUserOperationLogs(userID uint, page, pageSize int) ([]LogEntry, int64, error) {
+	if s.db() != nil {
+		return s.SearchOperationLogs(LogQuery{UserType: "user", UserID: userID, Page: page, PageSize: pageSize})
+	}
+
 	// 获取所有可用日期
 	dates, err := s.GetAvailableLogDates()
 	if err != nil {
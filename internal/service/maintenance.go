@@ -0,0 +1,247 @@
+// Package service 提供业务逻辑服务
+// maintenance.go - 维护模式（数据库恢复等操作期间暂停对外服务和后台任务）
+//
+// 维护状态保存在配置数据库的 maintenance_locks 表中，共用配置目录的所有实例据此返回 503 并跳过后台任务，
+// 各实例按 maintenanceCheckInterval 缓存读取结果。持有者定期刷新心跳，异常退出后超过
+// maintenanceLockTTL 未刷新的锁视为失效，可被重新获取。
+// 后台任务每轮执行前调用 BeginBackgroundWork；进入维护模式时等待本实例执行中的后台任务结束，
+// 并等待其他实例读到维护状态后再返回。
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"user-frontend/internal/model"
+)
+
+const (
+	// maintenanceLockTTL 心跳超过该时间未刷新的维护锁视为失效
+	maintenanceLockTTL = time.Minute
+	// maintenanceHeartbeat 持有维护锁时刷新心跳的间隔
+	maintenanceHeartbeat = 15 * time.Second
+	// maintenanceCheckInterval 读取配置数据库中维护状态的缓存时间
+	maintenanceCheckInterval = time.Second
+	// maintenanceDrainTimeout 进入维护模式时等待执行中的后台任务结束的时限
+	maintenanceDrainTimeout = 2 * time.Minute
+	// maintenanceLockID 维护锁记录的固定ID
+	maintenanceLockID = 1
+)
+
+// errMaintenanceBusy 已处于维护模式
+var errMaintenanceBusy = errors.New("系统正处于维护模式，已有恢复、导入或密钥轮换任务正在进行")
+
+// maintenanceSettle 进入维护模式后等待其他实例读到维护状态的时间
+var maintenanceSettle = 2 * maintenanceCheckInterval
+
+// MaintenanceStatus 维护模式状态
+type MaintenanceStatus struct {
+	Active bool      `json:"active"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+	Shared bool      `json:"shared"` // 维护状态已写入配置数据库，所有实例可见
+}
+
+var (
+	maintenanceMu     sync.Mutex
+	maintenanceStatus MaintenanceStatus // 本实例发起的维护
+	maintenanceStop   chan struct{}     // 停止心跳
+	backgroundWork    int               // 本实例执行中的后台任务数
+
+	sharedMaintenanceMu      sync.Mutex
+	sharedMaintenanceStatus  MaintenanceStatus // 配置数据库中其他实例发起的维护
+	sharedMaintenanceChecked time.Time
+
+	// maintenanceOwner 本实例的维护锁持有者标识
+	maintenanceOwner = func() string {
+		host, _ := os.Hostname()
+		return fmt.Sprintf("%s:%d", host, os.Getpid())
+	}()
+)
+
+// EnterMaintenance 进入维护模式
+// 写入配置数据库中的维护锁（其他实例持有有效的锁时返回错误），等待本实例执行中的后台任务结束，
+// 超过 maintenanceDrainTimeout 仍未结束时退出维护模式并返回错误。配置数据库未初始化时只在本实例生效。
+func EnterMaintenance(reason string) error {
+	maintenanceMu.Lock()
+	if maintenanceStatus.Active {
+		maintenanceMu.Unlock()
+		return errMaintenanceBusy
+	}
+	now := time.Now()
+	shared, err := acquireMaintenanceLock(reason, now)
+	if err != nil {
+		maintenanceMu.Unlock()
+		return err
+	}
+	stop := make(chan struct{})
+	maintenanceStatus = MaintenanceStatus{Active: true, Reason: reason, Since: now, Shared: shared}
+	maintenanceStop = stop
+	maintenanceMu.Unlock()
+
+	if shared {
+		go maintenanceHeartbeatLoop(stop)
+	}
+
+	deadline := now.Add(maintenanceDrainTimeout)
+	for {
+		maintenanceMu.Lock()
+		running := backgroundWork
+		maintenanceMu.Unlock()
+		if running == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			LeaveMaintenance()
+			return fmt.Errorf("仍有 %d 个后台任务正在执行，请稍后再试", running)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if wait := maintenanceSettle - time.Since(now); shared && wait > 0 {
+		time.Sleep(wait)
+	}
+	return nil
+}
+
+// LeaveMaintenance 退出维护模式并释放配置数据库中的维护锁
+func LeaveMaintenance() {
+	maintenanceMu.Lock()
+	if maintenanceStop != nil {
+		close(maintenanceStop)
+		maintenanceStop = nil
+	}
+	shared := maintenanceStatus.Shared
+	maintenanceStatus = MaintenanceStatus{}
+	maintenanceMu.Unlock()
+
+	if shared && model.ConfigDB != nil {
+		model.ConfigDB.Where("id = ? AND owner = ?", maintenanceLockID, maintenanceOwner).Delete(&model.MaintenanceLockDB{})
+	}
+	resetSharedMaintenance()
+}
+
+// GetMaintenanceStatus 获取当前维护模式状态（本实例或其他实例发起）
+func GetMaintenanceStatus() MaintenanceStatus {
+	maintenanceMu.Lock()
+	local := maintenanceStatus
+	maintenanceMu.Unlock()
+	if local.Active {
+		return local
+	}
+	return sharedMaintenance()
+}
+
+// holdsSharedMaintenance 本实例是否持有配置数据库中的维护锁（其他实例已据此暂停写入）
+func holdsSharedMaintenance() bool {
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+	return maintenanceStatus.Active && maintenanceStatus.Shared
+}
+
+// BeginBackgroundWork 后台任务每轮执行前调用
+// 处于维护模式时返回 false，本轮应跳过；否则登记为执行中，执行完毕后调用 done
+func BeginBackgroundWork() (done func(), ok bool) {
+	if GetMaintenanceStatus().Active {
+		return nil, false
+	}
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+	if maintenanceStatus.Active {
+		return nil, false
+	}
+	backgroundWork++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			maintenanceMu.Lock()
+			backgroundWork--
+			maintenanceMu.Unlock()
+		})
+	}, true
+}
+
+// acquireMaintenanceLock 写入维护锁，锁已失效时接管；返回是否写入了配置数据库
+func acquireMaintenanceLock(reason string, now time.Time) (bool, error) {
+	if model.ConfigDB == nil {
+		return false, nil
+	}
+	lock := &model.MaintenanceLockDB{
+		ID:          maintenanceLockID,
+		Owner:       maintenanceOwner,
+		Reason:      reason,
+		Since:       now,
+		HeartbeatAt: now,
+	}
+	if model.ConfigDB.Create(lock).Error == nil {
+		return true, nil
+	}
+
+	result := model.ConfigDB.Model(&model.MaintenanceLockDB{}).
+		Where("id = ? AND (heartbeat_at < ? OR owner = ?)", maintenanceLockID, now.Add(-maintenanceLockTTL), maintenanceOwner).
+		Updates(map[string]interface{}{
+			"owner":        maintenanceOwner,
+			"reason":       reason,
+			"since":        now,
+			"heartbeat_at": now,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("写入维护状态失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, errMaintenanceBusy
+	}
+	return true, nil
+}
+
+// maintenanceHeartbeatLoop 持有维护锁期间定期刷新心跳
+func maintenanceHeartbeatLoop(stop chan struct{}) {
+	ticker := time.NewTicker(maintenanceHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if model.ConfigDB != nil {
+				model.ConfigDB.Model(&model.MaintenanceLockDB{}).
+					Where("id = ? AND owner = ?", maintenanceLockID, maintenanceOwner).
+					Update("heartbeat_at", time.Now())
+			}
+		}
+	}
+}
+
+// sharedMaintenance 读取配置数据库中其他实例持有的有效维护锁（带缓存，读取失败时视为未处于维护模式）
+func sharedMaintenance() MaintenanceStatus {
+	sharedMaintenanceMu.Lock()
+	defer sharedMaintenanceMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(sharedMaintenanceChecked) < maintenanceCheckInterval {
+		return sharedMaintenanceStatus
+	}
+	sharedMaintenanceChecked = now
+	sharedMaintenanceStatus = MaintenanceStatus{}
+	if model.ConfigDB == nil {
+		return sharedMaintenanceStatus
+	}
+
+	var lock model.MaintenanceLockDB
+	err := model.ConfigDB.Where("id = ? AND owner <> ? AND heartbeat_at >= ?", maintenanceLockID, maintenanceOwner, now.Add(-maintenanceLockTTL)).
+		Limit(1).Find(&lock).Error
+	if err == nil && lock.ID != 0 {
+		sharedMaintenanceStatus = MaintenanceStatus{Active: true, Reason: lock.Reason, Since: lock.Since, Shared: true}
+	}
+	return sharedMaintenanceStatus
+}
+
+// resetSharedMaintenance 清除维护状态缓存，下次读取时重新查询配置数据库
+func resetSharedMaintenance() {
+	sharedMaintenanceMu.Lock()
+	sharedMaintenanceChecked = time.Time{}
+	sharedMaintenanceMu.Unlock()
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"user-frontend/internal/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestConfigDB 将 model.ConfigDB 替换为内存数据库，测试结束后恢复
+func useTestConfigDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("无法创建配置数据库: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("迁移配置数据库失败: %v", err)
+	}
+	old := model.ConfigDB
+	model.ConfigDB = db
+	resetSharedMaintenance()
+	t.Cleanup(func() {
+		model.ConfigDB = old
+		resetSharedMaintenance()
+		sqlDB.Close()
+	})
+	return db
+}
+
+// withoutMaintenanceSettle 测试中不等待其他实例读取维护状态
+func withoutMaintenanceSettle(t *testing.T) {
+	old := maintenanceSettle
+	maintenanceSettle = 0
+	t.Cleanup(func() { maintenanceSettle = old })
+}
+
+// TestMaintenance_SharedLock 测试维护状态写入配置数据库
+func TestMaintenance_SharedLock(t *testing.T) {
+	db := useTestConfigDB(t, &model.MaintenanceLockDB{})
+	withoutMaintenanceSettle(t)
+
+	if err := EnterMaintenance("正在恢复数据库"); err != nil {
+		t.Fatalf("进入维护模式失败: %v", err)
+	}
+	status := GetMaintenanceStatus()
+	if !status.Active || !status.Shared || status.Reason != "正在恢复数据库" {
+		t.Errorf("维护状态错误: %+v", status)
+	}
+	var lock model.MaintenanceLockDB
+	if err := db.First(&lock, maintenanceLockID).Error; err != nil || lock.Owner != maintenanceOwner {
+		t.Errorf("维护锁未写入配置数据库: %+v, %v", lock, err)
+	}
+
+	if err := EnterMaintenance("正在导入数据"); !errors.Is(err, errMaintenanceBusy) {
+		t.Errorf("重复进入维护模式应返回 errMaintenanceBusy，实际 %v", err)
+	}
+	if _, ok := BeginBackgroundWork(); ok {
+		t.Error("维护模式中不应执行后台任务")
+	}
+
+	LeaveMaintenance()
+	if GetMaintenanceStatus().Active {
+		t.Error("退出后不应处于维护模式")
+	}
+	var count int64
+	db.Model(&model.MaintenanceLockDB{}).Count(&count)
+	if count != 0 {
+		t.Errorf("退出后应删除维护锁，实际 %d 条", count)
+	}
+	done, ok := BeginBackgroundWork()
+	if !ok {
+		t.Fatal("退出维护模式后应可执行后台任务")
+	}
+	done()
+}
+
+// TestMaintenance_OtherInstance 测试其他实例持有的维护锁及失效接管
+func TestMaintenance_OtherInstance(t *testing.T) {
+	db := useTestConfigDB(t, &model.MaintenanceLockDB{})
+	withoutMaintenanceSettle(t)
+
+	now := time.Now()
+	db.Create(&model.MaintenanceLockDB{ID: maintenanceLockID, Owner: "other:1", Reason: "正在轮换加密密钥", Since: now, HeartbeatAt: now})
+
+	status := GetMaintenanceStatus()
+	if !status.Active || !status.Shared || status.Reason != "正在轮换加密密钥" {
+		t.Errorf("应读到其他实例的维护状态: %+v", status)
+	}
+	if _, ok := BeginBackgroundWork(); ok {
+		t.Error("其他实例维护期间不应执行后台任务")
+	}
+	if err := EnterMaintenance("正在恢复数据库"); !errors.Is(err, errMaintenanceBusy) {
+		t.Errorf("其他实例持有维护锁时应返回 errMaintenanceBusy，实际 %v", err)
+	}
+
+	// 心跳超时的锁失效，可以接管
+	db.Model(&model.MaintenanceLockDB{}).Where("id = ?", maintenanceLockID).Update("heartbeat_at", now.Add(-2*maintenanceLockTTL))
+	resetSharedMaintenance()
+	if GetMaintenanceStatus().Active {
+		t.Error("心跳超时的维护锁应视为失效")
+	}
+	if err := EnterMaintenance("正在恢复数据库"); err != nil {
+		t.Fatalf("应接管失效的维护锁: %v", err)
+	}
+	defer LeaveMaintenance()
+	var lock model.MaintenanceLockDB
+	db.First(&lock, maintenanceLockID)
+	if lock.Owner != maintenanceOwner || lock.Reason != "正在恢复数据库" {
+		t.Errorf("维护锁未被接管: %+v", lock)
+	}
+}
+
+// TestMaintenance_DrainsBackgroundWork 测试进入维护模式时等待执行中的后台任务
+func TestMaintenance_DrainsBackgroundWork(t *testing.T) {
+	useTestConfigDB(t, &model.MaintenanceLockDB{})
+	withoutMaintenanceSettle(t)
+
+	done, ok := BeginBackgroundWork()
+	if !ok {
+		t.Fatal("应可执行后台任务")
+	}
+	entered := make(chan error, 1)
+	go func() { entered <- EnterMaintenance("正在恢复数据库") }()
+
+	select {
+	case err := <-entered:
+		t.Fatalf("后台任务结束前不应返回: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+	// 等待期间已拒绝新的后台任务
+	if _, ok := BeginBackgroundWork(); ok {
+		t.Error("等待期间不应开始新的后台任务")
+	}
+
+	done()
+	done() // 重复调用无影响
+	select {
+	case err := <-entered:
+		if err != nil {
+			t.Fatalf("进入维护模式失败: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("后台任务结束后应进入维护模式")
+	}
+	LeaveMaintenance()
+}
+
+// TestMaintenance_LocalOnly 测试配置数据库未初始化时只在本实例生效
+func TestMaintenance_LocalOnly(t *testing.T) {
+	old := model.ConfigDB
+	model.ConfigDB = nil
+	defer func() { model.ConfigDB = old }()
+
+	if err := EnterMaintenance("正在恢复数据库"); err != nil {
+		t.Fatalf("进入维护模式失败: %v", err)
+	}
+	if status := GetMaintenanceStatus(); !status.Active || status.Shared {
+		t.Errorf("维护状态错误: %+v", status)
+	}
+	LeaveMaintenance()
+	if GetMaintenanceStatus().Active {
+		t.Error("退出后不应处于维护模式")
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	"user-frontend/internal/model"
 	"user-frontend/internal/repository"
+
+	"gorm.io/gorm"
 )
 
 // ManualKamiService 手动卡密服务
@@ -254,6 +256,100 @@ func (s *ManualKamiService) MarkKamiSold(kamiID uint, orderID uint, orderNo stri
 	return nil
 }
 
+// GetOrderKamis 获取订单已分配的卡密（含已作废的）
+func (s *ManualKamiService) GetOrderKamis(orderID uint) ([]model.ManualKami, error) {
+	var kamis []model.ManualKami
+	err := s.repo.GetDB().Where("order_id = ?", orderID).Order("id ASC").Find(&kamis).Error
+	return kamis, err
+}
+
+// ReplaceSoldKami 为订单更换一张失效卡密
+// 旧卡密标记为已作废（不会回到卡密池），从卡密池原子领取一张新卡密替换到订单中
+// 参数：
+//   - order: 订单
+//   - oldKamiID: 需要更换的卡密ID（必须属于该订单且为已售出状态）
+//
+// 返回：
+//   - 新卡密
+//   - 错误信息
+func (s *ManualKamiService) ReplaceSoldKami(order *model.Order, oldKamiID uint) (*model.ManualKami, error) {
+	var newKami model.ManualKami
+
+	err := s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var oldKami model.ManualKami
+		if err := tx.Where("id = ? AND order_id = ?", oldKamiID, order.ID).First(&oldKami).Error; err != nil {
+			return errors.New("卡密不属于该订单")
+		}
+
+		// 条件更新防止并发重复更换
+		now := time.Now()
+		result := tx.Model(&model.ManualKami{}).
+			Where("id = ? AND status = ?", oldKami.ID, model.ManualKamiStatusSold).
+			Update("status", model.ManualKamiStatusInvalid)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("该卡密已被更换")
+		}
+
+		// 领取新卡密，被其他请求抢占时重试
+		// 每次重试前清空，否则 First 会把上一次读到的主键作为查询条件
+		for attempt := 0; ; attempt++ {
+			newKami = model.ManualKami{}
+			if err := tx.Where("product_id = ? AND status = ?", oldKami.ProductID, model.ManualKamiStatusAvailable).
+				Order("id ASC").First(&newKami).Error; err != nil {
+				return errors.New("卡密库存不足")
+			}
+			result := tx.Model(&model.ManualKami{}).
+				Where("id = ? AND status = ?", newKami.ID, model.ManualKamiStatusAvailable).
+				Updates(map[string]interface{}{
+					"status":   model.ManualKamiStatusSold,
+					"order_id": order.ID,
+					"order_no": order.OrderNo,
+					"sold_at":  now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				break
+			}
+			if attempt >= 3 {
+				return errors.New("卡密分配失败，请重试")
+			}
+		}
+		newKami.Status = model.ManualKamiStatusSold
+		newKami.OrderID = order.ID
+		newKami.OrderNo = order.OrderNo
+		newKami.SoldAt = &now
+
+		// 替换订单中的卡密内容
+		lines := strings.Split(order.KamiCode, "\n")
+		replaced := false
+		for i, line := range lines {
+			if strings.TrimSpace(line) == oldKami.KamiCode {
+				lines[i] = newKami.KamiCode
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			lines = append(lines, newKami.KamiCode)
+		}
+		order.KamiCode = strings.Join(lines, "\n")
+		return tx.Model(&model.Order{}).Where("id = ?", order.ID).Update("kami_code", order.KamiCode).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 更新商品库存
+	s.UpdateProductStock(newKami.ProductID)
+
+	return &newKami, nil
+}
+
 // GetKamiStats 获取商品的卡密统计
 // 参数：
 //   - productID: 商品ID
@@ -372,8 +468,17 @@ func (s *ManualKamiService) UpdateProductStock(productID uint) {
 
 	// 只更新手动卡密类型商品的库存
 	if product.ProductType == model.ProductTypeManual {
-		product.Stock = int(stats["available"])
-		s.repo.UpdateProduct(product)
+		stock := int(stats["available"])
+		if product.Stock == stock {
+			return
+		}
+		product.Stock = stock
+		if err := s.repo.UpdateProduct(product); err == nil {
+			invalidateCatalogCache()
+			if product.IsVariant() {
+				syncVariantParent(s.repo, product.ParentID)
+			}
+		}
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"runtime"
 	"time"
 
+	"user-frontend/internal/metrics"
 	"user-frontend/internal/model"
 	"user-frontend/internal/repository"
 )
@@ -69,6 +70,16 @@ type APIStats struct {
 	ErrorRate       float64 `json:"error_rate"`        // 错误率
 }
 
+// GetAPIStats 获取自启动以来的 API 统计（与 /metrics 中的请求指标同源）
+func (s *MonitorService) GetAPIStats() *APIStats {
+	total, avg, errorRate := metrics.APISnapshot()
+	return &APIStats{
+		TotalRequests:   total,
+		AvgResponseTime: avg,
+		ErrorRate:       errorRate,
+	}
+}
+
 // GetSystemInfo 获取系统信息
 // 返回：
 //   - 系统信息
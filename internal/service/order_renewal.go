@@ -0,0 +1,212 @@
+// Package service 提供业务逻辑服务
+// order_renewal.go - 续费订单（续费下单、支付后延长原订单有效期）
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"user-frontend/internal/metrics"
+	"user-frontend/internal/model"
+	"user-frontend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// MaxRenewalPeriods 单次续费最多购买的周期数
+const MaxRenewalPeriods = 12
+
+// CreateRenewalParams 创建续费订单参数
+type CreateRenewalParams struct {
+	UserID   uint
+	Username string
+	OrderNo  string // 要续费的订单号（原始订单或其续费订单均可）
+	Periods  int    // 续费周期数（每周期为商品时长），默认1
+	ClientIP string
+	Currency string // 期望支付币种（为空则使用基础货币）
+}
+
+// RenewalUnitPrice 计算商品的续费单价
+// 优先使用续费单价，未设置时按售价和续费折扣计算
+func RenewalUnitPrice(product *model.Product) float64 {
+	if product.RenewalPrice > 0 {
+		return product.RenewalPrice
+	}
+	price := product.Price
+	if product.RenewalDiscount > 0 && product.RenewalDiscount < 100 {
+		price = price * (100 - product.RenewalDiscount) / 100
+	}
+	return roundAmount(price)
+}
+
+// addOrderDuration 在指定时间上增加时长
+func addOrderDuration(t time.Time, duration int, durationUnit string) time.Time {
+	switch durationUnit {
+	case "天":
+		return t.AddDate(0, 0, duration)
+	case "周":
+		return t.AddDate(0, 0, duration*7)
+	case "月":
+		return t.AddDate(0, duration, 0)
+	case "年":
+		return t.AddDate(duration, 0, 0)
+	default:
+		return t.AddDate(0, 0, duration)
+	}
+}
+
+// OrderExpireTime 获取订单的到期时间
+// 已记录到期时间（续费过）的订单直接返回，否则按支付时间加商品时长计算
+func OrderExpireTime(order *model.Order) time.Time {
+	if order.ExpireAt != nil {
+		return *order.ExpireAt
+	}
+	if order.PaymentTime == nil {
+		return time.Now()
+	}
+	return addOrderDuration(*order.PaymentTime, order.Duration, order.DurationUnit)
+}
+
+// resolveRenewalRoot 获取续费链的原始购买订单
+func (s *OrderService) resolveRenewalRoot(order *model.Order) (*model.Order, error) {
+	if !order.IsRenewal() {
+		return order, nil
+	}
+	root, err := s.repo.GetOrderByID(order.RenewOrderID)
+	if err != nil {
+		return nil, errors.New("原始订单不存在")
+	}
+	return root, nil
+}
+
+// CreateRenewalOrder 创建续费订单
+// 续费订单购买与原订单相同的商品，支付后延长原订单的有效期，不分配新卡密
+// 同一原始订单存在待支付的续费订单时直接复用（避免重复点击续费链接产生多个订单）
+func (s *OrderService) CreateRenewalOrder(params *CreateRenewalParams) (*model.Order, error) {
+	order, err := s.repo.GetOrderByOrderNo(params.OrderNo)
+	if err != nil {
+		return nil, errors.New("订单不存在")
+	}
+	if order.UserID != params.UserID {
+		return nil, errors.New("无权操作此订单")
+	}
+
+	root, err := s.resolveRenewalRoot(order)
+	if err != nil {
+		return nil, err
+	}
+	if root.Status != model.OrderStatusCompleted || root.KamiCode == "" {
+		return nil, errors.New("仅已完成的订单可以续费")
+	}
+
+	product, err := s.repo.GetProductByID(root.ProductID)
+	if err != nil {
+		return nil, errors.New("商品不存在")
+	}
+	if product.Status != 1 {
+		return nil, errors.New("商品已下架，无法续费")
+	}
+
+	periods := params.Periods
+	if periods < 1 {
+		periods = 1
+	}
+	if periods > MaxRenewalPeriods {
+		return nil, fmt.Errorf("单次最多续费 %d 个周期", MaxRenewalPeriods)
+	}
+
+	// 复用待支付的续费订单
+	var pending model.Order
+	err = s.repo.GetDB().Where("renew_order_id = ? AND order_type = ? AND status = ?",
+		root.ID, model.OrderTypeRenewal, model.OrderStatusPending).
+		Order("id DESC").First(&pending).Error
+	if err == nil && pending.Quantity == periods {
+		return &pending, nil
+	}
+
+	unitPrice := RenewalUnitPrice(product)
+	originalPrice := roundAmount(unitPrice * float64(periods))
+	renewal := &model.Order{
+		OrderNo:       utils.GenerateLocalOrderNo(),
+		UserID:        params.UserID,
+		Username:      params.Username,
+		ProductID:     product.ID,
+		ProductName:   product.Name,
+		Quantity:      periods, // 续费订单的数量表示续费周期数
+		OriginalPrice: originalPrice,
+		Price:         originalPrice,
+		Duration:      product.Duration,
+		DurationUnit:  product.DurationUnit,
+		OrderType:     model.OrderTypeRenewal,
+		RenewOrderID:  root.ID,
+		Status:        model.OrderStatusPending,
+		ClientIP:      params.ClientIP,
+		Remark:        "续费订单: " + root.OrderNo,
+	}
+
+	if err := s.lockOrderCurrency(renewal, params.Currency); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateOrder(renewal); err != nil {
+		return nil, err
+	}
+	metrics.RecordOrderCreated(renewal.OrderType)
+
+	return renewal, nil
+}
+
+// completeRenewal 完成续费订单
+// 新到期时间 = max(当前到期时间, 现在) + 时长 * 周期数，未过期时在原到期时间上顺延
+func (s *OrderService) completeRenewal(order *model.Order, paymentMethod, paymentNo string, paidAmount float64) (*model.Order, error) {
+	root, err := s.resolveRenewalRoot(order)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start := OrderExpireTime(root)
+	if start.Before(now) {
+		start = now
+	}
+
+	periods := order.Quantity
+	if periods < 1 {
+		periods = 1
+	}
+	expireAt := addOrderDuration(start, order.Duration*periods, order.DurationUnit)
+
+	order.Status = model.OrderStatusCompleted
+	order.PaymentMethod = paymentMethod
+	order.PaymentNo = paymentNo
+	order.PaymentTime = &now
+	order.PaidAmount = paidAmount
+	order.KamiCode = root.KamiCode // 续费沿用原卡密
+	order.ExpireAt = &expireAt
+
+	err = s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Order{}).Where("id = ?", root.ID).Update("expire_at", expireAt).Error; err != nil {
+			return err
+		}
+		// 清除旧到期时间的提醒记录，使新的到期时间可以重新提醒
+		return tx.Where("order_id = ?", root.ID).Delete(&model.RenewalReminder{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetRenewalOrders 获取原始订单的续费记录（按时间倒序，仅已完成）
+func (s *OrderService) GetRenewalOrders(rootOrderID uint) ([]model.Order, error) {
+	var orders []model.Order
+	err := s.repo.GetDB().Where("renew_order_id = ? AND order_type = ? AND status = ?",
+		rootOrderID, model.OrderTypeRenewal, model.OrderStatusCompleted).
+		Order("payment_time DESC").Find(&orders).Error
+	return orders, err
+}
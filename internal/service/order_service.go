@@ -1,14 +1,17 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/metrics"
 	"user-frontend/internal/model"
 	"user-frontend/internal/repository"
+	"user-frontend/internal/trace"
 	"user-frontend/internal/utils"
 )
 
@@ -17,6 +20,10 @@ type OrderService struct {
 	cfg           *config.Config
 	configSvc     *ConfigService
 	manualKamiSvc *ManualKamiService
+	currencySvc   *CurrencyService
+	routeSvc      *PaymentRouteService
+	pricingSvc    *PricingService
+	requestScope
 }
 
 func NewOrderService(repo *repository.Repository, cfg *config.Config) *OrderService {
@@ -26,6 +33,17 @@ func NewOrderService(repo *repository.Repository, cfg *config.Config) *OrderServ
 	}
 }
 
+// WithContext 返回绑定 ctx 的服务副本，数据库查询和日志会带上 ctx 中的请求ID
+// 只继承 ctx 中的值、不继承取消：支付回调处理扣库存、分配卡密和更新订单不在同一事务内，
+// 客户端或网关中途断开时若随请求取消，会留下已售出卡密但订单未支付的状态，网关重试时重复分配
+func (s *OrderService) WithContext(ctx context.Context) *OrderService {
+	ctx = context.WithoutCancel(ctx)
+	clone := *s
+	clone.ctx = ctx
+	clone.repo = s.repo.WithContext(ctx)
+	return &clone
+}
+
 // SetConfigService 设置配置服务
 func (s *OrderService) SetConfigService(configSvc *ConfigService) {
 	s.configSvc = configSvc
@@ -36,6 +54,21 @@ func (s *OrderService) SetManualKamiService(manualKamiSvc *ManualKamiService) {
 	s.manualKamiSvc = manualKamiSvc
 }
 
+// SetCurrencyService 设置多币种服务
+func (s *OrderService) SetCurrencyService(currencySvc *CurrencyService) {
+	s.currencySvc = currencySvc
+}
+
+// SetPaymentRouteService 设置支付方式路由服务
+func (s *OrderService) SetPaymentRouteService(routeSvc *PaymentRouteService) {
+	s.routeSvc = routeSvc
+}
+
+// SetPricingService 设置价格服务（阶梯价与用户价格等级）
+func (s *OrderService) SetPricingService(pricingSvc *PricingService) {
+	s.pricingSvc = pricingSvc
+}
+
 // CreateOrderParams 创建订单参数
 type CreateOrderParams struct {
 	UserID     uint
@@ -47,6 +80,7 @@ type CreateOrderParams struct {
 	CouponCode string  // 优惠券码
 	CouponID   uint    // 优惠券ID
 	Discount   float64 // 优惠金额
+	Currency   string  // 期望支付币种（为空则使用基础货币）
 }
 
 // CreateOrder 创建订单（单个数量，向后兼容）
@@ -89,7 +123,7 @@ func (s *OrderService) CreateOrderWithRemark(userID uint, username string, produ
 
 // CreateOrderWithParams 创建订单（完整参数）
 // 安全特性：
-//   - 锁定商品原价到订单
+//   - 按阶梯价与用户价格等级计算成交单价，锁定到订单原价
 //   - 记录优惠券信息
 //   - 计算实际应付金额
 //   - 支持多数量购买
@@ -103,6 +137,9 @@ func (s *OrderService) CreateOrderWithParams(params *CreateOrderParams) (*model.
 	if product.Status != 1 {
 		return nil, errors.New("商品已下架")
 	}
+	if err := checkPurchasable(s.repo, product); err != nil {
+		return nil, err
+	}
 
 	// 数量校验
 	quantity := params.Quantity
@@ -121,9 +158,15 @@ func (s *OrderService) CreateOrderWithParams(params *CreateOrderParams) (*model.
 	// 生成订单号（本地生成）
 	orderNo := utils.GenerateLocalOrderNo()
 
-	// 计算实际应付金额（单价 * 数量 - 优惠）
+	// 计算实际应付金额（成交单价 * 数量 - 优惠）
 	unitPrice := product.Price
-	originalPrice := unitPrice * float64(quantity)
+	priceLevel := ""
+	if s.pricingSvc != nil {
+		quote := s.pricingSvc.Quote(product, s.pricingSvc.UserLevel(params.UserID), quantity)
+		unitPrice = quote.UnitPrice
+		priceLevel = quote.Level
+	}
+	originalPrice := roundAmount(unitPrice * float64(quantity))
 	discountAmount := params.Discount
 	if discountAmount < 0 {
 		discountAmount = 0
@@ -141,25 +184,141 @@ func (s *OrderService) CreateOrderWithParams(params *CreateOrderParams) (*model.
 		ProductID:      params.ProductID,
 		ProductName:    product.Name,
 		Quantity:       quantity,       // 购买数量
-		OriginalPrice:  originalPrice,  // 锁定原价（单价*数量）
+		ListPrice:      product.Price,  // 商品标价
+		UnitPrice:      unitPrice,      // 成交单价
+		PriceLevel:     priceLevel,     // 用户价格等级
+		OriginalPrice:  originalPrice,  // 锁定原价（成交单价*数量）
 		DiscountAmount: discountAmount, // 优惠金额
 		Price:          finalPrice,     // 实际应付
 		CouponID:       params.CouponID,
 		CouponCode:     params.CouponCode,
 		Duration:       product.Duration,
 		DurationUnit:   product.DurationUnit,
+		OrderType:      model.OrderTypeNormal,
 		Status:         model.OrderStatusPending,
 		ClientIP:       params.ClientIP,
 		Remark:         params.Remark,
 	}
 
+	// 锁定订单币种与汇率
+	if err := s.lockOrderCurrency(order, params.Currency); err != nil {
+		return nil, err
+	}
+
 	if err := s.repo.CreateOrder(order); err != nil {
 		return nil, err
 	}
 
+	metrics.RecordOrderCreated(order.OrderType)
 	return order, nil
 }
 
+// lockOrderCurrency 锁定订单的基础货币、支付币种和汇率
+func (s *OrderService) lockOrderCurrency(order *model.Order, payCurrency string) error {
+	if s.currencySvc == nil {
+		return nil
+	}
+
+	if order.Currency == "" {
+		order.Currency = s.currencySvc.GetBaseCurrency()
+	}
+	payCurrency = NormalizeCurrency(payCurrency)
+	if payCurrency == "" {
+		payCurrency = order.Currency
+	}
+
+	payAmount, rate, err := s.currencySvc.QuoteOrder(order, payCurrency)
+	if err != nil {
+		return err
+	}
+	order.PayCurrency = payCurrency
+	order.PayAmount = payAmount
+	order.ExchangeRate = rate
+	return nil
+}
+
+// LockPaymentCurrency 按支付方式重新锁定待支付订单的支付币种和汇率
+// 例如 PayPal/Stripe 以美元/欧元扣款，支付宝/微信以人民币扣款
+// 返回锁定后的订单（已支付订单或币种未变化时原样返回）
+func (s *OrderService) LockPaymentCurrency(order *model.Order, paymentMethod string) (*model.Order, error) {
+	if s.currencySvc == nil || order.Status != model.OrderStatusPending {
+		return order, nil
+	}
+
+	payCurrency := s.currencySvc.PaymentMethodCurrency(paymentMethod)
+	if order.PayCurrency == payCurrency && order.PayAmount > 0 {
+		return order, nil
+	}
+
+	if err := s.lockOrderCurrency(order, payCurrency); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateOrder(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// BuildPaymentRouteContext 构建订单的支付路由上下文
+// 金额不含已锁定的支付手续费，分类和用户等级从商品与用户信息读取
+func (s *OrderService) BuildPaymentRouteContext(order *model.Order, country string) *PaymentRouteContext {
+	ctx := &PaymentRouteContext{
+		Amount:  order.Price - order.PaymentFee,
+		Country: strings.ToUpper(country),
+	}
+	if product, err := s.repo.GetProductByID(order.ProductID); err == nil {
+		ctx.CategoryID = product.CategoryID
+	}
+	if order.UserID > 0 {
+		if user, err := s.repo.GetUserByID(order.UserID); err == nil {
+			ctx.UserLevel = user.GetLevel()
+		}
+	}
+	return ctx
+}
+
+// ApplyPaymentMethod 按支付方式校验路由规则、锁定手续费，并重新锁定支付币种
+// 手续费作为独立金额记录在订单上，Price = 原价 - 优惠 + 手续费；切换支付方式时按新方式重新计算
+// 参数：
+//   - order: 待支付订单
+//   - paymentMethod: 支付方式
+//   - country: 客户端国家代码（用于路由规则匹配，可为空）
+// 返回：
+//   - 锁定后的订单
+//   - 错误信息
+func (s *OrderService) ApplyPaymentMethod(order *model.Order, paymentMethod, country string) (*model.Order, error) {
+	if order.Status != model.OrderStatusPending {
+		return order, nil
+	}
+
+	if s.routeSvc != nil {
+		method := model.NormalizePaymentMethod(paymentMethod)
+		ctx := s.BuildPaymentRouteContext(order, country)
+		if !s.routeSvc.IsMethodAllowed(ctx, method) {
+			return nil, errors.New("该订单不支持此支付方式")
+		}
+
+		fee := s.routeSvc.CalculateFee(method, ctx.Amount)
+		if fee != order.PaymentFee || (fee != 0 && order.FeeMethod != method) {
+			order.Price = roundAmount(ctx.Amount + fee)
+			order.PaymentFee = fee
+			order.FeeMethod = method
+			if fee == 0 {
+				order.FeeMethod = ""
+			}
+			// 应付金额变化，需按新金额重新报价
+			order.PayAmount = 0
+			if s.currencySvc == nil {
+				if err := s.repo.UpdateOrder(order); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return s.LockPaymentCurrency(order, paymentMethod)
+}
+
 // ProcessPaymentParams 处理支付参数
 type ProcessPaymentParams struct {
 	OrderNo       string
@@ -181,6 +340,29 @@ func (s *OrderService) ProcessPayment(orderNo, paymentMethod, paymentNo string)
 //   - paymentNo: 支付流水号
 //   - paidAmount: 实际支付金额（0表示跳过验证，用于无法获取金额的支付方式）
 func (s *OrderService) ProcessPaymentWithAmount(orderNo, paymentMethod, paymentNo string, paidAmount float64) (*model.Order, error) {
+	ctx, span := trace.Start(s.requestContext(), "order.process_payment", trace.KindInternal)
+	span.SetAttr("order.no", orderNo)
+	span.SetAttr("payment.method", paymentMethod)
+	span.SetAttr("payment.no", paymentNo)
+
+	order, err := s.WithContext(ctx).processPayment(orderNo, paymentMethod, paymentNo, paidAmount)
+	span.End(err)
+
+	logger := utils.WithContext(ctx)
+	fields := map[string]interface{}{"order_no": orderNo, "payment_method": paymentMethod, "payment_no": paymentNo}
+	if err != nil {
+		logger.Error("订单支付处理失败", err, fields)
+	} else {
+		logger.Info("订单支付处理完成", fields)
+	}
+	return order, err
+}
+
+// processPayment 处理支付：校验订单状态和金额、扣减库存并分配卡密
+func (s *OrderService) processPayment(orderNo, paymentMethod, paymentNo string, paidAmount float64) (*model.Order, error) {
+	// 指标按支付方式代码统计，各回调传入的名称（如“支付宝”“PayPal”）不一致
+	provider := model.NormalizePaymentMethod(paymentMethod)
+
 	order, err := s.repo.GetOrderByOrderNo(orderNo)
 	if err != nil {
 		return nil, errors.New("订单不存在")
@@ -191,17 +373,30 @@ func (s *OrderService) ProcessPaymentWithAmount(orderNo, paymentMethod, paymentN
 		if order.Status == model.OrderStatusCompleted {
 			return order, nil
 		}
+		metrics.RecordOrderPayment(provider, false)
 		return nil, errors.New("订单状态异常")
 	}
 
-	// 验证支付金额（如果提供了金额）
+	// 验证支付金额（如果提供了金额，按锁定的支付币种比较）
 	if paidAmount > 0 && !order.ValidatePaymentAmount(paidAmount) {
-		return nil, fmt.Errorf("支付金额不匹配，应付: %.2f, 实付: %.2f", order.Price, paidAmount)
+		metrics.RecordOrderPayment(provider, false)
+		return nil, fmt.Errorf("支付金额不匹配，应付: %.2f %s, 实付: %.2f", order.GetPayAmount(), order.PayCurrency, paidAmount)
+	}
+
+	// 续费订单：延长原订单有效期，不扣库存、不分配新卡密
+	if order.IsRenewal() {
+		renewed, err := s.completeRenewal(order, paymentMethod, paymentNo, paidAmount)
+		metrics.RecordOrderFulfilment(provider, err == nil)
+		if err == nil {
+			metrics.RecordOrderPayment(provider, true)
+		}
+		return renewed, err
 	}
 
 	// 获取商品信息
 	product, _ := s.repo.GetProductByID(order.ProductID)
 	if product == nil {
+		metrics.RecordOrderFulfilment(provider, false)
 		return nil, errors.New("商品不存在")
 	}
 
@@ -214,9 +409,11 @@ func (s *OrderService) ProcessPaymentWithAmount(orderNo, paymentMethod, paymentN
 	if product.Stock != -1 {
 		affected, err := s.repo.DecrementProductStock(product.ID, quantity)
 		if err != nil {
+			metrics.RecordOrderFulfilment(provider, false)
 			return nil, errors.New("库存扣减失败")
 		}
 		if affected == 0 {
+			metrics.RecordOrderFulfilment(provider, false)
 			return nil, errors.New("商品库存不足，请联系客服处理")
 		}
 	}
@@ -231,11 +428,17 @@ func (s *OrderService) ProcessPaymentWithAmount(orderNo, paymentMethod, paymentN
 			if product.Stock != -1 {
 				s.repo.IncrementProductStock(product.ID, quantity)
 			}
+			metrics.RecordOrderFulfilment(provider, false)
 			return nil, err
 		}
 		kamiCodes = append(kamiCodes, kamiCode)
 	}
 
+	// 规格商品库存变化后同步父商品展示库存
+	if product.IsVariant() && product.Stock != -1 {
+		syncVariantParent(s.repo, product.ParentID)
+	}
+
 	// 更新订单状态
 	now := time.Now()
 	order.Status = model.OrderStatusCompleted
@@ -247,9 +450,12 @@ func (s *OrderService) ProcessPaymentWithAmount(orderNo, paymentMethod, paymentN
 	order.KamiCode = strings.Join(kamiCodes, "\n")
 
 	if err := s.repo.UpdateOrder(order); err != nil {
+		metrics.RecordOrderFulfilment(provider, false)
 		return nil, err
 	}
 
+	metrics.RecordOrderFulfilment(provider, true)
+	metrics.RecordOrderPayment(provider, true)
 	return order, nil
 }
 
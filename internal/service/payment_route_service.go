@@ -0,0 +1,223 @@
+// Package service 提供业务逻辑服务
+// payment_route_service.go - 支付方式路由规则与手续费服务
+package service
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+)
+
+// PaymentRouteService 支付方式路由服务
+// 根据订单金额、商品分类、用户等级和客户端国家决定可用的支付方式，并计算各方式的手续费
+type PaymentRouteService struct {
+	repo *repository.Repository
+}
+
+// NewPaymentRouteService 创建支付方式路由服务
+func NewPaymentRouteService(repo *repository.Repository) *PaymentRouteService {
+	return &PaymentRouteService{repo: repo}
+}
+
+// PaymentRouteContext 路由匹配上下文
+type PaymentRouteContext struct {
+	Amount     float64 // 订单金额（基础货币，不含支付手续费）
+	CategoryID uint    // 商品分类ID（0表示未知）
+	UserLevel  string  // 用户等级（为空表示游客/未知）
+	Country    string  // 客户端国家代码
+}
+
+// ==================== 路由规则 ====================
+
+// GetRules 获取所有路由规则（按优先级排序）
+func (s *PaymentRouteService) GetRules() ([]model.PaymentRule, error) {
+	var rules []model.PaymentRule
+	err := s.repo.GetDB().Order("priority DESC, id ASC").Find(&rules).Error
+	return rules, err
+}
+
+// SaveRule 新增或更新路由规则
+func (s *PaymentRouteService) SaveRule(rule *model.PaymentRule) error {
+	rule.Method = model.NormalizePaymentMethod(rule.Method)
+	if rule.Method == "" {
+		return errors.New("支付方式不能为空")
+	}
+	if rule.Action != model.PaymentRuleShow && rule.Action != model.PaymentRuleHide {
+		return errors.New("无效的规则动作")
+	}
+	if rule.MaxAmount > 0 && rule.MinAmount > rule.MaxAmount {
+		return errors.New("金额下限不能大于上限")
+	}
+	rule.Countries = strings.ToUpper(rule.Countries)
+	rule.UserLevels = strings.ToLower(rule.UserLevels)
+
+	if rule.ID > 0 {
+		return s.repo.GetDB().Save(rule).Error
+	}
+	return s.repo.GetDB().Create(rule).Error
+}
+
+// DeleteRule 删除路由规则
+func (s *PaymentRouteService) DeleteRule(id uint) error {
+	return s.repo.GetDB().Delete(&model.PaymentRule{}, id).Error
+}
+
+// ruleMatches 判断规则条件是否命中
+func ruleMatches(rule *model.PaymentRule, ctx *PaymentRouteContext) bool {
+	if rule.MinAmount > 0 && ctx.Amount < rule.MinAmount {
+		return false
+	}
+	if rule.MaxAmount > 0 && ctx.Amount > rule.MaxAmount {
+		return false
+	}
+	if ids := model.SplitList(rule.CategoryIDs); len(ids) > 0 {
+		if !containsFold(ids, strconv.FormatUint(uint64(ctx.CategoryID), 10)) {
+			return false
+		}
+	}
+	if levels := model.SplitList(rule.UserLevels); len(levels) > 0 && !containsFold(levels, ctx.UserLevel) {
+		return false
+	}
+	if countries := model.SplitList(rule.Countries); len(countries) > 0 && !containsFold(countries, ctx.Country) {
+		return false
+	}
+	return true
+}
+
+// containsFold 判断列表是否包含指定值（不区分大小写）
+func containsFold(list []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterMethods 按路由规则过滤支付方式
+// 参数：
+//   - ctx: 路由上下文
+//   - methods: 已启用的支付方式代码
+//
+// 返回：
+//   - 支付方式是否可用
+func (s *PaymentRouteService) FilterMethods(ctx *PaymentRouteContext, methods []string) map[string]bool {
+	result := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		result[m] = true
+	}
+
+	var rules []model.PaymentRule
+	s.repo.GetDB().Where("enabled = ?", true).Order("priority DESC, id ASC").Find(&rules)
+
+	decided := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		if !ruleMatches(rule, ctx) {
+			continue
+		}
+		for _, m := range methods {
+			if decided[m] || (rule.Method != model.PayMethodAll && rule.Method != m) {
+				continue
+			}
+			decided[m] = true
+			result[m] = rule.Action == model.PaymentRuleShow
+		}
+	}
+	return result
+}
+
+// IsMethodAllowed 判断指定支付方式在当前上下文下是否可用
+func (s *PaymentRouteService) IsMethodAllowed(ctx *PaymentRouteContext, method string) bool {
+	method = model.NormalizePaymentMethod(method)
+	return s.FilterMethods(ctx, []string{method})[method]
+}
+
+// ==================== 手续费 ====================
+
+// GetFees 获取所有支付方式手续费配置
+func (s *PaymentRouteService) GetFees() ([]model.PaymentFee, error) {
+	var fees []model.PaymentFee
+	err := s.repo.GetDB().Order("method ASC").Find(&fees).Error
+	return fees, err
+}
+
+// SaveFee 新增或更新支付方式手续费（每种支付方式一条）
+func (s *PaymentRouteService) SaveFee(fee *model.PaymentFee) error {
+	fee.Method = model.NormalizePaymentMethod(fee.Method)
+	if fee.Method == "" || fee.Method == model.PayMethodAll {
+		return errors.New("无效的支付方式")
+	}
+	if fee.FeeType != model.PaymentFeePercent && fee.FeeType != model.PaymentFeeFixed {
+		return errors.New("无效的手续费类型")
+	}
+	if fee.FeeType == model.PaymentFeePercent && (fee.Value <= -100 || fee.Value > 100) {
+		return errors.New("手续费比例必须在 -100 到 100 之间")
+	}
+
+	var existing model.PaymentFee
+	if err := s.repo.GetDB().Where("method = ?", fee.Method).First(&existing).Error; err == nil {
+		fee.ID = existing.ID
+		fee.CreatedAt = existing.CreatedAt
+	}
+	return s.repo.GetDB().Save(fee).Error
+}
+
+// DeleteFee 删除支付方式手续费
+func (s *PaymentRouteService) DeleteFee(method string) error {
+	return s.repo.GetDB().Where("method = ?", model.NormalizePaymentMethod(method)).Delete(&model.PaymentFee{}).Error
+}
+
+// GetFee 获取支付方式的手续费配置（未配置或未启用返回nil）
+func (s *PaymentRouteService) GetFee(method string) *model.PaymentFee {
+	var fee model.PaymentFee
+	if err := s.repo.GetDB().Where("method = ? AND enabled = ?", model.NormalizePaymentMethod(method), true).First(&fee).Error; err != nil {
+		return nil
+	}
+	return &fee
+}
+
+// CalculateFee 计算支付方式手续费
+// 参数：
+//   - method: 支付方式
+//   - amount: 订单金额（基础货币，不含手续费）
+//
+// 返回：
+//   - 手续费（正数为附加费，负数为优惠，保留两位小数）
+func (s *PaymentRouteService) CalculateFee(method string, amount float64) float64 {
+	fee := s.GetFee(method)
+	if fee == nil || amount <= 0 {
+		return 0
+	}
+	return calculateFee(fee, amount)
+}
+
+// calculateFee 按手续费配置计算金额
+func calculateFee(fee *model.PaymentFee, amount float64) float64 {
+	var value float64
+	switch fee.FeeType {
+	case model.PaymentFeePercent:
+		value = amount * fee.Value / 100
+		if fee.MinFee > 0 && value > 0 && value < fee.MinFee {
+			value = fee.MinFee
+		}
+	case model.PaymentFeeFixed:
+		value = fee.Value
+	}
+
+	if fee.MaxFee > 0 && math.Abs(value) > fee.MaxFee {
+		value = math.Copysign(fee.MaxFee, value)
+	}
+	// 优惠不能超过订单金额
+	if value < -amount {
+		value = -amount
+	}
+	return roundAmount(value)
+}
@@ -2,21 +2,25 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/trace"
 )
 
 // PayPalService PayPal支付服务
 type PayPalService struct {
 	config     *config.PayPalConfig
 	httpClient *http.Client
+	requestScope
 }
 
 // PayPalOrder PayPal订单响应
@@ -57,16 +61,30 @@ type PayPalCaptureResponse struct {
 // NewPayPalService 创建PayPal服务
 func NewPayPalService(cfg *config.PayPalConfig) *PayPalService {
 	return &PayPalService{
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		config:     cfg,
+		httpClient: trace.NewHTTPClient(30 * time.Second),
 	}
 }
 
+// WithContext 返回绑定 ctx 的服务副本，网关请求会携带 ctx 中的请求ID
+func (s *PayPalService) WithContext(ctx context.Context) *PayPalService {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
 // getBaseURL 获取API基础URL
 func (s *PayPalService) getBaseURL() string {
-	if s.config.Sandbox {
+	return PayPalAPIBaseURL(s.config)
+}
+
+// PayPalAPIBaseURL 获取PayPal API基础URL
+// 优先使用配置的自定义地址（沙箱模拟网关），否则按 Sandbox 选择官方地址
+func PayPalAPIBaseURL(cfg *config.PayPalConfig) string {
+	if cfg.APIBaseURL != "" {
+		return strings.TrimRight(cfg.APIBaseURL, "/")
+	}
+	if cfg.Sandbox {
 		return "https://api-m.sandbox.paypal.com"
 	}
 	return "https://api-m.paypal.com"
@@ -76,7 +94,7 @@ func (s *PayPalService) getBaseURL() string {
 func (s *PayPalService) getAccessToken() (string, error) {
 	url := s.getBaseURL() + "/v1/oauth2/token"
 
-	req, err := http.NewRequest("POST", url, bytes.NewBufferString("grant_type=client_credentials"))
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", url, bytes.NewBufferString("grant_type=client_credentials"))
 	if err != nil {
 		return "", err
 	}
@@ -153,7 +171,7 @@ func (s *PayPalService) CreateOrder(orderNo string, amount float64, description
 	jsonData, _ := json.Marshal(orderData)
 
 	url := s.getBaseURL() + "/v2/checkout/orders"
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -194,7 +212,7 @@ func (s *PayPalService) CaptureOrder(paypalOrderID string) (*PayPalCaptureRespon
 	}
 
 	url := s.getBaseURL() + "/v2/checkout/orders/" + paypalOrderID + "/capture"
-	req, err := http.NewRequest("POST", url, nil)
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -234,7 +252,7 @@ func (s *PayPalService) GetOrderDetails(paypalOrderID string) (*PayPalOrder, err
 	}
 
 	url := s.getBaseURL() + "/v2/checkout/orders/" + paypalOrderID
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(s.requestContext(), "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,296 @@
+// Package service 提供业务逻辑服务
+// pricing_service.go - 批发阶梯价与用户价格等级服务
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// 阶梯价限制
+const maxPriceTiersPerProduct = 20
+
+// priceLevels 可设置折扣的用户价格等级（与用户等级一致）
+var priceLevels = []string{model.UserLevelRetail, model.UserLevelVIP, model.UserLevelReseller}
+
+// PricingService 价格服务
+// 单价计算规则：
+//   - 通用阶梯价（Level 为空）：取购买数量可达到的最低阶梯单价，未命中时为商品标价，再叠加用户等级折扣
+//   - 等级专属阶梯价：取该等级购买数量可达到的最低单价，不叠加等级折扣
+//   - 最终单价取两者较低者，且不高于商品标价
+type PricingService struct {
+	repo *repository.Repository
+}
+
+// NewPricingService 创建价格服务
+func NewPricingService(repo *repository.Repository) *PricingService {
+	return &PricingService{repo: repo}
+}
+
+// PriceQuote 报价结果
+type PriceQuote struct {
+	Level           string  `json:"level"`                       // 用户价格等级
+	Quantity        int     `json:"quantity"`                    // 购买数量
+	ListPrice       float64 `json:"list_price"`                  // 商品标价（单价）
+	UnitPrice       float64 `json:"unit_price"`                  // 成交单价
+	Total           float64 `json:"total"`                       // 小计（成交单价*数量）
+	TierMinQuantity int     `json:"tier_min_quantity,omitempty"` // 命中阶梯的起购数量（0表示未命中阶梯）
+}
+
+// PriceLadderStep 价格阶梯（购买数量达到 MinQuantity 时的单价）
+type PriceLadderStep struct {
+	MinQuantity int     `json:"min_quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// ==================== 价格等级 ====================
+
+// GetPriceLevels 获取各用户等级的折扣设置（未设置的等级折扣为0）
+func (s *PricingService) GetPriceLevels() ([]model.PriceLevel, error) {
+	var saved []model.PriceLevel
+	if err := s.repo.GetDB().Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	byLevel := make(map[string]model.PriceLevel, len(saved))
+	for _, l := range saved {
+		byLevel[l.Level] = l
+	}
+
+	result := make([]model.PriceLevel, 0, len(priceLevels))
+	for _, level := range priceLevels {
+		if l, ok := byLevel[level]; ok {
+			result = append(result, l)
+		} else {
+			result = append(result, model.PriceLevel{Level: level})
+		}
+	}
+	return result, nil
+}
+
+// SetLevelDiscount 设置用户等级折扣百分比
+func (s *PricingService) SetLevelDiscount(level string, discount float64) error {
+	level = normalizePriceLevel(level)
+	if level == "" {
+		return errors.New("无效的用户等级")
+	}
+	if discount < 0 || discount >= 100 {
+		return errors.New("折扣必须在0到100之间")
+	}
+
+	var existing model.PriceLevel
+	err := s.repo.GetDB().Where("level = ?", level).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.repo.GetDB().Create(&model.PriceLevel{Level: level, Discount: discount}).Error
+	}
+	if err != nil {
+		return err
+	}
+	existing.Discount = discount
+	return s.repo.GetDB().Save(&existing).Error
+}
+
+// levelDiscount 获取等级折扣百分比
+func (s *PricingService) levelDiscount(level string) float64 {
+	var l model.PriceLevel
+	if err := s.repo.GetDB().Where("level = ?", level).First(&l).Error; err != nil {
+		return 0
+	}
+	return l.Discount
+}
+
+// normalizePriceLevel 规范化用户等级，空值视为零售，无效等级返回空字符串
+func normalizePriceLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" {
+		return model.UserLevelRetail
+	}
+	for _, l := range priceLevels {
+		if l == level {
+			return level
+		}
+	}
+	return ""
+}
+
+// UserLevel 获取用户的价格等级（游客或用户不存在时为零售）
+func (s *PricingService) UserLevel(userID uint) string {
+	if userID == 0 {
+		return model.UserLevelRetail
+	}
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return model.UserLevelRetail
+	}
+	return user.GetLevel()
+}
+
+// ==================== 阶梯价 ====================
+
+// GetTiers 获取商品的阶梯价
+func (s *PricingService) GetTiers(productID uint) ([]model.ProductPriceTier, error) {
+	var tiers []model.ProductPriceTier
+	err := s.repo.GetDB().Where("product_id = ?", productID).
+		Order("level ASC, min_quantity ASC").Find(&tiers).Error
+	return tiers, err
+}
+
+// SaveTiers 整体替换商品的阶梯价
+func (s *PricingService) SaveTiers(productID uint, tiers []model.ProductPriceTier) error {
+	if _, err := s.repo.GetProductByID(productID); err != nil {
+		return errors.New("商品不存在")
+	}
+	if len(tiers) > maxPriceTiersPerProduct {
+		return fmt.Errorf("阶梯价最多 %d 条", maxPriceTiersPerProduct)
+	}
+
+	seen := map[string]bool{}
+	for i := range tiers {
+		t := &tiers[i]
+		t.ID = 0
+		t.ProductID = productID
+		if t.Level != "" {
+			t.Level = normalizePriceLevel(t.Level)
+			if t.Level == "" {
+				return errors.New("无效的用户等级")
+			}
+		}
+		if t.MinQuantity < 1 {
+			return errors.New("起购数量必须大于0")
+		}
+		if t.Price < 0 {
+			return errors.New("阶梯单价不能为负数")
+		}
+		key := fmt.Sprintf("%s:%d", t.Level, t.MinQuantity)
+		if seen[key] {
+			return fmt.Errorf("起购数量 %d 重复", t.MinQuantity)
+		}
+		seen[key] = true
+	}
+
+	return s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&model.ProductPriceTier{}).Error; err != nil {
+			return err
+		}
+		if len(tiers) == 0 {
+			return nil
+		}
+		return tx.Create(&tiers).Error
+	})
+}
+
+// ==================== 报价 ====================
+
+// Quote 计算指定用户等级、购买数量下的商品单价
+func (s *PricingService) Quote(product *model.Product, level string, quantity int) *PriceQuote {
+	if quantity < 1 {
+		quantity = 1
+	}
+	if level = normalizePriceLevel(level); level == "" {
+		level = model.UserLevelRetail
+	}
+	tiers, _ := s.GetTiers(product.ID)
+	unit, tierMin := resolveUnitPrice(product.Price, tiers, s.levelDiscount(level), level, quantity)
+	return &PriceQuote{
+		Level:           level,
+		Quantity:        quantity,
+		ListPrice:       product.Price,
+		UnitPrice:       unit,
+		Total:           roundAmount(unit * float64(quantity)),
+		TierMinQuantity: tierMin,
+	}
+}
+
+// Ladder 获取指定用户等级可见的价格阶梯（从 1 件起，仅保留单价发生变化的阶梯）
+func (s *PricingService) Ladder(product *model.Product, level string) []PriceLadderStep {
+	if level = normalizePriceLevel(level); level == "" {
+		level = model.UserLevelRetail
+	}
+	tiers, _ := s.GetTiers(product.ID)
+	discount := s.levelDiscount(level)
+
+	quantities := []int{1}
+	for _, t := range tiers {
+		if t.Level == "" || t.Level == level {
+			quantities = append(quantities, t.MinQuantity)
+		}
+	}
+	sort.Ints(quantities)
+
+	steps := []PriceLadderStep{}
+	for _, q := range quantities {
+		unit, _ := resolveUnitPrice(product.Price, tiers, discount, level, q)
+		if len(steps) > 0 && (steps[len(steps)-1].MinQuantity == q || steps[len(steps)-1].UnitPrice == unit) {
+			continue
+		}
+		steps = append(steps, PriceLadderStep{MinQuantity: q, UnitPrice: unit})
+	}
+	return steps
+}
+
+// UnitPrices 批量计算商品在指定用户等级下的单件价格（用于商品列表）
+func (s *PricingService) UnitPrices(products []model.Product, level string) map[uint]float64 {
+	result := make(map[uint]float64, len(products))
+	if len(products) == 0 {
+		return result
+	}
+	if level = normalizePriceLevel(level); level == "" {
+		level = model.UserLevelRetail
+	}
+
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	var tiers []model.ProductPriceTier
+	s.repo.GetDB().Where("product_id IN ? AND min_quantity <= ?", ids, 1).Find(&tiers)
+	byProduct := make(map[uint][]model.ProductPriceTier)
+	for _, t := range tiers {
+		byProduct[t.ProductID] = append(byProduct[t.ProductID], t)
+	}
+
+	discount := s.levelDiscount(level)
+	for _, p := range products {
+		result[p.ID], _ = resolveUnitPrice(p.Price, byProduct[p.ID], discount, level, 1)
+	}
+	return result
+}
+
+// resolveUnitPrice 按阶梯价与等级折扣计算单价，返回单价与命中阶梯的起购数量
+func resolveUnitPrice(listPrice float64, tiers []model.ProductPriceTier, discount float64, level string, quantity int) (float64, int) {
+	base, baseMin := listPrice, 0
+	special, specialMin := -1.0, 0
+	for _, t := range tiers {
+		if t.MinQuantity > quantity {
+			continue
+		}
+		switch t.Level {
+		case "":
+			if t.Price < base {
+				base, baseMin = t.Price, t.MinQuantity
+			}
+		case level:
+			if special < 0 || t.Price < special {
+				special, specialMin = t.Price, t.MinQuantity
+			}
+		}
+	}
+
+	unit, tierMin := base*(1-discount/100), baseMin
+	if special >= 0 && special < unit {
+		unit, tierMin = special, specialMin
+	}
+	if unit > listPrice {
+		unit, tierMin = listPrice, 0
+	}
+	if unit < 0 {
+		unit = 0
+	}
+	return roundAmount(unit), tierMin
+}
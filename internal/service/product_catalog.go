@@ -0,0 +1,521 @@
+// Package service 提供业务逻辑服务
+// product_catalog.go - 商品目录查询（关键词搜索、筛选、排序、分页、分面统计）
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"user-frontend/internal/cache"
+	"user-frontend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 商品目录排序方式
+const (
+	CatalogSortDefault   = "default"    // 后台排序值升序，新商品在前
+	CatalogSortPriceAsc  = "price_asc"  // 价格从低到高
+	CatalogSortPriceDesc = "price_desc" // 价格从高到低
+	CatalogSortSales     = "sales"      // 销量从高到低
+	CatalogSortRating    = "rating"     // 评分从高到低
+	CatalogSortNewest    = "newest"     // 最新上架
+)
+
+// 商品目录分页设置
+const (
+	catalogDefaultPageSize = 20
+	catalogMaxPageSize     = 100
+	catalogMaxKeywordTerms = 5
+)
+
+// ErrInvalidCatalogCursor 分页游标无效（格式错误或与排序方式不匹配）
+var ErrInvalidCatalogCursor = errors.New("无效的分页游标")
+
+// catalogPriceRanges 价格分面区间（Max 为 0 表示不设上限）
+var catalogPriceRanges = []struct{ Min, Max float64 }{
+	{0, 10}, {10, 50}, {50, 100}, {100, 500}, {500, 0},
+}
+
+// catalogSorts 排序方式对应的排序表达式与方向（同值时均按商品ID降序）
+var catalogSorts = map[string]struct {
+	expr string
+	desc bool
+}{
+	CatalogSortDefault:   {"products.sort_order", false},
+	CatalogSortPriceAsc:  {"products.price", false},
+	CatalogSortPriceDesc: {"products.price", true},
+	CatalogSortSales:     {"COALESCE(sales.sales_count, 0)", true},
+	CatalogSortRating:    {"COALESCE(rating.avg_rating, 0)", true},
+	CatalogSortNewest:    {"products.id", true},
+}
+
+// catalogSalesSQL 按商品统计销量（已支付、已完成订单），规格商品的销量计入父商品
+const catalogSalesSQL = "SELECT CASE WHEN p.parent_id > 0 THEN p.parent_id ELSE o.product_id END AS product_id, SUM(o.quantity) AS sales_count " +
+	"FROM orders o JOIN products p ON p.id = o.product_id " +
+	"WHERE o.status IN (1, 2) AND o.deleted_at IS NULL " +
+	"GROUP BY CASE WHEN p.parent_id > 0 THEN p.parent_id ELSE o.product_id END"
+
+// CatalogQuery 商品目录查询条件
+// 传入 Cursor 时使用游标分页（忽略 Page），否则使用页码分页
+type CatalogQuery struct {
+	Keyword    string  `json:"q"`
+	CategoryID uint    `json:"category_id"`
+	MinPrice   float64 `json:"min_price"`
+	MaxPrice   float64 `json:"max_price"` // 0 表示不限
+	InStock    bool    `json:"in_stock"`
+	Sort       string  `json:"sort"`
+	Page       int     `json:"page"`
+	PageSize   int     `json:"page_size"`
+	Cursor     string  `json:"cursor"`
+}
+
+// CatalogProduct 目录中的商品（附带销量与评分）
+type CatalogProduct struct {
+	model.Product
+	SalesCount  int64   `json:"sales_count"`
+	Rating      float64 `json:"rating"`
+	ReviewCount int64   `json:"review_count"`
+	InStock     bool    `json:"in_stock"`
+	UserPrice   float64 `json:"user_price,omitempty"` // 当前登录用户的单件价格（阶梯价与价格等级，游客不返回）
+}
+
+// CatalogCategoryFacet 分类分面
+type CatalogCategoryFacet struct {
+	CategoryID uint   `json:"category_id"`
+	Name       string `json:"name"`
+	Count      int64  `json:"count"`
+}
+
+// CatalogPriceFacet 价格区间分面
+type CatalogPriceFacet struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"` // 0 表示不设上限
+	Count int64   `json:"count"`
+}
+
+// CatalogFacets 分面统计（每个分面统计时不应用自身的筛选条件）
+type CatalogFacets struct {
+	Categories  []CatalogCategoryFacet `json:"categories"`
+	PriceRanges []CatalogPriceFacet    `json:"price_ranges"`
+	InStock     int64                  `json:"in_stock"`
+}
+
+// CatalogResult 商品目录查询结果
+type CatalogResult struct {
+	Products   []CatalogProduct `json:"products"`
+	Total      int64            `json:"total"`
+	Page       int              `json:"page,omitempty"`
+	PageSize   int              `json:"page_size"`
+	HasMore    bool             `json:"has_more"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Facets     CatalogFacets    `json:"facets"`
+}
+
+// catalogCursor 游标内容：排序方式、最后一条记录的排序值与ID
+type catalogCursor struct {
+	Sort  string  `json:"s"`
+	Value float64 `json:"v"`
+	ID    uint    `json:"id"`
+}
+
+// normalize 规范化查询条件
+func (q *CatalogQuery) normalize() {
+	q.Keyword = strings.Join(strings.Fields(q.Keyword), " ")
+	q.Sort = strings.ToLower(strings.TrimSpace(q.Sort))
+	if _, ok := catalogSorts[q.Sort]; !ok {
+		q.Sort = CatalogSortDefault
+	}
+	if q.MinPrice < 0 {
+		q.MinPrice = 0
+	}
+	if q.MaxPrice < 0 {
+		q.MaxPrice = 0
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = catalogDefaultPageSize
+	}
+	if q.PageSize > catalogMaxPageSize {
+		q.PageSize = catalogMaxPageSize
+	}
+	if q.Cursor != "" {
+		q.Page = 0
+	} else if q.Page < 1 {
+		q.Page = 1
+	}
+}
+
+// SearchCatalog 查询商品目录（仅上架商品）
+// 结果按目录版本号缓存，商品、分类或库存变更时版本号自增使缓存整体失效
+func (s *ProductService) SearchCatalog(q CatalogQuery) (*CatalogResult, error) {
+	q.normalize()
+
+	var cursor *catalogCursor
+	if q.Cursor != "" {
+		c, err := decodeCatalogCursor(q.Cursor)
+		if err != nil || c.Sort != q.Sort {
+			return nil, ErrInvalidCatalogCursor
+		}
+		cursor = c
+	}
+
+	cacheKey := catalogCacheKey(q)
+	if cacheKey != "" {
+		if result := getCatalogFromCache(cacheKey); result != nil {
+			return result, nil
+		}
+	}
+
+	db := s.repo.GetDB()
+	result := &CatalogResult{Page: q.Page, PageSize: q.PageSize, Products: []CatalogProduct{}}
+
+	if err := applyCatalogFilters(db.Model(&model.Product{}), q, "").Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var products []model.Product
+	if err := s.catalogPageQuery(db, q, cursor).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	if len(products) > q.PageSize {
+		products = products[:q.PageSize]
+		result.HasMore = true
+	}
+
+	items, err := s.attachCatalogStats(db, products)
+	if err != nil {
+		return nil, err
+	}
+	result.Products = items
+	if result.HasMore {
+		last := items[len(items)-1]
+		result.NextCursor = encodeCatalogCursor(catalogCursor{Sort: q.Sort, Value: catalogSortValue(q.Sort, last), ID: last.ID})
+	}
+
+	facets, err := s.catalogFacets(db, q)
+	if err != nil {
+		return nil, err
+	}
+	result.Facets = *facets
+
+	if cacheKey != "" {
+		setCatalogCache(cacheKey, result)
+	}
+	return result, nil
+}
+
+// applyCatalogFilters 应用筛选条件，except 指定需要跳过的筛选项（用于分面统计）
+func applyCatalogFilters(tx *gorm.DB, q CatalogQuery, except string) *gorm.DB {
+	// 规格商品随父商品展示，不单独出现在目录中
+	tx = tx.Where("products.status = ? AND products.parent_id = ?", 1, 0)
+
+	terms := strings.Fields(strings.ToLower(q.Keyword))
+	if len(terms) > catalogMaxKeywordTerms {
+		terms = terms[:catalogMaxKeywordTerms]
+	}
+	for _, term := range terms {
+		pattern := "%" + escapeLikePattern(term) + "%"
+		tx = tx.Where("(LOWER(products.name) LIKE ? ESCAPE '!' OR LOWER(products.description) LIKE ? ESCAPE '!' OR LOWER(products.tags) LIKE ? ESCAPE '!')",
+			pattern, pattern, pattern)
+	}
+
+	if except != "category" && q.CategoryID > 0 {
+		tx = tx.Where("products.category_id = ?", q.CategoryID)
+	}
+	if except != "price" {
+		if q.MinPrice > 0 {
+			tx = tx.Where("products.price >= ?", q.MinPrice)
+		}
+		if q.MaxPrice > 0 {
+			tx = tx.Where("products.price <= ?", q.MaxPrice)
+		}
+	}
+	if except != "stock" && q.InStock {
+		tx = tx.Where("products.stock != ?", 0)
+	}
+	return tx
+}
+
+// escapeLikePattern 转义 LIKE 通配符（转义字符为 !）
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// catalogPageQuery 构建当前页的查询（排序、游标条件、数量限制）
+func (s *ProductService) catalogPageQuery(db *gorm.DB, q CatalogQuery, cursor *catalogCursor) *gorm.DB {
+	sortDef := catalogSorts[q.Sort]
+	tx := applyCatalogFilters(db.Model(&model.Product{}).Select("products.*"), q, "")
+
+	switch q.Sort {
+	case CatalogSortSales:
+		tx = tx.Joins("LEFT JOIN (" + catalogSalesSQL + ") AS sales ON sales.product_id = products.id")
+	case CatalogSortRating:
+		tx = tx.Joins("LEFT JOIN (SELECT product_id, AVG(rating) AS avg_rating FROM product_reviews WHERE status = 1 AND deleted_at IS NULL GROUP BY product_id) AS rating ON rating.product_id = products.id")
+	}
+
+	dir, cmp := "ASC", ">"
+	if sortDef.desc {
+		dir, cmp = "DESC", "<"
+	}
+	if cursor != nil {
+		if sortDef.expr == "products.id" {
+			tx = tx.Where("products.id < ?", cursor.ID)
+		} else {
+			tx = tx.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND products.id < ?))", sortDef.expr, cmp, sortDef.expr),
+				cursor.Value, cursor.Value, cursor.ID)
+		}
+	}
+
+	if sortDef.expr == "products.id" {
+		tx = tx.Order("products.id " + dir)
+	} else {
+		tx = tx.Order(sortDef.expr + " " + dir).Order("products.id DESC")
+	}
+	if cursor == nil && q.Page > 1 {
+		tx = tx.Offset((q.Page - 1) * q.PageSize)
+	}
+	return tx.Limit(q.PageSize + 1)
+}
+
+// attachCatalogStats 为当前页商品附加销量与评分
+func (s *ProductService) attachCatalogStats(db *gorm.DB, products []model.Product) ([]CatalogProduct, error) {
+	items := make([]CatalogProduct, len(products))
+	if len(products) == 0 {
+		return items, nil
+	}
+
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+
+	var sales []struct {
+		ProductID  uint
+		SalesCount int64
+	}
+	if err := db.Raw("SELECT * FROM ("+catalogSalesSQL+") AS sales WHERE product_id IN ?", ids).
+		Scan(&sales).Error; err != nil {
+		return nil, err
+	}
+	salesMap := make(map[uint]int64, len(sales))
+	for _, row := range sales {
+		salesMap[row.ProductID] = row.SalesCount
+	}
+
+	var ratings []struct {
+		ProductID   uint
+		AvgRating   float64
+		ReviewCount int64
+	}
+	if err := db.Model(&model.ProductReview{}).
+		Select("product_id, AVG(rating) AS avg_rating, COUNT(*) AS review_count").
+		Where("product_id IN ? AND status = ?", ids, 1).
+		Group("product_id").Scan(&ratings).Error; err != nil {
+		return nil, err
+	}
+	ratingMap := make(map[uint]int, len(ratings))
+	for i, row := range ratings {
+		ratingMap[row.ProductID] = i
+	}
+
+	for i, p := range products {
+		items[i] = CatalogProduct{Product: p, SalesCount: salesMap[p.ID], InStock: p.Stock != 0}
+		if idx, ok := ratingMap[p.ID]; ok {
+			items[i].Rating = ratings[idx].AvgRating
+			items[i].ReviewCount = ratings[idx].ReviewCount
+		}
+	}
+	return items, nil
+}
+
+// catalogSortValue 取商品在当前排序方式下的排序值（用于生成游标）
+func catalogSortValue(sort string, p CatalogProduct) float64 {
+	switch sort {
+	case CatalogSortPriceAsc, CatalogSortPriceDesc:
+		return p.Price
+	case CatalogSortSales:
+		return float64(p.SalesCount)
+	case CatalogSortRating:
+		return p.Rating
+	case CatalogSortNewest:
+		return float64(p.ID)
+	}
+	return float64(p.SortOrder)
+}
+
+// catalogFacets 统计分面数据
+func (s *ProductService) catalogFacets(db *gorm.DB, q CatalogQuery) (*CatalogFacets, error) {
+	facets := &CatalogFacets{Categories: []CatalogCategoryFacet{}}
+
+	// 分类分面
+	if err := applyCatalogFilters(db.Model(&model.Product{}), q, "category").
+		Select("products.category_id AS category_id, COUNT(*) AS count").
+		Group("products.category_id").Order("count DESC").
+		Scan(&facets.Categories).Error; err != nil {
+		return nil, err
+	}
+	if len(facets.Categories) > 0 {
+		ids := make([]uint, 0, len(facets.Categories))
+		for _, f := range facets.Categories {
+			ids = append(ids, f.CategoryID)
+		}
+		var categories []model.ProductCategory
+		db.Where("id IN ?", ids).Find(&categories)
+		names := make(map[uint]string, len(categories))
+		for _, c := range categories {
+			names[c.ID] = c.Name
+		}
+		for i := range facets.Categories {
+			if name, ok := names[facets.Categories[i].CategoryID]; ok {
+				facets.Categories[i].Name = name
+			} else {
+				facets.Categories[i].Name = "未分类"
+			}
+		}
+	}
+
+	// 价格区间分面
+	selects := make([]string, len(catalogPriceRanges))
+	args := make([]interface{}, 0, len(catalogPriceRanges)*2)
+	for i, r := range catalogPriceRanges {
+		if r.Max > 0 {
+			selects[i] = fmt.Sprintf("COALESCE(SUM(CASE WHEN products.price >= ? AND products.price < ? THEN 1 ELSE 0 END), 0) AS r%d", i)
+			args = append(args, r.Min, r.Max)
+		} else {
+			selects[i] = fmt.Sprintf("COALESCE(SUM(CASE WHEN products.price >= ? THEN 1 ELSE 0 END), 0) AS r%d", i)
+			args = append(args, r.Min)
+		}
+	}
+	row := map[string]interface{}{}
+	if err := applyCatalogFilters(db.Model(&model.Product{}), q, "price").
+		Select(strings.Join(selects, ", "), args...).
+		Take(&row).Error; err != nil {
+		return nil, err
+	}
+	facets.PriceRanges = make([]CatalogPriceFacet, len(catalogPriceRanges))
+	for i, r := range catalogPriceRanges {
+		facets.PriceRanges[i] = CatalogPriceFacet{Min: r.Min, Max: r.Max, Count: toInt64(row[fmt.Sprintf("r%d", i)])}
+	}
+
+	// 有货分面
+	if err := applyCatalogFilters(db.Model(&model.Product{}), q, "stock").
+		Where("products.stock != ?", 0).
+		Count(&facets.InStock).Error; err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// toInt64 将聚合查询返回的数值转换为 int64（不同数据库驱动返回类型不同）
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	case float64:
+		return int64(n)
+	case []byte:
+		var x int64
+		fmt.Sscan(string(n), &x)
+		return x
+	case string:
+		var x int64
+		fmt.Sscan(n, &x)
+		return x
+	}
+	return 0
+}
+
+// encodeCatalogCursor 编码分页游标
+func encodeCatalogCursor(c catalogCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCatalogCursor 解码分页游标
+func decodeCatalogCursor(s string) (*catalogCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c catalogCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.ID == 0 {
+		return nil, ErrInvalidCatalogCursor
+	}
+	return &c, nil
+}
+
+// ==================== 目录缓存 ====================
+
+// catalogCacheKey 生成查询结果缓存键，缓存不可用时返回空字符串
+func catalogCacheKey(q CatalogQuery) string {
+	cm := cache.GetManager()
+	if cm == nil {
+		return ""
+	}
+	version, err := cm.IncrBy(cache.ProductCatalogVersionKey(), 0)
+	if err != nil {
+		return ""
+	}
+	data, _ := json.Marshal(q)
+	sum := sha1.Sum(data)
+	return cache.ProductCatalogKey(version, hex.EncodeToString(sum[:]))
+}
+
+// getCatalogFromCache 从缓存读取查询结果
+func getCatalogFromCache(key string) *CatalogResult {
+	cm := cache.GetManager()
+	if cm == nil {
+		return nil
+	}
+	data, ok := cm.GetString(key)
+	if !ok {
+		return nil
+	}
+	var result CatalogResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil
+	}
+	return &result
+}
+
+// setCatalogCache 缓存查询结果
+func setCatalogCache(key string, result *CatalogResult) {
+	cm := cache.GetManager()
+	if cm == nil {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := cm.Set(key, string(data), cache.CatalogTTL); err != nil {
+		log.Printf("[ProductService] 缓存商品目录失败: %v", err)
+	}
+}
+
+// invalidateCatalogCache 使所有商品目录查询缓存失效（自增目录版本号）
+func invalidateCatalogCache() {
+	cm := cache.GetManager()
+	if cm == nil {
+		return
+	}
+	if _, err := cm.Incr(cache.ProductCatalogVersionKey()); err != nil {
+		log.Printf("[ProductService] 更新商品目录版本失败: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+// Package service 提供业务逻辑服务
+// product_files.go - 商品文件（图片、详情文档）的对象存储读写
+//
+// 商品文件以 products/{商品ID}/... 为对象键保存在对象存储中，
+// 对外地址保持 /product-files/{商品ID}/... 不变；
+// 尚未迁移的旧文件仍可从 ./Product 目录读取。
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"user-frontend/internal/storage"
+)
+
+// 商品文件路径设置
+const (
+	// ProductFileURLPrefix 商品文件访问地址前缀
+	ProductFileURLPrefix = "/product-files/"
+	// LegacyProductDir 旧版商品文件本地目录
+	LegacyProductDir = "./Product"
+	// productObjectPrefix 商品文件对象键前缀
+	productObjectPrefix = "products/"
+	// productDetailFile 商品详情文档文件名
+	productDetailFile = "detail.md"
+)
+
+// ProductObjectKey 将商品文件相对路径（{商品ID}/xxx）转换为对象键
+func ProductObjectKey(relPath string) (string, error) {
+	cleaned, err := storage.CleanKey(strings.TrimPrefix(relPath, "/"))
+	if err != nil {
+		return "", err
+	}
+	return productObjectPrefix + cleaned, nil
+}
+
+// productRelPath 从商品文件地址中提取相对路径，非商品文件地址返回 false
+func productRelPath(url string) (string, bool) {
+	if idx := strings.IndexAny(url, "?#"); idx >= 0 {
+		url = url[:idx]
+	}
+	if !strings.HasPrefix(url, ProductFileURLPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, ProductFileURLPrefix), true
+}
+
+// SaveProductFile 保存商品文件到对象存储，返回访问地址
+func SaveProductFile(productID uint, name string, r io.Reader, size int64, contentType string) (string, error) {
+	relPath := fmt.Sprintf("%d/%s", productID, name)
+	key, err := ProductObjectKey(relPath)
+	if err != nil {
+		return "", err
+	}
+	if err := storage.Default().Put(key, r, size, contentType); err != nil {
+		return "", err
+	}
+	return ProductFileURLPrefix + relPath, nil
+}
+
+// DeleteProductFile 按访问地址删除商品文件（同时清理旧版本地文件）
+func DeleteProductFile(url string) error {
+	relPath, ok := productRelPath(url)
+	if !ok {
+		return nil
+	}
+	key, err := ProductObjectKey(relPath)
+	if err != nil {
+		return err
+	}
+	if legacyPath, err := legacyProductPath(relPath); err == nil {
+		os.Remove(legacyPath)
+	}
+	return storage.Default().Delete(key)
+}
+
+// OpenProductFile 读取商品文件，对象存储中不存在时回退到旧版本地目录
+func OpenProductFile(relPath string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	key, err := ProductObjectKey(relPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, info, err := storage.Default().Get(key)
+	if err == nil || !errors.Is(err, storage.ErrNotFound) {
+		return rc, info, err
+	}
+
+	legacyPath, err := legacyProductPath(relPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(legacyPath)
+	if err != nil {
+		return nil, nil, storage.ErrNotFound
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		f.Close()
+		return nil, nil, storage.ErrNotFound
+	}
+	return f, &storage.ObjectInfo{Key: key, Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+// legacyProductPath 将相对路径转换为旧版本地文件路径
+func legacyProductPath(relPath string) (string, error) {
+	cleaned, err := storage.CleanKey(relPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(LegacyProductDir, filepath.FromSlash(cleaned)), nil
+}
+
+// ReadProductDetail 读取商品详情文档，返回内容和是否存在
+func ReadProductDetail(productID uint) (string, bool, error) {
+	rc, _, err := OpenProductFile(fmt.Sprintf("%d/%s", productID, productDetailFile))
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), true, nil
+}
+
+// SaveProductDetail 保存商品详情文档
+func SaveProductDetail(productID uint, content string) error {
+	_, err := SaveProductFile(productID, productDetailFile, bytes.NewReader([]byte(content)), int64(len(content)), "text/markdown; charset=utf-8")
+	return err
+}
+
+// walkLegacyProductFiles 遍历旧版商品目录下的所有文件，回调参数为相对路径
+func walkLegacyProductFiles(fn func(relPath, fullPath string) error) error {
+	err := filepath.WalkDir(LegacyProductDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(LegacyProductDir, p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), p)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,373 @@
+// Package service 提供业务逻辑服务
+// product_image_pipeline.go - 商品图片处理：类型与尺寸校验、方向校正、缩放、多尺寸与 WebP 变体
+//
+// 上传的图片一律解码后重新编码，原文件中的 EXIF/GPS 等元数据不会保留；
+// 主图长边限制在 2048 像素以内，另生成 320/640/1280 宽度的缩略图及对应 WebP，
+// 供前台通过 srcset 按屏幕选择合适尺寸。带透明通道的图片输出 PNG 且不生成 WebP。
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"sort"
+	"strings"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/webp"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+)
+
+// 商品图片处理参数
+const (
+	// productImageMaxBytes 读取上传图片的最大字节数
+	productImageMaxBytes = 20 << 20
+	// productImageMinSide 图片最小边长
+	productImageMinSide = 100
+	// productImageMaxSide 图片最大边长（超出视为异常文件）
+	productImageMaxSide = 12000
+	// productImageMaxPixels 图片最大像素数，防止解压炸弹
+	productImageMaxPixels = 50000000
+	// productImageMainMaxSide 主图长边上限，超出时等比缩小
+	productImageMainMaxSide = 2048
+	// productImageJPEGQuality JPEG 输出质量
+	productImageJPEGQuality = 85
+	// productImageWebPQuality WebP 输出质量
+	productImageWebPQuality = 75
+)
+
+// productImageWidths 缩略图宽度，仅生成小于主图宽度的尺寸
+var productImageWidths = []int{320, 640, 1280}
+
+// productImageTypes 允许的扩展名及对应内容类型
+var productImageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// processedImageFile 处理后待保存的一个图片文件
+type processedImageFile struct {
+	suffix      string // 文件名后缀，如 ".jpg"、"_w320.webp"
+	format      string // jpeg/png/gif/webp
+	contentType string
+	width       int
+	height      int
+	data        []byte
+}
+
+// processedProductImage 商品图片处理结果：主图及其变体
+type processedProductImage struct {
+	main     processedImageFile
+	variants []processedImageFile
+}
+
+// processProductImage 校验并处理上传的商品图片
+// declaredType 为按扩展名或请求头声明的内容类型，须与文件头识别结果一致
+func processProductImage(declaredType string, data []byte, withVariants bool) (*processedProductImage, error) {
+	if len(data) == 0 {
+		return nil, errors.New("图片内容为空")
+	}
+	detected := http.DetectContentType(data)
+	if idx := strings.Index(detected, ";"); idx > 0 {
+		detected = detected[:idx]
+	}
+	if detected != declaredType {
+		return nil, errors.New("文件内容与扩展名不符")
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("图片文件已损坏或格式不受支持")
+	}
+	if cfg.Width < productImageMinSide || cfg.Height < productImageMinSide {
+		return nil, fmt.Errorf("图片尺寸过小，宽高至少 %d 像素", productImageMinSide)
+	}
+	if cfg.Width > productImageMaxSide || cfg.Height > productImageMaxSide || cfg.Width*cfg.Height > productImageMaxPixels {
+		return nil, fmt.Errorf("图片尺寸过大，宽高不能超过 %d 像素", productImageMaxSide)
+	}
+
+	result := &processedProductImage{}
+	var img *image.RGBA
+
+	if detected == "image/gif" {
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(anim.Image) == 0 {
+			return nil, errors.New("图片文件已损坏")
+		}
+		img = image.NewRGBA(image.Rect(0, 0, anim.Config.Width, anim.Config.Height))
+		draw.Draw(img, anim.Image[0].Bounds(), anim.Image[0], anim.Image[0].Bounds().Min, draw.Over)
+		if len(anim.Image) > 1 {
+			// 动图保留动画，仅重新封装以去除注释等扩展块，缩略图取第一帧
+			var buf bytes.Buffer
+			if err := gif.EncodeAll(&buf, anim); err != nil {
+				return nil, errors.New("图片处理失败")
+			}
+			result.main = processedImageFile{suffix: ".gif", format: "gif", contentType: "image/gif", width: img.Rect.Dx(), height: img.Rect.Dy(), data: buf.Bytes()}
+		}
+	} else {
+		decoded, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("图片文件已损坏")
+		}
+		img = toRGBA(decoded)
+		if detected == "image/jpeg" {
+			img = applyOrientation(img, jpegOrientation(data))
+		}
+	}
+
+	opaque := img.Opaque()
+	if result.main.data == nil {
+		if w, h := fitWithin(img.Rect.Dx(), img.Rect.Dy(), productImageMainMaxSide); w != img.Rect.Dx() || h != img.Rect.Dy() {
+			img = resizeImage(img, w, h)
+		}
+		file, err := encodeProductImage(img, opaque, "")
+		if err != nil {
+			return nil, err
+		}
+		result.main = file
+	}
+
+	if !withVariants {
+		return result, nil
+	}
+
+	if opaque {
+		file, err := encodeWebPVariant(img, "")
+		if err != nil {
+			return nil, err
+		}
+		result.variants = append(result.variants, file)
+	}
+	for _, width := range productImageWidths {
+		if width >= img.Rect.Dx() {
+			break
+		}
+		height := (img.Rect.Dy()*width + img.Rect.Dx()/2) / img.Rect.Dx()
+		if height < 1 {
+			height = 1
+		}
+		resized := resizeImage(img, width, height)
+		suffix := fmt.Sprintf("_w%d", width)
+		file, err := encodeProductImage(resized, opaque, suffix)
+		if err != nil {
+			return nil, err
+		}
+		result.variants = append(result.variants, file)
+		if opaque {
+			file, err := encodeWebPVariant(resized, suffix)
+			if err != nil {
+				return nil, err
+			}
+			result.variants = append(result.variants, file)
+		}
+	}
+	return result, nil
+}
+
+// encodeProductImage 不透明图片编码为 JPEG，带透明通道的编码为 PNG
+func encodeProductImage(img *image.RGBA, opaque bool, suffix string) (processedImageFile, error) {
+	var buf bytes.Buffer
+	file := processedImageFile{width: img.Rect.Dx(), height: img.Rect.Dy()}
+	if opaque {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: productImageJPEGQuality}); err != nil {
+			return file, errors.New("图片处理失败")
+		}
+		file.suffix, file.format, file.contentType = suffix+".jpg", "jpeg", "image/jpeg"
+	} else {
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return file, errors.New("图片处理失败")
+		}
+		file.suffix, file.format, file.contentType = suffix+".png", "png", "image/png"
+	}
+	file.data = buf.Bytes()
+	return file, nil
+}
+
+// encodeWebPVariant 编码 WebP 变体
+func encodeWebPVariant(img *image.RGBA, suffix string) (processedImageFile, error) {
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img, &webp.Options{Quality: productImageWebPQuality}); err != nil {
+		return processedImageFile{}, errors.New("图片处理失败")
+	}
+	return processedImageFile{
+		suffix:      suffix + ".webp",
+		format:      "webp",
+		contentType: "image/webp",
+		width:       img.Rect.Dx(),
+		height:      img.Rect.Dy(),
+		data:        buf.Bytes(),
+	}, nil
+}
+
+// toRGBA 转换为从原点开始的 RGBA 图像
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && b.Min == (image.Point{}) {
+		return rgba
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// fitWithin 计算长边不超过 maxSide 的等比尺寸
+func fitWithin(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		h := (height*maxSide + width/2) / width
+		if h < 1 {
+			h = 1
+		}
+		return maxSide, h
+	}
+	w := (width*maxSide + height/2) / height
+	if w < 1 {
+		w = 1
+	}
+	return w, maxSide
+}
+
+// resizeImage 使用 Catmull-Rom 插值缩放
+func resizeImage(img *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+// applyOrientation 按 EXIF 方向标记旋转或翻转图像，使其按正常方向显示
+func applyOrientation(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // 水平翻转
+				sx, sy = w-1-x, y
+			case 3: // 旋转 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				sx, sy = x, h-1-y
+			case 5: // 沿主对角线翻转
+				sx, sy = y, x
+			case 6: // 顺时针旋转 90°
+				sx, sy = y, h-1-x
+			case 7: // 沿副对角线翻转
+				sx, sy = w-1-y, h-1-x
+			case 8: // 逆时针旋转 90°
+				sx, sy = w-1-y, x
+			}
+			si := sy*img.Stride + sx*4
+			di := y*dst.Stride + x*4
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// saveProcessedProductImage 保存主图及变体到对象存储，任一文件失败时清理已保存的文件
+func saveProcessedProductImage(productID uint, baseName string, processed *processedProductImage) (string, []model.ProductImageVariant, error) {
+	mainURL, err := SaveProductFile(productID, baseName+processed.main.suffix, bytes.NewReader(processed.main.data), int64(len(processed.main.data)), processed.main.contentType)
+	if err != nil {
+		return "", nil, errors.New("保存文件失败")
+	}
+
+	variants := make([]model.ProductImageVariant, 0, len(processed.variants))
+	for _, file := range processed.variants {
+		url, err := SaveProductFile(productID, baseName+file.suffix, bytes.NewReader(file.data), int64(len(file.data)), file.contentType)
+		if err != nil {
+			DeleteProductFile(mainURL)
+			for _, v := range variants {
+				DeleteProductFile(v.URL)
+			}
+			return "", nil, errors.New("保存文件失败")
+		}
+		variants = append(variants, model.ProductImageVariant{Width: file.width, Height: file.height, Format: file.format, URL: url})
+	}
+	return mainURL, variants, nil
+}
+
+// SaveProductMainImage 处理并保存商品主图（商品表 image_url），不生成变体
+// contentType 为上传时声明的类型，须与文件内容一致
+func SaveProductMainImage(productID uint, baseName, contentType string, data []byte) (string, error) {
+	processed, err := processProductImage(contentType, data, false)
+	if err != nil {
+		return "", err
+	}
+	url, _, err := saveProcessedProductImage(productID, baseName, processed)
+	return url, err
+}
+
+// productImageVariants 解析图片记录中的变体列表
+func productImageVariants(img *model.ProductImage) []model.ProductImageVariant {
+	if img.Variants == "" {
+		return nil
+	}
+	var variants []model.ProductImageVariant
+	if err := json.Unmarshal([]byte(img.Variants), &variants); err != nil {
+		return nil
+	}
+	return variants
+}
+
+// fillImageSources 根据变体生成 srcset、WebP srcset 和缩略图地址
+func fillImageSources(img *model.ProductImage) {
+	variants := productImageVariants(img)
+	if len(variants) == 0 {
+		return
+	}
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Width < variants[j].Width })
+
+	var srcSet, webpSet []string
+	for _, v := range variants {
+		entry := fmt.Sprintf("%s %dw", v.URL, v.Width)
+		if v.Format == "webp" {
+			webpSet = append(webpSet, entry)
+			continue
+		}
+		srcSet = append(srcSet, entry)
+		if img.Thumbnail == "" {
+			img.Thumbnail = v.URL
+		}
+	}
+	if img.Width > 0 {
+		srcSet = append(srcSet, fmt.Sprintf("%s %dw", img.URL, img.Width))
+	}
+	if img.Thumbnail == "" {
+		img.Thumbnail = img.URL
+	}
+	img.SrcSet = strings.Join(srcSet, ", ")
+	img.WebPSrcSet = strings.Join(webpSet, ", ")
+}
+
+// deleteProductImageFiles 删除图片文件及全部变体
+func deleteProductImageFiles(img *model.ProductImage) {
+	if img.URL != "" {
+		DeleteProductFile(img.URL)
+	}
+	for _, v := range productImageVariants(img) {
+		DeleteProductFile(v.URL)
+	}
+}
@@ -1,10 +1,10 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -27,6 +27,9 @@ func NewProductImageService(repo *repository.Repository) *ProductImageService {
 func (s *ProductImageService) GetProductImages(productID uint) ([]model.ProductImage, error) {
 	var images []model.ProductImage
 	err := s.repo.GetDB().Where("product_id = ?", productID).Order("sort_order ASC, id ASC").Find(&images).Error
+	for i := range images {
+		fillImageSources(&images[i])
+	}
 	return images, err
 }
 
@@ -38,31 +41,35 @@ func (s *ProductImageService) GetPrimaryImage(productID uint) (*model.ProductIma
 		// 如果没有主图，返回第一张图片
 		err = s.repo.GetDB().Where("product_id = ?", productID).Order("sort_order ASC, id ASC").First(&image).Error
 	}
+	fillImageSources(&image)
 	return &image, err
 }
 
 // AddProductImage 添加商品图片
 func (s *ProductImageService) AddProductImage(productID uint, url string, isPrimary bool) (*model.ProductImage, error) {
+	image := &model.ProductImage{
+		ProductID: productID,
+		URL:       url,
+		IsPrimary: isPrimary,
+	}
+	return image, s.createProductImage(image)
+}
+
+// createProductImage 保存图片记录，排在现有图片之后
+func (s *ProductImageService) createProductImage(image *model.ProductImage) error {
 	db := s.repo.GetDB()
 
 	// 如果设置为主图，先取消其他主图
-	if isPrimary {
-		db.Model(&model.ProductImage{}).Where("product_id = ?", productID).Update("is_primary", false)
+	if image.IsPrimary {
+		db.Model(&model.ProductImage{}).Where("product_id = ?", image.ProductID).Update("is_primary", false)
 	}
 
 	// 获取当前最大排序值
 	var maxSort int
-	db.Model(&model.ProductImage{}).Where("product_id = ?", productID).Select("COALESCE(MAX(sort_order), 0)").Scan(&maxSort)
-
-	image := &model.ProductImage{
-		ProductID: productID,
-		URL:       url,
-		SortOrder: maxSort + 1,
-		IsPrimary: isPrimary,
-	}
+	db.Model(&model.ProductImage{}).Where("product_id = ?", image.ProductID).Select("COALESCE(MAX(sort_order), 0)").Scan(&maxSort)
+	image.SortOrder = maxSort + 1
 
-	err := db.Create(image).Error
-	return image, err
+	return db.Create(image).Error
 }
 
 // DeleteProductImage 删除商品图片
@@ -72,11 +79,8 @@ func (s *ProductImageService) DeleteProductImage(imageID uint) error {
 		return errors.New("图片不存在")
 	}
 
-	// 删除文件
-	if image.URL != "" {
-		filePath := "." + image.URL // URL 格式为 /product/xxx.jpg
-		os.Remove(filePath)
-	}
+	// 删除文件及变体
+	deleteProductImageFiles(&image)
 
 	return s.repo.GetDB().Delete(&image).Error
 }
@@ -106,40 +110,51 @@ func (s *ProductImageService) UpdateImageOrder(productID uint, imageIDs []uint)
 }
 
 // UploadProductImage 上传商品图片
+// 校验真实类型与尺寸，去除元数据并生成缩略图和 WebP 变体
 func (s *ProductImageService) UploadProductImage(productID uint, filename string, file io.Reader, isPrimary bool) (*model.ProductImage, error) {
 	// 验证文件扩展名
 	ext := strings.ToLower(filepath.Ext(filename))
-	allowedExts := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
-	if !allowedExts[ext] {
+	contentType, ok := productImageTypes[ext]
+	if !ok {
 		return nil, errors.New("不支持的图片格式，仅支持 jpg/jpeg/png/gif/webp")
 	}
 
-	// 创建目录
-	uploadDir := fmt.Sprintf("./Product/%d", productID)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return nil, errors.New("创建目录失败")
+	data, err := io.ReadAll(io.LimitReader(file, productImageMaxBytes+1))
+	if err != nil {
+		return nil, errors.New("读取文件失败")
 	}
-
-	// 生成文件名
-	newFilename := fmt.Sprintf("%d_%d%s", time.Now().UnixNano(), productID, ext)
-	filePath := filepath.Join(uploadDir, newFilename)
-
-	// 保存文件
-	dst, err := os.Create(filePath)
+	if len(data) > productImageMaxBytes {
+		return nil, errors.New("图片文件过大")
+	}
+	processed, err := processProductImage(contentType, data, true)
 	if err != nil {
-		return nil, errors.New("创建文件失败")
+		return nil, err
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
-		return nil, errors.New("保存文件失败")
+	// 生成文件名并保存到对象存储
+	baseName := fmt.Sprintf("%d_%d", time.Now().UnixNano(), productID)
+	url, variants, err := saveProcessedProductImage(productID, baseName, processed)
+	if err != nil {
+		return nil, err
 	}
 
-	// 生成URL
-	url := fmt.Sprintf("/product-files/%d/%s", productID, newFilename)
+	variantsJSON, _ := json.Marshal(variants)
+	image := &model.ProductImage{
+		ProductID: productID,
+		URL:       url,
+		IsPrimary: isPrimary,
+		Width:     processed.main.width,
+		Height:    processed.main.height,
+		Variants:  string(variantsJSON),
+	}
 
-	// 添加到数据库
-	return s.AddProductImage(productID, url, isPrimary)
+	// 添加到数据库，失败时清理已保存的文件
+	if err := s.createProductImage(image); err != nil {
+		deleteProductImageFiles(image)
+		return nil, err
+	}
+	fillImageSources(image)
+	return image, nil
 }
 
 // DeleteAllProductImages 删除商品的所有图片
@@ -149,11 +164,8 @@ func (s *ProductImageService) DeleteAllProductImages(productID uint) error {
 		return err
 	}
 
-	for _, img := range images {
-		if img.URL != "" {
-			filePath := "." + img.URL
-			os.Remove(filePath)
-		}
+	for i := range images {
+		deleteProductImageFiles(&images[i])
 	}
 
 	return s.repo.GetDB().Where("product_id = ?", productID).Delete(&model.ProductImage{}).Error
@@ -102,6 +102,28 @@ func (s *ProductService) invalidateProductListCache() {
 			}
 		}
 	}
+
+	invalidateCatalogCache()
+}
+
+// InvalidateProducts 使指定商品及列表、目录缓存失效，并重新汇总涉及的多规格父商品
+// 用于绕过服务层直接批量修改商品后
+func (s *ProductService) InvalidateProducts(ids []uint) {
+	cm := cache.GetManager()
+	if cm != nil {
+		for _, id := range ids {
+			cm.Delete(cache.ProductKey(id))
+		}
+	}
+	s.invalidateProductListCache()
+
+	var parentIDs []uint
+	s.repo.GetDB().Unscoped().Model(&model.Product{}).
+		Where("id IN ? AND parent_id > 0", ids).
+		Distinct().Pluck("parent_id", &parentIDs)
+	for _, parentID := range parentIDs {
+		syncVariantParent(s.repo, parentID)
+	}
 }
 
 // CreateProduct 创建商品
@@ -131,6 +153,7 @@ func (s *ProductService) CreateProduct(name, description string, price float64,
 	if err := s.repo.CreateProduct(product); err != nil {
 		return nil, err
 	}
+	s.invalidateProductListCache()
 
 	return product, nil
 }
@@ -147,7 +170,11 @@ func (s *ProductService) CreateProductFull(product *model.Product) error {
 		return errors.New("时长必须大于0")
 	}
 	product.Status = 1
-	return s.repo.CreateProduct(product)
+	if err := s.repo.CreateProduct(product); err != nil {
+		return err
+	}
+	s.invalidateProductListCache()
+	return nil
 }
 
 // UpdateProduct 更新商品
@@ -183,6 +210,7 @@ func (s *ProductService) UpdateProduct(id uint, name, description string, price
 
 	// 更新成功后使缓存失效
 	s.invalidateProductCache(id)
+	s.afterProductChange(product)
 
 	return product, nil
 }
@@ -197,18 +225,31 @@ func (s *ProductService) UpdateProductFull(product *model.Product) error {
 	if existing.ProductType == model.ProductTypeManual {
 		product.Stock = existing.Stock
 	}
+	// 父子关系不通过通用更新修改
+	product.ParentID = existing.ParentID
+	product.VariantOptions = existing.VariantOptions
+	product.OptionValues = existing.OptionValues
 	err = s.repo.UpdateProduct(product)
 	if err == nil {
 		s.invalidateProductCache(product.ID)
+		s.afterProductChange(product)
 	}
 	return err
 }
 
-// DeleteProduct 删除商品
+// DeleteProduct 删除商品（多规格父商品会一并删除其规格）
 func (s *ProductService) DeleteProduct(id uint) error {
+	product, _ := s.repo.GetProductByID(id)
 	err := s.repo.DeleteProduct(id)
 	if err == nil {
 		s.invalidateProductCache(id)
+		if product != nil && product.ID > 0 {
+			if product.IsVariantParent() {
+				s.deleteVariants(id)
+			} else if product.IsVariant() {
+				syncVariantParent(s.repo, product.ParentID)
+			}
+		}
 	}
 	return err
 }
@@ -253,6 +294,7 @@ func (s *ProductService) UpdateProductStatus(id uint, status int) error {
 	err = s.repo.UpdateProduct(product)
 	if err == nil {
 		s.invalidateProductCache(id)
+		s.afterProductChange(product)
 	}
 	return err
 }
@@ -0,0 +1,423 @@
+// Package service 提供业务逻辑服务
+// product_variant.go - 商品多规格（规格维度、规格商品、父商品价格与库存汇总）
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"user-frontend/internal/cache"
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+)
+
+// 多规格限制
+const (
+	maxVariantDimensions = 3  // 规格维度数量上限
+	maxVariantValues     = 20 // 每个维度可选值数量上限
+)
+
+// defaultVariantLabels 常用规格维度的默认显示名称
+var defaultVariantLabels = map[string]string{
+	model.VariantDimensionDuration: "时长",
+	model.VariantDimensionEdition:  "版本",
+	model.VariantDimensionRegion:   "地区",
+}
+
+// ErrVariantRequired 多规格父商品不能直接购买
+var ErrVariantRequired = errors.New("请选择商品规格")
+
+// VariantInput 创建/更新规格商品的参数
+type VariantInput struct {
+	OptionValues map[string]string `json:"option_values"` // 各维度取值
+	Price        float64           `json:"price"`
+	Duration     int               `json:"duration"`      // 为 0 时沿用父商品时长
+	DurationUnit string            `json:"duration_unit"` // 为空时沿用父商品时长单位
+	Status       *int              `json:"status"`        // 为空时创建为上架、更新时不变
+	SortOrder    int               `json:"sort_order"`
+}
+
+// SetVariantOptions 设置父商品的规格维度
+// 传入空列表表示关闭多规格（仅在没有规格商品时允许）
+func (s *ProductService) SetVariantOptions(parentID uint, options []model.VariantOption) (*model.Product, error) {
+	parent, err := s.repo.GetProductByID(parentID)
+	if err != nil {
+		return nil, errors.New("商品不存在")
+	}
+	if parent.IsVariant() {
+		return nil, errors.New("规格商品不能再设置规格")
+	}
+
+	variants, err := s.GetVariants(parentID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options) == 0 {
+		if len(variants) > 0 {
+			return nil, errors.New("请先删除全部规格后再关闭多规格")
+		}
+		parent.VariantOptions = ""
+		if err := s.repo.UpdateProduct(parent); err != nil {
+			return nil, err
+		}
+		s.invalidateProductCache(parentID)
+		return parent, nil
+	}
+
+	normalized, err := normalizeVariantOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已有规格的取值必须仍然有效
+	for _, v := range variants {
+		if err := checkOptionValues(normalized, v.GetOptionValues()); err != nil {
+			return nil, fmt.Errorf("规格「%s」与新的规格维度不匹配：%v", v.Name, err)
+		}
+	}
+
+	data, _ := json.Marshal(normalized)
+	parent.VariantOptions = string(data)
+	if err := s.repo.UpdateProduct(parent); err != nil {
+		return nil, err
+	}
+	s.invalidateProductCache(parentID)
+	return parent, nil
+}
+
+// normalizeVariantOptions 校验并规范化规格维度定义
+func normalizeVariantOptions(options []model.VariantOption) ([]model.VariantOption, error) {
+	if len(options) > maxVariantDimensions {
+		return nil, fmt.Errorf("规格维度最多 %d 个", maxVariantDimensions)
+	}
+
+	result := make([]model.VariantOption, 0, len(options))
+	names := map[string]bool{}
+	for _, opt := range options {
+		name := strings.TrimSpace(opt.Name)
+		if name == "" {
+			return nil, errors.New("规格维度标识不能为空")
+		}
+		if names[name] {
+			return nil, fmt.Errorf("规格维度「%s」重复", name)
+		}
+		names[name] = true
+
+		label := strings.TrimSpace(opt.Label)
+		if label == "" {
+			label = defaultVariantLabels[name]
+		}
+		if label == "" {
+			label = name
+		}
+
+		values := make([]string, 0, len(opt.Values))
+		seen := map[string]bool{}
+		for _, v := range opt.Values {
+			v = strings.TrimSpace(v)
+			if v == "" || seen[v] {
+				continue
+			}
+			seen[v] = true
+			values = append(values, v)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("规格维度「%s」至少需要一个可选值", label)
+		}
+		if len(values) > maxVariantValues {
+			return nil, fmt.Errorf("规格维度「%s」可选值最多 %d 个", label, maxVariantValues)
+		}
+
+		result = append(result, model.VariantOption{Name: name, Label: label, Values: values})
+	}
+	return result, nil
+}
+
+// checkOptionValues 校验规格取值是否覆盖全部维度且都在可选值范围内
+func checkOptionValues(options []model.VariantOption, values map[string]string) error {
+	if len(values) != len(options) {
+		return errors.New("规格取值必须与规格维度一一对应")
+	}
+	for _, opt := range options {
+		value, ok := values[opt.Name]
+		if !ok {
+			return fmt.Errorf("缺少「%s」的取值", opt.Label)
+		}
+		valid := false
+		for _, v := range opt.Values {
+			if v == value {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("「%s」不是「%s」的可选值", value, opt.Label)
+		}
+	}
+	return nil
+}
+
+// variantName 生成规格商品名称，如「某软件（1年 / 专业版）」
+func variantName(parent *model.Product, values map[string]string) string {
+	parts := make([]string, 0, len(values))
+	for _, opt := range parent.GetVariantOptions() {
+		if v, ok := values[opt.Name]; ok {
+			parts = append(parts, v)
+		}
+	}
+	return fmt.Sprintf("%s（%s）", parent.Name, strings.Join(parts, " / "))
+}
+
+// CreateVariant 为父商品创建规格商品
+// 规格商品是独立的商品记录，拥有自己的价格、库存和卡密池
+func (s *ProductService) CreateVariant(parentID uint, input VariantInput) (*model.Product, error) {
+	parent, err := s.repo.GetProductByID(parentID)
+	if err != nil {
+		return nil, errors.New("商品不存在")
+	}
+	if !parent.IsVariantParent() {
+		return nil, errors.New("请先设置商品的规格维度")
+	}
+	if input.Price < 0 {
+		return nil, errors.New("价格不能为负数")
+	}
+
+	values, err := s.validateVariantValues(parent, input.OptionValues, 0)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(values)
+
+	variant := &model.Product{
+		Name:            variantName(parent, values),
+		Description:     parent.Description,
+		Tags:            parent.Tags,
+		Price:           input.Price,
+		Duration:        parent.Duration,
+		DurationUnit:    parent.DurationUnit,
+		Stock:           0, // 手动卡密类型库存由导入的卡密数量决定
+		Status:          1,
+		SortOrder:       input.SortOrder,
+		ImageURL:        parent.ImageURL,
+		CategoryID:      parent.CategoryID,
+		ProductType:     parent.ProductType,
+		RenewalDiscount: parent.RenewalDiscount,
+		ParentID:        parent.ID,
+		OptionValues:    string(data),
+	}
+	if input.Duration > 0 {
+		variant.Duration = input.Duration
+	}
+	if input.DurationUnit != "" {
+		variant.DurationUnit = input.DurationUnit
+	}
+	if input.Status != nil {
+		variant.Status = *input.Status
+	}
+
+	if err := s.repo.CreateProduct(variant); err != nil {
+		return nil, err
+	}
+	syncVariantParent(s.repo, parent.ID)
+	return variant, nil
+}
+
+// UpdateVariant 更新规格商品
+func (s *ProductService) UpdateVariant(id uint, input VariantInput) (*model.Product, error) {
+	variant, err := s.repo.GetProductByID(id)
+	if err != nil || !variant.IsVariant() {
+		return nil, errors.New("规格不存在")
+	}
+	parent, err := s.repo.GetProductByID(variant.ParentID)
+	if err != nil {
+		return nil, errors.New("父商品不存在")
+	}
+	if input.Price < 0 {
+		return nil, errors.New("价格不能为负数")
+	}
+
+	if len(input.OptionValues) > 0 {
+		values, err := s.validateVariantValues(parent, input.OptionValues, variant.ID)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(values)
+		variant.OptionValues = string(data)
+		variant.Name = variantName(parent, values)
+	}
+	variant.Price = input.Price
+	if input.Duration > 0 {
+		variant.Duration = input.Duration
+	}
+	if input.DurationUnit != "" {
+		variant.DurationUnit = input.DurationUnit
+	}
+	if input.Status != nil {
+		variant.Status = *input.Status
+	}
+	variant.SortOrder = input.SortOrder
+
+	if err := s.repo.UpdateProduct(variant); err != nil {
+		return nil, err
+	}
+	s.invalidateProductCache(variant.ID)
+	syncVariantParent(s.repo, parent.ID)
+	return variant, nil
+}
+
+// validateVariantValues 校验规格取值，并检查同一父商品下不存在相同组合（excludeID 为更新时的自身ID）
+func (s *ProductService) validateVariantValues(parent *model.Product, values map[string]string, excludeID uint) (map[string]string, error) {
+	trimmed := make(map[string]string, len(values))
+	for k, v := range values {
+		trimmed[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	if err := checkOptionValues(parent.GetVariantOptions(), trimmed); err != nil {
+		return nil, err
+	}
+
+	variants, err := s.GetVariants(parent.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		if v.ID == excludeID {
+			continue
+		}
+		existing := v.GetOptionValues()
+		same := len(existing) == len(trimmed)
+		for k, val := range trimmed {
+			if existing[k] != val {
+				same = false
+				break
+			}
+		}
+		if same {
+			return nil, fmt.Errorf("已存在相同规格：%s", v.Name)
+		}
+	}
+	return trimmed, nil
+}
+
+// GetVariants 获取父商品的规格商品列表
+func (s *ProductService) GetVariants(parentID uint, onlyActive bool) ([]model.Product, error) {
+	var variants []model.Product
+	query := s.repo.GetDB().Where("parent_id = ?", parentID)
+	if onlyActive {
+		query = query.Where("status = ?", 1)
+	}
+	err := query.Order("sort_order ASC, id ASC").Find(&variants).Error
+	return variants, err
+}
+
+// AttachVariants 为多规格父商品填充上架中的规格列表
+func (s *ProductService) AttachVariants(product *model.Product) {
+	if product == nil || !product.IsVariantParent() {
+		return
+	}
+	variants, err := s.GetVariants(product.ID, true)
+	if err != nil {
+		log.Printf("[ProductService] 获取商品规格失败: %v", err)
+		return
+	}
+	product.Variants = variants
+}
+
+// afterProductChange 商品变更后维护父子关系：父商品同步规格名称与分类，规格商品同步父商品汇总
+func (s *ProductService) afterProductChange(product *model.Product) {
+	switch {
+	case product.IsVariantParent():
+		s.refreshVariants(product)
+		syncVariantParent(s.repo, product.ID)
+	case product.IsVariant():
+		syncVariantParent(s.repo, product.ParentID)
+	}
+}
+
+// refreshVariants 父商品名称或分类变更后同步到规格商品
+func (s *ProductService) refreshVariants(parent *model.Product) {
+	variants, err := s.GetVariants(parent.ID, false)
+	if err != nil {
+		return
+	}
+	for i := range variants {
+		v := &variants[i]
+		name := variantName(parent, v.GetOptionValues())
+		if v.Name == name && v.CategoryID == parent.CategoryID {
+			continue
+		}
+		v.Name = name
+		v.CategoryID = parent.CategoryID
+		if err := s.repo.UpdateProduct(v); err != nil {
+			log.Printf("[ProductService] 同步规格商品失败: %v", err)
+			continue
+		}
+		s.invalidateProductCache(v.ID)
+	}
+}
+
+// deleteVariants 删除父商品下的全部规格商品
+func (s *ProductService) deleteVariants(parentID uint) {
+	variants, err := s.GetVariants(parentID, false)
+	if err != nil {
+		return
+	}
+	for _, v := range variants {
+		if err := s.repo.DeleteProduct(v.ID); err == nil {
+			s.invalidateProductCache(v.ID)
+		}
+	}
+}
+
+// syncVariantParent 按上架规格汇总父商品的展示价格（最低价）与库存（任一规格无限库存则为无限）
+// 父商品不能直接购买，价格和库存仅用于列表展示、筛选和排序
+func syncVariantParent(repo *repository.Repository, parentID uint) {
+	var variants []model.Product
+	if err := repo.GetDB().Where("parent_id = ? AND status = ?", parentID, 1).Find(&variants).Error; err != nil {
+		return
+	}
+
+	updates := map[string]interface{}{"stock": 0}
+	if len(variants) > 0 {
+		minPrice := variants[0].Price
+		stock := 0
+		for _, v := range variants {
+			if v.Price < minPrice {
+				minPrice = v.Price
+			}
+			if v.Stock == -1 || stock == -1 {
+				stock = -1
+			} else {
+				stock += v.Stock
+			}
+		}
+		updates["price"] = minPrice
+		updates["stock"] = stock
+	}
+
+	if err := repo.GetDB().Model(&model.Product{}).Where("id = ?", parentID).Updates(updates).Error; err != nil {
+		log.Printf("[ProductService] 同步父商品价格库存失败: %v", err)
+		return
+	}
+	if cm := cache.GetManager(); cm != nil {
+		cm.Delete(cache.ProductKey(parentID))
+	}
+	invalidateCatalogCache()
+}
+
+// checkPurchasable 检查商品是否可购买（多规格父商品需选择规格，规格商品要求父商品上架）
+func checkPurchasable(repo *repository.Repository, product *model.Product) error {
+	if product.IsVariantParent() {
+		return ErrVariantRequired
+	}
+	if product.IsVariant() {
+		parent, err := repo.GetProductByID(product.ParentID)
+		if err != nil || parent.Status != 1 {
+			return errors.New("商品已下架")
+		}
+	}
+	return nil
+}
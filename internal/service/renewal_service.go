@@ -1,14 +1,28 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"user-frontend/internal/config"
 	"user-frontend/internal/model"
 	"user-frontend/internal/repository"
+	"user-frontend/internal/utils"
 )
 
+// renewalTokenTTL 一键续费链接有效期
+const renewalTokenTTL = 30 * 24 * time.Hour
+
+// renewableOrderCondition 可续费的原始购买订单条件（排除续费订单本身）
+const renewableOrderCondition = "status = ? AND kami_code != '' AND (order_type IS NULL OR order_type <> ?)"
+
 // RenewalService 续费服务
 type RenewalService struct {
 	repo     *repository.Repository
@@ -25,36 +39,74 @@ func NewRenewalService(repo *repository.Repository, emailSvc *EmailService) *Ren
 
 // UserKamiInfo 用户卡密信息（用于续费展示）
 type UserKamiInfo struct {
-	OrderID      uint      `json:"order_id"`
-	OrderNo      string    `json:"order_no"`
-	ProductID    uint      `json:"product_id"`
-	ProductName  string    `json:"product_name"`
-	KamiCode     string    `json:"kami_code"`
-	Duration     int       `json:"duration"`
-	DurationUnit string    `json:"duration_unit"`
-	PurchaseTime time.Time `json:"purchase_time"`
-	ExpireTime   time.Time `json:"expire_time"`
-	DaysLeft     int       `json:"days_left"`     // 剩余天数
-	IsExpired    bool      `json:"is_expired"`    // 是否已过期
-	CanRenew     bool      `json:"can_renew"`     // 是否可续费
+	OrderID      uint            `json:"order_id"`
+	OrderNo      string          `json:"order_no"`
+	ProductID    uint            `json:"product_id"`
+	ProductName  string          `json:"product_name"`
+	KamiCode     string          `json:"kami_code"`
+	Duration     int             `json:"duration"`
+	DurationUnit string          `json:"duration_unit"`
+	PurchaseTime time.Time       `json:"purchase_time"`
+	ExpireTime   time.Time       `json:"expire_time"`
+	DaysLeft     int             `json:"days_left"`          // 剩余天数
+	IsExpired    bool            `json:"is_expired"`         // 是否已过期
+	CanRenew     bool            `json:"can_renew"`          // 是否可续费
+	RenewalPrice float64         `json:"renewal_price"`      // 续费单价（每周期）
+	RenewalCount int             `json:"renewal_count"`      // 已续费次数
+	Renewals     []RenewalRecord `json:"renewals,omitempty"` // 续费记录（按时间倒序）
+}
+
+// RenewalRecord 续费记录
+type RenewalRecord struct {
+	OrderNo      string     `json:"order_no"`
+	Periods      int        `json:"periods"` // 续费周期数
+	Duration     int        `json:"duration"`
+	DurationUnit string     `json:"duration_unit"`
+	Price        float64    `json:"price"`
+	Currency     string     `json:"currency"`
+	PaidAt       *time.Time `json:"paid_at"`
+	ExpireAt     *time.Time `json:"expire_at"` // 本次续费后的到期时间
 }
 
 // GetUserKamis 获取用户的卡密列表（用于续费页面）
+// 每个原始购买订单一条记录，附带其续费历史，到期时间按续费链计算
 func (s *RenewalService) GetUserKamis(userID uint) ([]UserKamiInfo, error) {
-	// 获取用户已完成的订单（有卡密的）
+	// 获取用户已完成的购买订单（有卡密的，不含续费订单）
 	var orders []model.Order
-	err := s.repo.GetDB().Where("user_id = ? AND status = ? AND kami_code != ''", userID, 2).
+	err := s.repo.GetDB().Where("user_id = ? AND "+renewableOrderCondition, userID, model.OrderStatusCompleted, model.OrderTypeRenewal).
 		Order("created_at DESC").Find(&orders).Error
 	if err != nil {
 		return nil, err
 	}
 
+	// 一次性加载用户的续费记录，按原始订单分组
+	var renewalOrders []model.Order
+	s.repo.GetDB().Where("user_id = ? AND order_type = ? AND status = ?", userID, model.OrderTypeRenewal, model.OrderStatusCompleted).
+		Order("payment_time DESC").Find(&renewalOrders)
+	renewals := make(map[uint][]RenewalRecord)
+	for _, r := range renewalOrders {
+		renewals[r.RenewOrderID] = append(renewals[r.RenewOrderID], RenewalRecord{
+			OrderNo:      r.OrderNo,
+			Periods:      r.Quantity,
+			Duration:     r.Duration,
+			DurationUnit: r.DurationUnit,
+			Price:        r.Price,
+			Currency:     r.Currency,
+			PaidAt:       r.PaymentTime,
+			ExpireAt:     r.ExpireAt,
+		})
+	}
+
 	var kamis []UserKamiInfo
 	now := time.Now()
 
 	for _, order := range orders {
-		// 计算过期时间
-		expireTime := s.calculateExpireTime(order.PaymentTime, order.Duration, order.DurationUnit)
+		if order.PaymentTime == nil {
+			continue
+		}
+
+		// 计算过期时间（包含续费延长）
+		expireTime := OrderExpireTime(&order)
 		daysLeft := int(expireTime.Sub(now).Hours() / 24)
 		if daysLeft < 0 {
 			daysLeft = 0
@@ -62,8 +114,10 @@ func (s *RenewalService) GetUserKamis(userID uint) ([]UserKamiInfo, error) {
 
 		// 检查商品是否仍然存在且可购买
 		canRenew := false
+		renewalPrice := 0.0
 		if product, err := s.repo.GetProductByID(order.ProductID); err == nil && product.Status == 1 {
 			canRenew = true
+			renewalPrice = RenewalUnitPrice(product)
 		}
 
 		kamis = append(kamis, UserKamiInfo{
@@ -79,6 +133,9 @@ func (s *RenewalService) GetUserKamis(userID uint) ([]UserKamiInfo, error) {
 			DaysLeft:     daysLeft,
 			IsExpired:    now.After(expireTime),
 			CanRenew:     canRenew,
+			RenewalPrice: renewalPrice,
+			RenewalCount: len(renewals[order.ID]),
+			Renewals:     renewals[order.ID],
 		})
 	}
 
@@ -90,7 +147,7 @@ func (s *RenewalService) GetUserKamis(userID uint) ([]UserKamiInfo, error) {
 func (s *RenewalService) GetExpiringKamis(daysBeforeExpire int) ([]UserKamiInfo, error) {
 	// 获取所有已完成的订单
 	var orders []model.Order
-	err := s.repo.GetDB().Where("status = ? AND kami_code != ''", 2).Find(&orders).Error
+	err := s.repo.GetDB().Where(renewableOrderCondition, model.OrderStatusCompleted, model.OrderTypeRenewal).Find(&orders).Error
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +161,7 @@ func (s *RenewalService) GetExpiringKamis(daysBeforeExpire int) ([]UserKamiInfo,
 			continue
 		}
 
-		expireTime := s.calculateExpireTime(order.PaymentTime, order.Duration, order.DurationUnit)
+		expireTime := OrderExpireTime(&order)
 
 		// 检查是否在指定天数内过期
 		if expireTime.After(now) && expireTime.Before(deadline) {
@@ -130,26 +187,6 @@ func (s *RenewalService) GetExpiringKamis(daysBeforeExpire int) ([]UserKamiInfo,
 	return expiringKamis, nil
 }
 
-// calculateExpireTime 计算过期时间
-func (s *RenewalService) calculateExpireTime(paymentTime *time.Time, duration int, durationUnit string) time.Time {
-	if paymentTime == nil {
-		return time.Now()
-	}
-
-	switch durationUnit {
-	case "天":
-		return paymentTime.AddDate(0, 0, duration)
-	case "周":
-		return paymentTime.AddDate(0, 0, duration*7)
-	case "月":
-		return paymentTime.AddDate(0, duration, 0)
-	case "年":
-		return paymentTime.AddDate(duration, 0, 0)
-	default:
-		return paymentTime.AddDate(0, 0, duration)
-	}
-}
-
 // SendRenewalReminder 发送续费提醒邮件
 func (s *RenewalService) SendRenewalReminder(userID uint, orderNo string, remindType string) error {
 	// 获取用户信息
@@ -172,8 +209,8 @@ func (s *RenewalService) SendRenewalReminder(userID uint, orderNo string, remind
 		return errors.New("订单未支付")
 	}
 
-	// 计算过期时间
-	expireTime := s.calculateExpireTime(order.PaymentTime, order.Duration, order.DurationUnit)
+	// 计算过期时间（包含续费延长）
+	expireTime := OrderExpireTime(order)
 	daysLeft := int(expireTime.Sub(time.Now()).Hours() / 24)
 
 	// 检查是否已发送过该类型的提醒
@@ -189,7 +226,8 @@ func (s *RenewalService) SendRenewalReminder(userID uint, orderNo string, remind
 	}
 
 	subject := s.getReminderSubject(remindType, order.ProductName)
-	body := s.getReminderBody(remindType, user.Username, order.ProductName, order.KamiCode, expireTime, daysLeft)
+	body := s.getReminderBody(remindType, user.Username, order.ProductName, order.KamiCode, expireTime, daysLeft,
+		s.RenewalLink(order.OrderNo, userID))
 
 	if err := s.emailSvc.SendEmail(user.Email, subject, body); err != nil {
 		return fmt.Errorf("发送邮件失败: %v", err)
@@ -227,7 +265,8 @@ func (s *RenewalService) getReminderSubject(remindType, productName string) stri
 }
 
 // getReminderBody 获取提醒邮件内容
-func (s *RenewalService) getReminderBody(remindType, username, productName, kamiCode string, expireTime time.Time, daysLeft int) string {
+// renewLink 为一键续费链接，为空时不显示续费按钮
+func (s *RenewalService) getReminderBody(remindType, username, productName, kamiCode string, expireTime time.Time, daysLeft int, renewLink string) string {
 	var urgency string
 	switch remindType {
 	case model.RemindType7Day:
@@ -242,6 +281,14 @@ func (s *RenewalService) getReminderBody(remindType, username, productName, kami
 		urgency = "即将"
 	}
 
+	renewButton := ""
+	if renewLink != "" {
+		renewButton = fmt.Sprintf(`
+    <p style="text-align: center; margin: 25px 0;">
+        <a href="%s" style="background: #1677ff; color: #fff; padding: 10px 28px; border-radius: 5px; text-decoration: none;">一键续费</a>
+    </p>`, renewLink)
+	}
+
 	return fmt.Sprintf(`
 <div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
     <h2 style="color: #333;">尊敬的 %s：</h2>
@@ -255,20 +302,20 @@ func (s *RenewalService) getReminderBody(remindType, username, productName, kami
         <p style="margin: 5px 0;"><strong>剩余天数：</strong>%d 天</p>
     </div>
     <p style="color: #666; line-height: 1.6;">
-        为了避免服务中断，建议您尽快登录系统进行续费。
-    </p>
+        为了避免服务中断，建议您尽快登录系统进行续费。续费将在当前到期时间基础上顺延，卡密保持不变。
+    </p>%s
     <p style="color: #999; font-size: 12px; margin-top: 30px;">
         此邮件由系统自动发送，请勿直接回复。
     </p>
 </div>
-`, username, productName, urgency, productName, kamiCode, expireTime.Format("2006-01-02 15:04:05"), daysLeft)
+`, username, productName, urgency, productName, kamiCode, expireTime.Format("2006-01-02 15:04:05"), daysLeft, renewButton)
 }
 
 // CheckAndSendReminders 检查并发送续费提醒（定时任务调用）
 func (s *RenewalService) CheckAndSendReminders() {
 	// 获取所有已完成的订单
 	var orders []model.Order
-	err := s.repo.GetDB().Where("status = ? AND kami_code != ''", 2).Find(&orders).Error
+	err := s.repo.GetDB().Where(renewableOrderCondition, model.OrderStatusCompleted, model.OrderTypeRenewal).Find(&orders).Error
 	if err != nil {
 		return
 	}
@@ -286,7 +333,7 @@ func (s *RenewalService) CheckAndSendReminders() {
 			continue
 		}
 
-		expireTime := s.calculateExpireTime(order.PaymentTime, order.Duration, order.DurationUnit)
+		expireTime := OrderExpireTime(&order)
 		daysLeft := int(expireTime.Sub(now).Hours() / 24)
 
 		// 根据剩余天数发送不同类型的提醒
@@ -321,3 +368,53 @@ func (s *RenewalService) GetRenewalHistory(userID uint) ([]model.RenewalReminder
 	err := s.repo.GetDB().Where("user_id = ?", userID).Order("created_at DESC").Find(&reminders).Error
 	return reminders, err
 }
+
+// RenewalLink 生成一键续费链接（需配置 SITE_URL，未配置时返回空）
+func (s *RenewalService) RenewalLink(orderNo string, userID uint) string {
+	if config.GlobalEnvConfig == nil || config.GlobalEnvConfig.SiteURL == "" {
+		return ""
+	}
+	return config.GlobalEnvConfig.SiteURL + "/renew/" + url.PathEscape(orderNo) +
+		"?token=" + url.QueryEscape(GenerateRenewalToken(orderNo, userID, time.Now().Add(renewalTokenTTL)))
+}
+
+// GenerateRenewalToken 生成一键续费令牌
+// 格式：用户ID.过期时间戳.HMAC签名（签名覆盖订单号、用户ID和过期时间）
+func GenerateRenewalToken(orderNo string, userID uint, expireAt time.Time) string {
+	payload := fmt.Sprintf("%d.%d", userID, expireAt.Unix())
+	return payload + "." + renewalTokenSign(orderNo, payload)
+}
+
+// VerifyRenewalToken 验证一键续费令牌
+// 返回：
+//   - 令牌对应的用户ID
+//   - 错误信息
+func VerifyRenewalToken(orderNo, token string) (uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, errors.New("续费链接无效")
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(renewalTokenSign(orderNo, payload))) {
+		return 0, errors.New("续费链接无效")
+	}
+
+	expireAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expireAt {
+		return 0, errors.New("续费链接已过期，请登录后在用户中心续费")
+	}
+
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, errors.New("续费链接无效")
+	}
+	return uint(userID), nil
+}
+
+// renewalTokenSign 计算续费令牌签名（使用配置加密密钥）
+func renewalTokenSign(orderNo, payload string) string {
+	mac := hmac.New(sha256.New, utils.GetConfigEncryptionKey())
+	mac.Write([]byte("renewal:" + orderNo + ":" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
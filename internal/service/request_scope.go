@@ -0,0 +1,18 @@
+package service
+
+import "context"
+
+// requestScope 服务副本绑定的请求上下文
+// 嵌入到需要按请求传递请求ID的服务中，由各服务的 WithContext 设置；
+// 支付网关调用和数据库查询使用该上下文，使请求ID出现在出站请求头、Span 和日志中
+type requestScope struct {
+	ctx context.Context
+}
+
+// requestContext 返回所属请求的上下文，未绑定时返回 context.Background()
+func (r requestScope) requestContext() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
@@ -54,8 +54,15 @@ func (s *ReviewService) CreateReview(userID uint, username, orderNo string, prod
 		return nil, errors.New("只能评价已完成的订单")
 	}
 
-	// 验证商品ID是否匹配
-	if order.ProductID != productID {
+	// 验证商品ID是否匹配（多规格商品可按父商品或所购规格评价，评价归集到父商品并记录所购规格）
+	var variantID uint
+	if product, err := s.repo.GetProductByID(order.ProductID); err == nil && product.IsVariant() {
+		if productID != product.ID && productID != product.ParentID {
+			return nil, errors.New("商品ID与订单不匹配")
+		}
+		productID = product.ParentID
+		variantID = product.ID
+	} else if order.ProductID != productID {
 		return nil, errors.New("商品ID与订单不匹配")
 	}
 
@@ -76,6 +83,7 @@ func (s *ReviewService) CreateReview(userID uint, username, orderNo string, prod
 	// 创建评价
 	review := &model.ProductReview{
 		ProductID: productID,
+		VariantID: variantID,
 		UserID:    userID,
 		Username:  username,
 		OrderNo:   orderNo,
@@ -107,7 +115,7 @@ func (s *ReviewService) GetProductReviews(productID uint, page, pageSize, rating
 	var reviews []model.ProductReview
 	var total int64
 
-	query := s.repo.GetDB().Model(&model.ProductReview{}).Where("product_id = ? AND status = 1", productID)
+	query := s.reviewScope(productID).Where("status = 1")
 
 	// 筛选评分
 	if rating > 0 && rating <= 5 {
@@ -149,8 +157,8 @@ func (s *ReviewService) GetProductReviewStats(productID uint) (*model.ProductRev
 		TotalCount int64
 		AvgRating  float64
 	}
-	if err := s.repo.GetDB().Model(&model.ProductReview{}).
-		Where("product_id = ? AND status = 1", productID).
+	if err := s.reviewScope(productID).
+		Where("status = 1").
 		Select("COUNT(*) as total_count, COALESCE(AVG(rating), 0) as avg_rating").
 		Scan(&result).Error; err != nil {
 		return nil, err
@@ -163,8 +171,8 @@ func (s *ReviewService) GetProductReviewStats(productID uint) (*model.ProductRev
 		Rating int
 		Count  int64
 	}
-	if err := s.repo.GetDB().Model(&model.ProductReview{}).
-		Where("product_id = ? AND status = 1", productID).
+	if err := s.reviewScope(productID).
+		Where("status = 1").
 		Select("rating, COUNT(*) as count").
 		Group("rating").
 		Scan(&ratingCounts).Error; err != nil {
@@ -299,7 +307,7 @@ func (s *ReviewService) GetAllReviews(page, pageSize int, productID uint, status
 	query := s.repo.GetDB().Model(&model.ProductReview{})
 
 	if productID > 0 {
-		query = query.Where("product_id = ?", productID)
+		query = s.reviewScope(productID)
 	}
 	if status >= 0 {
 		query = query.Where("status = ?", status)
@@ -317,6 +325,15 @@ func (s *ReviewService) GetAllReviews(page, pageSize int, productID uint, status
 	return reviews, total, nil
 }
 
+// reviewScope 按商品筛选评价：父商品包含其全部规格的评价，规格商品只包含该规格的评价
+func (s *ReviewService) reviewScope(productID uint) *gorm.DB {
+	query := s.repo.GetDB().Model(&model.ProductReview{})
+	if product, err := s.repo.GetProductByID(productID); err == nil && product.IsVariant() {
+		return query.Where("product_id = ? AND variant_id = ?", product.ParentID, product.ID)
+	}
+	return query.Where("product_id = ?", productID)
+}
+
 // maskUsername 隐藏用户名中间部分
 func maskUsername(username string) string {
 	runes := []rune(username)
@@ -431,6 +431,18 @@ func (s *RoleService) UpdateAdminPassword(id uint, newPassword string) error {
 		Update("password_hash", string(hashedPassword)).Error
 }
 
+// SetAdminStatus 设置管理员状态：1启用 0禁用
+func (s *RoleService) SetAdminStatus(id uint, status int) error {
+	return s.repo.GetDB().Model(&model.Admin{}).Where("id = ?", id).
+		Update("status", status).Error
+}
+
+// DisableAdmin2FA 关闭管理员两步验证并清除TOTP密钥
+func (s *RoleService) DisableAdmin2FA(id uint) error {
+	return s.repo.GetDB().Model(&model.Admin{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"Enable2FA": false, "TOTPSecret": ""}).Error
+}
+
 // DeleteAdmin 删除管理员
 func (s *RoleService) DeleteAdmin(id uint) error {
 	admin, err := s.GetAdminByID(id)
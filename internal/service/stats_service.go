@@ -53,6 +53,14 @@ type PaymentMethodStats struct {
 	Percent float64 `json:"percent"`
 }
 
+// CurrencyStats 支付币种统计
+type CurrencyStats struct {
+	Currency  string  `json:"currency"`   // 实际支付币种
+	Count     int64   `json:"count"`      // 订单数
+	PayAmount float64 `json:"pay_amount"` // 按支付币种计的实收金额
+	Revenue   float64 `json:"revenue"`    // 折算为基础货币的收入
+}
+
 // UserStats 用户统计数据
 type UserStats struct {
 	TotalUsers      int64   `json:"total_users"`       // 总用户数
@@ -244,6 +252,48 @@ func (s *StatsService) GetPaymentMethodStats(startDate, endDate time.Time) ([]Pa
 	return result, nil
 }
 
+// GetCurrencyStats 获取按支付币种分组的收入统计
+// 历史订单未记录支付币种时，视为以基础货币支付
+// 参数：
+//   - startDate: 开始日期
+//   - endDate: 结束日期
+//   - baseCurrency: 店铺基础货币
+// 返回：
+//   - 币种统计列表
+//   - 错误信息
+func (s *StatsService) GetCurrencyStats(startDate, endDate time.Time, baseCurrency string) ([]CurrencyStats, error) {
+	var rows []CurrencyStats
+	err := s.repo.GetDB().Model(&model.Order{}).
+		Where("created_at BETWEEN ? AND ? AND status IN ?", startDate, endDate, []int{1, 2}).
+		Select("COALESCE(pay_currency, '') as currency, COUNT(*) as count, " +
+			"COALESCE(SUM(CASE WHEN pay_amount > 0 THEN pay_amount ELSE price END), 0) as pay_amount, " +
+			"COALESCE(SUM(price), 0) as revenue").
+		Group("pay_currency").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 合并未记录币种的历史订单到基础货币
+	index := make(map[string]int)
+	var result []CurrencyStats
+	for _, r := range rows {
+		if r.Currency == "" {
+			r.Currency = baseCurrency
+		}
+		if i, ok := index[r.Currency]; ok {
+			result[i].Count += r.Count
+			result[i].PayAmount += r.PayAmount
+			result[i].Revenue += r.Revenue
+			continue
+		}
+		index[r.Currency] = len(result)
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
 // GetUserStats 获取用户统计
 // 返回：
 //   - 用户统计数据
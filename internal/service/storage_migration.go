@@ -0,0 +1,156 @@
+// Package service 提供业务逻辑服务
+// storage_migration.go - 本地文件迁移到对象存储
+//
+// 迁移可重复执行：已存在且大小一致的对象会跳过，旧文件保留不删除，
+// 确认无误后可手动清理 ./Product、./attachments、./uploads/tickets 目录。
+package service
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/storage"
+)
+
+// storageMigrationMaxErrors 迁移结果中保留的最大错误条数
+const storageMigrationMaxErrors = 20
+
+// StorageMigrationResult 文件迁移结果
+type StorageMigrationResult struct {
+	Migrated int      `json:"migrated"` // 新写入的对象数
+	Skipped  int      `json:"skipped"`  // 已存在而跳过的对象数
+	Failed   int      `json:"failed"`   // 失败数
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// fail 记录一次失败
+func (r *StorageMigrationResult) fail(format string, args ...interface{}) {
+	r.Failed++
+	if len(r.Errors) < storageMigrationMaxErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// putLocalFile 将本地文件写入对象存储，目标已存在且大小一致时跳过
+func putLocalFile(st storage.Storage, key, filePath string, result *StorageMigrationResult) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		result.fail("%s: %v", filePath, err)
+		return false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		result.fail("%s: %v", filePath, err)
+		return false
+	}
+
+	if info, err := st.Stat(key); err == nil && info.Size == stat.Size() {
+		result.Skipped++
+		return true
+	}
+	if err := st.Put(key, f, stat.Size(), mime.TypeByExtension(path.Ext(key))); err != nil {
+		result.fail("%s: %v", key, err)
+		return false
+	}
+	result.Migrated++
+	return true
+}
+
+// copyFromLocalStorage 当前为远程存储时，将本地存储中指定前缀的对象复制过去
+// 用于从单机本地存储切换到 S3 兼容存储
+func copyFromLocalStorage(localRoot, prefix string, result *StorageMigrationResult) {
+	dst := storage.Default()
+	if dst.Driver() == storage.DriverLocal {
+		return
+	}
+	if localRoot == "" {
+		localRoot = storage.DefaultLocalRoot
+	}
+	if _, err := os.Stat(localRoot); err != nil {
+		return
+	}
+	src, err := storage.NewLocalStorage(localRoot)
+	if err != nil {
+		result.fail("本地存储: %v", err)
+		return
+	}
+	objects, err := src.List(prefix)
+	if err != nil {
+		result.fail("本地存储: %v", err)
+		return
+	}
+	for _, obj := range objects {
+		if info, err := dst.Stat(obj.Key); err == nil && info.Size == obj.Size {
+			result.Skipped++
+			continue
+		}
+		if err := storage.Copy(dst, src, obj.Key); err != nil {
+			result.fail("%s: %v", obj.Key, err)
+			continue
+		}
+		result.Migrated++
+	}
+}
+
+// MigrateFilesToStorage 将 ./Product 目录下的商品文件迁移到对象存储
+// 商品文件地址不变，迁移后由对象存储提供；localRoot 为切换前本地存储的根目录
+func (s *ProductImageService) MigrateFilesToStorage(localRoot string) *StorageMigrationResult {
+	result := &StorageMigrationResult{}
+	st := storage.Default()
+
+	err := walkLegacyProductFiles(func(relPath, fullPath string) error {
+		key, err := ProductObjectKey(relPath)
+		if err != nil {
+			result.fail("%s: %v", fullPath, err)
+			return nil
+		}
+		putLocalFile(st, key, fullPath, result)
+		return nil
+	})
+	if err != nil {
+		result.fail("%s: %v", LegacyProductDir, err)
+	}
+
+	copyFromLocalStorage(localRoot, productObjectPrefix, result)
+	return result
+}
+
+// MigrateAttachmentsToStorage 将本地路径保存的工单附件迁移到对象存储并更新记录
+func (s *SupportService) MigrateAttachmentsToStorage(localRoot string) *StorageMigrationResult {
+	result := &StorageMigrationResult{}
+	st := storage.Default()
+	db := s.repo.GetDB()
+
+	var attachments []model.SupportAttachment
+	db.Where("file_path NOT LIKE ?", ticketObjectPrefix+"%").Find(&attachments)
+
+	ticketNos := make(map[uint]string)
+	for _, attachment := range attachments {
+		ticketNo, ok := ticketNos[attachment.TicketID]
+		if !ok {
+			var ticket model.SupportTicket
+			if err := db.Select("ticket_no").First(&ticket, attachment.TicketID).Error; err != nil {
+				result.fail("附件 %d: 工单不存在", attachment.ID)
+				continue
+			}
+			ticketNo = ticket.TicketNo
+			ticketNos[attachment.TicketID] = ticketNo
+		}
+
+		key := ticketObjectPrefix + filepath.Base(ticketNo) + "/" + filepath.Base(attachment.FilePath)
+		if !putLocalFile(st, key, attachment.FilePath, result) {
+			continue
+		}
+		if err := db.Model(&model.SupportAttachment{}).Where("id = ?", attachment.ID).Update("file_path", key).Error; err != nil {
+			result.fail("附件 %d: %v", attachment.ID, err)
+		}
+	}
+
+	copyFromLocalStorage(localRoot, ticketObjectPrefix, result)
+	return result
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -15,11 +16,13 @@ import (
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/trace"
 )
 
 // StripeService Stripe支付服务
 type StripeService struct {
 	cfg *config.Config
+	requestScope
 }
 
 // NewStripeService 创建Stripe支付服务
@@ -27,6 +30,13 @@ func NewStripeService(cfg *config.Config) *StripeService {
 	return &StripeService{cfg: cfg}
 }
 
+// WithContext 返回绑定 ctx 的服务副本，网关请求会携带 ctx 中的请求ID
+func (s *StripeService) WithContext(ctx context.Context) *StripeService {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
 // StripeConfig Stripe配置
 type StripeConfig struct {
 	Enabled       bool   `json:"enabled"`
@@ -92,7 +102,7 @@ func (s *StripeService) CreateCheckoutSession(orderNo string, amount int64, prod
 	data.Set("metadata[order_no]", orderNo)
 
 	// 发送请求
-	req, err := http.NewRequest("POST", "https://api.stripe.com/v1/checkout/sessions", strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", s.apiURL("/v1/checkout/sessions"), strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +110,7 @@ func (s *StripeService) CreateCheckoutSession(orderNo string, amount int64, prod
 	req.Header.Set("Authorization", "Bearer "+stripeCfg.SecretKey)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -143,7 +153,7 @@ func (s *StripeService) CreatePaymentIntent(orderNo string, amount int64, descri
 	data.Set("metadata[order_no]", orderNo)
 	data.Set("automatic_payment_methods[enabled]", "true")
 
-	req, err := http.NewRequest("POST", "https://api.stripe.com/v1/payment_intents", strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", s.apiURL("/v1/payment_intents"), strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +161,7 @@ func (s *StripeService) CreatePaymentIntent(orderNo string, amount int64, descri
 	req.Header.Set("Authorization", "Bearer "+stripeCfg.SecretKey)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -182,14 +192,14 @@ func (s *StripeService) RetrieveCheckoutSession(sessionID string) (*StripeChecko
 		return nil, errors.New("Stripe未配置")
 	}
 
-	req, err := http.NewRequest("GET", "https://api.stripe.com/v1/checkout/sessions/"+sessionID, nil)
+	req, err := http.NewRequestWithContext(s.requestContext(), "GET", s.apiURL("/v1/checkout/sessions/"+sessionID), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+stripeCfg.SecretKey)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -220,14 +230,14 @@ func (s *StripeService) RetrievePaymentIntent(intentID string) (*StripePaymentIn
 		return nil, errors.New("Stripe未配置")
 	}
 
-	req, err := http.NewRequest("GET", "https://api.stripe.com/v1/payment_intents/"+intentID, nil)
+	req, err := http.NewRequestWithContext(s.requestContext(), "GET", s.apiURL("/v1/payment_intents/"+intentID), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+stripeCfg.SecretKey)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -415,6 +425,15 @@ func (s *StripeService) getStripeConfig() *StripeConfig {
 	}
 }
 
+// apiURL 拼接Stripe API地址（支持配置自定义地址，用于沙箱模拟网关）
+func (s *StripeService) apiURL(path string) string {
+	base := "https://api.stripe.com"
+	if s.cfg != nil && s.cfg.PaymentConfig.StripeAPIBaseURL != "" {
+		base = strings.TrimRight(s.cfg.PaymentConfig.StripeAPIBaseURL, "/")
+	}
+	return base + path
+}
+
 // GetPublishableKey 获取公钥（前端使用）
 func (s *StripeService) GetPublishableKey() string {
 	cfg := s.getStripeConfig()
@@ -443,7 +462,7 @@ func (s *StripeService) CreateRefund(paymentIntentID string, amount int64, reaso
 		data.Set("reason", reason)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.stripe.com/v1/refunds", strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", s.apiURL("/v1/refunds"), strings.NewReader(data.Encode()))
 	if err != nil {
 		return err
 	}
@@ -451,7 +470,7 @@ func (s *StripeService) CreateRefund(paymentIntentID string, amount int64, reaso
 	req.Header.Set("Authorization", "Bearer "+stripeCfg.SecretKey)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -473,14 +492,14 @@ func (s *StripeService) TestConnection() error {
 		return errors.New("Stripe密钥未配置")
 	}
 
-	req, err := http.NewRequest("GET", "https://api.stripe.com/v1/balance", nil)
+	req, err := http.NewRequestWithContext(s.requestContext(), "GET", s.apiURL("/v1/balance"), nil)
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+stripeCfg.SecretKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := trace.NewHTTPClient(10 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("连接失败: %v", err)
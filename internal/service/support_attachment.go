@@ -32,6 +32,9 @@ func (s *SupportService) GetTicketAttachments(ticketID uint) ([]model.SupportAtt
 	if err := s.repo.GetDB().Where("ticket_id = ?", ticketID).Order("created_at ASC").Find(&attachments).Error; err != nil {
 		return nil, err
 	}
+	for i := range attachments {
+		attachments[i].URL = SignAttachmentURL(attachments[i].ID)
+	}
 	return attachments, nil
 }
 
@@ -71,6 +74,8 @@ func (s *SupportService) ReplyTicketWithAttachment(ticketID uint, senderType str
 		"last_reply_by": senderName,
 	}
 
+	s.applyReplySLA(ticketID, senderType, isInternal, now, updates)
+
 	if senderType == "staff" && !isInternal {
 		updates["status"] = model.TicketStatusReplied
 	}
@@ -0,0 +1,296 @@
+// Package service 提供业务逻辑服务
+// support_attachment_file.go - 工单附件私有存储、内容校验与签名访问
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-frontend/internal/model"
+	"user-frontend/internal/storage"
+	"user-frontend/internal/utils"
+)
+
+// 工单附件存储与访问设置
+const (
+	// LegacyTicketAttachmentDir 旧版工单附件本地目录（迁移前的数据仍从此读取）
+	LegacyTicketAttachmentDir = "./attachments/tickets"
+	// ticketObjectPrefix 工单附件对象键前缀（对象存储不对外公开，仅经授权接口读取）
+	ticketObjectPrefix = "tickets/"
+	// AttachmentURLPrefix 附件访问地址前缀
+	AttachmentURLPrefix = "/api/support/attachment/"
+	// attachmentURLTTL 签名访问地址有效期
+	attachmentURLTTL = 30 * time.Minute
+)
+
+// defaultAttachmentTypes 未配置 AllowedFileTypes 时允许的文件类型
+var defaultAttachmentTypes = []string{"jpg", "jpeg", "png", "gif", "webp", "pdf", "txt", "zip"}
+
+// attachmentContentTypes 扩展名与按文件头识别出的内容类型的对应关系
+var attachmentContentTypes = map[string]string{
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+	"bmp":  "image/bmp",
+	"pdf":  "application/pdf",
+	"txt":  "text/plain",
+	"log":  "text/plain",
+	"csv":  "text/plain",
+	"zip":  "application/zip",
+	"docx": "application/zip",
+	"xlsx": "application/zip",
+	"rar":  "application/x-rar-compressed",
+	"gz":   "application/x-gzip",
+}
+
+// StoredAttachment 已保存的附件文件信息
+type StoredAttachment struct {
+	FileName string // 原始文件名
+	FilePath string // 对象键
+	MimeType string // 按文件内容识别的类型
+	FileSize int64  // 实际保存大小（清理元数据后）
+}
+
+// allowedAttachmentTypes 解析配置中允许的文件扩展名
+// 配置为 JSON 数组，也兼容逗号分隔；条目可带点号或写成 MIME 类型（如 image/png、image/*）
+func allowedAttachmentTypes(config string) map[string]bool {
+	var entries []string
+	config = strings.TrimSpace(config)
+	if config != "" {
+		if err := json.Unmarshal([]byte(config), &entries); err != nil {
+			entries = strings.Split(config, ",")
+		}
+	}
+	if len(entries) == 0 {
+		entries = defaultAttachmentTypes
+	}
+
+	allowed := make(map[string]bool)
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(entry), "."))
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			allowed[entry] = true
+			continue
+		}
+		// MIME 类型条目展开为对应的扩展名
+		for ext, contentType := range attachmentContentTypes {
+			if entry == contentType || (strings.HasSuffix(entry, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(entry, "*"))) {
+				allowed[ext] = true
+			}
+		}
+	}
+	return allowed
+}
+
+// detectAttachmentType 按文件头识别内容类型，并校验与扩展名一致且在允许范围内
+func detectAttachmentType(fileName string, data []byte, allowed map[string]bool) (string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if ext == "" || !allowed[ext] {
+		return "", errors.New("不支持的文件类型")
+	}
+
+	expected, known := attachmentContentTypes[ext]
+	if !known {
+		return "", errors.New("不支持的文件类型")
+	}
+
+	detected := http.DetectContentType(data)
+	if idx := strings.Index(detected, ";"); idx > 0 {
+		detected = detected[:idx]
+	}
+	if detected != expected {
+		return "", errors.New("文件内容与扩展名不符")
+	}
+	return detected, nil
+}
+
+// StoreTicketAttachment 校验并保存工单附件到私有目录
+// 按配置检查大小和类型（以文件头为准），图片会清理 EXIF 等元数据
+func (s *SupportService) StoreTicketAttachment(ticketNo string, file *multipart.FileHeader) (*StoredAttachment, error) {
+	config, _ := s.GetSupportConfig()
+	maxSize := int64(config.MaxAttachmentSize) * 1024 * 1024
+	if maxSize <= 0 {
+		maxSize = 5 * 1024 * 1024 // 默认5MB
+	}
+	if file.Size > maxSize {
+		return nil, fmt.Errorf("文件大小超过限制（%dMB）", maxSize/1024/1024)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, errors.New("读取文件失败")
+	}
+	defer src.Close()
+
+	// 按实际读取的字节数判断大小，不信任客户端声明
+	data, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+	if err != nil {
+		return nil, errors.New("读取文件失败")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("文件大小超过限制（%dMB）", maxSize/1024/1024)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("文件内容为空")
+	}
+
+	fileName := filepath.Base(file.Filename)
+	mimeType, err := detectAttachmentType(fileName, data, allowedAttachmentTypes(config.AllowedFileTypes))
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(mimeType, "image/") {
+		data, err = StripImageMetadata(mimeType, data)
+		if err != nil {
+			return nil, errors.New("图片文件已损坏")
+		}
+	}
+
+	// 随机文件名，避免原始文件名带来的路径与猜测问题
+	b := make([]byte, 16)
+	rand.Read(b)
+	key := ticketObjectPrefix + filepath.Base(ticketNo) + "/" + hex.EncodeToString(b) + strings.ToLower(filepath.Ext(fileName))
+	if err := storage.Default().Put(key, bytes.NewReader(data), int64(len(data)), mimeType); err != nil {
+		return nil, errors.New("文件保存失败")
+	}
+
+	return &StoredAttachment{
+		FileName: fileName,
+		FilePath: key,
+		MimeType: mimeType,
+		FileSize: int64(len(data)),
+	}, nil
+}
+
+// CreateAttachmentReply 以附件消息回复工单并保存附件记录
+// 消息中的文件地址为附件访问地址，读取消息时再签名
+func (s *SupportService) CreateAttachmentReply(ticketID uint, senderType string, senderID uint, senderName, content string, isInternal bool, stored *StoredAttachment) (*model.SupportMessage, error) {
+	attachment, err := s.SaveAttachment(ticketID, 0, stored.FileName, stored.FilePath, stored.MimeType, stored.FileSize)
+	if err != nil {
+		storage.Default().Delete(stored.FilePath)
+		return nil, err
+	}
+
+	fileURL := AttachmentURLPrefix + strconv.FormatUint(uint64(attachment.ID), 10)
+	msg, err := s.ReplyTicketWithAttachment(ticketID, senderType, senderID, senderName, content, isInternal, fileURL, stored.FileName, stored.FileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	s.repo.GetDB().Model(attachment).Update("message_id", msg.ID)
+	msg.FileURL = SignAttachmentURL(attachment.ID)
+	return msg, nil
+}
+
+// GetAttachmentByID 获取附件记录
+func (s *SupportService) GetAttachmentByID(id uint) (*model.SupportAttachment, error) {
+	var attachment model.SupportAttachment
+	if err := s.repo.GetDB().First(&attachment, id).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// OpenAttachmentFile 读取附件文件内容
+// 对象键从对象存储读取，迁移前的旧记录（本地路径）直接读取本地文件
+func OpenAttachmentFile(attachment *model.SupportAttachment) (io.ReadCloser, *storage.ObjectInfo, error) {
+	if strings.HasPrefix(attachment.FilePath, ticketObjectPrefix) {
+		return storage.Default().Get(attachment.FilePath)
+	}
+
+	f, err := os.Open(attachment.FilePath)
+	if err != nil {
+		return nil, nil, storage.ErrNotFound
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		f.Close()
+		return nil, nil, storage.ErrNotFound
+	}
+	return f, &storage.ObjectInfo{Key: attachment.FilePath, Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+// IsAttachmentInternal 附件是否属于内部备注（仅客服可见）
+func (s *SupportService) IsAttachmentInternal(attachment *model.SupportAttachment) bool {
+	if attachment.MessageID == 0 {
+		return false
+	}
+	var msg model.SupportMessage
+	if err := s.repo.GetDB().Select("is_internal").First(&msg, attachment.MessageID).Error; err != nil {
+		return false
+	}
+	return msg.IsInternal
+}
+
+// signMessageAttachments 将消息中的附件地址替换为短期签名地址（兼容旧的 /uploads 地址）
+func (s *SupportService) signMessageAttachments(messages []model.SupportMessage) {
+	var messageIDs []uint
+	for _, msg := range messages {
+		if msg.FileURL != "" {
+			messageIDs = append(messageIDs, msg.ID)
+		}
+	}
+	if len(messageIDs) == 0 {
+		return
+	}
+
+	var attachments []model.SupportAttachment
+	s.repo.GetDB().Where("message_id IN ?", messageIDs).Find(&attachments)
+	byMessage := make(map[uint]uint, len(attachments))
+	for _, a := range attachments {
+		byMessage[a.MessageID] = a.ID
+	}
+
+	for i := range messages {
+		if messages[i].FileURL == "" {
+			continue
+		}
+		if id, ok := byMessage[messages[i].ID]; ok {
+			messages[i].FileURL = SignAttachmentURL(id)
+		} else {
+			messages[i].FileURL = ""
+		}
+	}
+}
+
+// SignAttachmentURL 生成附件的短期签名访问地址
+func SignAttachmentURL(attachmentID uint) string {
+	expires := time.Now().Add(attachmentURLTTL).Unix()
+	return fmt.Sprintf("%s%d?expires=%d&sig=%s", AttachmentURLPrefix, attachmentID, expires, attachmentSign(attachmentID, expires))
+}
+
+// VerifyAttachmentSignature 校验附件签名地址
+func VerifyAttachmentSignature(attachmentID uint, expiresStr, sig string) bool {
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || sig == "" || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(attachmentSign(attachmentID, expires)))
+}
+
+// attachmentSign 计算附件地址签名（使用配置加密密钥）
+func attachmentSign(attachmentID uint, expires int64) string {
+	mac := hmac.New(sha256.New, utils.GetConfigEncryptionKey())
+	mac.Write([]byte(fmt.Sprintf("attachment:%d:%d", attachmentID, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
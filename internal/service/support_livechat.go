@@ -8,6 +8,7 @@ import (
 	"user-frontend/internal/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CreateLiveChat 创建实时聊天会话
@@ -46,7 +47,10 @@ func (s *SupportService) GetLiveChatBySession(sessionID string) (*model.LiveChat
 }
 
 // GetWaitingChats 获取等待接入的聊天
+// 启用自动分配时先按分配策略为等待中的聊天分配客服，返回仍在等待的聊天
 func (s *SupportService) GetWaitingChats() ([]model.LiveChat, error) {
+	s.dispatchWaitingChats()
+
 	var chats []model.LiveChat
 	if err := s.repo.GetDB().Where("status = ?", model.ChatStatusWaiting).Order("created_at ASC").Find(&chats).Error; err != nil {
 		return nil, err
@@ -54,22 +58,35 @@ func (s *SupportService) GetWaitingChats() ([]model.LiveChat, error) {
 	return chats, nil
 }
 
-// AcceptChat 客服接入聊天
+// AcceptChat 客服接入聊天（同一聊天只能被一位客服接入）
 func (s *SupportService) AcceptChat(chatID, staffID uint, staffName string) error {
-	return s.repo.GetDB().Model(&model.LiveChat{}).Where("id = ?", chatID).Updates(map[string]interface{}{
-		"staff_id":   staffID,
-		"staff_name": staffName,
-		"status":     model.ChatStatusActive,
-	}).Error
+	return s.acceptChat(chatID, staffID, staffName, true)
 }
 
-// EndChat 结束聊天
+// EndChat 结束聊天，并释放接待客服的名额
 func (s *SupportService) EndChat(chatID uint) error {
+	var chat model.LiveChat
+	if err := s.repo.GetDB().First(&chat, chatID).Error; err != nil {
+		return err
+	}
+
 	now := time.Now()
-	return s.repo.GetDB().Model(&model.LiveChat{}).Where("id = ?", chatID).Updates(map[string]interface{}{
-		"status":   model.ChatStatusEnded,
-		"ended_at": now,
-	}).Error
+	return s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.LiveChat{}).
+			Where("id = ? AND status <> ?", chatID, model.ChatStatusEnded).
+			Updates(map[string]interface{}{
+				"status":   model.ChatStatusEnded,
+				"ended_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		// 重复结束时不再重复释放名额
+		if result.RowsAffected > 0 && chat.Status == model.ChatStatusActive {
+			s.releaseStaffSlot(tx, chat.StaffID)
+		}
+		return nil
+	})
 }
 
 // SendChatMessage 发送聊天消息
@@ -0,0 +1,439 @@
+// Package service 提供业务逻辑服务
+// support_macro.go - 客服快捷回复（宏）：占位符渲染、一键操作、使用统计
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"user-frontend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// macroPlaceholderPattern 匹配 {{name}} 形式的占位符（允许两侧空格）
+var macroPlaceholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// MacroPlaceholders 支持的占位符及说明
+var MacroPlaceholders = map[string]string{
+	"username":         "用户名",
+	"ticket_no":        "工单编号",
+	"subject":          "工单主题",
+	"order_no":         "关联订单号",
+	"product_name":     "关联订单商品名称",
+	"kami_code_masked": "关联订单卡密（脱敏）",
+	"staff_name":       "当前客服名称",
+}
+
+// MacroApplyResult 执行快捷回复的结果
+type MacroApplyResult struct {
+	Message       *model.SupportMessage `json:"message,omitempty"`
+	Ticket        *model.SupportTicket  `json:"ticket"`
+	StatusChanged bool                  `json:"status_changed"`
+	AssignedStaff *model.SupportStaff   `json:"assigned_staff,omitempty"`
+}
+
+// ==========================================
+//         快捷回复管理
+// ==========================================
+
+// GetMacros 获取客服可用的快捷回复（共享 + 个人）
+// scope: shared 仅共享，personal 仅个人，其他为全部；staffID 为0时返回全部共享及所有个人宏（管理后台）
+func (s *SupportService) GetMacros(staffID uint, scope string, onlyEnabled bool) ([]model.SupportMacro, error) {
+	query := s.repo.GetDB().Model(&model.SupportMacro{})
+	switch scope {
+	case "shared":
+		query = query.Where("owner_id = 0")
+	case "personal":
+		if staffID > 0 {
+			query = query.Where("owner_id = ?", staffID)
+		} else {
+			query = query.Where("owner_id > 0")
+		}
+	default:
+		if staffID > 0 {
+			query = query.Where("owner_id = 0 OR owner_id = ?", staffID)
+		}
+	}
+	if onlyEnabled {
+		query = query.Where("status = 1")
+	}
+
+	var macros []model.SupportMacro
+	if err := query.Order("owner_id DESC, sort_order ASC, use_count DESC").Find(&macros).Error; err != nil {
+		return nil, err
+	}
+	return macros, nil
+}
+
+// GetMacro 获取客服可用的快捷回复（共享或本人的个人宏），staffID 为0时不校验归属
+func (s *SupportService) GetMacro(id, staffID uint) (*model.SupportMacro, error) {
+	var macro model.SupportMacro
+	if err := s.repo.GetDB().First(&macro, id).Error; err != nil {
+		return nil, errors.New("快捷回复不存在")
+	}
+	if staffID > 0 && !macro.IsShared() && macro.OwnerID != staffID {
+		return nil, errors.New("快捷回复不存在")
+	}
+	return &macro, nil
+}
+
+// SaveMacro 创建或更新快捷回复（ID 为0时创建）
+// canManageShared 为 false 时只能维护自己的个人宏
+func (s *SupportService) SaveMacro(macro *model.SupportMacro, canManageShared bool) error {
+	macro.Name = strings.TrimSpace(macro.Name)
+	macro.Shortcut = strings.TrimSpace(macro.Shortcut)
+	macro.AddTags = NormalizeTagList(macro.AddTags)
+	if macro.Name == "" {
+		return errors.New("名称不能为空")
+	}
+	if strings.TrimSpace(macro.Content) == "" && macro.SetStatus == nil && macro.AddTags == "" &&
+		macro.AssignStaffID == 0 && !macro.AssignToSelf {
+		return errors.New("回复内容和操作至少设置一项")
+	}
+	if macro.SetStatus != nil && (*macro.SetStatus < model.TicketStatusPending || *macro.SetStatus > model.TicketStatusClosed) {
+		return errors.New("无效的工单状态")
+	}
+	if macro.AssignStaffID > 0 {
+		if _, err := s.GetStaffByID(macro.AssignStaffID); err != nil {
+			return errors.New("分配的客服不存在")
+		}
+	}
+	if macro.IsShared() && !canManageShared {
+		return errors.New("无权维护共享快捷回复")
+	}
+
+	if macro.ID == 0 {
+		status := macro.Status
+		if err := s.repo.GetDB().Create(macro).Error; err != nil {
+			return err
+		}
+		// status 字段有默认值，创建时零值会被忽略，禁用状态需单独写入
+		if status == 0 {
+			macro.Status = 0
+			return s.repo.GetDB().Model(macro).Update("status", 0).Error
+		}
+		return nil
+	}
+
+	var existing model.SupportMacro
+	if err := s.repo.GetDB().First(&existing, macro.ID).Error; err != nil {
+		return errors.New("快捷回复不存在")
+	}
+	if !canManageShared && existing.OwnerID != macro.OwnerID {
+		return errors.New("无权修改此快捷回复")
+	}
+	// 个人宏保持原归属，仅可在共享与个人之间切换
+	if !macro.IsShared() && existing.OwnerID > 0 {
+		macro.OwnerID = existing.OwnerID
+	}
+	macro.UseCount = existing.UseCount
+	macro.LastUsedAt = existing.LastUsedAt
+	macro.CreatedAt = existing.CreatedAt
+	return s.repo.GetDB().Save(macro).Error
+}
+
+// DeleteMacro 删除快捷回复，ownerID 不为0时只能删除该客服的个人宏
+func (s *SupportService) DeleteMacro(id, ownerID uint) error {
+	query := s.repo.GetDB().Where("id = ?", id)
+	if ownerID > 0 {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+	result := query.Delete(&model.SupportMacro{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("快捷回复不存在或无权删除")
+	}
+	return nil
+}
+
+// ==========================================
+//         占位符渲染
+// ==========================================
+
+// maskKamiCode 卡密脱敏（多个卡密按行分别处理），保留首尾字符
+func maskKamiCode(kamiCode string) string {
+	var lines []string
+	for _, code := range strings.Split(kamiCode, "\n") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		runes := []rune(code)
+		keep := 4
+		if len(runes) <= 8 {
+			keep = len(runes) / 4
+		}
+		lines = append(lines, string(runes[:keep])+strings.Repeat("*", len(runes)-keep*2)+string(runes[len(runes)-keep:]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ticketRelatedOrder 获取工单关联的订单（须属于工单提交人，游客按邮箱校验）
+func (s *SupportService) ticketRelatedOrder(ticket *model.SupportTicket) *model.Order {
+	orderNo := strings.TrimSpace(ticket.RelatedOrder)
+	if orderNo == "" {
+		return nil
+	}
+	if ticket.UserID > 0 {
+		order, err := s.repo.GetOrderByOrderNo(orderNo)
+		if err != nil || order.UserID != ticket.UserID {
+			return nil
+		}
+		return order
+	}
+	if ticket.Email == "" {
+		return nil
+	}
+	order, err := s.repo.GetOrderByOrderNoAndEmail(orderNo, ticket.Email)
+	if err != nil {
+		return nil
+	}
+	return order
+}
+
+// macroTicketVariables 获取工单的占位符取值
+func (s *SupportService) macroTicketVariables(ticket *model.SupportTicket, staffName string) map[string]string {
+	vars := map[string]string{
+		"username":   ticket.Username,
+		"ticket_no":  ticket.TicketNo,
+		"subject":    ticket.Subject,
+		"staff_name": staffName,
+	}
+	if order := s.ticketRelatedOrder(ticket); order != nil {
+		vars["order_no"] = order.OrderNo
+		vars["product_name"] = order.ProductName
+		if order.KamiCode != "" {
+			vars["kami_code_masked"] = maskKamiCode(order.KamiCode)
+		}
+	}
+	return vars
+}
+
+// renderMacroContent 替换占位符，返回渲染结果和缺少取值的占位符
+func renderMacroContent(content string, vars map[string]string) (string, []string) {
+	var missing []string
+	seen := make(map[string]bool)
+	rendered := macroPlaceholderPattern.ReplaceAllStringFunc(content, func(match string) string {
+		name := macroPlaceholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[name]; ok && value != "" {
+			return value
+		}
+		if !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return ""
+	})
+	return rendered, missing
+}
+
+// PreviewMacro 预览快捷回复在指定工单上的渲染结果
+func (s *SupportService) PreviewMacro(macroID, ticketID, staffID uint, staffName string) (string, []string, error) {
+	macro, err := s.GetMacro(macroID, staffID)
+	if err != nil {
+		return "", nil, err
+	}
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return "", nil, errors.New("工单不存在")
+	}
+	content, missing := renderMacroContent(macro.Content, s.macroTicketVariables(ticket, staffName))
+	return content, missing, nil
+}
+
+// ==========================================
+//         执行快捷回复
+// ==========================================
+
+// ApplyMacroToTicket 在工单上执行快捷回复：发送回复并依次执行设置状态、添加标签、分配操作
+// content 不为空时使用客服编辑后的内容代替模板渲染结果
+func (s *SupportService) ApplyMacroToTicket(macroID, ticketID, staffID uint, staffName, content string) (*MacroApplyResult, error) {
+	macro, err := s.GetMacro(macroID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	if macro.Status != 1 {
+		return nil, errors.New("快捷回复已禁用")
+	}
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return nil, errors.New("工单不存在")
+	}
+	if ticket.Status == model.TicketStatusMerged {
+		return nil, errors.New("工单已合并，无法操作")
+	}
+
+	result := &MacroApplyResult{}
+
+	if strings.TrimSpace(content) == "" {
+		content, _ = renderMacroContent(macro.Content, s.macroTicketVariables(ticket, staffName))
+	}
+	if strings.TrimSpace(content) != "" {
+		msg, err := s.ReplyTicket(ticketID, "staff", staffID, staffName, content, macro.IsInternal)
+		if err != nil {
+			return nil, err
+		}
+		result.Message = msg
+	}
+
+	if macro.AddTags != "" {
+		s.AddTicketTags(ticketID, macro.AddTags)
+	}
+
+	assignID := macro.AssignStaffID
+	if macro.AssignToSelf {
+		assignID = staffID
+	}
+	if assignID > 0 && assignID != ticket.AssignedTo {
+		if staff, err := s.GetStaffByID(assignID); err == nil {
+			if err := s.AssignTicket(ticketID, staff.ID, staff.Nickname); err == nil {
+				result.AssignedStaff = staff
+			}
+		}
+	}
+
+	// 状态操作最后执行，覆盖回复和分配带来的状态变化
+	if macro.SetStatus != nil {
+		if err := s.UpdateTicketStatus(ticketID, *macro.SetStatus, staffName); err == nil {
+			result.StatusChanged = true
+		}
+	}
+
+	s.recordMacroUsage(macro.ID, staffID, ticketID, 0)
+
+	result.Ticket, _ = s.GetTicketByID(ticketID)
+	return result, nil
+}
+
+// ApplyMacroToChat 在实时聊天中发送快捷回复（聊天不执行工单操作）
+func (s *SupportService) ApplyMacroToChat(macroID, chatID, staffID uint, staffName, content string) (*model.LiveChatMessage, error) {
+	macro, err := s.GetMacro(macroID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	if macro.Status != 1 {
+		return nil, errors.New("快捷回复已禁用")
+	}
+	var chat model.LiveChat
+	if err := s.repo.GetDB().First(&chat, chatID).Error; err != nil {
+		return nil, errors.New("聊天会话不存在")
+	}
+	if chat.Status != model.ChatStatusActive {
+		return nil, errors.New("聊天未在进行中")
+	}
+
+	if strings.TrimSpace(content) == "" {
+		content, _ = renderMacroContent(macro.Content, map[string]string{
+			"username":   chat.Username,
+			"staff_name": staffName,
+		})
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, errors.New("回复内容为空")
+	}
+
+	msg, err := s.SendChatMessage(chatID, "staff", staffID, staffName, content, "text")
+	if err != nil {
+		return nil, err
+	}
+	s.recordMacroUsage(macro.ID, staffID, 0, chatID)
+	return msg, nil
+}
+
+// recordMacroUsage 记录快捷回复使用
+func (s *SupportService) recordMacroUsage(macroID, staffID, ticketID, chatID uint) {
+	now := time.Now()
+	s.repo.GetDB().Model(&model.SupportMacro{}).Where("id = ?", macroID).UpdateColumns(map[string]interface{}{
+		"use_count":    gorm.Expr("use_count + 1"),
+		"last_used_at": now,
+	})
+	s.repo.GetDB().Create(&model.SupportMacroUsage{
+		MacroID:  macroID,
+		StaffID:  staffID,
+		TicketID: ticketID,
+		ChatID:   chatID,
+	})
+}
+
+// AddTicketTags 为工单添加标签（自动去重）
+func (s *SupportService) AddTicketTags(ticketID uint, tags string) error {
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return err
+	}
+	merged := NormalizeTagList(ticket.Tags + "," + tags)
+	return s.repo.GetDB().Model(&model.SupportTicket{}).Where("id = ?", ticketID).Update("tags", merged).Error
+}
+
+// ==========================================
+//         使用统计
+// ==========================================
+
+// MacroUsageStat 快捷回复使用统计
+type MacroUsageStat struct {
+	MacroID uint   `json:"macro_id"`
+	Name    string `json:"name"`
+	Count   int64  `json:"count"`
+}
+
+// StaffMacroUsageStat 客服使用快捷回复统计
+type StaffMacroUsageStat struct {
+	StaffID uint   `json:"staff_id"`
+	Name    string `json:"name"`
+	Count   int64  `json:"count"`
+}
+
+// GetMacroStats 获取最近 days 天的快捷回复使用统计
+func (s *SupportService) GetMacroStats(days int) (map[string]interface{}, error) {
+	if days <= 0 {
+		days = 30
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	db := s.repo.GetDB()
+
+	var total int64
+	db.Model(&model.SupportMacroUsage{}).Where("created_at >= ?", since).Count(&total)
+
+	var byMacro []MacroUsageStat
+	err := db.Model(&model.SupportMacroUsage{}).
+		Select("support_macro_usages.macro_id AS macro_id, support_macros.name AS name, COUNT(*) AS count").
+		Joins("LEFT JOIN support_macros ON support_macros.id = support_macro_usages.macro_id").
+		Where("support_macro_usages.created_at >= ?", since).
+		Group("support_macro_usages.macro_id, support_macros.name").
+		Order("count DESC").
+		Limit(20).
+		Scan(&byMacro).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var byStaff []StaffMacroUsageStat
+	err = db.Model(&model.SupportMacroUsage{}).
+		Select("support_macro_usages.staff_id AS staff_id, support_staff.nickname AS name, COUNT(*) AS count").
+		Joins("LEFT JOIN support_staff ON support_staff.id = support_macro_usages.staff_id").
+		Where("support_macro_usages.created_at >= ?", since).
+		Group("support_macro_usages.staff_id, support_staff.nickname").
+		Order("count DESC").
+		Scan(&byStaff).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 工单回复中使用快捷回复的比例
+	var staffReplies, macroTicketUses int64
+	db.Model(&model.SupportMessage{}).Where("sender_type = ? AND created_at >= ?", "staff", since).Count(&staffReplies)
+	db.Model(&model.SupportMacroUsage{}).Where("ticket_id > 0 AND created_at >= ?", since).Count(&macroTicketUses)
+
+	return map[string]interface{}{
+		"days":        days,
+		"total":       total,
+		"macro_rate":  formatRate(macroTicketUses, staffReplies),
+		"top_macros":  byMacro,
+		"staff_usage": byStaff,
+	}, nil
+}
@@ -84,3 +84,31 @@ func (s *SupportService) NotifyStaffOnNewTicket(ticket *model.SupportTicket) {
 		}
 	}
 }
+
+// NotifyStaffOnEscalation 工单 SLA 超时升级时邮件通知主管
+func (s *SupportService) NotifyStaffOnEscalation(ticket *model.SupportTicket, supervisor *model.SupportStaff, reason string) {
+	config, _ := s.GetSupportConfig()
+	if !config.EnableEmailNotify || supervisor.Email == "" {
+		return
+	}
+
+	if supportEmailSvc == nil {
+		return
+	}
+
+	subject := fmt.Sprintf("工单超时升级 [%s] %s", ticket.TicketNo, ticket.Subject)
+	body := fmt.Sprintf(`
+<h3>有工单超出 SLA 时限，已升级给您处理</h3>
+<div style="background:#fff4e5;padding:15px;border-radius:5px;margin:15px 0;">
+<p><strong>工单编号：</strong>%s</p>
+<p><strong>用户：</strong>%s</p>
+<p><strong>主题：</strong>%s</p>
+<p><strong>分类：</strong>%s</p>
+<p><strong>优先级：</strong>%s</p>
+<p><strong>超时原因：</strong>%s</p>
+</div>
+<p>请尽快登录客服后台处理。</p>
+`, ticket.TicketNo, ticket.Username, ticket.Subject, ticket.Category, model.GetTicketPriorityText(ticket.Priority), reason)
+
+	go supportEmailSvc.SendEmail(supervisor.Email, subject, body)
+}
@@ -0,0 +1,372 @@
+// Package service 提供业务逻辑服务
+// support_routing.go - 工单与实时聊天自动分配（分配策略、技能匹配、负载计数）
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"user-frontend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// normalizeAssignStrategy 规范化分配策略，未知策略按负载最低处理
+func normalizeAssignStrategy(strategy string) string {
+	switch strategy {
+	case model.AssignStrategyRoundRobin, model.AssignStrategySkillMatch, model.AssignStrategySticky:
+		return strategy
+	default:
+		return model.AssignStrategyLeastLoaded
+	}
+}
+
+// NormalizeTagList 规范化逗号分隔的标签列表（客服技能、工单标签）：去除空白和重复项
+func NormalizeTagList(skills string) string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, skill := range strings.Split(skills, ",") {
+		skill = strings.TrimSpace(skill)
+		key := strings.ToLower(skill)
+		if skill == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, skill)
+	}
+	return strings.Join(result, ",")
+}
+
+// staffHasSkill 判断客服是否具备指定分类的技能
+func staffHasSkill(staff *model.SupportStaff, category string) bool {
+	category = strings.TrimSpace(category)
+	if category == "" || staff.Skills == "" {
+		return false
+	}
+	for _, skill := range strings.Split(staff.Skills, ",") {
+		if strings.EqualFold(strings.TrimSpace(skill), category) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateStaffSkills 更新客服技能标签
+func (s *SupportService) UpdateStaffSkills(id uint, skills string) error {
+	return s.repo.GetDB().Model(&model.SupportStaff{}).Where("id = ?", id).
+		Update("skills", NormalizeTagList(skills)).Error
+}
+
+// ==========================================
+//         负载计数（原子操作）
+// ==========================================
+
+// claimStaffSlot 原子占用客服的一个处理名额
+// enforceMax 为 true 时仅在未满负载时占用（自动分配），手动分配不受上限限制
+func (s *SupportService) claimStaffSlot(db *gorm.DB, staffID uint, enforceMax bool) bool {
+	query := db.Model(&model.SupportStaff{}).Where("id = ?", staffID)
+	if enforceMax {
+		query = query.Where("current_load < max_tickets")
+	}
+	result := query.Update("current_load", gorm.Expr("current_load + 1"))
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// releaseStaffSlot 原子释放客服的一个处理名额
+func (s *SupportService) releaseStaffSlot(db *gorm.DB, staffID uint) {
+	if staffID == 0 {
+		return
+	}
+	db.Model(&model.SupportStaff{}).Where("id = ? AND current_load > 0", staffID).
+		Update("current_load", gorm.Expr("current_load - 1"))
+}
+
+// ==========================================
+//         候选客服排序
+// ==========================================
+
+// routingCandidates 获取在线且未满负载的客服
+func (s *SupportService) routingCandidates() []model.SupportStaff {
+	var staff []model.SupportStaff
+	s.repo.GetDB().Where("status = 1 AND current_load < max_tickets").Find(&staff)
+	return staff
+}
+
+// rankCandidates 按分配策略对候选客服排序
+//   - least_loaded: 负载最低优先
+//   - round_robin: 最久未被分配的优先
+//   - skill_match: 具备分类技能的优先，其次负载最低
+//   - sticky: 该用户上次的客服优先，其次同 skill_match
+func rankCandidates(candidates []model.SupportStaff, strategy, category string, stickyStaffID uint) []model.SupportStaff {
+	byLoad := func(a, b *model.SupportStaff) bool {
+		// 按负载率比较，容量不同的客服也能均衡分配
+		la := float64(a.CurrentLoad) / float64(maxInt(a.MaxTickets, 1))
+		lb := float64(b.CurrentLoad) / float64(maxInt(b.MaxTickets, 1))
+		if la != lb {
+			return la < lb
+		}
+		return a.ID < b.ID
+	}
+
+	ranked := append([]model.SupportStaff(nil), candidates...)
+	switch strategy {
+	case model.AssignStrategyRoundRobin:
+		sort.SliceStable(ranked, func(i, j int) bool {
+			a, b := ranked[i].LastAssignAt, ranked[j].LastAssignAt
+			switch {
+			case a == nil && b == nil:
+				return ranked[i].ID < ranked[j].ID
+			case a == nil:
+				return true
+			case b == nil:
+				return false
+			case !a.Equal(*b):
+				return a.Before(*b)
+			}
+			return ranked[i].ID < ranked[j].ID
+		})
+	case model.AssignStrategySkillMatch, model.AssignStrategySticky:
+		sort.SliceStable(ranked, func(i, j int) bool {
+			if strategy == model.AssignStrategySticky && stickyStaffID > 0 {
+				si, sj := ranked[i].ID == stickyStaffID, ranked[j].ID == stickyStaffID
+				if si != sj {
+					return si
+				}
+			}
+			mi, mj := staffHasSkill(&ranked[i], category), staffHasSkill(&ranked[j], category)
+			if mi != mj {
+				return mi
+			}
+			return byLoad(&ranked[i], &ranked[j])
+		})
+	default:
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return byLoad(&ranked[i], &ranked[j])
+		})
+	}
+	return ranked
+}
+
+// maxInt 返回较大值
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// lastStaffForUser 获取该用户最近一次对接的客服（工单或聊天）
+func (s *SupportService) lastStaffForUser(userID uint, guestToken string) uint {
+	if userID == 0 && guestToken == "" {
+		return 0
+	}
+
+	db := s.repo.GetDB()
+	userScope := func(q *gorm.DB) *gorm.DB {
+		if userID > 0 {
+			return q.Where("user_id = ?", userID)
+		}
+		return q.Where("guest_token = ?", guestToken)
+	}
+
+	var ticket model.SupportTicket
+	ticketErr := userScope(db.Where("assigned_to > 0")).Order("updated_at DESC").First(&ticket).Error
+	var chat model.LiveChat
+	chatErr := userScope(db.Where("staff_id > 0")).Order("updated_at DESC").First(&chat).Error
+
+	switch {
+	case ticketErr == nil && chatErr == nil:
+		if chat.UpdatedAt.After(ticket.UpdatedAt) {
+			return chat.StaffID
+		}
+		return ticket.AssignedTo
+	case ticketErr == nil:
+		return ticket.AssignedTo
+	case chatErr == nil:
+		return chat.StaffID
+	}
+	return 0
+}
+
+// pickStaff 按配置的策略选择客服并原子占用名额，无可用客服时返回 nil
+func (s *SupportService) pickStaff(category string, userID uint, guestToken string) *model.SupportStaff {
+	config, _ := s.GetSupportConfig()
+	strategy := normalizeAssignStrategy(config.AssignStrategy)
+
+	var stickyID uint
+	if strategy == model.AssignStrategySticky {
+		stickyID = s.lastStaffForUser(userID, guestToken)
+	}
+
+	for _, staff := range rankCandidates(s.routingCandidates(), strategy, category, stickyID) {
+		// 并发分配时名额可能已被占满，占用失败则尝试下一位
+		if !s.claimStaffSlot(s.repo.GetDB(), staff.ID, true) {
+			continue
+		}
+		now := time.Now()
+		s.repo.GetDB().Model(&model.SupportStaff{}).Where("id = ?", staff.ID).Update("last_assign_at", now)
+		staff.CurrentLoad++
+		staff.LastAssignAt = &now
+		return &staff
+	}
+	return nil
+}
+
+// ==========================================
+//         工单自动分配
+// ==========================================
+
+// AutoAssignTicket 按分配策略自动分配工单
+// 未启用自动分配、工单已分配或没有可用客服时返回 nil
+func (s *SupportService) AutoAssignTicket(ticketID uint) (*model.SupportStaff, error) {
+	config, _ := s.GetSupportConfig()
+	if !config.EnableAutoAssign {
+		return nil, nil
+	}
+
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.AssignedTo > 0 || !isTicketOpen(ticket.Status) {
+		return nil, nil
+	}
+
+	staff := s.pickStaff(ticket.Category, ticket.UserID, ticket.GuestToken)
+	if staff == nil {
+		return nil, nil // 没有可用客服，不分配
+	}
+
+	// 仅在工单仍未分配时写入，避免与手动分配冲突
+	result := s.repo.GetDB().Model(&model.SupportTicket{}).
+		Where("id = ? AND assigned_to = 0", ticketID).
+		Updates(map[string]interface{}{
+			"assigned_to":   staff.ID,
+			"assigned_name": staff.Nickname,
+			"status":        model.TicketStatusProcessing,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		s.releaseStaffSlot(s.repo.GetDB(), staff.ID)
+		return nil, result.Error
+	}
+
+	return staff, nil
+}
+
+// ==========================================
+//         实时聊天自动分配
+// ==========================================
+
+// AutoAssignChat 按分配策略自动接入等待中的聊天
+// 分配成功后发送系统消息并通过 WebSocket 通知访客和客服
+func (s *SupportService) AutoAssignChat(chatID uint) (*model.SupportStaff, error) {
+	config, _ := s.GetSupportConfig()
+	if !config.EnableAutoAssign {
+		return nil, nil
+	}
+
+	var chat model.LiveChat
+	if err := s.repo.GetDB().First(&chat, chatID).Error; err != nil {
+		return nil, err
+	}
+	if chat.Status != model.ChatStatusWaiting {
+		return nil, nil
+	}
+
+	staff := s.pickStaff("", chat.UserID, chat.GuestToken)
+	if staff == nil {
+		return nil, nil
+	}
+
+	if err := s.acceptChat(chatID, staff.ID, staff.Nickname, false); err != nil {
+		s.releaseStaffSlot(s.repo.GetDB(), staff.ID)
+		return nil, nil
+	}
+
+	sysMsg, _ := s.SendChatMessage(chatID, "system", 0, "系统", "客服 "+staff.Nickname+" 已接入对话", "text")
+
+	hub := GetWSHub()
+	now := time.Now().Unix()
+	hub.BroadcastToChat(chatID, &WSMessage{
+		Type:   "chat_accepted",
+		ChatID: chatID,
+		Data: map[string]interface{}{
+			"staff_id":   staff.ID,
+			"staff_name": staff.Nickname,
+		},
+		Timestamp: now,
+	}, nil)
+	if sysMsg != nil {
+		hub.BroadcastToChat(chatID, &WSMessage{Type: "new_message", ChatID: chatID, Data: sysMsg, Timestamp: now}, nil)
+	}
+	hub.SendToStaff(staff.ID, &WSMessage{
+		Type:      "new_chat_assignment",
+		ChatID:    chatID,
+		Data:      map[string]interface{}{"chat_id": chatID, "username": chat.Username},
+		Timestamp: now,
+	})
+
+	return staff, nil
+}
+
+// dispatchWaitingChats 尝试为所有等待中的聊天自动分配客服（按等待时间先后）
+func (s *SupportService) dispatchWaitingChats() {
+	config, _ := s.GetSupportConfig()
+	if !config.EnableAutoAssign {
+		return
+	}
+
+	var chatIDs []uint
+	s.repo.GetDB().Model(&model.LiveChat{}).Where("status = ?", model.ChatStatusWaiting).
+		Order("created_at ASC").Pluck("id", &chatIDs)
+	for _, id := range chatIDs {
+		staff, _ := s.AutoAssignChat(id)
+		if staff == nil {
+			// 已无可用客服，剩余聊天继续等待
+			break
+		}
+	}
+}
+
+// acceptChat 接入聊天（仅等待中的聊天可接入）
+// claimSlot 为 true 时同时占用客服名额（手动接入），自动分配时名额已提前占用
+func (s *SupportService) acceptChat(chatID, staffID uint, staffName string, claimSlot bool) error {
+	return s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.LiveChat{}).
+			Where("id = ? AND status = ?", chatID, model.ChatStatusWaiting).
+			Updates(map[string]interface{}{
+				"staff_id":   staffID,
+				"staff_name": staffName,
+				"status":     model.ChatStatusActive,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("该聊天已被接入或已结束")
+		}
+		if claimSlot {
+			s.claimStaffSlot(tx, staffID, false)
+		}
+		return nil
+	})
+}
+
+// ==========================================
+//         负载校准
+// ==========================================
+
+// RecalculateStaffLoads 按实际处理中的工单和聊天重新计算所有客服负载
+// 用于修正历史数据或异常中断导致的计数偏差
+func (s *SupportService) RecalculateStaffLoads() error {
+	var staffIDs []uint
+	if err := s.repo.GetDB().Model(&model.SupportStaff{}).Pluck("id", &staffIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range staffIDs {
+		s.UpdateStaffLoad(id)
+	}
+	return nil
+}
@@ -12,7 +12,9 @@ import (
 
 // SupportService 客服支持服务
 type SupportService struct {
-	repo *repository.Repository
+	repo          *repository.Repository
+	manualKamiSvc *ManualKamiService // 手动卡密服务（工作台更换卡密）
+	balanceSvc    *BalanceService    // 余额服务（退款退回余额）
 }
 
 // NewSupportService 创建客服支持服务
@@ -20,6 +22,16 @@ func NewSupportService(repo *repository.Repository) *SupportService {
 	return &SupportService{repo: repo}
 }
 
+// SetManualKamiService 设置手动卡密服务引用
+func (s *SupportService) SetManualKamiService(manualKamiSvc *ManualKamiService) {
+	s.manualKamiSvc = manualKamiSvc
+}
+
+// SetBalanceService 设置余额服务引用
+func (s *SupportService) SetBalanceService(balanceSvc *BalanceService) {
+	s.balanceSvc = balanceSvc
+}
+
 // ==========================================
 //         配置管理
 // ==========================================
@@ -39,6 +51,7 @@ func (s *SupportService) GetSupportConfig() (*model.SupportConfigDB, error) {
 			OfflineMessage:    "当前客服不在线，请留言或提交工单，我们会尽快回复您。",
 			AutoCloseHours:    72,
 			TicketCategories:  `["订单问题","商品咨询","支付问题","账户问题","其他"]`,
+			AssignStrategy:    model.AssignStrategyLeastLoaded,
 		}, nil
 	}
 	return &config, nil
@@ -46,6 +59,8 @@ func (s *SupportService) GetSupportConfig() (*model.SupportConfigDB, error) {
 
 // SaveSupportConfig 保存客服配置
 func (s *SupportService) SaveSupportConfig(config *model.SupportConfigDB) error {
+	config.AssignStrategy = normalizeAssignStrategy(config.AssignStrategy)
+
 	var existing model.SupportConfigDB
 	if err := s.repo.GetDB().First(&existing).Error; err != nil {
 		// 创建新配置
@@ -0,0 +1,544 @@
+// Package service 提供业务逻辑服务
+// support_sla.go - 工单 SLA 策略（截止时间计算、超时升级、达标统计）
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-frontend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ==========================================
+//         SLA 策略管理
+// ==========================================
+
+// GetSLAPolicies 获取所有 SLA 策略
+func (s *SupportService) GetSLAPolicies() ([]model.SupportSLAPolicy, error) {
+	var policies []model.SupportSLAPolicy
+	if err := s.repo.GetDB().Order("id ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// SaveSLAPolicy 创建或更新 SLA 策略（ID 为0时创建）
+func (s *SupportService) SaveSLAPolicy(policy *model.SupportSLAPolicy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	policy.Category = strings.TrimSpace(policy.Category)
+	if policy.Name == "" {
+		return errors.New("策略名称不能为空")
+	}
+	if policy.Priority < 0 || policy.Priority > model.TicketPriorityCritical {
+		return errors.New("无效的优先级")
+	}
+	if policy.FirstResponseMinutes < 0 || policy.ResolutionMinutes < 0 {
+		return errors.New("时限不能为负数")
+	}
+	if policy.FirstResponseMinutes == 0 && policy.ResolutionMinutes == 0 {
+		return errors.New("响应时限和解决时限至少设置一项")
+	}
+
+	if policy.ID == 0 {
+		return s.repo.GetDB().Create(policy).Error
+	}
+
+	var existing model.SupportSLAPolicy
+	if err := s.repo.GetDB().First(&existing, policy.ID).Error; err != nil {
+		return errors.New("策略不存在")
+	}
+	policy.CreatedAt = existing.CreatedAt
+	return s.repo.GetDB().Save(policy).Error
+}
+
+// DeleteSLAPolicy 删除 SLA 策略（已应用到工单的截止时间不受影响）
+func (s *SupportService) DeleteSLAPolicy(id uint) error {
+	return s.repo.GetDB().Delete(&model.SupportSLAPolicy{}, id).Error
+}
+
+// matchSLAPolicy 为工单匹配 SLA 策略
+// 同时指定分类和优先级的策略最优先，其次为仅指定分类、仅指定优先级，最后为通用策略
+func (s *SupportService) matchSLAPolicy(category string, priority int) *model.SupportSLAPolicy {
+	var policies []model.SupportSLAPolicy
+	if err := s.repo.GetDB().Where("enabled = ?", true).Order("id ASC").Find(&policies).Error; err != nil {
+		return nil
+	}
+
+	var best *model.SupportSLAPolicy
+	bestScore := -1
+	for i := range policies {
+		p := &policies[i]
+		score := 0
+		if p.Category != "" {
+			if p.Category != category {
+				continue
+			}
+			score += 2
+		}
+		if p.Priority > 0 {
+			if p.Priority != priority {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
+
+// ==========================================
+//         工作时间计时
+// ==========================================
+
+// supportSchedule 客服工作时间表
+type supportSchedule struct {
+	days  [8]bool // 下标1-7对应周一至周日
+	start int     // 每日开始时间（分钟）
+	end   int     // 每日结束时间（分钟）
+}
+
+// parseClockMinutes 解析 "HH:MM" 为当日分钟数
+func parseClockMinutes(value string) (int, bool) {
+	parts := strings.SplitN(strings.TrimSpace(value), ":", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 24 || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// newSupportSchedule 根据客服配置构建工作时间表
+// 配置无效（无工作日或开始、结束时间相同）时返回 nil，按自然时间计时；结束时间早于开始时间为跨夜班次
+func newSupportSchedule(config *model.SupportConfigDB) *supportSchedule {
+	sch := &supportSchedule{}
+
+	workingDays := config.WorkingDays
+	if workingDays == "" {
+		workingDays = "1,2,3,4,5"
+	}
+	hasDay := false
+	for _, d := range strings.Split(workingDays, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(d))
+		if err == nil && n >= 1 && n <= 7 {
+			sch.days[n] = true
+			hasDay = true
+		}
+	}
+
+	startTime, endTime := config.WorkingHoursStart, config.WorkingHoursEnd
+	if startTime == "" {
+		startTime = "09:00"
+	}
+	if endTime == "" {
+		endTime = "18:00"
+	}
+	var ok1, ok2 bool
+	sch.start, ok1 = parseClockMinutes(startTime)
+	sch.end, ok2 = parseClockMinutes(endTime)
+
+	if !hasDay || !ok1 || !ok2 || sch.start >= 24*60 || sch.end == sch.start {
+		return nil
+	}
+	return sch
+}
+
+// isWorkingDay 判断指定日期是否为工作日
+func (sch *supportSchedule) isWorkingDay(t time.Time) bool {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7 // 周日改为7
+	}
+	return sch.days[weekday]
+}
+
+// add 从 from 开始累加 d 的工作时间，返回到期时间
+// 结束时间早于开始时间表示跨夜班次（如 22:00-06:00），班次按开始当天判断是否为工作日
+func (sch *supportSchedule) add(from time.Time, d time.Duration) time.Time {
+	length := time.Duration(sch.end-sch.start) * time.Minute
+	if length <= 0 {
+		length += 24 * time.Hour
+	}
+
+	cursor := from
+	remaining := d
+	// 从前一天的班次开始（跨夜班次可能覆盖 from），最多向后查找两年，避免配置异常时死循环
+	day := time.Date(from.Year(), from.Month(), from.Day()-1, 0, 0, 0, 0, from.Location())
+	for i := 0; i < 731 && remaining > 0; i++ {
+		workStart := day.Add(time.Duration(sch.start) * time.Minute)
+		workEnd := workStart.Add(length)
+
+		if sch.isWorkingDay(day) && cursor.Before(workEnd) {
+			if cursor.Before(workStart) {
+				cursor = workStart
+			}
+			available := workEnd.Sub(cursor)
+			if remaining <= available {
+				return cursor.Add(remaining)
+			}
+			remaining -= available
+			cursor = workEnd
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return cursor.Add(remaining)
+}
+
+// slaDueTime 计算 SLA 截止时间，minutes 为0表示不限
+func slaDueTime(policy *model.SupportSLAPolicy, sch *supportSchedule, from time.Time, minutes int) *time.Time {
+	if minutes <= 0 {
+		return nil
+	}
+	d := time.Duration(minutes) * time.Minute
+	var due time.Time
+	if policy.UseWorkingHours && sch != nil {
+		due = sch.add(from, d)
+	} else {
+		due = from.Add(d)
+	}
+	return &due
+}
+
+// ==========================================
+//         工单 SLA 跟踪
+// ==========================================
+
+// applyTicketSLA 为新工单匹配策略并计算响应、解决截止时间
+func (s *SupportService) applyTicketSLA(ticket *model.SupportTicket, now time.Time) {
+	policy := s.matchSLAPolicy(ticket.Category, ticket.Priority)
+	if policy == nil {
+		return
+	}
+	config, _ := s.GetSupportConfig()
+	sch := newSupportSchedule(config)
+
+	ticket.SLAPolicyID = policy.ID
+	ticket.ResponseDueAt = slaDueTime(policy, sch, now, policy.FirstResponseMinutes)
+	ticket.ResolutionDueAt = slaDueTime(policy, sch, now, policy.ResolutionMinutes)
+}
+
+// applyReplySLA 根据回复更新工单的 SLA 字段（写入 updates）
+// 客服公开回复时记录首次响应并结束本轮响应计时；
+// 用户回复时工单重新等待客服处理，按策略重新计算响应截止时间
+func (s *SupportService) applyReplySLA(ticketID uint, senderType string, isInternal bool, now time.Time, updates map[string]interface{}) {
+	var ticket model.SupportTicket
+	if err := s.repo.GetDB().First(&ticket, ticketID).Error; err != nil || ticket.SLAPolicyID == 0 {
+		return
+	}
+
+	switch {
+	case senderType == "staff" && !isInternal:
+		if ticket.FirstResponseAt == nil {
+			updates["first_response_at"] = now
+		}
+		if ticket.ResponseDueAt != nil {
+			if now.After(*ticket.ResponseDueAt) {
+				updates["response_breached"] = true
+			}
+			updates["response_due_at"] = nil
+		}
+	case senderType == "user" || senderType == "guest":
+		if ticket.ResponseDueAt != nil || !isTicketOpen(ticket.Status) {
+			return
+		}
+		var policy model.SupportSLAPolicy
+		if err := s.repo.GetDB().First(&policy, ticket.SLAPolicyID).Error; err != nil {
+			return
+		}
+		config, _ := s.GetSupportConfig()
+		if due := slaDueTime(&policy, newSupportSchedule(config), now, policy.FirstResponseMinutes); due != nil {
+			updates["response_due_at"] = *due
+		}
+	}
+}
+
+// applyCloseSLA 工单解决或关闭时记录解决是否超时（写入 updates）
+func (s *SupportService) applyCloseSLA(ticketID uint, now time.Time, updates map[string]interface{}) {
+	var ticket model.SupportTicket
+	if err := s.repo.GetDB().First(&ticket, ticketID).Error; err != nil || ticket.SLAPolicyID == 0 {
+		return
+	}
+	if ticket.ResolutionDueAt != nil && now.After(*ticket.ResolutionDueAt) {
+		updates["resolution_breached"] = true
+	}
+	updates["response_due_at"] = nil
+}
+
+// slaOverdueScope 筛选当前已超时的工单
+// 响应超时仅在等待客服处理（待处理/处理中）时计算；解决超时在工单关闭前均计算
+func slaOverdueScope(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("sla_policy_id > 0").
+		Where("((response_due_at < ? AND status IN (?, ?)) OR (resolution_due_at < ? AND status IN (?, ?, ?)))",
+			now, model.TicketStatusPending, model.TicketStatusProcessing,
+			now, model.TicketStatusPending, model.TicketStatusProcessing, model.TicketStatusReplied)
+}
+
+// isTicketOpen 判断工单是否仍在处理中（未解决、关闭或合并）
+func isTicketOpen(status int) bool {
+	return status == model.TicketStatusPending ||
+		status == model.TicketStatusProcessing ||
+		status == model.TicketStatusReplied
+}
+
+// ==========================================
+//         超时检查与升级
+// ==========================================
+
+// CheckSLABreaches 检查超时工单，标记超时并按策略升级
+// 同一截止时间只升级一次，新的截止时间再次超时会再次升级
+func (s *SupportService) CheckSLABreaches() {
+	now := time.Now()
+	var tickets []model.SupportTicket
+	err := slaOverdueScope(s.repo.GetDB(), now).Find(&tickets).Error
+	if err != nil {
+		return
+	}
+
+	policies := make(map[uint]*model.SupportSLAPolicy)
+	for i := range tickets {
+		ticket := &tickets[i]
+
+		updates := map[string]interface{}{}
+		var reasons []string
+		var latestDue time.Time
+
+		if ticket.ResponseDueAt != nil && ticket.ResponseDueAt.Before(now) &&
+			(ticket.Status == model.TicketStatusPending || ticket.Status == model.TicketStatusProcessing) {
+			if !ticket.ResponseBreached {
+				updates["response_breached"] = true
+			}
+			if ticket.EscalatedAt == nil || ticket.EscalatedAt.Before(*ticket.ResponseDueAt) {
+				reasons = append(reasons, "响应超时")
+				latestDue = *ticket.ResponseDueAt
+			}
+		}
+		if ticket.ResolutionDueAt != nil && ticket.ResolutionDueAt.Before(now) {
+			if !ticket.ResolutionBreached {
+				updates["resolution_breached"] = true
+			}
+			if ticket.EscalatedAt == nil || ticket.EscalatedAt.Before(*ticket.ResolutionDueAt) {
+				reasons = append(reasons, "解决超时")
+				if ticket.ResolutionDueAt.After(latestDue) {
+					latestDue = *ticket.ResolutionDueAt
+				}
+			}
+		}
+
+		if len(updates) > 0 {
+			s.repo.GetDB().Model(&model.SupportTicket{}).Where("id = ?", ticket.ID).Updates(updates)
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		policy, ok := policies[ticket.SLAPolicyID]
+		if !ok {
+			var p model.SupportSLAPolicy
+			if err := s.repo.GetDB().First(&p, ticket.SLAPolicyID).Error; err == nil {
+				policy = &p
+			}
+			policies[ticket.SLAPolicyID] = policy
+		}
+		if policy == nil || !policy.EscalateOnBreach {
+			continue
+		}
+
+		s.escalateTicket(ticket, strings.Join(reasons, "、"), now)
+	}
+}
+
+// escalateTicket 升级超时工单：提升优先级、转交主管并通知
+func (s *SupportService) escalateTicket(ticket *model.SupportTicket, reason string, now time.Time) {
+	priority := ticket.Priority
+	if priority < model.TicketPriorityCritical {
+		priority++
+	}
+
+	updates := map[string]interface{}{
+		"priority":         priority,
+		"escalation_level": ticket.EscalationLevel + 1,
+		"escalated_at":     now,
+	}
+
+	// 已分配给主管的工单不再转交
+	supervisor := s.pickSupervisor(ticket.AssignedTo)
+	previousStaff := ticket.AssignedTo
+	if supervisor != nil && supervisor.ID != ticket.AssignedTo {
+		updates["assigned_to"] = supervisor.ID
+		updates["assigned_name"] = supervisor.Nickname
+		if ticket.Status == model.TicketStatusPending {
+			updates["status"] = model.TicketStatusProcessing
+		}
+	}
+
+	err := s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SupportTicket{}).Where("id = ?", ticket.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if supervisor != nil && supervisor.ID != previousStaff {
+			s.releaseStaffSlot(tx, previousStaff)
+			s.claimStaffSlot(tx, supervisor.ID, false)
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	note := fmt.Sprintf("工单%s，已自动升级（优先级：%s）", reason, model.GetTicketPriorityText(priority))
+	if supervisor != nil && supervisor.ID != previousStaff {
+		note += "，转交主管 " + supervisor.Nickname
+	}
+	s.ReplyTicket(ticket.ID, "system", 0, "系统", note, true)
+
+	ticket.Priority = priority
+	s.notifySLAEscalation(ticket, supervisor, previousStaff, reason)
+}
+
+// pickSupervisor 选择负载最低的主管（优先在线），当前处理人已是主管时返回该主管
+func (s *SupportService) pickSupervisor(currentStaffID uint) *model.SupportStaff {
+	if currentStaffID > 0 {
+		if current, err := s.GetStaffByID(currentStaffID); err == nil && current.Role == model.StaffRoleSupervisor && current.Status >= 0 {
+			return current
+		}
+	}
+
+	var supervisor model.SupportStaff
+	err := s.repo.GetDB().
+		Where("role = ? AND status >= 0", model.StaffRoleSupervisor).
+		Order("status DESC, current_load ASC, id ASC").
+		First(&supervisor).Error
+	if err != nil {
+		return nil
+	}
+	return &supervisor
+}
+
+// notifySLAEscalation 通过 WebSocket 和邮件通知工单升级
+func (s *SupportService) notifySLAEscalation(ticket *model.SupportTicket, supervisor *model.SupportStaff, previousStaff uint, reason string) {
+	msg := &WSMessage{
+		Type:     "sla_escalation",
+		TicketID: ticket.ID,
+		Data: map[string]interface{}{
+			"ticket_id": ticket.ID,
+			"ticket_no": ticket.TicketNo,
+			"subject":   ticket.Subject,
+			"priority":  ticket.Priority,
+			"reason":    reason,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+
+	hub := GetWSHub()
+	if supervisor != nil {
+		hub.SendToStaff(supervisor.ID, msg)
+	} else {
+		// 没有主管时通知所有在线客服
+		hub.BroadcastToAllStaff(msg)
+	}
+	if previousStaff > 0 && (supervisor == nil || supervisor.ID != previousStaff) {
+		hub.SendToStaff(previousStaff, msg)
+	}
+
+	if supervisor != nil {
+		s.NotifyStaffOnEscalation(ticket, supervisor, reason)
+	}
+}
+
+// ==========================================
+//         SLA 统计
+// ==========================================
+
+// GetSLAStats 获取 SLA 达标统计
+func (s *SupportService) GetSLAStats() (map[string]interface{}, error) {
+	return s.slaStats(0)
+}
+
+// GetStaffSLAStats 获取客服个人 SLA 达标统计
+func (s *SupportService) GetStaffSLAStats(staffID uint) (map[string]interface{}, error) {
+	return s.slaStats(staffID)
+}
+
+// slaStats 统计 SLA 达标情况，staffID 为0时统计全部
+func (s *SupportService) slaStats(staffID uint) (map[string]interface{}, error) {
+	db := s.repo.GetDB()
+	base := func() *gorm.DB {
+		q := db.Model(&model.SupportTicket{}).Where("sla_policy_id > 0")
+		if staffID > 0 {
+			q = q.Where("assigned_to = ?", staffID)
+		}
+		return q
+	}
+
+	stats := make(map[string]interface{})
+
+	var tracked, responseMet, responseBreached, resolutionMet, resolutionBreached, escalated, overdue int64
+	base().Count(&tracked)
+	base().Where("first_response_at IS NOT NULL AND response_breached = ?", false).Count(&responseMet)
+	base().Where("response_breached = ?", true).Count(&responseBreached)
+	base().Where("status IN (?, ?) AND resolution_due_at IS NOT NULL AND resolution_breached = ?",
+		model.TicketStatusResolved, model.TicketStatusClosed, false).Count(&resolutionMet)
+	base().Where("resolution_breached = ?", true).Count(&resolutionBreached)
+	base().Where("escalation_level > 0").Count(&escalated)
+
+	now := time.Now()
+	slaOverdueScope(base(), now).Count(&overdue)
+
+	stats["tracked"] = tracked
+	stats["response_met"] = responseMet
+	stats["response_breached"] = responseBreached
+	stats["response_rate"] = formatRate(responseMet, responseMet+responseBreached)
+	stats["resolution_met"] = resolutionMet
+	stats["resolution_breached"] = resolutionBreached
+	stats["resolution_rate"] = formatRate(resolutionMet, resolutionMet+resolutionBreached)
+	stats["escalated"] = escalated
+	stats["overdue"] = overdue
+
+	// 平均首次响应时长（分钟）
+	var responded []model.SupportTicket
+	base().Select("created_at, first_response_at").Where("first_response_at IS NOT NULL").Find(&responded)
+	if len(responded) > 0 {
+		var total time.Duration
+		for _, t := range responded {
+			total += t.FirstResponseAt.Sub(t.CreatedAt)
+		}
+		stats["avg_first_response_minutes"] = fmt.Sprintf("%.1f", total.Minutes()/float64(len(responded)))
+	} else {
+		stats["avg_first_response_minutes"] = "0.0"
+	}
+
+	return stats, nil
+}
+
+// formatRate 格式化达标率
+func formatRate(met, total int64) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(met)/float64(total)*100)
+}
+
+// GetOverdueTickets 获取当前已超时的工单（按截止时间排序），staffID 为0时返回全部
+func (s *SupportService) GetOverdueTickets(staffID uint) ([]model.SupportTicket, error) {
+	now := time.Now()
+	query := slaOverdueScope(s.repo.GetDB(), now)
+	if staffID > 0 {
+		query = query.Where("assigned_to = ?", staffID)
+	}
+
+	var tickets []model.SupportTicket
+	if err := query.Order("priority DESC, resolution_due_at ASC").Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
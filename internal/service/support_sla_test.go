@@ -0,0 +1,170 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"user-frontend/internal/model"
+)
+
+// slaTestZone 测试使用的时区（UTC+8）
+var slaTestZone = time.FixedZone("CST", 8*3600)
+
+// slaAt 返回 2026 年 10 月 day 日 hh:mm 的时间，19 日为周一
+func slaAt(day, hh, mm int) time.Time {
+	return time.Date(2026, time.October, day, hh, mm, 0, 0, slaTestZone)
+}
+
+// TestNewSupportSchedule 测试工作时间配置解析
+func TestNewSupportSchedule(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    model.SupportConfigDB
+		wantNil   bool
+		wantStart int
+		wantEnd   int
+		wantDays  []int
+	}{
+		{"默认配置", model.SupportConfigDB{}, false, 9 * 60, 18 * 60, []int{1, 2, 3, 4, 5}},
+		{"自定义", model.SupportConfigDB{WorkingHoursStart: "08:30", WorkingHoursEnd: "20:15", WorkingDays: "1, 3,7"}, false, 8*60 + 30, 20*60 + 15, []int{1, 3, 7}},
+		{"跨夜班次", model.SupportConfigDB{WorkingHoursStart: "22:00", WorkingHoursEnd: "06:00"}, false, 22 * 60, 6 * 60, []int{1, 2, 3, 4, 5}},
+		{"全天", model.SupportConfigDB{WorkingHoursStart: "00:00", WorkingHoursEnd: "24:00", WorkingDays: "1,2,3,4,5,6,7"}, false, 0, 24 * 60, []int{1, 2, 3, 4, 5, 6, 7}},
+		{"忽略无效工作日", model.SupportConfigDB{WorkingDays: "0,5,8,x"}, false, 9 * 60, 18 * 60, []int{5}},
+		{"无有效工作日", model.SupportConfigDB{WorkingDays: "0,8"}, true, 0, 0, nil},
+		{"开始结束相同", model.SupportConfigDB{WorkingHoursStart: "09:00", WorkingHoursEnd: "09:00"}, true, 0, 0, nil},
+		{"开始时间为 24:00", model.SupportConfigDB{WorkingHoursStart: "24:00", WorkingHoursEnd: "06:00"}, true, 0, 0, nil},
+		{"小时超出范围", model.SupportConfigDB{WorkingHoursEnd: "25:00"}, true, 0, 0, nil},
+		{"格式错误", model.SupportConfigDB{WorkingHoursStart: "9点"}, true, 0, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch := newSupportSchedule(&tt.config)
+			if tt.wantNil {
+				if sch != nil {
+					t.Errorf("期望 nil，实际 %+v", sch)
+				}
+				return
+			}
+			if sch == nil {
+				t.Fatal("期望有效的工作时间表，实际 nil")
+			}
+			if sch.start != tt.wantStart || sch.end != tt.wantEnd {
+				t.Errorf("工作时间不匹配: 期望 %d-%d, 实际 %d-%d", tt.wantStart, tt.wantEnd, sch.start, sch.end)
+			}
+			var days [8]bool
+			for _, d := range tt.wantDays {
+				days[d] = true
+			}
+			if sch.days != days {
+				t.Errorf("工作日不匹配: 期望 %v, 实际 %v", days, sch.days)
+			}
+		})
+	}
+}
+
+// TestSupportSchedule_Add 测试按工作时间累加截止时间
+func TestSupportSchedule_Add(t *testing.T) {
+	if wd := slaAt(19, 0, 0).Weekday(); wd != time.Monday {
+		t.Fatalf("测试日期应为周一，实际 %v", wd)
+	}
+
+	weekdays := model.SupportConfigDB{}                                                      // 周一至周五 09:00-18:00
+	midweekOff := model.SupportConfigDB{WorkingDays: "1,2,4,5"}                              // 周三休息
+	weekendOnly := model.SupportConfigDB{WorkingDays: "6,7"}                                 // 仅周末
+	overnight := model.SupportConfigDB{WorkingHoursStart: "22:00", WorkingHoursEnd: "06:00"} // 周一至周五夜班
+	allDay := model.SupportConfigDB{WorkingHoursEnd: "24:00", WorkingHoursStart: "00:00", WorkingDays: "1,2,3,4,5,6,7"}
+
+	tests := []struct {
+		name   string
+		config model.SupportConfigDB
+		from   time.Time
+		d      time.Duration
+		want   time.Time
+	}{
+		// 日间班次
+		{"当天内", weekdays, slaAt(19, 10, 0), 2 * time.Hour, slaAt(19, 12, 0)},
+		{"上班前开始", weekdays, slaAt(19, 7, 0), time.Hour, slaAt(19, 10, 0)},
+		{"恰好在下班时到期", weekdays, slaAt(19, 17, 0), time.Hour, slaAt(19, 18, 0)},
+		{"下班后顺延到次日", weekdays, slaAt(19, 19, 0), time.Hour, slaAt(20, 10, 0)},
+		{"下班时刻开始", weekdays, slaAt(19, 18, 0), 30 * time.Minute, slaAt(20, 9, 30)},
+		{"跨天", weekdays, slaAt(19, 17, 0), 3 * time.Hour, slaAt(20, 11, 0)},
+		{"持续时间为0", weekdays, slaAt(24, 12, 0), 0, slaAt(24, 12, 0)},
+
+		// 周末与休息日
+		{"周五跨周末", weekdays, slaAt(23, 17, 0), 2 * time.Hour, slaAt(26, 10, 0)},
+		{"周六开始", weekdays, slaAt(24, 12, 0), time.Hour, slaAt(26, 10, 0)},
+		{"周日深夜开始", weekdays, slaAt(25, 23, 0), 30 * time.Minute, slaAt(26, 9, 30)},
+		{"跳过周中休息日", midweekOff, slaAt(20, 17, 0), 2 * time.Hour, slaAt(22, 10, 0)},
+		{"休息日当天开始", midweekOff, slaAt(21, 10, 0), time.Hour, slaAt(22, 10, 0)},
+		{"仅周末工作", weekendOnly, slaAt(23, 10, 0), time.Hour, slaAt(24, 10, 0)},
+		{"仅周末工作跨周", weekendOnly, slaAt(25, 17, 0), 2 * time.Hour, slaAt(31, 10, 0)},
+
+		// 多日截止
+		{"两天多", weekdays, slaAt(19, 9, 0), 24 * time.Hour, slaAt(21, 15, 0)},
+		{"整周工作时间", weekdays, slaAt(19, 9, 0), 45 * time.Hour, slaAt(23, 18, 0)},
+		{"跨周", weekdays, slaAt(19, 9, 0), 50 * time.Hour, slaAt(26, 14, 0)},
+		{"跨月", weekdays, slaAt(29, 12, 0), 20 * time.Hour, time.Date(2026, time.November, 2, 14, 0, 0, 0, slaTestZone)},
+
+		// 跨夜班次（班次归属开始当天）
+		{"夜班内", overnight, slaAt(19, 23, 0), 2 * time.Hour, slaAt(20, 1, 0)},
+		{"凌晨属于前一天班次", overnight, slaAt(20, 3, 0), time.Hour, slaAt(20, 4, 0)},
+		{"夜班结束顺延到当晚", overnight, slaAt(20, 5, 0), 2 * time.Hour, slaAt(20, 23, 0)},
+		{"白天开始", overnight, slaAt(20, 12, 0), time.Hour, slaAt(20, 23, 0)},
+		{"周五夜班延续到周六凌晨", overnight, slaAt(24, 2, 0), time.Hour, slaAt(24, 3, 0)},
+		{"周五夜班跨周末", overnight, slaAt(23, 23, 0), 8 * time.Hour, slaAt(26, 23, 0)},
+		{"周一凌晨不属于周日班次", overnight, slaAt(19, 3, 0), time.Hour, slaAt(19, 23, 0)},
+		{"夜班多日", overnight, slaAt(19, 22, 0), 20 * time.Hour, slaAt(22, 2, 0)},
+
+		// 全天工作等同自然时间
+		{"全天工作", allDay, slaAt(19, 10, 0), 30 * time.Hour, slaAt(20, 16, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch := newSupportSchedule(&tt.config)
+			if sch == nil {
+				t.Fatal("工作时间配置无效")
+			}
+			if got := sch.add(tt.from, tt.d); !got.Equal(tt.want) {
+				t.Errorf("期望 %s, 实际 %s", tt.want.Format("2006-01-02 Mon 15:04"), got.Format("2006-01-02 Mon 15:04"))
+			}
+		})
+	}
+}
+
+// TestSLADueTime 测试截止时间是否按策略使用工作时间
+func TestSLADueTime(t *testing.T) {
+	sch := newSupportSchedule(&model.SupportConfigDB{})
+	from := slaAt(23, 17, 0) // 周五 17:00
+
+	tests := []struct {
+		name    string
+		policy  model.SupportSLAPolicy
+		sch     *supportSchedule
+		minutes int
+		want    *time.Time
+	}{
+		{"不限时", model.SupportSLAPolicy{UseWorkingHours: true}, sch, 0, nil},
+		{"自然时间", model.SupportSLAPolicy{}, sch, 120, ptrTime(slaAt(23, 19, 0))},
+		{"工作时间", model.SupportSLAPolicy{UseWorkingHours: true}, sch, 120, ptrTime(slaAt(26, 10, 0))},
+		{"工作时间配置无效时按自然时间", model.SupportSLAPolicy{UseWorkingHours: true}, nil, 120, ptrTime(slaAt(23, 19, 0))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slaDueTime(&tt.policy, tt.sch, from, tt.minutes)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("期望 nil, 实际 %s", got)
+			case tt.want != nil && (got == nil || !got.Equal(*tt.want)):
+				t.Errorf("期望 %s, 实际 %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// ptrTime 返回时间指针
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -215,6 +215,14 @@ func (s *SupportService) UpdateStaff(id uint, nickname, email string, maxTickets
 	}).Error
 }
 
+// UpdateStaffRole 更新客服角色（staff/supervisor）
+func (s *SupportService) UpdateStaffRole(id uint, role string) error {
+	if role != model.StaffRoleStaff && role != model.StaffRoleSupervisor {
+		return errors.New("无效的客服角色")
+	}
+	return s.repo.GetDB().Model(&model.SupportStaff{}).Where("id = ?", id).Update("role", role).Error
+}
+
 // UpdateStaffPassword 更新客服密码
 func (s *SupportService) UpdateStaffPassword(id uint, newPassword string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
@@ -229,15 +237,19 @@ func (s *SupportService) DeleteStaff(id uint) error {
 	return s.repo.GetDB().Delete(&model.SupportStaff{}, id).Error
 }
 
-// UpdateStaffLoad 更新客服工单负载
+// UpdateStaffLoad 按实际数据重新计算客服负载（未关闭的工单和进行中的聊天）
 func (s *SupportService) UpdateStaffLoad(staffID uint) {
-	var count int64
+	var tickets, chats int64
 	s.repo.GetDB().Model(&model.SupportTicket{}).
-		Where("assigned_to = ? AND status IN (?, ?)", staffID, model.TicketStatusProcessing, model.TicketStatusReplied).
-		Count(&count)
+		Where("assigned_to = ? AND status IN (?, ?, ?)", staffID,
+			model.TicketStatusPending, model.TicketStatusProcessing, model.TicketStatusReplied).
+		Count(&tickets)
+	s.repo.GetDB().Model(&model.LiveChat{}).
+		Where("staff_id = ? AND status = ?", staffID, model.ChatStatusActive).
+		Count(&chats)
 
 	s.repo.GetDB().Model(&model.SupportStaff{}).Where("id = ?", staffID).
-		Update("current_load", count)
+		Update("current_load", tickets+chats)
 }
 
 // CleanupExpiredStaffSessions 清理过期的客服会话
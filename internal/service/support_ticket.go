@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"user-frontend/internal/model"
+
+	"gorm.io/gorm"
 )
 
 // CreateTicket 创建工单
@@ -48,6 +50,9 @@ func (s *SupportService) CreateTicket(userID uint, username, email, subject, cat
 		GuestToken:   guestToken,
 	}
 
+	// 匹配 SLA 策略并计算截止时间
+	s.applyTicketSLA(ticket, time.Now())
+
 	if err := s.repo.GetDB().Create(ticket).Error; err != nil {
 		return nil, err
 	}
@@ -136,6 +141,7 @@ func (s *SupportService) GetTicketMessages(ticketID uint, includeInternal bool)
 	if err := query.Order("created_at ASC").Find(&messages).Error; err != nil {
 		return nil, err
 	}
+	s.signMessageAttachments(messages)
 	return messages, nil
 }
 
@@ -161,6 +167,9 @@ func (s *SupportService) ReplyTicket(ticketID uint, senderType string, senderID
 		"last_reply_by": senderName,
 	}
 
+	// 更新 SLA 响应计时
+	s.applyReplySLA(ticketID, senderType, isInternal, now, updates)
+
 	// 客服回复时更新状态为已回复
 	if senderType == "staff" && !isInternal {
 		updates["status"] = model.TicketStatusReplied
@@ -180,7 +189,13 @@ func (s *SupportService) ReplyTicket(ticketID uint, senderType string, senderID
 }
 
 // UpdateTicketStatus 更新工单状态
+// 工单关闭时释放处理客服的名额，重新打开时重新占用
 func (s *SupportService) UpdateTicketStatus(ticketID uint, status int, operatorName string) error {
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return err
+	}
+
 	updates := map[string]interface{}{
 		"status": status,
 	}
@@ -189,18 +204,50 @@ func (s *SupportService) UpdateTicketStatus(ticketID uint, status int, operatorN
 		now := time.Now()
 		updates["closed_at"] = now
 		updates["closed_by"] = operatorName
+		s.applyCloseSLA(ticketID, now, updates)
 	}
 
-	return s.repo.GetDB().Model(&model.SupportTicket{}).Where("id = ?", ticketID).Updates(updates).Error
+	return s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SupportTicket{}).Where("id = ?", ticketID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if ticket.AssignedTo > 0 {
+			wasOpen, nowOpen := isTicketOpen(ticket.Status), isTicketOpen(status)
+			if wasOpen && !nowOpen {
+				s.releaseStaffSlot(tx, ticket.AssignedTo)
+			} else if !wasOpen && nowOpen {
+				s.claimStaffSlot(tx, ticket.AssignedTo, false)
+			}
+		}
+		return nil
+	})
 }
 
-// AssignTicket 分配工单给客服
+// AssignTicket 分配工单给客服（手动分配，不受最大工单数限制）
 func (s *SupportService) AssignTicket(ticketID, staffID uint, staffName string) error {
-	return s.repo.GetDB().Model(&model.SupportTicket{}).Where("id = ?", ticketID).Updates(map[string]interface{}{
-		"assigned_to":   staffID,
-		"assigned_name": staffName,
-		"status":        model.TicketStatusProcessing,
-	}).Error
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.SupportTicket{}).Where("id = ?", ticketID).Updates(map[string]interface{}{
+			"assigned_to":   staffID,
+			"assigned_name": staffName,
+			"status":        model.TicketStatusProcessing,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if ticket.AssignedTo == staffID && isTicketOpen(ticket.Status) {
+			return nil
+		}
+		if ticket.AssignedTo > 0 && isTicketOpen(ticket.Status) {
+			s.releaseStaffSlot(tx, ticket.AssignedTo)
+		}
+		s.claimStaffSlot(tx, staffID, false)
+		return nil
+	})
 }
 
 // GetAllTickets 获取所有工单（客服后台）
@@ -275,6 +322,11 @@ func (s *SupportService) GetTicketStats() (map[string]int64, error) {
 	s.repo.GetDB().Model(&model.SupportTicket{}).Where("created_at >= ?", todayStart).Count(&todayCount)
 	stats["today"] = todayCount
 
+	// 当前 SLA 超时工单
+	var overdue int64
+	slaOverdueScope(s.repo.GetDB().Model(&model.SupportTicket{}), now).Count(&overdue)
+	stats["sla_overdue"] = overdue
+
 	return stats, nil
 }
 
@@ -310,13 +362,23 @@ func (s *SupportService) TransferTicket(ticketID, fromStaffID, toStaffID uint, r
 	}
 	newLog += transferLog + "]"
 
-	// 更新工单
-	err = s.repo.GetDB().Model(&model.SupportTicket{}).Where("id = ?", ticketID).Updates(map[string]interface{}{
-		"assigned_to":    toStaffID,
-		"assigned_name":  toStaff.Nickname,
-		"transfer_count": ticket.TransferCount + 1,
-		"transfer_log":   newLog,
-	}).Error
+	// 更新工单并转移双方客服负载
+	err = s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.SupportTicket{}).Where("id = ?", ticketID).Updates(map[string]interface{}{
+			"assigned_to":    toStaffID,
+			"assigned_name":  toStaff.Nickname,
+			"transfer_count": ticket.TransferCount + 1,
+			"transfer_log":   newLog,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if isTicketOpen(ticket.Status) && ticket.AssignedTo != toStaffID {
+			s.releaseStaffSlot(tx, ticket.AssignedTo)
+			s.claimStaffSlot(tx, toStaffID, false)
+		}
+		return nil
+	})
 
 	if err != nil {
 		return err
@@ -326,12 +388,6 @@ func (s *SupportService) TransferTicket(ticketID, fromStaffID, toStaffID uint, r
 	s.ReplyTicket(ticketID, "system", 0, "系统",
 		fmt.Sprintf("工单已从 %s 转接给 %s，原因：%s", fromName, toStaff.Nickname, reason), true)
 
-	// 更新双方客服负载
-	if fromStaffID > 0 {
-		s.UpdateStaffLoad(fromStaffID)
-	}
-	s.UpdateStaffLoad(toStaffID)
-
 	return nil
 }
 
@@ -373,13 +429,16 @@ func (s *SupportService) MergeTickets(targetTicketID uint, sourceTicketIDs []uin
 			s.repo.GetDB().Create(&newMsg)
 		}
 
-		// 标记源工单为已合并
+		// 标记源工单为已合并，并释放其处理客服的名额
 		s.repo.GetDB().Model(&model.SupportTicket{}).Where("id = ?", sourceID).Updates(map[string]interface{}{
 			"status":    model.TicketStatusMerged,
 			"merged_to": targetTicketID,
 			"closed_at": time.Now(),
 			"closed_by": operatorName,
 		})
+		if sourceTicket.AssignedTo > 0 && isTicketOpen(sourceTicket.Status) {
+			s.releaseStaffSlot(s.repo.GetDB(), sourceTicket.AssignedTo)
+		}
 
 		// 添加系统消息到源工单
 		s.ReplyTicket(sourceID, "system", 0, "系统",
@@ -403,44 +462,25 @@ func (s *SupportService) MergeTickets(targetTicketID uint, sourceTicketIDs []uin
 	return nil
 }
 
-// AutoAssignTicket 自动分配工单给负载最低的在线客服
-func (s *SupportService) AutoAssignTicket(ticketID uint) error {
-	config, _ := s.GetSupportConfig()
-	if !config.EnableAutoAssign {
-		return nil
+// AutoCloseInactiveTickets 自动关闭长时间无回复的工单
+func (s *SupportService) AutoCloseInactiveTickets(hours int) {
+	threshold := time.Now().Add(-time.Duration(hours) * time.Hour)
+	query := func() *gorm.DB {
+		return s.repo.GetDB().Model(&model.SupportTicket{}).
+			Where("status IN (?, ?) AND last_reply_at < ?", model.TicketStatusReplied, model.TicketStatusProcessing, threshold)
 	}
 
-	// 获取在线且未满负载的客服，按当前负载排序
-	var staff model.SupportStaff
-	err := s.repo.GetDB().
-		Where("status = 1 AND current_load < max_tickets").
-		Order("current_load ASC").
-		First(&staff).Error
+	var staffIDs []uint
+	query().Where("assigned_to > 0").Distinct("assigned_to").Pluck("assigned_to", &staffIDs)
 
-	if err != nil {
-		return nil // 没有可用客服，不分配
-	}
+	query().Updates(map[string]interface{}{
+		"status":    model.TicketStatusClosed,
+		"closed_at": time.Now(),
+		"closed_by": "系统自动关闭",
+	})
 
-	// 分配工单
-	if err := s.AssignTicket(ticketID, staff.ID, staff.Nickname); err != nil {
-		return err
+	// 批量关闭后重新计算相关客服负载
+	for _, id := range staffIDs {
+		s.UpdateStaffLoad(id)
 	}
-
-	// 更新客服负载
-	s.repo.GetDB().Model(&model.SupportStaff{}).Where("id = ?", staff.ID).
-		Update("current_load", staff.CurrentLoad+1)
-
-	return nil
-}
-
-// AutoCloseInactiveTickets 自动关闭长时间无回复的工单
-func (s *SupportService) AutoCloseInactiveTickets(hours int) {
-	threshold := time.Now().Add(-time.Duration(hours) * time.Hour)
-	s.repo.GetDB().Model(&model.SupportTicket{}).
-		Where("status IN (?, ?) AND last_reply_at < ?", model.TicketStatusReplied, model.TicketStatusProcessing, threshold).
-		Updates(map[string]interface{}{
-			"status":    model.TicketStatusClosed,
-			"closed_at": time.Now(),
-			"closed_by": "系统自动关闭",
-		})
 }
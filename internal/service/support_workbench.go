@@ -0,0 +1,503 @@
+// Package service 提供业务逻辑服务
+// support_workbench.go - 客服工作台订单操作（订单上下文、重发发货、更换卡密、退款申请）
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-frontend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 工作台操作限制
+const (
+	resendDeliveryCooldown   = 2 * time.Minute // 同一订单重发发货邮件的最小间隔
+	staffKamiReplaceLimit    = 2               // 普通客服对同一订单可更换卡密的次数（主管不限）
+	workbenchRecentListLimit = 5               // 用户近期订单/工单展示数量
+)
+
+// WorkbenchKami 工作台展示的订单卡密（已脱敏）
+type WorkbenchKami struct {
+	ID         uint       `json:"id"`
+	CodeMasked string     `json:"code_masked"`
+	Status     int        `json:"status"`
+	SoldAt     *time.Time `json:"sold_at"`
+}
+
+// WorkbenchUser 工作台展示的用户信息
+type WorkbenchUser struct {
+	ID            uint       `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	Level         string     `json:"level"`
+	Status        int        `json:"status"`
+	EmailVerified bool       `json:"email_verified"`
+	LastLoginAt   *time.Time `json:"last_login_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// TicketOrderContext 工单关联的订单及用户上下文
+type TicketOrderContext struct {
+	Order          *model.Order                 `json:"order"`
+	Kamis          []WorkbenchKami              `json:"kamis"`
+	User           *WorkbenchUser               `json:"user"`
+	Balance        *model.UserBalance           `json:"balance"`
+	RecentOrders   []model.Order                `json:"recent_orders"`
+	RecentTickets  []model.SupportTicket        `json:"recent_tickets"`
+	Actions        []model.SupportOrderAction   `json:"actions"`
+	RefundRequests []model.SupportRefundRequest `json:"refund_requests"`
+}
+
+// maskOrderKami 返回卡密脱敏后的订单副本
+func maskOrderKami(order model.Order) model.Order {
+	order.KamiCode = maskKamiCode(order.KamiCode)
+	return order
+}
+
+// workbenchOrder 获取工单关联的有效订单
+func (s *SupportService) workbenchOrder(ticket *model.SupportTicket) (*model.Order, error) {
+	order := s.ticketRelatedOrder(ticket)
+	if order == nil {
+		return nil, errors.New("工单未关联有效订单")
+	}
+	return order, nil
+}
+
+// orderContactEmail 获取订单的联系邮箱（注册用户取账户邮箱，否则取工单邮箱）
+func (s *SupportService) orderContactEmail(order *model.Order, ticket *model.SupportTicket) string {
+	if order.UserID > 0 {
+		if user, err := s.repo.GetUserByID(order.UserID); err == nil && user.Email != "" {
+			return user.Email
+		}
+	}
+	return ticket.Email
+}
+
+// GetTicketOrderContext 获取工单关联的订单、用户、余额、近期订单与工单等上下文
+// 未关联订单时仍返回用户信息
+func (s *SupportService) GetTicketOrderContext(ticketID uint) (*TicketOrderContext, error) {
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.repo.GetDB()
+	ctx := &TicketOrderContext{}
+
+	if order := s.ticketRelatedOrder(ticket); order != nil {
+		masked := maskOrderKami(*order)
+		ctx.Order = &masked
+
+		var kamis []model.ManualKami
+		db.Where("order_id = ?", order.ID).Order("id ASC").Find(&kamis)
+		for _, k := range kamis {
+			ctx.Kamis = append(ctx.Kamis, WorkbenchKami{
+				ID:         k.ID,
+				CodeMasked: maskKamiCode(k.KamiCode),
+				Status:     k.Status,
+				SoldAt:     k.SoldAt,
+			})
+		}
+
+		db.Where("order_id = ?", order.ID).Order("id DESC").Find(&ctx.Actions)
+		db.Where("order_id = ?", order.ID).Order("id DESC").Find(&ctx.RefundRequests)
+	}
+
+	if ticket.UserID > 0 {
+		if user, err := s.repo.GetUserByID(ticket.UserID); err == nil {
+			ctx.User = &WorkbenchUser{
+				ID:            user.ID,
+				Username:      user.Username,
+				Email:         user.Email,
+				Level:         user.GetLevel(),
+				Status:        user.Status,
+				EmailVerified: user.EmailVerified,
+				LastLoginAt:   user.LastLoginAt,
+				CreatedAt:     user.CreatedAt,
+			}
+		}
+
+		var balance model.UserBalance
+		if err := db.Where("user_id = ?", ticket.UserID).First(&balance).Error; err == nil {
+			ctx.Balance = &balance
+		}
+
+		var orders []model.Order
+		db.Where("user_id = ?", ticket.UserID).Order("id DESC").Limit(workbenchRecentListLimit).Find(&orders)
+		for _, o := range orders {
+			ctx.RecentOrders = append(ctx.RecentOrders, maskOrderKami(o))
+		}
+
+		db.Where("user_id = ? AND id <> ?", ticket.UserID, ticket.ID).
+			Order("id DESC").Limit(workbenchRecentListLimit).Find(&ctx.RecentTickets)
+	} else if ticket.Email != "" {
+		db.Where("user_id = 0 AND email = ? AND id <> ?", ticket.Email, ticket.ID).
+			Order("id DESC").Limit(workbenchRecentListLimit).Find(&ctx.RecentTickets)
+	}
+
+	return ctx, nil
+}
+
+// recordOrderAction 记录订单操作并在工单中留下内部备注
+func (s *SupportService) recordOrderAction(action *model.SupportOrderAction, note string) {
+	s.repo.GetDB().Create(action)
+	if action.TicketID > 0 && note != "" {
+		s.ReplyTicket(action.TicketID, "system", 0, "系统", note, true)
+	}
+}
+
+// ==========================================
+//         重发发货邮件
+// ==========================================
+
+// ResendDelivery 重新发送订单发货邮件（包含卡密），返回收件邮箱
+func (s *SupportService) ResendDelivery(ticketID, staffID uint, staffName string) (string, error) {
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return "", err
+	}
+	order, err := s.workbenchOrder(ticket)
+	if err != nil {
+		return "", err
+	}
+	if order.Status != model.OrderStatusCompleted || order.KamiCode == "" {
+		return "", errors.New("订单未发货或已退款")
+	}
+
+	var last model.SupportOrderAction
+	if err := s.repo.GetDB().Where("order_id = ? AND action = ?", order.ID, model.OrderActionResendDelivery).
+		Order("id DESC").First(&last).Error; err == nil && time.Since(last.CreatedAt) < resendDeliveryCooldown {
+		return "", errors.New("发货邮件刚刚已重发，请稍后再试")
+	}
+
+	email := s.orderContactEmail(order, ticket)
+	if email == "" {
+		return "", errors.New("订单没有可用的联系邮箱")
+	}
+	if supportEmailSvc == nil {
+		return "", errors.New("邮件服务未配置")
+	}
+
+	notifySvc := NewNotificationService(s.repo, supportEmailSvc)
+	if err := notifySvc.NotifyOrderPaid(order, email, strings.ReplaceAll(order.KamiCode, "\n", "<br>")); err != nil {
+		return "", fmt.Errorf("邮件发送失败: %v", err)
+	}
+
+	s.recordOrderAction(&model.SupportOrderAction{
+		TicketID:     ticketID,
+		OrderID:      order.ID,
+		OrderNo:      order.OrderNo,
+		Action:       model.OrderActionResendDelivery,
+		OperatorType: "staff",
+		OperatorID:   staffID,
+		OperatorName: staffName,
+		Reason:       "发送至 " + email,
+	}, fmt.Sprintf("客服 %s 重发了订单 %s 的发货邮件（%s）", staffName, order.OrderNo, email))
+
+	return email, nil
+}
+
+// ==========================================
+//         更换失效卡密
+// ==========================================
+
+// ReplaceTicketKami 为工单关联订单更换一张失效卡密，返回脱敏后的新卡密
+// 普通客服对同一订单的更换次数受限，主管不限
+func (s *SupportService) ReplaceTicketKami(ticketID, kamiID, staffID uint, staffName, staffRole, reason string, sendEmail bool) (*WorkbenchKami, error) {
+	if s.manualKamiSvc == nil {
+		return nil, errors.New("手动卡密服务未初始化")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("请填写更换原因")
+	}
+
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.workbenchOrder(ticket)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != model.OrderStatusCompleted {
+		return nil, errors.New("只能为已完成的订单更换卡密")
+	}
+
+	if staffRole != model.StaffRoleSupervisor {
+		var replaced int64
+		s.repo.GetDB().Model(&model.SupportOrderAction{}).
+			Where("order_id = ? AND action = ?", order.ID, model.OrderActionReplaceKami).Count(&replaced)
+		if replaced >= staffKamiReplaceLimit {
+			return nil, errors.New("该订单更换次数已达上限，请联系主管处理")
+		}
+	}
+
+	newKami, err := s.manualKamiSvc.ReplaceSoldKami(order, kamiID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordOrderAction(&model.SupportOrderAction{
+		TicketID:     ticketID,
+		OrderID:      order.ID,
+		OrderNo:      order.OrderNo,
+		Action:       model.OrderActionReplaceKami,
+		OperatorType: "staff",
+		OperatorID:   staffID,
+		OperatorName: staffName,
+		OldKamiID:    kamiID,
+		NewKamiID:    newKami.ID,
+		Reason:       reason,
+	}, fmt.Sprintf("客服 %s 更换了订单 %s 的卡密 #%d → #%d，原因：%s", staffName, order.OrderNo, kamiID, newKami.ID, reason))
+
+	if sendEmail {
+		if email := s.orderContactEmail(order, ticket); email != "" && supportEmailSvc != nil {
+			notifySvc := NewNotificationService(s.repo, supportEmailSvc)
+			go notifySvc.NotifyOrderPaid(order, email, strings.ReplaceAll(order.KamiCode, "\n", "<br>"))
+		}
+	}
+
+	return &WorkbenchKami{
+		ID:         newKami.ID,
+		CodeMasked: maskKamiCode(newKami.KamiCode),
+		Status:     newKami.Status,
+		SoldAt:     newKami.SoldAt,
+	}, nil
+}
+
+// ==========================================
+//         退款申请
+// ==========================================
+
+// orderRefundedAmount 获取订单已通过的退款总额
+func (s *SupportService) orderRefundedAmount(db *gorm.DB, orderID uint) float64 {
+	var total float64
+	db.Model(&model.SupportRefundRequest{}).
+		Where("order_id = ? AND status = ?", orderID, model.RefundRequestApproved).
+		Select("COALESCE(SUM(amount), 0)").Scan(&total)
+	return total
+}
+
+// RequestRefund 客服为工单关联订单提交退款申请，由管理员审批
+// 注册用户的订单退回余额，其余订单由管理员线下原路退回
+func (s *SupportService) RequestRefund(ticketID, staffID uint, staffName string, amount float64, reason string) (*model.SupportRefundRequest, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("请填写退款原因")
+	}
+	amount = roundAmount(amount)
+	if amount <= 0 {
+		return nil, errors.New("退款金额必须大于0")
+	}
+
+	ticket, err := s.GetTicketByID(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.workbenchOrder(ticket)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != model.OrderStatusPaid && order.Status != model.OrderStatusCompleted {
+		return nil, errors.New("订单当前状态不可退款")
+	}
+
+	db := s.repo.GetDB()
+	var pending int64
+	db.Model(&model.SupportRefundRequest{}).
+		Where("order_id = ? AND status = ?", order.ID, model.RefundRequestPending).Count(&pending)
+	if pending > 0 {
+		return nil, errors.New("该订单已有待审批的退款申请")
+	}
+
+	refundable := roundAmount(order.Price - s.orderRefundedAmount(db, order.ID))
+	if amount > refundable {
+		return nil, fmt.Errorf("退款金额不能超过可退金额 %.2f", refundable)
+	}
+
+	req := &model.SupportRefundRequest{
+		TicketID:     ticketID,
+		OrderID:      order.ID,
+		OrderNo:      order.OrderNo,
+		UserID:       order.UserID,
+		Amount:       amount,
+		RefundMethod: model.RefundMethodManual,
+		Reason:       reason,
+		StaffID:      staffID,
+		StaffName:    staffName,
+		Status:       model.RefundRequestPending,
+	}
+	if order.UserID > 0 {
+		req.RefundMethod = model.RefundMethodBalance
+	}
+	if err := db.Create(req).Error; err != nil {
+		return nil, err
+	}
+
+	s.recordOrderAction(&model.SupportOrderAction{
+		TicketID:     ticketID,
+		OrderID:      order.ID,
+		OrderNo:      order.OrderNo,
+		Action:       model.OrderActionRefundRequest,
+		OperatorType: "staff",
+		OperatorID:   staffID,
+		OperatorName: staffName,
+		Amount:       amount,
+		Reason:       reason,
+	}, fmt.Sprintf("客服 %s 为订单 %s 提交了退款申请 %.2f，等待管理员审批。原因：%s", staffName, order.OrderNo, amount, reason))
+
+	return req, nil
+}
+
+// GetRefundRequests 获取退款申请列表（status 为 nil 表示全部）
+func (s *SupportService) GetRefundRequests(status *int, page, pageSize int) ([]model.SupportRefundRequest, int64, error) {
+	var requests []model.SupportRefundRequest
+	var total int64
+
+	query := s.repo.GetDB().Model(&model.SupportRefundRequest{})
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	query.Count(&total)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&requests).Error
+	return requests, total, err
+}
+
+// claimRefundRequest 将待审批的申请原子地置为指定状态，防止重复审批
+func (s *SupportService) claimRefundRequest(id uint, status int, adminName, remark string) (*model.SupportRefundRequest, error) {
+	now := time.Now()
+	result := s.repo.GetDB().Model(&model.SupportRefundRequest{}).
+		Where("id = ? AND status = ?", id, model.RefundRequestPending).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"reviewed_by":   adminName,
+			"review_remark": remark,
+			"reviewed_at":   now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("申请不存在或已处理")
+	}
+
+	var req model.SupportRefundRequest
+	if err := s.repo.GetDB().First(&req, id).Error; err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ApproveRefundRequest 管理员通过退款申请
+// 余额退款立即退回用户余额；全额退款后订单标记为已退款
+func (s *SupportService) ApproveRefundRequest(id uint, adminName, remark, clientIP string) (*model.SupportRefundRequest, error) {
+	req, err := s.claimRefundRequest(id, model.RefundRequestApproved, adminName, remark)
+	if err != nil {
+		return nil, err
+	}
+
+	// 执行失败时恢复为待审批
+	revert := func() {
+		s.repo.GetDB().Model(&model.SupportRefundRequest{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": model.RefundRequestPending, "reviewed_by": "", "review_remark": "", "reviewed_at": nil})
+	}
+
+	order, err := s.repo.GetOrderByOrderNo(req.OrderNo)
+	if err != nil {
+		revert()
+		return nil, errors.New("订单不存在")
+	}
+	if order.Status != model.OrderStatusPaid && order.Status != model.OrderStatusCompleted {
+		revert()
+		return nil, errors.New("订单当前状态不可退款")
+	}
+
+	if req.RefundMethod == model.RefundMethodBalance {
+		if s.balanceSvc == nil {
+			revert()
+			return nil, errors.New("余额服务未初始化")
+		}
+		operator := &OperatorInfo{OperatorType: "admin", ClientIP: clientIP}
+		if err := s.balanceSvc.Refund(req.UserID, req.Amount, req.OrderNo, "客服退款申请 #"+fmt.Sprint(req.ID), operator); err != nil {
+			revert()
+			return nil, fmt.Errorf("退款失败: %v", err)
+		}
+	}
+
+	// 累计退款达到实付金额时订单标记为已退款
+	if s.orderRefundedAmount(s.repo.GetDB(), order.ID) >= roundAmount(order.Price) {
+		s.repo.GetDB().Model(&model.Order{}).Where("id = ?", order.ID).Update("status", model.OrderStatusRefunded)
+		order.Status = model.OrderStatusRefunded
+	}
+
+	note := fmt.Sprintf("管理员 %s 通过了订单 %s 的退款申请 %.2f（%s）", adminName, order.OrderNo, req.Amount, refundMethodText(req.RefundMethod))
+	if remark != "" {
+		note += "，备注：" + remark
+	}
+	s.recordOrderAction(&model.SupportOrderAction{
+		TicketID:     req.TicketID,
+		OrderID:      order.ID,
+		OrderNo:      order.OrderNo,
+		Action:       model.OrderActionRefundApprove,
+		OperatorType: "admin",
+		OperatorName: adminName,
+		Amount:       req.Amount,
+		Reason:       remark,
+	}, note)
+
+	if supportEmailSvc != nil && order.Status == model.OrderStatusRefunded {
+		if ticket, err := s.GetTicketByID(req.TicketID); err == nil {
+			if email := s.orderContactEmail(order, ticket); email != "" {
+				notifySvc := NewNotificationService(s.repo, supportEmailSvc)
+				go notifySvc.NotifyOrderRefunded(order, email)
+			}
+		}
+	}
+
+	return req, nil
+}
+
+// RejectRefundRequest 管理员驳回退款申请
+func (s *SupportService) RejectRefundRequest(id uint, adminName, remark string) (*model.SupportRefundRequest, error) {
+	req, err := s.claimRefundRequest(id, model.RefundRequestRejected, adminName, remark)
+	if err != nil {
+		return nil, err
+	}
+
+	note := fmt.Sprintf("管理员 %s 驳回了订单 %s 的退款申请 %.2f", adminName, req.OrderNo, req.Amount)
+	if remark != "" {
+		note += "，原因：" + remark
+	}
+	s.recordOrderAction(&model.SupportOrderAction{
+		TicketID:     req.TicketID,
+		OrderID:      req.OrderID,
+		OrderNo:      req.OrderNo,
+		Action:       model.OrderActionRefundReject,
+		OperatorType: "admin",
+		OperatorName: adminName,
+		Amount:       req.Amount,
+		Reason:       remark,
+	}, note)
+
+	return req, nil
+}
+
+// refundMethodText 退款方式描述
+func refundMethodText(method string) string {
+	if method == model.RefundMethodBalance {
+		return "退回余额"
+	}
+	return "线下原路退回"
+}
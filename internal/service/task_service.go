@@ -1,11 +1,14 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
 	"time"
+	"user-frontend/internal/lifecycle"
+	"user-frontend/internal/metrics"
 	"user-frontend/internal/model"
 	"user-frontend/internal/repository"
 )
@@ -17,6 +20,11 @@ type TaskService struct {
 	stopChan  chan struct{}
 	mutex     sync.Mutex
 	taskFuncs map[string]TaskFunc
+	// 执行中的任务，同一任务执行结束前不会被再次触发
+	active   map[uint]bool
+	inflight sync.WaitGroup
+	// 日志服务，清理旧日志任务用于归档审计日志和删除CSV日志文件
+	logSvc *LogService
 }
 
 // TaskFunc 任务执行函数类型
@@ -28,12 +36,18 @@ func NewTaskService(repo *repository.Repository) *TaskService {
 		repo:      repo,
 		stopChan:  make(chan struct{}),
 		taskFuncs: make(map[string]TaskFunc),
+		active:    make(map[uint]bool),
 	}
 	// 注册内置任务
 	s.registerBuiltinTasks()
 	return s
 }
 
+// SetLogService 设置日志服务
+func (s *TaskService) SetLogService(logSvc *LogService) {
+	s.logSvc = logSvc
+}
+
 // registerBuiltinTasks 注册内置任务
 func (s *TaskService) registerBuiltinTasks() {
 	s.taskFuncs[model.TaskTypeCleanExpiredOrders] = s.cleanExpiredOrders
@@ -76,6 +90,18 @@ func (s *TaskService) Stop() {
 	close(s.stopChan)
 }
 
+// Run 运行任务调度器直到 ctx 取消，用于注册到应用生命周期
+func (s *TaskService) Run(ctx context.Context) {
+	s.Start()
+	<-ctx.Done()
+	s.Stop()
+}
+
+// Wait 等待执行中的任务结束，ctx 到期时返回错误
+func (s *TaskService) Wait(ctx context.Context) error {
+	return lifecycle.WaitGroup(ctx, &s.inflight)
+}
+
 // runScheduler 运行调度器
 func (s *TaskService) runScheduler() {
 	ticker := time.NewTicker(time.Minute)
@@ -91,15 +117,48 @@ func (s *TaskService) runScheduler() {
 	}
 }
 
-// checkAndRunTasks 检查并执行到期任务
+// checkAndRunTasks 检查并执行到期任务，维护模式中跳过
 func (s *TaskService) checkAndRunTasks() {
+	if GetMaintenanceStatus().Active {
+		return
+	}
 	var tasks []model.ScheduledTask
 	now := time.Now()
 	s.repo.GetDB().Where("status = 1 AND (next_run_at IS NULL OR next_run_at <= ?)", now).Find(&tasks)
 
 	for _, task := range tasks {
-		go s.executeTask(&task)
+		s.dispatch(&task)
+	}
+}
+
+// dispatch 在新协程中执行任务，任务仍在执行或处于维护模式时跳过
+// 执行中的任务登记为后台任务，进入维护模式时等待其结束
+func (s *TaskService) dispatch(task *model.ScheduledTask) bool {
+	done, ok := BeginBackgroundWork()
+	if !ok {
+		return false
+	}
+	s.mutex.Lock()
+	if s.active[task.ID] {
+		s.mutex.Unlock()
+		done()
+		return false
 	}
+	s.active[task.ID] = true
+	s.inflight.Add(1)
+	s.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			s.mutex.Lock()
+			delete(s.active, task.ID)
+			s.mutex.Unlock()
+			s.inflight.Done()
+			done()
+		}()
+		s.executeTask(task)
+	}()
+	return true
 }
 
 // executeTask 执行单个任务
@@ -124,7 +183,9 @@ func (s *TaskService) executeTask(task *model.ScheduledTask) {
 		}
 	}
 
-	duration := int(time.Since(startTime).Milliseconds())
+	elapsed := time.Since(startTime)
+	duration := int(elapsed.Milliseconds())
+	metrics.ObserveTaskRun(task.Type, elapsed, status == "failed")
 
 	// 记录执行日志
 	log := model.TaskLog{
@@ -200,21 +261,35 @@ func (s *TaskService) cleanExpiredSessions(config string) error {
 func (s *TaskService) cleanOldLogs(config string) error {
 	// 解析配置
 	var cfg struct {
-		RetainDays int `json:"retain_days"`
+		RetainDays      int `json:"retain_days"`
+		AuditRetainDays int `json:"audit_retain_days"` // 操作审计日志保留天数，未设置时与 retain_days 相同
 	}
 	cfg.RetainDays = 30 // 默认保留30天
 	if config != "" {
 		json.Unmarshal([]byte(config), &cfg)
 	}
+	if cfg.AuditRetainDays <= 0 {
+		cfg.AuditRetainDays = cfg.RetainDays
+	}
 
 	expireTime := time.Now().AddDate(0, 0, -cfg.RetainDays)
 
-	// 注意：操作日志已改为文件存储，不再从数据库清理
-	// 文件日志的清理由日志服务自行管理
-
 	// 清理任务日志
 	s.repo.GetDB().Where("created_at < ?", expireTime).Delete(&model.TaskLog{})
 
+	if s.logSvc == nil {
+		return nil
+	}
+
+	// 删除过期的CSV日志文件
+	if _, err := s.logSvc.CleanLogFiles(expireTime); err != nil {
+		return fmt.Errorf("清理日志文件失败: %v", err)
+	}
+
+	// 操作审计日志归档到文件后从数据库删除，归档记录保留哈希链的衔接
+	if _, err := s.logSvc.ArchiveAuditLogs(time.Now().AddDate(0, 0, -cfg.AuditRetainDays)); err != nil {
+		return fmt.Errorf("归档操作日志失败: %v", err)
+	}
 	return nil
 }
 
@@ -282,7 +357,9 @@ func (s *TaskService) RunTaskNow(taskID uint) error {
 		return errors.New("任务不存在")
 	}
 
-	go s.executeTask(task)
+	if !s.dispatch(task) {
+		return errors.New("任务正在执行中")
+	}
 	return nil
 }
 
@@ -393,7 +470,7 @@ func (s *TaskService) InitDefaultTasks() error {
 			CronExpr:    "0 4 * * *", // 每天凌晨4点
 			Config:      `{"retain_days": 30}`,
 			Status:      0, // 默认禁用
-			Description: "清理30天前的操作日志",
+			Description: "清理30天前的任务日志和日志文件，操作日志归档后从数据库删除",
 		},
 	}
 
@@ -219,12 +219,20 @@ func (s *UndoService) undoProductDelete(operation *model.UndoOperation) error {
 	}
 
 	// 恢复商品（软删除恢复）
-	return s.repo.GetDB().Unscoped().Model(&model.Product{}).Where("id = ?", operation.TargetID).Update("deleted_at", nil).Error
+	if err := s.repo.GetDB().Unscoped().Model(&model.Product{}).Where("id = ?", operation.TargetID).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	invalidateCatalogCache()
+	return nil
 }
 
 // undoProductDisable 撤销商品禁用
 func (s *UndoService) undoProductDisable(operation *model.UndoOperation) error {
-	return s.repo.GetDB().Model(&model.Product{}).Where("id = ?", operation.TargetID).Update("status", 1).Error
+	if err := s.repo.GetDB().Model(&model.Product{}).Where("id = ?", operation.TargetID).Update("status", 1).Error; err != nil {
+		return err
+	}
+	invalidateCatalogCache()
+	return nil
 }
 
 // undoUserDisable 撤销用户禁用
@@ -0,0 +1,1024 @@
+// Package service 提供业务逻辑服务
+// usdt_chain_service.go - 自托管链上USDT收款检测
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+)
+
+// 链上接口类型
+const (
+	ChainAPITronGrid = "trongrid" // TronGrid 兼容接口（TRC20）
+	ChainAPIJSONRPC  = "jsonrpc"  // 以太坊 JSON-RPC 兼容接口（ERC20/BEP20）
+)
+
+const (
+	// USDTChainPollInterval 链上轮询间隔
+	USDTChainPollInterval = 30 * time.Second
+	// USDTPaymentTimeout 链上收款有效期（与订单自动取消时间一致）
+	USDTPaymentTimeout = 30 * time.Minute
+	// usdtAmountStep 唯一金额尾数步长（0.0001 USDT，尾数最多 0.0099，不超过订单金额校验误差）
+	usdtAmountStep = 0.0001
+	// usdtAmountSlots 同一基础金额下可同时分配的尾数数量
+	usdtAmountSlots = 99
+	// usdtAmountEpsilon 金额匹配精度
+	usdtAmountEpsilon = 0.0000005
+	// usdtTimeSkew 允许的链上时间与本地时间偏差
+	usdtTimeSkew = time.Minute
+	// jsonRPCLookbackBlocks 没有扫描进度时首次回溯的区块数
+	jsonRPCLookbackBlocks = 1000
+	// jsonRPCLogsRange 单次 eth_getLogs 查询的区块范围（多数节点限制在数千个区块以内）
+	jsonRPCLogsRange = 1000
+	// jsonRPCMaxScanBlocks 单次轮询最多扫描的区块数，落后更多时分多次轮询追赶
+	jsonRPCMaxScanBlocks = 20000
+	// usdtScanCursorKey 扫描进度在系统设置中的键前缀（后接网络与合约地址）
+	usdtScanCursorKey = "usdt_scan_block:"
+	// erc20TransferTopic ERC20 Transfer 事件签名
+	erc20TransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+// 各网络默认配置
+var (
+	defaultUSDTContracts = map[string]string{
+		"TRC20": "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+		"ERC20": "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+		"BEP20": "0x55d398326f99059fF775485246999027B3197955",
+	}
+	defaultUSDTDecimals = map[string]int{
+		"TRC20": 6,
+		"ERC20": 6,
+		"BEP20": 18,
+	}
+	defaultUSDTConfirmations = map[string]int{
+		"TRC20": 20,
+		"ERC20": 12,
+		"BEP20": 15,
+	}
+)
+
+// ChainTransfer 链上USDT转账记录
+type ChainTransfer struct {
+	TxHash      string
+	Contract    string // 代币合约地址（仅交易回执中返回）
+	From        string
+	To          string
+	Amount      float64
+	BlockNumber int64 // 为0表示接口未返回，需要单独查询
+	Timestamp   time.Time
+}
+
+// ChainReceipt 链上交易回执
+type ChainReceipt struct {
+	BlockNumber int64
+	Success     bool            // 交易执行成功
+	Transfers   []ChainTransfer // 交易产生的代币 Transfer 事件
+}
+
+// chainClient 链上接口客户端
+type chainClient interface {
+	// LatestBlock 获取最新区块高度
+	LatestBlock() (int64, error)
+	// Transfers 获取转入收款地址的USDT转账（按时间或区块范围，由接口类型决定）
+	Transfers(wallet string, since time.Time, fromBlock, toBlock int64) ([]ChainTransfer, error)
+	// TxReceipt 获取交易回执（交易不存在或未上链返回 nil）
+	TxReceipt(txHash string) (*ChainReceipt, error)
+}
+
+// USDTChainService 自托管链上USDT收款服务
+// 为每笔待支付订单分配唯一金额，定时轮询链上接口匹配转账，达到确认数后自动完成订单/充值
+type USDTChainService struct {
+	repo   *repository.Repository
+	cfg    *config.Config
+	client *http.Client
+
+	allocMu sync.Mutex // 唯一金额分配锁
+	pollMu  sync.Mutex // 轮询互斥，避免并发扫描
+
+	onConfirmed func(payment *model.USDTPayment) error
+}
+
+// NewUSDTChainService 创建链上USDT收款服务
+func NewUSDTChainService(repo *repository.Repository, cfg *config.Config) *USDTChainService {
+	return &USDTChainService{
+		repo:   repo,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// SetConfirmHandler 设置收款确认后的入账回调
+func (s *USDTChainService) SetConfirmHandler(handler func(payment *model.USDTPayment) error) {
+	s.onConfirmed = handler
+}
+
+// IsActive 是否启用了链上收款模式
+func (s *USDTChainService) IsActive() bool {
+	if s.cfg == nil {
+		return false
+	}
+	return s.cfg.PaymentConfig.USDTEnabled && s.cfg.PaymentConfig.USDTAPIProvider == "onchain"
+}
+
+// network 当前网络（默认TRC20）
+func (s *USDTChainService) network() string {
+	network := strings.ToUpper(s.cfg.PaymentConfig.USDTNetwork)
+	if network == "" {
+		network = "TRC20"
+	}
+	return network
+}
+
+// requiredConfirmations 需要的确认数
+func (s *USDTChainService) requiredConfirmations() int {
+	if n := s.cfg.PaymentConfig.USDTConfirmations; n > 0 {
+		return n
+	}
+	if n, ok := defaultUSDTConfirmations[s.network()]; ok {
+		return n
+	}
+	return 1
+}
+
+// contractAddress USDT 合约地址（未配置时使用网络默认值）
+func (s *USDTChainService) contractAddress() string {
+	if contract := s.cfg.PaymentConfig.USDTContractAddress; contract != "" {
+		return contract
+	}
+	return defaultUSDTContracts[s.network()]
+}
+
+// newClient 根据配置创建链上接口客户端
+func (s *USDTChainService) newClient() (chainClient, error) {
+	paymentCfg := &s.cfg.PaymentConfig
+	network := s.network()
+
+	contract := s.contractAddress()
+	decimals, ok := defaultUSDTDecimals[network]
+	if !ok {
+		decimals = 6
+	}
+
+	apiType := strings.ToLower(paymentCfg.USDTChainAPIType)
+	if apiType == "" {
+		if network == "TRC20" {
+			apiType = ChainAPITronGrid
+		} else {
+			apiType = ChainAPIJSONRPC
+		}
+	}
+
+	apiURL := strings.TrimRight(paymentCfg.USDTChainAPIURL, "/")
+	switch apiType {
+	case ChainAPITronGrid:
+		if apiURL == "" {
+			apiURL = "https://api.trongrid.io"
+		}
+		return &tronGridClient{baseURL: apiURL, apiKey: paymentCfg.USDTChainAPIKey, contract: contract, decimals: decimals, http: s.client}, nil
+	case ChainAPIJSONRPC:
+		if apiURL == "" {
+			return nil, errors.New("JSON-RPC 接口地址未配置")
+		}
+		return &jsonRPCClient{url: apiURL, contract: strings.ToLower(contract), decimals: decimals, http: s.client, blockTimes: make(map[int64]time.Time)}, nil
+	default:
+		return nil, fmt.Errorf("不支持的链上接口类型: %s", apiType)
+	}
+}
+
+// ==================== 收款创建 ====================
+
+// CreatePayment 为订单创建链上收款，分配唯一的USDT金额
+// 同一订单重复调用时返回仍有效的收款记录
+func (s *USDTChainService) CreatePayment(orderNo, kind string, usdtAmount float64) (*model.USDTPayment, error) {
+	wallet := s.cfg.PaymentConfig.USDTWalletAddress
+	if wallet == "" {
+		return nil, errors.New("收款钱包地址未配置")
+	}
+	if usdtAmount <= 0 {
+		return nil, errors.New("支付金额无效")
+	}
+
+	s.allocMu.Lock()
+	defer s.allocMu.Unlock()
+
+	db := s.repo.GetDB()
+	now := time.Now()
+
+	var existing model.USDTPayment
+	found := db.Where("order_no = ?", orderNo).First(&existing).Error == nil
+	if found {
+		switch existing.Status {
+		case model.USDTPaymentConfirming, model.USDTPaymentConfirmed, model.USDTPaymentFailed:
+			return &existing, nil
+		case model.USDTPaymentWaiting:
+			if existing.ExpiresAt.After(now) && existing.WalletAddress == wallet {
+				return &existing, nil
+			}
+		}
+	}
+
+	amount, err := s.allocateAmount(usdtAmount, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := existing
+	payment.OrderNo = orderNo
+	payment.Kind = kind
+	payment.Network = s.network()
+	payment.WalletAddress = wallet
+	payment.Amount = amount
+	payment.Status = model.USDTPaymentWaiting
+	payment.TxHash = ""
+	payment.FromAddress = ""
+	payment.PaidAmount = 0
+	payment.BlockNumber = 0
+	payment.Confirmations = 0
+	payment.StartBlock = 0
+	payment.Error = ""
+	payment.ExpiresAt = now.Add(USDTPaymentTimeout)
+	payment.ConfirmedAt = nil
+	if found {
+		// 重新计时，匹配窗口从本次创建开始
+		payment.CreatedAt = now
+	}
+
+	if err := db.Save(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// allocateAmount 在基础金额上追加唯一尾数，避免与其他待支付收款金额相同
+func (s *USDTChainService) allocateAmount(usdtAmount float64, excludeID uint) (float64, error) {
+	base := math.Round(usdtAmount*100) / 100
+	for i := 1; i <= usdtAmountSlots; i++ {
+		candidate := math.Round((base+float64(i)*usdtAmountStep)*1e6) / 1e6
+		var count int64
+		query := s.repo.GetDB().Model(&model.USDTPayment{}).
+			Where("status IN ? AND amount BETWEEN ? AND ?",
+				[]string{model.USDTPaymentWaiting, model.USDTPaymentConfirming},
+				candidate-usdtAmountEpsilon, candidate+usdtAmountEpsilon)
+		if excludeID > 0 {
+			query = query.Where("id <> ?", excludeID)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return 0, errors.New("当前同金额待支付订单过多，请稍后再试")
+}
+
+// GetPayment 获取订单的链上收款记录
+func (s *USDTChainService) GetPayment(orderNo string) (*model.USDTPayment, error) {
+	var payment model.USDTPayment
+	if err := s.repo.GetDB().Where("order_no = ?", orderNo).First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ListPayments 分页获取链上收款记录（管理员）
+func (s *USDTChainService) ListPayments(page, pageSize int, status string) ([]model.USDTPayment, int64, error) {
+	var payments []model.USDTPayment
+	var total int64
+
+	query := s.repo.GetDB().Model(&model.USDTPayment{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Count(&total)
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&payments).Error
+	return payments, total, err
+}
+
+// ==================== 链上轮询 ====================
+
+// Poll 执行一次链上扫描：过期处理、转账匹配、确认数更新与入账
+func (s *USDTChainService) Poll() error {
+	if !s.IsActive() {
+		return nil
+	}
+
+	s.pollMu.Lock()
+	defer s.pollMu.Unlock()
+
+	db := s.repo.GetDB()
+	now := time.Now()
+
+	// 超时未检测到转账的收款标记为过期
+	db.Model(&model.USDTPayment{}).
+		Where("status = ? AND expires_at < ?", model.USDTPaymentWaiting, now.Add(-usdtTimeSkew)).
+		Update("status", model.USDTPaymentExpired)
+
+	var waiting, confirming []model.USDTPayment
+	db.Where("status = ?", model.USDTPaymentWaiting).Order("created_at ASC").Find(&waiting)
+	db.Where("status = ?", model.USDTPaymentConfirming).Find(&confirming)
+	if len(waiting) == 0 && len(confirming) == 0 {
+		return nil
+	}
+
+	client, err := s.newClient()
+	if err != nil {
+		return err
+	}
+
+	latest, err := client.LatestBlock()
+	if err != nil {
+		return fmt.Errorf("获取最新区块失败: %v", err)
+	}
+
+	if len(waiting) > 0 {
+		matched, err := s.matchTransfers(client, waiting, latest)
+		if err != nil {
+			return err
+		}
+		confirming = append(confirming, matched...)
+	} else {
+		s.saveScanCursor(latest)
+	}
+
+	for i := range confirming {
+		s.updateConfirmations(client, &confirming[i], latest)
+	}
+	return nil
+}
+
+// matchTransfers 拉取转账并按金额+时间窗口匹配待支付收款
+//
+// 区块范围从扫描进度与最早的待支付收款的起始区块中较小者开始：收款首次被轮询到时记录当时的扫描进度
+// 作为起始区块，重启或链重组后退回待支付的收款仍会从其起始区块重新扫描
+func (s *USDTChainService) matchTransfers(client chainClient, waiting []model.USDTPayment, latest int64) ([]model.USDTPayment, error) {
+	wallet := s.cfg.PaymentConfig.USDTWalletAddress
+	since := waiting[0].CreatedAt.Add(-usdtTimeSkew)
+	db := s.repo.GetDB()
+
+	start := latest - jsonRPCLookbackBlocks
+	if cursor := s.scanCursor(latest); cursor > 0 {
+		start = cursor + 1
+	}
+	start = max(start, 0)
+	fromBlock := start
+	for i := range waiting {
+		p := &waiting[i]
+		if p.StartBlock == 0 {
+			p.StartBlock = start
+			db.Model(&model.USDTPayment{}).Where("id = ? AND start_block = 0", p.ID).Update("start_block", start)
+		}
+		fromBlock = min(fromBlock, p.StartBlock)
+	}
+	toBlock := min(latest, fromBlock+jsonRPCMaxScanBlocks-1)
+	if toBlock < latest {
+		log.Printf("[USDT] 待扫描区块过多，本次扫描 %d-%d，最新区块 %d", fromBlock, toBlock, latest)
+	}
+
+	transfers, err := client.Transfers(wallet, since, fromBlock, toBlock)
+	if err != nil {
+		return nil, fmt.Errorf("获取链上转账失败: %v", err)
+	}
+	s.saveScanCursor(toBlock)
+
+	var matched []model.USDTPayment
+	used := make(map[int]bool)
+
+	for _, tx := range transfers {
+		if !sameAddress(tx.To, wallet) {
+			continue
+		}
+
+		// 同一交易只能匹配一次
+		var count int64
+		db.Model(&model.USDTPayment{}).Where("tx_hash = ?", tx.TxHash).Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		for i := range waiting {
+			p := &waiting[i]
+			if used[i] || p.WalletAddress != wallet || math.Abs(tx.Amount-p.Amount) > usdtAmountEpsilon {
+				continue
+			}
+			if !tx.Timestamp.IsZero() && (tx.Timestamp.Before(p.CreatedAt.Add(-usdtTimeSkew)) || tx.Timestamp.After(p.ExpiresAt.Add(usdtTimeSkew))) {
+				continue
+			}
+
+			result := db.Model(&model.USDTPayment{}).
+				Where("id = ? AND status = ?", p.ID, model.USDTPaymentWaiting).
+				Updates(map[string]interface{}{
+					"status":       model.USDTPaymentConfirming,
+					"tx_hash":      tx.TxHash,
+					"from_address": tx.From,
+					"paid_amount":  tx.Amount,
+					"block_number": tx.BlockNumber,
+				})
+			if result.Error != nil || result.RowsAffected == 0 {
+				break
+			}
+
+			used[i] = true
+			p.Status = model.USDTPaymentConfirming
+			p.TxHash = tx.TxHash
+			p.FromAddress = tx.From
+			p.PaidAmount = tx.Amount
+			p.BlockNumber = tx.BlockNumber
+			matched = append(matched, *p)
+			log.Printf("[USDT] 检测到订单 %s 的链上转账: %s (%.6f USDT)", p.OrderNo, tx.TxHash, tx.Amount)
+			break
+		}
+	}
+	return matched, nil
+}
+
+// scanCursorKey 扫描进度的设置键，更换网络或合约后重新开始
+func (s *USDTChainService) scanCursorKey() string {
+	return usdtScanCursorKey + s.network() + ":" + strings.ToLower(s.contractAddress())
+}
+
+// scanCursor 读取已扫描到的区块，没有记录或超过最新区块（更换了节点）时返回 0
+func (s *USDTChainService) scanCursor(latest int64) int64 {
+	value, err := s.repo.GetSetting(s.scanCursorKey())
+	if err != nil {
+		return 0
+	}
+	cursor, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || cursor <= 0 || cursor > latest {
+		return 0
+	}
+	return cursor
+}
+
+// saveScanCursor 保存扫描进度（只向前推进，多实例轮询时保留较新的进度）
+func (s *USDTChainService) saveScanCursor(block int64) {
+	if value, err := s.repo.GetSetting(s.scanCursorKey()); err == nil {
+		if cursor, err := strconv.ParseInt(value, 10, 64); err == nil && cursor > block {
+			return
+		}
+	}
+	if err := s.repo.SetSetting(s.scanCursorKey(), strconv.FormatInt(block, 10), "链上USDT收款扫描进度"); err != nil {
+		log.Printf("[USDT] 保存扫描进度失败: %v", err)
+	}
+}
+
+// updateConfirmations 更新确认数，达到要求后重新核对交易回执并触发入账
+func (s *USDTChainService) updateConfirmations(client chainClient, p *model.USDTPayment, latest int64) {
+	db := s.repo.GetDB()
+
+	if p.BlockNumber == 0 {
+		receipt, err := client.TxReceipt(p.TxHash)
+		if err != nil || receipt == nil {
+			return
+		}
+		p.BlockNumber = receipt.BlockNumber
+	}
+
+	confirmations := int(latest - p.BlockNumber + 1)
+	if confirmations < 0 {
+		confirmations = 0
+	}
+	p.Confirmations = confirmations
+	saveProgress := func() {
+		db.Model(&model.USDTPayment{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+			"block_number":  p.BlockNumber,
+			"confirmations": p.Confirmations,
+		})
+	}
+
+	if confirmations < s.requiredConfirmations() {
+		saveProgress()
+		return
+	}
+
+	// 入账前重新查询回执：链重组后交易可能已被移出、换到其他区块或执行结果不同
+	receipt, err := client.TxReceipt(p.TxHash)
+	if err != nil {
+		log.Printf("[USDT] 查询订单 %s 的交易回执失败，下次轮询重试: %v", p.OrderNo, err)
+		saveProgress()
+		return
+	}
+	switch {
+	case receipt == nil:
+		s.resetPayment(p, "已不在链上")
+		return
+	case !receipt.Success:
+		s.resetPayment(p, "执行失败")
+		return
+	case !s.receiptHasTransfer(receipt, p):
+		s.resetPayment(p, "中没有与收款记录一致的USDT转账")
+		return
+	case receipt.BlockNumber != p.BlockNumber:
+		// 交易被重新打包到其他区块，按新区块重新累计确认数
+		log.Printf("[USDT] 订单 %s 的交易 %s 所在区块由 %d 变为 %d，重新计算确认数", p.OrderNo, p.TxHash, p.BlockNumber, receipt.BlockNumber)
+		p.BlockNumber = receipt.BlockNumber
+		p.Confirmations = max(int(latest-p.BlockNumber+1), 0)
+		saveProgress()
+		return
+	}
+
+	// 抢占确认状态，防止多实例重复入账
+	now := time.Now()
+	result := db.Model(&model.USDTPayment{}).
+		Where("id = ? AND status = ?", p.ID, model.USDTPaymentConfirming).
+		Updates(map[string]interface{}{
+			"status":        model.USDTPaymentConfirmed,
+			"block_number":  p.BlockNumber,
+			"confirmations": confirmations,
+			"confirmed_at":  now,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+	p.Status = model.USDTPaymentConfirmed
+	p.ConfirmedAt = &now
+
+	if s.onConfirmed == nil {
+		return
+	}
+	if err := s.onConfirmed(p); err != nil {
+		log.Printf("[USDT] 订单 %s 链上收款入账失败: %v", p.OrderNo, err)
+		db.Model(&model.USDTPayment{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+			"status": model.USDTPaymentFailed,
+			"error":  err.Error(),
+		})
+		return
+	}
+	log.Printf("[USDT] 订单 %s 链上收款已确认（%d 确认）", p.OrderNo, confirmations)
+}
+
+// receiptHasTransfer 回执中是否有与收款记录一致的USDT转账（合约、付款地址、收款地址与金额）
+func (s *USDTChainService) receiptHasTransfer(receipt *ChainReceipt, p *model.USDTPayment) bool {
+	contract := s.contractAddress()
+	for _, t := range receipt.Transfers {
+		if sameAddress(t.Contract, contract) && sameAddress(t.To, p.WalletAddress) &&
+			(p.FromAddress == "" || sameAddress(t.From, p.FromAddress)) &&
+			math.Abs(t.Amount-p.PaidAmount) <= usdtAmountEpsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// resetPayment 匹配的交易失效时将收款退回待支付并清除交易信息，之后可重新匹配（已超时的在下次轮询时过期）
+func (s *USDTChainService) resetPayment(p *model.USDTPayment, reason string) {
+	result := s.repo.GetDB().Model(&model.USDTPayment{}).
+		Where("id = ? AND status = ? AND tx_hash = ?", p.ID, model.USDTPaymentConfirming, p.TxHash).
+		Updates(map[string]interface{}{
+			"status":        model.USDTPaymentWaiting,
+			"tx_hash":       "",
+			"from_address":  "",
+			"paid_amount":   0,
+			"block_number":  0,
+			"confirmations": 0,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+	log.Printf("[USDT] 订单 %s 的交易 %s %s，已退回待支付", p.OrderNo, p.TxHash, reason)
+	p.Status = model.USDTPaymentWaiting
+	p.TxHash = ""
+	p.FromAddress = ""
+	p.PaidAmount = 0
+	p.BlockNumber = 0
+	p.Confirmations = 0
+}
+
+// TestConnection 测试链上接口连通性
+func (s *USDTChainService) TestConnection() (int64, error) {
+	client, err := s.newClient()
+	if err != nil {
+		return 0, err
+	}
+	return client.LatestBlock()
+}
+
+// sameAddress 比较链上地址（EVM地址不区分大小写）
+func sameAddress(a, b string) bool {
+	if strings.HasPrefix(a, "0x") || strings.HasPrefix(b, "0x") {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// tokenAmount 将链上整数金额按精度换算为浮点金额
+func tokenAmount(raw string, decimals int) (float64, error) {
+	value, ok := new(big.Int).SetString(strings.TrimPrefix(raw, "0x"), baseOf(raw))
+	if !ok {
+		return 0, fmt.Errorf("无效的金额: %s", raw)
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	amount, _ := new(big.Rat).SetFrac(value, denom).Float64()
+	return amount, nil
+}
+
+// baseOf 判断数字字符串进制
+func baseOf(raw string) int {
+	if strings.HasPrefix(raw, "0x") {
+		return 16
+	}
+	return 10
+}
+
+// ==================== TronGrid 客户端 ====================
+
+// tronGridClient TronGrid 兼容接口客户端
+type tronGridClient struct {
+	baseURL  string
+	apiKey   string
+	contract string
+	decimals int
+	http     *http.Client
+}
+
+// do 发送请求
+func (c *tronGridClient) do(method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("TRON-PRO-API-KEY", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("接口错误(%d): %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// LatestBlock 获取最新区块高度
+func (c *tronGridClient) LatestBlock() (int64, error) {
+	var result struct {
+		BlockHeader struct {
+			RawData struct {
+				Number int64 `json:"number"`
+			} `json:"raw_data"`
+		} `json:"block_header"`
+	}
+	if err := c.do("POST", "/wallet/getnowblock", map[string]interface{}{}, &result); err != nil {
+		return 0, err
+	}
+	return result.BlockHeader.RawData.Number, nil
+}
+
+// Transfers 获取转入收款地址的TRC20转账
+func (c *tronGridClient) Transfers(wallet string, since time.Time, _, _ int64) ([]ChainTransfer, error) {
+	params := url.Values{}
+	params.Set("only_to", "true")
+	params.Set("limit", "200")
+	params.Set("contract_address", c.contract)
+	params.Set("min_timestamp", strconv.FormatInt(since.UnixMilli(), 10))
+
+	var transfers []ChainTransfer
+	// 最多翻5页，单次轮询最多处理1000笔
+	for page := 0; page < 5; page++ {
+		var result struct {
+			Data []struct {
+				TransactionID  string `json:"transaction_id"`
+				From           string `json:"from"`
+				To             string `json:"to"`
+				Value          string `json:"value"`
+				BlockTimestamp int64  `json:"block_timestamp"`
+				TokenInfo      struct {
+					Address  string `json:"address"`
+					Decimals int    `json:"decimals"`
+				} `json:"token_info"`
+			} `json:"data"`
+			Meta struct {
+				Fingerprint string `json:"fingerprint"`
+			} `json:"meta"`
+		}
+		path := "/v1/accounts/" + url.PathEscape(wallet) + "/transactions/trc20?" + params.Encode()
+		if err := c.do("GET", path, nil, &result); err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Data {
+			if item.TokenInfo.Address != "" && item.TokenInfo.Address != c.contract {
+				continue
+			}
+			decimals := c.decimals
+			if item.TokenInfo.Decimals > 0 {
+				decimals = item.TokenInfo.Decimals
+			}
+			amount, err := tokenAmount(item.Value, decimals)
+			if err != nil {
+				continue
+			}
+			transfers = append(transfers, ChainTransfer{
+				TxHash:    item.TransactionID,
+				From:      item.From,
+				To:        item.To,
+				Amount:    amount,
+				Timestamp: time.UnixMilli(item.BlockTimestamp),
+			})
+		}
+
+		if result.Meta.Fingerprint == "" {
+			break
+		}
+		params.Set("fingerprint", result.Meta.Fingerprint)
+	}
+	return transfers, nil
+}
+
+// TxReceipt 获取交易回执，事件中的地址转换为 Base58 格式
+func (c *tronGridClient) TxReceipt(txHash string) (*ChainReceipt, error) {
+	var result struct {
+		ID          string `json:"id"`
+		BlockNumber int64  `json:"blockNumber"`
+		Receipt     struct {
+			Result string `json:"result"`
+		} `json:"receipt"`
+		Log []struct {
+			Address string   `json:"address"`
+			Topics  []string `json:"topics"`
+			Data    string   `json:"data"`
+		} `json:"log"`
+	}
+	if err := c.do("POST", "/wallet/gettransactioninfobyid", map[string]string{"value": txHash}, &result); err != nil {
+		return nil, err
+	}
+	if result.ID == "" || result.BlockNumber == 0 {
+		return nil, nil
+	}
+
+	receipt := &ChainReceipt{BlockNumber: result.BlockNumber, Success: result.Receipt.Result == "SUCCESS"}
+	for _, l := range result.Log {
+		if len(l.Topics) < 3 || !strings.EqualFold("0x"+l.Topics[0], erc20TransferTopic) {
+			continue
+		}
+		amount, err := tokenAmount("0x"+l.Data, c.decimals)
+		if err != nil {
+			continue
+		}
+		receipt.Transfers = append(receipt.Transfers, ChainTransfer{
+			TxHash:      txHash,
+			Contract:    tronAddress(l.Address),
+			From:        tronAddress(l.Topics[1]),
+			To:          tronAddress(l.Topics[2]),
+			Amount:      amount,
+			BlockNumber: result.BlockNumber,
+		})
+	}
+	return receipt, nil
+}
+
+// base58Alphabet Base58 字符表
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// tronAddress 将十六进制地址（20 字节地址、41 开头的地址或 32 字节 topic）转换为 Base58Check 格式
+func tronAddress(hexAddr string) string {
+	hexAddr = strings.TrimPrefix(hexAddr, "0x")
+	if len(hexAddr) < 40 {
+		return ""
+	}
+	raw, err := hex.DecodeString(hexAddr[len(hexAddr)-40:])
+	if err != nil {
+		return ""
+	}
+	payload := append([]byte{0x41}, raw...)
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	payload = append(payload, second[:4]...)
+
+	n := new(big.Int).SetBytes(payload)
+	radix, mod := big.NewInt(58), new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// ==================== JSON-RPC 客户端 ====================
+
+// jsonRPCClient 以太坊 JSON-RPC 兼容接口客户端
+type jsonRPCClient struct {
+	url        string
+	contract   string
+	decimals   int
+	http       *http.Client
+	blockTimes map[int64]time.Time
+}
+
+// call 调用 JSON-RPC 方法
+func (c *jsonRPCClient) call(method string, params []interface{}, out interface{}) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+
+	resp, err := c.http.Post(c.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("接口错误(%d): %s", resp.StatusCode, string(data))
+	}
+
+	var result struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	if result.Error != nil {
+		return fmt.Errorf("RPC错误(%d): %s", result.Error.Code, result.Error.Message)
+	}
+	return json.Unmarshal(result.Result, out)
+}
+
+// parseHexInt 解析十六进制整数
+func parseHexInt(hex string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimPrefix(hex, "0x"), 16, 64)
+	return n
+}
+
+// LatestBlock 获取最新区块高度
+func (c *jsonRPCClient) LatestBlock() (int64, error) {
+	var hex string
+	if err := c.call("eth_blockNumber", []interface{}{}, &hex); err != nil {
+		return 0, err
+	}
+	return parseHexInt(hex), nil
+}
+
+// Transfers 通过 eth_getLogs 获取区块范围内转入收款地址的USDT转账，按 jsonRPCLogsRange 分段查询
+func (c *jsonRPCClient) Transfers(wallet string, _ time.Time, fromBlock, toBlock int64) ([]ChainTransfer, error) {
+	var transfers []ChainTransfer
+	for start := fromBlock; start <= toBlock; start += jsonRPCLogsRange {
+		end := min(start+jsonRPCLogsRange-1, toBlock)
+		items, err := c.transferLogs(wallet, start, end)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, items...)
+	}
+	return transfers, nil
+}
+
+// transferLogs 查询一段区块内的 Transfer 事件
+func (c *jsonRPCClient) transferLogs(wallet string, fromBlock, toBlock int64) ([]ChainTransfer, error) {
+	toTopic := "0x000000000000000000000000" + strings.ToLower(strings.TrimPrefix(wallet, "0x"))
+	filter := map[string]interface{}{
+		"fromBlock": "0x" + strconv.FormatInt(fromBlock, 16),
+		"toBlock":   "0x" + strconv.FormatInt(toBlock, 16),
+		"address":   c.contract,
+		"topics":    []interface{}{erc20TransferTopic, nil, toTopic},
+	}
+
+	var logs []struct {
+		TransactionHash string   `json:"transactionHash"`
+		BlockNumber     string   `json:"blockNumber"`
+		BlockTimestamp  string   `json:"blockTimestamp"`
+		Data            string   `json:"data"`
+		Topics          []string `json:"topics"`
+		Removed         bool     `json:"removed"`
+	}
+	if err := c.call("eth_getLogs", []interface{}{filter}, &logs); err != nil {
+		return nil, err
+	}
+
+	var transfers []ChainTransfer
+	for _, l := range logs {
+		if l.Removed || len(l.Topics) < 3 {
+			continue
+		}
+		amount, err := tokenAmount(l.Data, c.decimals)
+		if err != nil {
+			continue
+		}
+
+		block := parseHexInt(l.BlockNumber)
+		var ts time.Time
+		if l.BlockTimestamp != "" {
+			ts = time.Unix(parseHexInt(l.BlockTimestamp), 0)
+		} else {
+			ts = c.blockTime(block)
+		}
+
+		transfers = append(transfers, ChainTransfer{
+			TxHash:      l.TransactionHash,
+			From:        topicAddress(l.Topics[1]),
+			To:          topicAddress(l.Topics[2]),
+			Amount:      amount,
+			BlockNumber: block,
+			Timestamp:   ts,
+		})
+	}
+	return transfers, nil
+}
+
+// blockTime 获取区块时间（带缓存，失败返回零值表示不做时间校验）
+func (c *jsonRPCClient) blockTime(block int64) time.Time {
+	if ts, ok := c.blockTimes[block]; ok {
+		return ts
+	}
+	var result struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := c.call("eth_getBlockByNumber", []interface{}{"0x" + strconv.FormatInt(block, 16), false}, &result); err != nil || result.Timestamp == "" {
+		return time.Time{}
+	}
+	ts := time.Unix(parseHexInt(result.Timestamp), 0)
+	c.blockTimes[block] = ts
+	return ts
+}
+
+// TxReceipt 通过 eth_getTransactionReceipt 获取交易回执
+func (c *jsonRPCClient) TxReceipt(txHash string) (*ChainReceipt, error) {
+	var result *struct {
+		BlockNumber string `json:"blockNumber"`
+		Status      string `json:"status"`
+		Logs        []struct {
+			Address string   `json:"address"`
+			Topics  []string `json:"topics"`
+			Data    string   `json:"data"`
+			Removed bool     `json:"removed"`
+		} `json:"logs"`
+	}
+	if err := c.call("eth_getTransactionReceipt", []interface{}{txHash}, &result); err != nil {
+		return nil, err
+	}
+	if result == nil || parseHexInt(result.BlockNumber) == 0 {
+		return nil, nil
+	}
+
+	receipt := &ChainReceipt{BlockNumber: parseHexInt(result.BlockNumber), Success: result.Status == "0x1"}
+	for _, l := range result.Logs {
+		if l.Removed || len(l.Topics) < 3 || !strings.EqualFold(l.Topics[0], erc20TransferTopic) {
+			continue
+		}
+		amount, err := tokenAmount(l.Data, c.decimals)
+		if err != nil {
+			continue
+		}
+		receipt.Transfers = append(receipt.Transfers, ChainTransfer{
+			TxHash:      txHash,
+			Contract:    l.Address,
+			From:        topicAddress(l.Topics[1]),
+			To:          topicAddress(l.Topics[2]),
+			Amount:      amount,
+			BlockNumber: receipt.BlockNumber,
+		})
+	}
+	return receipt, nil
+}
+
+// topicAddress 从事件 topic 中提取地址
+func topicAddress(topic string) string {
+	topic = strings.TrimPrefix(topic, "0x")
+	if len(topic) < 40 {
+		return "0x" + topic
+	}
+	return "0x" + strings.ToLower(topic[len(topic)-40:])
+}
@@ -0,0 +1,697 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testUSDTWallet = "TWalletAddressForTests0000000000000"
+
+// newTestUSDTChainService 创建使用内存数据库的链上收款服务
+func newTestUSDTChainService(t *testing.T) *USDTChainService {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("无法创建测试数据库: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接独立
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.USDTPayment{}, &model.SystemSetting{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.PaymentConfig.USDTEnabled = true
+	cfg.PaymentConfig.USDTAPIProvider = "onchain"
+	cfg.PaymentConfig.USDTNetwork = "TRC20"
+	cfg.PaymentConfig.USDTWalletAddress = testUSDTWallet
+	return NewUSDTChainService(repository.NewRepository(db), cfg)
+}
+
+// holdAmount 直接写入一条占用金额的收款记录
+func holdAmount(t *testing.T, s *USDTChainService, orderNo string, amount float64, status string, expiresAt time.Time) model.USDTPayment {
+	t.Helper()
+	p := model.USDTPayment{
+		OrderNo:       orderNo,
+		Kind:          model.USDTPaymentKindOrder,
+		Network:       "TRC20",
+		WalletAddress: testUSDTWallet,
+		Amount:        amount,
+		Status:        status,
+		ExpiresAt:     expiresAt,
+	}
+	if err := s.repo.GetDB().Create(&p).Error; err != nil {
+		t.Fatalf("创建收款记录失败: %v", err)
+	}
+	return p
+}
+
+// sameAmount 按匹配精度比较金额
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) <= usdtAmountEpsilon
+}
+
+// TestUSDTChainService_AllocateAmount 测试唯一尾数分配
+func TestUSDTChainService_AllocateAmount(t *testing.T) {
+	future := time.Now().Add(USDTPaymentTimeout)
+
+	tests := []struct {
+		name    string
+		held    []float64 // 已占用的金额（待支付）
+		request float64
+		want    float64
+	}{
+		{"无占用取第一个尾数", nil, 10, 10.0001},
+		{"跳过已占用尾数", []float64{10.0001, 10.0002}, 10, 10.0003},
+		{"使用空出的尾数", []float64{10.0001, 10.0003}, 10, 10.0002},
+		{"基础金额按分取整", nil, 10.004, 10.0001},
+		{"基础金额向上取整", nil, 9.996, 10.0001},
+		{"取整后与已占用金额相同", []float64{10.0001}, 9.999, 10.0002},
+		{"相邻基础金额互不影响", []float64{10.0101, 9.9901}, 10, 10.0001},
+		{"小额", []float64{0.1001}, 0.1, 0.1002},
+		{"大额保留四位小数", nil, 123456.78, 123456.7801},
+		// 尾数 0.0010 与基础金额多一分的 0.01 不应混淆
+		{"不同小数位不冲突", []float64{1.01, 1.001}, 1, 1.0001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestUSDTChainService(t)
+			for i, amount := range tt.held {
+				holdAmount(t, s, "HELD"+strconv.Itoa(i), amount, model.USDTPaymentWaiting, future)
+			}
+			got, err := s.allocateAmount(tt.request, 0)
+			if err != nil {
+				t.Fatalf("分配失败: %v", err)
+			}
+			if !sameAmount(got, tt.want) {
+				t.Errorf("期望 %.6f, 实际 %.6f", tt.want, got)
+			}
+			// 结果应恰好是 6 位小数，避免浮点误差导致匹配失败
+			if got != math.Round(got*1e6)/1e6 {
+				t.Errorf("金额应精确到 6 位小数，实际 %v", got)
+			}
+		})
+	}
+}
+
+// TestUSDTChainService_AllocateAmountExhausted 测试尾数用尽
+func TestUSDTChainService_AllocateAmountExhausted(t *testing.T) {
+	s := newTestUSDTChainService(t)
+	future := time.Now().Add(USDTPaymentTimeout)
+	for i := 1; i <= usdtAmountSlots; i++ {
+		status := model.USDTPaymentWaiting
+		if i%2 == 0 {
+			status = model.USDTPaymentConfirming // 已检测到转账的同样占用尾数
+		}
+		holdAmount(t, s, "HELD"+strconv.Itoa(i), 5+float64(i)*usdtAmountStep, status, future)
+	}
+
+	if _, err := s.allocateAmount(5, 0); err == nil {
+		t.Fatal("尾数用尽时应返回错误")
+	}
+
+	// 其他基础金额不受影响
+	if got, err := s.allocateAmount(5.01, 0); err != nil || !sameAmount(got, 5.0101) {
+		t.Errorf("相邻基础金额应可分配，实际 %.6f (%v)", got, err)
+	}
+
+	// 排除自身：同一订单重新创建收款时可复用原金额
+	var own model.USDTPayment
+	s.repo.GetDB().Where("order_no = ?", "HELD37").First(&own)
+	got, err := s.allocateAmount(5, own.ID)
+	if err != nil {
+		t.Fatalf("排除自身后应可分配: %v", err)
+	}
+	if !sameAmount(got, own.Amount) {
+		t.Errorf("应复用自身金额 %.6f，实际 %.6f", own.Amount, got)
+	}
+}
+
+// TestUSDTChainService_HeldAmountRelease 测试收款结束或过期后释放尾数
+func TestUSDTChainService_HeldAmountRelease(t *testing.T) {
+	s := newTestUSDTChainService(t)
+	now := time.Now()
+
+	// 已结束的收款不占用尾数
+	holdAmount(t, s, "DONE1", 20.0001, model.USDTPaymentConfirmed, now.Add(-time.Hour))
+	holdAmount(t, s, "DONE2", 20.0002, model.USDTPaymentExpired, now.Add(-time.Hour))
+	holdAmount(t, s, "DONE3", 20.0003, model.USDTPaymentFailed, now.Add(-time.Hour))
+	if got, _ := s.allocateAmount(20, 0); !sameAmount(got, 20.0001) {
+		t.Fatalf("已结束的收款不应占用尾数，实际分配 %.6f", got)
+	}
+
+	// 刚过期但仍在时间偏差内的收款继续占用，超出偏差的收款在轮询时过期并释放
+	withinSkew := holdAmount(t, s, "SKEW", 20.0001, model.USDTPaymentWaiting, now.Add(-usdtTimeSkew/2))
+	expired := holdAmount(t, s, "EXPIRED", 20.0002, model.USDTPaymentWaiting, now.Add(-2*usdtTimeSkew))
+	if got, _ := s.allocateAmount(20, 0); !sameAmount(got, 20.0003) {
+		t.Fatalf("过期前应继续占用尾数，实际分配 %.6f", got)
+	}
+
+	// 只剩过期记录时 Poll 不会访问链上接口
+	s.repo.GetDB().Model(&model.USDTPayment{}).Where("id = ?", withinSkew.ID).Update("status", model.USDTPaymentConfirmed)
+	if err := s.Poll(); err != nil {
+		t.Fatalf("轮询失败: %v", err)
+	}
+
+	var p model.USDTPayment
+	s.repo.GetDB().First(&p, expired.ID)
+	if p.Status != model.USDTPaymentExpired {
+		t.Errorf("超时的收款应标记为过期，实际 %s", p.Status)
+	}
+	if got, _ := s.allocateAmount(20, 0); !sameAmount(got, 20.0001) {
+		t.Errorf("过期后应释放尾数，实际分配 %.6f", got)
+	}
+}
+
+// TestUSDTChainService_CreatePaymentReuse 测试同一订单重复创建收款
+func TestUSDTChainService_CreatePaymentReuse(t *testing.T) {
+	s := newTestUSDTChainService(t)
+
+	first, err := s.CreatePayment("ORDER1", model.USDTPaymentKindOrder, 8)
+	if err != nil {
+		t.Fatalf("创建收款失败: %v", err)
+	}
+	second, err := s.CreatePayment("ORDER1", model.USDTPaymentKindOrder, 8)
+	if err != nil {
+		t.Fatalf("重复创建收款失败: %v", err)
+	}
+	if second.ID != first.ID || !sameAmount(second.Amount, first.Amount) {
+		t.Errorf("有效期内应返回原收款记录: %+v / %+v", first, second)
+	}
+
+	// 过期后重新创建，复用记录并重新计时
+	s.repo.GetDB().Model(&model.USDTPayment{}).Where("id = ?", first.ID).
+		Updates(map[string]interface{}{"status": model.USDTPaymentExpired, "expires_at": time.Now().Add(-time.Hour)})
+	other, _ := s.CreatePayment("ORDER2", model.USDTPaymentKindOrder, 8)
+	renewed, err := s.CreatePayment("ORDER1", model.USDTPaymentKindOrder, 8)
+	if err != nil {
+		t.Fatalf("过期后重新创建失败: %v", err)
+	}
+	if renewed.ID != first.ID || renewed.Status != model.USDTPaymentWaiting || !renewed.ExpiresAt.After(time.Now()) {
+		t.Errorf("应复用原记录并重新计时: %+v", renewed)
+	}
+	if sameAmount(renewed.Amount, other.Amount) {
+		t.Errorf("重新分配的金额不应与其他待支付收款相同: %.6f", renewed.Amount)
+	}
+}
+
+// fakeChainClient 返回固定转账列表和交易回执的链上客户端，并记录查询的区块范围
+type fakeChainClient struct {
+	latest     int64
+	transfers  []ChainTransfer
+	receipts   map[string]*ChainReceipt
+	receiptErr error
+	ranges     [][2]int64
+}
+
+func (c *fakeChainClient) LatestBlock() (int64, error) { return c.latest, nil }
+
+func (c *fakeChainClient) Transfers(_ string, _ time.Time, fromBlock, toBlock int64) ([]ChainTransfer, error) {
+	c.ranges = append(c.ranges, [2]int64{fromBlock, toBlock})
+	return c.transfers, nil
+}
+
+func (c *fakeChainClient) TxReceipt(txHash string) (*ChainReceipt, error) {
+	if c.receiptErr != nil {
+		return nil, c.receiptErr
+	}
+	return c.receipts[txHash], nil
+}
+
+// TestUSDTChainService_MatchTransfers 测试转账按地址、金额、时间窗口匹配
+func TestUSDTChainService_MatchTransfers(t *testing.T) {
+	now := time.Now()
+	future := now.Add(USDTPaymentTimeout)
+
+	tests := []struct {
+		name      string
+		amounts   []float64 // 待支付收款金额，订单号依次为 P0、P1...
+		transfers []ChainTransfer
+		want      map[string]string // 订单号 -> 匹配的交易哈希
+	}{
+		{
+			name:      "精确金额匹配",
+			amounts:   []float64{10.0001, 10.0002},
+			transfers: []ChainTransfer{{TxHash: "tx1", To: testUSDTWallet, Amount: 10.0002, Timestamp: now}},
+			want:      map[string]string{"P1": "tx1"},
+		},
+		{
+			name:    "金额相差超过精度不匹配",
+			amounts: []float64{10.0001},
+			transfers: []ChainTransfer{
+				{TxHash: "tx1", To: testUSDTWallet, Amount: 10.000101, Timestamp: now},
+				{TxHash: "tx2", To: testUSDTWallet, Amount: 10, Timestamp: now},
+			},
+			want: map[string]string{},
+		},
+		{
+			name:    "不同小数位金额不混淆",
+			amounts: []float64{1.0010, 1.0001, 1.0100},
+			transfers: []ChainTransfer{
+				{TxHash: "tx1", To: testUSDTWallet, Amount: 1.0001, Timestamp: now},
+				{TxHash: "tx2", To: testUSDTWallet, Amount: 1.01, Timestamp: now},
+			},
+			want: map[string]string{"P1": "tx1", "P2": "tx2"},
+		},
+		{
+			name:    "18 位精度换算后匹配",
+			amounts: []float64{25.0007},
+			transfers: []ChainTransfer{
+				{TxHash: "tx1", To: testUSDTWallet, Amount: mustTokenAmount(t, "25000700000000000000", 18), Timestamp: now},
+			},
+			want: map[string]string{"P0": "tx1"},
+		},
+		{
+			name:      "其他收款地址忽略",
+			amounts:   []float64{10.0001},
+			transfers: []ChainTransfer{{TxHash: "tx1", To: "TOtherWallet", Amount: 10.0001, Timestamp: now}},
+			want:      map[string]string{},
+		},
+		{
+			name:    "时间窗口外不匹配",
+			amounts: []float64{10.0001},
+			transfers: []ChainTransfer{
+				{TxHash: "early", To: testUSDTWallet, Amount: 10.0001, Timestamp: now.Add(-2 * usdtTimeSkew)},
+				{TxHash: "late", To: testUSDTWallet, Amount: 10.0001, Timestamp: future.Add(2 * usdtTimeSkew)},
+			},
+			want: map[string]string{},
+		},
+		{
+			name:      "接口未返回时间时按金额匹配",
+			amounts:   []float64{10.0001},
+			transfers: []ChainTransfer{{TxHash: "tx1", To: testUSDTWallet, Amount: 10.0001}},
+			want:      map[string]string{"P0": "tx1"},
+		},
+		{
+			name:    "同金额的第二笔转账不重复匹配",
+			amounts: []float64{10.0001},
+			transfers: []ChainTransfer{
+				{TxHash: "tx1", To: testUSDTWallet, Amount: 10.0001, Timestamp: now},
+				{TxHash: "tx2", To: testUSDTWallet, Amount: 10.0001, Timestamp: now},
+			},
+			want: map[string]string{"P0": "tx1"},
+		},
+		{
+			name:    "同一交易只匹配一次",
+			amounts: []float64{10.0001, 20.0001},
+			transfers: []ChainTransfer{
+				{TxHash: "tx1", To: testUSDTWallet, Amount: 10.0001, Timestamp: now},
+				{TxHash: "tx1", To: testUSDTWallet, Amount: 20.0001, Timestamp: now},
+			},
+			want: map[string]string{"P0": "tx1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestUSDTChainService(t)
+			var waiting []model.USDTPayment
+			for i, amount := range tt.amounts {
+				p := holdAmount(t, s, "P"+strconv.Itoa(i), amount, model.USDTPaymentWaiting, future)
+				waiting = append(waiting, p)
+			}
+
+			client := &fakeChainClient{latest: 100, transfers: tt.transfers}
+			matched, err := s.matchTransfers(client, waiting, client.latest)
+			if err != nil {
+				t.Fatalf("匹配失败: %v", err)
+			}
+			if len(matched) != len(tt.want) {
+				t.Errorf("期望匹配 %d 笔，实际 %d 笔", len(tt.want), len(matched))
+			}
+
+			var stored []model.USDTPayment
+			s.repo.GetDB().Order("id").Find(&stored)
+			for _, p := range stored {
+				wantTx, ok := tt.want[p.OrderNo]
+				switch {
+				case ok && (p.Status != model.USDTPaymentConfirming || p.TxHash != wantTx):
+					t.Errorf("%s 应匹配 %s，实际状态 %s 交易 %q", p.OrderNo, wantTx, p.Status, p.TxHash)
+				case !ok && p.Status != model.USDTPaymentWaiting:
+					t.Errorf("%s 不应被匹配，实际状态 %s 交易 %q", p.OrderNo, p.Status, p.TxHash)
+				}
+			}
+		})
+	}
+}
+
+// TestUSDTChainService_MatchTransfersUsedTx 测试已被其他收款使用的交易不再匹配
+func TestUSDTChainService_MatchTransfersUsedTx(t *testing.T) {
+	s := newTestUSDTChainService(t)
+	future := time.Now().Add(USDTPaymentTimeout)
+
+	done := holdAmount(t, s, "DONE", 10.0001, model.USDTPaymentConfirmed, future)
+	s.repo.GetDB().Model(&done).Update("tx_hash", "tx1")
+	p := holdAmount(t, s, "P0", 10.0001, model.USDTPaymentWaiting, future)
+
+	client := &fakeChainClient{latest: 100, transfers: []ChainTransfer{{TxHash: "tx1", To: testUSDTWallet, Amount: 10.0001}}}
+	matched, err := s.matchTransfers(client, []model.USDTPayment{p}, client.latest)
+	if err != nil {
+		t.Fatalf("匹配失败: %v", err)
+	}
+	if len(matched) != 0 {
+		t.Errorf("已使用的交易不应再次匹配: %+v", matched)
+	}
+}
+
+// mustTokenAmount 换算链上整数金额
+func mustTokenAmount(t *testing.T, raw string, decimals int) float64 {
+	t.Helper()
+	amount, err := tokenAmount(raw, decimals)
+	if err != nil {
+		t.Fatalf("金额换算失败: %v", err)
+	}
+	return amount
+}
+
+// TestUSDTChainService_UpdateConfirmations 测试达到确认数后重新核对交易回执
+func TestUSDTChainService_UpdateConfirmations(t *testing.T) {
+	const contract = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t" // TRC20 默认 USDT 合约
+	transfer := ChainTransfer{TxHash: "tx1", Contract: contract, From: "TPayer", To: testUSDTWallet, Amount: 10.0001}
+	receipt := func(block int64, success bool, transfers ...ChainTransfer) *ChainReceipt {
+		return &ChainReceipt{BlockNumber: block, Success: success, Transfers: transfers}
+	}
+	with := func(change func(t *ChainTransfer)) ChainTransfer {
+		t := transfer
+		change(&t)
+		return t
+	}
+
+	tests := []struct {
+		name       string
+		latest     int64
+		receipt    *ChainReceipt
+		receiptErr error
+		wantStatus string
+		wantBlock  int64
+		wantConf   int
+		wantPaid   bool
+	}{
+		{"确认数不足", 92, receipt(90, true, transfer), nil, model.USDTPaymentConfirming, 90, 3, false},
+		{"回执一致时入账", 100, receipt(90, true, transfer), nil, model.USDTPaymentConfirmed, 90, 11, true},
+		{"回执含其他事件", 100, receipt(90, true, with(func(t *ChainTransfer) { t.To = "TOther" }), transfer), nil, model.USDTPaymentConfirmed, 90, 11, true},
+		{"查询回执失败时保持不变", 100, nil, errors.New("timeout"), model.USDTPaymentConfirming, 90, 11, false},
+		{"交易已不在链上", 100, nil, nil, model.USDTPaymentWaiting, 0, 0, false},
+		{"交易执行失败", 100, receipt(90, false, transfer), nil, model.USDTPaymentWaiting, 0, 0, false},
+		{"没有转账事件", 100, receipt(90, true), nil, model.USDTPaymentWaiting, 0, 0, false},
+		{"金额不符", 100, receipt(90, true, with(func(t *ChainTransfer) { t.Amount = 10.0002 })), nil, model.USDTPaymentWaiting, 0, 0, false},
+		{"收款地址不符", 100, receipt(90, true, with(func(t *ChainTransfer) { t.To = "TOther" })), nil, model.USDTPaymentWaiting, 0, 0, false},
+		{"付款地址不符", 100, receipt(90, true, with(func(t *ChainTransfer) { t.From = "TOther" })), nil, model.USDTPaymentWaiting, 0, 0, false},
+		{"合约不符", 100, receipt(90, true, with(func(t *ChainTransfer) { t.Contract = "TFakeToken" })), nil, model.USDTPaymentWaiting, 0, 0, false},
+		{"区块变化后重新计算确认数", 100, receipt(98, true, transfer), nil, model.USDTPaymentConfirming, 98, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestUSDTChainService(t)
+			s.cfg.PaymentConfig.USDTConfirmations = 5
+			var paid []string
+			s.SetConfirmHandler(func(p *model.USDTPayment) error {
+				paid = append(paid, p.OrderNo)
+				return nil
+			})
+
+			p := holdAmount(t, s, "P0", 10.0001, model.USDTPaymentConfirming, time.Now().Add(USDTPaymentTimeout))
+			s.repo.GetDB().Model(&p).Updates(map[string]interface{}{
+				"tx_hash": "tx1", "from_address": "TPayer", "paid_amount": 10.0001, "block_number": 90,
+			})
+			s.repo.GetDB().First(&p, p.ID)
+
+			client := &fakeChainClient{latest: tt.latest, receipts: map[string]*ChainReceipt{}, receiptErr: tt.receiptErr}
+			if tt.receipt != nil {
+				client.receipts["tx1"] = tt.receipt
+			}
+			s.updateConfirmations(client, &p, tt.latest)
+
+			var stored model.USDTPayment
+			s.repo.GetDB().First(&stored, p.ID)
+			if stored.Status != tt.wantStatus || stored.BlockNumber != tt.wantBlock || stored.Confirmations != tt.wantConf {
+				t.Errorf("期望 %s/区块 %d/确认 %d，实际 %s/区块 %d/确认 %d",
+					tt.wantStatus, tt.wantBlock, tt.wantConf, stored.Status, stored.BlockNumber, stored.Confirmations)
+			}
+			if tt.wantStatus == model.USDTPaymentWaiting && (stored.TxHash != "" || stored.FromAddress != "" || stored.PaidAmount != 0) {
+				t.Errorf("退回待支付时应清除交易信息: %+v", stored)
+			}
+			if (len(paid) > 0) != tt.wantPaid {
+				t.Errorf("入账回调调用 %v，期望 %v", paid, tt.wantPaid)
+			}
+		})
+	}
+}
+
+// TestTronAddress 测试十六进制地址转换为 Base58Check 格式
+func TestTronAddress(t *testing.T) {
+	const usdt = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	tests := []struct {
+		name string
+		hex  string
+		want string
+	}{
+		{"41 开头", "41a614f803b6fd780986a42c78ec9c7f77e6ded13c", usdt},
+		{"20 字节", "a614f803b6fd780986a42c78ec9c7f77e6ded13c", usdt},
+		{"32 字节 topic", "000000000000000000000000a614f803b6fd780986a42c78ec9c7f77e6ded13c", usdt},
+		{"0x 前缀", "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c", usdt},
+		{"长度不足", "a614f803", ""},
+		{"非十六进制", "zz14f803b6fd780986a42c78ec9c7f77e6ded13c", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tronAddress(tt.hex); got != tt.want {
+				t.Errorf("期望 %q, 实际 %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestUSDTChainService_ScanCursor 测试扫描进度持久化与起始区块
+func TestUSDTChainService_ScanCursor(t *testing.T) {
+	s := newTestUSDTChainService(t)
+	future := time.Now().Add(USDTPaymentTimeout)
+	db := s.repo.GetDB()
+
+	scan := func(svc *USDTChainService, latest int64) [2]int64 {
+		t.Helper()
+		var waiting []model.USDTPayment
+		db.Where("status = ?", model.USDTPaymentWaiting).Order("created_at ASC").Find(&waiting)
+		client := &fakeChainClient{latest: latest}
+		if _, err := svc.matchTransfers(client, waiting, latest); err != nil {
+			t.Fatalf("扫描失败: %v", err)
+		}
+		if len(client.ranges) != 1 {
+			t.Fatalf("应查询一次转账，实际 %d 次", len(client.ranges))
+		}
+		return client.ranges[0]
+	}
+	startBlock := func(orderNo string) int64 {
+		var p model.USDTPayment
+		db.Where("order_no = ?", orderNo).First(&p)
+		return p.StartBlock
+	}
+
+	// 没有扫描进度时回溯固定区块数
+	holdAmount(t, s, "P1", 10.0001, model.USDTPaymentWaiting, future)
+	if got := scan(s, 5000); got != [2]int64{5000 - jsonRPCLookbackBlocks, 5000} {
+		t.Errorf("首次扫描范围错误: %v", got)
+	}
+	if got := startBlock("P1"); got != 5000-jsonRPCLookbackBlocks {
+		t.Errorf("P1 起始区块应为 %d，实际 %d", 5000-jsonRPCLookbackBlocks, got)
+	}
+	if got := s.scanCursor(5000); got != 5000 {
+		t.Errorf("扫描进度应为 5000，实际 %d", got)
+	}
+
+	// 新收款从扫描进度之后开始，范围仍覆盖更早的待支付收款
+	holdAmount(t, s, "P2", 10.0002, model.USDTPaymentWaiting, future)
+	if got := scan(s, 5010); got != [2]int64{5000 - jsonRPCLookbackBlocks, 5010} {
+		t.Errorf("第二次扫描范围错误: %v", got)
+	}
+	if got := startBlock("P2"); got != 5001 {
+		t.Errorf("P2 起始区块应为 5001，实际 %d", got)
+	}
+
+	// 重启后从持久化的进度和最早的待支付收款继续
+	db.Model(&model.USDTPayment{}).Where("order_no = ?", "P1").Update("status", model.USDTPaymentExpired)
+	restarted := NewUSDTChainService(s.repo, s.cfg)
+	if got := scan(restarted, 5020); got != [2]int64{5001, 5020} {
+		t.Errorf("重启后扫描范围错误: %v", got)
+	}
+
+	// 落后过多时分多次追赶，进度只向前推进
+	db.Model(&model.USDTPayment{}).Where("order_no = ?", "P2").Update("start_block", 100)
+	if got := scan(restarted, 50000); got != [2]int64{100, 100 + jsonRPCMaxScanBlocks - 1} {
+		t.Errorf("追赶扫描范围错误: %v", got)
+	}
+	if got := restarted.scanCursor(50000); got != 100+jsonRPCMaxScanBlocks-1 {
+		t.Errorf("追赶后扫描进度错误: %d", got)
+	}
+	restarted.saveScanCursor(5000)
+	if got := restarted.scanCursor(50000); got != 100+jsonRPCMaxScanBlocks-1 {
+		t.Errorf("扫描进度不应回退: %d", got)
+	}
+
+	// 进度超过最新区块（更换了节点）时忽略
+	if got := restarted.scanCursor(1000); got != 0 {
+		t.Errorf("超过最新区块的进度应忽略，实际 %d", got)
+	}
+
+	// 更换网络后重新开始
+	restarted.cfg.PaymentConfig.USDTNetwork = "BEP20"
+	if got := restarted.scanCursor(50000); got != 0 {
+		t.Errorf("更换网络后不应沿用扫描进度，实际 %d", got)
+	}
+}
+
+// newTestRPCServer 模拟 JSON-RPC 节点，handler 按方法和参数返回 result
+func newTestRPCServer(t *testing.T, handler func(method string, params []json.RawMessage) interface{}) *jsonRPCClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": handler(req.Method, req.Params)})
+	}))
+	t.Cleanup(srv.Close)
+	return &jsonRPCClient{url: srv.URL, contract: "0xcontract", decimals: 6, http: srv.Client(), blockTimes: make(map[int64]time.Time)}
+}
+
+// TestJSONRPCClient_TransfersChunked 测试 eth_getLogs 按区块范围分段查询
+func TestJSONRPCClient_TransfersChunked(t *testing.T) {
+	var ranges []string
+	client := newTestRPCServer(t, func(method string, params []json.RawMessage) interface{} {
+		var filter struct {
+			FromBlock string `json:"fromBlock"`
+			ToBlock   string `json:"toBlock"`
+		}
+		json.Unmarshal(params[0], &filter)
+		ranges = append(ranges, filter.FromBlock+"-"+filter.ToBlock)
+		return []map[string]interface{}{{
+			"transactionHash": "0xtx" + filter.FromBlock,
+			"blockNumber":     filter.FromBlock,
+			"blockTimestamp":  "0x0",
+			"data":            "0xf4240", // 1 USDT
+			"topics":          []string{erc20TransferTopic, "0x01", "0x02"},
+		}}
+	})
+
+	transfers, err := client.Transfers("0xwallet", time.Time{}, 100, 2600)
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	want := []string{"0x64-0x44b", "0x44c-0x833", "0x834-0xa28"} // 100-1099, 1100-2099, 2100-2600
+	if strings.Join(ranges, ",") != strings.Join(want, ",") {
+		t.Errorf("分段错误: 期望 %v, 实际 %v", want, ranges)
+	}
+	if len(transfers) != 3 || transfers[0].Amount != 1 || transfers[2].BlockNumber != 2100 {
+		t.Errorf("转账解析错误: %+v", transfers)
+	}
+}
+
+// TestJSONRPCClient_TxReceipt 测试 eth_getTransactionReceipt 解析
+func TestJSONRPCClient_TxReceipt(t *testing.T) {
+	wallet := "0x" + strings.Repeat("ab", 20)
+	client := newTestRPCServer(t, func(method string, params []json.RawMessage) interface{} {
+		var hash string
+		json.Unmarshal(params[0], &hash)
+		switch hash {
+		case "0xpending":
+			return nil
+		case "0xreverted":
+			return map[string]interface{}{"blockNumber": "0x10", "status": "0x0", "logs": []interface{}{}}
+		}
+		return map[string]interface{}{
+			"blockNumber": "0x10",
+			"status":      "0x1",
+			"logs": []map[string]interface{}{
+				{"address": "0xcontract", "data": "0x1", "topics": []string{"0xother", "0x01", "0x02"}},
+				{"address": "0xcontract", "data": "0x1", "topics": []string{erc20TransferTopic, "0x01", "0x02"}, "removed": true},
+				{"address": "0xCONTRACT", "data": "0x1e8480", "topics": []string{erc20TransferTopic, "0x" + strings.Repeat("0", 24) + strings.Repeat("cd", 20), "0x" + strings.Repeat("0", 24) + strings.Repeat("ab", 20)}},
+			},
+		}
+	})
+
+	if r, err := client.TxReceipt("0xpending"); err != nil || r != nil {
+		t.Errorf("未上链的交易应返回 nil: %+v, %v", r, err)
+	}
+	if r, err := client.TxReceipt("0xreverted"); err != nil || r == nil || r.Success {
+		t.Errorf("执行失败的交易应返回 Success=false: %+v, %v", r, err)
+	}
+
+	r, err := client.TxReceipt("0xok")
+	if err != nil || r == nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if !r.Success || r.BlockNumber != 16 || len(r.Transfers) != 1 {
+		t.Fatalf("回执解析错误: %+v", r)
+	}
+	tr := r.Transfers[0]
+	if !sameAddress(tr.Contract, "0xcontract") || !sameAddress(tr.To, wallet) || tr.From != "0x"+strings.Repeat("cd", 20) || tr.Amount != 2 {
+		t.Errorf("转账事件解析错误: %+v", tr)
+	}
+}
+
+// TestTronGridClient_TxReceipt 测试 gettransactioninfobyid 解析
+func TestTronGridClient_TxReceipt(t *testing.T) {
+	const usdtHex = "a614f803b6fd780986a42c78ec9c7f77e6ded13c"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Value string `json:"value"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Value != "tx1" {
+			w.Write([]byte("{}"))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "tx1",
+			"blockNumber": 42,
+			"receipt":     map[string]string{"result": "SUCCESS"},
+			"log": []map[string]interface{}{{
+				"address": usdtHex,
+				"topics":  []string{strings.TrimPrefix(erc20TransferTopic, "0x"), strings.Repeat("0", 24) + usdtHex, strings.Repeat("0", 24) + usdtHex},
+				"data":    strings.Repeat("0", 58) + "0f4240",
+			}},
+		})
+	}))
+	defer srv.Close()
+	client := &tronGridClient{baseURL: srv.URL, decimals: 6, http: srv.Client()}
+
+	if r, err := client.TxReceipt("missing"); err != nil || r != nil {
+		t.Errorf("不存在的交易应返回 nil: %+v, %v", r, err)
+	}
+	r, err := client.TxReceipt("tx1")
+	if err != nil || r == nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if !r.Success || r.BlockNumber != 42 || len(r.Transfers) != 1 {
+		t.Fatalf("回执解析错误: %+v", r)
+	}
+	tr := r.Transfers[0]
+	const usdt = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	if tr.Contract != usdt || tr.From != usdt || tr.To != usdt || tr.Amount != 1 {
+		t.Errorf("转账事件解析错误: %+v", tr)
+	}
+}
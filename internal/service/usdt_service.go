@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -14,11 +15,15 @@ import (
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/model"
+	"user-frontend/internal/trace"
 )
 
 // USDTService USDT支付服务
 type USDTService struct {
-	cfg *config.Config
+	cfg   *config.Config
+	chain *USDTChainService // 自托管链上收款（onchain 模式）
+	requestScope
 }
 
 // NewUSDTService 创建USDT支付服务
@@ -26,18 +31,31 @@ func NewUSDTService(cfg *config.Config) *USDTService {
 	return &USDTService{cfg: cfg}
 }
 
+// WithContext 返回绑定 ctx 的服务副本，网关请求会携带 ctx 中的请求ID
+func (s *USDTService) WithContext(ctx context.Context) *USDTService {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
+// SetChainService 设置链上收款服务
+func (s *USDTService) SetChainService(chain *USDTChainService) {
+	s.chain = chain
+}
+
 // USDTConfig USDT支付配置
 type USDTConfig struct {
 	Enabled       bool    `json:"enabled"`         // 是否启用
 	Network       string  `json:"network"`         // 网络类型：TRC20, ERC20, BEP20
 	WalletAddress string  `json:"wallet_address"`  // 收款钱包地址
-	APIProvider   string  `json:"api_provider"`    // API提供商：nowpayments, coingate, manual
+	APIProvider   string  `json:"api_provider"`    // API提供商：nowpayments, coingate, manual, onchain
 	APIKey        string  `json:"api_key"`         // API密钥
 	APISecret     string  `json:"api_secret"`      // API密钥（部分提供商需要）
 	WebhookSecret string  `json:"webhook_secret"`  // Webhook签名密钥
 	ExchangeRate  float64 `json:"exchange_rate"`   // 汇率（手动模式使用）
 	MinAmount     float64 `json:"min_amount"`      // 最小支付金额（USDT）
 	Confirmations int     `json:"confirmations"`   // 需要的确认数
+	APIBaseURL    string  `json:"api_base_url"`    // 第三方网关API地址（为空使用提供商正式地址）
 }
 
 // USDTPaymentRequest USDT支付请求
@@ -46,6 +64,7 @@ type USDTPaymentRequest struct {
 	Amount      float64 `json:"amount"`       // 原始金额（法币）
 	Currency    string  `json:"currency"`     // 原始货币
 	Description string  `json:"description"`
+	Kind        string  `json:"kind"`         // 业务类型：order/recharge（onchain 模式使用）
 }
 
 // USDTPaymentResponse USDT支付响应
@@ -87,6 +106,8 @@ func (s *USDTService) CreatePayment(req *USDTPaymentRequest) (*USDTPaymentRespon
 		return s.createCoinGatePayment(req, cfg)
 	case "manual":
 		return s.createManualPayment(req, cfg)
+	case "onchain":
+		return s.createOnchainPayment(req, cfg)
 	default:
 		return nil, errors.New("不支持的API提供商")
 	}
@@ -117,7 +138,7 @@ func (s *USDTService) createNowPaymentsPayment(req *USDTPaymentRequest, cfg *USD
 
 	jsonData, _ := json.Marshal(payload)
 
-	httpReq, err := http.NewRequest("POST", "https://api.nowpayments.io/v1/payment", strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(s.requestContext(), "POST", s.apiURL(cfg, "/v1/payment"), strings.NewReader(string(jsonData)))
 	if err != nil {
 		return nil, err
 	}
@@ -125,7 +146,7 @@ func (s *USDTService) createNowPaymentsPayment(req *USDTPaymentRequest, cfg *USD
 	httpReq.Header.Set("x-api-key", cfg.APIKey)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %v", err)
@@ -183,7 +204,7 @@ func (s *USDTService) createCoinGatePayment(req *USDTPaymentRequest, cfg *USDTCo
 
 	jsonData, _ := json.Marshal(payload)
 
-	httpReq, err := http.NewRequest("POST", "https://api.coingate.com/v2/orders", strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(s.requestContext(), "POST", s.apiURL(cfg, "/v2/orders"), strings.NewReader(string(jsonData)))
 	if err != nil {
 		return nil, err
 	}
@@ -191,7 +212,7 @@ func (s *USDTService) createCoinGatePayment(req *USDTPaymentRequest, cfg *USDTCo
 	httpReq.Header.Set("Authorization", "Token "+cfg.APIKey)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %v", err)
@@ -238,9 +259,9 @@ func (s *USDTService) createManualPayment(req *USDTPaymentRequest, cfg *USDTConf
 		return nil, errors.New("收款钱包地址未配置")
 	}
 
-	// 计算USDT金额
+	// 计算USDT金额（订单已锁定USDT币种时直接使用，否则按静态汇率换算）
 	usdtAmount := req.Amount
-	if cfg.ExchangeRate > 0 {
+	if !strings.EqualFold(req.Currency, "USDT") && cfg.ExchangeRate > 0 {
 		usdtAmount = req.Amount / cfg.ExchangeRate
 	}
 
@@ -259,6 +280,39 @@ func (s *USDTService) createManualPayment(req *USDTPaymentRequest, cfg *USDTConf
 	}, nil
 }
 
+// createOnchainPayment 创建自托管链上收款（分配唯一金额，由链上轮询自动确认）
+func (s *USDTService) createOnchainPayment(req *USDTPaymentRequest, cfg *USDTConfig) (*USDTPaymentResponse, error) {
+	if s.chain == nil {
+		return nil, errors.New("链上收款服务未初始化")
+	}
+
+	usdtAmount := req.Amount
+	if !strings.EqualFold(req.Currency, "USDT") && cfg.ExchangeRate > 0 {
+		usdtAmount = req.Amount / cfg.ExchangeRate
+	}
+	if cfg.MinAmount > 0 && usdtAmount < cfg.MinAmount {
+		return nil, fmt.Errorf("支付金额不能小于 %.2f USDT", cfg.MinAmount)
+	}
+
+	kind := req.Kind
+	if kind == "" {
+		kind = model.USDTPaymentKindOrder
+	}
+	payment, err := s.chain.CreatePayment(req.OrderNo, kind, usdtAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &USDTPaymentResponse{
+		PaymentID:     payment.OrderNo,
+		WalletAddress: payment.WalletAddress,
+		Amount:        payment.Amount,
+		Network:       payment.Network,
+		ExpiresAt:     payment.ExpiresAt.Unix(),
+		QRCode:        payment.WalletAddress,
+	}, nil
+}
+
 // GetPaymentStatus 获取支付状态
 func (s *USDTService) GetPaymentStatus(paymentID string) (*USDTPaymentStatus, error) {
 	cfg := s.getUSDTConfig()
@@ -277,6 +331,21 @@ func (s *USDTService) GetPaymentStatus(paymentID string) (*USDTPaymentStatus, er
 			PaymentID: paymentID,
 			Status:    "waiting",
 		}, nil
+	case "onchain":
+		if s.chain == nil {
+			return nil, errors.New("链上收款服务未初始化")
+		}
+		payment, err := s.chain.GetPayment(paymentID)
+		if err != nil {
+			return nil, errors.New("支付记录不存在")
+		}
+		return &USDTPaymentStatus{
+			PaymentID:     payment.OrderNo,
+			Status:        payment.Status,
+			Confirmations: payment.Confirmations,
+			TxHash:        payment.TxHash,
+			AmountPaid:    payment.PaidAmount,
+		}, nil
 	default:
 		return nil, errors.New("不支持的API提供商")
 	}
@@ -284,14 +353,14 @@ func (s *USDTService) GetPaymentStatus(paymentID string) (*USDTPaymentStatus, er
 
 // getNowPaymentsStatus 获取NOWPayments支付状态
 func (s *USDTService) getNowPaymentsStatus(paymentID string, cfg *USDTConfig) (*USDTPaymentStatus, error) {
-	httpReq, err := http.NewRequest("GET", "https://api.nowpayments.io/v1/payment/"+paymentID, nil)
+	httpReq, err := http.NewRequestWithContext(s.requestContext(), "GET", s.apiURL(cfg, "/v1/payment/"+paymentID), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	httpReq.Header.Set("x-api-key", cfg.APIKey)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, err
@@ -338,14 +407,14 @@ func (s *USDTService) getNowPaymentsStatus(paymentID string, cfg *USDTConfig) (*
 
 // getCoinGateStatus 获取CoinGate支付状态
 func (s *USDTService) getCoinGateStatus(paymentID string, cfg *USDTConfig) (*USDTPaymentStatus, error) {
-	httpReq, err := http.NewRequest("GET", "https://api.coingate.com/v2/orders/"+paymentID, nil)
+	httpReq, err := http.NewRequestWithContext(s.requestContext(), "GET", s.apiURL(cfg, "/v2/orders/"+paymentID), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	httpReq.Header.Set("Authorization", "Token "+cfg.APIKey)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := trace.NewHTTPClient(30 * time.Second)
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, err
@@ -483,9 +552,24 @@ func (s *USDTService) getUSDTConfig() *USDTConfig {
 		ExchangeRate:  s.cfg.PaymentConfig.USDTExchangeRate,
 		MinAmount:     s.cfg.PaymentConfig.USDTMinAmount,
 		Confirmations: s.cfg.PaymentConfig.USDTConfirmations,
+		APIBaseURL:    s.cfg.PaymentConfig.USDTAPIBaseURL,
 	}
 }
 
+// apiURL 拼接第三方网关API地址（支持配置自定义地址，用于沙箱模拟网关）
+func (s *USDTService) apiURL(cfg *USDTConfig, path string) string {
+	base := cfg.APIBaseURL
+	if base == "" {
+		switch cfg.APIProvider {
+		case "coingate":
+			base = "https://api.coingate.com"
+		default:
+			base = "https://api.nowpayments.io"
+		}
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
 // IsEnabled 检查USDT支付是否启用
 func (s *USDTService) IsEnabled() bool {
 	cfg := s.getUSDTConfig()
@@ -514,9 +598,9 @@ func (s *USDTService) TestConnection() error {
 
 	switch cfg.APIProvider {
 	case "nowpayments":
-		httpReq, _ := http.NewRequest("GET", "https://api.nowpayments.io/v1/status", nil)
+		httpReq, _ := http.NewRequestWithContext(s.requestContext(), "GET", s.apiURL(cfg, "/v1/status"), nil)
 		httpReq.Header.Set("x-api-key", cfg.APIKey)
-		client := &http.Client{Timeout: 10 * time.Second}
+		client := trace.NewHTTPClient(10 * time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			return fmt.Errorf("连接失败: %v", err)
@@ -528,9 +612,9 @@ func (s *USDTService) TestConnection() error {
 		return nil
 
 	case "coingate":
-		httpReq, _ := http.NewRequest("GET", "https://api.coingate.com/v2/ping", nil)
+		httpReq, _ := http.NewRequestWithContext(s.requestContext(), "GET", s.apiURL(cfg, "/v2/ping"), nil)
 		httpReq.Header.Set("Authorization", "Token "+cfg.APIKey)
-		client := &http.Client{Timeout: 10 * time.Second}
+		client := trace.NewHTTPClient(10 * time.Second)
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			return fmt.Errorf("连接失败: %v", err)
@@ -547,6 +631,18 @@ func (s *USDTService) TestConnection() error {
 		}
 		return nil
 
+	case "onchain":
+		if cfg.WalletAddress == "" {
+			return errors.New("钱包地址未配置")
+		}
+		if s.chain == nil {
+			return errors.New("链上收款服务未初始化")
+		}
+		if _, err := s.chain.TestConnection(); err != nil {
+			return fmt.Errorf("链上接口连接失败: %v", err)
+		}
+		return nil
+
 	default:
 		return errors.New("不支持的API提供商")
 	}
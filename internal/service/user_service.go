@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"user-frontend/internal/model"
@@ -150,6 +151,23 @@ func (s *UserService) UpdateUserStatus(id uint, status int) error {
 	return s.repo.UpdateUser(user)
 }
 
+// UpdateUserLevel 更新用户等级
+func (s *UserService) UpdateUserLevel(id uint, level string) error {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case model.UserLevelRetail, model.UserLevelVIP, model.UserLevelReseller:
+	default:
+		return errors.New("无效的用户等级")
+	}
+
+	user, err := s.repo.GetUserByID(id)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	user.Level = level
+	return s.repo.UpdateUser(user)
+}
+
 // UpdateUser 更新用户信息
 func (s *UserService) UpdateUser(user *model.User) error {
 	return s.repo.UpdateUser(user)
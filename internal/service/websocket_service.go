@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"sync"
 	"time"
@@ -53,6 +54,8 @@ type WSHub struct {
 	unregister chan *WSClient
 	// 广播消息
 	broadcast chan *WSBroadcast
+	// Hub 停止后关闭，此后的注册、注销和广播直接丢弃
+	done chan struct{}
 	// 互斥锁
 	mu sync.RWMutex
 }
@@ -67,14 +70,16 @@ type WSBroadcast struct {
 }
 
 // 全局WebSocket Hub实例
-var wsHub *WSHub
+var (
+	wsHub     *WSHub
+	wsHubOnce sync.Once
+)
 
-// GetWSHub 获取WebSocket Hub实例
+// GetWSHub 获取WebSocket Hub实例（由应用生命周期调用 Run 启动）
 func GetWSHub() *WSHub {
-	if wsHub == nil {
+	wsHubOnce.Do(func() {
 		wsHub = NewWSHub()
-		go wsHub.Run()
-	}
+	})
 	return wsHub
 }
 
@@ -90,13 +95,18 @@ func NewWSHub() *WSHub {
 		register:          make(chan *WSClient),
 		unregister:        make(chan *WSClient),
 		broadcast:         make(chan *WSBroadcast),
+		done:              make(chan struct{}),
 	}
 }
 
-// Run 运行WebSocket Hub
-func (h *WSHub) Run() {
+// Run 运行WebSocket Hub，ctx 取消后通知所有客户端服务重启并断开连接
+func (h *WSHub) Run(ctx context.Context) {
 	for {
 		select {
+		case <-ctx.Done():
+			h.closeAll()
+			close(h.done)
+			return
 		case client := <-h.register:
 			h.registerClient(client)
 		case client := <-h.unregister:
@@ -146,6 +156,26 @@ func (h *WSHub) registerClient(client *WSClient) {
 	}
 }
 
+// closeAll 以“服务重启”（1012）关闭码断开全部客户端，前端收到后自动重连
+func (h *WSHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+	deadline := time.Now().Add(time.Second)
+	for client := range h.clients {
+		// WriteControl 可与写协程并发调用
+		client.Conn.WriteControl(websocket.CloseMessage, closeMsg, deadline)
+		client.Conn.Close()
+	}
+	h.clients = make(map[*WSClient]bool)
+	h.userClients = make(map[uint]*WSClient)
+	h.guestClients = make(map[string]*WSClient)
+	h.staffClients = make(map[uint]*WSClient)
+	h.ticketSubscribers = make(map[uint]map[*WSClient]bool)
+	h.chatSubscribers = make(map[uint]map[*WSClient]bool)
+}
+
 // unregisterClient 注销客户端
 func (h *WSHub) unregisterClient(client *WSClient) {
 	h.mu.Lock()
@@ -363,27 +393,35 @@ func (h *WSHub) SendToStaff(staffID uint, msg *WSMessage) {
 
 // BroadcastToTicket 广播消息到工单
 func (h *WSHub) BroadcastToTicket(ticketID uint, msg *WSMessage, exclude *WSClient) {
-	h.broadcast <- &WSBroadcast{
+	h.send(&WSBroadcast{
 		TicketID: ticketID,
 		Message:  msg,
 		Exclude:  exclude,
-	}
+	})
 }
 
 // BroadcastToChat 广播消息到聊天
 func (h *WSHub) BroadcastToChat(chatID uint, msg *WSMessage, exclude *WSClient) {
-	h.broadcast <- &WSBroadcast{
+	h.send(&WSBroadcast{
 		ChatID:  chatID,
 		Message: msg,
 		Exclude: exclude,
-	}
+	})
 }
 
 // BroadcastToAllStaff 广播消息给所有客服
 func (h *WSHub) BroadcastToAllStaff(msg *WSMessage) {
-	h.broadcast <- &WSBroadcast{
+	h.send(&WSBroadcast{
 		StaffAll: true,
 		Message:  msg,
+	})
+}
+
+// send 投递广播，Hub 停止后丢弃
+func (h *WSHub) send(b *WSBroadcast) {
+	select {
+	case h.broadcast <- b:
+	case <-h.done:
 	}
 }
 
@@ -394,6 +432,18 @@ func (h *WSHub) GetOnlineStaffCount() int {
 	return len(h.staffClients)
 }
 
+// GetConnectionCounts 按用户类型统计当前连接数（user/guest/staff）
+func (h *WSHub) GetConnectionCounts() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := map[string]int{"user": 0, "guest": 0, "staff": 0}
+	for client := range h.clients {
+		counts[client.UserType]++
+	}
+	return counts
+}
+
 // GetOnlineStaffIDs 获取在线客服ID列表
 func (h *WSHub) GetOnlineStaffIDs() []uint {
 	h.mu.RLock()
@@ -408,10 +458,17 @@ func (h *WSHub) GetOnlineStaffIDs() []uint {
 
 // Register 注册客户端（公开方法）
 func (h *WSHub) Register(client *WSClient) {
-	h.register <- client
+	select {
+	case h.register <- client:
+	case <-h.done:
+		client.Conn.Close()
+	}
 }
 
 // Unregister 注销客户端（公开方法）
 func (h *WSHub) Unregister(client *WSClient) {
-	h.unregister <- client
+	select {
+	case h.unregister <- client:
+	case <-h.done:
+	}
 }
@@ -1,23 +1,28 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
 	"user-frontend/internal/config"
+	"user-frontend/internal/trace"
 )
 
 // WechatPayService 微信支付服务
 type WechatPayService struct {
 	config *config.WechatPayConfig
+	requestScope
 }
 
 // NewWechatPayService 创建微信支付服务
@@ -25,6 +30,13 @@ func NewWechatPayService(cfg *config.WechatPayConfig) *WechatPayService {
 	return &WechatPayService{config: cfg}
 }
 
+// WithContext 返回绑定 ctx 的服务副本，网关请求会携带 ctx 中的请求ID
+func (s *WechatPayService) WithContext(ctx context.Context) *WechatPayService {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
 // WechatNotifyResult 微信支付通知结果
 type WechatNotifyResult struct {
 	XMLName       xml.Name `xml:"xml"`
@@ -61,7 +73,7 @@ func (s *WechatPayService) CreateNativeOrder(orderNo string, amount float64, des
 	}
 
 	// 金额转为分
-	totalFee := int(amount * 100)
+	totalFee := int(math.Round(amount * 100))
 
 	// 构建请求参数
 	params := map[string]string{
@@ -76,12 +88,15 @@ func (s *WechatPayService) CreateNativeOrder(orderNo string, amount float64, des
 		"trade_type":       "NATIVE",
 	}
 
-	// 签名
-	params["sign"] = s.sign(params)
+	result, err := s.post("/pay/unifiedorder", params)
+	if err != nil {
+		return "", err
+	}
+	if result["code_url"] == "" {
+		return "", errors.New("微信支付未返回二维码")
+	}
 
-	// 注意：这里只是构建参数，实际需要发送到微信支付接口
-	// 由于没有实际调用，返回占位符
-	return fmt.Sprintf("weixin://wxpay/bizpayurl?orderNo=%s&amount=%.2f", orderNo, amount), nil
+	return result["code_url"], nil
 }
 
 // VerifyNotify 验证微信支付异步通知
@@ -153,9 +168,135 @@ func (s *WechatPayService) QueryOrder(orderNo string) (bool, string, error) {
 		return false, "", errors.New("微信支付未启用")
 	}
 
-	// 注意：这里需要实际调用微信查询接口
-	// 由于没有实际的SDK，返回false
-	return false, "", nil
+	params := map[string]string{
+		"appid":        s.config.AppID,
+		"mch_id":       s.config.MchID,
+		"out_trade_no": orderNo,
+		"nonce_str":    generateNonceStr(),
+	}
+
+	result, err := s.post("/pay/orderquery", params)
+	if err != nil {
+		// 用户尚未扫码时微信返回订单不存在
+		if strings.Contains(err.Error(), "ORDERNOTEXIST") {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+
+	return result["trade_state"] == "SUCCESS", result["transaction_id"], nil
+}
+
+// apiURL 拼接微信支付API地址（支持配置模拟网关）
+func (s *WechatPayService) apiURL(path string) string {
+	base := "https://api.mch.weixin.qq.com"
+	if s.config.APIBaseURL != "" {
+		base = strings.TrimRight(s.config.APIBaseURL, "/")
+	}
+	return base + path
+}
+
+// post 签名并调用微信支付接口（XML），校验返回码与响应签名
+func (s *WechatPayService) post(path string, params map[string]string) (map[string]string, error) {
+	params["sign"] = s.sign(params)
+
+	req, err := http.NewRequestWithContext(s.requestContext(), "POST", s.apiURL(path), bytes.NewReader(EncodeWechatXML(params)))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+
+	client := trace.NewHTTPClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求微信支付失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	result, err := DecodeWechatXML(body)
+	if err != nil {
+		return nil, fmt.Errorf("解析XML失败: %v", err)
+	}
+
+	if result["return_code"] != "SUCCESS" {
+		return nil, errors.New("微信返回失败: " + result["return_msg"])
+	}
+
+	// 验证响应签名
+	signParams := make(map[string]string, len(result))
+	for k, v := range result {
+		if k != "sign" {
+			signParams[k] = v
+		}
+	}
+	if s.sign(signParams) != result["sign"] {
+		return nil, errors.New("响应签名验证失败")
+	}
+
+	if result["result_code"] != "SUCCESS" {
+		return nil, fmt.Errorf("微信支付业务失败: %s %s", result["err_code"], result["err_code_des"])
+	}
+
+	return result, nil
+}
+
+// EncodeWechatXML 将参数编码为微信支付XML格式
+func EncodeWechatXML(params map[string]string) []byte {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("<xml>")
+	for _, k := range keys {
+		buf.WriteString("<" + k + "><![CDATA[")
+		buf.WriteString(strings.ReplaceAll(params[k], "]]>", "]]]]><![CDATA[>"))
+		buf.WriteString("]]></" + k + ">")
+	}
+	buf.WriteString("</xml>")
+	return buf.Bytes()
+}
+
+// DecodeWechatXML 解析微信支付XML为键值对（仅处理一级节点）
+func DecodeWechatXML(data []byte) (map[string]string, error) {
+	result := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	var key string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				key = t.Name.Local
+				result[key] = ""
+			}
+		case xml.CharData:
+			if depth == 2 {
+				result[key] += string(t)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("空的XML数据")
+	}
+	return result, nil
 }
 
 // sign 生成签名
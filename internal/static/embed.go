@@ -25,8 +25,7 @@ func IsEmbedded() bool {
 func SetupStaticRoutes(r *gin.Engine) {
 	r.Static("/static", "./web")
 	r.Static("/_next", "./web/_next")
-	r.Static("/product-files", "./Product")
-	r.Static("/uploads", "./uploads")
+	setupUploadsRoute(r)
 }
 
 // ServeEmbeddedPage 从文件系统服务页面
@@ -44,9 +44,8 @@ func SetupStaticRoutes(r *gin.Engine) {
 		serveEmbeddedFile(c, webFS, path)
 	})
 
-	// 嵌入模式下，product-files 和 uploads 仍从外部加载
-	r.Static("/product-files", "./Product")
-	r.Static("/uploads", "./uploads")
+	// 嵌入模式下，uploads 仍从外部加载（product-files 由 api 包从对象存储提供）
+	setupUploadsRoute(r)
 }
 
 // serveEmbeddedFile 从嵌入的文件系统服务文件
@@ -0,0 +1,35 @@
+// Package static 提供静态资源支持
+// uploads.go - 上传文件目录路由（嵌入与非嵌入模式共用）
+package static
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// privateUploadDirs 上传目录下不允许公开访问的子目录
+// 旧版本的工单附件保存在 uploads/tickets，现仅能通过鉴权接口访问
+var privateUploadDirs = []string{"tickets"}
+
+// setupUploadsRoute 注册 /uploads 静态路由，屏蔽私有子目录且不列出目录
+func setupUploadsRoute(r *gin.Engine) {
+	fileServer := http.StripPrefix("/uploads", http.FileServer(gin.Dir("./uploads", false)))
+
+	handler := func(c *gin.Context) {
+		cleaned := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+		first := strings.SplitN(cleaned, "/", 2)[0]
+		for _, dir := range privateUploadDirs {
+			if strings.EqualFold(first, dir) {
+				c.Status(http.StatusNotFound)
+				return
+			}
+		}
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	}
+
+	r.GET("/uploads/*filepath", handler)
+	r.HEAD("/uploads/*filepath", handler)
+}
@@ -0,0 +1,179 @@
+// Package storage 提供统一的对象存储抽象层
+// local.go - 本地文件系统存储实现
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage 本地文件系统存储
+//
+// 对象键映射为根目录下的相对路径，适用于单机部署。
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage 创建本地存储
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if root == "" {
+		root = DefaultLocalRoot
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// Driver 返回驱动类型
+func (s *LocalStorage) Driver() string {
+	return DriverLocal
+}
+
+// Root 返回根目录
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+// path 将对象键转换为文件路径
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put 写入对象（先写临时文件再重命名，避免读到写了一半的文件）
+func (s *LocalStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Get 读取对象
+func (s *LocalStorage) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		f.Close()
+		return nil, nil, ErrNotFound
+	}
+	return f, localObjectInfo(key, stat), nil
+}
+
+// Stat 获取对象元信息
+func (s *LocalStorage) Stat(key string) (*ObjectInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(filePath)
+	if err != nil || stat.IsDir() {
+		return nil, ErrNotFound
+	}
+	return localObjectInfo(key, stat), nil
+}
+
+// Delete 删除对象
+func (s *LocalStorage) Delete(key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List 列出指定前缀下的所有对象
+func (s *LocalStorage) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		objects = append(objects, *localObjectInfo(key, info))
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Ping 检查根目录是否可写
+func (s *LocalStorage) Ping() error {
+	f, err := os.CreateTemp(s.root, ".upload-ping-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// localObjectInfo 由文件信息构造对象元信息（内容类型按扩展名推断）
+func localObjectInfo(key string, stat fs.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     stat.ModTime(),
+	}
+}
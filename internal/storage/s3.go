@@ -0,0 +1,337 @@
+// Package storage 提供统一的对象存储抽象层
+// s3.go - S3 兼容对象存储实现（AWS S3 / MinIO / 各云厂商兼容接口）
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3MaxBufferedPut 无法预先计算哈希时允许缓冲到内存的最大对象大小
+const s3MaxBufferedPut = 64 * 1024 * 1024
+
+// S3Storage S3 兼容对象存储
+//
+// 使用 Signature V4 签名直接调用 REST 接口，不依赖第三方 SDK。
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	prefix    string
+	client    *http.Client
+}
+
+// NewS3Storage 创建 S3 兼容存储
+func NewS3Storage(cfg *Config) (*S3Storage, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("未配置 S3 服务地址")
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("未配置存储桶")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("未配置访问密钥")
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, errors.New("S3 服务地址格式错误，需以 http:// 或 https:// 开头")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30
+	}
+
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		if _, err := CleanKey(prefix); err != nil {
+			return nil, errors.New("对象键前缀格式错误")
+		}
+		prefix += "/"
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		pathStyle: cfg.PathStyle,
+		prefix:    prefix,
+		client:    &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}, nil
+}
+
+// Driver 返回驱动类型
+func (s *S3Storage) Driver() string {
+	return DriverS3
+}
+
+// objectURL 构造对象（key 为空时为存储桶）的请求地址
+func (s *S3Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	basePath := strings.TrimRight(u.Path, "/")
+	if s.pathStyle {
+		u.Path = basePath + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do 签名并发送请求
+func (s *S3Storage) do(method, key string, query url.Values, body io.Reader, size int64, payloadHash, contentType string) (*http.Response, error) {
+	u := s.objectURL(key, query)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if payloadHash == "" {
+		payloadHash = emptyPayloadHash
+	}
+
+	amzDate := time.Now().UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+		signed = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	}
+
+	sig := &sigV4Request{
+		Method:        method,
+		EscapedPath:   u.EscapedPath(),
+		Query:         query,
+		Host:          u.Host,
+		Header:        req.Header,
+		SignedHeaders: signed,
+		PayloadHash:   payloadHash,
+		AmzDate:       amzDate,
+		Region:        s.region,
+	}
+	req.Header.Set("Authorization", sig.authorization(s.accessKey, s.secretKey))
+
+	return s.client.Do(req)
+}
+
+// s3Error S3 错误响应
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// responseError 将非成功响应转换为错误
+func responseError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e s3Error
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("S3 请求失败（%d %s）: %s", resp.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Errorf("S3 请求失败（%d）", resp.StatusCode)
+}
+
+// Put 上传对象
+//
+// 可定位的读取器（如文件）先计算哈希再流式上传，其余读取器缓冲到内存。
+func (s *S3Storage) Put(key string, r io.Reader, size int64, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	h := sha256.New()
+	if seeker, ok := r.(io.ReadSeeker); ok && size >= 0 {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if _, err := io.CopyN(h, seeker, size); err != nil {
+			return err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		body = io.LimitReader(seeker, size)
+	} else {
+		data, err := io.ReadAll(io.LimitReader(r, s3MaxBufferedPut+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > s3MaxBufferedPut {
+			return errors.New("对象过大")
+		}
+		h.Write(data)
+		body = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	resp, err := s.do(http.MethodPut, s.prefix+key, nil, body, size, hex.EncodeToString(h.Sum(nil)), contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Get 下载对象
+func (s *S3Storage) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(http.MethodGet, s.prefix+key, nil, nil, 0, "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, nil, responseError(resp)
+	}
+	return resp.Body, s3ObjectInfo(key, resp.Header), nil
+}
+
+// Stat 获取对象元信息
+func (s *S3Storage) Stat(key string) (*ObjectInfo, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(http.MethodHead, s.prefix+key, nil, nil, 0, "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return s3ObjectInfo(key, resp.Header), nil
+}
+
+// Delete 删除对象
+func (s *S3Storage) Delete(key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, s.prefix+key, nil, nil, 0, "", "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// listBucketResult ListObjectsV2 响应
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List 列出指定前缀下的所有对象（自动翻页）
+func (s *S3Storage) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		result, err := s.listPage(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:     strings.TrimPrefix(obj.Key, s.prefix),
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// listPage 请求一页对象列表
+func (s *S3Storage) listPage(query url.Values) (*listBucketResult, error) {
+	resp, err := s.do(http.MethodGet, "", query, nil, 0, "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, errors.New("存储桶不存在")
+		}
+		return nil, responseError(resp)
+	}
+	var result listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析对象列表失败: %v", err)
+	}
+	return &result, nil
+}
+
+// Ping 检查存储桶可访问且凭证有效
+func (s *S3Storage) Ping() error {
+	_, err := s.listPage(url.Values{"list-type": {"2"}, "max-keys": {"1"}})
+	return err
+}
+
+// s3ObjectInfo 由响应头构造对象元信息
+func s3ObjectInfo(key string, header http.Header) *ObjectInfo {
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(header.Get("Last-Modified"))
+	return &ObjectInfo{
+		Key:         key,
+		Size:        size,
+		ContentType: header.Get("Content-Type"),
+		ModTime:     modTime,
+	}
+}
@@ -0,0 +1,291 @@
+// Package storage 提供统一的对象存储抽象层
+// s3_standin.go - S3 兼容模拟服务（MinIO 替身，仅用于本地开发与 CI 测试）
+//
+// 实现 S3 客户端用到的最小接口子集（路径风格）：
+// PUT/GET/HEAD/DELETE 对象、ListObjectsV2、HEAD/PUT 存储桶，
+// 并按 Signature V4 校验请求签名与负载哈希。数据保存在本地目录中。
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// standInMaxObjectSize 模拟服务接受的最大对象大小
+const standInMaxObjectSize = 256 * 1024 * 1024
+
+// standInClockSkew 允许的请求时间偏差
+const standInClockSkew = 15 * time.Minute
+
+// S3StandIn S3 兼容模拟服务
+type S3StandIn struct {
+	root      string
+	basePath  string // 挂载路径前缀，如 /dev/s3
+	accessKey string
+	secretKey string
+}
+
+// NewS3StandIn 创建 S3 兼容模拟服务
+func NewS3StandIn(root, basePath, accessKey, secretKey string) (*S3StandIn, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &S3StandIn{
+		root:      root,
+		basePath:  strings.TrimRight(basePath, "/"),
+		accessKey: accessKey,
+		secretKey: secretKey,
+	}, nil
+}
+
+// ServeHTTP 处理 S3 请求
+func (s *S3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, s.basePath)
+	rest = strings.TrimPrefix(rest, "/")
+	bucket, key, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		writeS3Error(w, http.StatusBadRequest, "InvalidBucketName", "未指定存储桶")
+		return
+	}
+	if bucket == "." || bucket == ".." || strings.Contains(bucket, `\`) {
+		writeS3Error(w, http.StatusBadRequest, "InvalidBucketName", "存储桶名称非法")
+		return
+	}
+
+	payload, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	bucketDir := filepath.Join(s.root, bucket)
+	if key == "" {
+		s.serveBucket(w, r, bucket, bucketDir)
+		return
+	}
+
+	if _, err := os.Stat(bucketDir); err != nil {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "存储桶不存在")
+		return
+	}
+	backend := &LocalStorage{root: bucketDir}
+
+	switch r.Method {
+	case http.MethodPut:
+		if err := backend.Put(key, bytes.NewReader(payload), int64(len(payload)), ""); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+			return
+		}
+		sum := sha256.Sum256(payload)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		rc, info, err := backend.Get(key)
+		if err != nil {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "对象不存在")
+			return
+		}
+		defer rc.Close()
+		if info.ContentType != "" {
+			w.Header().Set("Content-Type", info.ContentType)
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			io.Copy(w, rc)
+		}
+	case http.MethodDelete:
+		if err := backend.Delete(key); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "不支持的请求方法")
+	}
+}
+
+// serveBucket 处理存储桶级请求
+func (s *S3StandIn) serveBucket(w http.ResponseWriter, r *http.Request, bucket, bucketDir string) {
+	switch r.Method {
+	case http.MethodPut:
+		if err := os.MkdirAll(bucketDir, 0755); err != nil {
+			writeS3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodHead, http.MethodGet:
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "不支持的请求方法")
+		return
+	}
+
+	if _, err := os.Stat(bucketDir); err != nil {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "存储桶不存在")
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil || maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+
+	objects, err := (&LocalStorage{root: bucketDir}).List(prefix)
+	if err != nil {
+		writeS3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	// 续传令牌即上一页最后一个键
+	if token := query.Get("continuation-token"); token != "" {
+		start := sort.Search(len(objects), func(i int) bool { return objects[i].Key > token })
+		objects = objects[start:]
+	}
+
+	type content struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		Size         int64  `xml:"Size"`
+	}
+	result := struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		Name                  string    `xml:"Name"`
+		Prefix                string    `xml:"Prefix"`
+		KeyCount              int       `xml:"KeyCount"`
+		MaxKeys               int       `xml:"MaxKeys"`
+		IsTruncated           bool      `xml:"IsTruncated"`
+		NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+		Contents              []content `xml:"Contents"`
+	}{Name: bucket, Prefix: prefix, MaxKeys: maxKeys}
+
+	if len(objects) > maxKeys {
+		objects = objects[:maxKeys]
+		result.IsTruncated = true
+		result.NextContinuationToken = objects[len(objects)-1].Key
+	}
+	for _, obj := range objects {
+		result.Contents = append(result.Contents, content{
+			Key:          obj.Key,
+			LastModified: obj.ModTime.UTC().Format(time.RFC3339),
+			Size:         obj.Size,
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(result)
+}
+
+// authenticate 校验 Signature V4 签名与负载哈希，返回请求体
+func (s *S3StandIn) authenticate(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "缺少签名")
+		return nil, false
+	}
+
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[k] = v
+		}
+	}
+	credential := strings.SplitN(fields["Credential"], "/", 5)
+	if len(credential) != 5 || credential[0] != s.accessKey || credential[3] != sigV4Service {
+		writeS3Error(w, http.StatusForbidden, "InvalidAccessKeyId", "访问密钥无效")
+		return nil, false
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	if !containsString(signedHeaders, "host") || !containsString(signedHeaders, "x-amz-date") {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "签名必须包含 host 与 x-amz-date")
+		return nil, false
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	t, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil || credential[1] != amzDate[:8] {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "请求时间无效")
+		return nil, false
+	}
+	if d := time.Since(t); d > standInClockSkew || d < -standInClockSkew {
+		writeS3Error(w, http.StatusForbidden, "RequestTimeTooSkewed", "请求时间与服务器相差过大")
+		return nil, false
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, standInMaxObjectSize+1))
+	if err != nil || len(payload) > standInMaxObjectSize {
+		writeS3Error(w, http.StatusBadRequest, "EntityTooLarge", "对象过大")
+		return nil, false
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != unsignedPayload {
+		sum := sha256.Sum256(payload)
+		if payloadHash != hex.EncodeToString(sum[:]) {
+			writeS3Error(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "请求体哈希不匹配")
+			return nil, false
+		}
+	}
+
+	query, _ := url.ParseQuery(r.URL.RawQuery)
+	req := &sigV4Request{
+		Method:        r.Method,
+		EscapedPath:   r.URL.EscapedPath(),
+		Query:         query,
+		Host:          r.Host,
+		Header:        r.Header,
+		SignedHeaders: signedHeaders,
+		PayloadHash:   payloadHash,
+		AmzDate:       amzDate,
+		Region:        credential[2],
+	}
+	if !hmac.Equal([]byte(req.signature(s.secretKey)), []byte(fields["Signature"])) {
+		writeS3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", "签名不匹配")
+		return nil, false
+	}
+	return payload, true
+}
+
+// writeS3Error 输出 S3 风格的 XML 错误
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
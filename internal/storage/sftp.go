@@ -0,0 +1,765 @@
+// Package storage 提供统一的对象存储抽象层
+// sftp.go - SFTP 存储实现（用于备份文件异地存放）
+//
+// 基于 SSH 的 sftp 子系统实现 SFTP v3 协议中读写文件所需的最小子集，
+// 每次操作单独建立连接，适合备份上传这类低频、大文件的场景。
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DriverSFTP SFTP 存储驱动（仅用于备份异地存放）
+const DriverSFTP = "sftp"
+
+// SFTPConfig SFTP 连接配置
+type SFTPConfig struct {
+	Host               string // 主机地址
+	Port               int    // 端口，默认 22
+	User               string // 用户名
+	Password           string // 密码（与私钥二选一）
+	PrivateKey         string // PEM 格式私钥
+	HostKeyFingerprint string // 主机公钥 SHA256 指纹（如 SHA256:xxxx），为空时不校验
+	Root               string // 远程根目录
+	Timeout            int    // 连接超时（秒）
+}
+
+// SFTPStorage SFTP 存储
+type SFTPStorage struct {
+	cfg  SFTPConfig
+	auth []ssh.AuthMethod
+}
+
+// NewSFTPStorage 创建 SFTP 存储
+func NewSFTPStorage(cfg *SFTPConfig) (*SFTPStorage, error) {
+	if cfg == nil || cfg.Host == "" || cfg.User == "" {
+		return nil, errors.New("SFTP 主机和用户名不能为空")
+	}
+
+	s := &SFTPStorage{cfg: *cfg}
+	if s.cfg.Port == 0 {
+		s.cfg.Port = 22
+	}
+	if s.cfg.Timeout <= 0 {
+		s.cfg.Timeout = 30
+	}
+	if s.cfg.Root == "" {
+		s.cfg.Root = "."
+	}
+
+	if cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("SFTP 私钥无效: %v", err)
+		}
+		s.auth = append(s.auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		s.auth = append(s.auth, ssh.Password(cfg.Password))
+	}
+	if len(s.auth) == 0 {
+		return nil, errors.New("SFTP 需要配置密码或私钥")
+	}
+	return s, nil
+}
+
+// Driver 返回驱动类型
+func (s *SFTPStorage) Driver() string {
+	return DriverSFTP
+}
+
+// HostKeyFingerprint 连接服务器并返回其主机公钥指纹（用于首次配置时确认）
+func (s *SFTPStorage) HostKeyFingerprint() (string, error) {
+	conn, err := s.connect()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.fingerprint, nil
+}
+
+// Put 写入对象（先写 .part 临时文件再重命名，避免留下写了一半的文件）
+func (s *SFTPStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	full, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	conn, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.mkdirAll(path.Dir(full)); err != nil {
+		return err
+	}
+
+	tmp := full + ".part"
+	handle, err := conn.open(tmp, sftpFlagWrite|sftpFlagCreate|sftpFlagTruncate)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, sftpMaxData)
+	var offset uint64
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := conn.write(handle, offset, buf[:n]); err != nil {
+				conn.closeHandle(handle)
+				conn.remove(tmp)
+				return err
+			}
+			offset += uint64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			conn.closeHandle(handle)
+			conn.remove(tmp)
+			return readErr
+		}
+	}
+	if err := conn.closeHandle(handle); err != nil {
+		conn.remove(tmp)
+		return err
+	}
+
+	// SFTP v3 的重命名不覆盖已存在的文件
+	if err := conn.remove(full); err != nil && !errors.Is(err, ErrNotFound) {
+		conn.remove(tmp)
+		return err
+	}
+	return conn.rename(tmp, full)
+}
+
+// Get 读取对象，返回的 ReadCloser 关闭时断开连接
+func (s *SFTPStorage) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	full, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := s.connect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attrs, err := conn.stat(full)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	handle, err := conn.open(full, sftpFlagRead)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	info := &ObjectInfo{Key: key, Size: int64(attrs.size), ModTime: attrs.modTime()}
+	return &sftpFileReader{conn: conn, handle: handle}, info, nil
+}
+
+// Stat 获取对象元信息
+func (s *SFTPStorage) Stat(key string) (*ObjectInfo, error) {
+	full, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	attrs, err := conn.stat(full)
+	if err != nil {
+		return nil, err
+	}
+	if attrs.isDir() {
+		return nil, ErrNotFound
+	}
+	return &ObjectInfo{Key: key, Size: int64(attrs.size), ModTime: attrs.modTime()}, nil
+}
+
+// Delete 删除对象（不存在时不报错）
+func (s *SFTPStorage) Delete(key string) error {
+	full, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	conn, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.remove(full); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// List 列出指定前缀下的所有对象
+func (s *SFTPStorage) List(prefix string) ([]ObjectInfo, error) {
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var objects []ObjectInfo
+	var walk func(dir, keyPrefix string) error
+	walk = func(dir, keyPrefix string) error {
+		entries, err := conn.readDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.name == "." || e.name == ".." {
+				continue
+			}
+			key := keyPrefix + e.name
+			if e.attrs.isDir() {
+				if err := walk(path.Join(dir, e.name), key+"/"); err != nil {
+					return err
+				}
+				continue
+			}
+			if strings.HasPrefix(key, prefix) && !strings.HasSuffix(key, ".part") {
+				objects = append(objects, ObjectInfo{Key: key, Size: int64(e.attrs.size), ModTime: e.attrs.modTime()})
+			}
+		}
+		return nil
+	}
+
+	if err := walk(s.cfg.Root, ""); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Ping 检查服务器可连接且根目录可用（不存在时创建）
+func (s *SFTPStorage) Ping() error {
+	conn, err := s.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.mkdirAll(s.cfg.Root)
+}
+
+// path 将对象键转换为远程路径
+func (s *SFTPStorage) path(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(s.cfg.Root, cleaned), nil
+}
+
+// connect 建立 SSH 连接并启动 sftp 子系统
+func (s *SFTPStorage) connect() (*sftpConn, error) {
+	var fingerprint string
+	clientCfg := &ssh.ClientConfig{
+		User: s.cfg.User,
+		Auth: s.auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint = ssh.FingerprintSHA256(key)
+			if s.cfg.HostKeyFingerprint != "" && fingerprint != s.cfg.HostKeyFingerprint {
+				return fmt.Errorf("主机密钥指纹不匹配: %s", fingerprint)
+			}
+			return nil
+		},
+		Timeout: time.Duration(s.cfg.Timeout) * time.Second,
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port)), clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("SFTP 连接失败: %v", err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		client.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		client.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		client.Close()
+		return nil, fmt.Errorf("服务器未启用 SFTP: %v", err)
+	}
+
+	conn := newSFTPConn(r, w, func() error {
+		session.Close()
+		return client.Close()
+	})
+	conn.fingerprint = fingerprint
+	if err := conn.init(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// sftpFileReader 远程文件顺序读取
+type sftpFileReader struct {
+	conn   *sftpConn
+	handle string
+	offset uint64
+}
+
+func (f *sftpFileReader) Read(p []byte) (int, error) {
+	if len(p) > sftpMaxData {
+		p = p[:sftpMaxData]
+	}
+	data, err := f.conn.read(f.handle, f.offset, uint32(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data)
+	f.offset += uint64(n)
+	return n, nil
+}
+
+func (f *sftpFileReader) Close() error {
+	f.conn.closeHandle(f.handle)
+	return f.conn.Close()
+}
+
+// ==================== SFTP v3 协议 ====================
+
+// 数据包类型
+const (
+	sftpPacketInit    = 1
+	sftpPacketVersion = 2
+	sftpPacketOpen    = 3
+	sftpPacketClose   = 4
+	sftpPacketRead    = 5
+	sftpPacketWrite   = 6
+	sftpPacketOpenDir = 11
+	sftpPacketReadDir = 12
+	sftpPacketRemove  = 13
+	sftpPacketMkdir   = 14
+	sftpPacketStat    = 17
+	sftpPacketRename  = 18
+	sftpPacketStatus  = 101
+	sftpPacketHandle  = 102
+	sftpPacketData    = 103
+	sftpPacketName    = 104
+	sftpPacketAttrs   = 105
+	sftpProtocolVer   = 3
+	sftpMaxData       = 32 * 1024
+	sftpMaxPacket     = 256 * 1024
+	sftpStatusOK      = 0
+	sftpStatusEOF     = 1
+	sftpStatusNoSuch  = 2
+	sftpAttrSize      = 0x00000001
+	sftpAttrUIDGID    = 0x00000002
+	sftpAttrPerms     = 0x00000004
+	sftpAttrACModTime = 0x00000008
+	sftpAttrExtended  = 0x80000000
+	sftpModeTypeMask  = 0170000
+	sftpModeDir       = 0040000
+	sftpFlagRead      = 0x00000001
+	sftpFlagWrite     = 0x00000002
+	sftpFlagCreate    = 0x00000008
+	sftpFlagTruncate  = 0x00000010
+)
+
+// sftpAttrs 文件属性
+type sftpAttrs struct {
+	size  uint64
+	perms uint32
+	mtime uint32
+}
+
+func (a sftpAttrs) isDir() bool {
+	return a.perms&sftpModeTypeMask == sftpModeDir
+}
+
+func (a sftpAttrs) modTime() time.Time {
+	if a.mtime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(a.mtime), 0)
+}
+
+// sftpDirEntry 目录项
+type sftpDirEntry struct {
+	name  string
+	attrs sftpAttrs
+}
+
+// sftpConn SFTP 会话（请求按顺序发送并等待响应）
+type sftpConn struct {
+	r           io.Reader
+	w           io.Writer
+	closer      func() error
+	nextID      uint32
+	fingerprint string
+}
+
+func newSFTPConn(r io.Reader, w io.Writer, closer func() error) *sftpConn {
+	return &sftpConn{r: r, w: w, closer: closer}
+}
+
+// Close 关闭会话
+func (c *sftpConn) Close() error {
+	if c.closer != nil {
+		return c.closer()
+	}
+	return nil
+}
+
+// init 协商协议版本
+func (c *sftpConn) init() error {
+	if err := c.send(sftpPacketInit, binary.BigEndian.AppendUint32(nil, sftpProtocolVer)); err != nil {
+		return err
+	}
+	typ, _, err := c.recv()
+	if err != nil {
+		return err
+	}
+	if typ != sftpPacketVersion {
+		return fmt.Errorf("SFTP 握手失败: 意外的响应类型 %d", typ)
+	}
+	return nil
+}
+
+// send 发送数据包
+func (c *sftpConn) send(typ byte, payload []byte) error {
+	packet := make([]byte, 0, 5+len(payload))
+	packet = binary.BigEndian.AppendUint32(packet, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	packet = append(packet, payload...)
+	_, err := c.w.Write(packet)
+	return err
+}
+
+// recv 读取数据包
+func (c *sftpConn) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("SFTP 数据包长度无效: %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+// request 发送带请求ID的数据包并返回对应响应（已去掉请求ID）
+func (c *sftpConn) request(typ byte, payload []byte) (byte, *sftpBuffer, error) {
+	c.nextID++
+	id := c.nextID
+	if err := c.send(typ, append(binary.BigEndian.AppendUint32(nil, id), payload...)); err != nil {
+		return 0, nil, err
+	}
+	respType, data, err := c.recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	buf := &sftpBuffer{b: data}
+	if respID, err := buf.uint32(); err != nil || respID != id {
+		return 0, nil, errors.New("SFTP 响应与请求不匹配")
+	}
+	return respType, buf, nil
+}
+
+// statusError 将 STATUS 响应转换为错误（成功返回 nil）
+func statusError(buf *sftpBuffer) error {
+	code, err := buf.uint32()
+	if err != nil {
+		return err
+	}
+	msg, _ := buf.string()
+	switch code {
+	case sftpStatusOK:
+		return nil
+	case sftpStatusEOF:
+		return io.EOF
+	case sftpStatusNoSuch:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("SFTP 错误(%d): %s", code, msg)
+	}
+}
+
+// expectStatus 执行只返回 STATUS 的请求
+func (c *sftpConn) expectStatus(typ byte, payload []byte) error {
+	respType, buf, err := c.request(typ, payload)
+	if err != nil {
+		return err
+	}
+	if respType != sftpPacketStatus {
+		return fmt.Errorf("SFTP 意外的响应类型 %d", respType)
+	}
+	return statusError(buf)
+}
+
+// expectHandle 执行返回 HANDLE 的请求
+func (c *sftpConn) expectHandle(typ byte, payload []byte) (string, error) {
+	respType, buf, err := c.request(typ, payload)
+	if err != nil {
+		return "", err
+	}
+	switch respType {
+	case sftpPacketHandle:
+		return buf.string()
+	case sftpPacketStatus:
+		if err := statusError(buf); err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("SFTP 意外的响应类型 %d", respType)
+}
+
+func (c *sftpConn) open(p string, flags uint32) (string, error) {
+	payload := appendSFTPString(nil, p)
+	payload = binary.BigEndian.AppendUint32(payload, flags)
+	payload = binary.BigEndian.AppendUint32(payload, 0) // 空属性
+	return c.expectHandle(sftpPacketOpen, payload)
+}
+
+func (c *sftpConn) closeHandle(handle string) error {
+	return c.expectStatus(sftpPacketClose, appendSFTPString(nil, handle))
+}
+
+func (c *sftpConn) write(handle string, offset uint64, data []byte) error {
+	payload := appendSFTPString(nil, handle)
+	payload = binary.BigEndian.AppendUint64(payload, offset)
+	payload = appendSFTPString(payload, string(data))
+	return c.expectStatus(sftpPacketWrite, payload)
+}
+
+func (c *sftpConn) read(handle string, offset uint64, n uint32) ([]byte, error) {
+	payload := appendSFTPString(nil, handle)
+	payload = binary.BigEndian.AppendUint64(payload, offset)
+	payload = binary.BigEndian.AppendUint32(payload, n)
+	respType, buf, err := c.request(sftpPacketRead, payload)
+	if err != nil {
+		return nil, err
+	}
+	switch respType {
+	case sftpPacketData:
+		data, err := buf.string()
+		return []byte(data), err
+	case sftpPacketStatus:
+		if err := statusError(buf); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("SFTP 意外的响应类型 %d", respType)
+}
+
+func (c *sftpConn) stat(p string) (sftpAttrs, error) {
+	respType, buf, err := c.request(sftpPacketStat, appendSFTPString(nil, p))
+	if err != nil {
+		return sftpAttrs{}, err
+	}
+	switch respType {
+	case sftpPacketAttrs:
+		return buf.attrs()
+	case sftpPacketStatus:
+		if err := statusError(buf); err != nil {
+			return sftpAttrs{}, err
+		}
+	}
+	return sftpAttrs{}, fmt.Errorf("SFTP 意外的响应类型 %d", respType)
+}
+
+func (c *sftpConn) remove(p string) error {
+	return c.expectStatus(sftpPacketRemove, appendSFTPString(nil, p))
+}
+
+func (c *sftpConn) rename(from, to string) error {
+	return c.expectStatus(sftpPacketRename, appendSFTPString(appendSFTPString(nil, from), to))
+}
+
+func (c *sftpConn) mkdir(p string) error {
+	return c.expectStatus(sftpPacketMkdir, binary.BigEndian.AppendUint32(appendSFTPString(nil, p), 0))
+}
+
+// mkdirAll 逐级创建目录
+func (c *sftpConn) mkdirAll(dir string) error {
+	if dir == "" || dir == "." || dir == "/" {
+		return nil
+	}
+	attrs, err := c.stat(dir)
+	if err == nil {
+		if !attrs.isDir() {
+			return fmt.Errorf("%s 不是目录", dir)
+		}
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := c.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	return c.mkdir(dir)
+}
+
+// readDir 读取目录全部条目
+func (c *sftpConn) readDir(dir string) ([]sftpDirEntry, error) {
+	handle, err := c.expectHandle(sftpPacketOpenDir, appendSFTPString(nil, dir))
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+
+	var entries []sftpDirEntry
+	for {
+		respType, buf, err := c.request(sftpPacketReadDir, appendSFTPString(nil, handle))
+		if err != nil {
+			return nil, err
+		}
+		if respType == sftpPacketStatus {
+			if err := statusError(buf); err != nil && err != io.EOF {
+				return nil, err
+			}
+			return entries, nil
+		}
+		if respType != sftpPacketName {
+			return nil, fmt.Errorf("SFTP 意外的响应类型 %d", respType)
+		}
+		count, err := buf.uint32()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			name, err := buf.string()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := buf.string(); err != nil { // longname
+				return nil, err
+			}
+			attrs, err := buf.attrs()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, sftpDirEntry{name: name, attrs: attrs})
+		}
+	}
+}
+
+// appendSFTPString 追加 uint32 长度前缀的字符串
+func appendSFTPString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// sftpBuffer 响应数据解析
+type sftpBuffer struct {
+	b []byte
+}
+
+var errSFTPShortPacket = errors.New("SFTP 数据包不完整")
+
+func (p *sftpBuffer) uint32() (uint32, error) {
+	if len(p.b) < 4 {
+		return 0, errSFTPShortPacket
+	}
+	v := binary.BigEndian.Uint32(p.b)
+	p.b = p.b[4:]
+	return v, nil
+}
+
+func (p *sftpBuffer) uint64() (uint64, error) {
+	if len(p.b) < 8 {
+		return 0, errSFTPShortPacket
+	}
+	v := binary.BigEndian.Uint64(p.b)
+	p.b = p.b[8:]
+	return v, nil
+}
+
+func (p *sftpBuffer) string() (string, error) {
+	n, err := p.uint32()
+	if err != nil {
+		return "", err
+	}
+	if uint32(len(p.b)) < n {
+		return "", errSFTPShortPacket
+	}
+	s := string(p.b[:n])
+	p.b = p.b[n:]
+	return s, nil
+}
+
+func (p *sftpBuffer) attrs() (sftpAttrs, error) {
+	var a sftpAttrs
+	flags, err := p.uint32()
+	if err != nil {
+		return a, err
+	}
+	if flags&sftpAttrSize != 0 {
+		if a.size, err = p.uint64(); err != nil {
+			return a, err
+		}
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		if _, err = p.uint64(); err != nil {
+			return a, err
+		}
+	}
+	if flags&sftpAttrPerms != 0 {
+		if a.perms, err = p.uint32(); err != nil {
+			return a, err
+		}
+	}
+	if flags&sftpAttrACModTime != 0 {
+		if _, err = p.uint32(); err != nil { // atime
+			return a, err
+		}
+		if a.mtime, err = p.uint32(); err != nil {
+			return a, err
+		}
+	}
+	if flags&sftpAttrExtended != 0 {
+		count, err := p.uint32()
+		if err != nil {
+			return a, err
+		}
+		for i := uint32(0); i < count; i++ {
+			if _, err := p.string(); err != nil {
+				return a, err
+			}
+			if _, err := p.string(); err != nil {
+				return a, err
+			}
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,413 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// packet 按 SFTP 格式拼接数据包：uint32 长度 + 类型 + 负载
+func packet(typ byte, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	b = append(b, typ)
+	return append(b, payload...)
+}
+
+// statusPayload 构造 STATUS 响应负载（不含请求ID）
+func statusPayload(code uint32, msg string) []byte {
+	b := binary.BigEndian.AppendUint32(nil, code)
+	b = appendSFTPString(b, msg)
+	return appendSFTPString(b, "") // 语言标签
+}
+
+// fakeSFTPServer 在内存管道上模拟 SFTP 服务端，handler 根据请求返回响应类型和负载（不含请求ID）
+func fakeSFTPServer(t *testing.T, handler func(typ byte, payload *sftpBuffer) (byte, []byte)) *sftpConn {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	server := newSFTPConn(serverR, serverW, nil)
+
+	go func() {
+		defer serverW.Close()
+		for {
+			typ, data, err := server.recv()
+			if err != nil {
+				return
+			}
+			buf := &sftpBuffer{b: data}
+			id, err := buf.uint32()
+			if err != nil {
+				return
+			}
+			respType, resp := handler(typ, buf)
+			if server.send(respType, append(binary.BigEndian.AppendUint32(nil, id), resp...)) != nil {
+				return
+			}
+		}
+	}()
+
+	conn := newSFTPConn(clientR, clientW, func() error {
+		clientW.Close()
+		return clientR.Close()
+	})
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// TestSFTPConn_SendFraming 测试发送数据包的长度前缀和类型
+func TestSFTPConn_SendFraming(t *testing.T) {
+	var out bytes.Buffer
+	conn := newSFTPConn(nil, &out, nil)
+
+	if err := conn.send(sftpPacketInit, binary.BigEndian.AppendUint32(nil, sftpProtocolVer)); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	want := []byte{0, 0, 0, 5, sftpPacketInit, 0, 0, 0, 3}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("INIT 数据包不匹配: 期望 %v, 实际 %v", want, out.Bytes())
+	}
+
+	out.Reset()
+	if err := conn.send(sftpPacketRemove, appendSFTPString(nil, "a/b.txt")); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	want = packet(sftpPacketRemove, append([]byte{0, 0, 0, 7}, "a/b.txt"...))
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("REMOVE 数据包不匹配: 期望 %v, 实际 %v", want, out.Bytes())
+	}
+}
+
+// TestSFTPConn_RecvFraming 测试读取数据包及长度校验
+func TestSFTPConn_RecvFraming(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		wantType byte
+		wantData []byte
+		wantErr  bool
+	}{
+		{"只有类型", packet(sftpPacketVersion, nil), sftpPacketVersion, []byte{}, false},
+		{"带负载", packet(sftpPacketData, []byte("hello")), sftpPacketData, []byte("hello"), false},
+		{"长度为0", []byte{0, 0, 0, 0, sftpPacketStatus}, 0, nil, true},
+		{"超过最大长度", binary.BigEndian.AppendUint32(nil, sftpMaxPacket+1), 0, nil, true},
+		{"头部不完整", []byte{0, 0, 0}, 0, nil, true},
+		{"负载不完整", packet(sftpPacketData, []byte("hello"))[:7], 0, nil, true},
+		{"空输入", nil, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newSFTPConn(bytes.NewReader(tt.input), io.Discard, nil)
+			typ, data, err := conn.recv()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("期望返回错误，实际类型 %d 数据 %v", typ, data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("读取失败: %v", err)
+			}
+			if typ != tt.wantType || !bytes.Equal(data, tt.wantData) {
+				t.Errorf("期望 (%d, %q), 实际 (%d, %q)", tt.wantType, tt.wantData, typ, data)
+			}
+		})
+	}
+}
+
+// TestSFTPConn_RecvConsecutive 测试连续数据包按长度切分
+func TestSFTPConn_RecvConsecutive(t *testing.T) {
+	var input []byte
+	input = append(input, packet(sftpPacketHandle, []byte("h1"))...)
+	input = append(input, packet(sftpPacketData, nil)...)
+	input = append(input, packet(sftpPacketStatus, []byte("xyz"))...)
+	conn := newSFTPConn(bytes.NewReader(input), io.Discard, nil)
+
+	for _, want := range []struct {
+		typ  byte
+		data string
+	}{{sftpPacketHandle, "h1"}, {sftpPacketData, ""}, {sftpPacketStatus, "xyz"}} {
+		typ, data, err := conn.recv()
+		if err != nil {
+			t.Fatalf("读取失败: %v", err)
+		}
+		if typ != want.typ || string(data) != want.data {
+			t.Errorf("期望 (%d, %q), 实际 (%d, %q)", want.typ, want.data, typ, data)
+		}
+	}
+	if _, _, err := conn.recv(); err != io.EOF {
+		t.Errorf("数据读完后应返回 io.EOF，实际 %v", err)
+	}
+}
+
+// TestSFTPConn_RequestIDMismatch 测试响应ID与请求不一致时报错
+func TestSFTPConn_RequestIDMismatch(t *testing.T) {
+	resp := packet(sftpPacketStatus, append(binary.BigEndian.AppendUint32(nil, 99), statusPayload(sftpStatusOK, "")...))
+	conn := newSFTPConn(bytes.NewReader(resp), io.Discard, nil)
+	if err := conn.remove("x"); err == nil || !strings.Contains(err.Error(), "不匹配") {
+		t.Errorf("期望请求ID不匹配错误，实际 %v", err)
+	}
+}
+
+// TestStatusError 测试 STATUS 响应到错误的转换
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    error
+		wantMsg string
+	}{
+		{"成功", statusPayload(sftpStatusOK, ""), nil, ""},
+		{"文件结束", statusPayload(sftpStatusEOF, "End of file"), io.EOF, ""},
+		{"不存在", statusPayload(sftpStatusNoSuch, "No such file"), ErrNotFound, ""},
+		{"权限不足", statusPayload(3, "Permission denied"), nil, "SFTP 错误(3): Permission denied"},
+		{"缺少消息", binary.BigEndian.AppendUint32(nil, 4), nil, "SFTP 错误(4): "},
+		{"缺少状态码", []byte{0, 0}, errSFTPShortPacket, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := statusError(&sftpBuffer{b: tt.payload})
+			if tt.wantMsg != "" {
+				if err == nil || err.Error() != tt.wantMsg {
+					t.Errorf("期望错误 %q, 实际 %v", tt.wantMsg, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("期望 %v, 实际 %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestSFTPConn_StatusHandling 测试各类请求对 STATUS 响应和意外响应类型的处理
+func TestSFTPConn_StatusHandling(t *testing.T) {
+	conn := fakeSFTPServer(t, func(typ byte, payload *sftpBuffer) (byte, []byte) {
+		p, _ := payload.string()
+		switch {
+		case p == "missing":
+			return sftpPacketStatus, statusPayload(sftpStatusNoSuch, "No such file")
+		case p == "denied":
+			return sftpPacketStatus, statusPayload(3, "Permission denied")
+		case p == "eof":
+			return sftpPacketStatus, statusPayload(sftpStatusEOF, "")
+		case p == "ok":
+			return sftpPacketStatus, statusPayload(sftpStatusOK, "")
+		default:
+			return sftpPacketName, nil // 意外的响应类型
+		}
+	})
+
+	if _, err := conn.stat("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stat 不存在的文件应返回 ErrNotFound，实际 %v", err)
+	}
+	if _, err := conn.open("denied", sftpFlagRead); err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("open 应返回服务端错误信息，实际 %v", err)
+	}
+	if _, err := conn.read("eof", 0, 16); err != io.EOF {
+		t.Errorf("read 到文件末尾应返回 io.EOF，实际 %v", err)
+	}
+	if err := conn.remove("ok"); err != nil {
+		t.Errorf("remove 成功不应返回错误，实际 %v", err)
+	}
+	if err := conn.remove("denied"); err == nil {
+		t.Error("remove 失败应返回错误")
+	}
+	// 期望 HANDLE 却收到成功的 STATUS 也是协议错误
+	if _, err := conn.open("ok", sftpFlagRead); err == nil || !strings.Contains(err.Error(), "意外的响应类型") {
+		t.Errorf("open 收到 STATUS OK 应返回意外响应类型错误，实际 %v", err)
+	}
+	if err := conn.remove("other"); err == nil || !strings.Contains(err.Error(), "意外的响应类型") {
+		t.Errorf("remove 收到 NAME 应返回意外响应类型错误，实际 %v", err)
+	}
+	if _, err := conn.stat("other"); err == nil || !strings.Contains(err.Error(), "意外的响应类型") {
+		t.Errorf("stat 收到 NAME 应返回意外响应类型错误，实际 %v", err)
+	}
+}
+
+// TestSFTPConn_Init 测试版本协商
+func TestSFTPConn_Init(t *testing.T) {
+	var out bytes.Buffer
+	conn := newSFTPConn(bytes.NewReader(packet(sftpPacketVersion, binary.BigEndian.AppendUint32(nil, 3))), &out, nil)
+	if err := conn.init(); err != nil {
+		t.Fatalf("握手失败: %v", err)
+	}
+	if want := packet(sftpPacketInit, []byte{0, 0, 0, 3}); !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("INIT 数据包不匹配: 期望 %v, 实际 %v", want, out.Bytes())
+	}
+
+	conn = newSFTPConn(bytes.NewReader(packet(sftpPacketStatus, nil)), io.Discard, nil)
+	if err := conn.init(); err == nil {
+		t.Error("响应类型不是 VERSION 时应返回错误")
+	}
+}
+
+// TestSFTPConn_ReadWrite 测试读写请求的负载编码
+func TestSFTPConn_ReadWrite(t *testing.T) {
+	var gotWrite struct {
+		handle string
+		offset uint64
+		data   string
+	}
+	conn := fakeSFTPServer(t, func(typ byte, p *sftpBuffer) (byte, []byte) {
+		switch typ {
+		case sftpPacketWrite:
+			gotWrite.handle, _ = p.string()
+			gotWrite.offset, _ = p.uint64()
+			gotWrite.data, _ = p.string()
+			return sftpPacketStatus, statusPayload(sftpStatusOK, "")
+		case sftpPacketRead:
+			handle, _ := p.string()
+			offset, _ := p.uint64()
+			n, _ := p.uint32()
+			data := strings.Repeat("x", int(n))
+			if handle != "h" || offset != 10 {
+				data = ""
+			}
+			return sftpPacketData, appendSFTPString(nil, data)
+		}
+		return sftpPacketStatus, statusPayload(4, "unsupported")
+	})
+
+	if err := conn.write("h", 1<<33, []byte("payload")); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if gotWrite.handle != "h" || gotWrite.offset != 1<<33 || gotWrite.data != "payload" {
+		t.Errorf("WRITE 负载不匹配: %+v", gotWrite)
+	}
+
+	data, err := conn.read("h", 10, 5)
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	if string(data) != "xxxxx" {
+		t.Errorf("读取数据不匹配: %q", data)
+	}
+}
+
+// TestSFTPBuffer_Attrs 测试文件属性解析
+func TestSFTPBuffer_Attrs(t *testing.T) {
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, sftpAttrSize|sftpAttrUIDGID|sftpAttrPerms|sftpAttrACModTime|sftpAttrExtended)
+	b = binary.BigEndian.AppendUint64(b, 12345)      // size
+	b = binary.BigEndian.AppendUint64(b, 1000<<32|1) // uid, gid
+	b = binary.BigEndian.AppendUint32(b, 0100644)    // 普通文件
+	b = binary.BigEndian.AppendUint32(b, 1700000000) // atime
+	b = binary.BigEndian.AppendUint32(b, 1700000100) // mtime
+	b = binary.BigEndian.AppendUint32(b, 1)          // 扩展属性数量
+	b = appendSFTPString(b, "key@example.com")
+	b = appendSFTPString(b, "value")
+	b = append(b, 0xAA) // 之后的数据
+
+	buf := &sftpBuffer{b: b}
+	a, err := buf.attrs()
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if a.size != 12345 || a.perms != 0100644 || a.mtime != 1700000100 {
+		t.Errorf("属性不匹配: %+v", a)
+	}
+	if a.isDir() {
+		t.Error("普通文件不应识别为目录")
+	}
+	if !a.modTime().Equal(time.Unix(1700000100, 0)) {
+		t.Errorf("修改时间不匹配: %v", a.modTime())
+	}
+	if !bytes.Equal(buf.b, []byte{0xAA}) {
+		t.Errorf("属性之后的数据应保留，实际 %v", buf.b)
+	}
+
+	// 只有权限
+	dir, err := (&sftpBuffer{b: binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, sftpAttrPerms), 040755)}).attrs()
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !dir.isDir() || dir.size != 0 || !dir.modTime().IsZero() {
+		t.Errorf("目录属性不匹配: %+v", dir)
+	}
+
+	// 任意位置截断都应返回 errSFTPShortPacket
+	for i := 0; i < len(b)-1; i++ {
+		if _, err := (&sftpBuffer{b: b[:i]}).attrs(); err != errSFTPShortPacket {
+			t.Fatalf("截断到 %d 字节应返回 errSFTPShortPacket，实际 %v", i, err)
+		}
+	}
+}
+
+// TestSFTPBuffer_String 测试长度前缀字符串的编解码
+func TestSFTPBuffer_String(t *testing.T) {
+	b := appendSFTPString(nil, "")
+	b = appendSFTPString(b, "中文路径/文件.txt")
+	buf := &sftpBuffer{b: b}
+	for _, want := range []string{"", "中文路径/文件.txt"} {
+		s, err := buf.string()
+		if err != nil || s != want {
+			t.Errorf("期望 %q, 实际 %q (%v)", want, s, err)
+		}
+	}
+	if _, err := buf.string(); err != errSFTPShortPacket {
+		t.Errorf("数据读完应返回 errSFTPShortPacket，实际 %v", err)
+	}
+
+	// 声明长度超过剩余数据
+	if _, err := (&sftpBuffer{b: append([]byte{0, 0, 0, 10}, "short"...)}).string(); err != errSFTPShortPacket {
+		t.Errorf("长度超出应返回 errSFTPShortPacket，实际 %v", err)
+	}
+	if _, err := (&sftpBuffer{b: []byte{1, 2, 3, 4, 5, 6, 7}}).uint64(); err != errSFTPShortPacket {
+		t.Errorf("uint64 不足 8 字节应返回 errSFTPShortPacket，实际 %v", err)
+	}
+}
+
+// TestSFTPConn_ReadDir 测试分批读取目录直到 EOF
+func TestSFTPConn_ReadDir(t *testing.T) {
+	batches := [][]string{{"a.txt", "b.txt"}, {"sub"}}
+	var closed bool
+	conn := fakeSFTPServer(t, func(typ byte, p *sftpBuffer) (byte, []byte) {
+		switch typ {
+		case sftpPacketOpenDir:
+			return sftpPacketHandle, appendSFTPString(nil, "dh")
+		case sftpPacketReadDir:
+			if len(batches) == 0 {
+				return sftpPacketStatus, statusPayload(sftpStatusEOF, "")
+			}
+			names := batches[0]
+			batches = batches[1:]
+			resp := binary.BigEndian.AppendUint32(nil, uint32(len(names)))
+			for _, n := range names {
+				resp = appendSFTPString(resp, n)
+				resp = appendSFTPString(resp, "-rw-r--r-- 1 u g 0 "+n)
+				perms := uint32(0100644)
+				if n == "sub" {
+					perms = 040755
+				}
+				resp = binary.BigEndian.AppendUint32(resp, sftpAttrSize|sftpAttrPerms)
+				resp = binary.BigEndian.AppendUint64(resp, uint64(len(n)))
+				resp = binary.BigEndian.AppendUint32(resp, perms)
+			}
+			return sftpPacketName, resp
+		case sftpPacketClose:
+			closed = true
+			return sftpPacketStatus, statusPayload(sftpStatusOK, "")
+		}
+		return sftpPacketStatus, statusPayload(8, "unsupported")
+	})
+
+	entries, err := conn.readDir("/data")
+	if err != nil {
+		t.Fatalf("读取目录失败: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("期望 3 个条目，实际 %d", len(entries))
+	}
+	if entries[0].name != "a.txt" || entries[0].attrs.size != 5 || entries[2].name != "sub" || !entries[2].attrs.isDir() {
+		t.Errorf("目录条目不匹配: %+v", entries)
+	}
+	if !closed {
+		t.Error("读取完成后应关闭目录句柄")
+	}
+}
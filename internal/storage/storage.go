@@ -0,0 +1,177 @@
+// Package storage 提供统一的对象存储抽象层
+// storage.go - 存储接口定义与全局实例
+//
+// 商品图片、工单附件等文件统一以对象键（如 products/12/a.png）读写，
+// 支持本地文件系统和 S3 兼容存储两种实现。多台应用服务器部署时
+// 需使用 S3 兼容存储，保证任一节点上传的文件在其他节点可读。
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 存储驱动类型
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// DefaultLocalRoot 本地存储默认根目录
+const DefaultLocalRoot = "./storage"
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// ErrInvalidKey 对象键非法
+var ErrInvalidKey = errors.New("非法的对象键")
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// Storage 对象存储接口
+//
+// 对象键使用 / 分隔的相对路径，不允许出现 .. 或以 / 开头。
+type Storage interface {
+	// Driver 返回驱动类型（local/s3）
+	Driver() string
+
+	// Put 写入对象（已存在则覆盖）
+	Put(key string, r io.Reader, size int64, contentType string) error
+
+	// Get 读取对象，调用方负责关闭返回的 ReadCloser
+	Get(key string) (io.ReadCloser, *ObjectInfo, error)
+
+	// Stat 获取对象元信息
+	Stat(key string) (*ObjectInfo, error)
+
+	// Delete 删除对象（不存在时不报错）
+	Delete(key string) error
+
+	// List 列出指定前缀下的所有对象
+	List(prefix string) ([]ObjectInfo, error)
+
+	// Ping 检查存储是否可用
+	Ping() error
+}
+
+// Config 存储配置
+type Config struct {
+	Driver    string // local/s3
+	LocalRoot string // 本地存储根目录
+
+	Endpoint  string // S3 服务地址，如 https://s3.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string // 区域，默认 us-east-1
+	Bucket    string // 存储桶
+	AccessKey string // 访问密钥ID
+	SecretKey string // 访问密钥
+	PathStyle bool   // 使用路径风格地址（MinIO 等自建服务通常需要开启）
+	Prefix    string // 对象键统一前缀（同一存储桶供多个站点使用时区分）
+	Timeout   int    // 请求超时（秒）
+}
+
+// New 根据配置创建存储实例
+func New(cfg *Config) (Storage, error) {
+	if cfg == nil || cfg.Driver == "" || cfg.Driver == DriverLocal {
+		root := DefaultLocalRoot
+		if cfg != nil && cfg.LocalRoot != "" {
+			root = cfg.LocalRoot
+		}
+		return NewLocalStorage(root)
+	}
+	if cfg.Driver == DriverS3 {
+		return NewS3Storage(cfg)
+	}
+	return nil, errors.New("不支持的存储驱动: " + cfg.Driver)
+}
+
+// 全局存储实例
+var (
+	defaultStorage Storage
+	defaultMu      sync.RWMutex
+)
+
+// Init 根据配置初始化全局存储，失败时降级为本地存储
+func Init(cfg *Config) error {
+	s, err := New(cfg)
+	if err != nil {
+		log.Printf("警告: 对象存储初始化失败，使用本地存储: %v", err)
+		local, localErr := NewLocalStorage(DefaultLocalRoot)
+		if localErr != nil {
+			return localErr
+		}
+		SetDefault(local)
+		return err
+	}
+	SetDefault(s)
+	return nil
+}
+
+// SetDefault 设置全局存储实例
+func SetDefault(s Storage) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStorage = s
+}
+
+// Default 获取全局存储实例
+//
+// 未初始化时返回默认根目录的本地存储。
+func Default() Storage {
+	defaultMu.RLock()
+	s := defaultStorage
+	defaultMu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultStorage == nil {
+		defaultStorage, _ = NewLocalStorage(DefaultLocalRoot)
+	}
+	return defaultStorage
+}
+
+// CleanKey 校验并规范化对象键
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// JoinKey 拼接对象键
+func JoinKey(parts ...string) string {
+	return path.Join(parts...)
+}
+
+// Copy 将对象从一个存储复制到另一个存储
+func Copy(dst, src Storage, key string) error {
+	r, info, err := src.Get(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.Put(key, r, info.Size, info.ContentType)
+}
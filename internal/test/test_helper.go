@@ -46,7 +46,7 @@ import (
 	"user-frontend/internal/service"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/sqlite"
+	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -67,30 +67,8 @@ func SetupTestDB(t *testing.T) (*gorm.DB, func()) {
 		t.Fatalf("无法创建测试数据库: %v", err)
 	}
 
-	// 自动迁移表结构
-	err = db.AutoMigrate(
-		&model.User{},
-		&model.Product{},
-		&model.ProductCategory{},
-		&model.Order{},
-		&model.Coupon{},
-		&model.UserCoupon{},
-		&model.Balance{},
-		&model.BalanceLog{},
-		&model.Points{},
-		&model.PointsLog{},
-		&model.CartItem{},
-		&model.Favorite{},
-		&model.SupportTicket{},
-		&model.TicketMessage{},
-		&model.Announcement{},
-		&model.FAQ{},
-		&model.FAQCategory{},
-		&model.UserSession{},
-		&model.AdminSession{},
-		&model.LoginDevice{},
-		// 注意：OperationLog 已改为文件存储，不再使用数据库
-	)
+	// 自动迁移表结构（与正式环境相同的模型列表）
+	err = db.AutoMigrate(model.AllModels()...)
 	if err != nil {
 		t.Fatalf("无法迁移数据库表: %v", err)
 	}
@@ -138,7 +116,7 @@ func SetupTestServices(t *testing.T) (*TestServices, func()) {
 		UserSvc:    service.NewUserService(repo),
 		OrderSvc:   service.NewOrderService(repo, cfg),
 		ProductSvc: service.NewProductService(repo),
-		SessionSvc: service.NewSessionService(db),
+		SessionSvc: service.NewSessionService(repo),
 		BalanceSvc: service.NewBalanceService(repo),
 		CouponSvc:  service.NewCouponService(repo),
 	}
@@ -244,7 +222,7 @@ func CreateTestProduct(t *testing.T, services *TestServices, name string, price
 
 // CreateTestOrder 创建测试订单
 func CreateTestOrder(t *testing.T, services *TestServices, userID uint, productID uint) *model.Order {
-	order, err := services.OrderSvc.CreateOrder(userID, "testuser", productID, "127.0.0.1", false)
+	order, err := services.OrderSvc.CreateOrder(userID, "testuser", productID, "127.0.0.1")
 	if err != nil {
 		t.Fatalf("创建测试订单失败: %v", err)
 	}
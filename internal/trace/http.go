@@ -0,0 +1,56 @@
+// Package trace 提供请求ID与链路追踪
+// http.go - 出站 HTTP 调用的请求ID传播与 Span 记录
+package trace
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Transport 为出站请求附加 X-Request-ID 和 traceparent 头，并记录客户端 Span
+// 请求需通过 http.NewRequestWithContext 携带 context，否则不附加请求ID
+type Transport struct {
+	// Base 实际执行请求的 RoundTripper，为空时使用 http.DefaultTransport
+	Base http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := Start(req.Context(), "HTTP "+req.Method+" "+req.URL.Host, KindClient)
+	// 只记录主机和路径，查询参数中可能包含签名等敏感信息
+	span.SetAttr("http.method", req.Method)
+	span.SetAttr("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+
+	out := req.Clone(ctx)
+	if id := RequestID(ctx); id != "" {
+		out.Header.Set(HeaderRequestID, id)
+	}
+	out.Header.Set(HeaderTraceparent, span.Traceparent())
+
+	resp, err := base.RoundTrip(out)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	span.SetAttr("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.End(fmt.Errorf("HTTP %d", resp.StatusCode))
+	} else {
+		span.End(nil)
+	}
+	return resp, nil
+}
+
+// NewHTTPClient 创建带请求ID传播的 HTTP 客户端
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &Transport{},
+	}
+}
@@ -0,0 +1,252 @@
+// Package trace 提供请求ID与链路追踪
+// otlp.go - OTLP/HTTP（JSON 编码）Span 导出
+//
+// 结束的 Span 进入缓冲队列，由 Run 协程按批次（最多 exportBatchSize 个或每 exportInterval）
+// 发送到采集器的 /v1/traces。队列满时丢弃新 Span，导出失败只记录日志，不影响业务请求。
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	exportQueueSize = 2048
+	exportBatchSize = 256
+	exportInterval  = 5 * time.Second
+)
+
+// Exporter OTLP Span 导出器
+type Exporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	queue       chan *Span
+	dropped     atomic.Int64
+}
+
+var exporter atomic.Pointer[Exporter]
+
+// NewExporter 创建导出器，endpoint 为采集器地址（如 http://localhost:4318），未包含路径时追加 /v1/traces
+func NewExporter(endpoint, serviceName string) *Exporter {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	return &Exporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		// 导出请求不经过 Transport，避免为导出本身再生成 Span
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan *Span, exportQueueSize),
+	}
+}
+
+// SetExporter 设置全局导出器，传入 nil 关闭导出
+func SetExporter(e *Exporter) {
+	exporter.Store(e)
+}
+
+// currentExporter 获取全局导出器
+func currentExporter() *Exporter {
+	return exporter.Load()
+}
+
+// enqueue 将结束的 Span 放入队列
+func (e *Exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Run 批量导出 Span 直到 ctx 取消，取消后导出队列中剩余的 Span
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			log.Printf("[Trace] 导出 %d 个 Span 失败: %v", len(batch), err)
+		}
+		batch = batch[:0]
+		if n := e.dropped.Swap(0); n > 0 {
+			log.Printf("[Trace] 导出队列已满，丢弃 %d 个 Span", n)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case s := <-e.queue:
+					batch = append(batch, s)
+					if len(batch) >= exportBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// export 发送一批 Span
+func (e *Exporter) export(spans []*Span) error {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		otlpSpans = append(otlpSpans, toOTLP(s))
+	}
+	body, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: []otlpKeyValue{stringAttr("service.name", e.serviceName)}},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "user-frontend/internal/trace"},
+				Spans: otlpSpans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("采集器返回 HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ==================== OTLP JSON 结构 ====================
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1 成功 2 失败
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// toOTLP 转换为 OTLP JSON 结构
+func toOTLP(s *Span) otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := otlpSpan{
+		TraceID:           s.TraceID,
+		SpanID:            s.SpanID,
+		ParentSpanID:      s.ParentID,
+		Name:              s.Name,
+		Kind:              int(s.Kind),
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+		Status:            otlpStatus{Code: 1},
+	}
+	if s.RequestID != "" {
+		out.Attributes = append(out.Attributes, stringAttr("request.id", s.RequestID))
+	}
+	for key, value := range s.attrs {
+		out.Attributes = append(out.Attributes, toAttr(key, value))
+	}
+	if s.errMsg != "" {
+		out.Status = otlpStatus{Code: 2, Message: s.errMsg}
+	}
+	return out
+}
+
+// toAttr 按取值类型转换属性
+func toAttr(key string, value interface{}) otlpKeyValue {
+	switch v := value.(type) {
+	case string:
+		return stringAttr(key, v)
+	case bool:
+		return otlpKeyValue{Key: key, Value: otlpValue{BoolValue: &v}}
+	case int:
+		s := strconv.Itoa(v)
+		return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &s}}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &s}}
+	case uint:
+		s := strconv.FormatUint(uint64(v), 10)
+		return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &s}}
+	case float64:
+		return otlpKeyValue{Key: key, Value: otlpValue{DoubleValue: &v}}
+	default:
+		return stringAttr(key, fmt.Sprint(v))
+	}
+}
+
+func stringAttr(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: &value}}
+}
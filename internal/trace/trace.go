@@ -0,0 +1,214 @@
+// Package trace 提供请求ID与链路追踪
+// trace.go - 请求ID、Span 与上下文传递
+//
+// 每个 HTTP 请求分配一个请求ID（优先使用入站的 X-Request-ID），随 context 传入服务层、
+// 数据库查询、支付网关 HTTP 调用和操作日志，日志中的 request_id 可串起一次请求的全部记录。
+// Span 记录各环节的耗时和结果，配置了 OTLP 采集器地址时批量导出（见 otlp.go），
+// 未配置时 Span 只用于生成 traceparent 等传播头，不做额外处理。
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind Span 类型（取值与 OTLP 一致）
+type SpanKind int
+
+const (
+	// KindInternal 内部处理
+	KindInternal SpanKind = 1
+	// KindServer 入站请求
+	KindServer SpanKind = 2
+	// KindClient 出站调用（数据库、支付网关）
+	KindClient SpanKind = 3
+)
+
+// HeaderRequestID 请求ID的 HTTP 头
+const HeaderRequestID = "X-Request-ID"
+
+// HeaderTraceparent W3C Trace Context 传播头
+const HeaderTraceparent = "traceparent"
+
+// maxRequestIDLen 入站请求ID的最大长度，超出或含非法字符时重新生成
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+type spanKey struct{}
+
+// Span 一段处理过程
+type Span struct {
+	TraceID   string
+	SpanID    string
+	ParentID  string
+	RequestID string
+	Name      string
+	Kind      SpanKind
+	StartTime time.Time
+	EndTime   time.Time
+
+	mu     sync.Mutex
+	attrs  map[string]interface{}
+	errMsg string
+	ended  bool
+	remote bool // 来自入站 traceparent 的父 Span，仅用于继承 ID
+}
+
+// NewRequestID 生成请求ID（32位十六进制，可直接作为 Trace ID）
+func NewRequestID() string {
+	return randomHex(16)
+}
+
+// ValidRequestID 判断入站请求ID是否可用（长度受限，仅允许字母、数字和 -_.:）
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID 将请求ID写入 context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 从 context 读取请求ID，不存在时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// SpanFromContext 从 context 读取当前 Span
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceID 从 context 读取 Trace ID，不存在时返回空字符串
+func TraceID(ctx context.Context) string {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.TraceID
+	}
+	return ""
+}
+
+// WithRemoteParent 以入站 traceparent 作为父 Span，后续 Start 的 Span 继承其 Trace ID
+func WithRemoteParent(ctx context.Context, traceparent string) context.Context {
+	traceID, spanID, ok := parseTraceparent(traceparent)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, &Span{TraceID: traceID, SpanID: spanID, remote: true})
+}
+
+// Start 开始一个 Span，父 Span 取自 ctx；没有父 Span 时以请求ID（符合格式时）或随机值作为 Trace ID
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{
+		SpanID:    randomHex(8),
+		RequestID: RequestID(ctx),
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else if isHexID(span.RequestID, 32) {
+		span.TraceID = strings.ToLower(span.RequestID)
+	} else {
+		span.TraceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttr 设置 Span 属性
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = make(map[string]interface{})
+	}
+	s.attrs[key] = value
+}
+
+// End 结束 Span，err 不为空时标记为失败；重复调用无效
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended || s.remote {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	if err != nil {
+		s.errMsg = err.Error()
+	}
+	s.mu.Unlock()
+
+	if exp := currentExporter(); exp != nil {
+		exp.enqueue(s)
+	}
+}
+
+// Traceparent 生成 W3C traceparent 头
+func (s *Span) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+// parseTraceparent 解析 traceparent（版本-TraceID-ParentID-标志）
+func parseTraceparent(h string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) != 4 || !isHexID(parts[1], 32) || !isHexID(parts[2], 16) {
+		return "", "", false
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false
+	}
+	return strings.ToLower(parts[1]), strings.ToLower(parts[2]), true
+}
+
+// isHexID 判断是否为指定长度的十六进制字符串
+func isHexID(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// randomHex 生成 n 字节随机数的十六进制表示
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
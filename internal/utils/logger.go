@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"user-frontend/internal/trace"
 )
 
 // ==================== 日志级别定义 ====================
@@ -66,6 +69,22 @@ func (l LogLevel) ColorString() string {
 	}
 }
 
+// ParseLogLevel 解析日志级别字符串（debug/info/warn/error），无法识别时返回 LogLevelInfo
+func ParseLogLevel(level string) LogLevel {
+	switch strings.ToLower(level) {
+	case "debug":
+		return LogLevelDebug
+	case "warn", "warning":
+		return LogLevelWarn
+	case "error":
+		return LogLevelError
+	case "fatal":
+		return LogLevelFatal
+	default:
+		return LogLevelInfo
+	}
+}
+
 // ==================== 日志条目结构 ====================
 
 // LogEntry 日志条目
@@ -132,8 +151,9 @@ func GetLogger() *Logger {
 	return defaultLogger
 }
 
-// SetLogger 设置全局日志实例
+// SetLogger 设置全局日志实例（需在首次 GetLogger 前后均可调用，不会被默认实例覆盖）
 func SetLogger(logger *Logger) {
+	loggerOnce.Do(func() {})
 	defaultLogger = logger
 }
 
@@ -448,6 +468,18 @@ func WithFields(fields map[string]interface{}) *LoggerWithFields {
 	return GetLogger().WithFields(fields)
 }
 
+// WithContext 创建带请求ID和 Trace ID 的日志记录器，用于将日志关联到所属请求
+func WithContext(ctx context.Context) *LoggerWithFields {
+	fields := make(map[string]interface{})
+	if id := trace.RequestID(ctx); id != "" {
+		fields["request_id"] = id
+	}
+	if id := trace.TraceID(ctx); id != "" {
+		fields["trace_id"] = id
+	}
+	return GetLogger().WithFields(fields)
+}
+
 // ==================== 请求日志中间件辅助 ====================
 
 // RequestLogFields 请求日志字段
@@ -459,6 +491,8 @@ type RequestLogFields struct {
 	StatusCode int           `json:"status_code"`
 	Latency    time.Duration `json:"latency"`
 	UserID     uint          `json:"user_id,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+	TraceID    string        `json:"trace_id,omitempty"`
 }
 
 // ToMap 转换为map
@@ -474,6 +508,12 @@ func (r *RequestLogFields) ToMap() map[string]interface{} {
 	if r.UserID > 0 {
 		m["user_id"] = r.UserID
 	}
+	if r.RequestID != "" {
+		m["request_id"] = r.RequestID
+	}
+	if r.TraceID != "" {
+		m["trace_id"] = r.TraceID
+	}
 	return m
 }
 
@@ -0,0 +1,97 @@
+// Package webp 提供 WebP 图片编码
+// boolcoder.go - VP8 布尔算术编码器（RFC 6386 第 7 章）
+package webp
+
+// uniformProb 表示 0/1 概率各 50%
+const uniformProb = 128
+
+// boolEncoder 布尔算术编码器
+type boolEncoder struct {
+	buf      []byte
+	rng      uint32 // 128 <= rng <= 255
+	bottom   uint32
+	bitCount int
+}
+
+// newBoolEncoder 创建布尔算术编码器
+func newBoolEncoder() *boolEncoder {
+	return &boolEncoder{rng: 255, bitCount: 24}
+}
+
+// addOne 向已输出的字节传递进位
+func (e *boolEncoder) addOne() {
+	i := len(e.buf) - 1
+	for i >= 0 && e.buf[i] == 255 {
+		e.buf[i] = 0
+		i--
+	}
+	if i >= 0 {
+		e.buf[i]++
+	}
+}
+
+// putBit 按概率 prob（值为 0 的概率，单位 1/256）编码一位
+func (e *boolEncoder) putBit(bit bool, prob uint8) {
+	split := 1 + ((e.rng-1)*uint32(prob))>>8
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			e.addOne()
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// putUint 以均匀概率编码 n 位无符号整数（高位在前）
+func (e *boolEncoder) putUint(v uint32, n uint) {
+	for n > 0 {
+		n--
+		e.putBit(v>>n&1 != 0, uniformProb)
+	}
+}
+
+// putOptionalInt 编码可选的有符号整数，为 0 时只写一位标记
+func (e *boolEncoder) putOptionalInt(v int32, n uint) {
+	if v == 0 {
+		e.putBit(false, uniformProb)
+		return
+	}
+	e.putBit(true, uniformProb)
+	if v < 0 {
+		e.putUint(uint32(-v), n)
+		e.putBit(true, uniformProb)
+	} else {
+		e.putUint(uint32(v), n)
+		e.putBit(false, uniformProb)
+	}
+}
+
+// finish 输出剩余数据并返回编码结果
+func (e *boolEncoder) finish() []byte {
+	c := e.bitCount
+	v := e.bottom
+	if v&(1<<(32-uint(c))) != 0 {
+		e.addOne()
+	}
+	v <<= uint(c) & 7
+	for c >>= 3; c > 0; c-- {
+		v <<= 8
+	}
+	for i := 0; i < 4; i++ {
+		e.buf = append(e.buf, byte(v>>24))
+		v <<= 8
+	}
+	return e.buf
+}
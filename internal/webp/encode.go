@@ -0,0 +1,806 @@
+// Package webp 提供 WebP 图片编码
+// encode.go - WebP 有损编码（VP8 关键帧）
+//
+// 标准库与 golang.org/x/image 只提供 WebP 解码，这里实现一个精简的 VP8 编码器：
+// 仅使用 16x16 帧内预测（DC/TM/VE/HE）、默认系数概率和单个数据分区，
+// 重建过程与解码器逐位一致，环路滤波在解码端作为后处理执行。
+// 透明通道不写入，透明像素按白色背景合成，需要保留透明度的图片应使用 PNG。
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+)
+
+// 编码参数
+const (
+	// DefaultQuality 默认编码质量
+	DefaultQuality = 80
+	// MaxDimension VP8 支持的最大宽高
+	MaxDimension = 16383
+)
+
+// ErrInvalidSize 图片尺寸超出 VP8 支持范围
+var ErrInvalidSize = errors.New("webp: 图片尺寸无效")
+
+// Options 编码选项
+type Options struct {
+	// Quality 编码质量（1-100），越大画质越好、文件越大
+	Quality int
+}
+
+// 预测模式（与解码器的枚举值一致）
+const (
+	predDC = iota
+	predTM
+	predVE
+	predHE
+)
+
+// quantMatrix 一类系数的量化步长与舍入偏置（偏置以 1/256 为单位）
+type quantMatrix struct {
+	q    [2]int32 // 直流、交流量化步长
+	bias [2]int32
+}
+
+// quantize 量化一个系数，返回量化级别
+func (m *quantMatrix) quantize(c int32, ac int) int32 {
+	q := m.q[ac]
+	sign := int32(1)
+	if c < 0 {
+		sign, c = -1, -c
+	}
+	level := (c + q*m.bias[ac]>>8) / q
+	// 级别上限 2048，同时保证反量化结果不超出 int16
+	if max := 32767 / q; level > max {
+		level = max
+	}
+	if level > 2048 {
+		level = 2048
+	}
+	return sign * level
+}
+
+// nzContext 宏块的非零系数上下文（左侧或上方）
+type nzContext struct {
+	y  [4]uint8
+	uv [4]uint8 // 0-1 为 U，2-3 为 V
+	y2 uint8
+}
+
+// mbInfo 宏块模式信息，写入第一分区
+type mbInfo struct {
+	yMode  uint8
+	uvMode uint8
+	skip   bool
+}
+
+// encoder VP8 编码器状态
+type encoder struct {
+	width, height int
+	mbw, mbh      int
+	yStride       int
+	cStride       int
+
+	// 源图像平面（已按宏块对齐补边）
+	y, u, v []uint8
+	// 重建图像平面，与解码结果一致
+	ry, ru, rv []uint8
+
+	qIndex int
+	y1     quantMatrix
+	y2     quantMatrix
+	uv     quantMatrix
+
+	tokens *boolEncoder
+	mbs    []mbInfo
+	upNz   []nzContext
+	leftNz nzContext
+}
+
+// Encode 将图片编码为有损 WebP 写入 w，o 为 nil 时使用默认质量
+func Encode(w io.Writer, m image.Image, o *Options) error {
+	b := m.Bounds()
+	if b.Dx() <= 0 || b.Dy() <= 0 || b.Dx() > MaxDimension || b.Dy() > MaxDimension {
+		return ErrInvalidSize
+	}
+	quality := DefaultQuality
+	if o != nil && o.Quality > 0 {
+		quality = o.Quality
+	}
+	if quality > 100 {
+		quality = 100
+	}
+
+	e := newEncoder(b.Dx(), b.Dy(), quality)
+	e.importPixels(m)
+	frame := e.encodeFrame()
+
+	var header [20]byte
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+len(frame)+len(frame)&1))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8 ")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(frame)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if len(frame)&1 == 1 {
+		frame = append(frame, 0)
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// newEncoder 创建编码器并计算量化参数
+func newEncoder(width, height, quality int) *encoder {
+	e := &encoder{
+		width:  width,
+		height: height,
+		mbw:    (width + 15) >> 4,
+		mbh:    (height + 15) >> 4,
+	}
+	e.yStride = e.mbw * 16
+	e.cStride = e.mbw * 8
+	e.y = make([]uint8, e.yStride*e.mbh*16)
+	e.u = make([]uint8, e.cStride*e.mbh*8)
+	e.v = make([]uint8, e.cStride*e.mbh*8)
+	e.ry = make([]uint8, len(e.y))
+	e.ru = make([]uint8, len(e.u))
+	e.rv = make([]uint8, len(e.v))
+
+	e.qIndex = (100 - quality) * 127 / 99
+	if e.qIndex > 127 {
+		e.qIndex = 127
+	}
+	q := e.qIndex
+	uvDC := q
+	if uvDC > 117 {
+		uvDC = 117
+	}
+	y2AC := int32(dequantTableAC[q]) * 155 / 100
+	if y2AC < 8 {
+		y2AC = 8
+	}
+	e.y1 = quantMatrix{q: [2]int32{int32(dequantTableDC[q]), int32(dequantTableAC[q])}, bias: [2]int32{96, 110}}
+	e.y2 = quantMatrix{q: [2]int32{int32(dequantTableDC[q]) * 2, y2AC}, bias: [2]int32{96, 108}}
+	e.uv = quantMatrix{q: [2]int32{int32(dequantTableDC[uvDC]), int32(dequantTableAC[q])}, bias: [2]int32{110, 115}}
+	return e
+}
+
+// importPixels 将图片转换为 YUV 4:2:0（BT.601 有限范围），超出部分复制边缘像素
+func (e *encoder) importPixels(m image.Image) {
+	b := m.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), m, b.Min, draw.Over)
+
+	rgb := func(x, y int) (int, int, int) {
+		if x >= e.width {
+			x = e.width - 1
+		}
+		if y >= e.height {
+			y = e.height - 1
+		}
+		i := y*src.Stride + x*4
+		return int(src.Pix[i]), int(src.Pix[i+1]), int(src.Pix[i+2])
+	}
+
+	for py := 0; py < e.mbh*16; py++ {
+		for px := 0; px < e.yStride; px++ {
+			r, g, bb := rgb(px, py)
+			e.y[py*e.yStride+px] = uint8((16839*r + 33059*g + 6420*bb + 1<<15 + 16<<16) >> 16)
+		}
+	}
+	for py := 0; py < e.mbh*8; py++ {
+		for px := 0; px < e.cStride; px++ {
+			var r, g, bb int
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				r1, g1, b1 := rgb(2*px+d[0], 2*py+d[1])
+				r, g, bb = r+r1, g+g1, bb+b1
+			}
+			e.u[py*e.cStride+px] = clipUV(-9719*r - 19081*g + 28800*bb)
+			e.v[py*e.cStride+px] = clipUV(28800*r - 24116*g - 4684*bb)
+		}
+	}
+}
+
+// clipUV 将 4 像素累加的色度值换算到 0-255
+func clipUV(v int) uint8 {
+	v = (v + 1<<17 + 128<<18) >> 18
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// encodeFrame 编码所有宏块并返回 VP8 帧数据
+func (e *encoder) encodeFrame() []byte {
+	e.tokens = newBoolEncoder()
+	e.mbs = make([]mbInfo, 0, e.mbw*e.mbh)
+	e.upNz = make([]nzContext, e.mbw)
+	skipped := 0
+	for mby := 0; mby < e.mbh; mby++ {
+		e.leftNz = nzContext{}
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			info := e.encodeMacroblock(mbx, mby)
+			if info.skip {
+				skipped++
+			}
+			e.mbs = append(e.mbs, info)
+		}
+	}
+
+	// 跳过标志概率为“不跳过”的比例
+	total := len(e.mbs)
+	skipProb := (total - skipped) * 255 / total
+	if skipProb < 1 {
+		skipProb = 1
+	}
+	if skipProb > 255 {
+		skipProb = 255
+	}
+
+	fp := newBoolEncoder()
+	fp.putBit(false, uniformProb) // 色彩空间
+	fp.putBit(false, uniformProb) // 像素钳位
+	fp.putBit(false, uniformProb) // 不使用分段
+	// 环路滤波：普通滤波，强度随量化步长增加
+	filterLevel := e.qIndex * 2 / 5
+	if filterLevel > 63 {
+		filterLevel = 63
+	}
+	fp.putBit(false, uniformProb)
+	fp.putUint(uint32(filterLevel), 6)
+	fp.putUint(0, 3)
+	fp.putBit(false, uniformProb)
+	fp.putUint(0, 2) // 单个系数分区
+	fp.putUint(uint32(e.qIndex), 7)
+	for i := 0; i < 5; i++ {
+		fp.putOptionalInt(0, 4)
+	}
+	fp.putBit(false, uniformProb) // refresh_entropy_probs
+	for i := range tokenProbUpdateProb {
+		for j := range tokenProbUpdateProb[i] {
+			for k := range tokenProbUpdateProb[i][j] {
+				for l := range tokenProbUpdateProb[i][j][k] {
+					fp.putBit(false, tokenProbUpdateProb[i][j][k][l])
+				}
+			}
+		}
+	}
+	fp.putBit(true, uniformProb)
+	fp.putUint(uint32(skipProb), 8)
+
+	for _, info := range e.mbs {
+		fp.putBit(info.skip, uint8(skipProb))
+		fp.putBit(true, 145) // 16x16 预测
+		switch info.yMode {
+		case predDC:
+			fp.putBit(false, 156)
+			fp.putBit(false, 163)
+		case predVE:
+			fp.putBit(false, 156)
+			fp.putBit(true, 163)
+		case predHE:
+			fp.putBit(true, 156)
+			fp.putBit(false, 128)
+		case predTM:
+			fp.putBit(true, 156)
+			fp.putBit(true, 128)
+		}
+		switch info.uvMode {
+		case predDC:
+			fp.putBit(false, 142)
+		case predVE:
+			fp.putBit(true, 142)
+			fp.putBit(false, 114)
+		case predHE:
+			fp.putBit(true, 142)
+			fp.putBit(true, 114)
+			fp.putBit(false, 183)
+		case predTM:
+			fp.putBit(true, 142)
+			fp.putBit(true, 114)
+			fp.putBit(true, 183)
+		}
+	}
+
+	first := fp.finish()
+	tokens := e.tokens.finish()
+
+	frame := make([]byte, 0, 10+len(first)+len(tokens))
+	tag := uint32(len(first))<<5 | 1<<4 // 关键帧、版本 0、显示
+	frame = append(frame, byte(tag), byte(tag>>8), byte(tag>>16))
+	frame = append(frame, 0x9d, 0x01, 0x2a)
+	frame = append(frame, byte(e.width), byte(e.width>>8), byte(e.height), byte(e.height>>8))
+	frame = append(frame, first...)
+	return append(frame, tokens...)
+}
+
+// edges 宏块预测所需的上方、左侧和左上角像素
+type edges struct {
+	top     []uint8
+	left    []uint8
+	topLeft uint8
+}
+
+// loadEdges 按解码器规则读取重建平面中的相邻像素，图像边界外使用固定值
+func loadEdges(plane []uint8, stride, size, mbx, mby int) edges {
+	ed := edges{top: make([]uint8, size), left: make([]uint8, size)}
+	x0, y0 := mbx*size, mby*size
+	for i := 0; i < size; i++ {
+		if mby == 0 {
+			ed.top[i] = 0x7f
+		} else {
+			ed.top[i] = plane[(y0-1)*stride+x0+i]
+		}
+		if mbx == 0 {
+			ed.left[i] = 0x81
+		} else {
+			ed.left[i] = plane[(y0+i)*stride+x0-1]
+		}
+	}
+	switch {
+	case mby == 0:
+		ed.topLeft = 0x7f
+	case mbx == 0:
+		ed.topLeft = 0x81
+	default:
+		ed.topLeft = plane[(y0-1)*stride+x0-1]
+	}
+	return ed
+}
+
+// predict 生成 size x size 的预测块
+func predict(dst []uint8, mode uint8, ed edges, size, mbx, mby int) {
+	switch mode {
+	case predDC:
+		var avg uint8
+		switch {
+		case mbx == 0 && mby == 0:
+			avg = 0x80
+		case mbx == 0:
+			avg = uint8((sumBytes(ed.top) + size/2) / size)
+		case mby == 0:
+			avg = uint8((sumBytes(ed.left) + size/2) / size)
+		default:
+			avg = uint8((sumBytes(ed.top) + sumBytes(ed.left) + size) / (2 * size))
+		}
+		for i := range dst[:size*size] {
+			dst[i] = avg
+		}
+	case predTM:
+		for j := 0; j < size; j++ {
+			for i := 0; i < size; i++ {
+				dst[j*size+i] = clip8(int32(ed.left[j]) + int32(ed.top[i]) - int32(ed.topLeft))
+			}
+		}
+	case predVE:
+		for j := 0; j < size; j++ {
+			copy(dst[j*size:j*size+size], ed.top)
+		}
+	case predHE:
+		for j := 0; j < size; j++ {
+			for i := 0; i < size; i++ {
+				dst[j*size+i] = ed.left[j]
+			}
+		}
+	}
+}
+
+// sumBytes 求和
+func sumBytes(b []uint8) int {
+	sum := 0
+	for _, v := range b {
+		sum += int(v)
+	}
+	return sum
+}
+
+// sse 计算源块与预测块的误差平方和
+func sse(src []uint8, stride, x0, y0 int, pred []uint8, size int) int {
+	sum := 0
+	for j := 0; j < size; j++ {
+		row := src[(y0+j)*stride+x0:]
+		for i := 0; i < size; i++ {
+			d := int(row[i]) - int(pred[j*size+i])
+			sum += d * d
+		}
+	}
+	return sum
+}
+
+// encodeMacroblock 编码一个宏块：选择预测模式、变换量化、写入系数并更新重建平面
+func (e *encoder) encodeMacroblock(mbx, mby int) mbInfo {
+	var info mbInfo
+
+	// 亮度：选择误差最小的 16x16 预测模式
+	yEdges := loadEdges(e.ry, e.yStride, 16, mbx, mby)
+	var yPred, candidate [256]uint8
+	best := -1
+	for mode := uint8(predDC); mode <= predHE; mode++ {
+		predict(candidate[:], mode, yEdges, 16, mbx, mby)
+		if s := sse(e.y, e.yStride, mbx*16, mby*16, candidate[:], 16); best < 0 || s < best {
+			best, info.yMode, yPred = s, mode, candidate
+		}
+	}
+
+	// 色度：U、V 共用一个 8x8 预测模式
+	uEdges := loadEdges(e.ru, e.cStride, 8, mbx, mby)
+	vEdges := loadEdges(e.rv, e.cStride, 8, mbx, mby)
+	var uPred, vPred, uCand, vCand [64]uint8
+	best = -1
+	for mode := uint8(predDC); mode <= predHE; mode++ {
+		predict(uCand[:], mode, uEdges, 8, mbx, mby)
+		predict(vCand[:], mode, vEdges, 8, mbx, mby)
+		s := sse(e.u, e.cStride, mbx*8, mby*8, uCand[:], 8) + sse(e.v, e.cStride, mbx*8, mby*8, vCand[:], 8)
+		if best < 0 || s < best {
+			best, info.uvMode, uPred, vPred = s, mode, uCand, vCand
+		}
+	}
+
+	// 亮度变换与量化：各 4x4 块的直流系数再经 WHT 变换
+	var yLevels [16][16]int32
+	var dc [16]int32
+	for n := 0; n < 16; n++ {
+		x, y := (n&3)*4, (n>>2)*4
+		var coeffs [16]int32
+		fdct4(e.y[(mby*16+y)*e.yStride+mbx*16+x:], e.yStride, yPred[y*16+x:], 16, &coeffs)
+		dc[n] = coeffs[0]
+		for i := 1; i < 16; i++ {
+			yLevels[n][i] = e.y1.quantize(coeffs[i], 1)
+		}
+	}
+	var whtCoeffs, y2Levels [16]int32
+	fwht(&dc, &whtCoeffs)
+	for i := range whtCoeffs {
+		y2Levels[i] = e.y2.quantize(whtCoeffs[i], btoi(i > 0))
+	}
+
+	// 色度变换与量化：U 的 4 个块在前，V 在后
+	var uvLevels [8][16]int32
+	for n := 0; n < 8; n++ {
+		src, pred := e.u, uPred[:]
+		if n >= 4 {
+			src, pred = e.v, vPred[:]
+		}
+		x, y := (n&1)*4, (n>>1&1)*4
+		var coeffs [16]int32
+		fdct4(src[(mby*8+y)*e.cStride+mbx*8+x:], e.cStride, pred[y*8+x:], 8, &coeffs)
+		for i := 0; i < 16; i++ {
+			uvLevels[n][i] = e.uv.quantize(coeffs[i], btoi(i > 0))
+		}
+	}
+
+	info.skip = allZero(y2Levels[:]) && allZero(uvLevels[0][:], uvLevels[1][:], uvLevels[2][:], uvLevels[3][:],
+		uvLevels[4][:], uvLevels[5][:], uvLevels[6][:], uvLevels[7][:])
+	for n := 0; n < 16 && info.skip; n++ {
+		info.skip = allZero(yLevels[n][:])
+	}
+
+	if info.skip {
+		e.leftNz = nzContext{}
+		e.upNz[mbx] = nzContext{}
+	} else {
+		e.writeTokens(mbx, &y2Levels, &yLevels, &uvLevels)
+	}
+
+	e.reconstruct(mbx, mby, &yPred, &uPred, &vPred, &y2Levels, &yLevels, &uvLevels)
+	return info
+}
+
+// writeTokens 按解码顺序写入宏块的全部系数并更新非零上下文
+func (e *encoder) writeTokens(mbx int, y2Levels *[16]int32, yLevels *[16][16]int32, uvLevels *[8][16]int32) {
+	up := &e.upNz[mbx]
+	left := &e.leftNz
+
+	nz := e.putCoeffs(planeY2, left.y2+up.y2, y2Levels[:], 0)
+	left.y2, up.y2 = nz, nz
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			nz := e.putCoeffs(planeY1WithY2, left.y[y]+up.y[x], yLevels[y*4+x][:], 1)
+			left.y[y], up.y[x] = nz, nz
+		}
+	}
+
+	for c := 0; c < 4; c += 2 {
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 2; x++ {
+				nz := e.putCoeffs(planeUV, left.uv[y+c]+up.uv[x+c], uvLevels[c*2+y*2+x][:], 0)
+				left.uv[y+c], up.uv[x+c] = nz, nz
+			}
+		}
+	}
+}
+
+// putCoeffs 写入一个 4x4 块的量化系数（光栅顺序），返回是否含非零系数
+func (e *encoder) putCoeffs(plane int, ctx uint8, levels []int32, first int) uint8 {
+	last := -1
+	for n := 15; n >= first; n-- {
+		if levels[zigzag[n]] != 0 {
+			last = n
+			break
+		}
+	}
+
+	bw := e.tokens
+	probs := &defaultTokenProb[plane]
+	p := &probs[bands[first]][ctx]
+	if last < 0 {
+		bw.putBit(false, p[0])
+		return 0
+	}
+	bw.putBit(true, p[0])
+
+	for n := first; n < 16; {
+		level := levels[zigzag[n]]
+		n++
+		if level == 0 {
+			bw.putBit(false, p[1])
+			p = &probs[bands[n]][0]
+			continue
+		}
+		bw.putBit(true, p[1])
+
+		v := level
+		if v < 0 {
+			v = -v
+		}
+		if v == 1 {
+			bw.putBit(false, p[2])
+			p = &probs[bands[n]][1]
+		} else {
+			bw.putBit(true, p[2])
+			switch {
+			case v <= 4:
+				bw.putBit(false, p[3])
+				if v == 2 {
+					bw.putBit(false, p[4])
+				} else {
+					bw.putBit(true, p[4])
+					bw.putBit(v == 4, p[5])
+				}
+			case v <= 10:
+				bw.putBit(true, p[3])
+				bw.putBit(false, p[6])
+				if v <= 6 {
+					bw.putBit(false, p[7])
+					bw.putBit(v == 6, 159)
+				} else {
+					bw.putBit(true, p[7])
+					bw.putBit((v-7)&2 != 0, 165)
+					bw.putBit((v-7)&1 != 0, 145)
+				}
+			default:
+				bw.putBit(true, p[3])
+				bw.putBit(true, p[6])
+				cat := 3
+				switch {
+				case v < 19:
+					cat = 0
+				case v < 35:
+					cat = 1
+				case v < 67:
+					cat = 2
+				}
+				bw.putBit(cat >= 2, p[8])
+				bw.putBit(cat&1 != 0, p[9+cat>>1])
+				tab := &cat3456[cat]
+				nBits := 0
+				for tab[nBits] != 0 {
+					nBits++
+				}
+				extra := v - 3 - 8<<uint(cat)
+				for i := 0; i < nBits; i++ {
+					bw.putBit(extra>>uint(nBits-1-i)&1 != 0, tab[i])
+				}
+			}
+			p = &probs[bands[n]][2]
+		}
+		bw.putBit(level < 0, uniformProb)
+
+		if n == 16 {
+			break
+		}
+		if n > last {
+			bw.putBit(false, p[0])
+			break
+		}
+		bw.putBit(true, p[0])
+	}
+	return 1
+}
+
+// reconstruct 按解码器的方式反量化、反变换，得到重建像素
+func (e *encoder) reconstruct(mbx, mby int, yPred *[256]uint8, uPred, vPred *[64]uint8, y2Levels *[16]int32, yLevels *[16][16]int32, uvLevels *[8][16]int32) {
+	// 亮度
+	var wht [16]int16
+	var dc [16]int16
+	for i, level := range y2Levels {
+		wht[i] = int16(level * e.y2.q[btoi(i > 0)])
+	}
+	inverseWHT(&wht, &dc)
+	for j := 0; j < 16; j++ {
+		copy(e.ry[(mby*16+j)*e.yStride+mbx*16:], yPred[j*16:j*16+16])
+	}
+	for n := 0; n < 16; n++ {
+		var coeffs [16]int16
+		coeffs[0] = dc[n]
+		for i := 1; i < 16; i++ {
+			coeffs[i] = int16(yLevels[n][i] * e.y1.q[1])
+		}
+		x, y := (n&3)*4, (n>>2)*4
+		inverseDCT4(e.ry[(mby*16+y)*e.yStride+mbx*16+x:], e.yStride, &coeffs)
+	}
+
+	// 色度
+	for j := 0; j < 8; j++ {
+		copy(e.ru[(mby*8+j)*e.cStride+mbx*8:], uPred[j*8:j*8+8])
+		copy(e.rv[(mby*8+j)*e.cStride+mbx*8:], vPred[j*8:j*8+8])
+	}
+	for n := 0; n < 8; n++ {
+		plane := e.ru
+		if n >= 4 {
+			plane = e.rv
+		}
+		var coeffs [16]int16
+		for i := 0; i < 16; i++ {
+			coeffs[i] = int16(uvLevels[n][i] * e.uv.q[btoi(i > 0)])
+		}
+		x, y := (n&1)*4, (n>>1&1)*4
+		inverseDCT4(plane[(mby*8+y)*e.cStride+mbx*8+x:], e.cStride, &coeffs)
+	}
+}
+
+// fdct4 计算 4x4 残差块的正向 DCT，输出按光栅顺序排列
+func fdct4(src []uint8, srcStride int, pred []uint8, predStride int, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		s := src[i*srcStride:]
+		p := pred[i*predStride:]
+		d0 := int32(s[0]) - int32(p[0])
+		d1 := int32(s[1]) - int32(p[1])
+		d2 := int32(s[2]) - int32(p[2])
+		d3 := int32(s[3]) - int32(p[3])
+		a0 := d0 + d3
+		a1 := d1 + d2
+		a2 := d1 - d2
+		a3 := d0 - d3
+		tmp[0+i*4] = (a0 + a1) * 8
+		tmp[1+i*4] = (a2*2217 + a3*5352 + 1812) >> 9
+		tmp[2+i*4] = (a0 - a1) * 8
+		tmp[3+i*4] = (a3*2217 - a2*5352 + 937) >> 9
+	}
+	for i := 0; i < 4; i++ {
+		a0 := tmp[0+i] + tmp[12+i]
+		a1 := tmp[4+i] + tmp[8+i]
+		a2 := tmp[4+i] - tmp[8+i]
+		a3 := tmp[0+i] - tmp[12+i]
+		out[0+i] = (a0 + a1 + 7) >> 4
+		out[4+i] = (a2*2217+a3*5352+12000)>>16 + int32(btoi(a3 != 0))
+		out[8+i] = (a0 - a1 + 7) >> 4
+		out[12+i] = (a3*2217 - a2*5352 + 51000) >> 16
+	}
+}
+
+// fwht 计算 16 个直流系数的正向 Walsh-Hadamard 变换
+func fwht(in, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := in[i*4+0] + in[i*4+2]
+		a1 := in[i*4+1] + in[i*4+3]
+		a2 := in[i*4+1] - in[i*4+3]
+		a3 := in[i*4+0] - in[i*4+2]
+		tmp[0+i*4] = a0 + a1
+		tmp[1+i*4] = a3 + a2
+		tmp[2+i*4] = a3 - a2
+		tmp[3+i*4] = a0 - a1
+	}
+	for i := 0; i < 4; i++ {
+		a0 := tmp[0+i] + tmp[8+i]
+		a1 := tmp[4+i] + tmp[12+i]
+		a2 := tmp[4+i] - tmp[12+i]
+		a3 := tmp[0+i] - tmp[8+i]
+		out[0+i] = (a0 + a1) >> 1
+		out[4+i] = (a3 + a2) >> 1
+		out[8+i] = (a3 - a2) >> 1
+		out[12+i] = (a0 - a1) >> 1
+	}
+}
+
+// inverseWHT 反向 Walsh-Hadamard 变换（与解码器一致），输出各块的直流系数
+func inverseWHT(in, out *[16]int16) {
+	var m [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := int32(in[0+i]) + int32(in[12+i])
+		a1 := int32(in[4+i]) + int32(in[8+i])
+		a2 := int32(in[4+i]) - int32(in[8+i])
+		a3 := int32(in[0+i]) - int32(in[12+i])
+		m[0+i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[0+i*4] + 3
+		a0 := dc + m[3+i*4]
+		a1 := m[1+i*4] + m[2+i*4]
+		a2 := m[1+i*4] - m[2+i*4]
+		a3 := dc - m[3+i*4]
+		out[i*4+0] = int16((a0 + a1) >> 3)
+		out[i*4+1] = int16((a3 + a2) >> 3)
+		out[i*4+2] = int16((a0 - a1) >> 3)
+		out[i*4+3] = int16((a3 - a2) >> 3)
+	}
+}
+
+// inverseDCT4 反向 DCT（与解码器一致），将残差叠加到 dst 中的预测值上
+func inverseDCT4(dst []uint8, stride int, coeffs *[16]int16) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := int32(coeffs[0+i]) + int32(coeffs[8+i])
+		b := int32(coeffs[0+i]) - int32(coeffs[8+i])
+		c := (int32(coeffs[4+i])*c2)>>16 - (int32(coeffs[12+i])*c1)>>16
+		d := (int32(coeffs[4+i])*c1)>>16 + (int32(coeffs[12+i])*c2)>>16
+		m[i][0] = a + d
+		m[i][1] = b + c
+		m[i][2] = b - c
+		m[i][3] = a - d
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		row := dst[j*stride:]
+		row[0] = clip8(int32(row[0]) + (a+d)>>3)
+		row[1] = clip8(int32(row[1]) + (b+c)>>3)
+		row[2] = clip8(int32(row[2]) + (b-c)>>3)
+		row[3] = clip8(int32(row[3]) + (a-d)>>3)
+	}
+}
+
+// allZero 判断所有系数是否为 0
+func allZero(blocks ...[]int32) bool {
+	for _, block := range blocks {
+		for _, v := range block {
+			if v != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// clip8 截断到 0-255
+func clip8(v int32) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// btoi 布尔值转 0/1
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
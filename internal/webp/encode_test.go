@@ -0,0 +1,271 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+// gradient 生成横向、纵向和对角渐变的测试图片
+func gradient(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(x * 255 / max(w-1, 1)),
+				G: uint8(y * 255 / max(h-1, 1)),
+				B: uint8((x + y) * 255 / max(w+h-2, 1)),
+				A: 255,
+			})
+		}
+	}
+	return m
+}
+
+// roundTrip 编码后用 golang.org/x/image/webp 解码
+func roundTrip(t *testing.T, m image.Image, quality int) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &Options{Quality: quality}); err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if buf.Len()%2 != 0 {
+		t.Errorf("RIFF 数据长度应为偶数，实际 %d", buf.Len())
+	}
+	out, err := xwebp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if out.Bounds().Dx() != m.Bounds().Dx() || out.Bounds().Dy() != m.Bounds().Dy() {
+		t.Fatalf("尺寸不一致: 期望 %v, 实际 %v", m.Bounds().Size(), out.Bounds().Size())
+	}
+	return out
+}
+
+// planes 按 BT.601 有限范围计算参考 YUV 4:2:0 平面，源图按白色背景合成，
+// 色度取 2x2 像素均值，超出图片的部分复制边缘像素
+func planes(m image.Image) (y, u, v []float64) {
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+	cw, ch := (w+1)/2, (h+1)/2
+	rgb := func(x, y int) [3]float64 {
+		c := overWhite(m.At(b.Min.X+min(x, w-1), b.Min.Y+min(y, h-1)))
+		return [3]float64{float64(c[0]), float64(c[1]), float64(c[2])}
+	}
+	y = make([]float64, w*h)
+	for py := 0; py < h; py++ {
+		for px := 0; px < w; px++ {
+			c := rgb(px, py)
+			y[py*w+px] = 16 + 0.257*c[0] + 0.504*c[1] + 0.098*c[2]
+		}
+	}
+	u = make([]float64, cw*ch)
+	v = make([]float64, cw*ch)
+	for py := 0; py < ch; py++ {
+		for px := 0; px < cw; px++ {
+			var c [3]float64
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				p := rgb(2*px+d[0], 2*py+d[1])
+				c[0], c[1], c[2] = c[0]+p[0]/4, c[1]+p[1]/4, c[2]+p[2]/4
+			}
+			u[py*cw+px] = 128 - 0.148*c[0] - 0.291*c[1] + 0.439*c[2]
+			v[py*cw+px] = 128 + 0.439*c[0] - 0.368*c[1] - 0.071*c[2]
+		}
+	}
+	return y, u, v
+}
+
+// psnr 分别计算解码结果 Y、U、V 平面与参考平面的峰值信噪比（dB）
+//
+// 解码器返回 BT.601 有限范围的 YCbCr（与编码一致），直接比较平面可以把编码误差与
+// 色度下采样本身的损失区分开
+func psnr(t *testing.T, want, got image.Image) [3]float64 {
+	t.Helper()
+	ycc, ok := got.(*image.YCbCr)
+	if !ok {
+		t.Fatalf("解码结果应为 *image.YCbCr，实际 %T", got)
+	}
+	if ycc.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+		t.Fatalf("解码结果应为 4:2:0，实际 %v", ycc.SubsampleRatio)
+	}
+	b := ycc.Bounds()
+	w, h := b.Dx(), b.Dy()
+	cw, ch := (w+1)/2, (h+1)/2
+	ry, ru, rv := planes(want)
+
+	var out [3]float64
+	for i, p := range []struct {
+		ref    []float64
+		dec    []uint8
+		stride int
+		w, h   int
+	}{
+		{ry, ycc.Y, ycc.YStride, w, h},
+		{ru, ycc.Cb, ycc.CStride, cw, ch},
+		{rv, ycc.Cr, ycc.CStride, cw, ch},
+	} {
+		var sum float64
+		for y := 0; y < p.h; y++ {
+			for x := 0; x < p.w; x++ {
+				d := p.ref[y*p.w+x] - float64(p.dec[y*p.stride+x])
+				sum += d * d
+			}
+		}
+		out[i] = math.Inf(1)
+		if sum > 0 {
+			out[i] = 10 * math.Log10(255*255/(sum/float64(p.w*p.h)))
+		}
+	}
+	return out
+}
+
+// checkPSNR 检查各平面的峰值信噪比不低于 minPSNR
+func checkPSNR(t *testing.T, want, got image.Image, minPSNR float64) {
+	t.Helper()
+	for i, p := range psnr(t, want, got) {
+		if p < minPSNR {
+			t.Errorf("%s 平面 PSNR 过低: 期望 >= %.1f dB, 实际 %.2f dB", "YUV"[i:i+1], minPSNR, p)
+		}
+	}
+}
+
+// decodedRGB 按 BT.601 有限范围将解码结果的像素转换为 RGB
+// （image.YCbCr.At 按 JFIF 全范围换算，不能直接使用）
+func decodedRGB(m *image.YCbCr, x, y int) [3]uint32 {
+	yy := 1.164 * (float64(m.Y[m.YOffset(x, y)]) - 16)
+	cb := float64(m.Cb[m.COffset(x, y)]) - 128
+	cr := float64(m.Cr[m.COffset(x, y)]) - 128
+	return [3]uint32{
+		clamp8(yy + 1.596*cr),
+		clamp8(yy - 0.392*cb - 0.813*cr),
+		clamp8(yy + 2.017*cb),
+	}
+}
+
+// clamp8 四舍五入并限制到 0-255
+func clamp8(v float64) uint32 {
+	return uint32(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// overWhite 将颜色按白色背景合成，返回 8 位 RGB
+func overWhite(c color.Color) [3]uint32 {
+	r, g, b, a := c.RGBA()
+	bg := 0xffff - a
+	return [3]uint32{(r + bg) >> 8, (g + bg) >> 8, (b + bg) >> 8}
+}
+
+// TestEncode_RoundTrip 测试不同尺寸（含非 16 倍数和奇数宽高）的编解码
+func TestEncode_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		quality       int
+		minPSNR       float64
+	}{
+		{"单像素", 1, 1, DefaultQuality, 35},
+		{"奇数宽高", 17, 9, DefaultQuality, 35},
+		{"奇数宽", 33, 32, DefaultQuality, 35},
+		{"奇数高", 48, 31, DefaultQuality, 35},
+		{"宏块对齐", 64, 64, DefaultQuality, 35},
+		{"窄长条", 3, 101, DefaultQuality, 35},
+		{"扁长条", 101, 3, DefaultQuality, 35},
+		{"低质量", 65, 47, 10, 25},
+		{"最高质量", 65, 47, 100, 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := gradient(tt.width, tt.height)
+			out := roundTrip(t, src, tt.quality)
+			checkPSNR(t, src, out, tt.minPSNR)
+		})
+	}
+}
+
+// TestEncode_UniformColor 测试纯色图片解码后颜色基本不变
+func TestEncode_UniformColor(t *testing.T) {
+	colors := []color.NRGBA{
+		{0, 0, 0, 255},
+		{255, 255, 255, 255},
+		{200, 30, 60, 255},
+		{20, 120, 240, 255},
+	}
+	for _, c := range colors {
+		m := image.NewNRGBA(image.Rect(0, 0, 23, 19))
+		for i := 0; i < len(m.Pix); i += 4 {
+			copy(m.Pix[i:i+4], []uint8{c.R, c.G, c.B, c.A})
+		}
+		out := roundTrip(t, m, DefaultQuality)
+		checkPSNR(t, m, out, 40)
+	}
+}
+
+// TestEncode_Alpha 测试透明通道按白色背景合成
+func TestEncode_Alpha(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 31, 17))
+	for y := 0; y < 17; y++ {
+		for x := 0; x < 31; x++ {
+			switch {
+			case x < 10:
+				m.SetNRGBA(x, y, color.NRGBA{255, 0, 0, 0}) // 完全透明
+			case x < 20:
+				m.SetNRGBA(x, y, color.NRGBA{0, 0, 255, 128}) // 半透明
+			default:
+				m.SetNRGBA(x, y, color.NRGBA{0, 160, 0, 255}) // 不透明
+			}
+		}
+	}
+
+	out := roundTrip(t, m, DefaultQuality)
+
+	// 解码结果不带透明通道
+	if !out.(interface{ Opaque() bool }).Opaque() {
+		t.Error("解码结果应为不透明")
+	}
+	checkPSNR(t, m, out, 35)
+
+	// 透明区域应接近白色，而不是丢弃 alpha 后的红色；半透明区域为蓝白混合
+	ycc := out.(*image.YCbCr)
+	if c := decodedRGB(ycc, 4, 8); c[0] < 240 || c[1] < 240 || c[2] < 240 {
+		t.Errorf("透明像素应合成为白色，实际 %v", c)
+	}
+	if c := decodedRGB(ycc, 14, 8); c[0] < 110 || c[0] > 145 || c[2] < 240 {
+		t.Errorf("半透明像素应合成为浅蓝色，实际 %v", c)
+	}
+}
+
+// TestEncode_InvalidSize 测试尺寸校验
+func TestEncode_InvalidSize(t *testing.T) {
+	tests := []struct {
+		name string
+		rect image.Rectangle
+	}{
+		{"空图片", image.Rect(0, 0, 0, 0)},
+		{"宽度为0", image.Rect(0, 0, 0, 10)},
+		{"超出最大宽度", image.Rect(0, 0, MaxDimension+1, 1)},
+		{"超出最大高度", image.Rect(0, 0, 1, MaxDimension+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, image.NewNRGBA(tt.rect), nil); err != ErrInvalidSize {
+				t.Errorf("期望 ErrInvalidSize, 实际 %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("尺寸无效时不应写出数据")
+			}
+		})
+	}
+}
+
+// TestEncode_NonZeroOrigin 测试起点不为 (0,0) 的子图
+func TestEncode_NonZeroOrigin(t *testing.T) {
+	full := gradient(40, 40)
+	sub := full.SubImage(image.Rect(5, 7, 26, 30))
+	out := roundTrip(t, sub, DefaultQuality)
+	checkPSNR(t, sub, out, 35)
+}
@@ -1,6 +1,6 @@
 'use client'
 
-import { useState, useEffect, useMemo } from 'react'
+import { useState, useEffect } from 'react'
 import { motion } from 'framer-motion'
 import toast from 'react-hot-toast'
 import { Navbar, Footer } from '@/components/layout'
@@ -23,6 +23,9 @@ interface Product {
   image_url: string
 }
 
+// 每页加载的商品数量
+const PAGE_SIZE = 24
+
 /**
  * 商品列表页面
  */
@@ -39,43 +42,49 @@ export default function ProductsPage() {
   // 搜索相关状态
   const [searchQuery, setSearchQuery] = useState('')
   const [sortBy, setSortBy] = useState<'default' | 'price_asc' | 'price_desc'>('default')
-  
+  const [total, setTotal] = useState(0)
+  const [nextCursor, setNextCursor] = useState('')
+  const [loadingMore, setLoadingMore] = useState(false)
 
+  // 按搜索条件和排序方式加载商品（服务端分页）
+  const fetchProducts = (cursor = '') => {
+    const params = new URLSearchParams({ sort: sortBy, page_size: String(PAGE_SIZE) })
+    if (searchQuery.trim()) params.set('q', searchQuery.trim())
+    if (cursor) params.set('cursor', cursor)
+    return apiGet<{ products: Product[]; total: number; next_cursor?: string }>(`/api/products?${params}`)
+  }
 
-  // 加载商品列表
+  // 加载商品列表（搜索输入防抖）
   useEffect(() => {
-    const loadProducts = async () => {
-      const res = await apiGet<{ products: Product[] }>('/api/products')
+    let cancelled = false
+    const timer = setTimeout(async () => {
+      const res = await fetchProducts()
+      if (cancelled) return
       if (res.success && res.products) {
         setProducts(res.products)
+        setTotal(res.total || 0)
+        setNextCursor(res.next_cursor || '')
       }
       setLoading(false)
+    }, searchQuery ? 300 : 0)
+    return () => {
+      cancelled = true
+      clearTimeout(timer)
     }
-    loadProducts()
-  }, [])
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [searchQuery, sortBy])
 
-  // 过滤和排序商品
-  const filteredProducts = useMemo(() => {
-    let result = [...products]
-    
-    // 搜索过滤
-    if (searchQuery.trim()) {
-      const query = searchQuery.toLowerCase()
-      result = result.filter(p => 
-        p.name.toLowerCase().includes(query) || 
-        (p.description && p.description.toLowerCase().includes(query))
-      )
-    }
-    
-    // 排序
-    if (sortBy === 'price_asc') {
-      result.sort((a, b) => a.price - b.price)
-    } else if (sortBy === 'price_desc') {
-      result.sort((a, b) => b.price - a.price)
+  // 加载下一页
+  const handleLoadMore = async () => {
+    if (!nextCursor || loadingMore) return
+    setLoadingMore(true)
+    const res = await fetchProducts(nextCursor)
+    setLoadingMore(false)
+    if (res.success && res.products) {
+      setProducts(prev => [...prev, ...(res.products || [])])
+      setNextCursor(res.next_cursor || '')
     }
-    
-    return result
-  }, [products, searchQuery, sortBy])
+  }
 
   // 选择商品
   const handleSelectProduct = (product: Product) => {
@@ -173,8 +182,8 @@ export default function ProductsPage() {
               animate={{ opacity: 1 }}
               className="mb-4 text-dark-400 text-sm"
             >
-              {t('product.foundProducts').replace('{count}', String(filteredProducts.length))}
-              {filteredProducts.length === 0 && (
+              {t('product.foundProducts').replace('{count}', String(total))}
+              {total === 0 && (
                 <span className="ml-2">- {t('product.tryOtherKeywords')}</span>
               )}
             </motion.div>
@@ -190,7 +199,7 @@ export default function ProductsPage() {
                 </div>
               ))}
             </div>
-          ) : filteredProducts.length === 0 ? (
+          ) : products.length === 0 ? (
             <div className="text-center py-20">
               <div className="text-6xl mb-4">{searchQuery ? '🔍' : '📦'}</div>
               <p className="text-dark-400">
@@ -204,7 +213,7 @@ export default function ProductsPage() {
             </div>
           ) : (
             <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 gap-6">
-              {filteredProducts.map((product) => (
+              {products.map((product) => (
                 <div
                   key={product.id}
                   className="product-card cursor-pointer"
@@ -252,6 +261,14 @@ export default function ProductsPage() {
               ))}
             </div>
           )}
+
+          {!loading && nextCursor && (
+            <div className="mt-8 text-center">
+              <Button variant="secondary" onClick={handleLoadMore} loading={loadingMore}>
+                {t('product.loadMore')}
+              </Button>
+            </div>
+          )}
         </div>
       </main>
 
@@ -0,0 +1,192 @@
+'use client'
+
+import { useState, useEffect } from 'react'
+import toast from 'react-hot-toast'
+import { Button, Card, Input, Switch } from '@/components/ui'
+import { apiGet, apiPost, apiPut } from '@/lib/api'
+import { BackupConfig, BackupTarget } from './types'
+
+const selectClass = 'w-full px-3 py-2 bg-dark-700 border border-dark-600 rounded-lg text-dark-100'
+
+const emptyTarget = (): BackupTarget => ({
+  name: '', type: 'local', enabled: true, path: '',
+  endpoint: '', region: 'us-east-1', bucket: '', access_key: '', path_style: false, prefix: 'backups/',
+  host: '', port: 22, user: '', host_key_fingerprint: '', timeout: 60,
+})
+
+/**
+ * 备份设置组件
+ * 配置备份压缩加密、定时备份、保留策略与异地存放目标
+ */
+export function BackupSettings() {
+  const [form, setForm] = useState<BackupConfig | null>(null)
+  const [saving, setSaving] = useState(false)
+  const [testing, setTesting] = useState<number | null>(null)
+
+  useEffect(() => {
+    apiGet<{ config: BackupConfig }>('/api/admin/backup/config').then(res => {
+      if (res.success && res.config) setForm({ ...res.config, targets: res.config.targets || [] })
+    })
+  }, [])
+
+  if (!form) return null
+
+  const setTarget = (index: number, patch: Partial<BackupTarget>) => {
+    setForm({ ...form, targets: form.targets.map((t, i) => (i === index ? { ...t, ...patch } : t)) })
+  }
+
+  const handleTest = async (index: number) => {
+    setTesting(index)
+    const res = await apiPost<{ message: string; fingerprint?: string }>('/api/admin/backup/target/test', { ...form.targets[index] })
+    setTesting(null)
+    if (res.success) toast.success(res.message || '连接成功')
+    else toast.error(res.error || '连接失败')
+    if (res.fingerprint && !form.targets[index].host_key_fingerprint) {
+      setTarget(index, { host_key_fingerprint: res.fingerprint })
+      toast('已填入服务器主机指纹，请与服务器核对后保存', { icon: '🔑' })
+    }
+  }
+
+  const handleSave = async () => {
+    setSaving(true)
+    const res = await apiPut('/api/admin/backup/config', { ...form })
+    setSaving(false)
+    if (res.success) {
+      toast.success('备份配置已保存')
+      setForm({ ...form, passphrase: '', has_passphrase: form.has_passphrase || !!form.passphrase })
+    } else toast.error(res.error || '保存失败')
+  }
+
+  return (
+    <Card title="备份设置">
+      <div className="space-y-6">
+        {/* 压缩与加密 */}
+        <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+          <Switch checked={form.compress} onChange={(v) => setForm({ ...form, compress: v })} label="压缩备份" description="使用 gzip 压缩 SQL 导出文件" />
+          <Switch checked={form.encrypt} onChange={(v) => setForm({ ...form, encrypt: v })} label="加密备份" description="AES-256-GCM 加密，备份包含全部客户邮箱与卡密，强烈建议开启" />
+        </div>
+        {form.encrypt && (
+          <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+            <div>
+              <label className="block text-sm font-medium text-dark-300 mb-1">加密密钥</label>
+              <select className={selectClass} value={form.encryption_mode} onChange={(e) => setForm({ ...form, encryption_mode: e.target.value as BackupConfig['encryption_mode'] })}>
+                <option value="config_key">配置加密密钥</option>
+                <option value="passphrase">独立备份口令</option>
+              </select>
+            </div>
+            {form.encryption_mode === 'passphrase' && (
+              <Input
+                label="备份口令（至少12位）"
+                type="password"
+                value={form.passphrase || ''}
+                placeholder={form.has_passphrase ? '已设置，留空保持不变' : ''}
+                onChange={(e) => setForm({ ...form, passphrase: e.target.value })}
+              />
+            )}
+          </div>
+        )}
+        {form.encrypt && (
+          <p className="text-yellow-400/80 text-xs">
+            {form.encryption_mode === 'passphrase'
+              ? '请妥善保管备份口令，丢失后将无法恢复加密备份；修改口令后旧备份需使用旧口令恢复。'
+              : '使用配置加密密钥时，请同时备份 ENCRYPTION_KEY，否则在新服务器上无法解密备份。'}
+          </p>
+        )}
+
+        {/* 定时备份 */}
+        <div className="border-t border-dark-700 pt-4 space-y-4">
+          <Switch checked={form.schedule_enabled} onChange={(v) => setForm({ ...form, schedule_enabled: v })} label="定时备份" description="按间隔自动备份并上传到已启用的异地目标" />
+          {form.schedule_enabled && (
+            <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+              <Input label="备份间隔（小时）" type="number" min={1} value={form.interval_hours} onChange={(e) => setForm({ ...form, interval_hours: parseInt(e.target.value) || 0 })} />
+              <div className="flex items-end pb-2">
+                <Switch checked={form.include_objects} onChange={(v) => setForm({ ...form, include_objects: v })} label="包含存储文件" />
+              </div>
+            </div>
+          )}
+          <div>
+            <p className="text-sm font-medium text-dark-300 mb-2">保留策略（仅作用于定时备份，全部为 0 时不自动清理）</p>
+            <div className="grid grid-cols-2 md:grid-cols-4 gap-4">
+              <Input label="保留最近 N 个" type="number" min={0} value={form.keep_last} onChange={(e) => setForm({ ...form, keep_last: parseInt(e.target.value) || 0 })} />
+              <Input label="每日保留（天）" type="number" min={0} value={form.keep_daily} onChange={(e) => setForm({ ...form, keep_daily: parseInt(e.target.value) || 0 })} />
+              <Input label="每周保留（周）" type="number" min={0} value={form.keep_weekly} onChange={(e) => setForm({ ...form, keep_weekly: parseInt(e.target.value) || 0 })} />
+              <Input label="每月保留（月）" type="number" min={0} value={form.keep_monthly} onChange={(e) => setForm({ ...form, keep_monthly: parseInt(e.target.value) || 0 })} />
+            </div>
+          </div>
+        </div>
+
+        {/* 异地目标 */}
+        <div className="border-t border-dark-700 pt-4 space-y-4">
+          <div className="flex justify-between items-center">
+            <p className="text-sm font-medium text-dark-300">异地存放目标</p>
+            <Button size="sm" variant="secondary" onClick={() => setForm({ ...form, targets: [...form.targets, emptyTarget()] })}>添加目标</Button>
+          </div>
+          {form.targets.length === 0 && <p className="text-dark-500 text-sm">未配置异地目标，备份仅保存在本机</p>}
+          {form.targets.map((t, i) => (
+            <div key={i} className="p-4 border border-dark-700 rounded-lg space-y-4">
+              <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
+                <Input label="名称" value={t.name} onChange={(e) => setTarget(i, { name: e.target.value })} />
+                <div>
+                  <label className="block text-sm font-medium text-dark-300 mb-1">类型</label>
+                  <select className={selectClass} value={t.type} onChange={(e) => setTarget(i, { type: e.target.value as BackupTarget['type'] })}>
+                    <option value="local">本地挂载目录</option>
+                    <option value="s3">S3 兼容存储</option>
+                    <option value="sftp">SFTP</option>
+                  </select>
+                </div>
+                <div className="flex items-end pb-2">
+                  <Switch checked={t.enabled} onChange={(v) => setTarget(i, { enabled: v })} label="启用" />
+                </div>
+              </div>
+
+              {t.type === 'local' && (
+                <Input label="挂载目录" value={t.path} placeholder="/mnt/backup" onChange={(e) => setTarget(i, { path: e.target.value })} />
+              )}
+
+              {t.type === 's3' && (
+                <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+                  <Input label="Endpoint" value={t.endpoint} placeholder="https://s3.amazonaws.com" onChange={(e) => setTarget(i, { endpoint: e.target.value })} />
+                  <Input label="Region" value={t.region} onChange={(e) => setTarget(i, { region: e.target.value })} />
+                  <Input label="Bucket" value={t.bucket} onChange={(e) => setTarget(i, { bucket: e.target.value })} />
+                  <Input label="前缀" value={t.prefix} onChange={(e) => setTarget(i, { prefix: e.target.value })} />
+                  <Input label="Access Key" value={t.access_key} onChange={(e) => setTarget(i, { access_key: e.target.value })} />
+                  <Input label="Secret Key" type="password" value={t.secret_key || ''} placeholder={t.has_secret_key ? '已设置，留空保持不变' : ''} onChange={(e) => setTarget(i, { secret_key: e.target.value })} />
+                  <Switch checked={t.path_style} onChange={(v) => setTarget(i, { path_style: v })} label="Path-Style 访问" description="MinIO 等自建存储通常需要开启" />
+                </div>
+              )}
+
+              {t.type === 'sftp' && (
+                <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+                  <Input label="主机" value={t.host} onChange={(e) => setTarget(i, { host: e.target.value })} />
+                  <Input label="端口" type="number" value={t.port} onChange={(e) => setTarget(i, { port: parseInt(e.target.value) || 22 })} />
+                  <Input label="用户名" value={t.user} onChange={(e) => setTarget(i, { user: e.target.value })} />
+                  <Input label="远程目录" value={t.path} placeholder="backups" onChange={(e) => setTarget(i, { path: e.target.value })} />
+                  <Input label="密码" type="password" value={t.password || ''} placeholder={t.has_password ? '已设置，留空保持不变' : ''} onChange={(e) => setTarget(i, { password: e.target.value })} />
+                  <Input label="主机指纹" value={t.host_key_fingerprint} placeholder="SHA256:...（测试连接后自动填入）" onChange={(e) => setTarget(i, { host_key_fingerprint: e.target.value })} />
+                  <div className="md:col-span-2">
+                    <label className="block text-sm font-medium text-dark-300 mb-1">私钥（OpenSSH 格式，可选）</label>
+                    <textarea
+                      className={`${selectClass} h-24 font-mono text-xs`}
+                      value={t.private_key || ''}
+                      placeholder={t.has_private_key ? '已设置，留空保持不变' : ''}
+                      onChange={(e) => setTarget(i, { private_key: e.target.value })}
+                    />
+                  </div>
+                </div>
+              )}
+
+              <div className="flex justify-end gap-2">
+                <Button size="sm" variant="ghost" loading={testing === i} onClick={() => handleTest(i)}>测试连接</Button>
+                <Button size="sm" variant="ghost" onClick={() => setForm({ ...form, targets: form.targets.filter((_, j) => j !== i) })}>移除</Button>
+              </div>
+            </div>
+          ))}
+        </div>
+
+        <div className="flex justify-end">
+          <Button onClick={handleSave} loading={saving}>保存设置</Button>
+        </div>
+      </div>
+    </Card>
+  )
+}
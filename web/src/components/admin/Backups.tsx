@@ -1,10 +1,11 @@
 'use client'
 
-import { useState, useEffect, useCallback } from 'react'
+import { useState, useEffect, useCallback, useRef } from 'react'
 import toast from 'react-hot-toast'
 import { Button, Card, PromptModal, ConfirmModal } from '@/components/ui'
 import { apiGet, apiPost, apiDelete } from '@/lib/api'
-import { Backup } from './types'
+import { Backup, BackupVerification } from './types'
+import { BackupSettings } from './BackupSettings'
 
 export function BackupsPage() {
   const [backups, setBackups] = useState<Backup[]>([])
@@ -15,6 +16,12 @@ export function BackupsPage() {
   const [deleteId, setDeleteId] = useState<number | null>(null)
   const [createLoading, setCreateLoading] = useState(false)
   const [deleteLoading, setDeleteLoading] = useState(false)
+  const [restoreTarget, setRestoreTarget] = useState<{ backup: Backup; verification: BackupVerification } | null>(null)
+  const [restoreLoading, setRestoreLoading] = useState(false)
+  const [exportLoading, setExportLoading] = useState(false)
+  const [importTarget, setImportTarget] = useState<Backup | null>(null)
+  const [importLoading, setImportLoading] = useState(false)
+  const uploadRef = useRef<HTMLInputElement>(null)
 
   const loadBackups = useCallback(async () => {
     const [backupsRes, infoRes] = await Promise.all([
@@ -37,6 +44,57 @@ export function BackupsPage() {
     else toast.error(res.error || '备份失败')
   }
 
+  const handleExport = async () => {
+    setExportLoading(true)
+    const res = await apiPost<{ backup: Backup }>('/api/admin/backup/export', {})
+    setExportLoading(false)
+    if (res.success) { toast.success(`数据导出成功：${res.backup?.filename}`); loadBackups() }
+    else toast.error(res.error || '导出失败')
+  }
+
+  const handleUploadExport = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const file = e.target.files?.[0]
+    e.target.value = ''
+    if (!file) return
+    const formData = new FormData()
+    formData.append('file', file)
+    try {
+      const res = await fetch('/api/admin/backup/upload', { method: 'POST', body: formData, credentials: 'include' })
+      const data = await res.json()
+      if (data.success) { toast.success('导出文件上传成功'); loadBackups() }
+      else toast.error(data.error || '上传失败')
+    } catch {
+      toast.error('上传失败')
+    }
+  }
+
+  const handleImport = async (confirm: string, force = false) => {
+    if (!importTarget) return
+    setImportLoading(true)
+    const res = await apiPost<{ result: { snapshot_file: string; tables: number; rows: number; source_db: string; warnings?: string[] } }>(
+      `/api/admin/backup/${importTarget.id}/import`,
+      { confirm: confirm.trim(), force }
+    )
+    setImportLoading(false)
+    if (res.success) {
+      setImportTarget(null)
+      toast.success(`导入完成：${res.result?.tables} 张表，${res.result?.rows} 条记录（来源 ${res.result?.source_db}）。导入前快照：${res.result?.snapshot_file}`, { duration: 8000 })
+      res.result?.warnings?.forEach(w => toast(w, { icon: '⚠️' }))
+      loadBackups()
+    } else if (!force && res.error?.includes('强制导入') && confirm(`${res.error}\n\n是否仍然导入？`)) {
+      handleImport(confirm, true)
+    } else toast.error(res.error || '导入失败')
+  }
+
+  const handleUpload = async (id: number) => {
+    const res = await apiPost<{ uploads: { target: string; status: string; error?: string }[] }>(`/api/admin/backup/${id}/upload`, {})
+    if (!res.success) { toast.error(res.error || '上传失败'); return }
+    const failed = (res.uploads || []).filter(u => u.status !== 'success')
+    if (failed.length === 0) toast.success('已上传到全部异地目标')
+    else failed.forEach(u => toast.error(`${u.target}：${u.error}`))
+    loadBackups()
+  }
+
   const handleDownload = (id: number) => {
     window.open(`/api/admin/backup/${id}/download`, '_blank')
   }
@@ -57,7 +115,43 @@ export function BackupsPage() {
     else toast.error(res.error || '删除失败')
   }
 
+  const handleRestoreClick = async (backup: Backup) => {
+    const res = await apiPost<{ verification: BackupVerification }>(`/api/admin/backup/${backup.id}/verify`, {})
+    if (!res.success || !res.verification) { toast.error(res.error || '校验失败'); return }
+    if (res.verification.errors?.length) { toast.error(res.verification.errors.join('；')); return }
+    setRestoreTarget({ backup, verification: res.verification })
+  }
+
+  const handleRestore = async (confirm: string) => {
+    if (!restoreTarget) return
+    setRestoreLoading(true)
+    const res = await apiPost<{ result: { snapshot_file: string; tables: number; rows: number; warnings?: string[] } }>(
+      `/api/admin/backup/${restoreTarget.backup.id}/restore`,
+      {
+        confirm: confirm.trim(),
+        force: restoreTarget.verification.requires_force,
+        restore_objects: !!restoreTarget.backup.include_objects,
+      }
+    )
+    setRestoreLoading(false)
+    if (res.success) {
+      setRestoreTarget(null)
+      toast.success(`恢复完成：${res.result?.tables} 张表，${res.result?.rows} 条记录。恢复前快照：${res.result?.snapshot_file}`, { duration: 8000 })
+      res.result?.warnings?.forEach(w => toast(w, { icon: '⚠️' }))
+      loadBackups()
+    } else toast.error(res.error || '恢复失败')
+  }
+
+  const restoreMessage = (target: { backup: Backup; verification: BackupVerification }) => {
+    const lines = ['恢复将覆盖当前全部数据，恢复前会自动创建当前数据库快照，恢复期间网站暂停访问。']
+    if (target.verification.checksum_ok) lines.push('✓ 校验和一致')
+    target.verification.warnings?.forEach(w => lines.push(`⚠ ${w}`))
+    lines.push(`请输入备份文件名 ${target.backup.filename} 确认恢复`)
+    return lines.join('\n')
+  }
+
   const dbTypeText: Record<string, string> = { sqlite: 'SQLite', mysql: 'MySQL', postgres: 'PostgreSQL' }
+  const kindText: Record<string, string> = { manual: '手动', scheduled: '定时', snapshot: '快照', uploaded: '上传' }
 
   if (loading) return <div className="text-center py-12"><i className="fas fa-spinner fa-spin text-2xl text-primary-400" /></div>
 
@@ -66,7 +160,12 @@ export function BackupsPage() {
     <div className="space-y-4">
       <div className="flex justify-between items-center">
         <h2 className="text-lg font-medium text-dark-100">数据备份</h2>
-        <Button size="sm" onClick={() => setShowCreateModal(true)}>创建备份</Button>
+        <div className="flex gap-2">
+          <input ref={uploadRef} type="file" accept=".jsonl,.gz,.enc" className="hidden" onChange={handleUploadExport} />
+          <Button size="sm" variant="secondary" onClick={() => uploadRef.current?.click()}>上传导出文件</Button>
+          <Button size="sm" variant="secondary" loading={exportLoading} onClick={handleExport}>导出数据</Button>
+          <Button size="sm" onClick={() => setShowCreateModal(true)}>创建备份</Button>
+        </div>
       </div>
       <Card>
         <div className="p-4 bg-blue-500/10 border border-blue-500/20 rounded-lg mb-4">
@@ -83,6 +182,7 @@ export function BackupsPage() {
                   <th className="text-left py-3 px-4 text-dark-400 font-medium">文件名</th>
                   <th className="text-left py-3 px-4 text-dark-400 font-medium">大小</th>
                   <th className="text-left py-3 px-4 text-dark-400 font-medium">类型</th>
+                  <th className="text-left py-3 px-4 text-dark-400 font-medium">异地</th>
                   <th className="text-left py-3 px-4 text-dark-400 font-medium">备注</th>
                   <th className="text-left py-3 px-4 text-dark-400 font-medium">创建者</th>
                   <th className="text-left py-3 px-4 text-dark-400 font-medium">创建时间</th>
@@ -92,15 +192,34 @@ export function BackupsPage() {
               <tbody>
                 {backups.map((backup) => (
                   <tr key={backup.id} className="border-b border-dark-700/50 hover:bg-dark-700/30">
-                    <td className="py-3 px-4 text-dark-100 font-mono text-sm">{backup.filename}</td>
+                    <td className="py-3 px-4 text-dark-100 font-mono text-sm">
+                      {backup.encrypted && <i className="fas fa-lock text-green-400 mr-2" title="已加密" />}
+                      {backup.filename}
+                    </td>
                     <td className="py-3 px-4 text-dark-300">{backup.file_size_text}</td>
-                    <td className="py-3 px-4 text-dark-300">{backup.db_type.toUpperCase()}</td>
+                    <td className="py-3 px-4 text-dark-300">
+                      {backup.db_type.toUpperCase()}
+                      {backup.kind && <span className="text-dark-500 text-xs ml-1">{kindText[backup.kind] || backup.kind}</span>}
+                    </td>
+                    <td className="py-3 px-4 text-sm">
+                      {backup.uploads?.length ? backup.uploads.map(u => (
+                        <span key={u.target} className={`block ${u.status === 'success' ? 'text-green-400' : 'text-red-400'}`} title={u.error || u.remote_key}>
+                          {u.status === 'success' ? '✓' : '✗'} {u.target}
+                        </span>
+                      )) : <span className="text-dark-500">-</span>}
+                    </td>
                     <td className="py-3 px-4 text-dark-300">{backup.remark || '-'}</td>
                     <td className="py-3 px-4 text-dark-300">{backup.created_by}</td>
                     <td className="py-3 px-4 text-dark-300 text-sm">{backup.created_at}</td>
                     <td className="py-3 px-4">
                       <div className="flex gap-2">
                         <Button size="sm" variant="ghost" onClick={() => handleDownload(backup.id)}>下载</Button>
+                        <Button size="sm" variant="ghost" onClick={() => handleUpload(backup.id)}>上传</Button>
+                        {backup.db_type !== 'json' ? (
+                          <Button size="sm" variant="ghost" onClick={() => handleRestoreClick(backup)}>恢复</Button>
+                        ) : (
+                          <Button size="sm" variant="ghost" onClick={() => setImportTarget(backup)}>导入</Button>
+                        )}
                         <Button size="sm" variant="ghost" onClick={() => handleDeleteClick(backup.id)}>删除</Button>
                       </div>
                     </td>
@@ -115,12 +234,18 @@ export function BackupsPage() {
       <Card title="备份说明">
         <ul className="text-dark-400 text-sm space-y-2">
           <li>• <strong>SQLite</strong>：直接复制数据库文件并压缩为ZIP格式</li>
-          <li>• <strong>MySQL/PostgreSQL</strong>：导出SQL语句文件</li>
+          <li>• <strong>MySQL/PostgreSQL</strong>：导出SQL语句文件，可开启 gzip 压缩</li>
+          <li>• 开启加密后备份文件使用 AES-256-GCM 加密（.enc），下载的文件需由本系统恢复或使用相同密钥解密</li>
+          <li>• 定时备份完成后自动上传到已启用的异地目标，并按保留策略清理过期的定时备份（含异地副本）</li>
           <li>• 建议定期备份数据，并将备份文件下载到本地或其他安全位置</li>
-          <li>• 恢复数据需要手动操作，请参考技术文档</li>
+          <li>• 恢复前会校验备份文件的校验和与数据库结构版本，并自动创建当前数据库快照，恢复失败时数据不会变更</li>
+          <li>• 恢复期间网站进入维护模式，所有访问将暂时返回维护提示</li>
+          <li>• <strong>导出数据</strong>：导出全部业务数据为与数据库类型无关的 JSON Lines 文件，可在其他服务器上传后导入，用于 SQLite 迁移到 MySQL/PostgreSQL；导入会替换文件中包含的表并保留原有 ID</li>
         </ul>
       </Card>
 
+      <BackupSettings />
+
       {/* 创建备份弹窗 */}
       <PromptModal
         isOpen={showCreateModal}
@@ -133,6 +258,32 @@ export function BackupsPage() {
         loading={createLoading}
       />
 
+      {/* 恢复确认弹窗 */}
+      <PromptModal
+        isOpen={!!restoreTarget}
+        onClose={() => { if (!restoreLoading) setRestoreTarget(null) }}
+        title="恢复备份"
+        message={restoreTarget ? restoreMessage(restoreTarget) : ''}
+        placeholder="备份文件名"
+        confirmText="恢复"
+        required
+        onConfirm={handleRestore}
+        loading={restoreLoading}
+      />
+
+      {/* 导入确认弹窗 */}
+      <PromptModal
+        isOpen={!!importTarget}
+        onClose={() => { if (!importLoading) setImportTarget(null) }}
+        title="导入数据"
+        message={importTarget ? `导入将替换当前数据库中导出文件包含的全部表，导入前会自动创建当前数据库快照，导入期间网站暂停访问。\n请输入文件名 ${importTarget.filename} 确认导入` : ''}
+        placeholder="文件名"
+        confirmText="导入"
+        required
+        onConfirm={(v) => handleImport(v)}
+        loading={importLoading}
+      />
+
       {/* 删除确认弹窗 */}
       <ConfirmModal
         isOpen={showDeleteModal}
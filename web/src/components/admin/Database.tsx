@@ -65,7 +65,7 @@ export function DatabasePage() {
 
   // 重置加密密钥
   const handleResetKey = async () => {
-    if (resetConfirmText !== '我确认重置密钥并了解数据将永久丢失') {
+    if (resetConfirmText !== '我已保存旧密钥并确认重置') {
       toast.error('请输入正确的确认文字')
       return
     }
@@ -150,7 +150,7 @@ export function DatabasePage() {
             <Button variant="danger" onClick={() => setShowResetModal(true)}>
               <i className="fas fa-exclamation-triangle mr-2" />重置密钥
             </Button>
-            <p className="text-dark-500 text-xs mt-2">⚠️ 重置密钥后，之前加密的数据将无法解密，请谨慎操作</p>
+            <p className="text-dark-500 text-xs mt-2">⚠️ 重置后已加密的配置会自动改用新密钥，但旧备份和操作日志仍需旧密钥解密，请谨慎操作</p>
           </div>
         </div>
       </Card>
@@ -161,9 +161,10 @@ export function DatabasePage() {
           <div className="p-4 bg-red-500/10 border border-red-500/20 rounded-lg">
             <p className="text-red-400 text-sm font-medium mb-2">危险操作警告</p>
             <ul className="text-red-400/80 text-sm space-y-1 list-disc list-inside">
-              <li>重置密钥后，所有使用旧密钥加密的数据将<strong>永久无法解密</strong></li>
-              <li>数据库连接密码等敏感配置将丢失</li>
-              <li>需要重新配置数据库连接信息</li>
+              <li>数据库密码、SMTP 密码、支付密钥、TOTP 密钥及 Redis/对象存储/备份目标密钥将自动改用新密钥加密</li>
+              <li>使用配置加密密钥加密的<strong>备份文件和操作日志不会改写</strong>，仍需旧密钥才能解密，请先复制保存当前密钥</li>
+              <li>重置期间所有实例进入维护模式，暂停对外服务和后台任务</li>
+              <li>重置完成后<strong>必须重启所有服务实例</strong>以加载新密钥</li>
               <li>此操作<strong>不可撤销</strong></li>
             </ul>
           </div>
@@ -181,7 +182,7 @@ export function DatabasePage() {
           </div>
           <div>
             <label className="block text-sm font-medium text-dark-300 mb-1">
-              请输入确认文字：<span className="text-red-400">我确认重置密钥并了解数据将永久丢失</span>
+              请输入确认文字：<span className="text-red-400">我已保存旧密钥并确认重置</span>
             </label>
             <input
               type="text"
@@ -196,7 +197,7 @@ export function DatabasePage() {
             <Button
               variant="danger"
               onClick={handleResetKey}
-              disabled={resetLoading || resetConfirmText !== '我确认重置密钥并了解数据将永久丢失'}
+              disabled={resetLoading || resetConfirmText !== '我已保存旧密钥并确认重置'}
             >
               {resetLoading ? '重置中...' : '确认重置'}
             </Button>
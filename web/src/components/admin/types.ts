@@ -109,11 +109,84 @@ export interface Backup {
   filename: string
   file_size_text: string
   db_type: string
+  include_objects?: boolean
+  kind?: 'manual' | 'scheduled' | 'snapshot' | 'uploaded'
+  compressed?: boolean
+  encrypted?: boolean
+  checksum?: string
+  schema_version?: number
+  uploads?: BackupUpload[]
   remark: string
   created_by: string
   created_at: string
 }
 
+// 备份异地上传记录
+export interface BackupUpload {
+  id: number
+  backup_id: number
+  target: string
+  remote_key: string
+  status: 'success' | 'failed'
+  error?: string
+  updated_at: string
+}
+
+// 备份异地存放目标（密钥仅在填写时提交，留空沿用已保存的值）
+export interface BackupTarget {
+  name: string
+  type: 's3' | 'sftp' | 'local'
+  enabled: boolean
+  path: string
+  endpoint: string
+  region: string
+  bucket: string
+  access_key: string
+  secret_key?: string
+  has_secret_key?: boolean
+  path_style: boolean
+  prefix: string
+  host: string
+  port: number
+  user: string
+  password?: string
+  has_password?: boolean
+  private_key?: string
+  has_private_key?: boolean
+  host_key_fingerprint: string
+  timeout: number
+}
+
+// 备份配置
+export interface BackupConfig {
+  compress: boolean
+  encrypt: boolean
+  encryption_mode: 'config_key' | 'passphrase'
+  passphrase?: string
+  has_passphrase?: boolean
+  schedule_enabled: boolean
+  interval_hours: number
+  include_objects: boolean
+  keep_last: number
+  keep_daily: number
+  keep_weekly: number
+  keep_monthly: number
+  targets: BackupTarget[]
+}
+
+// 备份校验结果
+export interface BackupVerification {
+  backup_id: number
+  filename: string
+  checksum_ok: boolean
+  schema_version: number
+  current_schema_version: number
+  restorable: boolean
+  requires_force: boolean
+  errors?: string[]
+  warnings?: string[]
+}
+
 // 日志（文件存储版本，使用AES-256-GCM加密）
 export interface Log {
   id: number
@@ -32,9 +32,9 @@ export function ProductsSection({ config }: ProductsSectionProps) {
     if (!config.products_enabled) return
 
     const loadProducts = async () => {
-      const res = await apiGet<{ products: Product[] }>('/api/products')
+      const res = await apiGet<{ products: Product[] }>(`/api/products?page_size=${config.products_count || 6}`)
       if (res.success && res.products) {
-        setProducts(res.products.slice(0, config.products_count || 6))
+        setProducts(res.products)
       }
       setLoading(false)
     }
@@ -296,7 +296,7 @@ export function PromptModal({
   return (
     <Modal isOpen={isOpen} onClose={handleClose} title={title} size="sm">
       <div className="space-y-4">
-        {message && <p className="text-dark-300 text-sm">{message}</p>}
+        {message && <p className="text-dark-300 text-sm whitespace-pre-line">{message}</p>}
         <input
           type={inputType}
           value={value}
@@ -200,6 +200,7 @@ const zhTranslations = {
     noMatchingProducts: '没有找到匹配的商品',
     tryOtherKeywords: '试试其他关键词？',
     clearSearch: '清除搜索',
+    loadMore: '加载更多',
     confirmPurchase: '确认购买',
     purchaseSuccess: '购买成功',
     congratulations: '恭喜您，购买成功！',
@@ -462,6 +463,7 @@ const enTranslations: typeof zhTranslations = {
     noMatchingProducts: 'No matching products found',
     tryOtherKeywords: 'Try other keywords?',
     clearSearch: 'Clear search',
+    loadMore: 'Load more',
     confirmPurchase: 'Confirm Purchase',
     purchaseSuccess: 'Purchase Successful',
     congratulations: 'Congratulations! Purchase successful!',